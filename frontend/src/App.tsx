@@ -3,6 +3,7 @@ import ProtectedRoute from './components/ProtectedRoute'
 import LoginPage from './pages/LoginPage'
 import DashboardPage from './pages/DashboardPage'
 import WorkbookPage from './pages/WorkbookPage'
+import SharedPage from './pages/SharedPage'
 
 const router = createBrowserRouter([
   {
@@ -25,6 +26,10 @@ const router = createBrowserRouter([
       </ProtectedRoute>
     ),
   },
+  {
+    path: '/s/:token',
+    element: <SharedPage />,
+  },
   {
     path: '*',
     element: <Navigate to="/" replace />,
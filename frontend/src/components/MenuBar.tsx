@@ -1,5 +1,6 @@
 import { useState, useRef, useEffect, useCallback } from "react";
 import type { Model } from "@ironcalc/workbook";
+import type { StructureChange } from "../lib/api";
 import * as actions from "../lib/toolbar-actions";
 import styles from "./MenuBar.module.css";
 
@@ -8,6 +9,8 @@ interface MenuBarProps {
   title: string;
   onSave: () => void;
   onRefresh: () => void;
+  /** Called with the rows or columns an action inserted or deleted */
+  onStructureChange: (change: StructureChange) => void;
 }
 
 interface MenuItem {
@@ -24,6 +27,7 @@ export default function MenuBar({
   title,
   onSave,
   onRefresh,
+  onStructureChange,
 }: MenuBarProps) {
   const [openMenu, setOpenMenu] = useState<string | null>(null);
   const menuBarRef = useRef<HTMLDivElement>(null);
@@ -86,20 +90,20 @@ export default function MenuBar({
     Insert: [
       {
         label: "Row above",
-        action: () => run(() => actions.insertRowAbove(model)),
+        action: () => run(() => onStructureChange(actions.insertRowAbove(model))),
       },
       {
         label: "Row below",
-        action: () => run(() => actions.insertRowBelow(model)),
+        action: () => run(() => onStructureChange(actions.insertRowBelow(model))),
       },
       { label: "", divider: true },
       {
         label: "Column left",
-        action: () => run(() => actions.insertColumnLeft(model)),
+        action: () => run(() => onStructureChange(actions.insertColumnLeft(model))),
       },
       {
         label: "Column right",
-        action: () => run(() => actions.insertColumnRight(model)),
+        action: () => run(() => onStructureChange(actions.insertColumnRight(model))),
       },
     ],
     Format: [
@@ -203,11 +207,11 @@ export default function MenuBar({
     Data: [
       {
         label: "Delete row",
-        action: () => run(() => actions.deleteRow(model)),
+        action: () => run(() => onStructureChange(actions.deleteRow(model))),
       },
       {
         label: "Delete column",
-        action: () => run(() => actions.deleteColumn(model)),
+        action: () => run(() => onStructureChange(actions.deleteColumn(model))),
       },
     ],
   };
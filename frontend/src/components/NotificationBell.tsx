@@ -0,0 +1,173 @@
+import { useState, useEffect, useRef, useCallback } from "react";
+import { useNavigate } from "react-router-dom";
+import { Bell } from "lucide-react";
+import {
+  listNotifications,
+  markNotificationRead,
+  markAllNotificationsRead,
+  getNotificationPreferences,
+  updateNotificationPreferences,
+  streamNotifications,
+  type Notification,
+  type NotificationPreferences,
+  type NotificationType,
+} from "../lib/api";
+import styles from "./NotificationBell.module.css";
+
+// How long to wait before reopening a dropped notification stream.
+const RECONNECT_DELAY = 5000;
+
+const TYPE_LABELS: Record<NotificationType, string> = {
+  share: "Shared with me",
+  mention: "Mentions",
+  access_request: "Access requests",
+};
+
+export default function NotificationBell() {
+  const navigate = useNavigate();
+  const [open, setOpen] = useState(false);
+  const [items, setItems] = useState<Notification[]>([]);
+  const [unread, setUnread] = useState(0);
+  const [prefs, setPrefs] = useState<NotificationPreferences | null>(null);
+  const [showPrefs, setShowPrefs] = useState(false);
+  const ref = useRef<HTMLDivElement>(null);
+
+  const load = useCallback(async () => {
+    try {
+      const page = await listNotifications({ limit: 20 });
+      setItems(page.items);
+      setUnread(page.unread_count);
+    } catch (err) {
+      console.error("Failed to load notifications:", err);
+    }
+  }, []);
+
+  useEffect(() => {
+    load();
+  }, [load]);
+
+  // Follow the live stream, reopening it after it drops.
+  useEffect(() => {
+    const controller = new AbortController();
+    let timer: ReturnType<typeof setTimeout>;
+    const connect = () => {
+      streamNotifications((event) => {
+        if (event.type === "notification") {
+          setItems((prev) => [event.notification, ...prev.filter((n) => n.id !== event.notification.id)]);
+        } else {
+          setUnread(event.unread_count);
+        }
+      }, controller.signal)
+        .catch(() => {})
+        .finally(() => {
+          if (!controller.signal.aborted) timer = setTimeout(connect, RECONNECT_DELAY);
+        });
+    };
+    connect();
+    return () => {
+      controller.abort();
+      clearTimeout(timer);
+    };
+  }, []);
+
+  useEffect(() => {
+    const handleClick = (e: MouseEvent) => {
+      if (ref.current && !ref.current.contains(e.target as Node)) setOpen(false);
+    };
+    document.addEventListener("mousedown", handleClick);
+    return () => document.removeEventListener("mousedown", handleClick);
+  }, []);
+
+  const handleOpen = (n: Notification) => {
+    if (!n.read_at) {
+      setItems((prev) => prev.map((x) => (x.id === n.id ? { ...x, read_at: new Date().toISOString() } : x)));
+      markNotificationRead(n.id).catch((err) => console.error("Failed to mark notification read:", err));
+    }
+    setOpen(false);
+    if (n.spreadsheet_id && n.type !== "access_request") navigate(`/spreadsheet/${n.spreadsheet_id}`);
+  };
+
+  const handleMarkAll = async () => {
+    try {
+      await markAllNotificationsRead();
+      const now = new Date().toISOString();
+      setItems((prev) => prev.map((n) => (n.read_at ? n : { ...n, read_at: now })));
+    } catch (err) {
+      console.error("Failed to mark notifications read:", err);
+    }
+  };
+
+  const handleTogglePrefs = async () => {
+    if (!showPrefs && !prefs) {
+      try {
+        setPrefs(await getNotificationPreferences());
+      } catch (err) {
+        console.error("Failed to load notification preferences:", err);
+        return;
+      }
+    }
+    setShowPrefs(!showPrefs);
+  };
+
+  const handlePref = async (type: NotificationType, enabled: boolean) => {
+    try {
+      setPrefs(await updateNotificationPreferences({ [type]: enabled }));
+    } catch (err) {
+      console.error("Failed to update notification preferences:", err);
+    }
+  };
+
+  return (
+    <div className={styles.wrapper} ref={ref}>
+      <button className={styles.bell} onClick={() => setOpen(!open)} title="Notifications">
+        <Bell size={16} />
+        {unread > 0 && <span className={styles.badge}>{unread > 99 ? "99+" : unread}</span>}
+      </button>
+
+      {open && (
+        <div className={styles.panel}>
+          <div className={styles.panelHeader}>
+            <span className={styles.panelTitle}>Notifications</span>
+            <button className={styles.link} onClick={handleMarkAll} disabled={unread === 0}>
+              Mark all read
+            </button>
+            <button className={styles.link} onClick={handleTogglePrefs}>
+              {showPrefs ? "Done" : "Settings"}
+            </button>
+          </div>
+
+          {showPrefs && prefs ? (
+            <div className={styles.prefs}>
+              {(Object.keys(TYPE_LABELS) as NotificationType[]).map((type) => (
+                <label key={type} className={styles.pref}>
+                  <input
+                    type="checkbox"
+                    checked={prefs[type]}
+                    onChange={(e) => handlePref(type, e.target.checked)}
+                  />
+                  {TYPE_LABELS[type]}
+                </label>
+              ))}
+            </div>
+          ) : items.length === 0 ? (
+            <div className={styles.empty}>You're all caught up</div>
+          ) : (
+            <ul className={styles.list}>
+              {items.map((n) => (
+                <li key={n.id}>
+                  <button
+                    className={`${styles.item} ${n.read_at ? "" : styles.unread}`}
+                    onClick={() => handleOpen(n)}
+                  >
+                    <span className={styles.message}>{n.message}</span>
+                    <span className={styles.time}>{new Date(n.created_at).toLocaleString()}</span>
+                  </button>
+                </li>
+              ))}
+            </ul>
+          )}
+        </div>
+      )}
+    </div>
+  );
+}
@@ -12,15 +12,29 @@ function clearToken(): void {
   localStorage.removeItem('jaggle_token');
 }
 
+export class ApiError extends Error {
+  status: number;
+  body: Record<string, unknown>;
+
+  constructor(status: number, message: string, body: Record<string, unknown>) {
+    super(message);
+    this.status = status;
+    this.body = body;
+  }
+}
+
 async function request<T>(
   path: string,
   options: RequestInit = {}
 ): Promise<T> {
   const token = getToken();
   const headers: Record<string, string> = {
-    'Content-Type': 'application/json',
     ...(options.headers as Record<string, string>),
   };
+  // Let the browser set the multipart boundary for uploads.
+  if (!(options.body instanceof FormData)) {
+    headers['Content-Type'] = 'application/json';
+  }
 
   if (token) {
     headers['Authorization'] = `Bearer ${token}`;
@@ -31,7 +45,8 @@ async function request<T>(
     headers,
   });
 
-  if (response.status === 401) {
+  // A 401 without a token is a failed sign-in, not an expired session.
+  if (response.status === 401 && token) {
     clearToken();
     window.location.href = '/login';
     throw new Error('Unauthorized');
@@ -39,7 +54,7 @@ async function request<T>(
 
   if (!response.ok) {
     const error = await response.json().catch(() => ({ error: 'Request failed' }));
-    throw new Error(error.error || 'Request failed');
+    throw new ApiError(response.status, error.error || 'Request failed', error);
   }
 
   return response.json();
@@ -61,16 +76,94 @@ export interface AuthResponse {
   user: User;
 }
 
-export async function login(email: string, name: string): Promise<AuthResponse> {
+function storeSession(data: AuthResponse): void {
+  setToken(data.token);
+  localStorage.setItem('jaggle_user', JSON.stringify(data.user));
+}
+
+/**
+ * Sign in with a password. `linkToken` comes from a single sign-on attempt
+ * for an account that has a password, and links the two on success.
+ */
+export async function login(
+  email: string,
+  password: string,
+  linkToken?: string
+): Promise<AuthResponse> {
   const data = await request<AuthResponse>('/auth/login', {
     method: 'POST',
-    body: JSON.stringify({ email, name }),
+    body: JSON.stringify({ email, password, link_token: linkToken }),
   });
-  setToken(data.token);
-  localStorage.setItem('jaggle_user', JSON.stringify(data.user));
+  storeSession(data);
   return data;
 }
 
+export async function register(
+  email: string,
+  name: string,
+  password: string
+): Promise<AuthResponse> {
+  const data = await request<AuthResponse>('/auth/register', {
+    method: 'POST',
+    body: JSON.stringify({ email, name, password }),
+  });
+  storeSession(data);
+  return data;
+}
+
+export async function changePassword(
+  currentPassword: string,
+  newPassword: string
+): Promise<void> {
+  await request('/auth/password', {
+    method: 'POST',
+    body: JSON.stringify({ current_password: currentPassword, new_password: newPassword }),
+  });
+}
+
+/** Email a link to set a new password. Succeeds for unknown emails too. */
+export async function requestPasswordReset(email: string): Promise<void> {
+  await request('/auth/password/forgot', {
+    method: 'POST',
+    body: JSON.stringify({ email }),
+  });
+}
+
+/** Set a password with the token from an emailed link and sign in. */
+export async function resetPassword(
+  token: string,
+  password: string
+): Promise<AuthResponse> {
+  const data = await request<AuthResponse>('/auth/password/reset', {
+    method: 'POST',
+    body: JSON.stringify({ token, password }),
+  });
+  storeSession(data);
+  return data;
+}
+
+export interface AuthConfig {
+  mock_login: boolean;
+  oidc: boolean;
+}
+
+export async function getAuthConfig(): Promise<AuthConfig> {
+  return request<AuthConfig>('/auth/config');
+}
+
+/** Full-page redirect into the single sign-on flow. */
+export function startSsoLogin(): void {
+  window.location.href = `${API_BASE}/auth/oidc/login`;
+}
+
+/** Store the session token handed back by the SSO callback. */
+export async function completeSsoLogin(token: string): Promise<User> {
+  setToken(token);
+  const user = await getCurrentUser();
+  localStorage.setItem('jaggle_user', JSON.stringify(user));
+  return user;
+}
+
 export async function getCurrentUser(): Promise<User> {
   return request<User>('/auth/me');
 }
@@ -100,11 +193,15 @@ export function getCachedUser(): User | null {
 
 // Spreadsheets API
 
+export type Role = 'viewer' | 'commenter' | 'editor' | 'owner';
+
 export interface Spreadsheet {
   id: number;
   title: string;
   owner_id: number;
   data?: string;
+  version: number;
+  role?: Role;
   created_at: string;
   updated_at: string;
 }
@@ -114,12 +211,46 @@ export interface SpreadsheetListItem {
   title: string;
   owner_id: number;
   owner_name: string;
+  role: Role;
   created_at: string;
   updated_at: string;
+  deleted_at?: string;
+}
+
+export interface SpreadsheetPage {
+  items: SpreadsheetListItem[];
+  total: number;
+  next_cursor?: string;
+}
+
+export interface ListSpreadsheetsParams {
+  view?: 'all' | 'owned' | 'shared' | 'team';
+  title?: string;
+  sort?: 'title' | 'created_at' | 'updated_at';
+  order?: 'asc' | 'desc';
+  limit?: number;
+  cursor?: string;
+}
+
+export async function listSpreadsheetPage(params: ListSpreadsheetsParams = {}): Promise<SpreadsheetPage> {
+  const query = new URLSearchParams();
+  for (const [key, value] of Object.entries(params)) {
+    if (value !== undefined && value !== '') query.set(key, String(value));
+  }
+  const qs = query.toString();
+  return request<SpreadsheetPage>(`/spreadsheets${qs ? `?${qs}` : ''}`);
 }
 
+// listSpreadsheets fetches every page.
 export async function listSpreadsheets(): Promise<SpreadsheetListItem[]> {
-  return request<SpreadsheetListItem[]>('/spreadsheets');
+  const items: SpreadsheetListItem[] = [];
+  let cursor: string | undefined;
+  do {
+    const page = await listSpreadsheetPage({ limit: 200, cursor });
+    items.push(...page.items);
+    cursor = page.next_cursor;
+  } while (cursor);
+  return items;
 }
 
 export async function createSpreadsheet(title: string): Promise<Spreadsheet> {
@@ -129,13 +260,55 @@ export async function createSpreadsheet(title: string): Promise<Spreadsheet> {
   });
 }
 
+export interface ImportWarning {
+  code: string;
+  sheet?: string;
+  ref?: string;
+  message: string;
+}
+
+export interface ImportResult {
+  spreadsheet: Spreadsheet;
+  warnings: ImportWarning[];
+}
+
+/** Create a spreadsheet from an .xlsx, .ods or .csv file. */
+export async function importSpreadsheet(file: File, title?: string): Promise<ImportResult> {
+  const form = new FormData();
+  form.append('file', file);
+  if (title) form.append('title', title);
+  return request<ImportResult>('/spreadsheets/import', {
+    method: 'POST',
+    body: form,
+  });
+}
+
 export async function getSpreadsheet(id: number): Promise<Spreadsheet> {
   return request<Spreadsheet>(`/spreadsheets/${id}`);
 }
 
+/**
+ * Rows or columns inserted or deleted in a sheet (`index` is 1-based), or
+ * the sheet renamed to `name`.
+ */
+export type StructureChange =
+  | {
+      sheet: string;
+      op: 'insert_rows' | 'delete_rows' | 'insert_columns' | 'delete_columns';
+      index: number;
+      count?: number;
+    }
+  | { sheet: string; op: 'rename_sheet'; name: string };
+
+/**
+ * Pass `version` to make the write conditional: the server answers 409
+ * (ApiError with `body.version`) if someone else saved in the meantime.
+ * `structure` lists the rows and columns inserted or deleted and the
+ * sheets renamed since the last save, so comments stay on their cells.
+ */
 export async function updateSpreadsheet(
   id: number,
-  data: { title?: string; data?: string }
+  data: { title?: string; data?: string; version?: number; structure?: StructureChange[] }
 ): Promise<Spreadsheet> {
   return request<Spreadsheet>(`/spreadsheets/${id}`, {
     method: 'PATCH',
@@ -143,6 +316,315 @@ export async function updateSpreadsheet(
   });
 }
 
+/** Moves the spreadsheet to the trash; see restoreSpreadsheet. */
 export async function deleteSpreadsheet(id: number): Promise<void> {
   await request(`/spreadsheets/${id}`, { method: 'DELETE' });
 }
+
+// Trash API
+
+export async function listTrash(): Promise<SpreadsheetListItem[]> {
+  return request<SpreadsheetListItem[]>('/trash');
+}
+
+export async function restoreSpreadsheet(id: number): Promise<Spreadsheet> {
+  return request<Spreadsheet>(`/spreadsheets/${id}/restore`, { method: 'POST' });
+}
+
+export async function deleteSpreadsheetForever(id: number): Promise<void> {
+  await request(`/trash/${id}`, { method: 'DELETE' });
+}
+
+export async function emptyTrash(): Promise<void> {
+  await request('/trash', { method: 'DELETE' });
+}
+
+// Share links API
+
+export interface ShareLink {
+  id: number;
+  spreadsheet_id: number;
+  created_by_id: number;
+  prefix: string;
+  mode: 'viewer' | 'commenter';
+  has_password: boolean;
+  expires_at?: string;
+  last_used_at?: string;
+  created_at: string;
+}
+
+export interface CreateShareLinkResponse {
+  token: string;
+  share_link: ShareLink;
+}
+
+export interface SharedSpreadsheet {
+  title: string;
+  data: string;
+  version: number;
+  mode: 'viewer' | 'commenter';
+  updated_at: string;
+}
+
+export async function listShareLinks(id: number): Promise<ShareLink[]> {
+  return request<ShareLink[]>(`/spreadsheets/${id}/links`);
+}
+
+/** The token is only returned here; the link opens at /s/{token}. */
+export async function createShareLink(
+  id: number,
+  options: { mode?: 'viewer' | 'commenter'; password?: string; expires_at?: string } = {}
+): Promise<CreateShareLinkResponse> {
+  return request<CreateShareLinkResponse>(`/spreadsheets/${id}/links`, {
+    method: 'POST',
+    body: JSON.stringify(options),
+  });
+}
+
+export async function revokeShareLink(id: number, linkId: number): Promise<void> {
+  await request(`/spreadsheets/${id}/links/${linkId}`, { method: 'DELETE' });
+}
+
+/**
+ * Opens a share link without the session token, so a wrong password
+ * doesn't sign the user out. A protected link answers 401 with
+ * `body.password_required`.
+ */
+export async function openShareLink(token: string, password?: string): Promise<SharedSpreadsheet> {
+  const headers: Record<string, string> = {};
+  if (password) headers['X-Link-Password'] = password;
+  const response = await fetch(`${API_BASE}/public/links/${encodeURIComponent(token)}`, { headers });
+  if (!response.ok) {
+    const error = await response.json().catch(() => ({ error: 'Request failed' }));
+    throw new ApiError(response.status, error.error || 'Request failed', error);
+  }
+  return response.json();
+}
+
+/** Asks the owner for access; answers the same whether or not the spreadsheet exists. */
+export async function requestAccess(
+  id: number,
+  options: { role?: 'viewer' | 'commenter' | 'editor'; message?: string } = {}
+): Promise<void> {
+  await request(`/spreadsheets/${id}/access-requests`, {
+    method: 'POST',
+    body: JSON.stringify(options),
+  });
+}
+
+// Comments API
+
+export interface Comment {
+  id: number;
+  spreadsheet_id: number;
+  thread_id?: number;
+  sheet?: string;
+  anchor?: string;
+  author_id: number;
+  author?: User;
+  body: string;
+  mentions: User[];
+  resolved_at?: string;
+  resolved_by_id?: number;
+  edited_at?: string;
+  created_at: string;
+}
+
+export interface CommentThread extends Comment {
+  replies: Comment[];
+}
+
+export async function listComments(
+  id: number,
+  params: { sheet?: string; resolved?: boolean } = {}
+): Promise<CommentThread[]> {
+  const query = new URLSearchParams();
+  if (params.sheet) query.set('sheet', params.sheet);
+  if (params.resolved !== undefined) query.set('resolved', String(params.resolved));
+  const qs = query.toString();
+  return request<CommentThread[]>(`/spreadsheets/${id}/comments${qs ? `?${qs}` : ''}`);
+}
+
+/** Mention people by writing @ and their email in the body. */
+export async function createComment(
+  id: number,
+  sheet: string,
+  anchor: string,
+  body: string
+): Promise<CommentThread> {
+  return request<CommentThread>(`/spreadsheets/${id}/comments`, {
+    method: 'POST',
+    body: JSON.stringify({ sheet, anchor, body }),
+  });
+}
+
+export async function replyComment(id: number, commentId: number, body: string): Promise<Comment> {
+  return request<Comment>(`/spreadsheets/${id}/comments/${commentId}/replies`, {
+    method: 'POST',
+    body: JSON.stringify({ body }),
+  });
+}
+
+export async function editComment(id: number, commentId: number, body: string): Promise<Comment> {
+  return request<Comment>(`/spreadsheets/${id}/comments/${commentId}`, {
+    method: 'PATCH',
+    body: JSON.stringify({ body }),
+  });
+}
+
+export async function deleteComment(id: number, commentId: number): Promise<void> {
+  await request(`/spreadsheets/${id}/comments/${commentId}`, { method: 'DELETE' });
+}
+
+export async function resolveComment(id: number, commentId: number, resolved: boolean): Promise<CommentThread> {
+  return request<CommentThread>(
+    `/spreadsheets/${id}/comments/${commentId}/${resolved ? 'resolve' : 'reopen'}`,
+    { method: 'POST' }
+  );
+}
+
+/**
+ * Comments through a commenter link, for signed-in users the spreadsheet
+ * isn't shared with. A wrong or missing link password answers 403 with
+ * `body.password_required`.
+ */
+function linkCommentRequest<T>(token: string, path: string, password?: string, options: RequestInit = {}): Promise<T> {
+  const headers: Record<string, string> = {};
+  if (password) headers['X-Link-Password'] = password;
+  return request<T>(`/links/${encodeURIComponent(token)}/comments${path}`, { ...options, headers });
+}
+
+export async function listLinkComments(token: string, password?: string): Promise<CommentThread[]> {
+  return linkCommentRequest<CommentThread[]>(token, '', password);
+}
+
+export async function createLinkComment(
+  token: string,
+  sheet: string,
+  anchor: string,
+  body: string,
+  password?: string
+): Promise<CommentThread> {
+  return linkCommentRequest<CommentThread>(token, '', password, {
+    method: 'POST',
+    body: JSON.stringify({ sheet, anchor, body }),
+  });
+}
+
+export async function replyLinkComment(
+  token: string,
+  commentId: number,
+  body: string,
+  password?: string
+): Promise<Comment> {
+  return linkCommentRequest<Comment>(token, `/${commentId}/replies`, password, {
+    method: 'POST',
+    body: JSON.stringify({ body }),
+  });
+}
+
+// Notifications API
+
+export type NotificationType = 'share' | 'mention' | 'access_request';
+
+export interface Notification {
+  id: number;
+  user_id: number;
+  type: NotificationType;
+  actor_id?: number;
+  actor?: User;
+  spreadsheet_id?: number;
+  comment_id?: number;
+  message: string;
+  read_at?: string;
+  created_at: string;
+}
+
+export interface NotificationPage {
+  items: Notification[];
+  unread_count: number;
+}
+
+export type NotificationPreferences = Record<NotificationType, boolean>;
+
+/** Pass the ID of the last item as `before` to get the next page. */
+export async function listNotifications(
+  params: { unread?: boolean; before?: number; limit?: number } = {}
+): Promise<NotificationPage> {
+  const query = new URLSearchParams();
+  if (params.unread) query.set('unread', 'true');
+  if (params.before) query.set('before', String(params.before));
+  if (params.limit) query.set('limit', String(params.limit));
+  const qs = query.toString();
+  return request<NotificationPage>(`/notifications${qs ? `?${qs}` : ''}`);
+}
+
+export async function markNotificationRead(id: number): Promise<void> {
+  await request(`/notifications/${id}/read`, { method: 'POST' });
+}
+
+export async function markAllNotificationsRead(): Promise<void> {
+  await request('/notifications/read-all', { method: 'POST' });
+}
+
+export async function getNotificationPreferences(): Promise<NotificationPreferences> {
+  return request<NotificationPreferences>('/notifications/preferences');
+}
+
+export async function updateNotificationPreferences(
+  prefs: Partial<NotificationPreferences>
+): Promise<NotificationPreferences> {
+  return request<NotificationPreferences>('/notifications/preferences', {
+    method: 'PUT',
+    body: JSON.stringify(prefs),
+  });
+}
+
+export type NotificationStreamEvent =
+  | { type: 'notification'; notification: Notification }
+  | { type: 'unread'; unread_count: number };
+
+/**
+ * Follows the notification stream until the signal aborts. EventSource
+ * can't send the Authorization header, so this reads the server-sent
+ * events from fetch. The promise settles when the stream ends; callers
+ * reconnect as they see fit.
+ */
+export async function streamNotifications(
+  onEvent: (event: NotificationStreamEvent) => void,
+  signal: AbortSignal
+): Promise<void> {
+  const token = getToken();
+  const response = await fetch(`${API_BASE}/notifications/stream`, {
+    headers: token ? { Authorization: `Bearer ${token}` } : {},
+    signal,
+  });
+  if (!response.ok || !response.body) {
+    throw new ApiError(response.status, 'Failed to open notification stream', {});
+  }
+
+  const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+  let buffer = '';
+  for (;;) {
+    const { value, done } = await reader.read();
+    if (done) return;
+    buffer += value;
+
+    let end: number;
+    while ((end = buffer.indexOf('\n\n')) >= 0) {
+      const frame = buffer.slice(0, end);
+      buffer = buffer.slice(end + 2);
+
+      let name = '';
+      let data = '';
+      for (const line of frame.split('\n')) {
+        if (line.startsWith('event:')) name = line.slice(6).trim();
+        else if (line.startsWith('data:')) data += line.slice(5);
+      }
+      if (!data) continue; // heartbeat
+      const payload = JSON.parse(data);
+      if (name === 'notification') onEvent({ type: 'notification', notification: payload });
+      else if (name === 'unread') onEvent({ type: 'unread', unread_count: payload.unread_count });
+    }
+  }
+}
@@ -1,9 +1,10 @@
 import { useCallback, useEffect, useRef, useState } from 'react'
-import { updateSpreadsheet } from './api'
+import { ApiError, updateSpreadsheet, type StructureChange } from './api'
 
 /** Minimal interface for the IronCalc Model – avoids coupling to a specific @ironcalc/wasm version */
 interface SerialisableModel {
   toBytes(): Uint8Array
+  getWorksheetsProperties(): { name: string; sheet_id: number }[]
 }
 
 // ──────────────────────────────────────────────
@@ -25,8 +26,10 @@ export interface SaveManagerState {
   saveNow: () => Promise<void>
   /** Call this whenever the model may have been mutated */
   markDirty: () => void
-  /** Seed the last-saved snapshot so the first render doesn't trigger a save */
-  setInitialSnapshot: () => void
+  /** Record rows or columns inserted or deleted; sent with the next save */
+  recordStructure: (change: StructureChange) => void
+  /** Seed the last-saved snapshot (and server version) so the first render doesn't trigger a save */
+  setInitialSnapshot: (version?: number) => void
   /** Number of consecutive failures (for UI hints) */
   failureCount: number
 }
@@ -70,6 +73,12 @@ export function useSaveManager(
   const pendingWhileSaving = useRef(false)
   const retryCount = useRef(0)
   const lastSavedPayload = useRef<string | null>(null)
+  /** Server version the local model is based on; sent with every save */
+  const serverVersion = useRef<number | undefined>(undefined)
+  /** Structure changes not yet sent to the server, oldest first */
+  const pendingStructure = useRef<StructureChange[]>([])
+  /** Sheet names by sheet_id when last checked, to notice renames */
+  const sheetNames = useRef<Map<number, string>>(new Map())
   const dirtyCheckThrottled = useRef(false)
   const statusRef = useRef(status)
   statusRef.current = status
@@ -93,6 +102,24 @@ export function useSaveManager(
     }
   }, [modelRef])
 
+  // ── Notice renamed sheets ──────────────────
+  //
+  // Sheets are renamed from IronCalc's own tab bar, so renames are found
+  // by comparing names per sheet_id and queued like any structure change.
+  const recordRenames = useCallback(() => {
+    const model = modelRef.current
+    if (!model) return
+    const names = new Map<number, string>()
+    for (const sheet of model.getWorksheetsProperties()) {
+      names.set(sheet.sheet_id, sheet.name)
+      const previous = sheetNames.current.get(sheet.sheet_id)
+      if (previous !== undefined && previous !== sheet.name) {
+        pendingStructure.current.push({ sheet: previous, op: 'rename_sheet', name: sheet.name })
+      }
+    }
+    sheetNames.current = names
+  }, [modelRef])
+
   // ── Core persist function ──────────────────
   const persist = useCallback(async (): Promise<boolean> => {
     if (!spreadsheetId || isSaving.current) {
@@ -114,8 +141,16 @@ export function useSaveManager(
     setStatus('saving')
     setErrorMessage(null)
 
+    recordRenames()
+    const structure = pendingStructure.current.slice()
     try {
-      await updateSpreadsheet(spreadsheetId, { data: payload })
+      const updated = await updateSpreadsheet(spreadsheetId, {
+        data: payload,
+        version: serverVersion.current,
+        structure: structure.length > 0 ? structure : undefined,
+      })
+      serverVersion.current = updated.version
+      pendingStructure.current.splice(0, structure.length)
 
       // Success
       isSaving.current = false
@@ -140,6 +175,17 @@ export function useSaveManager(
       return true
     } catch (err) {
       isSaving.current = false
+
+      // Someone else saved a newer version: retrying would fail the same
+      // way, and overwriting would lose their changes.
+      if (err instanceof ApiError && err.status === 409) {
+        setStatus('error')
+        setErrorMessage(
+          'This spreadsheet was changed in another tab or by another user. Reload to get the latest version.',
+        )
+        return false
+      }
+
       retryCount.current += 1
       const count = retryCount.current
       setFailureCount(count)
@@ -162,7 +208,7 @@ export function useSaveManager(
       return false
     }
   // eslint-disable-next-line react-hooks/exhaustive-deps
-  }, [spreadsheetId, serialiseModel])
+  }, [spreadsheetId, serialiseModel, recordRenames])
 
   // ── Schedule a debounced save ──────────────
   const scheduleSave = useCallback(() => {
@@ -174,6 +220,9 @@ export function useSaveManager(
 
   // ── Public: mark the model as dirty ────────
   const markDirty = useCallback(() => {
+    // Cheap enough to run on every event, so each rename is seen in order
+    recordRenames()
+
     // Throttle serialisation checks to avoid expensive toBytes() on every DOM event
     if (dirtyCheckThrottled.current) return
     dirtyCheckThrottled.current = true
@@ -191,7 +240,13 @@ export function useSaveManager(
     setStatus('unsaved')
     setErrorMessage(null)
     scheduleSave()
-  }, [scheduleSave, serialiseModel])
+  }, [scheduleSave, serialiseModel, recordRenames])
+
+  // ── Public: record a structure change ──────
+  const recordStructure = useCallback((change: StructureChange) => {
+    recordRenames()
+    pendingStructure.current.push(change)
+  }, [recordRenames])
 
   // ── Public: immediate save (button / Ctrl+S)
   const saveNow = useCallback(async () => {
@@ -200,9 +255,12 @@ export function useSaveManager(
   }, [persist])
 
   // ── Public: seed snapshot after initial load ─
-  const setInitialSnapshot = useCallback(() => {
+  const setInitialSnapshot = useCallback((version?: number) => {
     lastSavedPayload.current = serialiseModel()
-  }, [serialiseModel])
+    serverVersion.current = version
+    sheetNames.current = new Map()
+    recordRenames()
+  }, [serialiseModel, recordRenames])
 
   // ── beforeunload: warn the user if there are unsaved changes
   //
@@ -245,5 +303,5 @@ export function useSaveManager(
     }
   }, [])
 
-  return { status, errorMessage, saveNow, markDirty, setInitialSnapshot, failureCount }
+  return { status, errorMessage, saveNow, markDirty, recordStructure, setInitialSnapshot, failureCount }
 }
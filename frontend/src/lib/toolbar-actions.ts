@@ -1,5 +1,6 @@
 import { Model } from "@ironcalc/workbook";
 import * as XLSX from "xlsx";
+import type { StructureChange } from "./api";
 
 /** Get the selected area as an Area object for updateRangeStyle */
 function getSelectedArea(model: Model) {
@@ -132,35 +133,53 @@ export function clearBorders(model: Model) {
 }
 
 // ── Rows & columns ───────────────────────────
+//
+// Each action returns what it changed, so the save can tell the server
+// (comments follow their cells).
 
-export function insertRowAbove(model: Model) {
+function structureChange(
+  model: Model,
+  sheet: number,
+  op: Exclude<StructureChange["op"], "rename_sheet">,
+  index: number,
+): StructureChange {
+  return { sheet: model.getWorksheetsProperties()[sheet].name, op, index };
+}
+
+export function insertRowAbove(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.insertRow(view.sheet, view.row);
+  return structureChange(model, view.sheet, "insert_rows", view.row);
 }
 
-export function insertRowBelow(model: Model) {
+export function insertRowBelow(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.insertRow(view.sheet, view.row + 1);
+  return structureChange(model, view.sheet, "insert_rows", view.row + 1);
 }
 
-export function insertColumnLeft(model: Model) {
+export function insertColumnLeft(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.insertColumn(view.sheet, view.column);
+  return structureChange(model, view.sheet, "insert_columns", view.column);
 }
 
-export function insertColumnRight(model: Model) {
+export function insertColumnRight(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.insertColumn(view.sheet, view.column + 1);
+  return structureChange(model, view.sheet, "insert_columns", view.column + 1);
 }
 
-export function deleteRow(model: Model) {
+export function deleteRow(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.deleteRow(view.sheet, view.row);
+  return structureChange(model, view.sheet, "delete_rows", view.row);
 }
 
-export function deleteColumn(model: Model) {
+export function deleteColumn(model: Model): StructureChange {
   const view = model.getSelectedView();
   model.deleteColumn(view.sheet, view.column);
+  return structureChange(model, view.sheet, "delete_columns", view.column);
 }
 
 // ── Freeze panes ─────────────────────────────
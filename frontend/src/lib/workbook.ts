@@ -0,0 +1,190 @@
+import { Model } from "@ironcalc/workbook";
+
+// The server stores spreadsheets it creates itself (imports, API edits) as
+// "grids/workbook" JSON instead of the editor's binary snapshot. These
+// helpers turn that JSON into an IronCalc model.
+
+export const WORKBOOK_FORMAT = "grids/workbook";
+
+type CellValue = number | string | boolean;
+
+interface WorkbookCell {
+  v?: CellValue;
+  e?: string;
+  f?: string;
+  s?: number;
+}
+
+interface BorderEdge {
+  style: string;
+  color?: string;
+}
+
+interface WorkbookStyle {
+  bold?: boolean;
+  italic?: boolean;
+  underline?: boolean;
+  strike?: boolean;
+  font_name?: string;
+  font_size?: number;
+  font_color?: string;
+  fill?: string;
+  h_align?: string;
+  v_align?: string;
+  wrap?: boolean;
+  num_fmt?: string;
+  border?: {
+    top?: BorderEdge;
+    right?: BorderEdge;
+    bottom?: BorderEdge;
+    left?: BorderEdge;
+  };
+}
+
+interface WorkbookSheet {
+  name: string;
+  cells: Record<string, WorkbookCell>;
+  merges?: string[];
+  col_widths?: Record<string, number>;
+  row_heights?: Record<string, number>;
+  frozen_rows?: number;
+  frozen_cols?: number;
+  hidden?: boolean;
+}
+
+export interface WorkbookDocument {
+  format: string;
+  version: number;
+  sheets: WorkbookSheet[];
+  styles: WorkbookStyle[];
+  names?: { name: string; ref: string; sheet?: string }[];
+}
+
+/** Whether stored spreadsheet data is workbook JSON rather than an editor snapshot. */
+export function isWorkbookJSON(data: string): boolean {
+  return data.trimStart().startsWith("{");
+}
+
+/** Parse "B12" into 1-based [row, column]. */
+function parseRef(ref: string): [number, number] {
+  const match = /^([A-Z]+)(\d+)$/.exec(ref.replace(/\$/g, "").toUpperCase());
+  if (!match) throw new Error(`Invalid cell reference ${ref}`);
+  let col = 0;
+  for (const ch of match[1]) col = col * 26 + (ch.charCodeAt(0) - 64);
+  return [parseInt(match[2]), col];
+}
+
+/** The text to type into a cell so IronCalc stores exactly this value. */
+function cellInput(cell: WorkbookCell): string {
+  if (cell.f) return cell.f;
+  if (cell.e) return cell.e;
+  if (cell.v === undefined) return "";
+  if (typeof cell.v === "boolean") return cell.v ? "TRUE" : "FALSE";
+  if (typeof cell.v === "number") return String(cell.v);
+  // Keep strings that would otherwise parse as numbers, booleans or
+  // formulas as text.
+  const text = cell.v;
+  if (
+    text.startsWith("=") ||
+    /^(true|false)$/i.test(text) ||
+    (text.trim() !== "" && !isNaN(Number(text)))
+  ) {
+    return "'" + text;
+  }
+  return text;
+}
+
+function applyStyle(
+  model: Model,
+  sheet: number,
+  row: number,
+  column: number,
+  style: WorkbookStyle,
+) {
+  const area = { sheet, row, column, width: 1, height: 1 };
+  const set = (path: string, value: string) =>
+    model.updateRangeStyle(area, path, value);
+
+  if (style.bold) set("font.b", "true");
+  if (style.italic) set("font.i", "true");
+  if (style.underline) set("font.u", "true");
+  if (style.strike) set("font.strike", "true");
+  if (style.font_name) set("font.name", style.font_name);
+  if (style.font_size) set("font.sz", String(style.font_size));
+  if (style.font_color) set("font.color", style.font_color);
+  if (style.fill) {
+    set("fill.fg_color", style.fill);
+    set("fill.pattern_type", "solid");
+  }
+  if (style.h_align) set("alignment.horizontal", style.h_align);
+  if (style.v_align) set("alignment.vertical", style.v_align);
+  if (style.wrap) set("alignment.wrap_text", "true");
+  if (style.num_fmt) set("num_fmt", style.num_fmt);
+
+  const edges = [
+    ["Top", style.border?.top],
+    ["Right", style.border?.right],
+    ["Bottom", style.border?.bottom],
+    ["Left", style.border?.left],
+  ] as const;
+  for (const [type, edge] of edges) {
+    if (!edge) continue;
+    model.setAreaWithBorder(area, {
+      item: { style: edge.style, color: edge.color || "#000000" },
+      type: type as never,
+    });
+  }
+}
+
+/** Build an editor model from workbook JSON. */
+export function modelFromWorkbook(data: string, title: string): Model {
+  const doc = JSON.parse(data) as WorkbookDocument;
+  if (doc.format !== WORKBOOK_FORMAT) {
+    throw new Error(`Unknown workbook format ${doc.format}`);
+  }
+
+  const model = new Model(title, "en", "UTC");
+  model.pauseEvaluation();
+
+  doc.sheets.forEach((sh, index) => {
+    if (index > 0) model.newSheet();
+    model.renameSheet(index, sh.name);
+
+    for (const [ref, cell] of Object.entries(sh.cells)) {
+      const [row, column] = parseRef(ref);
+      const input = cellInput(cell);
+      if (input !== "") model.setUserInput(index, row, column, input);
+      const style = cell.s ? doc.styles[cell.s] : undefined;
+      if (style) applyStyle(model, index, row, column, style);
+    }
+
+    for (const [col, width] of Object.entries(sh.col_widths ?? {})) {
+      model.setColumnsWidth(index, Number(col), Number(col), width);
+    }
+    for (const [row, height] of Object.entries(sh.row_heights ?? {})) {
+      model.setRowsHeight(index, Number(row), Number(row), height);
+    }
+    if (sh.frozen_rows) model.setFrozenRowsCount(index, sh.frozen_rows);
+    if (sh.frozen_cols) model.setFrozenColumnsCount(index, sh.frozen_cols);
+  });
+
+  for (const dn of doc.names ?? []) {
+    const scope = dn.sheet
+      ? doc.sheets.findIndex((sh) => sh.name === dn.sheet)
+      : undefined;
+    try {
+      model.newDefinedName(dn.name, scope, dn.ref);
+    } catch {
+      // Names IronCalc can't parse are dropped rather than failing the load.
+    }
+  }
+
+  // Hide sheets last: IronCalc won't hide the only visible sheet.
+  doc.sheets.forEach((sh, index) => {
+    if (sh.hidden) model.hideSheet(index);
+  });
+
+  model.resumeEvaluation();
+  model.evaluate();
+  return model;
+}
@@ -1,9 +1,14 @@
-import { useState, useEffect, useCallback } from 'react'
+import { useState, useEffect, useCallback, useRef } from 'react'
 import { useNavigate } from 'react-router-dom'
 import {
   listSpreadsheets,
   createSpreadsheet,
+  importSpreadsheet,
   deleteSpreadsheet,
+  listTrash,
+  restoreSpreadsheet,
+  deleteSpreadsheetForever,
+  emptyTrash,
   logout,
   getCachedUser,
   type SpreadsheetListItem,
@@ -18,7 +23,11 @@ import {
   MoreVertical,
   Clock,
   User,
+  RotateCcw,
+  ArrowLeft,
+  Upload,
 } from 'lucide-react'
+import NotificationBell from '../components/NotificationBell'
 import styles from './DashboardPage.module.css'
 
 function formatDate(dateString: string): string {
@@ -47,18 +56,22 @@ export default function DashboardPage() {
   const [search, setSearch] = useState('')
   const [creating, setCreating] = useState(false)
   const [menuOpen, setMenuOpen] = useState<number | null>(null)
+  const [showTrash, setShowTrash] = useState(false)
+  const [importing, setImporting] = useState(false)
+  const fileInputRef = useRef<HTMLInputElement>(null)
   const user = getCachedUser()
 
   const fetchSpreadsheets = useCallback(async () => {
+    setLoading(true)
     try {
-      const data = await listSpreadsheets()
+      const data = showTrash ? await listTrash() : await listSpreadsheets()
       setSpreadsheets(data)
     } catch {
       // If unauthorized, redirect to login
     } finally {
       setLoading(false)
     }
-  }, [])
+  }, [showTrash])
 
   useEffect(() => {
     fetchSpreadsheets()
@@ -85,10 +98,29 @@ export default function DashboardPage() {
     }
   }
 
+  async function handleImport(e: React.ChangeEvent<HTMLInputElement>) {
+    const file = e.target.files?.[0]
+    e.target.value = ''
+    if (!file) return
+    setImporting(true)
+    try {
+      const { spreadsheet, warnings } = await importSpreadsheet(file)
+      if (warnings.length > 0) {
+        const lines = warnings.map((w) =>
+          [w.sheet, w.ref].filter(Boolean).join(' ') + (w.sheet || w.ref ? ': ' : '') + w.message
+        )
+        alert(`Imported with some changes:\n\n${lines.join('\n')}`)
+      }
+      navigate(`/spreadsheet/${spreadsheet.id}`)
+    } catch (err) {
+      alert(err instanceof Error ? err.message : 'Import failed')
+      setImporting(false)
+    }
+  }
+
   async function handleDelete(id: number, e: React.MouseEvent) {
     e.stopPropagation()
     setMenuOpen(null)
-    if (!confirm('Delete this spreadsheet? This cannot be undone.')) return
     try {
       await deleteSpreadsheet(id)
       setSpreadsheets((prev) => prev.filter((s) => s.id !== id))
@@ -97,6 +129,43 @@ export default function DashboardPage() {
     }
   }
 
+  async function handleRestore(id: number, e: React.MouseEvent) {
+    e.stopPropagation()
+    setMenuOpen(null)
+    try {
+      await restoreSpreadsheet(id)
+      setSpreadsheets((prev) => prev.filter((s) => s.id !== id))
+    } catch {
+      // handle error
+    }
+  }
+
+  async function handleDeleteForever(id: number, e: React.MouseEvent) {
+    e.stopPropagation()
+    setMenuOpen(null)
+    if (!confirm('Delete this spreadsheet forever? This cannot be undone.')) return
+    try {
+      await deleteSpreadsheetForever(id)
+      setSpreadsheets((prev) => prev.filter((s) => s.id !== id))
+    } catch {
+      // handle error
+    }
+  }
+
+  async function handleEmptyTrash() {
+    if (!confirm('Delete everything in the trash forever? This cannot be undone.')) return
+    try {
+      await emptyTrash()
+      setSpreadsheets([])
+    } catch {
+      // handle error
+    }
+  }
+
+  function openSheet(id: number) {
+    if (!showTrash) navigate(`/spreadsheet/${id}`)
+  }
+
   async function handleLogout() {
     await logout()
     navigate('/login')
@@ -129,6 +198,7 @@ export default function DashboardPage() {
         </div>
 
         <div className={styles.headerRight}>
+          <NotificationBell />
           <button onClick={handleLogout} className={styles.userButton} title="Sign out">
             <div className={styles.avatar}>
               {user?.name?.[0]?.toUpperCase() || <User size={14} />}
@@ -155,13 +225,45 @@ export default function DashboardPage() {
               </div>
               <span className={styles.createCardLabel}>Blank spreadsheet</span>
             </button>
+            <button
+              onClick={() => fileInputRef.current?.click()}
+              disabled={importing}
+              className={styles.createCard}
+            >
+              <div className={styles.createCardIcon}>
+                <Upload size={32} strokeWidth={1.5} />
+              </div>
+              <span className={styles.createCardLabel}>
+                {importing ? 'Importing...' : 'Import file'}
+              </span>
+            </button>
+            <input
+              ref={fileInputRef}
+              type="file"
+              accept=".xlsx,.ods,.csv,application/vnd.openxmlformats-officedocument.spreadsheetml.sheet,application/vnd.oasis.opendocument.spreadsheet,text/csv"
+              onChange={handleImport}
+              hidden
+            />
           </div>
         </section>
 
         {/* Recent spreadsheets */}
         <section className={styles.recentSection}>
           <div className={styles.recentHeader}>
-            <h2 className={styles.sectionTitle}>Recent spreadsheets</h2>
+            <h2 className={styles.sectionTitle}>
+              {showTrash ? 'Trash' : 'Recent spreadsheets'}
+            </h2>
+            <div className={styles.recentActions}>
+              {showTrash && spreadsheets.length > 0 && (
+                <button onClick={handleEmptyTrash} className={styles.textButton}>
+                  Empty trash
+                </button>
+              )}
+              <button onClick={() => setShowTrash(!showTrash)} className={styles.textButton}>
+                {showTrash ? <ArrowLeft size={14} /> : <Trash2 size={14} />}
+                {showTrash ? 'Back to spreadsheets' : 'Trash'}
+              </button>
+            </div>
           </div>
 
           {loading ? (
@@ -173,12 +275,18 @@ export default function DashboardPage() {
             <div className={styles.emptyState}>
               <FileSpreadsheet size={48} strokeWidth={1} className={styles.emptyIcon} />
               <p className={styles.emptyTitle}>
-                {search ? 'No spreadsheets match your search' : 'No spreadsheets yet'}
+                {search
+                  ? 'No spreadsheets match your search'
+                  : showTrash
+                    ? 'Trash is empty'
+                    : 'No spreadsheets yet'}
               </p>
               <p className={styles.emptyDescription}>
                 {search
                   ? 'Try adjusting your search terms'
-                  : 'Create your first spreadsheet to get started'}
+                  : showTrash
+                    ? 'Deleted spreadsheets are kept here for a while before being removed'
+                    : 'Create your first spreadsheet to get started'}
               </p>
             </div>
           ) : (
@@ -187,11 +295,11 @@ export default function DashboardPage() {
                 <div
                   key={sheet.id}
                   className={styles.card}
-                  onClick={() => navigate(`/spreadsheet/${sheet.id}`)}
+                  onClick={() => openSheet(sheet.id)}
                   role="button"
                   tabIndex={0}
                   onKeyDown={(e) => {
-                    if (e.key === 'Enter') navigate(`/spreadsheet/${sheet.id}`)
+                    if (e.key === 'Enter') openSheet(sheet.id)
                   }}
                 >
                   <div className={styles.cardPreview}>
@@ -200,33 +308,58 @@ export default function DashboardPage() {
                   <div className={styles.cardInfo}>
                     <div className={styles.cardTitleRow}>
                       <h3 className={styles.cardTitle}>{sheet.title}</h3>
-                      <div className={styles.cardMenu}>
-                        <button
-                          className={styles.menuButton}
-                          onClick={(e) => {
-                            e.stopPropagation()
-                            setMenuOpen(menuOpen === sheet.id ? null : sheet.id)
-                          }}
-                          aria-label="More options"
-                        >
-                          <MoreVertical size={16} />
-                        </button>
-                        {menuOpen === sheet.id && (
-                          <div className={styles.dropdown}>
-                            <button
-                              className={styles.dropdownItemDanger}
-                              onClick={(e) => handleDelete(sheet.id, e)}
-                            >
-                              <Trash2 size={14} />
-                              Delete
-                            </button>
-                          </div>
-                        )}
-                      </div>
+                      {(showTrash || sheet.role === 'owner') && (
+                        <div className={styles.cardMenu}>
+                          <button
+                            className={styles.menuButton}
+                            onClick={(e) => {
+                              e.stopPropagation()
+                              setMenuOpen(menuOpen === sheet.id ? null : sheet.id)
+                            }}
+                            aria-label="More options"
+                          >
+                            <MoreVertical size={16} />
+                          </button>
+                          {menuOpen === sheet.id && (
+                            <div className={styles.dropdown}>
+                              {showTrash ? (
+                                <>
+                                  <button
+                                    className={styles.dropdownItem}
+                                    onClick={(e) => handleRestore(sheet.id, e)}
+                                  >
+                                    <RotateCcw size={14} />
+                                    Restore
+                                  </button>
+                                  <button
+                                    className={styles.dropdownItemDanger}
+                                    onClick={(e) => handleDeleteForever(sheet.id, e)}
+                                  >
+                                    <Trash2 size={14} />
+                                    Delete forever
+                                  </button>
+                                </>
+                              ) : (
+                                <button
+                                  className={styles.dropdownItemDanger}
+                                  onClick={(e) => handleDelete(sheet.id, e)}
+                                >
+                                  <Trash2 size={14} />
+                                  Move to trash
+                                </button>
+                              )}
+                            </div>
+                          )}
+                        </div>
+                      )}
                     </div>
                     <div className={styles.cardMeta}>
                       <Clock size={12} />
-                      <span>Opened {formatDate(sheet.updated_at)}</span>
+                      <span>
+                        {sheet.deleted_at
+                          ? `Deleted ${formatDate(sheet.deleted_at)}`
+                          : `Opened ${formatDate(sheet.updated_at)}`}
+                      </span>
                     </div>
                   </div>
                 </div>
@@ -1,6 +1,15 @@
-import { useState, type FormEvent } from 'react'
+import { useEffect, useState, type FormEvent } from 'react'
 import { useNavigate } from 'react-router-dom'
-import { login, isAuthenticated } from '../lib/api'
+import {
+  login,
+  register,
+  requestPasswordReset,
+  resetPassword,
+  isAuthenticated,
+  getAuthConfig,
+  startSsoLogin,
+  completeSsoLogin,
+} from '../lib/api'
 import { Grid3X3 } from 'lucide-react'
 import styles from './LoginPage.module.css'
 
@@ -8,10 +17,51 @@ export default function LoginPage() {
   const navigate = useNavigate()
   const [email, setEmail] = useState('')
   const [name, setName] = useState('')
+  const [password, setPassword] = useState('')
+  const [mode, setMode] = useState<'signin' | 'register' | 'forgot' | 'reset'>('signin')
   const [error, setError] = useState('')
+  const [notice, setNotice] = useState('')
   const [loading, setLoading] = useState(false)
+  const [ssoEnabled, setSsoEnabled] = useState(false)
+  const [linkToken, setLinkToken] = useState('')
+  const [resetToken, setResetToken] = useState('')
 
-  if (isAuthenticated()) {
+  useEffect(() => {
+    getAuthConfig()
+      .then((cfg) => setSsoEnabled(cfg.oidc))
+      .catch(() => setSsoEnabled(false))
+  }, [])
+
+  // The SSO callback redirects here with the session token (or an error)
+  // in the URL fragment, and password links carry their token there too.
+  useEffect(() => {
+    if (!window.location.hash) return
+    const params = new URLSearchParams(window.location.hash.slice(1))
+    window.history.replaceState(null, '', window.location.pathname)
+    const token = params.get('token')
+    const reset = params.get('reset')
+    if (reset) {
+      setResetToken(reset)
+      setMode('reset')
+    } else if (token) {
+      setLoading(true)
+      completeSsoLogin(token)
+        .then(() => navigate('/', { replace: true }))
+        .catch(() => setError('Single sign-on failed'))
+        .finally(() => setLoading(false))
+    } else if (params.get('error') === 'link_required') {
+      // The SSO account's email belongs to a password account, which has
+      // to be signed in to once before the two are linked.
+      setMode('signin')
+      setEmail(params.get('email') ?? '')
+      setLinkToken(params.get('link') ?? '')
+      setError('An account with this email already exists. Sign in with your password to link single sign-on.')
+    } else if (params.get('error')) {
+      setError('Single sign-on failed. Please try again.')
+    }
+  }, [navigate])
+
+  if (isAuthenticated() && mode !== 'reset') {
     navigate('/', { replace: true })
     return null
   }
@@ -19,10 +69,22 @@ export default function LoginPage() {
   async function handleSubmit(e: FormEvent) {
     e.preventDefault()
     setError('')
+    setNotice('')
     setLoading(true)
 
     try {
-      await login(email, name)
+      if (mode === 'forgot') {
+        await requestPasswordReset(email)
+        setNotice('If an account uses this email, a link to set its password is on its way.')
+        return
+      }
+      if (mode === 'register') {
+        await register(email, name, password)
+      } else if (mode === 'reset') {
+        await resetPassword(resetToken, password)
+      } else {
+        await login(email, password, linkToken || undefined)
+      }
       navigate('/')
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Login failed')
@@ -39,55 +101,129 @@ export default function LoginPage() {
             <Grid3X3 size={32} strokeWidth={2.5} />
           </div>
           <h1 className={styles.title}>Jaggle Grids</h1>
-          <p className={styles.subtitle}>Sign in to your workspace</p>
+          <p className={styles.subtitle}>
+            {mode === 'register'
+              ? 'Create your account'
+              : mode === 'forgot'
+                ? 'Get a link to set your password'
+                : mode === 'reset'
+                  ? 'Choose a new password'
+                  : 'Sign in to your workspace'}
+          </p>
         </div>
 
         <form onSubmit={handleSubmit} className={styles.form}>
           {error && <div className={styles.error}>{error}</div>}
+          {notice && <div className={styles.hint}>{notice}</div>}
 
-          <div className={styles.field}>
-            <label htmlFor="name" className={styles.label}>
-              Full name
-            </label>
-            <input
-              id="name"
-              type="text"
-              value={name}
-              onChange={(e) => setName(e.target.value)}
-              placeholder="Enter your name"
-              required
-              className={styles.input}
-              autoComplete="name"
-            />
-          </div>
+          {mode === 'register' && (
+            <div className={styles.field}>
+              <label htmlFor="name" className={styles.label}>
+                Full name
+              </label>
+              <input
+                id="name"
+                type="text"
+                value={name}
+                onChange={(e) => setName(e.target.value)}
+                placeholder="Enter your name"
+                required
+                className={styles.input}
+                autoComplete="name"
+              />
+            </div>
+          )}
 
-          <div className={styles.field}>
-            <label htmlFor="email" className={styles.label}>
-              Email address
-            </label>
-            <input
-              id="email"
-              type="email"
-              value={email}
-              onChange={(e) => setEmail(e.target.value)}
-              placeholder="you@example.com"
-              required
-              className={styles.input}
-              autoComplete="email"
-            />
-          </div>
+          {mode !== 'reset' && (
+            <div className={styles.field}>
+              <label htmlFor="email" className={styles.label}>
+                Email address
+              </label>
+              <input
+                id="email"
+                type="email"
+                value={email}
+                onChange={(e) => setEmail(e.target.value)}
+                placeholder="you@example.com"
+                required
+                className={styles.input}
+                autoComplete="email"
+              />
+            </div>
+          )}
+
+          {mode !== 'forgot' && (
+            <div className={styles.field}>
+              <label htmlFor="password" className={styles.label}>
+                Password
+              </label>
+              <input
+                id="password"
+                type="password"
+                value={password}
+                onChange={(e) => setPassword(e.target.value)}
+                placeholder={mode === 'signin' ? 'Your password' : 'At least 8 characters'}
+                required
+                minLength={mode === 'signin' ? undefined : 8}
+                className={styles.input}
+                autoComplete={mode === 'signin' ? 'current-password' : 'new-password'}
+              />
+            </div>
+          )}
 
           <button
             type="submit"
             disabled={loading}
             className={styles.button}
           >
-            {loading ? 'Signing in...' : 'Sign in'}
+            {mode === 'register'
+              ? loading ? 'Creating account...' : 'Create account'
+              : mode === 'forgot'
+                ? loading ? 'Sending...' : 'Email me a link'
+                : mode === 'reset'
+                  ? loading ? 'Saving...' : 'Set password'
+                  : loading ? 'Signing in...' : 'Sign in'}
           </button>
 
+          {mode === 'signin' && (
+            <p className={styles.hint}>
+              <button
+                type="button"
+                className={styles.link}
+                onClick={() => {
+                  setError('')
+                  setMode('forgot')
+                }}
+              >
+                Forgot or never set a password?
+              </button>
+            </p>
+          )}
+
+          {ssoEnabled && mode === 'signin' && (
+            <button
+              type="button"
+              disabled={loading}
+              className={styles.button}
+              onClick={startSsoLogin}
+            >
+              Sign in with SSO
+            </button>
+          )}
+
           <p className={styles.hint}>
-            This is a mocked authentication flow. Enter any name and email to continue.
-            Jaggle OAuth will be integrated at a later point.
+            {mode === 'signin' ? 'New to Jaggle Grids? ' : 'Already have an account? '}
+            <button
+              type="button"
+              className={styles.link}
+              onClick={() => {
+                setError('')
+                setNotice('')
+                setMode(mode === 'signin' ? 'register' : 'signin')
+              }}
+            >
+              {mode === 'signin' ? 'Create an account' : 'Sign in'}
+            </button>
           </p>
         </form>
       </div>
@@ -0,0 +1,154 @@
+import { useState, useEffect, memo } from "react";
+import { useParams } from "react-router-dom";
+import { ApiError, openShareLink, type SharedSpreadsheet } from "../lib/api";
+import { init, Model, IronCalc } from "@ironcalc/workbook";
+import "@ironcalc/workbook/dist/ironcalc.css";
+import { base64ToBytes, isWorkbookJSON, modelFromWorkbook } from "../lib/workbook";
+import { Eye, MessageSquare, Grid3X3, Loader2, Lock } from "lucide-react";
+import workbookStyles from "./WorkbookPage.module.css";
+import styles from "./SharedPage.module.css";
+
+const MemoizedIronCalc = memo(IronCalc);
+
+/** A spreadsheet opened from a public share link. Nothing on this page is
+ *  ever saved: the share link API has no write endpoints. */
+export default function SharedPage() {
+  const { token } = useParams<{ token: string }>();
+
+  const [shared, setShared] = useState<SharedSpreadsheet | null>(null);
+  const [model, setModel] = useState<Model | null>(null);
+  const [loading, setLoading] = useState(true);
+  const [error, setError] = useState("");
+  const [needsPassword, setNeedsPassword] = useState(false);
+  const [password, setPassword] = useState("");
+  const [submitted, setSubmitted] = useState<string | undefined>();
+
+  useEffect(() => {
+    let cancelled = false;
+
+    async function load() {
+      if (!token) return;
+      setLoading(true);
+      try {
+        await init();
+        const sheet = await openShareLink(token, submitted);
+        if (cancelled) return;
+
+        let m: Model;
+        if (isWorkbookJSON(sheet.data)) {
+          m = modelFromWorkbook(sheet.data, sheet.title);
+        } else {
+          try {
+            m = Model.from_bytes(base64ToBytes(sheet.data));
+          } catch {
+            m = new Model(sheet.title, "en", "UTC");
+          }
+        }
+        setShared(sheet);
+        setModel(m);
+        setNeedsPassword(false);
+        setError("");
+      } catch (err) {
+        if (cancelled) return;
+        if (err instanceof ApiError && err.body.password_required) {
+          setNeedsPassword(true);
+          setError(submitted ? err.message : "");
+        } else {
+          setError(
+            err instanceof ApiError && err.status === 404
+              ? "This link doesn't exist or has expired."
+              : err instanceof Error
+                ? err.message
+                : "Failed to open link",
+          );
+        }
+      } finally {
+        if (!cancelled) setLoading(false);
+      }
+    }
+
+    load();
+    return () => {
+      cancelled = true;
+    };
+  }, [token, submitted]);
+
+  if (loading) {
+    return (
+      <div className={workbookStyles.loadingContainer}>
+        <Loader2 size={32} className={workbookStyles.spinnerIcon} />
+        <p>Loading spreadsheet...</p>
+      </div>
+    );
+  }
+
+  if (needsPassword) {
+    return (
+      <div className={workbookStyles.errorContainer}>
+        <form
+          className={styles.passwordForm}
+          onSubmit={(e) => {
+            e.preventDefault();
+            if (password) setSubmitted(password);
+          }}
+        >
+          <Lock size={24} />
+          <p>This spreadsheet is protected by a password.</p>
+          <input
+            type="password"
+            title="Password"
+            placeholder="Password"
+            value={password}
+            onChange={(e) => setPassword(e.target.value)}
+            className={styles.passwordInput}
+            autoFocus
+          />
+          {error && <p className={workbookStyles.errorText}>{error}</p>}
+          <button type="submit" className={workbookStyles.saveButton}>
+            Open
+          </button>
+        </form>
+      </div>
+    );
+  }
+
+  if (error || !shared) {
+    return (
+      <div className={workbookStyles.errorContainer}>
+        <p className={workbookStyles.errorText}>{error}</p>
+      </div>
+    );
+  }
+
+  return (
+    <div className={workbookStyles.layout}>
+      <header className={workbookStyles.toolbar}>
+        <div className={workbookStyles.toolbarLeft}>
+          <div className={workbookStyles.logoMark}>
+            <Grid3X3 size={16} strokeWidth={2.5} />
+          </div>
+          <span className={styles.title}>{shared.title}</span>
+        </div>
+        <div className={workbookStyles.toolbarRight}>
+          <span className={styles.modeBadge}>
+            {shared.mode === "commenter" ? (
+              <>
+                <MessageSquare size={13} />
+                Can comment
+              </>
+            ) : (
+              <>
+                <Eye size={13} />
+                View only
+              </>
+            )}
+          </span>
+        </div>
+      </header>
+
+      <div className={workbookStyles.workbookContainer}>
+        {model && <MemoizedIronCalc model={model} refreshId={0} />}
+      </div>
+    </div>
+  );
+}
@@ -8,6 +8,7 @@ import {
 import { init, Model, IronCalc } from "@ironcalc/workbook";
 import "@ironcalc/workbook/dist/ironcalc.css";
 import { useSaveManager } from "../lib/save-manager";
+import { isWorkbookJSON, modelFromWorkbook } from "../lib/workbook";
 import {
   ArrowLeft,
   Save,
@@ -49,6 +50,7 @@ export default function WorkbookPage() {
     errorMessage: saveError,
     saveNow,
     markDirty,
+    recordStructure,
     setInitialSnapshot,
     failureCount,
   } = useSaveManager(spreadsheetId, modelRef);
@@ -91,7 +93,9 @@ export default function WorkbookPage() {
         setTitle(sheet.title);
 
         let m: Model;
-        if (sheet.data) {
+        if (sheet.data && isWorkbookJSON(sheet.data)) {
+          m = modelFromWorkbook(sheet.data, sheet.title);
+        } else if (sheet.data) {
           try {
             const bytes = Uint8Array.from(atob(sheet.data), (c) =>
               c.charCodeAt(0),
@@ -106,7 +110,7 @@ export default function WorkbookPage() {
 
         modelRef.current = m;
         setModel(m);
-        setInitialSnapshot();
+        setInitialSnapshot(sheet.version);
         setRefreshId((prev) => prev + 1);
       } catch (err) {
         if (!cancelled) {
@@ -377,6 +381,7 @@ export default function WorkbookPage() {
           title={title}
           onSave={saveNow}
           onRefresh={handleToolbarRefresh}
+          onStructureChange={recordStructure}
         />
       )}
 
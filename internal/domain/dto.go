@@ -5,17 +5,234 @@ import "time"
 // ── Requests ─────────────────────────────────
 
 type LoginRequest struct {
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password"`
+	Name      string `json:"name"`       // only used by mock login
+	LinkToken string `json:"link_token"` // from a single sign-on login that needs the password
+}
+
+type RegisterRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Name     string `json:"name" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+}
+
+type ForgotPasswordRequest struct {
 	Email string `json:"email" binding:"required,email"`
-	Name  string `json:"name" binding:"required"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
 }
 
 type CreateSpreadsheetRequest struct {
 	Title string `json:"title" binding:"required"`
+	// WorkspaceID defaults to the creator's personal workspace.
+	WorkspaceID *uint `json:"workspace_id,omitempty"`
+}
+
+type MoveSpreadsheetRequest struct {
+	WorkspaceID uint `json:"workspace_id" binding:"required"`
+}
+
+// FileSpreadsheetRequest puts a spreadsheet in a folder of its workspace;
+// a null folder_id moves it to the top level.
+type FileSpreadsheetRequest struct {
+	FolderID *uint `json:"folder_id"`
+}
+
+type CreateFolderRequest struct {
+	Name     string `json:"name" binding:"required,max=100"`
+	ParentID *uint  `json:"parent_id,omitempty"`
+}
+
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// MoveFolderRequest nests a folder in another; a null parent_id moves it
+// to the top level.
+type MoveFolderRequest struct {
+	ParentID *uint `json:"parent_id"`
+}
+
+type OrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type AddMemberRequest struct {
+	Email string  `json:"email" binding:"required,email"`
+	Role  OrgRole `json:"role" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role OrgRole `json:"role" binding:"required"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+	// MemberRole is what organization members get on the workspace's
+	// spreadsheets; editor by default.
+	MemberRole Role `json:"member_role,omitempty"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name       string `json:"name,omitempty" binding:"max=100"`
+	MemberRole Role   `json:"member_role,omitempty"`
+}
+
+// ListSpreadsheetsRequest holds the query parameters of the spreadsheet
+// list. Dates are RFC 3339 times or YYYY-MM-DD.
+type ListSpreadsheetsRequest struct {
+	View          string `form:"view"`
+	Title         string `form:"title"`
+	OwnerID       *uint  `form:"owner_id"`
+	WorkspaceID   *uint  `form:"workspace_id"`
+	FolderID      *uint  `form:"folder_id"`
+	CreatedAfter  string `form:"created_after"`
+	CreatedBefore string `form:"created_before"`
+	UpdatedAfter  string `form:"updated_after"`
+	UpdatedBefore string `form:"updated_before"`
+	Sort          string `form:"sort"`
+	Order         string `form:"order"`
+	Cursor        string `form:"cursor"`
+	Limit         int    `form:"limit"`
 }
 
 type UpdateSpreadsheetRequest struct {
 	Title string `json:"title,omitempty"`
 	Data  string `json:"data,omitempty"`
+	// Version, when set, must match the stored version (like If-Match).
+	Version *int `json:"version,omitempty"`
+	// Structure lists the rows and columns inserted or deleted and the
+	// sheets renamed since the last save, in order, so comments can follow
+	// their cells.
+	Structure []StructureChange `json:"structure,omitempty" binding:"omitempty,dive"`
+}
+
+// Kinds of StructureChange.
+const (
+	InsertRows    = "insert_rows"
+	DeleteRows    = "delete_rows"
+	InsertColumns = "insert_columns"
+	DeleteColumns = "delete_columns"
+	RenameSheet   = "rename_sheet"
+)
+
+// StructureChange is rows or columns inserted or deleted in one sheet,
+// or the sheet renamed. Index is the 1-based number of the first row or
+// column inserted or deleted; Count defaults to 1. Name is the new name
+// of a renamed sheet.
+type StructureChange struct {
+	Sheet string `json:"sheet" binding:"required"`
+	Op    string `json:"op" binding:"required,oneof=insert_rows delete_rows insert_columns delete_columns rename_sheet"`
+	Index int    `json:"index,omitempty" binding:"omitempty,min=1"`
+	Count int    `json:"count,omitempty" binding:"omitempty,min=1"`
+	Name  string `json:"name,omitempty"`
+}
+
+type CreateCommentRequest struct {
+	Sheet  string `json:"sheet" binding:"required"`
+	Anchor string `json:"anchor" binding:"required"`
+	Body   string `json:"body" binding:"required,max=10000"`
+}
+
+// CommentBodyRequest is the body of a reply or an edit.
+type CommentBodyRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+// ListCommentsRequest filters the threads of a spreadsheet.
+type ListCommentsRequest struct {
+	Sheet    string `form:"sheet"`
+	Resolved *bool  `form:"resolved"`
+}
+
+// CommentThread is the first comment of a thread with its replies,
+// oldest first.
+type CommentThread struct {
+	Comment
+	Replies []Comment `json:"replies"`
+}
+
+// AccessRequest asks a spreadsheet's owner for access.
+type AccessRequest struct {
+	// Role is viewer (the default), commenter or editor.
+	Role    Role   `json:"role,omitempty"`
+	Message string `json:"message,omitempty" binding:"max=500"`
+}
+
+type ListNotificationsRequest struct {
+	Unread bool `form:"unread"`
+	Before uint `form:"before"`
+	Limit  int  `form:"limit"`
+}
+
+// NotificationPage is a page of notifications. To get the next one, pass
+// the ID of the last item as before.
+type NotificationPage struct {
+	Items       []Notification `json:"items"`
+	UnreadCount int64          `json:"unread_count"`
+}
+
+// NotificationEvent is pushed to a user's notification stream: a new
+// notification, or just a changed unread count after marking read.
+type NotificationEvent struct {
+	Notification *Notification `json:"notification,omitempty"`
+	UnreadCount  int64         `json:"unread_count"`
+}
+
+// ValuesRequest writes rows of cell values; see workbook.ParseInput for
+// how each value is interpreted.
+type ValuesRequest struct {
+	Values [][]any `json:"values" binding:"required"`
+	// Version, when set, must match the stored version (like If-Match).
+	Version *int `json:"version,omitempty"`
+}
+
+type CreateAPITokenRequest struct {
+	Name  string     `json:"name" binding:"required,max=100"`
+	Scope TokenScope `json:"scope" binding:"required"`
+	// SpreadsheetID, when set, restricts the token to that spreadsheet.
+	SpreadsheetID *uint      `json:"spreadsheet_id,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+}
+
+type CreateShareLinkRequest struct {
+	// Mode is viewer (the default) or commenter.
+	Mode      Role       `json:"mode,omitempty"`
+	Password  string     `json:"password,omitempty" binding:"omitempty,min=4,max=72"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL    string         `json:"url" binding:"required,max=2048"`
+	Events []WebhookEvent `json:"events" binding:"required,min=1"`
+	// Secret signs deliveries; one is generated when it is left out.
+	Secret string `json:"secret,omitempty" binding:"omitempty,min=16,max=256"`
+}
+
+// UpdateWebhookRequest changes the fields that are set.
+type UpdateWebhookRequest struct {
+	URL    *string        `json:"url,omitempty" binding:"omitempty,max=2048"`
+	Events []WebhookEvent `json:"events,omitempty" binding:"omitempty,min=1"`
+	Active *bool          `json:"active,omitempty"`
+}
+
+type ListDeliveriesRequest struct {
+	Before uint `form:"before"`
+	Limit  int  `form:"limit"`
+}
+
+type SharePermissionRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  Role   `json:"role" binding:"required"`
 }
 
 // ── Responses ────────────────────────────────
@@ -25,11 +242,91 @@ type AuthResponse struct {
 	User  User   `json:"user"`
 }
 
-type SpreadsheetListItem struct {
-	ID        uint      `json:"id"`
+// CreateAPITokenResponse carries the secret token, which is not shown again.
+type CreateAPITokenResponse struct {
+	Token    string   `json:"token"`
+	APIToken APIToken `json:"api_token"`
+}
+
+// CreateShareLinkResponse carries the link's token, which is not shown
+// again. The link opens at /s/{token}.
+type CreateShareLinkResponse struct {
+	Token     string    `json:"token"`
+	ShareLink ShareLink `json:"share_link"`
+}
+
+// CreateWebhookResponse carries the signing secret, which is only shown
+// here.
+type CreateWebhookResponse struct {
+	Secret  string  `json:"secret"`
+	Webhook Webhook `json:"webhook"`
+}
+
+// SharedSpreadsheet is what a share link serves: the workbook and the
+// mode of the link, without anything about the owner or other users.
+type SharedSpreadsheet struct {
 	Title     string    `json:"title"`
-	OwnerID   uint      `json:"owner_id"`
-	OwnerName string    `json:"owner_name"`
-	CreatedAt time.Time `json:"created_at"`
+	Data      string    `json:"data"`
+	Version   int       `json:"version"`
+	Mode      Role      `json:"mode"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// ValueRange is a block of cell values, row by row.
+type ValueRange struct {
+	Range  string  `json:"range"`
+	Values [][]any `json:"values"`
+}
+
+type UpdateValuesResponse struct {
+	Range        string `json:"range"`
+	UpdatedCells int    `json:"updated_cells"`
+	Version      int    `json:"version"`
+}
+
+// SpreadsheetPage is one page of a spreadsheet listing. NextCursor is
+// empty on the last page.
+type SpreadsheetPage struct {
+	Items      []SpreadsheetListItem `json:"items"`
+	Total      int64                 `json:"total"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+type SpreadsheetListItem struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	OwnerID     uint       `json:"owner_id"`
+	OwnerName   string     `json:"owner_name"`
+	WorkspaceID uint       `json:"workspace_id"`
+	FolderID    *uint      `json:"folder_id,omitempty"`
+	Role        Role       `json:"role"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+}
+
+type SearchResponse struct {
+	Results []SearchResult `json:"results"`
+}
+
+// SearchResult is a spreadsheet matching a search, with where it matched.
+type SearchResult struct {
+	Spreadsheet SpreadsheetListItem `json:"spreadsheet"`
+	Matches     []SearchMatch       `json:"matches"`
+}
+
+// SearchMatch is a title or cell containing a search term. Snippet is
+// HTML-escaped text around the match with the terms wrapped in <mark>.
+type SearchMatch struct {
+	Sheet   string `json:"sheet,omitempty"` // empty for the title
+	Cell    string `json:"cell,omitempty"`
+	Snippet string `json:"snippet"`
+}
+
+// FolderContents lists a folder, or a workspace's top level when Folder is
+// nil: its subfolders and the spreadsheets directly in it.
+type FolderContents struct {
+	Folder       *Folder               `json:"folder,omitempty"`
+	Folders      []Folder              `json:"folders"`
+	Spreadsheets []SpreadsheetListItem `json:"spreadsheets"`
+}
@@ -1,31 +1,419 @@
 package domain
 
-import "time"
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
 
 type User struct {
-	ID        uint      `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	AvatarURL string    `json:"avatar_url"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID           uint      `json:"id"`
+	Email        string    `json:"email"`
+	Name         string    `json:"name"`
+	AvatarURL    string    `json:"avatar_url"`
+	PasswordHash string    `json:"-"`
+	OIDCIdentity string    `json:"-"` // issuer and subject of the linked SSO account
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// NormalizeEmail returns the form emails are stored and looked up in, so
+// that "Foo@Example.com " and "foo@example.com" name the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
 type Spreadsheet struct {
+	ID          uint         `json:"id"`
+	Title       string       `json:"title"`
+	OwnerID     uint         `json:"owner_id"`
+	Owner       *User        `json:"owner,omitempty"`
+	WorkspaceID uint         `json:"workspace_id"`
+	FolderID    *uint        `json:"folder_id,omitempty"`
+	Path        []Breadcrumb `json:"path,omitempty"` // folders down to FolderID, set by the service
+	Data        string       `json:"data,omitempty"`
+	Version     int          `json:"version"`        // incremented on every data save
+	Role        Role         `json:"role,omitempty"` // caller's effective role, set by the service
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+	DeletedAt   *time.Time   `json:"deleted_at,omitempty"` // set while the spreadsheet is in the trash
+}
+
+// Role is a user's access level on a spreadsheet. Each role includes the
+// capabilities of the ones before it.
+type Role string
+
+const (
+	RoleViewer    Role = "viewer"
+	RoleCommenter Role = "commenter"
+	RoleEditor    Role = "editor"
+	RoleOwner     Role = "owner"
+)
+
+var roleRank = map[Role]int{RoleViewer: 1, RoleCommenter: 2, RoleEditor: 3, RoleOwner: 4}
+
+// Allows reports whether r grants at least the capabilities of min.
+func (r Role) Allows(min Role) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// Shareable reports whether r can be granted through a permission. Owner
+// is implied by Spreadsheet.OwnerID and cannot be shared.
+func (r Role) Shareable() bool {
+	return r == RoleViewer || r == RoleCommenter || r == RoleEditor
+}
+
+// Organization is a team. Its members share the spreadsheets in the
+// organization's workspaces.
+type Organization struct {
 	ID        uint      `json:"id"`
-	Title     string    `json:"title"`
-	OwnerID   uint      `json:"owner_id"`
-	Owner     *User     `json:"owner,omitempty"`
-	Data      string    `json:"data,omitempty"`
+	Name      string    `json:"name"`
+	Role      OrgRole   `json:"role,omitempty"` // caller's role, set by the service
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// OrgRole is a member's role in an organization. Admins manage members,
+// workspaces and every spreadsheet in the organization; owners can also
+// appoint owners and delete the organization.
+type OrgRole string
+
+const (
+	OrgMember OrgRole = "member"
+	OrgAdmin  OrgRole = "admin"
+	OrgOwner  OrgRole = "owner"
+)
+
+var orgRoleRank = map[OrgRole]int{OrgMember: 1, OrgAdmin: 2, OrgOwner: 3}
+
+// Allows reports whether r grants at least the capabilities of min.
+func (r OrgRole) Allows(min OrgRole) bool {
+	return orgRoleRank[r] >= orgRoleRank[min]
+}
+
+func (r OrgRole) Valid() bool {
+	return orgRoleRank[r] > 0
+}
+
+type OrganizationMember struct {
+	ID             uint      `json:"id"`
+	OrganizationID uint      `json:"organization_id"`
+	UserID         uint      `json:"user_id"`
+	User           *User     `json:"user,omitempty"`
+	Role           OrgRole   `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Workspace holds spreadsheets. Every user has a personal workspace
+// (OwnerID set), where new spreadsheets go by default; organization
+// workspaces (OrganizationID set) give every member of the organization
+// MemberRole on their spreadsheets.
+type Workspace struct {
+	ID             uint      `json:"id"`
+	Name           string    `json:"name"`
+	OrganizationID *uint     `json:"organization_id,omitempty"`
+	OwnerID        *uint     `json:"owner_id,omitempty"`
+	MemberRole     Role      `json:"member_role,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Personal reports whether w is a user's personal workspace.
+func (w *Workspace) Personal() bool {
+	return w.OrganizationID == nil
+}
+
+// Folder organises the spreadsheets of a workspace. Folders nest; a nil
+// ParentID is the workspace's top level. They only group spreadsheets and
+// don't affect who can access them.
+type Folder struct {
+	ID          uint         `json:"id"`
+	WorkspaceID uint         `json:"workspace_id"`
+	ParentID    *uint        `json:"parent_id"`
+	Name        string       `json:"name"`
+	Path        []Breadcrumb `json:"path,omitempty"` // from the top level down to this folder, set by the service
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Breadcrumb is one folder on a path.
+type Breadcrumb struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// ShareLink lets anyone with its URL open a spreadsheet without an
+// account, read-only. Only a hash of the token is stored; the link itself
+// is shown once.
+type ShareLink struct {
+	ID            uint       `json:"id"`
+	SpreadsheetID uint       `json:"spreadsheet_id"`
+	CreatedByID   uint       `json:"created_by_id"`
+	Prefix        string     `json:"prefix"` // start of the token, to tell links apart
+	TokenHash     string     `json:"-"`
+	Mode          Role       `json:"mode"` // viewer or commenter
+	PasswordHash  string     `json:"-"`
+	HasPassword   bool       `json:"has_password"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // nil never expires
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Comment is a note on a spreadsheet, kept apart from the cells. A thread
+// starts with a comment anchored to a cell or range of a sheet; replies
+// have ThreadID set and share the thread's anchor and resolved state.
+type Comment struct {
+	ID            uint   `json:"id"`
+	SpreadsheetID uint   `json:"spreadsheet_id"`
+	ThreadID      *uint  `json:"thread_id,omitempty"` // nil for the first comment of a thread
+	Sheet         string `json:"sheet,omitempty"`
+	// Anchor is a cell ("B3") or range ("B3:D5"). It moves with rows and
+	// columns inserted or deleted on save, and is empty once every cell
+	// it pointed at has been deleted. Sheet follows renames.
+	Anchor       string     `json:"anchor,omitempty"`
+	AuthorID     uint       `json:"author_id"`
+	Author       *User      `json:"author,omitempty"`
+	Body         string     `json:"body"`
+	Mentions     []User     `json:"mentions"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	ResolvedByID *uint      `json:"resolved_by_id,omitempty"`
+	EditedAt     *time.Time `json:"edited_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// CommentAnchor is where a comment thread points: a sheet and a cell or
+// range in it.
+type CommentAnchor struct {
+	Sheet  string
+	Anchor string
+}
+
+// NotificationType is the kind of event a notification reports. Users
+// choose which kinds notify them.
+type NotificationType string
+
+const (
+	NotifyShare         NotificationType = "share"          // a spreadsheet was shared with you
+	NotifyMention       NotificationType = "mention"        // you were @mentioned in a comment
+	NotifyAccessRequest NotificationType = "access_request" // someone asked for access to your spreadsheet
+)
+
+// NotificationTypes lists every notification type.
+var NotificationTypes = []NotificationType{NotifyShare, NotifyMention, NotifyAccessRequest}
+
+func (t NotificationType) Valid() bool {
+	return t == NotifyShare || t == NotifyMention || t == NotifyAccessRequest
+}
+
+// Notification tells a user about something another user did. Message is
+// rendered when the notification is created.
+type Notification struct {
+	ID            uint             `json:"id"`
+	UserID        uint             `json:"user_id"`
+	Type          NotificationType `json:"type"`
+	ActorID       *uint            `json:"actor_id,omitempty"`
+	Actor         *User            `json:"actor,omitempty"`
+	SpreadsheetID *uint            `json:"spreadsheet_id,omitempty"`
+	CommentID     *uint            `json:"comment_id,omitempty"`
+	Message       string           `json:"message"`
+	ReadAt        *time.Time       `json:"read_at,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+}
+
+// WebhookEvent is something that happened to a spreadsheet that webhooks
+// can subscribe to.
+type WebhookEvent string
+
+const (
+	EventSpreadsheetCreated WebhookEvent = "spreadsheet.created"
+	EventSpreadsheetSaved   WebhookEvent = "spreadsheet.saved" // new data, from any source
+	EventSpreadsheetRenamed WebhookEvent = "spreadsheet.renamed"
+	EventSpreadsheetShared  WebhookEvent = "spreadsheet.shared"
+	EventSpreadsheetDeleted WebhookEvent = "spreadsheet.deleted" // moved to the trash
+)
+
+// WebhookEvents lists every webhook event.
+var WebhookEvents = []WebhookEvent{
+	EventSpreadsheetCreated,
+	EventSpreadsheetSaved,
+	EventSpreadsheetRenamed,
+	EventSpreadsheetShared,
+	EventSpreadsheetDeleted,
+}
+
+func (e WebhookEvent) Valid() bool {
+	for _, ev := range WebhookEvents {
+		if e == ev {
+			return true
+		}
+	}
+	return false
+}
+
+// Webhook posts the events of one spreadsheet, or of every spreadsheet in
+// a workspace, to a URL. Exactly one of SpreadsheetID and WorkspaceID is
+// set.
+type Webhook struct {
+	ID            uint           `json:"id"`
+	SpreadsheetID *uint          `json:"spreadsheet_id,omitempty"`
+	WorkspaceID   *uint          `json:"workspace_id,omitempty"`
+	CreatedByID   uint           `json:"created_by_id"`
+	URL           string         `json:"url"`
+	Events        []WebhookEvent `json:"events"`
+	Secret        string         `json:"-"` // signs deliveries; only shown on creation
+	Active        bool           `json:"active"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// Subscribed reports whether the webhook wants the event.
+func (w *Webhook) Subscribed(event WebhookEvent) bool {
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery states.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed" // given up after the last retry
+)
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook, with
+// the outcome of its latest attempt.
+type WebhookDelivery struct {
+	ID            uint            `json:"id"`
+	WebhookID     uint            `json:"webhook_id"`
+	Event         WebhookEvent    `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"` // one of the Delivery* states
+	Attempts      int             `json:"attempts"`
+	StatusCode    int             `json:"status_code,omitempty"` // HTTP status of the latest attempt
+	Error         string          `json:"error,omitempty"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time      `json:"delivered_at,omitempty"`
+	RedeliveryOf  *uint           `json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Email is a message in the outgoing mail queue. It is rendered when
+// queued and retried until sent or given up on.
+type Email struct {
+	ID            uint
+	To            string
+	Subject       string
+	Text          string
+	HTML          string
+	Attempts      int
+	NextAttemptAt time.Time
+	LastError     string
+	SentAt        *time.Time
+	FailedAt      *time.Time // set when delivery was given up
+	CreatedAt     time.Time
+}
+
+// SearchEntry is a piece of indexed spreadsheet text: the title when
+// Sheet is empty, otherwise the value of one cell.
+type SearchEntry struct {
+	SpreadsheetID uint
+	Sheet         string
+	Cell          string
+	Text          string
+}
+
+type SpreadsheetPermission struct {
+	ID            uint         `json:"id"`
+	SpreadsheetID uint         `json:"spreadsheet_id"`
+	Spreadsheet   *Spreadsheet `json:"-"`
+	UserID        uint         `json:"user_id"`
+	User          *User        `json:"user,omitempty"`
+	Role          Role         `json:"role"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// Revision is an immutable snapshot of a spreadsheet's data, recorded on
+// every save.
+type Revision struct {
+	ID            uint      `json:"id"`
+	SpreadsheetID uint      `json:"spreadsheet_id"`
+	AuthorID      uint      `json:"author_id"`
+	Author        *User     `json:"author,omitempty"`
+	Data          string    `json:"data,omitempty"`
+	Size          int       `json:"size"`
+	Live          bool      `json:"live"` // saved by a live-editing room
+	CreatedAt     time.Time `json:"created_at"`
+}
+
 type Session struct {
-	ID        uint      `json:"id"`
-	Token     string    `json:"token"`
-	UserID    uint      `json:"user_id"`
-	User      *User     `json:"user,omitempty"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID         uint      `json:"id"`
+	Token      string    `json:"-"`
+	UserID     uint      `json:"user_id"`
+	User       *User     `json:"user,omitempty"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"` // the session making the request, set by the service
+}
+
+// Client describes where a sign-in comes from, for listing sessions.
+type Client struct {
+	IP        string
+	UserAgent string
+}
+
+// LinkAccess is what a request through a share link presents: the link's
+// token, its password if it has one, and the client IP for rate limits.
+type LinkAccess struct {
+	Token    string
+	Password string
+	IP       string
+}
+
+// APIToken is a long-lived credential for scripts and integrations. It acts
+// as its user, narrowed by Scope and, when set, to a single spreadsheet.
+// Only a hash of the secret is stored; the token itself is shown once.
+type APIToken struct {
+	ID            uint       `json:"id"`
+	UserID        uint       `json:"user_id"`
+	User          *User      `json:"-"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"` // start of the token, to tell tokens apart
+	TokenHash     string     `json:"-"`
+	Scope         TokenScope `json:"scope"`
+	SpreadsheetID *uint      `json:"spreadsheet_id,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"` // nil never expires
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP    string     `json:"last_used_ip,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// TokenScope limits what an API token can do.
+type TokenScope string
+
+const (
+	ScopeRead  TokenScope = "read"  // GET requests only
+	ScopeWrite TokenScope = "write" // everything the user can do
+)
+
+func (s TokenScope) Valid() bool {
+	return s == ScopeRead || s == ScopeWrite
+}
+
+// PasswordReset is a single-use link, sent by email, that sets a user's
+// password. It is how accounts without a password get one. Only a hash of
+// the token is stored.
+type PasswordReset struct {
+	ID        uint
+	UserID    uint
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
 }
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Services wrap these sentinels with a short, user-facing description
+// (e.g. "spreadsheet not found") so handlers can pick the HTTP status with
+// errors.Is and show the message as-is.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrForbidden    = errors.New("permission denied")
+	ErrInvalidInput = errors.New("invalid input")
+	ErrConflict     = errors.New("conflict")
+	ErrUnsupported  = errors.New("unsupported")
+
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email already registered")
+	ErrPasswordRequired   = errors.New("password required")
+	ErrTooManyAttempts    = errors.New("too many attempts")
+)
+
+// VersionConflictError reports a write against a stale spreadsheet version.
+// It matches ErrConflict and carries the current version so clients can
+// reconcile.
+type VersionConflictError struct {
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("spreadsheet was modified concurrently (current version %d)", e.Current)
+}
+
+func (e *VersionConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// TooManyAttemptsError reports that an action was refused because of
+// repeated failures. It matches ErrTooManyAttempts and says when to retry.
+type TooManyAttemptsError struct {
+	What       string // e.g. "password attempts"
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	minutes := int((e.RetryAfter + time.Minute - 1) / time.Minute)
+	if minutes <= 1 {
+		return fmt.Sprintf("too many %s, try again in a minute", e.What)
+	}
+	return fmt.Sprintf("too many %s, try again in %d minutes", e.What, minutes)
+}
+
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
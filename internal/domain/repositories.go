@@ -1,22 +1,320 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
+// UserRepository stores users. Emails are normalized with NormalizeEmail
+// on the way in, and FindByEmail and FindByOIDCIdentity return an error
+// wrapping ErrNotFound when no user matches.
 type UserRepository interface {
+	FindByID(ctx context.Context, id uint) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByOIDCIdentity(ctx context.Context, identity string) (*User, error)
 	Create(ctx context.Context, user *User) error
+	UpdatePasswordHash(ctx context.Context, id uint, hash string) error
+	SetOIDCIdentity(ctx context.Context, id uint, identity string) error
+	UpdateProfile(ctx context.Context, user *User) error
+}
+
+// SpreadsheetQuery selects a page of the spreadsheets visible to UserID:
+// those they own, those shared with them and those in their
+// organizations' workspaces, as chosen by Owned, Shared and Team. Trashed
+// spreadsheets are excluded and nil filters match everything.
+type SpreadsheetQuery struct {
+	UserID              uint
+	Owned, Shared, Team bool
+
+	Title         string // case-insensitive substring
+	OwnerID       *uint
+	WorkspaceID   *uint
+	FolderID      *uint
+	CreatedAfter  *time.Time // inclusive
+	CreatedBefore *time.Time // exclusive
+	UpdatedAfter  *time.Time // inclusive
+	UpdatedBefore *time.Time // exclusive
+
+	Sort       SpreadsheetSort
+	Descending bool
+	After      *SpreadsheetCursor // continue after this row
+	Limit      int
+}
+
+// SpreadsheetSort is a column spreadsheets can be listed by. Ties are
+// broken by ID.
+type SpreadsheetSort string
+
+const (
+	SortTitle   SpreadsheetSort = "title"
+	SortCreated SpreadsheetSort = "created_at"
+	SortUpdated SpreadsheetSort = "updated_at"
+)
+
+func (s SpreadsheetSort) Valid() bool {
+	return s == SortTitle || s == SortCreated || s == SortUpdated
+}
+
+// SpreadsheetCursor is the position of a row in a listing: its sort value
+// (Title or Time, depending on the sort) and ID.
+type SpreadsheetCursor struct {
+	Title string
+	Time  time.Time
+	ID    uint
 }
 
 type SpreadsheetRepository interface {
 	ListByOwner(ctx context.Context, ownerID uint) ([]Spreadsheet, error)
-	FindByIDAndOwner(ctx context.Context, id, ownerID uint) (*Spreadsheet, error)
+	// ListVisible returns a page of spreadsheets with Owner populated, and
+	// how many match the query in total.
+	ListVisible(ctx context.Context, q SpreadsheetQuery) ([]Spreadsheet, int64, error)
+	ListByWorkspace(ctx context.Context, workspaceID uint) ([]Spreadsheet, error)
+	// ListByFolder returns the spreadsheets directly in a folder, or at the
+	// workspace's top level when folderID is nil.
+	ListByFolder(ctx context.Context, workspaceID uint, folderID *uint) ([]Spreadsheet, error)
+	// CountByWorkspace counts spreadsheets in a workspace, including trashed ones.
+	CountByWorkspace(ctx context.Context, workspaceID uint) (int64, error)
+	FindByID(ctx context.Context, id uint) (*Spreadsheet, error)
+	// FindByIDWithoutData is FindByID leaving out Data, for access checks.
+	FindByIDWithoutData(ctx context.Context, id uint) (*Spreadsheet, error)
 	Create(ctx context.Context, spreadsheet *Spreadsheet) error
+	// Update applies fields. When they include "data", the version is
+	// bumped and the write only succeeds if the stored version still
+	// equals spreadsheet.Version; otherwise it returns ErrConflict.
 	Update(ctx context.Context, spreadsheet *Spreadsheet, fields map[string]any) error
+	// SaveData is Update for fields that include "data", and records the
+	// new data as a revision by authorID in the same transaction. A zero
+	// mergeWindow always adds a revision. Live-editing rooms, which save
+	// every few seconds, pass a positive one: their revisions are marked
+	// live, and one replaces the latest revision if that is a live one by
+	// the same author from the same mergeWindow-long period. Other
+	// revisions are never changed.
+	SaveData(ctx context.Context, spreadsheet *Spreadsheet, fields map[string]any, authorID uint, mergeWindow time.Duration) error
+	// Trash soft-deletes a spreadsheet. Trashed spreadsheets are excluded
+	// from ListByOwner and FindByID.
+	Trash(ctx context.Context, id, ownerID uint) error
+	ListTrashedByOwner(ctx context.Context, ownerID uint) ([]Spreadsheet, error)
+	// ListTrashedBefore returns up to limit spreadsheets trashed before cutoff.
+	ListTrashedBefore(ctx context.Context, cutoff time.Time, limit int) ([]Spreadsheet, error)
+	Restore(ctx context.Context, id, ownerID uint) error
+	// Delete permanently removes a trashed spreadsheet together with its
+	// permissions and revisions.
 	Delete(ctx context.Context, id, ownerID uint) error
 }
 
+type OrganizationRepository interface {
+	// Create stores the organization with ownerID as its first owner.
+	Create(ctx context.Context, org *Organization, ownerID uint) error
+	FindByID(ctx context.Context, id uint) (*Organization, error)
+	// ListByUser returns the user's organizations with Role set.
+	ListByUser(ctx context.Context, userID uint) ([]Organization, error)
+	Update(ctx context.Context, org *Organization) error
+	// Delete removes the organization with its members and workspaces.
+	Delete(ctx context.Context, id uint) error
+
+	ListMembers(ctx context.Context, orgID uint) ([]OrganizationMember, error)
+	// FindMember returns ErrNotFound when the user isn't a member.
+	FindMember(ctx context.Context, orgID, userID uint) (*OrganizationMember, error)
+	UpsertMember(ctx context.Context, member *OrganizationMember) error
+	// RemoveMember deletes a membership and, in the same transaction, makes
+	// heirID the owner of every spreadsheet, trashed or not, that the
+	// member owns in the organization's workspaces.
+	RemoveMember(ctx context.Context, orgID, userID, heirID uint) error
+	CountOwners(ctx context.Context, orgID uint) (int64, error)
+}
+
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *Workspace) error
+	// FindByID returns ErrNotFound for unknown workspaces.
+	FindByID(ctx context.Context, id uint) (*Workspace, error)
+	// FindOrCreatePersonal returns the user's personal workspace, creating
+	// it on first use.
+	FindOrCreatePersonal(ctx context.Context, userID uint) (*Workspace, error)
+	ListByOrganization(ctx context.Context, orgID uint) ([]Workspace, error)
+	Update(ctx context.Context, workspace *Workspace) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type FolderRepository interface {
+	Create(ctx context.Context, folder *Folder) error
+	FindByID(ctx context.Context, id uint) (*Folder, error)
+	ListByWorkspace(ctx context.Context, workspaceID uint) ([]Folder, error)
+	// Update saves the folder's name and parent.
+	Update(ctx context.Context, folder *Folder) error
+	// Delete removes a folder, moving its subfolders and spreadsheets
+	// (trashed ones included) into its parent.
+	Delete(ctx context.Context, folder *Folder) error
+}
+
+type ShareLinkRepository interface {
+	Create(ctx context.Context, link *ShareLink) error
+	// ListBySpreadsheet returns a spreadsheet's links, newest first.
+	ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]ShareLink, error)
+	// FindValidByHash returns an unexpired link.
+	FindValidByHash(ctx context.Context, hash string) (*ShareLink, error)
+	RecordUse(ctx context.Context, id uint, at time.Time) error
+	Delete(ctx context.Context, id, spreadsheetID uint) error
+}
+
+type CommentRepository interface {
+	// Create stores a comment and records mentions of the given users.
+	Create(ctx context.Context, comment *Comment, mentions []uint) error
+	FindByID(ctx context.Context, id uint) (*Comment, error)
+	// ListThreads returns the threads of a spreadsheet matching the
+	// filter, first comments and replies alike, oldest first.
+	ListThreads(ctx context.Context, spreadsheetID uint, filter CommentFilter) ([]Comment, error)
+	// ListOnSheet returns the first comments of the threads on a sheet,
+	// including those whose anchor was deleted.
+	ListOnSheet(ctx context.Context, spreadsheetID uint, sheet string) ([]Comment, error)
+	UpdateBody(ctx context.Context, id uint, body string, mentions []uint, editedAt time.Time) error
+	// SetResolved resolves a thread, or reopens it when by is nil.
+	SetResolved(ctx context.Context, id uint, by *uint, at *time.Time) error
+	// SetAnchors moves threads to new sheets and anchors, keyed by
+	// comment ID.
+	SetAnchors(ctx context.Context, anchors map[uint]CommentAnchor) error
+	// Delete removes a comment and, for the first comment of a thread,
+	// its replies.
+	Delete(ctx context.Context, id uint) error
+}
+
+// CommentFilter narrows CommentRepository.ListThreads. A zero value
+// matches every thread.
+type CommentFilter struct {
+	ThreadID *uint
+	Sheet    string
+	Resolved *bool
+}
+
+type NotificationRepository interface {
+	Create(ctx context.Context, n *Notification) error
+	// List returns a user's notifications newest first, starting below
+	// beforeID when it is non-zero.
+	List(ctx context.Context, userID uint, unreadOnly bool, beforeID uint, limit int) ([]Notification, error)
+	CountUnread(ctx context.Context, userID uint) (int64, error)
+	// HasUnread reports whether the user has an unread notification of the
+	// type from actorID about the spreadsheet.
+	HasUnread(ctx context.Context, userID uint, t NotificationType, actorID, spreadsheetID uint) (bool, error)
+	MarkRead(ctx context.Context, id, userID uint, at time.Time) error
+	MarkAllRead(ctx context.Context, userID uint, at time.Time) error
+	DeleteReadBefore(ctx context.Context, before time.Time) (int64, error)
+	// Preferences returns the types a user has turned on or off; types
+	// missing from the map use the default.
+	Preferences(ctx context.Context, userID uint) (map[NotificationType]bool, error)
+	SetPreferences(ctx context.Context, userID uint, prefs map[NotificationType]bool) error
+}
+
+type WebhookRepository interface {
+	Create(ctx context.Context, hook *Webhook) error
+	FindByID(ctx context.Context, id uint) (*Webhook, error)
+	ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]Webhook, error)
+	ListByWorkspace(ctx context.Context, workspaceID uint) ([]Webhook, error)
+	// ListActive returns the active webhooks of a spreadsheet and of its
+	// workspace.
+	ListActive(ctx context.Context, spreadsheetID, workspaceID uint) ([]Webhook, error)
+	Update(ctx context.Context, hook *Webhook) error
+	// Delete removes a webhook with its deliveries.
+	Delete(ctx context.Context, id uint) error
+
+	CreateDelivery(ctx context.Context, d *WebhookDelivery) error
+	FindDelivery(ctx context.Context, webhookID, id uint) (*WebhookDelivery, error)
+	// ListDeliveries returns a webhook's deliveries newest first, starting
+	// below beforeID when it is non-zero.
+	ListDeliveries(ctx context.Context, webhookID, beforeID uint, limit int) ([]WebhookDelivery, error)
+	// DueDeliveries returns pending deliveries whose next attempt is at or
+	// before now, oldest first.
+	DueDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	// SaveAttempt stores the outcome of an attempt: status, attempts,
+	// status code, response, error, next attempt and delivery time.
+	SaveAttempt(ctx context.Context, d *WebhookDelivery) error
+	// DeleteDeliveriesBefore deletes finished deliveries created before
+	// the given time.
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type EmailRepository interface {
+	Enqueue(ctx context.Context, email *Email) error
+	// Due returns unsent emails whose next attempt is at or before now,
+	// oldest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]Email, error)
+	MarkSent(ctx context.Context, id uint, attempts int, at time.Time) error
+	// Retry records a failed attempt and schedules the next one.
+	Retry(ctx context.Context, id uint, attempts int, lastError string, next time.Time) error
+	// GiveUp records a failed attempt after which the email isn't retried.
+	GiveUp(ctx context.Context, id uint, attempts int, lastError string, at time.Time) error
+	// DeleteFinishedBefore deletes emails sent or given up on before the
+	// given time.
+	DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+type SearchRepository interface {
+	// Replace swaps the indexed text of a spreadsheet for entries; nil
+	// removes it from the index.
+	Replace(ctx context.Context, spreadsheetID uint, entries []SearchEntry) error
+	// Search returns up to limit spreadsheets visible to the user, as in
+	// SpreadsheetRepository.ListVisible, where every term occurs in the
+	// title or a cell. Most recently updated come first, with Owner
+	// populated.
+	Search(ctx context.Context, userID uint, terms []string, limit int) ([]Spreadsheet, error)
+	// Matches returns up to limit entries of a spreadsheet that contain
+	// any of terms, in index order.
+	Matches(ctx context.Context, spreadsheetID uint, terms []string, limit int) ([]SearchEntry, error)
+	// ListUnindexed returns up to limit spreadsheets, not trashed, that
+	// have no entries yet.
+	ListUnindexed(ctx context.Context, limit int) ([]Spreadsheet, error)
+}
+
+type PermissionRepository interface {
+	ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]SpreadsheetPermission, error)
+	// ListByUser returns the user's permissions with Spreadsheet (and its
+	// Owner) populated.
+	ListByUser(ctx context.Context, userID uint) ([]SpreadsheetPermission, error)
+	// FindRole returns ErrNotFound when the spreadsheet isn't shared with
+	// the user.
+	FindRole(ctx context.Context, spreadsheetID, userID uint) (Role, error)
+	// FindRoles returns the user's roles on those of the spreadsheets shared
+	// with them.
+	FindRoles(ctx context.Context, userID uint, spreadsheetIDs []uint) (map[uint]Role, error)
+	Upsert(ctx context.Context, permission *SpreadsheetPermission) error
+	Delete(ctx context.Context, spreadsheetID, userID uint) error
+}
+
+type RevisionRepository interface {
+	Create(ctx context.Context, revision *Revision) error
+	// ListBySpreadsheet returns revisions newest first, without Data.
+	ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]Revision, error)
+	FindByID(ctx context.Context, spreadsheetID, id uint) (*Revision, error)
+	// ListSpreadsheetIDs returns every spreadsheet that has revisions.
+	ListSpreadsheetIDs(ctx context.Context) ([]uint, error)
+	DeleteByIDs(ctx context.Context, ids []uint) error
+}
+
 type SessionRepository interface {
 	Create(ctx context.Context, session *Session) error
 	FindValidByToken(ctx context.Context, token string) (*Session, error)
+	// ListValidByUser returns unexpired sessions, most recently seen first.
+	ListValidByUser(ctx context.Context, userID uint) ([]Session, error)
+	Touch(ctx context.Context, id uint, seenAt time.Time, ip string, expiresAt time.Time) error
+	Delete(ctx context.Context, id, userID uint) error
 	DeleteByTokenAndUser(ctx context.Context, token string, userID uint) error
+	// DeleteOthers removes every session of the user except keepID.
+	DeleteOthers(ctx context.Context, userID, keepID uint) (int64, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+type APITokenRepository interface {
+	Create(ctx context.Context, token *APIToken) error
+	ListByUser(ctx context.Context, userID uint) ([]APIToken, error)
+	// FindValidByHash returns an unexpired token with User populated.
+	FindValidByHash(ctx context.Context, hash string) (*APIToken, error)
+	RecordUse(ctx context.Context, id uint, at time.Time, ip string) error
+	Delete(ctx context.Context, id, userID uint) error
+}
+
+type PasswordResetRepository interface {
+	Create(ctx context.Context, reset *PasswordReset) error
+	// Consume finds an unexpired reset by its token hash and deletes every
+	// reset of its user, so a link works once and older links stop
+	// working. It returns ErrNotFound for an unknown or expired token.
+	Consume(ctx context.Context, hash string, now time.Time) (*PasswordReset, error)
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
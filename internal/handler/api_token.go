@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AuthHandler) ListAPITokens(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	tokens, err := h.auth.ListAPITokens(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch API tokens")
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) CreateAPIToken(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	var req domain.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name and scope are required"})
+		return
+	}
+
+	resp, err := h.auth.CreateAPIToken(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create API token")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *AuthHandler) RevokeAPIToken(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseUintParam(c, "tokenId", "Invalid token ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.auth.RevokeAPIToken(c.Request.Context(), userID, id); err != nil {
+		respondError(c, err, "Failed to revoke API token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API token revoked"})
+}
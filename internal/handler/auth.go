@@ -1,34 +1,149 @@
 package handler
 
 import (
+	"errors"
 	"jaggle-grids/internal/domain"
 	"jaggle-grids/internal/service"
+	"log"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+const oidcStateCookie = "grids_oidc"
+
 type AuthHandler struct {
-	auth *service.AuthService
+	auth              *service.AuthService
+	oidc              *service.OIDCService
+	postLoginRedirect string
+}
+
+// NewAuthHandler creates the auth handler. oidc may be nil when single
+// sign-on is not configured; postLoginRedirect is the frontend page that
+// receives the session token after an SSO login.
+func NewAuthHandler(auth *service.AuthService, oidc *service.OIDCService, postLoginRedirect string) *AuthHandler {
+	return &AuthHandler{auth: auth, oidc: oidc, postLoginRedirect: postLoginRedirect}
 }
 
-func NewAuthHandler(auth *service.AuthService) *AuthHandler {
-	return &AuthHandler{auth: auth}
+// Config tells the login page which sign-in methods are available.
+func (h *AuthHandler) Config(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"mock_login": h.auth.MockLoginEnabled(),
+		"oidc":       h.oidc != nil,
+	})
+}
+
+func (h *AuthHandler) Register(c *gin.Context) {
+	var req domain.RegisterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email, name and a password of at least 8 characters are required"})
+		return
+	}
+
+	resp, err := h.auth.Register(c.Request.Context(), req.Email, req.Name, req.Password, client(c))
+	if errors.Is(err, domain.ErrEmailTaken) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An account with this email already exists"})
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to create account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req domain.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email and name are required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email is required"})
+		return
+	}
+
+	var resp *domain.AuthResponse
+	var err error
+	if req.LinkToken != "" {
+		if h.oidc == nil || req.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email and password are required to link single sign-on"})
+			return
+		}
+		resp, err = h.oidc.LinkWithPassword(c.Request.Context(), req.LinkToken, req.Email, req.Password, client(c))
+	} else if h.auth.MockLoginEnabled() {
+		resp, err = h.auth.MockLogin(c.Request.Context(), req.Email, req.Name, client(c))
+	} else {
+		if req.Password == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email and password are required"})
+			return
+		}
+		resp, err = h.auth.Login(c.Request.Context(), req.Email, req.Password, client(c))
+	}
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to authenticate")
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	sessionID := c.MustGet("session_id").(uint)
+
+	var req domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: current password and a new password of at least 8 characters are required"})
 		return
 	}
 
-	resp, err := h.auth.Login(c.Request.Context(), req.Email, req.Name)
+	err := h.auth.ChangePassword(c.Request.Context(), userID, sessionID, req.CurrentPassword, req.NewPassword)
+	if errors.Is(err, domain.ErrInvalidCredentials) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Current password is incorrect"})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to authenticate"})
+		respondError(c, err, "Failed to change password")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// ForgotPassword emails a link to set a new password. It answers the same
+// whether or not the email has an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req domain.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email is required"})
 		return
 	}
 
+	if err := h.auth.RequestPasswordReset(c.Request.Context(), req.Email, c.ClientIP()); err != nil {
+		respondError(c, err, "Failed to send password link")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "If an account uses this email, a link to set its password is on its way"})
+}
+
+// ResetPassword sets a password with the token from an emailed link and
+// signs in.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req domain.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: token and a password of at least 8 characters are required"})
+		return
+	}
+
+	resp, err := h.auth.ResetPassword(c.Request.Context(), req.Token, req.Password, client(c))
+	if err != nil {
+		respondError(c, err, "Failed to set password")
+		return
+	}
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -48,3 +163,66 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	_ = h.auth.Logout(c.Request.Context(), token.(string), userID.(uint))
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// OIDCLogin redirects the browser to the identity provider.
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	redirectURL, login, err := h.oidc.Begin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start single sign-on"})
+		return
+	}
+
+	value := strings.Join([]string{login.State, login.Nonce, login.Verifier}, ".")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, value, 600, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// OIDCCallback finishes the login and hands the session token to the
+// frontend in the URL fragment, which is never sent to servers.
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	cookie, _ := c.Cookie(oidcStateCookie)
+	c.SetCookie(oidcStateCookie, "", -1, "/api/auth/oidc", "", c.Request.TLS != nil, true)
+
+	if errParam := c.Query("error"); errParam != "" {
+		h.redirectToLogin(c, url.Values{"error": {errParam}})
+		return
+	}
+
+	parts := strings.Split(cookie, ".")
+	if len(parts) != 3 {
+		h.redirectToLogin(c, url.Values{"error": {"invalid_state"}})
+		return
+	}
+
+	login := service.OIDCLoginState{State: parts[0], Nonce: parts[1], Verifier: parts[2]}
+	resp, err := h.oidc.Complete(c.Request.Context(), c.Query("code"), c.Query("state"), login, client(c))
+	var linkErr *service.OIDCLinkRequiredError
+	if errors.As(err, &linkErr) {
+		h.redirectToLogin(c, url.Values{"error": {"link_required"}, "email": {linkErr.Email}, "link": {linkErr.Token}})
+		return
+	}
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		h.redirectToLogin(c, url.Values{"error": {"login_failed"}})
+		return
+	}
+
+	h.redirectToLogin(c, url.Values{"token": {resp.Token}})
+}
+
+// maxUserAgent caps the stored User-Agent; browsers send ~150 characters.
+const maxUserAgent = 512
+
+// client describes the device a sign-in request comes from.
+func client(c *gin.Context) domain.Client {
+	ua := c.Request.UserAgent()
+	if len(ua) > maxUserAgent {
+		ua = ua[:maxUserAgent]
+	}
+	return domain.Client{IP: c.ClientIP(), UserAgent: ua}
+}
+
+func (h *AuthHandler) redirectToLogin(c *gin.Context, fragment url.Values) {
+	c.Redirect(http.StatusFound, h.postLoginRedirect+"#"+fragment.Encode())
+}
@@ -0,0 +1,175 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SpreadsheetHandler) ListComments(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.ListCommentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: resolved must be true or false"})
+		return
+	}
+
+	threads, err := h.sheets.ListComments(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to fetch comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+func (h *SpreadsheetHandler) CreateComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: sheet, anchor and body are required"})
+		return
+	}
+
+	thread, err := h.sheets.CreateComment(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, thread)
+}
+
+func (h *SpreadsheetHandler) GetCommentThread(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	thread, err := h.sheets.GetThread(c.Request.Context(), id, userID, commentID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func (h *SpreadsheetHandler) ReplyComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CommentBodyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: body is required"})
+		return
+	}
+
+	reply, err := h.sheets.ReplyComment(c.Request.Context(), id, userID, commentID, req.Body)
+	if err != nil {
+		respondError(c, err, "Failed to reply to comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+func (h *SpreadsheetHandler) EditComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CommentBodyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: body is required"})
+		return
+	}
+
+	comment, err := h.sheets.EditComment(c.Request.Context(), id, userID, commentID, req.Body)
+	if err != nil {
+		respondError(c, err, "Failed to edit comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *SpreadsheetHandler) DeleteComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.sheets.DeleteComment(c.Request.Context(), id, userID, commentID); err != nil {
+		respondError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+func (h *SpreadsheetHandler) ResolveComment(c *gin.Context) {
+	h.setResolved(c, true)
+}
+
+func (h *SpreadsheetHandler) ReopenComment(c *gin.Context) {
+	h.setResolved(c, false)
+}
+
+func (h *SpreadsheetHandler) setResolved(c *gin.Context, resolved bool) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	thread, err := h.sheets.ResolveComment(c.Request.Context(), id, userID, commentID, resolved)
+	if err != nil {
+		respondError(c, err, "Failed to update comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func parseCommentID(c *gin.Context) (uint, error) {
+	return parseUintParam(c, "commentId", "Invalid comment ID")
+}
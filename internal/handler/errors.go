@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"jaggle-grids/internal/domain"
+	"math"
+	"net/http"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respondError writes a JSON error for err. Domain errors map to their
+// HTTP status and carry a user-facing message; anything else is reported
+// as a 500 with the fallback message.
+func respondError(c *gin.Context, err error, fallback string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, domain.ErrForbidden):
+		status = http.StatusForbidden
+	case errors.Is(err, domain.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, domain.ErrConflict):
+		status = http.StatusConflict
+	case errors.Is(err, domain.ErrUnsupported):
+		status = http.StatusUnprocessableEntity
+	case errors.Is(err, domain.ErrTooManyAttempts):
+		status = http.StatusTooManyRequests
+	}
+
+	var tm *domain.TooManyAttemptsError
+	if errors.As(err, &tm) {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(tm.RetryAfter.Seconds()))))
+	}
+
+	var vc *domain.VersionConflictError
+	if errors.As(err, &vc) {
+		c.Header("ETag", etag(vc.Current))
+		c.JSON(status, gin.H{"error": capitalize(err.Error()), "version": vc.Current})
+		return
+	}
+
+	if status == http.StatusInternalServerError {
+		c.JSON(status, gin.H{"error": fallback})
+		return
+	}
+	c.JSON(status, gin.H{"error": capitalize(err.Error())})
+}
+
+func capitalize(s string) string {
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"bytes"
+	"jaggle-grids/internal/workbook"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+var exportTypes = map[string]string{
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ods":  "application/vnd.oasis.opendocument.spreadsheet",
+	"csv":  "text/csv",
+}
+
+// Export downloads the spreadsheet as ?format=xlsx (default), ods or csv.
+// CSV exports one sheet (?sheet=, default the first) and accepts
+// ?delimiter= (a single character or "tab") and ?encoding=.
+func (h *SpreadsheetHandler) Export(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", "xlsx"))
+	contentType, ok := exportTypes[format]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of xlsx, ods, csv"})
+		return
+	}
+
+	var csvOpts workbook.CSVOptions
+	if format == "csv" {
+		csvOpts.Encoding = c.Query("encoding")
+		switch d := c.DefaultQuery("delimiter", ","); {
+		case d == "tab" || d == `\t`:
+			csvOpts.Comma = '\t'
+		case utf8.RuneCountInString(d) == 1:
+			csvOpts.Comma, _ = utf8.DecodeRuneInString(d)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "delimiter must be a single character or \"tab\""})
+			return
+		}
+		if err := workbook.ValidCSVOptions(csvOpts); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": capitalize(err.Error())})
+			return
+		}
+	}
+
+	sheet, wb, err := h.sheets.Workbook(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to export spreadsheet")
+		return
+	}
+
+	filename := sheet.Title
+	// Render into memory first so that a failure still gets a proper
+	// error response instead of a truncated download.
+	var buf bytes.Buffer
+	switch format {
+	case "xlsx":
+		err = workbook.WriteXLSX(&buf, wb)
+	case "ods":
+		err = workbook.WriteODS(&buf, wb)
+	case "csv":
+		ws := wb.Sheets[0]
+		if name := c.Query("sheet"); name != "" {
+			if ws = wb.Sheet(name); ws == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Sheet " + strconv.Quote(name) + " not found"})
+				return
+			}
+		}
+		if len(wb.Sheets) > 1 {
+			filename += " - " + ws.Name
+		}
+		contentType += "; charset=" + csvCharset(csvOpts.Encoding)
+		err = workbook.WriteCSV(&buf, ws, csvOpts)
+	}
+	if err != nil {
+		log.Printf("export spreadsheet %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export spreadsheet"})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": sanitizeFilename(filename) + "." + format,
+	}))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+func csvCharset(encoding string) string {
+	switch strings.ToLower(encoding) {
+	case workbook.EncodingUTF16LE, "utf-16":
+		return "utf-16"
+	case workbook.EncodingWindows1252, "cp1252":
+		return "windows-1252"
+	case workbook.EncodingLatin1, "latin1":
+		return "iso-8859-1"
+	default:
+		return "utf-8"
+	}
+}
+
+// sanitizeFilename drops characters that aren't allowed in file names on
+// common platforms.
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" {
+		return "spreadsheet"
+	}
+	return name
+}
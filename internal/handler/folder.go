@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type FolderHandler struct {
+	folders *service.FolderService
+}
+
+func NewFolderHandler(folders *service.FolderService) *FolderHandler {
+	return &FolderHandler{folders: folders}
+}
+
+// List returns every folder in a workspace.
+func (h *FolderHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	folders, err := h.folders.ListFolders(c.Request.Context(), workspaceID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+// TopLevel lists the folders and spreadsheets at a workspace's top level.
+func (h *FolderHandler) TopLevel(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	contents, err := h.folders.TopLevel(c.Request.Context(), workspaceID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, contents)
+}
+
+func (h *FolderHandler) Create(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name is required"})
+		return
+	}
+
+	folder, err := h.folders.CreateFolder(c.Request.Context(), workspaceID, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create folder")
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+// Get returns a folder with its path, subfolders and spreadsheets.
+func (h *FolderHandler) Get(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseFolderID(c)
+	if err != nil {
+		return
+	}
+
+	contents, err := h.folders.Contents(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, contents)
+}
+
+func (h *FolderHandler) Rename(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseFolderID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.RenameFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name is required"})
+		return
+	}
+
+	folder, err := h.folders.RenameFolder(c.Request.Context(), id, userID, req.Name)
+	if err != nil {
+		respondError(c, err, "Failed to rename folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *FolderHandler) Move(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseFolderID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.MoveFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	folder, err := h.folders.MoveFolder(c.Request.Context(), id, userID, req.ParentID)
+	if err != nil {
+		respondError(c, err, "Failed to move folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *FolderHandler) Delete(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseFolderID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.folders.DeleteFolder(c.Request.Context(), id, userID); err != nil {
+		respondError(c, err, "Failed to delete folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted; its contents moved to the parent folder"})
+}
+
+func parseFolderID(c *gin.Context) (uint, error) {
+	return parseUintParam(c, "folderId", "Invalid folder ID")
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type folder struct {
+	ID       uint
+	ParentID *uint `json:"parent_id"`
+	Name     string
+	Path     []struct{ Name string }
+}
+
+// personalWorkspace returns the folders path of the user's own workspace.
+func (s *testServer) personalWorkspace(token string) string {
+	s.t.Helper()
+	var sheet struct {
+		WorkspaceID uint `json:"workspace_id"`
+	}
+	decode(s.t, s.do(token, http.MethodGet, s.createSpreadsheet(token, "Untitled"), nil), http.StatusOK, &sheet)
+	return fmt.Sprintf("/api/workspaces/%d/folders", sheet.WorkspaceID)
+}
+
+func (s *testServer) createFolder(token, folders, name string, parent *folder) *folder {
+	s.t.Helper()
+	body := gin.H{"name": name}
+	if parent != nil {
+		body["parent_id"] = parent.ID
+	}
+	var f folder
+	decode(s.t, s.do(token, http.MethodPost, folders, body), http.StatusCreated, &f)
+	return &f
+}
+
+// moveFolder puts f in parent, or at the top level when parent is nil,
+// and returns the response.
+func (s *testServer) moveFolder(token string, f, parent *folder) *httptest.ResponseRecorder {
+	s.t.Helper()
+	body := gin.H{"parent_id": nil}
+	if parent != nil {
+		body["parent_id"] = parent.ID
+	}
+	return s.do(token, http.MethodPut, fmt.Sprintf("/api/folders/%d/parent", f.ID), body)
+}
+
+// folderPaths lists every folder of a workspace as "a/b/c".
+func (s *testServer) folderPaths(token, folders string) map[string]bool {
+	s.t.Helper()
+	var list []folder
+	decode(s.t, s.do(token, http.MethodGet, folders, nil), http.StatusOK, &list)
+	byID := map[uint]folder{}
+	for _, f := range list {
+		byID[f.ID] = f
+	}
+	paths := map[string]bool{}
+	for _, f := range list {
+		path := f.Name
+		for p := f.ParentID; p != nil; p = byID[*p].ParentID {
+			path = byID[*p].Name + "/" + path
+		}
+		paths[path] = true
+	}
+	return paths
+}
+
+func TestMoveFolderCycles(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("ada@example.com")
+	folders := s.personalWorkspace(token)
+	a := s.createFolder(token, folders, "a", nil)
+	b := s.createFolder(token, folders, "b", a)
+	c := s.createFolder(token, folders, "c", b)
+	d := s.createFolder(token, folders, "d", nil)
+
+	// A folder can't go into itself or anything below it.
+	for _, parent := range []*folder{a, b, c} {
+		if w := s.moveFolder(token, a, parent); w.Code != http.StatusBadRequest {
+			t.Errorf("moved a into %s: %d %s", parent.Name, w.Code, w.Body)
+		}
+	}
+	if w := s.moveFolder(token, b, c); w.Code != http.StatusBadRequest {
+		t.Errorf("moved b into c: %d %s", w.Code, w.Body)
+	}
+	want := map[string]bool{"a": true, "a/b": true, "a/b/c": true, "d": true}
+	if got := s.folderPaths(token, folders); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("folders = %v after refused moves", got)
+	}
+
+	// Moving to the top level, then back under what was its child.
+	w := s.moveFolder(token, c, nil)
+	var moved folder
+	decode(t, w, http.StatusOK, &moved)
+	if moved.ParentID != nil || len(moved.Path) != 1 {
+		t.Errorf("moved to top level = %+v", moved)
+	}
+	decode(t, s.moveFolder(token, a, c), http.StatusOK, &moved)
+	if len(moved.Path) != 2 || moved.Path[0].Name != "c" {
+		t.Errorf("path = %+v", moved.Path)
+	}
+	if w := s.moveFolder(token, c, b); w.Code != http.StatusBadRequest {
+		t.Errorf("moved c under its grandchild: %d %s", w.Code, w.Body)
+	}
+	if w := s.moveFolder(token, d, b); w.Code != http.StatusOK {
+		t.Errorf("moved d into b: %d %s", w.Code, w.Body)
+	}
+	want = map[string]bool{"c": true, "c/a": true, "c/a/b": true, "c/a/b/d": true}
+	if got := s.folderPaths(token, folders); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("folders = %v", got)
+	}
+}
+
+func TestMoveFolderAcrossWorkspaces(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.login("ada@example.com")
+	bob := s.login("bob@example.com")
+	adas := s.createFolder(ada, s.personalWorkspace(ada), "ada's", nil)
+	bobs := s.createFolder(bob, s.personalWorkspace(bob), "bob's", nil)
+
+	// A parent in another workspace is not found, whoever owns it.
+	if w := s.moveFolder(ada, adas, bobs); w.Code != http.StatusNotFound {
+		t.Errorf("moved into another workspace: %d %s", w.Code, w.Body)
+	}
+	if w := s.moveFolder(bob, adas, bobs); w.Code != http.StatusNotFound {
+		t.Errorf("moved another user's folder: %d %s", w.Code, w.Body)
+	}
+	if w := s.moveFolder(ada, adas, &folder{ID: 999}); w.Code != http.StatusNotFound {
+		t.Errorf("moved into a missing folder: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(ada, http.MethodPut, fmt.Sprintf("/api/folders/%d/parent", adas.ID), gin.H{"parent_id": "x"}); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed parent: %d %s", w.Code, w.Body)
+	}
+}
+
+func TestFolderDepth(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("ada@example.com")
+	folders := s.personalWorkspace(token)
+
+	levels := []*folder{nil}
+	for i := 1; i <= 16; i++ {
+		levels = append(levels, s.createFolder(token, folders, fmt.Sprint(i), levels[i-1]))
+	}
+	deepest := levels[16]
+	if len(deepest.Path) != 16 {
+		t.Fatalf("path = %d folders", len(deepest.Path))
+	}
+	if w := s.do(token, http.MethodPost, folders, gin.H{"name": "17", "parent_id": deepest.ID}); w.Code != http.StatusBadRequest {
+		t.Errorf("created a 17th level: %d %s", w.Code, w.Body)
+	}
+
+	// Moving counts the folders inside the one moved.
+	outer := s.createFolder(token, folders, "outer", nil)
+	s.createFolder(token, folders, "inner", outer)
+	if w := s.moveFolder(token, outer, levels[15]); w.Code != http.StatusBadRequest {
+		t.Errorf("moved two levels under level 15: %d %s", w.Code, w.Body)
+	}
+	if w := s.moveFolder(token, outer, levels[14]); w.Code != http.StatusOK {
+		t.Errorf("moved two levels under level 14: %d %s", w.Code, w.Body)
+	}
+	for path := range s.folderPaths(token, folders) {
+		if n := strings.Count(path, "/") + 1; n > 16 {
+			t.Errorf("%s is %d deep", path, n)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"jaggle-grids/internal/mail"
+	"jaggle-grids/internal/middleware"
+	"jaggle-grids/internal/repository/gormrepo"
+	"jaggle-grids/internal/repository/sqlite"
+	"jaggle-grids/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testServer serves the API routes under test from a fresh SQLite
+// database, with the services wired as in main.go and mock sign-in.
+type testServer struct {
+	t      *testing.T
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	db := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err := gormrepo.MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	userRepo := gormrepo.NewUserRepo(db)
+	sheetRepo := gormrepo.NewSpreadsheetRepo(db)
+	permissionRepo := gormrepo.NewPermissionRepo(db)
+	workspaceRepo := gormrepo.NewWorkspaceRepo(db)
+	orgRepo := gormrepo.NewOrganizationRepo(db)
+	folderRepo := gormrepo.NewFolderRepo(db)
+	searchRepo, err := gormrepo.NewSearchRepo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	emailSvc := service.NewEmailService(gormrepo.NewEmailRepo(db), mail.LogMailer{}, "http://localhost")
+	notifySvc := service.NewNotificationService(gormrepo.NewNotificationRepo(db), userRepo, emailSvc)
+	webhookSvc := service.NewWebhookService(gormrepo.NewWebhookRepo(db), sheetRepo, permissionRepo, workspaceRepo, orgRepo, userRepo, nil)
+	sheetSvc := service.NewSpreadsheetService(sheetRepo, permissionRepo, gormrepo.NewRevisionRepo(db), userRepo, workspaceRepo, orgRepo, folderRepo, searchRepo, gormrepo.NewCommentRepo(db), notifySvc, webhookSvc, time.Minute)
+	authSvc := service.NewAuthService(userRepo, gormrepo.NewSessionRepo(db), gormrepo.NewAPITokenRepo(db), gormrepo.NewPasswordResetRepo(db), sheetSvc, emailSvc, service.DefaultSessionPolicy, true)
+
+	authHandler := NewAuthHandler(authSvc, nil, "/")
+	sheetHandler := NewSpreadsheetHandler(sheetSvc)
+	folderHandler := NewFolderHandler(service.NewFolderService(folderRepo, workspaceRepo, orgRepo, sheetRepo))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/api/auth/login", authHandler.Login)
+	auth := r.Group("/api")
+	auth.Use(middleware.AuthRequired(authSvc))
+	{
+		auth.GET("/auth/me", authHandler.GetCurrentUser)
+
+		session := auth.Group("", middleware.SessionRequired())
+		session.POST("/auth/logout", authHandler.Logout)
+		session.POST("/auth/tokens", authHandler.CreateAPIToken)
+		session.GET("/auth/sessions", authHandler.ListSessions)
+		session.DELETE("/auth/sessions", authHandler.RevokeOtherSessions)
+		session.DELETE("/auth/sessions/:sessionId", authHandler.RevokeSession)
+		session.POST("/spreadsheets/:id/permissions", sheetHandler.Share)
+
+		auth.GET("/spreadsheets", sheetHandler.List)
+		auth.POST("/spreadsheets", sheetHandler.Create)
+		auth.GET("/spreadsheets/:id", sheetHandler.Get)
+		auth.PATCH("/spreadsheets/:id", sheetHandler.Update)
+		auth.DELETE("/spreadsheets/:id", sheetHandler.Delete)
+		auth.GET("/spreadsheets/:id/sheets/:sheet/values", sheetHandler.GetValues)
+		auth.PUT("/spreadsheets/:id/sheets/:sheet/values", sheetHandler.UpdateValues)
+		auth.POST("/spreadsheets/:id/sheets/:sheet/:action", sheetHandler.SheetAction)
+
+		auth.POST("/spreadsheets/:id/restore", sheetHandler.Restore)
+		auth.GET("/trash", sheetHandler.ListTrash)
+		auth.DELETE("/trash", sheetHandler.EmptyTrash)
+		auth.DELETE("/trash/:id", sheetHandler.DeleteForever)
+
+		auth.GET("/workspaces/:workspaceId/folders", folderHandler.List)
+		auth.POST("/workspaces/:workspaceId/folders", folderHandler.Create)
+		auth.PUT("/folders/:folderId/parent", folderHandler.Move)
+	}
+	return &testServer{t: t, router: r}
+}
+
+// do sends a request with body encoded as JSON, signed in with token
+// unless it is empty. header holds extra header names and values.
+func (s *testServer) do(token, method, path string, body any, header ...string) *httptest.ResponseRecorder {
+	s.t.Helper()
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req := httptest.NewRequest(method, path, r)
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	return w
+}
+
+// login signs in as email, creating the user, and returns the token.
+func (s *testServer) login(email string) string {
+	s.t.Helper()
+	w := s.do("", http.MethodPost, "/api/auth/login", gin.H{"email": email})
+	var resp struct{ Token string }
+	decode(s.t, w, http.StatusOK, &resp)
+	return resp.Token
+}
+
+// decode checks the status of a response and decodes its JSON body into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decode %s: %v", w.Body, err)
+	}
+}
+
+// createSpreadsheet creates a spreadsheet owned by the signed-in user and
+// returns its path.
+func (s *testServer) createSpreadsheet(token, title string) string {
+	s.t.Helper()
+	var sheet struct{ ID uint }
+	decode(s.t, s.do(token, http.MethodPost, "/api/spreadsheets", gin.H{"title": title}), http.StatusCreated, &sheet)
+	return "/api/spreadsheets/" + strconv.FormatUint(uint64(sheet.ID), 10)
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportSize limits uploaded workbooks. XLSX and ODS are compressed,
+// so this is far more than MaxImportCells worth of data.
+const maxImportSize = 20 << 20
+
+// importFormats are the file extensions Import accepts.
+var importFormats = map[string]bool{"xlsx": true, "ods": true, "csv": true}
+
+// Import creates a spreadsheet from an uploaded .xlsx, .ods or .csv file
+// (multipart field "file", optional "title" and "workspace_id").
+func (h *SpreadsheetHandler) Import(c *gin.Context) {
+	ownerID := c.MustGet("user_id").(uint)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is larger than 20 MB"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required"})
+		return
+	}
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(header.Filename), "."))
+	if !importFormats[format] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only .xlsx, .ods and .csv files can be imported"})
+		return
+	}
+
+	title := strings.TrimSpace(c.PostForm("title"))
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+
+	var workspaceID *uint
+	if v := c.PostForm("workspace_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid workspace ID"})
+			return
+		}
+		wsID := uint(id)
+		workspaceID = &wsID
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read the uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	sheet, warnings, err := h.sheets.Import(c.Request.Context(), ownerID, workspaceID, title, format, file)
+	if err != nil {
+		respondError(c, err, "Failed to import spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"spreadsheet": sheet, "warnings": warnings})
+}
@@ -0,0 +1,139 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/service"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat is how often an idle notification stream sends a comment
+// so proxies don't close it.
+const streamHeartbeat = 30 * time.Second
+
+type NotificationHandler struct {
+	notes *service.NotificationService
+}
+
+func NewNotificationHandler(notes *service.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notes: notes}
+}
+
+func (h *NotificationHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	var req domain.ListNotificationsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: unread must be true or false, before and limit must be numbers"})
+		return
+	}
+
+	page, err := h.notes.List(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to fetch notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *NotificationHandler) MarkRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseUintParam(c, "notificationId", "Invalid notification ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.notes.MarkRead(c.Request.Context(), userID, id); err != nil {
+		respondError(c, err, "Failed to update notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
+
+func (h *NotificationHandler) MarkAllRead(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	if err := h.notes.MarkAllRead(c.Request.Context(), userID); err != nil {
+		respondError(c, err, "Failed to update notifications")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "All notifications marked as read"})
+}
+
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	prefs, err := h.notes.Preferences(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	var req map[domain.NotificationType]bool
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: expected an object of notification types to true or false"})
+		return
+	}
+
+	prefs, err := h.notes.UpdatePreferences(c.Request.Context(), userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to update notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// Stream pushes the user's notifications as server-sent events: "unread"
+// with the unread count when the stream opens and whenever it changes, and
+// "notification" for each new notification.
+func (h *NotificationHandler) Stream(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	ctx := c.Request.Context()
+
+	events, cancel := h.notes.Subscribe(userID)
+	defer cancel()
+
+	unread, err := h.notes.UnreadCount(ctx, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch notifications")
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("unread", gin.H{"unread_count": unread})
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-events:
+			if ev.Notification != nil {
+				c.SSEvent("notification", ev.Notification)
+			}
+			c.SSEvent("unread", gin.H{"unread_count": ev.UnreadCount})
+		case <-heartbeat.C:
+			if _, err := c.Writer.WriteString(": ping\n\n"); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
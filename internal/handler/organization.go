@@ -0,0 +1,189 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OrganizationHandler struct {
+	orgs *service.OrganizationService
+}
+
+func NewOrganizationHandler(orgs *service.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{orgs: orgs}
+}
+
+func (h *OrganizationHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	orgs, err := h.orgs.ListOrganizations(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, orgs)
+}
+
+func (h *OrganizationHandler) Create(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	var req domain.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name is required"})
+		return
+	}
+
+	org, err := h.orgs.CreateOrganization(c.Request.Context(), userID, req.Name)
+	if err != nil {
+		respondError(c, err, "Failed to create organization")
+		return
+	}
+
+	c.JSON(http.StatusCreated, org)
+}
+
+func (h *OrganizationHandler) Get(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	org, err := h.orgs.GetOrganization(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func (h *OrganizationHandler) Update(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name is required"})
+		return
+	}
+
+	org, err := h.orgs.RenameOrganization(c.Request.Context(), orgID, userID, req.Name)
+	if err != nil {
+		respondError(c, err, "Failed to update organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, org)
+}
+
+func (h *OrganizationHandler) Delete(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.orgs.DeleteOrganization(c.Request.Context(), orgID, userID); err != nil {
+		respondError(c, err, "Failed to delete organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Organization deleted"})
+}
+
+// ── Members ──────────────────────────────────
+
+func (h *OrganizationHandler) ListMembers(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	members, err := h.orgs.ListMembers(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+func (h *OrganizationHandler) AddMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.AddMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email and role are required"})
+		return
+	}
+
+	member, err := h.orgs.AddMember(c.Request.Context(), orgID, userID, req.Email, req.Role)
+	if err != nil {
+		respondError(c, err, "Failed to add member")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+func (h *OrganizationHandler) UpdateMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+	targetID, err := parseUintParam(c, "userId", "Invalid user ID")
+	if err != nil {
+		return
+	}
+
+	var req domain.UpdateMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: role is required"})
+		return
+	}
+
+	member, err := h.orgs.UpdateMember(c.Request.Context(), orgID, userID, targetID, req.Role)
+	if err != nil {
+		respondError(c, err, "Failed to update member")
+		return
+	}
+
+	c.JSON(http.StatusOK, member)
+}
+
+func (h *OrganizationHandler) RemoveMember(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+	targetID, err := parseUintParam(c, "userId", "Invalid user ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.orgs.RemoveMember(c.Request.Context(), orgID, userID, targetID); err != nil {
+		respondError(c, err, "Failed to remove member")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+func parseOrgID(c *gin.Context) (uint, error) {
+	return parseUintParam(c, "orgId", "Invalid organization ID")
+}
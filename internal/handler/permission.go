@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"jaggle-grids/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SpreadsheetHandler) ListPermissions(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	perms, err := h.sheets.ListPermissions(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch permissions")
+		return
+	}
+
+	c.JSON(http.StatusOK, perms)
+}
+
+func (h *SpreadsheetHandler) Share(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.SharePermissionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: email and role are required"})
+		return
+	}
+
+	perm, err := h.sheets.Share(c.Request.Context(), id, userID, req.Email, req.Role)
+	if err != nil {
+		respondError(c, err, "Failed to share spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusOK, perm)
+}
+
+func (h *SpreadsheetHandler) Unshare(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	targetID, err := parseUintParam(c, "userId", "Invalid user ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.sheets.Unshare(c.Request.Context(), id, userID, targetID); err != nil {
+		respondError(c, err, "Failed to remove permission")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Permission removed"})
+}
+
+func (h *SpreadsheetHandler) RequestAccess(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	// The body is optional: an empty request asks for view access.
+	var req domain.AccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: message must be at most 500 characters"})
+		return
+	}
+
+	if err := h.sheets.RequestAccess(c.Request.Context(), id, userID, req); err != nil {
+		respondError(c, err, "Failed to request access")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Access requested"})
+}
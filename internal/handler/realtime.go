@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/middleware"
+	"jaggle-grids/internal/realtime"
+	"jaggle-grids/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+type RealtimeHandler struct {
+	hub      *realtime.Hub
+	sheets   *service.SpreadsheetService
+	upgrader websocket.Upgrader
+}
+
+// NewRealtimeHandler creates the WebSocket handler. Handshakes are accepted
+// from the server's own origin and from allowedOrigin ("*" allows any).
+func NewRealtimeHandler(hub *realtime.Hub, sheets *service.SpreadsheetService, allowedOrigin string) *RealtimeHandler {
+	return &RealtimeHandler{
+		hub:    hub,
+		sheets: sheets,
+		upgrader: websocket.Upgrader{
+			Subprotocols: []string{middleware.WebSocketProtocol},
+			CheckOrigin: func(r *http.Request) bool {
+				origin := r.Header.Get("Origin")
+				if origin == "" || allowedOrigin == "*" || origin == allowedOrigin {
+					return true
+				}
+				u, err := url.Parse(origin)
+				return err == nil && u.Host == r.Host
+			},
+		},
+	}
+}
+
+// Connect upgrades to a WebSocket and joins the spreadsheet's room.
+func (h *RealtimeHandler) Connect(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	user := c.MustGet("user").(*domain.User)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	sheet, err := h.sheets.Get(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to open spreadsheet")
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader has already written an HTTP error response.
+		log.Printf("realtime: upgrade failed: %v", err)
+		return
+	}
+	h.hub.Serve(conn, sheet.ID, user, sheet.Role)
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SpreadsheetHandler) ListRevisions(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	revs, err := h.sheets.ListRevisions(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, revs)
+}
+
+func (h *SpreadsheetHandler) GetRevision(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	revisionID, err := parseUintParam(c, "revisionId", "Invalid revision ID")
+	if err != nil {
+		return
+	}
+
+	rev, err := h.sheets.GetRevision(c.Request.Context(), id, userID, revisionID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, rev)
+}
+
+func (h *SpreadsheetHandler) RestoreRevision(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	revisionID, err := parseUintParam(c, "revisionId", "Invalid revision ID")
+	if err != nil {
+		return
+	}
+
+	sheet, err := h.sheets.RestoreRevision(c.Request.Context(), id, userID, revisionID)
+	if err != nil {
+		respondError(c, err, "Failed to restore revision")
+		return
+	}
+
+	c.Header("ETag", etag(sheet.Version))
+	c.JSON(http.StatusOK, sheet)
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Search finds spreadsheets by ?q= in their titles and cells. ?limit=
+// caps the number of spreadsheets returned.
+func (h *SpreadsheetHandler) Search(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	limit := 0
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	results, err := h.sheets.Search(c.Request.Context(), userID, c.Query("q"), limit)
+	if err != nil {
+		respondError(c, err, "Failed to search")
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	currentID := c.MustGet("session_id").(uint)
+
+	sessions, err := h.auth.ListSessions(c.Request.Context(), userID, currentID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseUintParam(c, "sessionId", "Invalid session ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.auth.RevokeSession(c.Request.Context(), userID, id); err != nil {
+		respondError(c, err, "Failed to revoke session")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// RevokeOtherSessions signs the user out on every other device.
+func (h *AuthHandler) RevokeOtherSessions(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	currentID := c.MustGet("session_id").(uint)
+
+	n, err := h.auth.RevokeOtherSessions(c.Request.Context(), userID, currentID)
+	if err != nil {
+		respondError(c, err, "Failed to revoke sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Other sessions revoked", "revoked": n})
+}
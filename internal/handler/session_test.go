@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type sessionInfo struct {
+	ID        uint
+	UserAgent string `json:"user_agent"`
+	Current   bool
+}
+
+func TestRevokeSessions(t *testing.T) {
+	s := newTestServer(t)
+	signIn := func(email, userAgent string) string {
+		t.Helper()
+		w := s.do("", http.MethodPost, "/api/auth/login", gin.H{"email": email}, "User-Agent", userAgent)
+		var resp struct{ Token string }
+		decode(t, w, http.StatusOK, &resp)
+		return resp.Token
+	}
+	laptop := signIn("ada@example.com", "laptop")
+	phone := signIn("ada@example.com", "phone")
+	tablet := signIn("ada@example.com", "tablet")
+	bob := signIn("bob@example.com", "bob's laptop")
+	signedIn := func(token string) bool {
+		t.Helper()
+		w := s.do(token, http.MethodGet, "/api/auth/me", nil)
+		if w.Code != http.StatusOK && w.Code != http.StatusUnauthorized {
+			t.Fatalf("me: %d %s", w.Code, w.Body)
+		}
+		return w.Code == http.StatusOK
+	}
+	list := func(token string) map[string]sessionInfo {
+		t.Helper()
+		var sessions []sessionInfo
+		decode(t, s.do(token, http.MethodGet, "/api/auth/sessions", nil), http.StatusOK, &sessions)
+		byAgent := map[string]sessionInfo{}
+		for _, sess := range sessions {
+			byAgent[sess.UserAgent] = sess
+		}
+		return byAgent
+	}
+
+	sessions := list(laptop)
+	if len(sessions) != 3 || !sessions["laptop"].Current || sessions["phone"].Current || sessions["tablet"].Current {
+		t.Fatalf("sessions = %+v", sessions)
+	}
+	bobs := list(bob)
+	if len(bobs) != 1 {
+		t.Fatalf("bob's sessions = %+v", bobs)
+	}
+
+	// Another user's session looks like one that doesn't exist.
+	path := func(sess sessionInfo) string {
+		return "/api/auth/sessions/" + strconv.FormatUint(uint64(sess.ID), 10)
+	}
+	if w := s.do(laptop, http.MethodDelete, path(bobs["bob's laptop"]), nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoked another user's session: %d", w.Code)
+	}
+	if w := s.do(laptop, http.MethodDelete, "/api/auth/sessions/999", nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoked a missing session: %d", w.Code)
+	}
+	if w := s.do(laptop, http.MethodDelete, "/api/auth/sessions/phone", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("malformed session ID: %d", w.Code)
+	}
+	if !signedIn(bob) {
+		t.Error("bob was signed out")
+	}
+
+	// A revoked session stops working at once.
+	if w := s.do(laptop, http.MethodDelete, path(sessions["phone"]), nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke: %d %s", w.Code, w.Body)
+	}
+	if signedIn(phone) {
+		t.Error("revoked session still works")
+	}
+	if w := s.do(laptop, http.MethodDelete, path(sessions["phone"]), nil); w.Code != http.StatusNotFound {
+		t.Errorf("revoked twice: %d", w.Code)
+	}
+
+	// Signing out everywhere else keeps the current session.
+	var resp struct{ Revoked int }
+	decode(t, s.do(laptop, http.MethodDelete, "/api/auth/sessions", nil), http.StatusOK, &resp)
+	if resp.Revoked != 1 {
+		t.Errorf("revoked %d others", resp.Revoked)
+	}
+	if signedIn(tablet) || !signedIn(laptop) || !signedIn(bob) {
+		t.Error("revoking other sessions signed out the wrong ones")
+	}
+	if sessions := list(laptop); len(sessions) != 1 || !sessions["laptop"].Current {
+		t.Errorf("sessions = %+v", sessions)
+	}
+
+	// The current session can be revoked too.
+	if w := s.do(laptop, http.MethodDelete, path(sessions["laptop"]), nil); w.Code != http.StatusOK {
+		t.Fatalf("revoke current: %d %s", w.Code, w.Body)
+	}
+	if signedIn(laptop) {
+		t.Error("revoked current session still works")
+	}
+}
+
+func TestSessionsNeedSession(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("ada@example.com")
+	var created struct{ Token string }
+	decode(t, s.do(token, http.MethodPost, "/api/auth/tokens", gin.H{"name": "script", "scope": "write"}), http.StatusCreated, &created)
+
+	// An API token can't list or revoke sessions.
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if w := s.do(created.Token, method, "/api/auth/sessions", nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s with an API token: %d", method, w.Code)
+		}
+	}
+	if w := s.do("", http.MethodGet, "/api/auth/sessions", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("signed out: %d", w.Code)
+	}
+
+	// Signing out revokes the session.
+	if w := s.do(token, http.MethodPost, "/api/auth/logout", nil); w.Code != http.StatusOK {
+		t.Fatalf("logout: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(token, http.MethodGet, "/api/auth/sessions", nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("after logout: %d", w.Code)
+	}
+}
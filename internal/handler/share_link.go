@@ -0,0 +1,238 @@
+package handler
+
+import (
+	"errors"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// linkPasswordHeader carries the password of a protected share link.
+const linkPasswordHeader = "X-Link-Password"
+
+type ShareLinkHandler struct {
+	links *service.ShareLinkService
+}
+
+func NewShareLinkHandler(links *service.ShareLinkService) *ShareLinkHandler {
+	return &ShareLinkHandler{links: links}
+}
+
+func (h *ShareLinkHandler) List(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	links, err := h.links.ListLinks(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, links)
+}
+
+func (h *ShareLinkHandler) Create(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: password must be 4 to 72 characters"})
+		return
+	}
+
+	resp, err := h.links.CreateLink(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create share link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *ShareLinkHandler) Revoke(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+	linkID, err := parseUintParam(c, "linkId", "Invalid share link ID")
+	if err != nil {
+		return
+	}
+
+	if err := h.links.RevokeLink(c.Request.Context(), id, userID, linkID); err != nil {
+		respondError(c, err, "Failed to revoke share link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+// Open serves the spreadsheet behind a share link to anyone holding it.
+// It is the only public route for share links and never writes to the
+// spreadsheet.
+func (h *ShareLinkHandler) Open(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Robots-Tag", "noindex")
+
+	shared, err := h.links.Open(c.Request.Context(), linkAccess(c))
+	if err != nil {
+		respondLinkError(c, err, "Failed to open share link")
+		return
+	}
+
+	c.JSON(http.StatusOK, shared)
+}
+
+// The comment routes of a commenter link mirror those of a spreadsheet,
+// with the link token in place of the spreadsheet ID.
+
+func (h *ShareLinkHandler) ListComments(c *gin.Context) {
+	var req domain.ListCommentsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: resolved must be true or false"})
+		return
+	}
+
+	threads, err := h.links.ListComments(c.Request.Context(), linkAccess(c), req)
+	if err != nil {
+		respondLinkError(c, err, "Failed to fetch comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, threads)
+}
+
+func (h *ShareLinkHandler) CreateComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	var req domain.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: sheet, anchor and body are required"})
+		return
+	}
+
+	thread, err := h.links.CreateComment(c.Request.Context(), linkAccess(c), userID, req)
+	if err != nil {
+		respondLinkError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, thread)
+}
+
+func (h *ShareLinkHandler) ReplyComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CommentBodyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: body is required"})
+		return
+	}
+
+	reply, err := h.links.ReplyComment(c.Request.Context(), linkAccess(c), userID, commentID, req.Body)
+	if err != nil {
+		respondLinkError(c, err, "Failed to reply to comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+func (h *ShareLinkHandler) EditComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CommentBodyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: body is required"})
+		return
+	}
+
+	comment, err := h.links.EditComment(c.Request.Context(), linkAccess(c), userID, commentID, req.Body)
+	if err != nil {
+		respondLinkError(c, err, "Failed to edit comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func (h *ShareLinkHandler) DeleteComment(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.links.DeleteComment(c.Request.Context(), linkAccess(c), userID, commentID); err != nil {
+		respondLinkError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment deleted"})
+}
+
+func (h *ShareLinkHandler) ResolveComment(c *gin.Context) {
+	h.setResolved(c, true)
+}
+
+func (h *ShareLinkHandler) ReopenComment(c *gin.Context) {
+	h.setResolved(c, false)
+}
+
+func (h *ShareLinkHandler) setResolved(c *gin.Context, resolved bool) {
+	userID := c.MustGet("user_id").(uint)
+	commentID, err := parseCommentID(c)
+	if err != nil {
+		return
+	}
+
+	thread, err := h.links.ResolveComment(c.Request.Context(), linkAccess(c), userID, commentID, resolved)
+	if err != nil {
+		respondLinkError(c, err, "Failed to update comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// linkAccess reads the share link a request goes through.
+func linkAccess(c *gin.Context) domain.LinkAccess {
+	return domain.LinkAccess{
+		Token:    c.Param("token"),
+		Password: c.GetHeader(linkPasswordHeader),
+		IP:       c.ClientIP(),
+	}
+}
+
+// respondLinkError is respondError, plus the answer a protected link gives
+// when its password is missing or wrong: 401, or 403 for signed-in users,
+// whose clients take a 401 to mean their session ended.
+func respondLinkError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, domain.ErrPasswordRequired) {
+		status := http.StatusUnauthorized
+		if _, signedIn := c.Get("user_id"); signedIn {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, gin.H{"error": capitalize(err.Error()), "password_required": true})
+		return
+	}
+	respondError(c, err, fallback)
+}
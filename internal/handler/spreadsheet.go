@@ -5,6 +5,7 @@ import (
 	"jaggle-grids/internal/service"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,15 +19,21 @@ func NewSpreadsheetHandler(sheets *service.SpreadsheetService) *SpreadsheetHandl
 }
 
 func (h *SpreadsheetHandler) List(c *gin.Context) {
-	ownerID := c.MustGet("user_id").(uint)
+	userID := c.MustGet("user_id").(uint)
+
+	var req domain.ListSpreadsheetsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid query: owner_id, workspace_id, folder_id and limit must be numbers"})
+		return
+	}
 
-	items, err := h.sheets.List(c.Request.Context(), ownerID)
+	page, err := h.sheets.List(c.Request.Context(), userID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch spreadsheets"})
+		respondError(c, err, "Failed to fetch spreadsheets")
 		return
 	}
 
-	c.JSON(http.StatusOK, items)
+	c.JSON(http.StatusOK, page)
 }
 
 func (h *SpreadsheetHandler) Create(c *gin.Context) {
@@ -38,9 +45,9 @@ func (h *SpreadsheetHandler) Create(c *gin.Context) {
 		return
 	}
 
-	sheet, err := h.sheets.Create(c.Request.Context(), req.Title, ownerID)
+	sheet, err := h.sheets.Create(c.Request.Context(), req.Title, ownerID, req.WorkspaceID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create spreadsheet"})
+		respondError(c, err, "Failed to create spreadsheet")
 		return
 	}
 
@@ -48,23 +55,24 @@ func (h *SpreadsheetHandler) Create(c *gin.Context) {
 }
 
 func (h *SpreadsheetHandler) Get(c *gin.Context) {
-	ownerID := c.MustGet("user_id").(uint)
+	userID := c.MustGet("user_id").(uint)
 	id, err := parseID(c)
 	if err != nil {
 		return
 	}
 
-	sheet, err := h.sheets.Get(c.Request.Context(), id, ownerID)
+	sheet, err := h.sheets.Get(c.Request.Context(), id, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Spreadsheet not found"})
+		respondError(c, err, "Failed to fetch spreadsheet")
 		return
 	}
 
+	c.Header("ETag", etag(sheet.Version))
 	c.JSON(http.StatusOK, sheet)
 }
 
 func (h *SpreadsheetHandler) Update(c *gin.Context) {
-	ownerID := c.MustGet("user_id").(uint)
+	userID := c.MustGet("user_id").(uint)
 	id, err := parseID(c)
 	if err != nil {
 		return
@@ -76,34 +84,124 @@ func (h *SpreadsheetHandler) Update(c *gin.Context) {
 		return
 	}
 
-	sheet, err := h.sheets.Update(c.Request.Context(), id, ownerID, req.Title, req.Data)
+	expected, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	sheet, err := h.sheets.Update(c.Request.Context(), id, userID, req.Title, req.Data, req.Structure, expected)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Spreadsheet not found"})
+		respondError(c, err, "Failed to update spreadsheet")
 		return
 	}
 
+	c.Header("ETag", etag(sheet.Version))
 	c.JSON(http.StatusOK, sheet)
 }
 
 func (h *SpreadsheetHandler) Delete(c *gin.Context) {
-	ownerID := c.MustGet("user_id").(uint)
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.sheets.Delete(c.Request.Context(), id, userID); err != nil {
+		respondError(c, err, "Failed to delete spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Spreadsheet moved to trash"})
+}
+
+// Move puts a spreadsheet in another workspace.
+func (h *SpreadsheetHandler) Move(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.MoveSpreadsheetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: workspace_id is required"})
+		return
+	}
+
+	sheet, err := h.sheets.Move(c.Request.Context(), id, userID, req.WorkspaceID)
+	if err != nil {
+		respondError(c, err, "Failed to move spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusOK, sheet)
+}
+
+// File puts a spreadsheet in a folder, or at the top level for a null
+// folder_id.
+func (h *SpreadsheetHandler) File(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
 	id, err := parseID(c)
 	if err != nil {
 		return
 	}
 
-	if err := h.sheets.Delete(c.Request.Context(), id, ownerID); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Spreadsheet not found"})
+	var req domain.FileSpreadsheetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Spreadsheet deleted"})
+	sheet, err := h.sheets.File(c.Request.Context(), id, userID, req.FolderID)
+	if err != nil {
+		respondError(c, err, "Failed to move spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusOK, sheet)
+}
+
+// etag formats a spreadsheet version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// expectedVersion returns the version a write is based on: the If-Match
+// header if present, otherwise the one from the body. A malformed header
+// is answered with 412 and ok=false.
+func expectedVersion(c *gin.Context, body *int) (*int, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" || header == "*" {
+		return body, true
+	}
+	v, ok := parseETag(header)
+	if !ok {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": "Malformed If-Match header"})
+		return nil, false
+	}
+	return &v, true
+}
+
+// parseETag accepts a single strong or weak entity tag produced by etag.
+func parseETag(header string) (int, bool) {
+	tag := strings.TrimPrefix(strings.TrimSpace(header), "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return 0, false
+	}
+	v, err := strconv.Atoi(tag[1 : len(tag)-1])
+	return v, err == nil
 }
 
 func parseID(c *gin.Context) (uint, error) {
-	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	return parseUintParam(c, "id", "Invalid spreadsheet ID")
+}
+
+// parseUintParam reads a numeric path parameter, writing a 400 with msg
+// when it is malformed.
+func parseUintParam(c *gin.Context, name, msg string) (uint, error) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid spreadsheet ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 		return 0, err
 	}
 	return uint(id), nil
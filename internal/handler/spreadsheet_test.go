@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type versioned struct {
+	Version int
+}
+
+func TestSpreadsheetETags(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("ada@example.com")
+	path := s.createSpreadsheet(token, "Budget")
+
+	w := s.do(token, http.MethodGet, path, nil)
+	var sheet versioned
+	decode(t, w, http.StatusOK, &sheet)
+	tag := w.Header().Get("ETag")
+	if tag != `"`+strconv.Itoa(sheet.Version)+`"` {
+		t.Fatalf("ETag = %q for version %d", tag, sheet.Version)
+	}
+
+	// A save based on the current version goes through and returns the
+	// next tag.
+	w = s.do(token, http.MethodPatch, path, gin.H{"data": "v1"}, "If-Match", tag)
+	var saved versioned
+	decode(t, w, http.StatusOK, &saved)
+	if saved.Version != sheet.Version+1 || w.Header().Get("ETag") != `"`+strconv.Itoa(saved.Version)+`"` {
+		t.Fatalf("saved version %d with ETag %q", saved.Version, w.Header().Get("ETag"))
+	}
+
+	// Saves based on the old version are refused with the current one.
+	for name, req := range map[string]struct {
+		body   gin.H
+		header []string
+	}{
+		"If-Match":      {gin.H{"data": "stale"}, []string{"If-Match", tag}},
+		"weak If-Match": {gin.H{"data": "stale"}, []string{"If-Match", "W/" + tag}},
+		"body version":  {gin.H{"data": "stale", "version": sheet.Version}, nil},
+		"title only":    {gin.H{"title": "Stale"}, []string{"If-Match", tag}},
+		"header wins":   {gin.H{"data": "stale", "version": saved.Version}, []string{"If-Match", tag}},
+	} {
+		w := s.do(token, http.MethodPatch, path, req.body, req.header...)
+		var conflict versioned
+		decode(t, w, http.StatusConflict, &conflict)
+		if conflict.Version != saved.Version || w.Header().Get("ETag") != `"`+strconv.Itoa(saved.Version)+`"` {
+			t.Errorf("%s: conflict version %d, ETag %q", name, conflict.Version, w.Header().Get("ETag"))
+		}
+	}
+
+	for _, header := range []string{"1", `"one"`, `"1", "2"`} {
+		if w := s.do(token, http.MethodPatch, path, gin.H{"data": "x"}, "If-Match", header); w.Code != http.StatusPreconditionFailed {
+			t.Errorf("If-Match %s: status %d, want 412", header, w.Code)
+		}
+	}
+
+	// "*" matches any version.
+	w = s.do(token, http.MethodPatch, path, gin.H{"data": "v2"}, "If-Match", "*")
+	decode(t, w, http.StatusOK, &saved)
+
+	w = s.do(token, http.MethodGet, path, nil)
+	var got struct {
+		Data    string
+		Version int
+	}
+	decode(t, w, http.StatusOK, &got)
+	if got.Data != "v2" || got.Version != sheet.Version+2 {
+		t.Errorf("stored %+v after two saves from version %d", got, sheet.Version)
+	}
+}
+
+type listPage struct {
+	Items      []listItem
+	Total      int
+	NextCursor string `json:"next_cursor"`
+}
+
+// listPages follows next_cursor from the first page of query, calling
+// between after each page but the last, and returns every item.
+func (s *testServer) listPages(token, query string, between func()) []listItem {
+	s.t.Helper()
+	var items []listItem
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			s.t.Fatal("paging doesn't end")
+		}
+		var page listPage
+		decode(s.t, s.do(token, http.MethodGet, "/api/spreadsheets?"+query+"&cursor="+cursor, nil), http.StatusOK, &page)
+		items = append(items, page.Items...)
+		if page.NextCursor == "" {
+			return items
+		}
+		cursor = page.NextCursor
+		if between != nil {
+			between()
+		}
+	}
+}
+
+func TestListPaging(t *testing.T) {
+	s := newTestServer(t)
+	token := s.login("ada@example.com")
+	for _, title := range []string{"Same", "Z", "Same", "A", "Same", "Same", "Same"} {
+		s.createSpreadsheet(token, title)
+	}
+
+	// Rows with the same title are ordered by ID, so pages don't overlap
+	// or skip any.
+	items := s.listPages(token, "sort=title&limit=2", nil)
+	if got := joinTitles(items); got != "A,Same,Same,Same,Same,Same,Z" {
+		t.Fatalf("titles = %s", got)
+	}
+	for i := 2; i < 6; i++ {
+		if items[i].ID <= items[i-1].ID {
+			t.Errorf("ties are out of order: %+v", items)
+		}
+	}
+	desc := s.listPages(token, "sort=title&order=desc&limit=3", nil)
+	for i := range desc {
+		if desc[i] != items[len(items)-1-i] {
+			t.Fatalf("descending = %+v, want the reverse of %+v", desc, items)
+		}
+	}
+
+	// Spreadsheets created while paging come up if they sort after the
+	// cursor, and the rest are listed once.
+	var created bool
+	items = s.listPages(token, "sort=title&limit=3", func() {
+		if !created {
+			s.createSpreadsheet(token, "AA")   // before the cursor
+			s.createSpreadsheet(token, "Same") // after it, by ID
+			created = true
+		}
+	})
+	if got := joinTitles(items); got != "A,Same,Same,Same,Same,Same,Same,Z" {
+		t.Errorf("titles = %s", got)
+	}
+
+	// A spreadsheet saved while paging by last update moves to the top,
+	// before the cursor, and is not listed again.
+	all := s.listPages(token, "limit=50", nil)
+	oldest := all[len(all)-1]
+	items = s.listPages(token, "limit=2", func() {
+		path := "/api/spreadsheets/" + strconv.FormatUint(uint64(oldest.ID), 10)
+		if w := s.do(token, http.MethodPatch, path, gin.H{"data": "saved"}); w.Code != http.StatusOK {
+			t.Fatalf("save: %d %s", w.Code, w.Body)
+		}
+	})
+	if len(items) != len(all)-1 || !slices.Equal(items, all[:len(all)-1]) {
+		t.Errorf("listed %+v while saving %d, want %+v", items, oldest.ID, all[:len(all)-1])
+	}
+
+	var page listPage
+	decode(t, s.do(token, http.MethodGet, "/api/spreadsheets?sort=title&limit=4", nil), http.StatusOK, &page)
+	if page.Total != 9 || len(page.Items) != 4 || page.NextCursor == "" {
+		t.Errorf("first page = %+v", page)
+	}
+	for name, query := range map[string]string{
+		"cursor from another sort":  "sort=created_at&cursor=" + page.NextCursor,
+		"cursor from another order": "sort=title&order=desc&cursor=" + page.NextCursor,
+		"cursor with default order": "cursor=" + page.NextCursor,
+		"garbage cursor":            "sort=title&cursor=abc",
+		"limit too big":             "limit=201",
+		"limit not a number":        "limit=ten",
+		"unknown sort":              "sort=size",
+		"unknown view":              "view=mine",
+	} {
+		if w := s.do(token, http.MethodGet, "/api/spreadsheets?"+query, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", name, w.Code)
+		}
+	}
+}
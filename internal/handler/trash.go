@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (h *SpreadsheetHandler) ListTrash(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	items, err := h.sheets.ListTrash(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *SpreadsheetHandler) Restore(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	sheet, err := h.sheets.Restore(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to restore spreadsheet")
+		return
+	}
+
+	c.Header("ETag", etag(sheet.Version))
+	c.JSON(http.StatusOK, sheet)
+}
+
+func (h *SpreadsheetHandler) DeleteForever(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.sheets.DeleteForever(c.Request.Context(), id, userID); err != nil {
+		respondError(c, err, "Failed to delete spreadsheet")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Spreadsheet permanently deleted"})
+}
+
+func (h *SpreadsheetHandler) EmptyTrash(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	deleted, err := h.sheets.EmptyTrash(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to empty trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Trash emptied", "deleted": deleted})
+}
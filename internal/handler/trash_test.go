@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type listItem struct {
+	ID    uint
+	Title string
+}
+
+// titles lists the spreadsheets the user sees on their home page.
+func (s *testServer) titles(token string) string {
+	s.t.Helper()
+	var page struct{ Items []listItem }
+	decode(s.t, s.do(token, http.MethodGet, "/api/spreadsheets", nil), http.StatusOK, &page)
+	return joinTitles(page.Items)
+}
+
+// trash lists the spreadsheets in the user's trash.
+func (s *testServer) trash(token string) string {
+	s.t.Helper()
+	var items []listItem
+	decode(s.t, s.do(token, http.MethodGet, "/api/trash", nil), http.StatusOK, &items)
+	return joinTitles(items)
+}
+
+func joinTitles(items []listItem) string {
+	titles := make([]string, len(items))
+	for i, it := range items {
+		titles[i] = it.Title
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestTrash(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.login("ada@example.com")
+	editor := s.login("bob@example.com")
+	budget := s.createSpreadsheet(owner, "Budget")
+	s.createSpreadsheet(owner, "Notes")
+	id := strings.TrimPrefix(budget, "/api/spreadsheets/")
+	w := s.do(owner, http.MethodPost, budget+"/permissions", gin.H{"email": "bob@example.com", "role": "editor"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("share: %d %s", w.Code, w.Body)
+	}
+
+	// Only the owner can move a spreadsheet to the trash.
+	if w := s.do(editor, http.MethodDelete, budget, nil); w.Code != http.StatusForbidden {
+		t.Fatalf("editor deleted: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(owner, http.MethodDelete, budget, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete: %d %s", w.Code, w.Body)
+	}
+
+	// A trashed spreadsheet is gone for everyone but shows in the owner's
+	// trash.
+	if got := s.titles(owner); got != "Notes" {
+		t.Errorf("owner lists %q", got)
+	}
+	if got := s.trash(owner); got != "Budget" {
+		t.Errorf("trash = %q", got)
+	}
+	if got := s.trash(editor); got != "" {
+		t.Errorf("editor's trash = %q", got)
+	}
+	for _, token := range []string{owner, editor} {
+		if w := s.do(token, http.MethodGet, budget, nil); w.Code != http.StatusNotFound {
+			t.Errorf("get from trash: %d", w.Code)
+		}
+	}
+	if w := s.do(owner, http.MethodPatch, budget, gin.H{"data": "x"}); w.Code != http.StatusNotFound {
+		t.Errorf("saved to a trashed spreadsheet: %d", w.Code)
+	}
+	if w := s.do(owner, http.MethodDelete, budget, nil); w.Code != http.StatusNotFound {
+		t.Errorf("deleted twice: %d", w.Code)
+	}
+
+	// Only the owner can restore or delete it for good, and only from the
+	// trash.
+	if w := s.do(editor, http.MethodPost, budget+"/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("editor restored: %d", w.Code)
+	}
+	if w := s.do(editor, http.MethodDelete, "/api/trash/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("editor deleted forever: %d", w.Code)
+	}
+	w = s.do(owner, http.MethodPost, budget+"/restore", nil)
+	var restored versioned
+	decode(t, w, http.StatusOK, &restored)
+	if w.Header().Get("ETag") == "" {
+		t.Error("restore sent no ETag")
+	}
+	if w := s.do(owner, http.MethodPost, budget+"/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("restored twice: %d", w.Code)
+	}
+	if w := s.do(owner, http.MethodDelete, "/api/trash/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("deleted forever from outside the trash: %d", w.Code)
+	}
+
+	// Restoring brings back the editor's access.
+	if w := s.do(editor, http.MethodPatch, budget, gin.H{"data": "edited"}); w.Code != http.StatusOK {
+		t.Errorf("editor after restore: %d %s", w.Code, w.Body)
+	}
+
+	s.do(owner, http.MethodDelete, budget, nil)
+	if w := s.do(owner, http.MethodDelete, "/api/trash/"+id, nil); w.Code != http.StatusOK {
+		t.Fatalf("delete forever: %d %s", w.Code, w.Body)
+	}
+	if w := s.do(owner, http.MethodPost, budget+"/restore", nil); w.Code != http.StatusNotFound {
+		t.Errorf("restored after deleting forever: %d", w.Code)
+	}
+	if got := s.trash(owner); got != "" {
+		t.Errorf("trash = %q after deleting forever", got)
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	s := newTestServer(t)
+	ada := s.login("ada@example.com")
+	bob := s.login("bob@example.com")
+	for _, title := range []string{"One", "Two"} {
+		s.do(ada, http.MethodDelete, s.createSpreadsheet(ada, title), nil)
+	}
+	s.do(bob, http.MethodDelete, s.createSpreadsheet(bob, "Bob's"), nil)
+
+	var resp struct{ Deleted int }
+	decode(t, s.do(ada, http.MethodDelete, "/api/trash", nil), http.StatusOK, &resp)
+	if resp.Deleted != 2 {
+		t.Errorf("deleted %d", resp.Deleted)
+	}
+	if got := s.trash(ada); got != "" {
+		t.Errorf("trash = %q after emptying", got)
+	}
+	if got := s.trash(bob); got != "Bob's" {
+		t.Errorf("another user's trash = %q", got)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetValues returns ?range= of a sheet (default: its used range) as rows of
+// typed values. ?render=formula returns formulas instead of their values.
+func (h *SpreadsheetHandler) GetValues(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var formulas bool
+	switch c.DefaultQuery("render", "value") {
+	case "value":
+	case "formula":
+		formulas = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Render must be value or formula"})
+		return
+	}
+
+	values, err := h.sheets.Values(c.Request.Context(), id, userID, c.Param("sheet"), c.Query("range"), formulas)
+	if err != nil {
+		respondError(c, err, "Failed to read values")
+		return
+	}
+	c.JSON(http.StatusOK, values)
+}
+
+// UpdateValues writes rows of values starting at the top-left cell of
+// ?range=.
+func (h *SpreadsheetHandler) UpdateValues(c *gin.Context) {
+	h.writeValues(c, false)
+}
+
+// SheetAction handles POST .../sheets/:sheet/values:append.
+func (h *SpreadsheetHandler) SheetAction(c *gin.Context) {
+	if c.Param("action") != "values:append" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown action " + strconv.Quote(c.Param("action"))})
+		return
+	}
+	h.writeValues(c, true)
+}
+
+func (h *SpreadsheetHandler) writeValues(c *gin.Context, appendRows bool) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.ValuesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	expected, ok := expectedVersion(c, req.Version)
+	if !ok {
+		return
+	}
+
+	rng := c.Query("range")
+	var resp *domain.UpdateValuesResponse
+	if appendRows {
+		resp, err = h.sheets.AppendValues(c.Request.Context(), id, userID, c.Param("sheet"), rng, req.Values, expected)
+	} else {
+		if rng == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Range is required"})
+			return
+		}
+		resp, err = h.sheets.UpdateValues(c.Request.Context(), id, userID, c.Param("sheet"), rng, req.Values, expected)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to write values")
+		return
+	}
+
+	c.Header("ETag", etag(resp.Version))
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type valueRange struct {
+	Range  string
+	Values [][]any
+}
+
+type updateValues struct {
+	Range        string
+	UpdatedCells int `json:"updated_cells"`
+	Version      int
+}
+
+func TestValues(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.login("ada@example.com")
+	s.login("bob@example.com")
+	sheet := s.createSpreadsheet(owner, "Budget")
+	values := sheet + "/sheets/Sheet1/values"
+
+	w := s.do(owner, http.MethodPut, values+"?range=b2", gin.H{"values": [][]any{
+		{1, "two", true, nil},
+		{"=B2*10", "#N/A", "'=text", "42"},
+	}})
+	var updated updateValues
+	decode(t, w, http.StatusOK, &updated)
+	if updated.Range != "Sheet1!B2:E3" || updated.UpdatedCells != 8 || w.Header().Get("ETag") == "" {
+		t.Errorf("update = %+v, ETag %q", updated, w.Header().Get("ETag"))
+	}
+
+	get := func(query string) [][]any {
+		t.Helper()
+		var got valueRange
+		decode(t, s.do(owner, http.MethodGet, values+query, nil), http.StatusOK, &got)
+		return got.Values
+	}
+	check := func(query string, want [][]any) {
+		t.Helper()
+		if got := get(query); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %#v, want %#v", query, got, want)
+		}
+	}
+	check("?range=B2:E3", [][]any{
+		{1.0, "two", true, nil},
+		{10.0, "#N/A", "=text", "42"},
+	})
+	check("?range=B2:E3&render=formula", [][]any{
+		{1.0, "two", true, nil},
+		{"=B2*10", "#N/A", "'=text", "42"},
+	})
+	// Without a range the used range is read from A1.
+	check("", [][]any{
+		{nil, nil, nil, nil, nil},
+		{nil, 1.0, "two", true, nil},
+		{nil, 10.0, "#N/A", "=text", "42"},
+	})
+
+	// Writing a cell recalculates the formulas that depend on it, and a
+	// multi-cell range must hold the values.
+	decode(t, s.do(owner, http.MethodPut, values+"?range=B2:C2", gin.H{"values": [][]any{{3}}}), http.StatusOK, &updated)
+	check("?range=B3", [][]any{{30.0}})
+
+	// Appending writes below the last row with content in the columns.
+	w = s.do(owner, http.MethodPost, values+":append?range=B1:C1", gin.H{"values": [][]any{{5, 6}, {7}}})
+	decode(t, w, http.StatusOK, &updated)
+	if updated.Range != "Sheet1!B4:C5" || updated.UpdatedCells != 3 {
+		t.Errorf("append = %+v", updated)
+	}
+	w = s.do(owner, http.MethodPost, values+":append?range=G1", gin.H{"values": [][]any{{"first"}}})
+	decode(t, w, http.StatusOK, &updated)
+	if updated.Range != "Sheet1!G1" {
+		t.Errorf("append to an empty column = %+v", updated)
+	}
+	check("?range=B4:C5", [][]any{{5.0, 6.0}, {7.0, nil}})
+
+	for name, req := range map[string]struct {
+		method, path string
+		body         any
+		status       int
+	}{
+		"invalid range":       {http.MethodGet, values + "?range=A%2B1", nil, http.StatusBadRequest},
+		"column past XFD":     {http.MethodPut, values + "?range=XFE1", gin.H{"values": [][]any{{1}}}, http.StatusBadRequest},
+		"too many cells":      {http.MethodGet, values + "?range=A1:Z10000", nil, http.StatusBadRequest},
+		"no range":            {http.MethodPut, values, gin.H{"values": [][]any{{1}}}, http.StatusBadRequest},
+		"too big for range":   {http.MethodPut, values + "?range=A1:B1", gin.H{"values": [][]any{{1}, {2}}}, http.StatusBadRequest},
+		"past the last row":   {http.MethodPut, values + "?range=A1048576", gin.H{"values": [][]any{{1}, {2}}}, http.StatusBadRequest},
+		"no values":           {http.MethodPut, values + "?range=A1", gin.H{"values": [][]any{}}, http.StatusBadRequest},
+		"invalid formula":     {http.MethodPut, values + "?range=A1", gin.H{"values": [][]any{{"=SUM("}}}, http.StatusBadRequest},
+		"object value":        {http.MethodPut, values + "?range=A1", gin.H{"values": [][]any{{gin.H{"a": 1}}}}, http.StatusBadRequest},
+		"unknown render":      {http.MethodGet, values + "?render=html", nil, http.StatusBadRequest},
+		"unknown sheet":       {http.MethodGet, sheet + "/sheets/Nope/values", nil, http.StatusNotFound},
+		"write unknown sheet": {http.MethodPut, sheet + "/sheets/Nope/values?range=A1", gin.H{"values": [][]any{{1}}}, http.StatusNotFound},
+		"unknown action":      {http.MethodPost, values + ":clear", gin.H{"values": [][]any{{1}}}, http.StatusNotFound},
+	} {
+		if w := s.do(owner, req.method, req.path, req.body); w.Code != req.status {
+			t.Errorf("%s: status %d, want %d: %s", name, w.Code, req.status, w.Body)
+		}
+	}
+	check("?range=A1", [][]any{{nil}})
+}
+
+func TestValuesAccess(t *testing.T) {
+	s := newTestServer(t)
+	owner := s.login("ada@example.com")
+	viewer := s.login("bob@example.com")
+	stranger := s.login("eve@example.com")
+	sheet := s.createSpreadsheet(owner, "Budget")
+	values := sheet + "/sheets/Sheet1/values"
+	if w := s.do(owner, http.MethodPost, sheet+"/permissions", gin.H{"email": "bob@example.com", "role": "viewer"}); w.Code != http.StatusOK {
+		t.Fatalf("share: %d %s", w.Code, w.Body)
+	}
+	write := gin.H{"values": [][]any{{1}}}
+
+	var updated updateValues
+	decode(t, s.do(owner, http.MethodPut, values+"?range=A1", write), http.StatusOK, &updated)
+
+	if w := s.do(viewer, http.MethodGet, values, nil); w.Code != http.StatusOK {
+		t.Errorf("viewer read: %d", w.Code)
+	}
+	if w := s.do(viewer, http.MethodPut, values+"?range=A1", write); w.Code != http.StatusForbidden {
+		t.Errorf("viewer wrote: %d", w.Code)
+	}
+	if w := s.do(viewer, http.MethodPost, values+":append", write); w.Code != http.StatusForbidden {
+		t.Errorf("viewer appended: %d", w.Code)
+	}
+	if w := s.do(stranger, http.MethodGet, values, nil); w.Code != http.StatusNotFound {
+		t.Errorf("stranger read: %d", w.Code)
+	}
+
+	// Writes check If-Match like saves from the editor.
+	stale := etag(updated.Version - 1)
+	w := s.do(owner, http.MethodPut, values+"?range=A1", write, "If-Match", stale)
+	var conflict versioned
+	decode(t, w, http.StatusConflict, &conflict)
+	if conflict.Version != updated.Version {
+		t.Errorf("conflict version %d, want %d", conflict.Version, updated.Version)
+	}
+	w = s.do(owner, http.MethodPost, values+":append", gin.H{"values": [][]any{{2}}, "version": updated.Version})
+	decode(t, w, http.StatusOK, &updated)
+	if updated.Range != "Sheet1!A2" {
+		t.Errorf("append = %+v", updated)
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WebhookHandler struct {
+	hooks *service.WebhookService
+}
+
+func NewWebhookHandler(hooks *service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{hooks: hooks}
+}
+
+func (h *WebhookHandler) ListSpreadsheetWebhooks(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	hooks, err := h.hooks.ListSpreadsheetWebhooks(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) CreateSpreadsheetWebhook(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: url and events are required, secret must be 16-256 characters"})
+		return
+	}
+
+	resp, err := h.hooks.CreateSpreadsheetWebhook(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *WebhookHandler) ListWorkspaceWebhooks(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	hooks, err := h.hooks.ListWorkspaceWebhooks(c.Request.Context(), workspaceID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *WebhookHandler) CreateWorkspaceWebhook(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	workspaceID, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: url and events are required, secret must be 16-256 characters"})
+		return
+	}
+
+	resp, err := h.hooks.CreateWorkspaceWebhook(c.Request.Context(), workspaceID, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, resp)
+}
+
+func (h *WebhookHandler) Get(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWebhookID(c)
+	if err != nil {
+		return
+	}
+
+	hook, err := h.hooks.GetWebhook(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) Update(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWebhookID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: events must not be empty, active must be true or false"})
+		return
+	}
+
+	hook, err := h.hooks.UpdateWebhook(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, hook)
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWebhookID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.hooks.DeleteWebhook(c.Request.Context(), id, userID); err != nil {
+		respondError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWebhookID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.ListDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: before and limit must be numbers"})
+		return
+	}
+
+	deliveries, err := h.hooks.ListDeliveries(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to fetch deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWebhookID(c)
+	if err != nil {
+		return
+	}
+	deliveryID, err := parseUintParam(c, "deliveryId", "Invalid delivery ID")
+	if err != nil {
+		return
+	}
+
+	delivery, err := h.hooks.Redeliver(c.Request.Context(), id, userID, deliveryID)
+	if err != nil {
+		respondError(c, err, "Failed to redeliver")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+func parseWebhookID(c *gin.Context) (uint, error) {
+	return parseUintParam(c, "webhookId", "Invalid webhook ID")
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"jaggle-grids/internal/domain"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListWorkspaces returns the caller's personal workspace and those of
+// their organizations.
+func (h *OrganizationHandler) ListWorkspaces(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+
+	workspaces, err := h.orgs.ListWorkspaces(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch workspaces")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (h *OrganizationHandler) ListOrganizationWorkspaces(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	workspaces, err := h.orgs.ListOrganizationWorkspaces(c.Request.Context(), orgID, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch workspaces")
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (h *OrganizationHandler) CreateWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	orgID, err := parseOrgID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request: name is required"})
+		return
+	}
+
+	ws, err := h.orgs.CreateWorkspace(c.Request.Context(), orgID, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to create workspace")
+		return
+	}
+
+	c.JSON(http.StatusCreated, ws)
+}
+
+func (h *OrganizationHandler) UpdateWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	var req domain.UpdateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	ws, err := h.orgs.UpdateWorkspace(c.Request.Context(), id, userID, req)
+	if err != nil {
+		respondError(c, err, "Failed to update workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, ws)
+}
+
+func (h *OrganizationHandler) DeleteWorkspace(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	if err := h.orgs.DeleteWorkspace(c.Request.Context(), id, userID); err != nil {
+		respondError(c, err, "Failed to delete workspace")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted"})
+}
+
+func (h *OrganizationHandler) ListWorkspaceSpreadsheets(c *gin.Context) {
+	userID := c.MustGet("user_id").(uint)
+	id, err := parseWorkspaceID(c)
+	if err != nil {
+		return
+	}
+
+	items, err := h.orgs.ListWorkspaceSpreadsheets(c.Request.Context(), id, userID)
+	if err != nil {
+		respondError(c, err, "Failed to fetch spreadsheets")
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+func parseWorkspaceID(c *gin.Context) (uint, error) {
+	return parseUintParam(c, "workspaceId", "Invalid workspace ID")
+}
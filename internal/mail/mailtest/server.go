@@ -0,0 +1,267 @@
+// Package mailtest provides an SMTP server for testing mail delivery.
+package mailtest
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Options configures a Server.
+type Options struct {
+	// StartTLS advertises STARTTLS with a self-signed certificate for
+	// 127.0.0.1; trust it with RootCAs.
+	StartTLS bool
+	// Username and Password, when set, must be given with AUTH PLAIN
+	// before sending.
+	Username string
+	Password string
+}
+
+// Message is an email the server accepted.
+type Message struct {
+	From string
+	To   []string
+	Data []byte // with LF line endings
+	TLS  bool   // received over STARTTLS
+}
+
+// Server is an SMTP server on the loopback interface that keeps the
+// messages it accepts. It speaks just enough SMTP for net/smtp.
+type Server struct {
+	Host string
+	Port int
+
+	opts    Options
+	tls     *tls.Config
+	rootCAs *x509.CertPool
+	ln      net.Listener
+	wg      sync.WaitGroup
+
+	mu       sync.Mutex
+	messages []Message
+	reject   string
+}
+
+// NewServer starts a server that is stopped when the test ends.
+func NewServer(t testing.TB, opts Options) *Server {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("mailtest: listen: %v", err)
+	}
+	addr := ln.Addr().(*net.TCPAddr)
+	s := &Server{Host: addr.IP.String(), Port: addr.Port, opts: opts, ln: ln}
+	if opts.StartTLS {
+		cert, err := selfSigned()
+		if err != nil {
+			t.Fatalf("mailtest: certificate: %v", err)
+		}
+		s.tls = &tls.Config{Certificates: []tls.Certificate{cert}}
+		s.rootCAs = x509.NewCertPool()
+		s.rootCAs.AddCert(cert.Leaf)
+	}
+
+	s.wg.Add(1)
+	go s.accept()
+	t.Cleanup(func() {
+		ln.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// RootCAs returns a pool that trusts the server's certificate.
+func (s *Server) RootCAs() *x509.CertPool {
+	return s.rootCAs
+}
+
+// Messages returns the messages accepted so far.
+func (s *Server) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.messages...)
+}
+
+// Reject answers every following RCPT command with reply, such as
+// "451 4.3.0 Try again later". An empty reply accepts recipients again.
+func (s *Server) Reject(reply string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reject = reply
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(conn)
+		}()
+	}
+}
+
+func (s *Server) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	tp := textproto.NewConn(conn)
+	var (
+		msg    Message
+		secure bool
+		authed bool
+	)
+	reply := func(format string, args ...any) {
+		_ = tp.PrintfLine(format, args...)
+	}
+	reply("220 localhost ESMTP mailtest")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			lines := []string{"localhost"}
+			if s.tls != nil && !secure {
+				lines = append(lines, "STARTTLS")
+			}
+			if s.opts.Username != "" {
+				lines = append(lines, "AUTH PLAIN")
+			}
+			for i, l := range lines {
+				sep := "-"
+				if i == len(lines)-1 {
+					sep = " "
+				}
+				reply("250%s%s", sep, l)
+			}
+		case "STARTTLS":
+			if s.tls == nil || secure {
+				reply("502 5.5.1 STARTTLS not available")
+				continue
+			}
+			reply("220 2.0.0 Ready to start TLS")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, tp, secure, msg = tlsConn, textproto.NewConn(tlsConn), true, Message{}
+		case "AUTH":
+			mech, resp, _ := strings.Cut(arg, " ")
+			creds, err := base64.StdEncoding.DecodeString(resp)
+			if !strings.EqualFold(mech, "PLAIN") || err != nil {
+				reply("504 5.5.4 Unrecognized authentication type")
+				continue
+			}
+			if fields := bytes.Split(creds, []byte{0}); len(fields) == 3 &&
+				string(fields[1]) == s.opts.Username && string(fields[2]) == s.opts.Password {
+				authed = true
+				reply("235 2.7.0 Authentication successful")
+			} else {
+				reply("535 5.7.8 Authentication credentials invalid")
+			}
+		case "MAIL":
+			if s.opts.Username != "" && !authed {
+				reply("530 5.7.0 Authentication required")
+				continue
+			}
+			msg = Message{From: address(arg, "FROM:"), TLS: secure}
+			reply("250 2.1.0 OK")
+		case "RCPT":
+			if rejection := s.rejection(); rejection != "" {
+				reply("%s", rejection)
+				continue
+			}
+			msg.To = append(msg.To, address(arg, "TO:"))
+			reply("250 2.1.5 OK")
+		case "DATA":
+			if len(msg.To) == 0 {
+				reply("503 5.5.1 No valid recipients")
+				continue
+			}
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.Data = data
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			msg = Message{TLS: secure}
+			reply("250 2.0.0 OK queued")
+		case "RSET":
+			msg = Message{TLS: secure}
+			reply("250 2.0.0 OK")
+		case "NOOP":
+			reply("250 2.0.0 OK")
+		case "QUIT":
+			reply("221 2.0.0 Bye")
+			return
+		default:
+			reply("502 5.5.2 Command not recognized")
+		}
+	}
+}
+
+func (s *Server) rejection() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reject
+}
+
+// address returns the path in a MAIL FROM or RCPT TO argument.
+func address(arg, prefix string) string {
+	if len(arg) >= len(prefix) && strings.EqualFold(arg[:len(prefix)], prefix) {
+		arg = arg[len(prefix):]
+	}
+	arg, _, _ = strings.Cut(arg, " ")
+	return strings.Trim(arg, "<>")
+}
+
+// selfSigned creates a certificate for 127.0.0.1 and localhost.
+func selfSigned() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:              []string{"localhost"},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
+}
@@ -0,0 +1,158 @@
+package mail
+
+import (
+	"context"
+	"io"
+	"jaggle-grids/internal/mail/mailtest"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+func newTestSMTPMailer(t *testing.T, server *mailtest.Server, tlsMode, username, password string) *SMTPMailer {
+	t.Helper()
+	m, err := NewSMTPMailer(SMTPConfig{
+		Host:     server.Host,
+		Port:     server.Port,
+		Username: username,
+		Password: password,
+		TLS:      tlsMode,
+		From:     "Jaggle Grids <grids@example.com>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+var testMessage = Message{
+	To:      "Ada <ada@example.com>",
+	Subject: "Crème brûlée was shared with you",
+	Text:    "Open it: https://grids.example.com/s/7",
+	HTML:    `<p>Open <a href="https://grids.example.com/s/7">Crème brûlée</a></p>`,
+}
+
+func TestSMTPSendsTextAndHTML(t *testing.T) {
+	server := mailtest.NewServer(t, mailtest.Options{})
+	m := newTestSMTPMailer(t, server, TLSNone, "", "")
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+
+	received := server.Messages()
+	if len(received) != 1 {
+		t.Fatalf("server received %d messages, want 1", len(received))
+	}
+	got := received[0]
+	if got.From != "grids@example.com" || len(got.To) != 1 || got.To[0] != "ada@example.com" {
+		t.Errorf("envelope from %q to %q", got.From, got.To)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(got.Data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != testMessage.Subject {
+		t.Errorf("subject %q (%v), want %q", subject, err, testMessage.Subject)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type %q (%v), want multipart/alternative", mediaType, err)
+	}
+
+	// Clients show the last alternative they understand, so the HTML part
+	// comes after the plain text.
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", testMessage.Text},
+		{"text/html; charset=utf-8", testMessage.HTML},
+	} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatalf("%s part: %v", want.contentType, err)
+		}
+		body, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != want.contentType {
+			t.Errorf("part content type %q, want %q", ct, want.contentType)
+		}
+		if string(body) != want.body {
+			t.Errorf("%s body %q, want %q", want.contentType, body, want.body)
+		}
+	}
+	if _, err := parts.NextPart(); err != io.EOF {
+		t.Errorf("more than two parts: %v", err)
+	}
+}
+
+func TestSMTPStartTLSAndAuth(t *testing.T) {
+	server := mailtest.NewServer(t, mailtest.Options{StartTLS: true, Username: "grids", Password: "secret"})
+	m := newTestSMTPMailer(t, server, TLSStartTLS, "grids", "secret")
+	m.rootCAs = server.RootCAs()
+	if err := m.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	received := server.Messages()
+	if len(received) != 1 || !received[0].TLS {
+		t.Errorf("got %+v, want one message over TLS", received)
+	}
+}
+
+func TestSMTPFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     mailtest.Options
+		trust    bool
+		password string
+		want     string
+	}{
+		{
+			name: "server without STARTTLS",
+			opts: mailtest.Options{Username: "grids", Password: "secret"},
+			want: "does not support STARTTLS",
+		},
+		{
+			name: "untrusted certificate",
+			opts: mailtest.Options{StartTLS: true, Username: "grids", Password: "secret"},
+			want: "starttls: tls: failed to verify certificate",
+		},
+		{
+			name:     "wrong password",
+			opts:     mailtest.Options{StartTLS: true, Username: "grids", Password: "secret"},
+			trust:    true,
+			password: "guess",
+			want:     "authenticate: 535",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := mailtest.NewServer(t, tt.opts)
+			m := newTestSMTPMailer(t, server, TLSStartTLS, "grids", tt.password)
+			if tt.trust {
+				m.rootCAs = server.RootCAs()
+			}
+			err := m.Send(context.Background(), testMessage)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v, want an error containing %q", err, tt.want)
+			}
+			if n := len(server.Messages()); n != 0 {
+				t.Errorf("server received %d messages", n)
+			}
+		})
+	}
+}
+
+func TestSMTPRecipientRejected(t *testing.T) {
+	server := mailtest.NewServer(t, mailtest.Options{})
+	server.Reject("550 5.1.1 No such user")
+	m := newTestSMTPMailer(t, server, TLSNone, "", "")
+	err := m.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "No such user") {
+		t.Fatalf("got %v, want the server's rejection", err)
+	}
+}
@@ -1,16 +1,27 @@
 package middleware
 
 import (
+	"fmt"
+	"jaggle-grids/internal/domain"
 	"jaggle-grids/internal/service"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
+// WebSocketProtocol is the subprotocol browsers offer, followed by the
+// session token, because the WebSocket API can't set an Authorization
+// header: new WebSocket(url, ["grids.bearer", token]).
+const WebSocketProtocol = "grids.bearer"
+
 func AuthRequired(auth *service.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		header := c.GetHeader("Authorization")
+		if header == "" {
+			header = webSocketAuthorization(c)
+		}
 		if header == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
@@ -24,7 +35,12 @@ func AuthRequired(auth *service.AuthService) gin.HandlerFunc {
 			return
 		}
 
-		session, err := auth.Authenticate(c.Request.Context(), token)
+		if strings.HasPrefix(token, service.APITokenPrefix) {
+			authenticateAPIToken(c, auth, token)
+			return
+		}
+
+		session, err := auth.Authenticate(c.Request.Context(), token, c.ClientIP())
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired session"})
 			c.Abort()
@@ -34,6 +50,72 @@ func AuthRequired(auth *service.AuthService) gin.HandlerFunc {
 		c.Set("user_id", session.UserID)
 		c.Set("user", session.User)
 		c.Set("token", session.Token)
+		c.Set("session_id", session.ID)
 		c.Next()
 	}
 }
+
+// authenticateAPIToken authenticates a request made with an API token and
+// enforces the token's restrictions.
+func authenticateAPIToken(c *gin.Context, auth *service.AuthService, secret string) {
+	token, err := auth.AuthenticateAPIToken(c.Request.Context(), secret, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API token"})
+		c.Abort()
+		return
+	}
+	if msg := tokenDenies(c, token); msg != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		c.Abort()
+		return
+	}
+
+	c.Set("user_id", token.UserID)
+	c.Set("user", token.User)
+	c.Set("api_token", token)
+	c.Next()
+}
+
+// tokenDenies returns why an API token may not make this request, or "".
+// Live collaboration is for browsers and isn't available to tokens.
+func tokenDenies(c *gin.Context, token *domain.APIToken) string {
+	if strings.HasSuffix(c.FullPath(), "/ws") {
+		return "API tokens can't open live collaboration sessions"
+	}
+	if token.Scope == domain.ScopeRead && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return "This API token is read-only"
+	}
+	if token.SpreadsheetID != nil {
+		if !strings.HasPrefix(c.FullPath(), "/api/spreadsheets/:id") || c.Param("id") != strconv.FormatUint(uint64(*token.SpreadsheetID), 10) {
+			return fmt.Sprintf("This API token is restricted to spreadsheet %d", *token.SpreadsheetID)
+		}
+	}
+	return ""
+}
+
+// SessionRequired rejects API tokens. It guards account management, so
+// that a leaked token can't be used to mint more tokens or lock the owner
+// out.
+func SessionRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get("api_token"); ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint requires a signed-in session, not an API token"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// webSocketAuthorization turns a "grids.bearer, <token>" subprotocol offer
+// on a WebSocket handshake into an Authorization header value.
+func webSocketAuthorization(c *gin.Context) string {
+	if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return ""
+	}
+	protocols := strings.Split(c.GetHeader("Sec-WebSocket-Protocol"), ",")
+	if len(protocols) != 2 || strings.TrimSpace(protocols[0]) != WebSocketProtocol {
+		return ""
+	}
+	return "Bearer " + strings.TrimSpace(protocols[1])
+}
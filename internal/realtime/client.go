@@ -0,0 +1,121 @@
+package realtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	writeWait      = 10 * time.Second
+	pongWait       = 60 * time.Second
+	pingPeriod     = pongWait * 9 / 10
+	maxMessageSize = 32 << 20 // snapshots carry the whole workbook
+	sendBuffer     = 1024     // frames; a client further behind than this is dropped
+	roleTimeout    = 5 * time.Second
+)
+
+type client struct {
+	sheetID uint
+	peer    Peer // owned by the room after registration
+	conn    *websocket.Conn
+	send    chan []byte // closed by the room
+}
+
+// Serve attaches an upgraded connection to the spreadsheet's room and
+// blocks until the client disconnects. The caller must already have
+// checked that the user may open the spreadsheet with the given role;
+// later edits are checked against the role the user has at that time.
+func (h *Hub) Serve(conn *websocket.Conn, sheetID uint, user *domain.User, role domain.Role) {
+	c := &client{
+		sheetID: sheetID,
+		peer: Peer{
+			ClientID:  newClientID(),
+			UserID:    user.ID,
+			Name:      user.Name,
+			AvatarURL: user.AvatarURL,
+			Role:      string(role),
+		},
+		conn: conn,
+		send: make(chan []byte, sendBuffer),
+	}
+
+	r := h.join(c)
+	go c.writePump()
+	c.readPump(r)
+	h.leave(r, c)
+}
+
+// readPump forwards frames to the room until the connection fails.
+func (c *client) readPump(r *room) {
+	defer c.conn.Close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		var msg Inbound
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		in := inbound{client: c, msg: msg}
+		if msg.Type == TypeOp || msg.Type == TypeSnapshot {
+			// Checked here rather than in the room so that one client's
+			// lookups don't hold up the others.
+			in.role, in.roleErr = c.currentRole(r.hub.store)
+		}
+		select {
+		case r.inbound <- in:
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// writePump drains the send queue and keeps the connection alive with
+// pings. It exits when the room closes the queue.
+func (c *client) writePump() {
+	ticker := time.NewTicker(pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if !ok {
+				_ = c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func (c *client) currentRole(store Store) (domain.Role, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), roleTimeout)
+	defer cancel()
+	return store.Role(ctx, c.sheetID, c.peer.UserID)
+}
+
+func newClientID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,113 @@
+// Package realtime relays live edits between clients editing the same
+// spreadsheet over WebSockets.
+//
+// Concurrency model: each open spreadsheet has a room, and each room is
+// owned by a single goroutine (room.run) that holds all of its state —
+// members, presence, the op sequence number and the latest snapshot. Every
+// client has a read goroutine that forwards frames to its room and a write
+// goroutine that drains a buffered send queue. The room never blocks on a
+// client: if a client's queue is full it is disconnected.
+//
+// The Hub maps spreadsheet IDs to rooms and reference-counts them under a
+// mutex. When the last client leaves, the room flushes its snapshot and
+// stops; a room opened for the same spreadsheet afterwards waits for that
+// flush before loading, so late joiners always see the latest data.
+//
+// Access can change while a socket is open. Ops and snapshots are only
+// accepted if the sender is an editor at the time they arrive, and every
+// member's role is checked again every revalidateInterval; members who
+// lost access are disconnected.
+package realtime
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"sync"
+	"time"
+)
+
+// Store is the part of the spreadsheet service the hub persists through.
+type Store interface {
+	Get(ctx context.Context, id, userID uint) (*domain.Spreadsheet, error)
+	SaveLive(ctx context.Context, id, userID uint, data string, structure []domain.StructureChange, expectedVersion *int) (*domain.Spreadsheet, error)
+	// Role returns the user's current role, or ErrNotFound without access.
+	Role(ctx context.Context, id, userID uint) (domain.Role, error)
+}
+
+// revalidateInterval is how often a room checks that its members still
+// have access.
+const revalidateInterval = time.Minute
+
+type Hub struct {
+	store              Store
+	flushInterval      time.Duration
+	revalidateInterval time.Duration
+
+	mu      sync.Mutex
+	rooms   map[uint]*room
+	closing map[uint]chan struct{} // done channels of rooms that are shutting down
+}
+
+// NewHub creates a hub. Accepted snapshots are persisted at most once per
+// flushInterval, and whenever a room empties.
+func NewHub(store Store, flushInterval time.Duration) *Hub {
+	return &Hub{
+		store:              store,
+		flushInterval:      flushInterval,
+		revalidateInterval: revalidateInterval,
+		rooms:              map[uint]*room{},
+		closing:            map[uint]chan struct{}{},
+	}
+}
+
+// join adds a client to the room for its spreadsheet, creating the room
+// if needed.
+func (h *Hub) join(c *client) *room {
+	h.mu.Lock()
+	r, ok := h.rooms[c.sheetID]
+	if !ok {
+		r = newRoom(h, c.sheetID, c.peer.UserID, h.closing[c.sheetID])
+		h.rooms[c.sheetID] = r
+		go r.run()
+	}
+	r.refs++
+	h.mu.Unlock()
+
+	r.register <- c
+	return r
+}
+
+// leave removes a client. The room keeps running while it has members, so
+// the unregister is delivered before the reference is dropped.
+func (h *Hub) leave(r *room, c *client) {
+	r.unregister <- c
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	r.refs--
+	if r.refs > 0 {
+		return
+	}
+
+	delete(h.rooms, r.sheetID)
+	h.closing[r.sheetID] = r.done
+	close(r.quit)
+	go func() {
+		<-r.done
+		h.mu.Lock()
+		if h.closing[r.sheetID] == r.done {
+			delete(h.closing, r.sheetID)
+		}
+		h.mu.Unlock()
+	}()
+}
+
+// RoomSize returns the number of clients connected to a spreadsheet.
+func (h *Hub) RoomSize(sheetID uint) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if r, ok := h.rooms[sheetID]; ok {
+		return r.refs
+	}
+	return 0
+}
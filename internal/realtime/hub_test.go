@@ -0,0 +1,355 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const testSheet = 1
+
+// fakeStore holds one spreadsheet and a role per user that tests can
+// change while clients are connected.
+type fakeStore struct {
+	mu      sync.Mutex
+	data    string
+	version int
+	roles   map[uint]domain.Role
+	saves   []string
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{data: "initial", version: 1, roles: map[uint]domain.Role{}}
+}
+
+func (s *fakeStore) setRole(userID uint, role domain.Role) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if role == "" {
+		delete(s.roles, userID)
+	} else {
+		s.roles[userID] = role
+	}
+}
+
+func (s *fakeStore) Role(_ context.Context, id, userID uint) (domain.Role, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	role, ok := s.roles[userID]
+	if !ok || id != testSheet {
+		return "", fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+	return role, nil
+}
+
+func (s *fakeStore) Get(ctx context.Context, id, userID uint) (*domain.Spreadsheet, error) {
+	role, err := s.Role(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &domain.Spreadsheet{ID: id, Data: s.data, Version: s.version, Role: role}, nil
+}
+
+func (s *fakeStore) SaveLive(ctx context.Context, id, userID uint, data string, _ []domain.StructureChange, expected *int) (*domain.Spreadsheet, error) {
+	role, err := s.Role(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Allows(domain.RoleEditor) {
+		return nil, fmt.Errorf("%w: editor role required", domain.ErrForbidden)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if expected != nil && *expected != s.version {
+		return nil, &domain.VersionConflictError{Current: s.version}
+	}
+	s.data = data
+	s.version++
+	s.saves = append(s.saves, data)
+	return &domain.Spreadsheet{ID: id, Data: s.data, Version: s.version}, nil
+}
+
+func (s *fakeStore) savedData() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.saves...)
+}
+
+// newTestServer serves the hub over a real WebSocket endpoint. The user
+// ID comes from the query string and the role from the store, as the
+// handler would look it up.
+func newTestServer(t *testing.T, store *fakeStore, hub *Hub) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, _ := strconv.ParseUint(r.URL.Query().Get("user"), 10, 64)
+		user := &domain.User{ID: uint(id), Name: "user " + r.URL.Query().Get("user")}
+		sheet, err := store.Get(r.Context(), testSheet, user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		hub.Serve(conn, testSheet, user, sheet.Role)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+type testClient struct {
+	t       *testing.T
+	conn    *websocket.Conn
+	welcome Outbound
+}
+
+func dial(t *testing.T, srv *httptest.Server, userID uint) *testClient {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "?user=" + strconv.Itoa(int(userID))
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial as user %d: %v", userID, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	c := &testClient{t: t, conn: conn}
+	c.welcome = c.expect(TypeWelcome)
+	return c
+}
+
+func (c *testClient) send(msg Inbound) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("send %s: %v", msg.Type, err)
+	}
+}
+
+// read returns the next frame, skipping presence updates.
+func (c *testClient) read() (Outbound, error) {
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg Outbound
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return msg, err
+		}
+		if msg.Type != TypeJoin && msg.Type != TypeLeave {
+			return msg, nil
+		}
+	}
+}
+
+func (c *testClient) expect(typ string) Outbound {
+	c.t.Helper()
+	msg, err := c.read()
+	if err != nil {
+		c.t.Fatalf("waiting for %s: %v", typ, err)
+	}
+	if msg.Type != typ {
+		c.t.Fatalf("got %s frame (%s), want %s", msg.Type, msg.Error, typ)
+	}
+	return msg
+}
+
+// expectClosed waits for the server to close the connection, ignoring
+// frames sent before that.
+func (c *testClient) expectClosed() {
+	c.t.Helper()
+	for {
+		if _, err := c.read(); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseNoStatusReceived) {
+				c.t.Fatalf("connection failed instead of closing: %v", err)
+			}
+			return
+		}
+	}
+}
+
+func TestManyClientsReceiveEveryOp(t *testing.T) {
+	const clients, opsEach = 40, 25
+
+	store := newFakeStore()
+	for i := 1; i <= clients; i++ {
+		store.setRole(uint(i), domain.RoleEditor)
+	}
+	hub := NewHub(store, 20*time.Millisecond)
+	srv := newTestServer(t, store, hub)
+
+	conns := make([]*testClient, clients)
+	for i := range conns {
+		conns[i] = dial(t, srv, uint(i+1))
+	}
+	if n := hub.RoomSize(testSheet); n != clients {
+		t.Fatalf("room size = %d, want %d", n, clients)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, clients)
+	for i, c := range conns {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := range opsEach {
+				op, _ := json.Marshal(map[string]int{"client": i, "n": n})
+				if err := c.conn.WriteJSON(Inbound{Type: TypeOp, Op: op}); err != nil {
+					errs <- err
+					return
+				}
+			}
+			// Every client sees every op: its own acked, the others relayed.
+			acks, relayed := 0, 0
+			var lastSeq uint64
+			for acks+relayed < clients*opsEach {
+				msg, err := c.read()
+				if err != nil {
+					errs <- fmt.Errorf("client %d after %d acks, %d ops: %w", i, acks, relayed, err)
+					return
+				}
+				switch msg.Type {
+				case TypeAck:
+					acks++
+				case TypeOp:
+					relayed++
+				default:
+					errs <- fmt.Errorf("client %d: unexpected %s frame: %s", i, msg.Type, msg.Error)
+					return
+				}
+				if msg.Seq <= lastSeq {
+					errs <- fmt.Errorf("client %d: seq %d after %d", i, msg.Seq, lastSeq)
+					return
+				}
+				lastSeq = msg.Seq
+			}
+			if acks != opsEach {
+				errs <- fmt.Errorf("client %d: %d acks, want %d", i, acks, opsEach)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	// A snapshot covering every op is persisted after the flush interval.
+	conns[0].send(Inbound{Type: TypeSnapshot, Seq: clients * opsEach, Data: "after"})
+	saved := conns[0].expect(TypeSaved)
+	if saved.Version != 2 {
+		t.Errorf("saved version = %d, want 2", saved.Version)
+	}
+	if got := store.savedData(); len(got) != 1 || got[0] != "after" {
+		t.Errorf("saves = %v, want [after]", got)
+	}
+
+	for _, c := range conns {
+		c.conn.Close()
+	}
+	waitEmpty(t, hub)
+}
+
+func waitEmpty(t *testing.T, hub *Hub) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for hub.RoomSize(testSheet) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("room still has %d clients", hub.RoomSize(testSheet))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEditsAreCheckedAgainstTheCurrentRole(t *testing.T) {
+	store := newFakeStore()
+	store.setRole(1, domain.RoleOwner)
+	store.setRole(2, domain.RoleEditor)
+	hub := NewHub(store, time.Hour)
+	srv := newTestServer(t, store, hub)
+
+	owner := dial(t, srv, 1)
+	editor := dial(t, srv, 2)
+
+	editor.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{"n":1}`)})
+	editor.expect(TypeAck)
+	owner.expect(TypeOp)
+
+	// Demoted after joining: further ops and snapshots are refused.
+	store.setRole(2, domain.RoleViewer)
+	editor.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{"n":2}`)})
+	if msg := editor.expect(TypeError); msg.Error != "Editor role required" {
+		t.Errorf("op error = %q", msg.Error)
+	}
+	editor.send(Inbound{Type: TypeSnapshot, Seq: 1, Data: "by viewer"})
+	editor.expect(TypeError)
+
+	// The owner doesn't see the refused op.
+	owner.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{"n":3}`)})
+	if ack := owner.expect(TypeAck); ack.Seq != 2 {
+		t.Errorf("owner's op got seq %d, want 2", ack.Seq)
+	}
+
+	// Unshared entirely: the next edit disconnects the client.
+	store.setRole(2, "")
+	editor.expect(TypeOp) // the owner's op
+	editor.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{"n":4}`)})
+	editor.expectClosed()
+	if got := store.savedData(); len(got) != 0 {
+		t.Errorf("saves = %v, want none", got)
+	}
+}
+
+func TestMembersWhoLoseAccessAreDisconnected(t *testing.T) {
+	store := newFakeStore()
+	store.setRole(1, domain.RoleOwner)
+	store.setRole(2, domain.RoleViewer)
+	hub := NewHub(store, time.Hour)
+	hub.revalidateInterval = 20 * time.Millisecond
+	srv := newTestServer(t, store, hub)
+
+	owner := dial(t, srv, 1)
+	viewer := dial(t, srv, 2)
+
+	// A viewer never sends edits, so only revalidation notices.
+	store.setRole(2, "")
+	viewer.expectClosed()
+
+	owner.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{}`)})
+	owner.expect(TypeAck)
+	if n := hub.RoomSize(testSheet); n != 1 {
+		t.Errorf("room size = %d, want 1", n)
+	}
+}
+
+func TestLastClientLeavingFlushesSnapshot(t *testing.T) {
+	store := newFakeStore()
+	store.setRole(1, domain.RoleEditor)
+	hub := NewHub(store, time.Hour)
+	srv := newTestServer(t, store, hub)
+
+	c := dial(t, srv, 1)
+	c.send(Inbound{Type: TypeSnapshot, Seq: 0, Data: "unsaved"})
+	// The snapshot is only queued; make sure the room has it before leaving.
+	c.send(Inbound{Type: TypeOp, Op: json.RawMessage(`{}`)})
+	c.expect(TypeAck)
+	c.conn.Close()
+	waitEmpty(t, hub)
+
+	// A client joining afterwards waits for the flush and sees the data.
+	late := dial(t, srv, 1)
+	if late.welcome.Data != "unsaved" || late.welcome.Version != 2 {
+		t.Errorf("welcome = %q version %d, want the flushed snapshot", late.welcome.Data, late.welcome.Version)
+	}
+	if got := store.savedData(); len(got) != 1 || got[0] != "unsaved" {
+		t.Errorf("saves = %v, want [unsaved]", got)
+	}
+}
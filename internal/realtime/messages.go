@@ -0,0 +1,62 @@
+package realtime
+
+import (
+	"encoding/json"
+	"jaggle-grids/internal/domain"
+)
+
+// Message types exchanged over the socket. Every frame is a JSON object
+// with a "type" field; the remaining fields depend on the type.
+const (
+	// Client → server
+	TypeOp        = "op"        // an edit operation, relayed to the other clients
+	TypeSelection = "selection" // the sender's cursor / selected range
+	TypeSnapshot  = "snapshot"  // the full document after applying every op up to Seq
+
+	// Server → client
+	TypeWelcome = "welcome" // initial state for a newly connected client
+	TypeJoin    = "join"    // a peer connected
+	TypeLeave   = "leave"   // a peer disconnected
+	TypeAck     = "ack"     // the sender's op was accepted as Seq
+	TypeSaved   = "saved"   // the document was persisted as Version
+	TypeReset   = "reset"   // the document changed outside the room; reload Data
+	TypeError   = "error"
+)
+
+// Inbound is a frame received from a client.
+type Inbound struct {
+	Type      string          `json:"type"`
+	Op        json.RawMessage `json:"op,omitempty"`
+	Selection json.RawMessage `json:"selection,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"`
+	// Structure, on a snapshot, lists the rows and columns inserted or
+	// deleted since the previous snapshot; see domain.StructureChange.
+	Structure []domain.StructureChange `json:"structure,omitempty"`
+}
+
+// Outbound is a frame sent to clients.
+type Outbound struct {
+	Type      string          `json:"type"`
+	ClientID  string          `json:"client_id,omitempty"`
+	From      string          `json:"from,omitempty"`
+	Seq       uint64          `json:"seq,omitempty"`
+	Op        json.RawMessage `json:"op,omitempty"`
+	Selection json.RawMessage `json:"selection,omitempty"`
+	Data      string          `json:"data,omitempty"`
+	Version   int             `json:"version,omitempty"`
+	Peer      *Peer           `json:"peer,omitempty"`
+	Peers     []Peer          `json:"peers,omitempty"`
+	Ops       []Outbound      `json:"ops,omitempty"` // welcome: ops not yet in Data
+	Error     string          `json:"error,omitempty"`
+}
+
+// Peer describes a connected client for presence.
+type Peer struct {
+	ClientID  string          `json:"client_id"`
+	UserID    uint            `json:"user_id"`
+	Name      string          `json:"name"`
+	AvatarURL string          `json:"avatar_url,omitempty"`
+	Role      string          `json:"role"`
+	Selection json.RawMessage `json:"selection,omitempty"`
+}
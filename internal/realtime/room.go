@@ -0,0 +1,340 @@
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"log"
+	"time"
+)
+
+// maxPendingOps bounds the ops a room keeps since the last snapshot (they
+// are replayed to late joiners). Beyond it, ops are refused until a client
+// sends a snapshot.
+const maxPendingOps = 10_000
+
+const persistTimeout = 10 * time.Second
+
+type inbound struct {
+	client *client
+	msg    Inbound
+	// The sender's role when an op or snapshot arrived.
+	role    domain.Role
+	roleErr error
+}
+
+type room struct {
+	hub      *Hub
+	sheetID  uint
+	loaderID uint          // user whose access is used to load the document
+	prevDone chan struct{} // done channel of the previous room for this sheet, if still flushing
+	refs     int           // guarded by hub.mu
+
+	register   chan *client
+	unregister chan *client
+	inbound    chan inbound
+	quit       chan struct{}
+	done       chan struct{}
+
+	// Owned by run.
+	clients map[*client]struct{}
+	seq     uint64
+	pending []Outbound // ops after the current snapshot
+	data    string
+	version int
+	dirty   bool
+	// structure collects the structure changes of the snapshots since
+	// the last save.
+	structure []domain.StructureChange
+	author    uint
+	loadErr   error
+	flush     *time.Timer
+	armed     bool
+}
+
+func newRoom(h *Hub, sheetID, loaderID uint, prevDone chan struct{}) *room {
+	return &room{
+		hub:        h,
+		sheetID:    sheetID,
+		loaderID:   loaderID,
+		prevDone:   prevDone,
+		register:   make(chan *client),
+		unregister: make(chan *client),
+		inbound:    make(chan inbound),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+		clients:    map[*client]struct{}{},
+	}
+}
+
+func (r *room) run() {
+	defer close(r.done)
+
+	if r.prevDone != nil {
+		<-r.prevDone
+	}
+	r.loadErr = r.load(r.loaderID)
+
+	r.flush = time.NewTimer(time.Hour)
+	r.flush.Stop()
+	defer r.flush.Stop()
+	revalidate := time.NewTicker(r.hub.revalidateInterval)
+	defer revalidate.Stop()
+
+	for {
+		select {
+		case c := <-r.register:
+			r.add(c)
+		case c := <-r.unregister:
+			r.remove(c)
+		case in := <-r.inbound:
+			r.handle(in)
+		case <-revalidate.C:
+			r.revalidate()
+		case <-r.flush.C:
+			r.armed = false
+			r.persist()
+		case <-r.quit:
+			r.persist()
+			return
+		}
+	}
+}
+
+func (r *room) load(userID uint) error {
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	sheet, err := r.hub.store.Get(ctx, r.sheetID, userID)
+	if err != nil {
+		return err
+	}
+	r.data = sheet.Data
+	r.version = sheet.Version
+	r.pending = nil
+	return nil
+}
+
+func (r *room) add(c *client) {
+	if r.loadErr != nil {
+		c.send <- mustMarshal(Outbound{Type: TypeError, Error: "Failed to load spreadsheet"})
+		close(c.send)
+		return
+	}
+
+	peers := make([]Peer, 0, len(r.clients))
+	for other := range r.clients {
+		peers = append(peers, other.peer)
+	}
+	r.clients[c] = struct{}{}
+
+	r.sendTo(c, Outbound{
+		Type:     TypeWelcome,
+		ClientID: c.peer.ClientID,
+		Data:     r.data,
+		Version:  r.version,
+		Seq:      r.seq,
+		Peers:    peers,
+		Ops:      r.pending,
+	})
+	peer := c.peer
+	r.broadcast(c, Outbound{Type: TypeJoin, Peer: &peer})
+}
+
+func (r *room) remove(c *client) {
+	if _, ok := r.clients[c]; !ok {
+		return
+	}
+	delete(r.clients, c)
+	close(c.send)
+
+	peer := c.peer
+	r.broadcast(nil, Outbound{Type: TypeLeave, Peer: &peer})
+}
+
+func (r *room) handle(in inbound) {
+	c, msg := in.client, in.msg
+	if _, ok := r.clients[c]; !ok {
+		return
+	}
+	if (msg.Type == TypeOp || msg.Type == TypeSnapshot) && !r.checkEditor(c, in.role, in.roleErr) {
+		return
+	}
+
+	switch msg.Type {
+	case TypeOp:
+		if len(r.pending) >= maxPendingOps {
+			r.sendTo(c, Outbound{Type: TypeError, Error: "Snapshot required before further ops"})
+			return
+		}
+		r.seq++
+		out := Outbound{Type: TypeOp, From: c.peer.ClientID, Seq: r.seq, Op: msg.Op}
+		r.pending = append(r.pending, out)
+		r.broadcast(c, out)
+		r.sendTo(c, Outbound{Type: TypeAck, Seq: r.seq})
+
+	case TypeSelection:
+		c.peer.Selection = msg.Selection
+		r.broadcast(c, Outbound{Type: TypeSelection, From: c.peer.ClientID, Selection: msg.Selection})
+
+	case TypeSnapshot:
+		// A snapshot is only accepted if it includes every op so far;
+		// otherwise some edits would be lost.
+		if msg.Seq != r.seq {
+			r.sendTo(c, Outbound{Type: TypeError, Error: "Stale snapshot", Seq: r.seq})
+			return
+		}
+		if msg.Data == "" {
+			r.sendTo(c, Outbound{Type: TypeError, Error: "Snapshot data is required"})
+			return
+		}
+		r.data = msg.Data
+		r.structure = append(r.structure, msg.Structure...)
+		r.pending = nil
+		r.dirty = true
+		r.author = c.peer.UserID
+		if !r.armed {
+			r.flush.Reset(r.hub.flushInterval)
+			r.armed = true
+		}
+
+	default:
+		r.sendTo(c, Outbound{Type: TypeError, Error: "Unknown message type"})
+	}
+}
+
+// checkEditor reports whether c may edit given its current role, telling
+// it why not otherwise. Clients that lost access are disconnected.
+func (r *room) checkEditor(c *client, role domain.Role, err error) bool {
+	switch {
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrForbidden):
+		r.revoke(c)
+		return false
+	case err != nil:
+		log.Printf("realtime: check role on spreadsheet %d: %v", r.sheetID, err)
+		r.sendTo(c, Outbound{Type: TypeError, Error: "Failed to check permissions"})
+		return false
+	}
+	c.peer.Role = string(role)
+	if !role.Allows(domain.RoleEditor) {
+		r.sendTo(c, Outbound{Type: TypeError, Error: "Editor role required"})
+		return false
+	}
+	return true
+}
+
+// revalidate looks up every member's role again, disconnecting those who
+// lost access to the spreadsheet.
+func (r *room) revalidate() {
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	type result struct {
+		role domain.Role
+		err  error
+	}
+	results := map[uint]result{} // by user; they may have several tabs open
+	for c := range r.clients {
+		res, ok := results[c.peer.UserID]
+		if !ok {
+			res.role, res.err = r.hub.store.Role(ctx, r.sheetID, c.peer.UserID)
+			results[c.peer.UserID] = res
+		}
+		switch {
+		case errors.Is(res.err, domain.ErrNotFound), errors.Is(res.err, domain.ErrForbidden):
+			r.revoke(c)
+		case res.err != nil:
+			log.Printf("realtime: check role on spreadsheet %d: %v", r.sheetID, res.err)
+		default:
+			c.peer.Role = string(res.role)
+		}
+	}
+}
+
+// revoke tells a client it lost access and disconnects it.
+func (r *room) revoke(c *client) {
+	r.sendTo(c, Outbound{Type: TypeError, Error: "Access to this spreadsheet was revoked"})
+	r.remove(c)
+}
+
+// persist saves the latest accepted snapshot through the store. If the
+// spreadsheet was saved outside the room in the meantime, the room adopts
+// the stored version and tells every client to reload.
+func (r *room) persist() {
+	if !r.dirty {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), persistTimeout)
+	defer cancel()
+
+	expected := r.version
+	sheet, err := r.hub.store.SaveLive(ctx, r.sheetID, r.author, r.data, r.structure, &expected)
+	switch {
+	case errors.Is(err, domain.ErrConflict):
+		r.dirty = false
+		r.structure = nil
+		if err := r.load(r.author); err != nil {
+			log.Printf("realtime: reload spreadsheet %d: %v", r.sheetID, err)
+			return
+		}
+		r.broadcast(nil, Outbound{Type: TypeReset, Data: r.data, Version: r.version, Seq: r.seq})
+	case errors.Is(err, domain.ErrNotFound), errors.Is(err, domain.ErrForbidden):
+		// Trashed, or the author lost access; retrying won't help.
+		r.dirty = false
+		r.structure = nil
+		r.broadcast(nil, Outbound{Type: TypeError, Error: "Changes could not be saved: spreadsheet is no longer available"})
+	case errors.Is(err, domain.ErrInvalidInput):
+		// Only the structure changes can be invalid. Save without them;
+		// comments may then point at the wrong cells.
+		log.Printf("realtime: spreadsheet %d: %v", r.sheetID, err)
+		r.structure = nil
+		if !r.armed {
+			r.flush.Reset(r.hub.flushInterval)
+			r.armed = true
+		}
+	case err != nil:
+		log.Printf("realtime: persist spreadsheet %d: %v", r.sheetID, err)
+		if !r.armed {
+			r.flush.Reset(r.hub.flushInterval)
+			r.armed = true
+		}
+	default:
+		r.dirty = false
+		r.structure = nil
+		r.version = sheet.Version
+		r.broadcast(nil, Outbound{Type: TypeSaved, Version: r.version})
+	}
+}
+
+// broadcast sends msg to every client except skip.
+func (r *room) broadcast(skip *client, msg Outbound) {
+	payload := mustMarshal(msg)
+	for c := range r.clients {
+		if c != skip {
+			r.enqueue(c, payload)
+		}
+	}
+}
+
+func (r *room) sendTo(c *client, msg Outbound) {
+	r.enqueue(c, mustMarshal(msg))
+}
+
+// enqueue never blocks: a client that can't keep up is disconnected.
+func (r *room) enqueue(c *client, payload []byte) {
+	select {
+	case c.send <- payload:
+	default:
+		r.remove(c)
+	}
+}
+
+func mustMarshal(msg Outbound) []byte {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		panic(err) // Outbound only holds marshallable fields
+	}
+	return payload
+}
@@ -0,0 +1,85 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APITokenRepo struct {
+	db *gorm.DB
+}
+
+func NewAPITokenRepo(db *gorm.DB) *APITokenRepo {
+	return &APITokenRepo{db: db}
+}
+
+func (r *APITokenRepo) Create(ctx context.Context, token *domain.APIToken) error {
+	t := APIToken{
+		UserID:        token.UserID,
+		Name:          token.Name,
+		Prefix:        token.Prefix,
+		TokenHash:     token.TokenHash,
+		Scope:         string(token.Scope),
+		SpreadsheetID: token.SpreadsheetID,
+		ExpiresAt:     token.ExpiresAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&t).Error; err != nil {
+		return err
+	}
+	token.ID = t.ID
+	token.CreatedAt = t.CreatedAt
+	return nil
+}
+
+func (r *APITokenRepo) ListByUser(ctx context.Context, userID uint) ([]domain.APIToken, error) {
+	var rows []APIToken
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.APIToken, len(rows))
+	for i, t := range rows {
+		out[i] = toDomainAPIToken(t)
+	}
+	return out, nil
+}
+
+func (r *APITokenRepo) FindValidByHash(ctx context.Context, hash string) (*domain.APIToken, error) {
+	var t APIToken
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		Preload("User").
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	token := toDomainAPIToken(t)
+	return &token, nil
+}
+
+func (r *APITokenRepo) RecordUse(ctx context.Context, id uint, at time.Time, ip string) error {
+	return r.db.WithContext(ctx).
+		Model(&APIToken{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_used_at": at, "last_used_ip": ip}).Error
+}
+
+func (r *APITokenRepo) Delete(ctx context.Context, id, userID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&APIToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepo struct {
+	db *gorm.DB
+}
+
+func NewCommentRepo(db *gorm.DB) *CommentRepo {
+	return &CommentRepo{db: db}
+}
+
+func (r *CommentRepo) Create(ctx context.Context, comment *domain.Comment, mentions []uint) error {
+	c := Comment{
+		SpreadsheetID: comment.SpreadsheetID,
+		ThreadID:      comment.ThreadID,
+		Sheet:         comment.Sheet,
+		Anchor:        comment.Anchor,
+		AuthorID:      comment.AuthorID,
+		Body:          comment.Body,
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Mentions").Create(&c).Error; err != nil {
+			return err
+		}
+		return insertMentions(tx, c.ID, mentions)
+	})
+	if err != nil {
+		return err
+	}
+	comment.ID = c.ID
+	comment.CreatedAt = c.CreatedAt
+	return nil
+}
+
+func (r *CommentRepo) FindByID(ctx context.Context, id uint) (*domain.Comment, error) {
+	var c Comment
+	if err := r.db.WithContext(ctx).Preload("Author").Preload("Mentions").First(&c, id).Error; err != nil {
+		return nil, err
+	}
+	comment := toDomainComment(c)
+	return &comment, nil
+}
+
+func (r *CommentRepo) ListThreads(ctx context.Context, spreadsheetID uint, filter domain.CommentFilter) ([]domain.Comment, error) {
+	threads := r.db.Model(&Comment{}).Select("id").
+		Where("spreadsheet_id = ? AND thread_id IS NULL", spreadsheetID)
+	if filter.ThreadID != nil {
+		threads = threads.Where("id = ?", *filter.ThreadID)
+	}
+	if filter.Sheet != "" {
+		threads = threads.Where("sheet = ?", filter.Sheet)
+	}
+	if filter.Resolved != nil {
+		if *filter.Resolved {
+			threads = threads.Where("resolved_at IS NOT NULL")
+		} else {
+			threads = threads.Where("resolved_at IS NULL")
+		}
+	}
+
+	var rows []Comment
+	err := r.db.WithContext(ctx).
+		Preload("Author").
+		Preload("Mentions").
+		Where("id IN (?) OR thread_id IN (?)", threads, threads).
+		Order("created_at, id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Comment, len(rows))
+	for i, c := range rows {
+		out[i] = toDomainComment(c)
+	}
+	return out, nil
+}
+
+func (r *CommentRepo) ListOnSheet(ctx context.Context, spreadsheetID uint, sheet string) ([]domain.Comment, error) {
+	var rows []Comment
+	err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ? AND sheet = ? AND thread_id IS NULL", spreadsheetID, sheet).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Comment, len(rows))
+	for i, c := range rows {
+		out[i] = toDomainComment(c)
+	}
+	return out, nil
+}
+
+func (r *CommentRepo) UpdateBody(ctx context.Context, id uint, body string, mentions []uint, editedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Comment{}).Where("id = ?", id).
+			Updates(map[string]any{"body": body, "edited_at": editedAt}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id = ?", id).Delete(&CommentMention{}).Error; err != nil {
+			return err
+		}
+		return insertMentions(tx, id, mentions)
+	})
+}
+
+func (r *CommentRepo) SetResolved(ctx context.Context, id uint, by *uint, at *time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&Comment{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"resolved_by_id": by, "resolved_at": at}).Error
+}
+
+func (r *CommentRepo) SetAnchors(ctx context.Context, anchors map[uint]domain.CommentAnchor) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for id, a := range anchors {
+			err := tx.Model(&Comment{}).Where("id = ?", id).
+				Updates(map[string]any{"sheet": a.Sheet, "anchor": a.Anchor}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *CommentRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&Comment{}).Select("id").Where("id = ? OR thread_id = ?", id, id)
+		if err := tx.Where("comment_id IN (?)", ids).Delete(&CommentMention{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("id = ? OR thread_id = ?", id, id).Delete(&Comment{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func insertMentions(tx *gorm.DB, commentID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	rows := make([]CommentMention, len(userIDs))
+	for i, id := range userIDs {
+		rows[i] = CommentMention{CommentID: commentID, UserID: id}
+	}
+	return tx.Create(&rows).Error
+}
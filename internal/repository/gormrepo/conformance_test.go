@@ -0,0 +1,642 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"slices"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The tests in this file pin down the repository contracts in
+// internal/domain that the services rely on, and run against every
+// driver forEachDB knows about.
+
+func TestUserRepositoryConformance(t *testing.T) {
+	forEachDB(t, testUserRepositoryConformance)
+}
+
+func testUserRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var users domain.UserRepository = NewUserRepo(db)
+
+	ada := &domain.User{Email: "  Ada@Example.COM ", Name: "Ada", PasswordHash: "h1"}
+	if err := users.Create(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	if ada.ID == 0 || ada.Email != "ada@example.com" || ada.CreatedAt.IsZero() {
+		t.Errorf("created user = %+v", ada)
+	}
+	if err := users.Create(ctx, &domain.User{Email: "ADA@example.com", Name: "Copy"}); err == nil {
+		t.Error("created a second user with the same email")
+	}
+
+	found, err := users.FindByEmail(ctx, "ada@EXAMPLE.com")
+	if err != nil || found.ID != ada.ID {
+		t.Fatalf("FindByEmail = %+v, %v", found, err)
+	}
+	if _, err := users.FindByEmail(ctx, "nobody@example.com"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("FindByEmail of a missing user: got %v, want ErrNotFound", err)
+	}
+	if _, err := users.FindByID(ctx, ada.ID+100); err == nil {
+		t.Error("FindByID of a missing user succeeded")
+	}
+
+	if err := users.UpdatePasswordHash(ctx, ada.ID, "h2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := users.UpdatePasswordHash(ctx, ada.ID+100, "h2"); err == nil {
+		t.Error("UpdatePasswordHash of a missing user succeeded")
+	}
+	ada.Name, ada.AvatarURL = "Ada L.", "https://example.com/ada.png"
+	if err := users.UpdateProfile(ctx, ada); err != nil {
+		t.Fatal(err)
+	}
+	found, err = users.FindByID(ctx, ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.PasswordHash != "h2" || found.Name != "Ada L." || found.AvatarURL != ada.AvatarURL {
+		t.Errorf("updated user = %+v", found)
+	}
+
+	// Users without an identity don't collide, linked ones must be unique.
+	bob := &domain.User{Email: "bob@example.com", Name: "Bob"}
+	if err := users.Create(ctx, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.FindByOIDCIdentity(ctx, "https://idp.example.com ada"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("FindByOIDCIdentity before linking: got %v, want ErrNotFound", err)
+	}
+	if err := users.SetOIDCIdentity(ctx, ada.ID, "https://idp.example.com ada"); err != nil {
+		t.Fatal(err)
+	}
+	found, err = users.FindByOIDCIdentity(ctx, "https://idp.example.com ada")
+	if err != nil || found.ID != ada.ID {
+		t.Fatalf("FindByOIDCIdentity = %+v, %v", found, err)
+	}
+	if err := users.SetOIDCIdentity(ctx, bob.ID, "https://idp.example.com ada"); err == nil {
+		t.Error("linked a second user to the same identity")
+	}
+	if err := users.SetOIDCIdentity(ctx, ada.ID+100, "https://idp.example.com x"); err == nil {
+		t.Error("SetOIDCIdentity of a missing user succeeded")
+	}
+}
+
+func TestSpreadsheetRepositoryConformance(t *testing.T) {
+	forEachDB(t, testSpreadsheetRepositoryConformance)
+}
+
+func testSpreadsheetRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var sheets domain.SpreadsheetRepository = NewSpreadsheetRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+
+	create := func(title string, owner *domain.User, workspaceID uint) *domain.Spreadsheet {
+		t.Helper()
+		s := &domain.Spreadsheet{Title: title, OwnerID: owner.ID, WorkspaceID: workspaceID, Data: "data of " + title}
+		if err := sheets.Create(ctx, s); err != nil {
+			t.Fatalf("create %s: %v", title, err)
+		}
+		return s
+	}
+	// The titles sort the same under byte-wise and locale-aware collations.
+	budget := create("Budget 2025", ada, 1)
+	create("budget_draft", ada, 1)
+	create("roadmap", ada, 2)
+	shared := create("Bob's budget", bob, 3)
+	create("Bob's private notes", bob, 3)
+
+	if budget.ID == 0 || budget.Version != 1 {
+		t.Errorf("created spreadsheet = %+v", budget)
+	}
+	found, err := sheets.FindByID(ctx, budget.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Data != "data of Budget 2025" || found.Owner == nil || found.Owner.ID != ada.ID {
+		t.Errorf("FindByID = %+v", found)
+	}
+	found, err = sheets.FindByIDWithoutData(ctx, budget.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Data != "" || found.Title != "Budget 2025" || found.Version != 1 {
+		t.Errorf("FindByIDWithoutData = %+v", found)
+	}
+
+	// Data writes are versioned, other fields are not.
+	stale := *budget
+	if err := sheets.Update(ctx, budget, map[string]any{"data": "v2"}); err != nil {
+		t.Fatal(err)
+	}
+	if budget.Version != 2 {
+		t.Errorf("version after save = %d, want 2", budget.Version)
+	}
+	if err := sheets.Update(ctx, &stale, map[string]any{"data": "lost"}); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("stale save: got %v, want ErrConflict", err)
+	}
+	if err := sheets.Update(ctx, &stale, map[string]any{"title": "Budget 2025"}); err != nil {
+		t.Errorf("rename with an old version: %v", err)
+	}
+
+	// Bob's budget is shared with Ada; his notes are not.
+	err = NewPermissionRepo(db).Upsert(ctx, &domain.SpreadsheetPermission{SpreadsheetID: shared.ID, UserID: ada.ID, Role: domain.RoleViewer})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := func(q domain.SpreadsheetQuery) ([]string, int64) {
+		t.Helper()
+		q.UserID = ada.ID
+		if q.Sort == "" {
+			q.Sort = domain.SortTitle
+		}
+		if q.Limit == 0 {
+			q.Limit = 50
+		}
+		rows, total, err := sheets.ListVisible(ctx, q)
+		if err != nil {
+			t.Fatalf("ListVisible(%+v): %v", q, err)
+		}
+		titles := make([]string, len(rows))
+		for i, r := range rows {
+			titles[i] = r.Title
+			if r.Owner == nil {
+				t.Errorf("%s listed without its owner", r.Title)
+			}
+		}
+		return titles, total
+	}
+	assertTitles(t, "owned", first(list(domain.SpreadsheetQuery{Owned: true})), "Budget 2025", "budget_draft", "roadmap")
+	assertTitles(t, "shared", first(list(domain.SpreadsheetQuery{Shared: true})), "Bob's budget")
+	assertTitles(t, "title filter", first(list(domain.SpreadsheetQuery{Owned: true, Shared: true, Title: "BUDGET"})),
+		"Bob's budget", "Budget 2025", "budget_draft")
+	// LIKE wildcards in the filter match literally.
+	assertTitles(t, "underscore", first(list(domain.SpreadsheetQuery{Owned: true, Title: "t_d"})), "budget_draft")
+	assertTitles(t, "descending", first(list(domain.SpreadsheetQuery{Owned: true, Descending: true})), "roadmap", "budget_draft", "Budget 2025")
+
+	// Paging by keyset visits every row once and reports the full count.
+	var paged []string
+	q := domain.SpreadsheetQuery{UserID: ada.ID, Owned: true, Shared: true, Sort: domain.SortCreated, Limit: 2}
+	for {
+		rows, total, err := sheets.ListVisible(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if total != 4 {
+			t.Fatalf("total = %d, want 4", total)
+		}
+		for _, r := range rows {
+			paged = append(paged, r.Title)
+		}
+		if len(rows) < q.Limit {
+			break
+		}
+		last := rows[len(rows)-1]
+		q.After = &domain.SpreadsheetCursor{Time: last.CreatedAt, ID: last.ID}
+	}
+	assertTitles(t, "paged", paged, "Budget 2025", "budget_draft", "roadmap", "Bob's budget")
+
+	// Trash, restore and permanent deletion are limited to the owner.
+	if err := sheets.Trash(ctx, budget.ID, bob.ID); err == nil {
+		t.Error("trashed someone else's spreadsheet")
+	}
+	if err := sheets.Trash(ctx, budget.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sheets.FindByID(ctx, budget.ID); err == nil {
+		t.Error("FindByID returned a trashed spreadsheet")
+	}
+	assertTitles(t, "owned after trashing", first(list(domain.SpreadsheetQuery{Owned: true})), "budget_draft", "roadmap")
+	trashed, err := sheets.ListTrashedByOwner(ctx, ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 1 || trashed[0].ID != budget.ID || trashed[0].DeletedAt == nil {
+		t.Errorf("trashed = %+v", trashed)
+	}
+	old, err := sheets.ListTrashedBefore(ctx, time.Now().Add(time.Minute), 10)
+	if err != nil || len(old) != 1 {
+		t.Errorf("ListTrashedBefore = %+v, %v", old, err)
+	}
+	if err := sheets.Restore(ctx, budget.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheets.Restore(ctx, budget.ID, ada.ID); err == nil {
+		t.Error("restored a spreadsheet that isn't in the trash")
+	}
+	if err := sheets.Delete(ctx, budget.ID, ada.ID); err == nil {
+		t.Error("deleted a spreadsheet that isn't in the trash")
+	}
+	if err := sheets.SaveData(ctx, budget, map[string]any{"data": "v3"}, ada.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheets.Trash(ctx, budget.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheets.Delete(ctx, budget.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if revs, err := NewRevisionRepo(db).ListBySpreadsheet(ctx, budget.ID); err != nil || len(revs) != 0 {
+		t.Errorf("revisions after delete = %d, %v", len(revs), err)
+	}
+
+	if n, err := sheets.CountByWorkspace(ctx, 1); err != nil || n != 1 {
+		t.Errorf("CountByWorkspace = %d, %v", n, err)
+	}
+}
+
+func TestOrganizationRepositoryConformance(t *testing.T) {
+	forEachDB(t, testOrganizationRepositoryConformance)
+}
+
+func testOrganizationRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var orgs domain.OrganizationRepository = NewOrganizationRepo(db)
+	workspaces := NewWorkspaceRepo(db)
+	sheets := NewSpreadsheetRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+
+	org := &domain.Organization{Name: "Acme"}
+	if err := orgs.Create(ctx, org, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := orgs.UpsertMember(ctx, &domain.OrganizationMember{OrganizationID: org.ID, UserID: bob.ID, Role: domain.OrgMember}); err != nil {
+		t.Fatal(err)
+	}
+	team := &domain.Workspace{Name: "Team", OrganizationID: &org.ID, MemberRole: domain.RoleEditor}
+	if err := workspaces.Create(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	personal, err := workspaces.FindOrCreatePersonal(ctx, bob.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	create := func(title string, workspaceID uint) *domain.Spreadsheet {
+		t.Helper()
+		s := &domain.Spreadsheet{Title: title, OwnerID: bob.ID, WorkspaceID: workspaceID}
+		if err := sheets.Create(ctx, s); err != nil {
+			t.Fatalf("create %s: %v", title, err)
+		}
+		return s
+	}
+	ownerOf := func(s *domain.Spreadsheet) uint {
+		t.Helper()
+		var row Spreadsheet
+		if err := db.Unscoped().First(&row, s.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		return row.OwnerID
+	}
+	plan := create("plan", team.ID)
+	old := create("old plan", team.ID)
+	notes := create("notes", personal.ID)
+	if err := sheets.Trash(ctx, old.ID, bob.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := orgs.RemoveMember(ctx, org.ID, bob.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := orgs.FindMember(ctx, org.ID, bob.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("FindMember after RemoveMember err = %v, want ErrNotFound", err)
+	}
+	if ownerOf(plan) != ada.ID || ownerOf(old) != ada.ID {
+		t.Error("spreadsheets in the organization's workspaces didn't pass to the heir, trashed ones included")
+	}
+	if ownerOf(notes) != bob.ID {
+		t.Error("a spreadsheet in the member's personal workspace changed owner")
+	}
+
+	// Removing someone who isn't a member changes nothing.
+	later := create("later", team.ID)
+	if err := orgs.RemoveMember(ctx, org.ID, bob.ID, ada.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("RemoveMember of a non-member err = %v, want ErrNotFound", err)
+	}
+	if ownerOf(later) != bob.ID {
+		t.Error("RemoveMember of a non-member transferred spreadsheets")
+	}
+}
+
+func TestCommentRepositoryConformance(t *testing.T) {
+	forEachDB(t, testCommentRepositoryConformance)
+}
+
+func testCommentRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var comments domain.CommentRepository = NewCommentRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	personal, err := NewWorkspaceRepo(db).FindOrCreatePersonal(ctx, ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheet := &domain.Spreadsheet{Title: "Budget", OwnerID: ada.ID, WorkspaceID: personal.ID}
+	if err := NewSpreadsheetRepo(db).Create(ctx, sheet); err != nil {
+		t.Fatal(err)
+	}
+	add := func(c *domain.Comment) *domain.Comment {
+		t.Helper()
+		c.SpreadsheetID, c.AuthorID, c.Body = sheet.ID, ada.ID, "note"
+		if err := comments.Create(ctx, c, nil); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	anchored := add(&domain.Comment{Sheet: "Costs", Anchor: "B3"})
+	add(&domain.Comment{ThreadID: &anchored.ID})
+	deleted := add(&domain.Comment{Sheet: "Costs", Anchor: ""})
+	add(&domain.Comment{Sheet: "Sales", Anchor: "A1"})
+
+	// Threads whose cells were deleted are listed too, replies are not.
+	list, err := comments.ListOnSheet(ctx, sheet.ID, "Costs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]uint, len(list))
+	for i, c := range list {
+		ids[i] = c.ID
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []uint{anchored.ID, deleted.ID}) {
+		t.Errorf("ListOnSheet = %v, want threads %d and %d", ids, anchored.ID, deleted.ID)
+	}
+
+	err = comments.SetAnchors(ctx, map[uint]domain.CommentAnchor{anchored.ID: {Sheet: "Budget", Anchor: "C4"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved, err := comments.FindByID(ctx, anchored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.Sheet != "Budget" || moved.Anchor != "C4" {
+		t.Errorf("after SetAnchors the thread is on %s!%s, want Budget!C4", moved.Sheet, moved.Anchor)
+	}
+}
+
+func TestSearchRepositoryConformance(t *testing.T) {
+	forEachDB(t, testSearchRepositoryConformance)
+}
+
+func testSearchRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	repo, err := NewSearchRepo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var search domain.SearchRepository = repo
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+	create := func(title string, owner *domain.User, cells ...string) *domain.Spreadsheet {
+		t.Helper()
+		s := &domain.Spreadsheet{Title: title, OwnerID: owner.ID}
+		if err := NewSpreadsheetRepo(db).Create(ctx, s); err != nil {
+			t.Fatal(err)
+		}
+		entries := []domain.SearchEntry{{Text: title}}
+		for i, text := range cells {
+			entries = append(entries, domain.SearchEntry{Sheet: "Sheet1", Cell: fmt.Sprintf("A%d", i+1), Text: text})
+		}
+		if err := search.Replace(ctx, s.ID, entries); err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	budget := create("Budget", ada, "Quarterly Costs", "pi is 3.14")
+	create("Menu", ada, "Crème brûlée")
+	create("Costs", bob)
+
+	found := func(terms ...string) []uint {
+		t.Helper()
+		sheets, err := search.Search(ctx, ada.ID, terms, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]uint, len(sheets))
+		for i, s := range sheets {
+			ids[i] = s.ID
+		}
+		return ids
+	}
+	// Words match from their start, ignoring case; every term must match,
+	// and other people's spreadsheets stay hidden.
+	for _, terms := range [][]string{{"cost"}, {"COSTS"}, {"3.14"}, {"quarterly", "pi"}} {
+		if ids := found(terms...); !slices.Equal(ids, []uint{budget.ID}) {
+			t.Errorf("Search(%q) = %v, want [%d]", terms, ids, budget.ID)
+		}
+	}
+	if ids := found("cost", "brûlée"); len(ids) != 0 {
+		t.Errorf("Search with terms in different spreadsheets = %v", ids)
+	}
+
+	// Replacing entries drops the old ones from the index.
+	if err := search.Replace(ctx, budget.ID, []domain.SearchEntry{{Text: "Budget"}}); err != nil {
+		t.Fatal(err)
+	}
+	if ids := found("cost"); len(ids) != 0 {
+		t.Errorf("Search after Replace = %v", ids)
+	}
+	matches, err := search.Matches(ctx, budget.ID, []string{"budg"}, 10)
+	if err != nil || len(matches) != 1 || matches[0].Text != "Budget" {
+		t.Errorf("Matches = %+v, %v", matches, err)
+	}
+}
+
+func TestSessionRepositoryConformance(t *testing.T) {
+	forEachDB(t, testSessionRepositoryConformance)
+}
+
+func testSessionRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var sessions domain.SessionRepository = NewSessionRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+	now := time.Now()
+
+	create := func(token string, user *domain.User, seen, expires time.Time) *domain.Session {
+		t.Helper()
+		s := &domain.Session{Token: token, UserID: user.ID, UserAgent: "test", IP: "192.0.2.1", LastSeenAt: seen, ExpiresAt: expires}
+		if err := sessions.Create(ctx, s); err != nil {
+			t.Fatalf("create session %s: %v", token, err)
+		}
+		return s
+	}
+	older := create("t-older", ada, now.Add(-time.Hour), now.Add(time.Hour))
+	newer := create("t-newer", ada, now, now.Add(time.Hour))
+	create("t-expired", ada, now.Add(-2*time.Hour), now.Add(-time.Minute))
+	other := create("t-bob", bob, now, now.Add(time.Hour))
+
+	found, err := sessions.FindValidByToken(ctx, "t-newer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != newer.ID || found.User == nil || found.User.Email != "ada@example.com" {
+		t.Errorf("FindValidByToken = %+v", found)
+	}
+	if _, err := sessions.FindValidByToken(ctx, "t-expired"); err == nil {
+		t.Error("found an expired session")
+	}
+
+	valid, err := sessions.ListValidByUser(ctx, ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(valid) != 2 || valid[0].ID != newer.ID || valid[1].ID != older.ID {
+		t.Errorf("ListValidByUser = %+v", valid)
+	}
+
+	// Touching the older session makes it the most recently seen.
+	if err := sessions.Touch(ctx, older.ID, now.Add(time.Minute), "198.51.100.7", now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	valid, _ = sessions.ListValidByUser(ctx, ada.ID)
+	if len(valid) != 2 || valid[0].ID != older.ID || valid[0].IP != "198.51.100.7" {
+		t.Errorf("after Touch = %+v", valid)
+	}
+
+	if err := sessions.Delete(ctx, other.ID, ada.ID); err == nil {
+		t.Error("deleted another user's session")
+	}
+	if err := sessions.DeleteByTokenAndUser(ctx, "t-bob", ada.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sessions.FindValidByToken(ctx, "t-bob"); err != nil {
+		t.Error("DeleteByTokenAndUser removed another user's session")
+	}
+
+	n, err := sessions.DeleteOthers(ctx, ada.ID, newer.ID)
+	if err != nil || n != 2 {
+		t.Errorf("DeleteOthers = %d, %v; want 2 (including the expired one)", n, err)
+	}
+	valid, _ = sessions.ListValidByUser(ctx, ada.ID)
+	if len(valid) != 1 || valid[0].ID != newer.ID {
+		t.Errorf("after DeleteOthers = %+v", valid)
+	}
+	if err := sessions.Delete(ctx, newer.ID, ada.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	create("t-stale", bob, now.Add(-2*time.Hour), now.Add(-time.Minute))
+	n, err = sessions.DeleteExpired(ctx, now)
+	if err != nil || n != 1 {
+		t.Errorf("DeleteExpired = %d, %v; want 1", n, err)
+	}
+	if _, err := sessions.FindValidByToken(ctx, "t-bob"); err != nil {
+		t.Errorf("DeleteExpired removed a valid session: %v", err)
+	}
+}
+
+func TestPasswordResetRepositoryConformance(t *testing.T) {
+	forEachDB(t, testPasswordResetRepositoryConformance)
+}
+
+func testPasswordResetRepositoryConformance(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	var resets domain.PasswordResetRepository = NewPasswordResetRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+	now := time.Now()
+
+	create := func(hash string, user *domain.User, expires time.Time) {
+		t.Helper()
+		if err := resets.Create(ctx, &domain.PasswordReset{UserID: user.ID, TokenHash: hash, ExpiresAt: expires}); err != nil {
+			t.Fatalf("create password reset %s: %v", hash, err)
+		}
+	}
+	create("h-first", ada, now.Add(time.Hour))
+	create("h-second", ada, now.Add(time.Hour))
+	create("h-expired", bob, now.Add(-time.Minute))
+	create("h-bob", bob, now.Add(time.Hour))
+
+	if _, err := resets.Consume(ctx, "h-expired", now); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Consume(expired) err = %v, want ErrNotFound", err)
+	}
+	reset, err := resets.Consume(ctx, "h-second", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.UserID != ada.ID {
+		t.Errorf("Consume = %+v", reset)
+	}
+	// Using one link voids the user's other links, and each works once.
+	for _, hash := range []string{"h-second", "h-first"} {
+		if _, err := resets.Consume(ctx, hash, now); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("Consume(%s) after use err = %v, want ErrNotFound", hash, err)
+		}
+	}
+
+	n, err := resets.DeleteExpired(ctx, now)
+	if err != nil || n != 1 {
+		t.Errorf("DeleteExpired = %d, %v; want 1", n, err)
+	}
+	if _, err := resets.Consume(ctx, "h-bob", now); err != nil {
+		t.Errorf("another user's link stopped working: %v", err)
+	}
+}
+
+func TestMigrationsRollBackAndReapply(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		ctx := context.Background()
+		createTestUser(t, db, "ada@example.com")
+		before := schemaIndexes(t, db)
+
+		applied, err := MigrationStatus(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := MigrateDown(db, len(applied)-1, false); err != nil {
+			t.Fatalf("roll back to the baseline: %v", err)
+		}
+		if _, err := MigrateUp(db); err != nil {
+			t.Fatalf("reapply: %v", err)
+		}
+		if _, err := NewUserRepo(db).FindByEmail(ctx, "ADA@example.com"); err != nil {
+			t.Errorf("user lost in the roundtrip: %v", err)
+		}
+		pending, err := PendingMigrations(db)
+		if err != nil || len(pending) != 0 {
+			t.Errorf("pending after reapplying = %d, %v", len(pending), err)
+		}
+		if after := schemaIndexes(t, db); !slices.Equal(after, before) {
+			t.Errorf("indexes after the roundtrip:\n%v\nwant\n%v", after, before)
+		}
+	})
+}
+
+// schemaIndexes lists every index as "table.name", sorted.
+func schemaIndexes(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	query := "SELECT tbl_name || '.' || name FROM sqlite_master WHERE type = 'index' ORDER BY 1"
+	if db.Dialector.Name() == "postgres" {
+		query = "SELECT tablename || '.' || indexname FROM pg_indexes WHERE schemaname = current_schema() ORDER BY 1"
+	}
+	var out []string
+	if err := db.Raw(query).Scan(&out).Error; err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func first(titles []string, _ int64) []string {
+	return titles
+}
+
+func assertTitles(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s: got %q, want %q", what, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: got %q, want %q", what, got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package gormrepo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/repository/sqlite"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// postgresDSNEnv names the variable that points the tests at a PostgreSQL
+// server. Without it only SQLite is tested.
+const postgresDSNEnv = "GRIDS_TEST_POSTGRES_DSN"
+
+// forEachDB runs fn as a subtest against a freshly migrated database of
+// every supported driver.
+func forEachDB(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	t.Helper()
+	t.Run("sqlite", func(t *testing.T) {
+		fn(t, openTestDB(t))
+	})
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv(postgresDSNEnv)
+		if dsn == "" {
+			t.Skip(postgresDSNEnv + " is not set")
+		}
+		fn(t, openTestPostgres(t, dsn))
+	})
+}
+
+// openTestDB returns a migrated SQLite database in a temporary directory.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := sqlite.Open(filepath.Join(t.TempDir(), "test.db"))
+	closeOnCleanup(t, db)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// openTestPostgres returns a migrated database in a schema of its own,
+// dropped when the test ends, so tests don't see each other's rows.
+func openTestPostgres(t *testing.T, dsn string) *gorm.DB {
+	t.Helper()
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	closeOnCleanup(t, admin)
+
+	buf := make([]byte, 6)
+	rand.Read(buf)
+	schema := "grids_test_" + hex.EncodeToString(buf)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := admin.Exec("DROP SCHEMA " + schema + " CASCADE").Error; err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+	})
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config)
+	if err != nil {
+		t.Fatalf("connect to postgres: %v", err)
+	}
+	closeOnCleanup(t, db)
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// withSearchPath adds a search_path parameter to a URL or key=value DSN.
+func withSearchPath(dsn, schema string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		return dsn + sep + "search_path=" + schema
+	}
+	return dsn + " search_path=" + schema
+}
+
+func closeOnCleanup(t *testing.T, db *gorm.DB) {
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string) *domain.User {
+	t.Helper()
+	user := &domain.User{Email: email, Name: email}
+	if err := NewUserRepo(db).Create(context.Background(), user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
@@ -0,0 +1,73 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailRepo struct {
+	db *gorm.DB
+}
+
+func NewEmailRepo(db *gorm.DB) *EmailRepo {
+	return &EmailRepo{db: db}
+}
+
+func (r *EmailRepo) Enqueue(ctx context.Context, email *domain.Email) error {
+	row := Email{
+		To:            email.To,
+		Subject:       email.Subject,
+		Text:          email.Text,
+		HTML:          email.HTML,
+		NextAttemptAt: email.NextAttemptAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	email.ID = row.ID
+	email.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *EmailRepo) Due(ctx context.Context, now time.Time, limit int) ([]domain.Email, error) {
+	var rows []Email
+	err := r.db.WithContext(ctx).
+		Where("sent_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+		Order("next_attempt_at, id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Email, len(rows))
+	for i, e := range rows {
+		out[i] = toDomainEmail(e)
+	}
+	return out, nil
+}
+
+func (r *EmailRepo) MarkSent(ctx context.Context, id uint, attempts int, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Email{}).Where("id = ?", id).
+		Updates(map[string]any{"attempts": attempts, "sent_at": at, "last_error": ""}).Error
+}
+
+func (r *EmailRepo) Retry(ctx context.Context, id uint, attempts int, lastError string, next time.Time) error {
+	return r.db.WithContext(ctx).Model(&Email{}).Where("id = ?", id).
+		Updates(map[string]any{"attempts": attempts, "last_error": lastError, "next_attempt_at": next}).Error
+}
+
+func (r *EmailRepo) GiveUp(ctx context.Context, id uint, attempts int, lastError string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Email{}).Where("id = ?", id).
+		Updates(map[string]any{"attempts": attempts, "last_error": lastError, "failed_at": at}).Error
+}
+
+func (r *EmailRepo) DeleteFinishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("sent_at < ? OR failed_at < ?", before, before).
+		Delete(&Email{})
+	return result.RowsAffected, result.Error
+}
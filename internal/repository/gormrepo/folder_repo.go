@@ -0,0 +1,102 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type FolderRepo struct {
+	db *gorm.DB
+}
+
+func NewFolderRepo(db *gorm.DB) *FolderRepo {
+	return &FolderRepo{db: db}
+}
+
+func (r *FolderRepo) Create(ctx context.Context, folder *domain.Folder) error {
+	f := Folder{
+		WorkspaceID: folder.WorkspaceID,
+		ParentID:    folder.ParentID,
+		Name:        folder.Name,
+	}
+	if err := r.db.WithContext(ctx).Create(&f).Error; err != nil {
+		return err
+	}
+	folder.ID = f.ID
+	folder.CreatedAt = f.CreatedAt
+	folder.UpdatedAt = f.UpdatedAt
+	return nil
+}
+
+func (r *FolderRepo) FindByID(ctx context.Context, id uint) (*domain.Folder, error) {
+	var f Folder
+	if err := r.db.WithContext(ctx).First(&f, id).Error; err != nil {
+		return nil, err
+	}
+	folder := toDomainFolder(f)
+	return &folder, nil
+}
+
+func (r *FolderRepo) ListByWorkspace(ctx context.Context, workspaceID uint) ([]domain.Folder, error) {
+	var rows []Folder
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ?", workspaceID).
+		Order("name ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Folder, len(rows))
+	for i, f := range rows {
+		out[i] = toDomainFolder(f)
+	}
+	return out, nil
+}
+
+func (r *FolderRepo) Update(ctx context.Context, folder *domain.Folder) error {
+	f := Folder{ID: folder.ID}
+	result := r.db.WithContext(ctx).Model(&f).Updates(map[string]any{
+		"name":      folder.Name,
+		"parent_id": folder.ParentID,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := r.db.WithContext(ctx).First(&f, f.ID).Error; err != nil {
+		return err
+	}
+	folder.UpdatedAt = f.UpdatedAt
+	return nil
+}
+
+func (r *FolderRepo) Delete(ctx context.Context, folder *domain.Folder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&Folder{}).
+			Where("parent_id = ?", folder.ID).
+			Update("parent_id", folder.ParentID).Error
+		if err != nil {
+			return err
+		}
+		// UpdateColumn keeps updated_at: the spreadsheets themselves are unchanged.
+		err = tx.Unscoped().Model(&Spreadsheet{}).
+			Where("folder_id = ?", folder.ID).
+			UpdateColumn("folder_id", folder.ParentID).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Delete(&Folder{}, folder.ID)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,186 @@
+// Package gormrepo implements the domain repositories on top of GORM. The
+// queries are portable SQL, so the same implementation backs every
+// supported database; the sqlite and postgres packages only open the
+// connection.
+package gormrepo
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one versioned schema change. Up and Down run inside a
+// transaction together with the schema_migrations bookkeeping, so a failed
+// step leaves no trace. Migrations must not use the models in models.go:
+// those follow the latest schema, while a migration has to keep working
+// against the schema it was written for.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration records an applied migration.
+type SchemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"not null"`
+	AppliedAt time.Time
+}
+
+// MigrationState is a migration together with when it was applied.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+func ensureMigrationTable(db *gorm.DB) error {
+	return db.AutoMigrate(&SchemaMigration{})
+}
+
+func appliedVersions(db *gorm.DB) (map[int]time.Time, error) {
+	if err := ensureMigrationTable(db); err != nil {
+		return nil, fmt.Errorf("create schema_migrations: %w", err)
+	}
+	var rows []SchemaMigration
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	applied := make(map[int]time.Time, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+// MigrationStatus lists every known migration in order, with the time it
+// was applied (nil if pending).
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return nil, err
+	}
+	states := make([]MigrationState, len(migrations))
+	for i, m := range migrations {
+		states[i] = MigrationState{Migration: m}
+		if at, ok := applied[m.Version]; ok {
+			states[i].AppliedAt = &at
+		}
+	}
+	return states, nil
+}
+
+// PendingMigrations returns the migrations that have not been applied.
+func PendingMigrations(db *gorm.DB) ([]Migration, error) {
+	states, err := MigrationStatus(db)
+	if err != nil {
+		return nil, err
+	}
+	var pending []Migration
+	for _, s := range states {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// baselineVersion is the migration that creates the original tables.
+// Rolling it back drops them along with all data, so MigrateDown only does
+// it when forced.
+const baselineVersion = 1
+
+// ErrBaselineRollback is returned by MigrateDown when it would roll back
+// the baseline migration without being forced to.
+var ErrBaselineRollback = errors.New("refusing to roll back the baseline migration, which drops every table")
+
+// migrationLockKey identifies the PostgreSQL advisory lock held while
+// migrating. The value is arbitrary but must never change.
+const migrationLockKey int64 = 0x6a67_6d69_6772
+
+// withMigrationLock runs fn while holding a lock that keeps other
+// processes, such as several replicas starting at once, from migrating the
+// same database concurrently. On PostgreSQL that is a session-level
+// advisory lock, so fn gets the connection it is held on. SQLite allows a
+// single writer, and a process that loses the race fails its migration's
+// transaction instead of applying it twice.
+func withMigrationLock(db *gorm.DB, fn func(db *gorm.DB) error) error {
+	if db.Dialector.Name() != "postgres" {
+		return fn(db)
+	}
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", migrationLockKey).Error; err != nil {
+			return fmt.Errorf("lock migrations: %w", err)
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", migrationLockKey)
+		return fn(conn)
+	})
+}
+
+// MigrateUp applies all pending migrations in order and returns them.
+// Pending migrations are looked up after taking the migration lock, so a
+// process that waited for another one only applies what is still missing.
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	var applied []Migration
+	err := withMigrationLock(db, func(db *gorm.DB) error {
+		pending, err := PendingMigrations(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown rolls back the most recently applied steps migrations, newest
+// first, and returns them. Unless force is set it returns
+// ErrBaselineRollback, before rolling back anything, if that would include
+// the baseline.
+func MigrateDown(db *gorm.DB, steps int, force bool) ([]Migration, error) {
+	var rolledBack []Migration
+	err := withMigrationLock(db, func(db *gorm.DB) error {
+		states, err := MigrationStatus(db)
+		if err != nil {
+			return err
+		}
+		var targets []Migration
+		for i := len(states) - 1; i >= 0 && len(targets) < steps; i-- {
+			if states[i].AppliedAt != nil {
+				targets = append(targets, states[i].Migration)
+			}
+		}
+		if !force && len(targets) > 0 && targets[len(targets)-1].Version == baselineVersion {
+			return ErrBaselineRollback
+		}
+
+		for _, m := range targets {
+			err := db.Transaction(func(tx *gorm.DB) error {
+				if err := m.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, m.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("rollback %d (%s): %w", m.Version, m.Name, err)
+			}
+			rolledBack = append(rolledBack, m)
+		}
+		return nil
+	})
+	return rolledBack, err
+}
@@ -0,0 +1,73 @@
+package gormrepo
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestMigrateDownKeepsBaselineUnlessForced(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		rolledBack, err := MigrateDown(db, len(migrations), false)
+		if !errors.Is(err, ErrBaselineRollback) {
+			t.Fatalf("got %v, want ErrBaselineRollback", err)
+		}
+		if len(rolledBack) != 0 {
+			t.Fatalf("rolled back %d migrations before refusing", len(rolledBack))
+		}
+		if pending, _ := PendingMigrations(db); len(pending) != 0 {
+			t.Fatalf("%d migrations pending after refusing", len(pending))
+		}
+
+		rolledBack, err = MigrateDown(db, len(migrations), true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rolledBack) != len(migrations) {
+			t.Errorf("rolled back %d migrations, want %d", len(rolledBack), len(migrations))
+		}
+		if db.Migrator().HasTable("users") {
+			t.Error("users table survived rolling back the baseline")
+		}
+	})
+}
+
+// Replicas starting at the same time all run MigrateUp; each migration
+// must be applied exactly once and none of them may fail.
+func TestConcurrentMigrateUp(t *testing.T) {
+	forEachDB(t, func(t *testing.T, db *gorm.DB) {
+		if db.Dialector.Name() != "postgres" {
+			t.Skip("only PostgreSQL takes a migration lock")
+		}
+		if _, err := MigrateDown(db, len(migrations), true); err != nil {
+			t.Fatal(err)
+		}
+
+		const replicas = 4
+		var wg sync.WaitGroup
+		applied := make([]int, replicas)
+		errs := make([]error, replicas)
+		for i := range replicas {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				ms, err := MigrateUp(db)
+				applied[i], errs[i] = len(ms), err
+			}()
+		}
+		wg.Wait()
+
+		total := 0
+		for i := range replicas {
+			if errs[i] != nil {
+				t.Errorf("replica %d: %v", i, errs[i])
+			}
+			total += applied[i]
+		}
+		if total != len(migrations) {
+			t.Errorf("applied %d migrations in total, want %d", total, len(migrations))
+		}
+	})
+}
@@ -0,0 +1,641 @@
+package gormrepo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrations is the ordered schema history. Append new migrations with the
+// next version number; never edit one that has been released.
+var migrations = []Migration{
+	{
+		// The schema as created by AutoMigrate before versioned migrations
+		// existed. AutoMigrate only adds what is missing, so this also adopts
+		// databases created by earlier releases.
+		Version: 1,
+		Name:    "baseline",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&v1User{}, &v1Spreadsheet{}, &v1SpreadsheetPermission{}, &v1Revision{}, &v1Session{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v1Session{}, &v1Revision{}, &v1SpreadsheetPermission{}, &v1Spreadsheet{}, &v1User{})
+		},
+	},
+	{
+		Version: 2,
+		Name:    "api_tokens",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v2APIToken{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v2APIToken{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "session_devices",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range []string{"UserAgent", "IP", "LastSeenAt"} {
+				if err := m.AddColumn(&v3Session{}, field); err != nil {
+					return err
+				}
+			}
+			if err := tx.Exec("UPDATE sessions SET last_seen_at = created_at").Error; err != nil {
+				return err
+			}
+			return m.CreateIndex(&v3Session{}, "ExpiresAt")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.DropIndex(&v3Session{}, "ExpiresAt"); err != nil {
+				return err
+			}
+			// Plain DROP COLUMN, as in migration 14, so SQLite keeps the
+			// other sessions indexes.
+			for _, column := range []string{"user_agent", "ip", "last_seen_at"} {
+				if err := tx.Exec("ALTER TABLE sessions DROP COLUMN " + column).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		// Every existing user gets a personal workspace holding the
+		// spreadsheets they own.
+		Version: 4,
+		Name:    "organizations",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.CreateTable(&v4Organization{}, &v4OrganizationMember{}, &v4Workspace{}); err != nil {
+				return err
+			}
+			if err := m.AddColumn(&v4Spreadsheet{}, "WorkspaceID"); err != nil {
+				return err
+			}
+			if err := m.CreateIndex(&v4Spreadsheet{}, "WorkspaceID"); err != nil {
+				return err
+			}
+			if err := tx.Exec(`INSERT INTO workspaces (name, owner_id, member_role, created_at, updated_at)
+				SELECT 'Personal', id, '', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP FROM users`).Error; err != nil {
+				return err
+			}
+			return tx.Exec(`UPDATE spreadsheets SET workspace_id = (
+				SELECT w.id FROM workspaces w WHERE w.owner_id = spreadsheets.owner_id)`).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.DropIndex(&v4Spreadsheet{}, "WorkspaceID"); err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE spreadsheets DROP COLUMN workspace_id").Error; err != nil {
+				return err
+			}
+			return m.DropTable(&v4Workspace{}, &v4OrganizationMember{}, &v4Organization{})
+		},
+	},
+	{
+		Version: 5,
+		Name:    "folders",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.CreateTable(&v5Folder{}); err != nil {
+				return err
+			}
+			if err := m.AddColumn(&v5Spreadsheet{}, "FolderID"); err != nil {
+				return err
+			}
+			return m.CreateIndex(&v5Spreadsheet{}, "FolderID")
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.DropIndex(&v5Spreadsheet{}, "FolderID"); err != nil {
+				return err
+			}
+			if err := tx.Exec("ALTER TABLE spreadsheets DROP COLUMN folder_id").Error; err != nil {
+				return err
+			}
+			return m.DropTable(&v5Folder{})
+		},
+	},
+	{
+		// Indexes for sorting and paginating the spreadsheet list.
+		Version: 6,
+		Name:    "spreadsheet_list_indexes",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range v6SortFields {
+				if err := m.CreateIndex(&v6Spreadsheet{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			for _, field := range v6SortFields {
+				if err := m.DropIndex(&v6Spreadsheet{}, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 7,
+		Name:    "search_entries",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v7SearchEntry{})
+		},
+		Down: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			// Before migration 17, SearchRepo created the FTS5 table itself.
+			if m.HasTable(searchFTSTable) {
+				if err := m.DropTable(searchFTSTable); err != nil {
+					return err
+				}
+			}
+			return m.DropTable(&v7SearchEntry{})
+		},
+	},
+	{
+		Version: 8,
+		Name:    "share_links",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v8ShareLink{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v8ShareLink{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "comments",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v9Comment{}, &v9CommentMention{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v9CommentMention{}, &v9Comment{})
+		},
+	},
+	{
+		Version: 10,
+		Name:    "notifications",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v10Notification{}, &v10NotificationPreference{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v10NotificationPreference{}, &v10Notification{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "email_queue",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v11Email{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v11Email{})
+		},
+	},
+	{
+		Version: 12,
+		Name:    "webhooks",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v12Webhook{}, &v12WebhookDelivery{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v12WebhookDelivery{}, &v12Webhook{})
+		},
+	},
+	{
+		// Emails used to be stored as typed, so addresses differing only in
+		// case could belong to separate accounts. Such duplicates have to be
+		// merged by hand before this can run.
+		Version: 13,
+		Name:    "normalize_emails",
+		Up: func(tx *gorm.DB) error {
+			var dupes []string
+			if err := tx.Raw(`SELECT lower(trim(email)) FROM users
+				GROUP BY lower(trim(email)) HAVING count(*) > 1`).Scan(&dupes).Error; err != nil {
+				return err
+			}
+			if len(dupes) > 0 {
+				return fmt.Errorf("users share an email when ignoring case and spaces, merge them first: %s", strings.Join(dupes, ", "))
+			}
+			if err := tx.Exec("UPDATE users SET email = lower(trim(email))").Error; err != nil {
+				return err
+			}
+			return tx.Exec("CREATE UNIQUE INDEX idx_users_email_lower ON users (lower(email))").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP INDEX idx_users_email_lower").Error
+		},
+	},
+	{
+		// Remembers which single sign-on account a user is linked to, so an
+		// identity provider account that merely shares an email can't take
+		// over a password account.
+		Version: 14,
+		Name:    "oidc_identities",
+		Up: func(tx *gorm.DB) error {
+			m := tx.Migrator()
+			if err := m.AddColumn(&v14User{}, "OIDCIdentity"); err != nil {
+				return err
+			}
+			return m.CreateIndex(&v14User{}, "OIDCIdentity")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&v14User{}, "OIDCIdentity"); err != nil {
+				return err
+			}
+			// The SQLite migrator drops columns by rebuilding the table,
+			// which loses the users indexes; both databases support this.
+			return tx.Exec("ALTER TABLE users DROP COLUMN oidc_identity").Error
+		},
+	},
+	{
+		// Marks the revisions written by live-editing rooms, the only ones
+		// later saves may update in place.
+		Version: 15,
+		Name:    "live_revisions",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&v15Revision{}, "Live")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("ALTER TABLE revisions DROP COLUMN live").Error
+		},
+	},
+	{
+		Version: 16,
+		Name:    "password_resets",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&v16PasswordReset{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&v16PasswordReset{})
+		},
+	},
+	{
+		// PostgreSQL gets a tsvector column with a GIN index, SQLite an FTS5
+		// table when it is compiled in. Databases created by earlier
+		// releases may have the FTS5 table already, so it is filled afresh.
+		Version: 17,
+		Name:    "search_full_text",
+		Up: func(tx *gorm.DB) error {
+			switch {
+			case tx.Dialector.Name() == "postgres":
+				err := tx.Exec("ALTER TABLE search_entries ADD COLUMN content_tsv tsvector" +
+					" GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED").Error
+				if err != nil {
+					return err
+				}
+				return tx.Exec("CREATE INDEX idx_search_entries_content_tsv ON search_entries USING GIN (content_tsv)").Error
+			case fts5Available(tx):
+				err := tx.Exec("CREATE VIRTUAL TABLE IF NOT EXISTS " + searchFTSTable +
+					" USING fts5(content, tokenize = 'unicode61 remove_diacritics 2')").Error
+				if err != nil {
+					return err
+				}
+				return refillSearchFTS(tx)
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() == "postgres" {
+				if err := tx.Exec("DROP INDEX idx_search_entries_content_tsv").Error; err != nil {
+					return err
+				}
+				return tx.Exec("ALTER TABLE search_entries DROP COLUMN content_tsv").Error
+			}
+			if m := tx.Migrator(); m.HasTable(searchFTSTable) {
+				return m.DropTable(searchFTSTable)
+			}
+			return nil
+		},
+	},
+}
+
+// ── Schema snapshots ─────────────────────────
+
+type v1User struct {
+	ID           uint   `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex;not null"`
+	Name         string `gorm:"not null"`
+	AvatarURL    string
+	PasswordHash string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+func (v1User) TableName() string { return "users" }
+
+type v1Spreadsheet struct {
+	ID        uint   `gorm:"primaryKey"`
+	Title     string `gorm:"not null"`
+	OwnerID   uint   `gorm:"not null;index"`
+	Data      string `gorm:"type:text"`
+	Version   int    `gorm:"not null;default:1"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+func (v1Spreadsheet) TableName() string { return "spreadsheets" }
+
+type v1SpreadsheetPermission struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;uniqueIndex:idx_permission_sheet_user"`
+	UserID        uint   `gorm:"not null;uniqueIndex:idx_permission_sheet_user;index"`
+	Role          string `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (v1SpreadsheetPermission) TableName() string { return "spreadsheet_permissions" }
+
+type v1Revision struct {
+	ID            uint      `gorm:"primaryKey"`
+	SpreadsheetID uint      `gorm:"not null;index:idx_revision_sheet_created,priority:1"`
+	AuthorID      uint      `gorm:"not null"`
+	Data          string    `gorm:"type:text"`
+	Size          int       `gorm:"not null;default:0"`
+	CreatedAt     time.Time `gorm:"index:idx_revision_sheet_created,priority:2"`
+}
+
+func (v1Revision) TableName() string { return "revisions" }
+
+type v1Session struct {
+	ID        uint   `gorm:"primaryKey"`
+	Token     string `gorm:"uniqueIndex;not null"`
+	UserID    uint   `gorm:"not null;index"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (v1Session) TableName() string { return "sessions" }
+
+type v2APIToken struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"not null;index"`
+	Name          string `gorm:"not null"`
+	Prefix        string `gorm:"not null"`
+	TokenHash     string `gorm:"uniqueIndex;not null"`
+	Scope         string `gorm:"not null"`
+	SpreadsheetID *uint
+	ExpiresAt     *time.Time
+	LastUsedAt    *time.Time
+	LastUsedIP    string
+	CreatedAt     time.Time
+}
+
+func (v2APIToken) TableName() string { return "api_tokens" }
+
+type v3Session struct {
+	ID         uint   `gorm:"primaryKey"`
+	Token      string `gorm:"uniqueIndex;not null"`
+	UserID     uint   `gorm:"not null;index"`
+	UserAgent  string
+	IP         string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+func (v3Session) TableName() string { return "sessions" }
+
+type v4Organization struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (v4Organization) TableName() string { return "organizations" }
+
+type v4OrganizationMember struct {
+	ID             uint   `gorm:"primaryKey"`
+	OrganizationID uint   `gorm:"not null;uniqueIndex:idx_org_member_org_user"`
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_org_member_org_user;index"`
+	Role           string `gorm:"not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (v4OrganizationMember) TableName() string { return "organization_members" }
+
+type v4Workspace struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"not null"`
+	OrganizationID *uint  `gorm:"index"`
+	OwnerID        *uint  `gorm:"uniqueIndex"`
+	MemberRole     string `gorm:"not null;default:''"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (v4Workspace) TableName() string { return "workspaces" }
+
+type v4Spreadsheet struct {
+	ID          uint   `gorm:"primaryKey"`
+	Title       string `gorm:"not null"`
+	OwnerID     uint   `gorm:"not null;index"`
+	WorkspaceID uint   `gorm:"index"`
+	Data        string `gorm:"type:text"`
+	Version     int    `gorm:"not null;default:1"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v4Spreadsheet) TableName() string { return "spreadsheets" }
+
+type v5Folder struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	ParentID    *uint  `gorm:"index"`
+	Name        string `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (v5Folder) TableName() string { return "folders" }
+
+type v5Spreadsheet struct {
+	ID          uint   `gorm:"primaryKey"`
+	Title       string `gorm:"not null"`
+	OwnerID     uint   `gorm:"not null;index"`
+	WorkspaceID uint   `gorm:"index"`
+	FolderID    *uint  `gorm:"index"`
+	Data        string `gorm:"type:text"`
+	Version     int    `gorm:"not null;default:1"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v5Spreadsheet) TableName() string { return "spreadsheets" }
+
+var v6SortFields = []string{"Title", "CreatedAt", "UpdatedAt"}
+
+type v6Spreadsheet struct {
+	ID          uint           `gorm:"primaryKey"`
+	Title       string         `gorm:"not null;index"`
+	OwnerID     uint           `gorm:"not null;index"`
+	WorkspaceID uint           `gorm:"index"`
+	FolderID    *uint          `gorm:"index"`
+	Data        string         `gorm:"type:text"`
+	Version     int            `gorm:"not null;default:1"`
+	CreatedAt   time.Time      `gorm:"index"`
+	UpdatedAt   time.Time      `gorm:"index"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+func (v6Spreadsheet) TableName() string { return "spreadsheets" }
+
+type v7SearchEntry struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index"`
+	Sheet         string `gorm:"not null;default:''"`
+	Cell          string `gorm:"not null;default:''"`
+	Content       string `gorm:"type:text;not null"`
+}
+
+func (v7SearchEntry) TableName() string { return "search_entries" }
+
+type v8ShareLink struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index"`
+	CreatedByID   uint   `gorm:"not null"`
+	Prefix        string `gorm:"not null"`
+	TokenHash     string `gorm:"uniqueIndex;not null"`
+	Mode          string `gorm:"not null"`
+	PasswordHash  string
+	ExpiresAt     *time.Time
+	LastUsedAt    *time.Time
+	CreatedAt     time.Time
+}
+
+func (v8ShareLink) TableName() string { return "share_links" }
+
+type v9Comment struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index:idx_comment_sheet,priority:1"`
+	ThreadID      *uint  `gorm:"index"`
+	Sheet         string `gorm:"not null;default:'';index:idx_comment_sheet,priority:2"`
+	Anchor        string `gorm:"not null;default:''"`
+	AuthorID      uint   `gorm:"not null"`
+	Body          string `gorm:"type:text;not null"`
+	ResolvedAt    *time.Time
+	ResolvedByID  *uint
+	EditedAt      *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (v9Comment) TableName() string { return "comments" }
+
+type v9CommentMention struct {
+	CommentID uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey;index"`
+}
+
+func (v9CommentMention) TableName() string { return "comment_mentions" }
+
+type v10Notification struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"not null;index:idx_notification_user,priority:1"`
+	Type          string `gorm:"not null"`
+	ActorID       *uint
+	SpreadsheetID *uint `gorm:"index"`
+	CommentID     *uint
+	Message       string `gorm:"type:text;not null"`
+	ReadAt        *time.Time
+	CreatedAt     time.Time `gorm:"index:idx_notification_user,priority:2"`
+}
+
+func (v10Notification) TableName() string { return "notifications" }
+
+type v10NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey"`
+	Type    string `gorm:"primaryKey"`
+	Enabled bool   `gorm:"not null"`
+}
+
+func (v10NotificationPreference) TableName() string { return "notification_preferences" }
+
+type v11Email struct {
+	ID            uint      `gorm:"primaryKey"`
+	To            string    `gorm:"column:recipient;not null"`
+	Subject       string    `gorm:"not null"`
+	Text          string    `gorm:"type:text;not null"`
+	HTML          string    `gorm:"column:html;type:text;not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string    `gorm:"type:text"`
+	SentAt        *time.Time
+	FailedAt      *time.Time
+	CreatedAt     time.Time
+}
+
+func (v11Email) TableName() string { return "emails" }
+
+type v12Webhook struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID *uint  `gorm:"index"`
+	WorkspaceID   *uint  `gorm:"index"`
+	CreatedByID   uint   `gorm:"not null"`
+	URL           string `gorm:"not null"`
+	Events        string `gorm:"not null"` // comma-separated
+	Secret        string `gorm:"not null"`
+	Active        bool   `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (v12Webhook) TableName() string { return "webhooks" }
+
+type v12WebhookDelivery struct {
+	ID            uint   `gorm:"primaryKey"`
+	WebhookID     uint   `gorm:"not null;index"`
+	Event         string `gorm:"not null"`
+	Payload       string `gorm:"type:text;not null"`
+	Status        string `gorm:"not null;index:idx_delivery_due,priority:1"`
+	Attempts      int    `gorm:"not null;default:0"`
+	StatusCode    int
+	Error         string     `gorm:"column:last_error;type:text"`
+	NextAttemptAt *time.Time `gorm:"index:idx_delivery_due,priority:2"`
+	DeliveredAt   *time.Time
+	RedeliveryOf  *uint
+	CreatedAt     time.Time
+}
+
+func (v12WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type v14User struct {
+	ID           uint    `gorm:"primaryKey"`
+	OIDCIdentity *string `gorm:"column:oidc_identity;uniqueIndex:idx_users_oidc_identity"`
+}
+
+func (v14User) TableName() string { return "users" }
+
+type v15Revision struct {
+	ID   uint `gorm:"primaryKey"`
+	Live bool `gorm:"not null;default:false"`
+}
+
+func (v15Revision) TableName() string { return "revisions" }
+
+type v16PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (v16PasswordReset) TableName() string { return "password_resets" }
@@ -0,0 +1,556 @@
+package gormrepo
+
+import (
+	"encoding/json"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ── GORM models (persistence concern only) ───
+
+type User struct {
+	ID           uint   `gorm:"primaryKey"`
+	Email        string `gorm:"uniqueIndex;not null"` // stored normalized, also unique on lower(email)
+	Name         string `gorm:"not null"`
+	AvatarURL    string
+	PasswordHash string
+	OIDCIdentity *string `gorm:"column:oidc_identity;uniqueIndex:idx_users_oidc_identity"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type Spreadsheet struct {
+	ID          uint           `gorm:"primaryKey"`
+	Title       string         `gorm:"not null;index"`
+	OwnerID     uint           `gorm:"not null;index"`
+	Owner       User           `gorm:"foreignKey:OwnerID"`
+	WorkspaceID uint           `gorm:"index"`
+	FolderID    *uint          `gorm:"index"`
+	Data        string         `gorm:"type:text"`
+	Version     int            `gorm:"not null;default:1"`
+	CreatedAt   time.Time      `gorm:"index"`
+	UpdatedAt   time.Time      `gorm:"index"`
+	DeletedAt   gorm.DeletedAt `gorm:"index"`
+}
+
+type Organization struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type OrganizationMember struct {
+	ID             uint   `gorm:"primaryKey"`
+	OrganizationID uint   `gorm:"not null;uniqueIndex:idx_org_member_org_user"`
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_org_member_org_user;index"`
+	User           User   `gorm:"foreignKey:UserID"`
+	Role           string `gorm:"not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type Workspace struct {
+	ID             uint   `gorm:"primaryKey"`
+	Name           string `gorm:"not null"`
+	OrganizationID *uint  `gorm:"index"`
+	OwnerID        *uint  `gorm:"uniqueIndex"`
+	MemberRole     string `gorm:"not null;default:''"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type Folder struct {
+	ID          uint   `gorm:"primaryKey"`
+	WorkspaceID uint   `gorm:"not null;index"`
+	ParentID    *uint  `gorm:"index"`
+	Name        string `gorm:"not null"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+type ShareLink struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index"`
+	CreatedByID   uint   `gorm:"not null"`
+	Prefix        string `gorm:"not null"`
+	TokenHash     string `gorm:"uniqueIndex;not null"`
+	Mode          string `gorm:"not null"`
+	PasswordHash  string
+	ExpiresAt     *time.Time
+	LastUsedAt    *time.Time
+	CreatedAt     time.Time
+}
+
+type Comment struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index:idx_comment_sheet,priority:1"`
+	ThreadID      *uint  `gorm:"index"`
+	Sheet         string `gorm:"not null;default:'';index:idx_comment_sheet,priority:2"`
+	Anchor        string `gorm:"not null;default:''"`
+	AuthorID      uint   `gorm:"not null"`
+	Author        User   `gorm:"foreignKey:AuthorID"`
+	Body          string `gorm:"type:text;not null"`
+	Mentions      []User `gorm:"many2many:comment_mentions;joinForeignKey:CommentID;joinReferences:UserID"`
+	ResolvedAt    *time.Time
+	ResolvedByID  *uint
+	EditedAt      *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// CommentMention records a user @mentioned in a comment.
+type CommentMention struct {
+	CommentID uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"primaryKey;index"`
+}
+
+type Notification struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"not null;index:idx_notification_user,priority:1"`
+	Type          string `gorm:"not null"`
+	ActorID       *uint
+	Actor         *User `gorm:"foreignKey:ActorID"`
+	SpreadsheetID *uint `gorm:"index"`
+	CommentID     *uint
+	Message       string `gorm:"type:text;not null"`
+	ReadAt        *time.Time
+	CreatedAt     time.Time `gorm:"index:idx_notification_user,priority:2"`
+}
+
+// Webhook subscribes a URL to the events of a spreadsheet or workspace.
+type Webhook struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID *uint  `gorm:"index"`
+	WorkspaceID   *uint  `gorm:"index"`
+	CreatedByID   uint   `gorm:"not null"`
+	URL           string `gorm:"not null"`
+	Events        string `gorm:"not null"` // comma-separated
+	Secret        string `gorm:"not null"`
+	Active        bool   `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type WebhookDelivery struct {
+	ID            uint   `gorm:"primaryKey"`
+	WebhookID     uint   `gorm:"not null;index"`
+	Event         string `gorm:"not null"`
+	Payload       string `gorm:"type:text;not null"`
+	Status        string `gorm:"not null;index:idx_delivery_due,priority:1"`
+	Attempts      int    `gorm:"not null;default:0"`
+	StatusCode    int
+	Error         string     `gorm:"column:last_error;type:text"`
+	NextAttemptAt *time.Time `gorm:"index:idx_delivery_due,priority:2"`
+	DeliveredAt   *time.Time
+	RedeliveryOf  *uint
+	CreatedAt     time.Time
+}
+
+// Email is a queued outgoing email.
+type Email struct {
+	ID            uint      `gorm:"primaryKey"`
+	To            string    `gorm:"column:recipient;not null"`
+	Subject       string    `gorm:"not null"`
+	Text          string    `gorm:"type:text;not null"`
+	HTML          string    `gorm:"column:html;type:text;not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"index"`
+	LastError     string    `gorm:"type:text"`
+	SentAt        *time.Time
+	FailedAt      *time.Time
+	CreatedAt     time.Time
+}
+
+// NotificationPreference turns a notification type on or off for a user.
+type NotificationPreference struct {
+	UserID  uint   `gorm:"primaryKey"`
+	Type    string `gorm:"primaryKey"`
+	Enabled bool   `gorm:"not null"`
+}
+
+// SearchEntry is a row of the search index; see SearchRepo.
+type SearchEntry struct {
+	ID            uint   `gorm:"primaryKey"`
+	SpreadsheetID uint   `gorm:"not null;index"`
+	Sheet         string `gorm:"not null;default:''"`
+	Cell          string `gorm:"not null;default:''"`
+	Content       string `gorm:"type:text;not null"`
+}
+
+type SpreadsheetPermission struct {
+	ID            uint        `gorm:"primaryKey"`
+	SpreadsheetID uint        `gorm:"not null;uniqueIndex:idx_permission_sheet_user"`
+	Spreadsheet   Spreadsheet `gorm:"foreignKey:SpreadsheetID"`
+	UserID        uint        `gorm:"not null;uniqueIndex:idx_permission_sheet_user;index"`
+	User          User        `gorm:"foreignKey:UserID"`
+	Role          string      `gorm:"not null"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type Revision struct {
+	ID            uint      `gorm:"primaryKey"`
+	SpreadsheetID uint      `gorm:"not null;index:idx_revision_sheet_created,priority:1"`
+	AuthorID      uint      `gorm:"not null"`
+	Author        User      `gorm:"foreignKey:AuthorID"`
+	Data          string    `gorm:"type:text"`
+	Size          int       `gorm:"not null;default:0"`
+	Live          bool      `gorm:"not null;default:false"`
+	CreatedAt     time.Time `gorm:"index:idx_revision_sheet_created,priority:2"`
+}
+
+type Session struct {
+	ID         uint   `gorm:"primaryKey"`
+	Token      string `gorm:"uniqueIndex;not null"`
+	UserID     uint   `gorm:"not null;index"`
+	User       User   `gorm:"foreignKey:UserID"`
+	UserAgent  string
+	IP         string
+	LastSeenAt time.Time
+	ExpiresAt  time.Time `gorm:"index"`
+	CreatedAt  time.Time
+}
+
+type APIToken struct {
+	ID            uint   `gorm:"primaryKey"`
+	UserID        uint   `gorm:"not null;index"`
+	User          User   `gorm:"foreignKey:UserID"`
+	Name          string `gorm:"not null"`
+	Prefix        string `gorm:"not null"`
+	TokenHash     string `gorm:"uniqueIndex;not null"`
+	Scope         string `gorm:"not null"`
+	SpreadsheetID *uint
+	ExpiresAt     *time.Time
+	LastUsedAt    *time.Time
+	LastUsedIP    string
+	CreatedAt     time.Time
+}
+
+type PasswordReset struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// ── Mappers ──────────────────────────────────
+
+func toDomainUser(u User) domain.User {
+	return domain.User{
+		ID:           u.ID,
+		Email:        u.Email,
+		Name:         u.Name,
+		AvatarURL:    u.AvatarURL,
+		PasswordHash: u.PasswordHash,
+		OIDCIdentity: derefString(u.OIDCIdentity),
+		CreatedAt:    u.CreatedAt,
+		UpdatedAt:    u.UpdatedAt,
+	}
+}
+
+func toGormUser(u *domain.User) User {
+	return User{
+		ID:           u.ID,
+		Email:        u.Email,
+		Name:         u.Name,
+		AvatarURL:    u.AvatarURL,
+		PasswordHash: u.PasswordHash,
+		OIDCIdentity: nilIfEmpty(u.OIDCIdentity),
+	}
+}
+
+// nilIfEmpty stores an empty string as NULL, which unique indexes ignore.
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func toDomainSpreadsheet(s Spreadsheet) domain.Spreadsheet {
+	owner := toDomainUser(s.Owner)
+	sheet := domain.Spreadsheet{
+		ID:          s.ID,
+		Title:       s.Title,
+		OwnerID:     s.OwnerID,
+		Owner:       &owner,
+		WorkspaceID: s.WorkspaceID,
+		FolderID:    s.FolderID,
+		Data:        s.Data,
+		Version:     s.Version,
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+	}
+	if s.DeletedAt.Valid {
+		deletedAt := s.DeletedAt.Time
+		sheet.DeletedAt = &deletedAt
+	}
+	return sheet
+}
+
+func toDomainOrganization(o Organization) domain.Organization {
+	return domain.Organization{
+		ID:        o.ID,
+		Name:      o.Name,
+		CreatedAt: o.CreatedAt,
+		UpdatedAt: o.UpdatedAt,
+	}
+}
+
+func toDomainMember(m OrganizationMember) domain.OrganizationMember {
+	member := domain.OrganizationMember{
+		ID:             m.ID,
+		OrganizationID: m.OrganizationID,
+		UserID:         m.UserID,
+		Role:           domain.OrgRole(m.Role),
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+	if m.User.ID != 0 {
+		user := toDomainUser(m.User)
+		member.User = &user
+	}
+	return member
+}
+
+func toDomainWorkspace(w Workspace) domain.Workspace {
+	return domain.Workspace{
+		ID:             w.ID,
+		Name:           w.Name,
+		OrganizationID: w.OrganizationID,
+		OwnerID:        w.OwnerID,
+		MemberRole:     domain.Role(w.MemberRole),
+		CreatedAt:      w.CreatedAt,
+		UpdatedAt:      w.UpdatedAt,
+	}
+}
+
+func toDomainFolder(f Folder) domain.Folder {
+	return domain.Folder{
+		ID:          f.ID,
+		WorkspaceID: f.WorkspaceID,
+		ParentID:    f.ParentID,
+		Name:        f.Name,
+		CreatedAt:   f.CreatedAt,
+		UpdatedAt:   f.UpdatedAt,
+	}
+}
+
+func toDomainShareLink(l ShareLink) domain.ShareLink {
+	return domain.ShareLink{
+		ID:            l.ID,
+		SpreadsheetID: l.SpreadsheetID,
+		CreatedByID:   l.CreatedByID,
+		Prefix:        l.Prefix,
+		TokenHash:     l.TokenHash,
+		Mode:          domain.Role(l.Mode),
+		PasswordHash:  l.PasswordHash,
+		HasPassword:   l.PasswordHash != "",
+		ExpiresAt:     l.ExpiresAt,
+		LastUsedAt:    l.LastUsedAt,
+		CreatedAt:     l.CreatedAt,
+	}
+}
+
+func toDomainComment(c Comment) domain.Comment {
+	comment := domain.Comment{
+		ID:            c.ID,
+		SpreadsheetID: c.SpreadsheetID,
+		ThreadID:      c.ThreadID,
+		Sheet:         c.Sheet,
+		Anchor:        c.Anchor,
+		AuthorID:      c.AuthorID,
+		Body:          c.Body,
+		Mentions:      make([]domain.User, len(c.Mentions)),
+		ResolvedAt:    c.ResolvedAt,
+		ResolvedByID:  c.ResolvedByID,
+		EditedAt:      c.EditedAt,
+		CreatedAt:     c.CreatedAt,
+	}
+	if c.Author.ID != 0 {
+		author := toDomainUser(c.Author)
+		comment.Author = &author
+	}
+	for i, u := range c.Mentions {
+		comment.Mentions[i] = toDomainUser(u)
+	}
+	return comment
+}
+
+func toDomainNotification(n Notification) domain.Notification {
+	note := domain.Notification{
+		ID:            n.ID,
+		UserID:        n.UserID,
+		Type:          domain.NotificationType(n.Type),
+		ActorID:       n.ActorID,
+		SpreadsheetID: n.SpreadsheetID,
+		CommentID:     n.CommentID,
+		Message:       n.Message,
+		ReadAt:        n.ReadAt,
+		CreatedAt:     n.CreatedAt,
+	}
+	if n.Actor != nil {
+		actor := toDomainUser(*n.Actor)
+		note.Actor = &actor
+	}
+	return note
+}
+
+func toDomainWebhook(w Webhook) domain.Webhook {
+	var events []domain.WebhookEvent
+	for _, e := range strings.Split(w.Events, ",") {
+		if e != "" {
+			events = append(events, domain.WebhookEvent(e))
+		}
+	}
+	return domain.Webhook{
+		ID:            w.ID,
+		SpreadsheetID: w.SpreadsheetID,
+		WorkspaceID:   w.WorkspaceID,
+		CreatedByID:   w.CreatedByID,
+		URL:           w.URL,
+		Events:        events,
+		Secret:        w.Secret,
+		Active:        w.Active,
+		CreatedAt:     w.CreatedAt,
+		UpdatedAt:     w.UpdatedAt,
+	}
+}
+
+func joinEvents(events []domain.WebhookEvent) string {
+	parts := make([]string, len(events))
+	for i, e := range events {
+		parts[i] = string(e)
+	}
+	return strings.Join(parts, ",")
+}
+
+func toDomainWebhookDelivery(d WebhookDelivery) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		ID:            d.ID,
+		WebhookID:     d.WebhookID,
+		Event:         domain.WebhookEvent(d.Event),
+		Payload:       json.RawMessage(d.Payload),
+		Status:        d.Status,
+		Attempts:      d.Attempts,
+		StatusCode:    d.StatusCode,
+		Error:         d.Error,
+		NextAttemptAt: d.NextAttemptAt,
+		DeliveredAt:   d.DeliveredAt,
+		RedeliveryOf:  d.RedeliveryOf,
+		CreatedAt:     d.CreatedAt,
+	}
+}
+
+func toDomainEmail(e Email) domain.Email {
+	return domain.Email{
+		ID:            e.ID,
+		To:            e.To,
+		Subject:       e.Subject,
+		Text:          e.Text,
+		HTML:          e.HTML,
+		Attempts:      e.Attempts,
+		NextAttemptAt: e.NextAttemptAt,
+		LastError:     e.LastError,
+		SentAt:        e.SentAt,
+		FailedAt:      e.FailedAt,
+		CreatedAt:     e.CreatedAt,
+	}
+}
+
+func toDomainSearchEntry(e SearchEntry) domain.SearchEntry {
+	return domain.SearchEntry{
+		SpreadsheetID: e.SpreadsheetID,
+		Sheet:         e.Sheet,
+		Cell:          e.Cell,
+		Text:          e.Content,
+	}
+}
+
+func toDomainPermission(p SpreadsheetPermission) domain.SpreadsheetPermission {
+	perm := domain.SpreadsheetPermission{
+		ID:            p.ID,
+		SpreadsheetID: p.SpreadsheetID,
+		UserID:        p.UserID,
+		Role:          domain.Role(p.Role),
+		CreatedAt:     p.CreatedAt,
+		UpdatedAt:     p.UpdatedAt,
+	}
+	if p.User.ID != 0 {
+		user := toDomainUser(p.User)
+		perm.User = &user
+	}
+	if p.Spreadsheet.ID != 0 {
+		sheet := toDomainSpreadsheet(p.Spreadsheet)
+		perm.Spreadsheet = &sheet
+	}
+	return perm
+}
+
+func toDomainRevision(r Revision) domain.Revision {
+	rev := domain.Revision{
+		ID:            r.ID,
+		SpreadsheetID: r.SpreadsheetID,
+		AuthorID:      r.AuthorID,
+		Data:          r.Data,
+		Size:          r.Size,
+		Live:          r.Live,
+		CreatedAt:     r.CreatedAt,
+	}
+	if r.Author.ID != 0 {
+		author := toDomainUser(r.Author)
+		rev.Author = &author
+	}
+	return rev
+}
+
+func toDomainSession(s Session) domain.Session {
+	session := domain.Session{
+		ID:         s.ID,
+		Token:      s.Token,
+		UserID:     s.UserID,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+		CreatedAt:  s.CreatedAt,
+	}
+	if s.User.ID != 0 {
+		user := toDomainUser(s.User)
+		session.User = &user
+	}
+	return session
+}
+
+func toDomainAPIToken(t APIToken) domain.APIToken {
+	token := domain.APIToken{
+		ID:            t.ID,
+		UserID:        t.UserID,
+		Name:          t.Name,
+		Prefix:        t.Prefix,
+		TokenHash:     t.TokenHash,
+		Scope:         domain.TokenScope(t.Scope),
+		SpreadsheetID: t.SpreadsheetID,
+		ExpiresAt:     t.ExpiresAt,
+		LastUsedAt:    t.LastUsedAt,
+		LastUsedIP:    t.LastUsedIP,
+		CreatedAt:     t.CreatedAt,
+	}
+	if t.User.ID != 0 {
+		user := toDomainUser(t.User)
+		token.User = &user
+	}
+	return token
+}
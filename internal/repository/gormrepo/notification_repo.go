@@ -0,0 +1,123 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepo struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepo(db *gorm.DB) *NotificationRepo {
+	return &NotificationRepo{db: db}
+}
+
+func (r *NotificationRepo) Create(ctx context.Context, n *domain.Notification) error {
+	row := Notification{
+		UserID:        n.UserID,
+		Type:          string(n.Type),
+		ActorID:       n.ActorID,
+		SpreadsheetID: n.SpreadsheetID,
+		CommentID:     n.CommentID,
+		Message:       n.Message,
+	}
+	if err := r.db.WithContext(ctx).Omit("Actor").Create(&row).Error; err != nil {
+		return err
+	}
+	n.ID = row.ID
+	n.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *NotificationRepo) List(ctx context.Context, userID uint, unreadOnly bool, beforeID uint, limit int) ([]domain.Notification, error) {
+	query := r.db.WithContext(ctx).Preload("Actor").Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if beforeID != 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+
+	var rows []Notification
+	if err := query.Order("id DESC").Limit(limit).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Notification, len(rows))
+	for i, n := range rows {
+		out[i] = toDomainNotification(n)
+	}
+	return out, nil
+}
+
+func (r *NotificationRepo) CountUnread(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *NotificationRepo) HasUnread(ctx context.Context, userID uint, t domain.NotificationType, actorID, spreadsheetID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND type = ? AND actor_id = ? AND spreadsheet_id = ? AND read_at IS NULL",
+			userID, string(t), actorID, spreadsheetID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *NotificationRepo) MarkRead(ctx context.Context, id, userID uint, at time.Time) error {
+	var n Notification
+	if err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&n).Error; err != nil {
+		return err
+	}
+	if n.ReadAt != nil {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&n).Update("read_at", at).Error
+}
+
+func (r *NotificationRepo) MarkAllRead(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", at).Error
+}
+
+func (r *NotificationRepo) DeleteReadBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("read_at IS NOT NULL AND created_at < ?", before).
+		Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *NotificationRepo) Preferences(ctx context.Context, userID uint) (map[domain.NotificationType]bool, error) {
+	var rows []NotificationPreference
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	prefs := make(map[domain.NotificationType]bool, len(rows))
+	for _, p := range rows {
+		prefs[domain.NotificationType(p.Type)] = p.Enabled
+	}
+	return prefs, nil
+}
+
+func (r *NotificationRepo) SetPreferences(ctx context.Context, userID uint, prefs map[domain.NotificationType]bool) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	rows := make([]NotificationPreference, 0, len(prefs))
+	for t, enabled := range prefs {
+		rows = append(rows, NotificationPreference{UserID: userID, Type: string(t), Enabled: enabled})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled"}),
+	}).Create(&rows).Error
+}
@@ -0,0 +1,204 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrganizationRepo struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepo(db *gorm.DB) *OrganizationRepo {
+	return &OrganizationRepo{db: db}
+}
+
+func (r *OrganizationRepo) Create(ctx context.Context, org *domain.Organization, ownerID uint) error {
+	o := Organization{Name: org.Name}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&o).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrganizationID: o.ID,
+			UserID:         ownerID,
+			Role:           string(domain.OrgOwner),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+	org.ID = o.ID
+	org.CreatedAt = o.CreatedAt
+	org.UpdatedAt = o.UpdatedAt
+	return nil
+}
+
+func (r *OrganizationRepo) FindByID(ctx context.Context, id uint) (*domain.Organization, error) {
+	var o Organization
+	if err := r.db.WithContext(ctx).First(&o, id).Error; err != nil {
+		return nil, err
+	}
+	org := toDomainOrganization(o)
+	return &org, nil
+}
+
+func (r *OrganizationRepo) ListByUser(ctx context.Context, userID uint) ([]domain.Organization, error) {
+	var rows []struct {
+		Organization
+		Role string
+	}
+	err := r.db.WithContext(ctx).Model(&Organization{}).
+		Select("organizations.*, organization_members.role").
+		Joins("JOIN organization_members ON organization_members.organization_id = organizations.id").
+		Where("organization_members.user_id = ?", userID).
+		Order("organizations.name ASC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Organization, len(rows))
+	for i, row := range rows {
+		out[i] = toDomainOrganization(row.Organization)
+		out[i].Role = domain.OrgRole(row.Role)
+	}
+	return out, nil
+}
+
+func (r *OrganizationRepo) Update(ctx context.Context, org *domain.Organization) error {
+	o := Organization{ID: org.ID}
+	result := r.db.WithContext(ctx).Model(&o).Update("name", org.Name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := r.db.WithContext(ctx).First(&o, o.ID).Error; err != nil {
+		return err
+	}
+	org.UpdatedAt = o.UpdatedAt
+	return nil
+}
+
+func (r *OrganizationRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		workspaces := tx.Model(&Workspace{}).Select("id").Where("organization_id = ?", id)
+		if err := tx.Where("workspace_id IN (?)", workspaces).Delete(&Folder{}).Error; err != nil {
+			return err
+		}
+		hooks := tx.Model(&Webhook{}).Select("id").Where("workspace_id IN (?)", workspaces)
+		if err := tx.Where("webhook_id IN (?)", hooks).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id IN (?)", workspaces).Delete(&Webhook{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&Workspace{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("organization_id = ?", id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Organization{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *OrganizationRepo) ListMembers(ctx context.Context, orgID uint) ([]domain.OrganizationMember, error) {
+	var rows []OrganizationMember
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ?", orgID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.OrganizationMember, len(rows))
+	for i, m := range rows {
+		out[i] = toDomainMember(m)
+	}
+	return out, nil
+}
+
+func (r *OrganizationRepo) FindMember(ctx context.Context, orgID, userID uint) (*domain.OrganizationMember, error) {
+	var m OrganizationMember
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", orgID, userID).
+		First(&m).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("member %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	member := toDomainMember(m)
+	return &member, nil
+}
+
+func (r *OrganizationRepo) UpsertMember(ctx context.Context, member *domain.OrganizationMember) error {
+	now := time.Now()
+	m := OrganizationMember{
+		OrganizationID: member.OrganizationID,
+		UserID:         member.UserID,
+		Role:           string(member.Role),
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "organization_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&m).Error
+	if err != nil {
+		return err
+	}
+
+	// Reload: on conflict the insert's ID and CreatedAt are not the stored ones
+	if err := r.db.WithContext(ctx).
+		Where("organization_id = ? AND user_id = ?", m.OrganizationID, m.UserID).
+		Preload("User").
+		First(&m).Error; err != nil {
+		return err
+	}
+	*member = toDomainMember(m)
+	return nil
+}
+
+func (r *OrganizationRepo) RemoveMember(ctx context.Context, orgID, userID, heirID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&OrganizationMember{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("member %w", domain.ErrNotFound)
+		}
+		workspaces := tx.Model(&Workspace{}).Select("id").Where("organization_id = ?", orgID)
+		return tx.Unscoped().Model(&Spreadsheet{}).
+			Where("workspace_id IN (?) AND owner_id = ?", workspaces, userID).
+			UpdateColumn("owner_id", heirID).Error
+	})
+}
+
+func (r *OrganizationRepo) CountOwners(ctx context.Context, orgID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", orgID, string(domain.OrgOwner)).
+		Count(&count).Error
+	return count, err
+}
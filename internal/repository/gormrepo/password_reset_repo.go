@@ -0,0 +1,70 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PasswordResetRepo struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepo(db *gorm.DB) *PasswordResetRepo {
+	return &PasswordResetRepo{db: db}
+}
+
+func (r *PasswordResetRepo) Create(ctx context.Context, reset *domain.PasswordReset) error {
+	row := PasswordReset{
+		UserID:    reset.UserID,
+		TokenHash: reset.TokenHash,
+		ExpiresAt: reset.ExpiresAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	reset.ID = row.ID
+	reset.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *PasswordResetRepo) Consume(ctx context.Context, hash string, now time.Time) (*domain.PasswordReset, error) {
+	var row PasswordReset
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("token_hash = ? AND expires_at > ?", hash, now).First(&row).Error; err != nil {
+			return err
+		}
+		// Of two requests racing with the same token, only the one that
+		// deletes it wins.
+		result := tx.Where("user_id = ?", row.UserID).Delete(&PasswordReset{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("password reset %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PasswordReset{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		TokenHash: row.TokenHash,
+		ExpiresAt: row.ExpiresAt,
+		CreatedAt: row.CreatedAt,
+	}, nil
+}
+
+func (r *PasswordResetRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&PasswordReset{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,129 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PermissionRepo struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepo(db *gorm.DB) *PermissionRepo {
+	return &PermissionRepo{db: db}
+}
+
+func (r *PermissionRepo) ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]domain.SpreadsheetPermission, error) {
+	var rows []SpreadsheetPermission
+	err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ?", spreadsheetID).
+		Preload("User").
+		Order("created_at ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.SpreadsheetPermission, len(rows))
+	for i, p := range rows {
+		out[i] = toDomainPermission(p)
+	}
+	return out, nil
+}
+
+func (r *PermissionRepo) ListByUser(ctx context.Context, userID uint) ([]domain.SpreadsheetPermission, error) {
+	var rows []SpreadsheetPermission
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Preload("Spreadsheet").
+		Preload("Spreadsheet.Owner").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.SpreadsheetPermission, len(rows))
+	for i, p := range rows {
+		out[i] = toDomainPermission(p)
+	}
+	return out, nil
+}
+
+func (r *PermissionRepo) FindRole(ctx context.Context, spreadsheetID, userID uint) (domain.Role, error) {
+	var p SpreadsheetPermission
+	err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ? AND user_id = ?", spreadsheetID, userID).
+		First(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("permission %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return domain.Role(p.Role), nil
+}
+
+func (r *PermissionRepo) FindRoles(ctx context.Context, userID uint, spreadsheetIDs []uint) (map[uint]domain.Role, error) {
+	roles := make(map[uint]domain.Role)
+	if len(spreadsheetIDs) == 0 {
+		return roles, nil
+	}
+	var rows []SpreadsheetPermission
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND spreadsheet_id IN ?", userID, spreadsheetIDs).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range rows {
+		roles[p.SpreadsheetID] = domain.Role(p.Role)
+	}
+	return roles, nil
+}
+
+func (r *PermissionRepo) Upsert(ctx context.Context, permission *domain.SpreadsheetPermission) error {
+	now := time.Now()
+	p := SpreadsheetPermission{
+		SpreadsheetID: permission.SpreadsheetID,
+		UserID:        permission.UserID,
+		Role:          string(permission.Role),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "spreadsheet_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(&p).Error
+	if err != nil {
+		return err
+	}
+
+	// Reload: on conflict the insert's ID and CreatedAt are not the stored ones
+	if err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ? AND user_id = ?", p.SpreadsheetID, p.UserID).
+		Preload("User").
+		First(&p).Error; err != nil {
+		return err
+	}
+	*permission = toDomainPermission(p)
+	return nil
+}
+
+func (r *PermissionRepo) Delete(ctx context.Context, spreadsheetID, userID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ? AND user_id = ?", spreadsheetID, userID).
+		Delete(&SpreadsheetPermission{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type RevisionRepo struct {
+	db *gorm.DB
+}
+
+func NewRevisionRepo(db *gorm.DB) *RevisionRepo {
+	return &RevisionRepo{db: db}
+}
+
+func (r *RevisionRepo) Create(ctx context.Context, revision *domain.Revision) error {
+	rev := Revision{
+		SpreadsheetID: revision.SpreadsheetID,
+		AuthorID:      revision.AuthorID,
+		Data:          revision.Data,
+		Size:          len(revision.Data),
+	}
+	if err := r.db.WithContext(ctx).Create(&rev).Error; err != nil {
+		return err
+	}
+	revision.ID = rev.ID
+	revision.Size = rev.Size
+	revision.CreatedAt = rev.CreatedAt
+	return nil
+}
+
+func (r *RevisionRepo) ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]domain.Revision, error) {
+	var rows []Revision
+	err := r.db.WithContext(ctx).
+		Omit("data").
+		Where("spreadsheet_id = ?", spreadsheetID).
+		Preload("Author").
+		Order("created_at DESC, id DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Revision, len(rows))
+	for i, rev := range rows {
+		out[i] = toDomainRevision(rev)
+	}
+	return out, nil
+}
+
+func (r *RevisionRepo) FindByID(ctx context.Context, spreadsheetID, id uint) (*domain.Revision, error) {
+	var rev Revision
+	err := r.db.WithContext(ctx).
+		Where("id = ? AND spreadsheet_id = ?", id, spreadsheetID).
+		Preload("Author").
+		First(&rev).Error
+	if err != nil {
+		return nil, err
+	}
+	revision := toDomainRevision(rev)
+	return &revision, nil
+}
+
+func (r *RevisionRepo) ListSpreadsheetIDs(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&Revision{}).Distinct().Pluck("spreadsheet_id", &ids).Error
+	return ids, err
+}
+
+func (r *RevisionRepo) DeleteByIDs(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("id IN ?", ids).Delete(&Revision{}).Error
+}
@@ -0,0 +1,221 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// searchFTSTable is the SQLite FTS5 index over search_entries, keyed by
+// entry ID. Migration 17 creates it when SQLite is compiled with FTS5
+// (build with -tags sqlite_fts5).
+const searchFTSTable = "search_fts"
+
+// searchIndex is how SearchRepo finds the entries containing a term.
+type searchIndex int
+
+const (
+	// searchLike scans every entry with LIKE: SQLite without FTS5.
+	searchLike searchIndex = iota
+	// searchFTS5 uses the search_fts table.
+	searchFTS5
+	// searchTSVector uses the content_tsv column of PostgreSQL.
+	searchTSVector
+)
+
+// SearchRepo keeps the searchable text of spreadsheets in search_entries,
+// one row for the title and one per non-empty cell.
+type SearchRepo struct {
+	db    *gorm.DB
+	index searchIndex
+}
+
+// NewSearchRepo uses the full-text index the migrations set up. On SQLite
+// with FTS5 it fills the FTS table again if it is out of step with
+// search_entries (say, after running a build without FTS5).
+func NewSearchRepo(db *gorm.DB) (*SearchRepo, error) {
+	r := &SearchRepo{db: db}
+	switch {
+	case db.Dialector.Name() == "postgres":
+		r.index = searchTSVector
+		return r, nil
+	case !fts5Available(db):
+		return r, nil
+	case !db.Migrator().HasTable(searchFTSTable):
+		return nil, errors.New("SQLite has FTS5 but the search_fts table is missing, as the database was migrated by a build without it: " +
+			"roll back migration 17 (search_full_text) and apply it again")
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var indexed, entries int64
+		if err := tx.Table(searchFTSTable).Count(&indexed).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&SearchEntry{}).Count(&entries).Error; err != nil {
+			return err
+		}
+		if indexed == entries {
+			return nil
+		}
+		return refillSearchFTS(tx)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("prepare full-text index: %w", err)
+	}
+	r.index = searchFTS5
+	return r, nil
+}
+
+// refillSearchFTS replaces the contents of the FTS5 table with every entry
+// of search_entries.
+func refillSearchFTS(tx *gorm.DB) error {
+	if err := tx.Exec("DELETE FROM " + searchFTSTable).Error; err != nil {
+		return err
+	}
+	return tx.Exec("INSERT INTO " + searchFTSTable + " (rowid, content) SELECT id, content FROM search_entries").Error
+}
+
+// FullText reports whether searches use a full-text index rather than
+// scanning with LIKE.
+func (r *SearchRepo) FullText() bool {
+	return r.index != searchLike
+}
+
+func (r *SearchRepo) Replace(ctx context.Context, spreadsheetID uint, entries []domain.SearchEntry) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if r.index == searchFTS5 {
+			err := tx.Exec("DELETE FROM "+searchFTSTable+" WHERE rowid IN (SELECT id FROM search_entries WHERE spreadsheet_id = ?)", spreadsheetID).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("spreadsheet_id = ?", spreadsheetID).Delete(&SearchEntry{}).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		rows := make([]SearchEntry, len(entries))
+		for i, e := range entries {
+			rows[i] = SearchEntry{SpreadsheetID: spreadsheetID, Sheet: e.Sheet, Cell: e.Cell, Content: e.Text}
+		}
+		if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+			return err
+		}
+		if r.index == searchFTS5 {
+			return tx.Exec("INSERT INTO "+searchFTSTable+" (rowid, content) SELECT id, content FROM search_entries WHERE spreadsheet_id = ?", spreadsheetID).Error
+		}
+		return nil
+	})
+}
+
+func (r *SearchRepo) Search(ctx context.Context, userID uint, terms []string, limit int) ([]domain.Spreadsheet, error) {
+	query := r.db.WithContext(ctx).Model(&Spreadsheet{}).
+		Where(visibleClause(true, true, true), sql.Named("user", userID))
+	for _, term := range terms {
+		match, arg := r.match(term)
+		query = query.Where("spreadsheets.id IN (SELECT spreadsheet_id FROM search_entries WHERE "+match+")", arg)
+	}
+
+	var rows []Spreadsheet
+	err := query.Preload("Owner").
+		Order("spreadsheets.updated_at DESC").
+		Order("spreadsheets.id DESC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SearchRepo) Matches(ctx context.Context, spreadsheetID uint, terms []string, limit int) ([]domain.SearchEntry, error) {
+	conds := make([]string, len(terms))
+	args := make([]any, len(terms))
+	for i, term := range terms {
+		conds[i], args[i] = r.match(term)
+	}
+
+	var rows []SearchEntry
+	err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ?", spreadsheetID).
+		Where("("+strings.Join(conds, " OR ")+")", args...).
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.SearchEntry, len(rows))
+	for i, e := range rows {
+		out[i] = toDomainSearchEntry(e)
+	}
+	return out, nil
+}
+
+func (r *SearchRepo) ListUnindexed(ctx context.Context, limit int) ([]domain.Spreadsheet, error) {
+	var rows []Spreadsheet
+	err := r.db.WithContext(ctx).
+		Where("NOT EXISTS (SELECT 1 FROM search_entries e WHERE e.spreadsheet_id = spreadsheets.id)").
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+// match returns a condition on search_entries for the entries containing
+// term, with its argument. The full-text indexes match words starting with
+// the term; LIKE matches it anywhere. PostgreSQL only uses its index for
+// terms made of letters and digits, as its parser keeps tokens such as
+// "3.14" or "a-b" whole.
+func (r *SearchRepo) match(term string) (string, any) {
+	switch {
+	case r.index == searchFTS5:
+		phrase := `"` + strings.ReplaceAll(term, `"`, `""`) + `"*`
+		return "search_entries.id IN (SELECT rowid FROM " + searchFTSTable + " WHERE " + searchFTSTable + " MATCH ?)", phrase
+	case r.index == searchTSVector && isWord(term):
+		return "search_entries.content_tsv @@ to_tsquery('simple', ?)", term + ":*"
+	}
+	return `LOWER(search_entries.content) LIKE ? ESCAPE '\'`, "%" + escapeLike(strings.ToLower(term)) + "%"
+}
+
+// isWord reports whether s is made only of letters and digits.
+func isWord(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// fts5Available reports whether db is SQLite compiled with FTS5.
+func fts5Available(db *gorm.DB) bool {
+	if db.Dialector.Name() != "sqlite" {
+		return false
+	}
+	var enabled int
+	err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error
+	return err == nil && enabled == 1
+}
@@ -0,0 +1,86 @@
+//go:build sqlite_fts5
+
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"slices"
+	"testing"
+)
+
+// Builds with the sqlite_fts5 tag, as released ones are, must search with
+// the FTS5 index: prefix matches ignoring accents only work there.
+func TestSearchUsesFTS5(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	search, err := NewSearchRepo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !search.FullText() {
+		t.Fatal("SQLite built with sqlite_fts5 has no FTS5")
+	}
+
+	ada := createTestUser(t, db, "ada@example.com")
+	sheet := &domain.Spreadsheet{Title: "Menu", OwnerID: ada.ID}
+	if err := NewSpreadsheetRepo(db).Create(ctx, sheet); err != nil {
+		t.Fatal(err)
+	}
+	err = search.Replace(ctx, sheet.ID, []domain.SearchEntry{{Sheet: "Sheet1", Cell: "A1", Text: "Crème brûlée"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := search.Search(ctx, ada.ID, []string{"brul"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != sheet.ID {
+		t.Errorf("search for a prefix without accents found %+v", found)
+	}
+}
+
+func TestSearchFTSTableFollowsMigrations(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if !db.Migrator().HasTable(searchFTSTable) {
+		t.Fatal("migrating didn't create the FTS5 table")
+	}
+
+	// Entries written without the index, as a build without FTS5 does,
+	// are indexed when the repository starts.
+	ada := createTestUser(t, db, "ada@example.com")
+	sheet := &domain.Spreadsheet{Title: "Menu", OwnerID: ada.ID}
+	if err := NewSpreadsheetRepo(db).Create(ctx, sheet); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&SearchEntry{SpreadsheetID: sheet.ID, Content: "Menu"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	search, err := NewSearchRepo(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found, err := search.Search(ctx, ada.ID, []string{"menu"}, 10); err != nil || len(found) != 1 {
+		t.Errorf("search after refilling the index = %+v, %v", found, err)
+	}
+
+	// A database migrated without FTS5 has no table, which the repository
+	// reports rather than creating it.
+	if err := db.Migrator().DropTable(searchFTSTable); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSearchRepo(db); err == nil {
+		t.Error("NewSearchRepo without the FTS5 table succeeded")
+	}
+	steps := len(migrations) - slices.IndexFunc(migrations, func(m Migration) bool { return m.Name == "search_full_text" })
+	if _, err := MigrateDown(db, steps, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := MigrateUp(db); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSearchRepo(db); err != nil {
+		t.Errorf("after reapplying migration 17: %v", err)
+	}
+}
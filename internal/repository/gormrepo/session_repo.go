@@ -0,0 +1,104 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepo struct {
+	db *gorm.DB
+}
+
+func NewSessionRepo(db *gorm.DB) *SessionRepo {
+	return &SessionRepo{db: db}
+}
+
+func (r *SessionRepo) Create(ctx context.Context, session *domain.Session) error {
+	s := Session{
+		Token:      session.Token,
+		UserID:     session.UserID,
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&s).Error; err != nil {
+		return err
+	}
+	session.ID = s.ID
+	session.CreatedAt = s.CreatedAt
+	return nil
+}
+
+func (r *SessionRepo) FindValidByToken(ctx context.Context, token string) (*domain.Session, error) {
+	var s Session
+	err := r.db.WithContext(ctx).
+		Where("token = ? AND expires_at > ?", token, time.Now()).
+		Preload("User").
+		First(&s).Error
+	if err != nil {
+		return nil, err
+	}
+	session := toDomainSession(s)
+	return &session, nil
+}
+
+func (r *SessionRepo) ListValidByUser(ctx context.Context, userID uint) ([]domain.Session, error) {
+	var rows []Session
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Session, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSession(s)
+	}
+	return out, nil
+}
+
+func (r *SessionRepo) Touch(ctx context.Context, id uint, seenAt time.Time, ip string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_seen_at": seenAt, "ip": ip, "expires_at": expiresAt}).Error
+}
+
+func (r *SessionRepo) Delete(ctx context.Context, id, userID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND user_id = ?", id, userID).
+		Delete(&Session{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *SessionRepo) DeleteByTokenAndUser(ctx context.Context, token string, userID uint) error {
+	return r.db.WithContext(ctx).
+		Where("token = ? AND user_id = ?", token, userID).
+		Delete(&Session{}).Error
+}
+
+func (r *SessionRepo) DeleteOthers(ctx context.Context, userID, keepID uint) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND id <> ?", userID, keepID).
+		Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *SessionRepo) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("expires_at <= ?", before).
+		Delete(&Session{})
+	return result.RowsAffected, result.Error
+}
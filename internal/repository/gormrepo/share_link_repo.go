@@ -0,0 +1,85 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ShareLinkRepo struct {
+	db *gorm.DB
+}
+
+func NewShareLinkRepo(db *gorm.DB) *ShareLinkRepo {
+	return &ShareLinkRepo{db: db}
+}
+
+func (r *ShareLinkRepo) Create(ctx context.Context, link *domain.ShareLink) error {
+	l := ShareLink{
+		SpreadsheetID: link.SpreadsheetID,
+		CreatedByID:   link.CreatedByID,
+		Prefix:        link.Prefix,
+		TokenHash:     link.TokenHash,
+		Mode:          string(link.Mode),
+		PasswordHash:  link.PasswordHash,
+		ExpiresAt:     link.ExpiresAt,
+	}
+	if err := r.db.WithContext(ctx).Create(&l).Error; err != nil {
+		return err
+	}
+	link.ID = l.ID
+	link.HasPassword = l.PasswordHash != ""
+	link.CreatedAt = l.CreatedAt
+	return nil
+}
+
+func (r *ShareLinkRepo) ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]domain.ShareLink, error) {
+	var rows []ShareLink
+	err := r.db.WithContext(ctx).
+		Where("spreadsheet_id = ?", spreadsheetID).
+		Order("created_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.ShareLink, len(rows))
+	for i, l := range rows {
+		out[i] = toDomainShareLink(l)
+	}
+	return out, nil
+}
+
+func (r *ShareLinkRepo) FindValidByHash(ctx context.Context, hash string) (*domain.ShareLink, error) {
+	var l ShareLink
+	err := r.db.WithContext(ctx).
+		Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hash, time.Now()).
+		First(&l).Error
+	if err != nil {
+		return nil, err
+	}
+	link := toDomainShareLink(l)
+	return &link, nil
+}
+
+func (r *ShareLinkRepo) RecordUse(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).
+		Model(&ShareLink{}).
+		Where("id = ?", id).
+		Update("last_used_at", at).Error
+}
+
+func (r *ShareLinkRepo) Delete(ctx context.Context, id, spreadsheetID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND spreadsheet_id = ?", id, spreadsheetID).
+		Delete(&ShareLink{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,400 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SpreadsheetRepo struct {
+	db *gorm.DB
+}
+
+func NewSpreadsheetRepo(db *gorm.DB) *SpreadsheetRepo {
+	return &SpreadsheetRepo{db: db}
+}
+
+func (r *SpreadsheetRepo) ListByOwner(ctx context.Context, ownerID uint) ([]domain.Spreadsheet, error) {
+	var rows []Spreadsheet
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ?", ownerID).
+		Preload("Owner").
+		Order("updated_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SpreadsheetRepo) ListVisible(ctx context.Context, q domain.SpreadsheetQuery) ([]domain.Spreadsheet, int64, error) {
+	visible := visibleClause(q.Owned, q.Shared, q.Team)
+	if visible == "" {
+		return nil, 0, nil
+	}
+
+	query := r.db.WithContext(ctx).Model(&Spreadsheet{}).Where(visible, sql.Named("user", q.UserID))
+	if q.Title != "" {
+		query = query.Where(`LOWER(spreadsheets.title) LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(q.Title))+"%")
+	}
+	if q.OwnerID != nil {
+		query = query.Where("spreadsheets.owner_id = ?", *q.OwnerID)
+	}
+	if q.WorkspaceID != nil {
+		query = query.Where("spreadsheets.workspace_id = ?", *q.WorkspaceID)
+	}
+	if q.FolderID != nil {
+		query = query.Where("spreadsheets.folder_id = ?", *q.FolderID)
+	}
+	if q.CreatedAfter != nil {
+		query = query.Where("spreadsheets.created_at >= ?", *q.CreatedAfter)
+	}
+	if q.CreatedBefore != nil {
+		query = query.Where("spreadsheets.created_at < ?", *q.CreatedBefore)
+	}
+	if q.UpdatedAfter != nil {
+		query = query.Where("spreadsheets.updated_at >= ?", *q.UpdatedAfter)
+	}
+	if q.UpdatedBefore != nil {
+		query = query.Where("spreadsheets.updated_at < ?", *q.UpdatedBefore)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	// Keyset pagination: rows strictly after the cursor in (column, id)
+	// order, so pages stay stable while spreadsheets are added.
+	column := "spreadsheets." + string(q.Sort)
+	cmp, dir := ">", "ASC"
+	if q.Descending {
+		cmp, dir = "<", "DESC"
+	}
+	if q.After != nil {
+		var value any = q.After.Time
+		if q.Sort == domain.SortTitle {
+			value = q.After.Title
+		}
+		query = query.Where(
+			"("+column+" "+cmp+" ? OR ("+column+" = ? AND spreadsheets.id "+cmp+" ?))",
+			value, value, q.After.ID,
+		)
+	}
+
+	var rows []Spreadsheet
+	err := query.Preload("Owner").
+		Order(column + " " + dir).
+		Order("spreadsheets.id " + dir).
+		Limit(q.Limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, total, nil
+}
+
+// visibleClause is the condition for spreadsheets that the user in the
+// named parameter @user owns, has been shared, or can reach through an
+// organization workspace, as selected. It is empty if none are.
+func visibleClause(owned, shared, team bool) string {
+	var visible []string
+	if owned {
+		visible = append(visible, "spreadsheets.owner_id = @user")
+	}
+	if shared {
+		visible = append(visible, "spreadsheets.id IN (SELECT spreadsheet_id FROM spreadsheet_permissions WHERE user_id = @user)")
+	}
+	if team {
+		visible = append(visible, `(spreadsheets.owner_id <> @user AND spreadsheets.workspace_id IN (
+			SELECT w.id FROM workspaces w
+			JOIN organization_members m ON m.organization_id = w.organization_id
+			WHERE m.user_id = @user))`)
+	}
+	if len(visible) == 0 {
+		return ""
+	}
+	return "(" + strings.Join(visible, " OR ") + ")"
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *SpreadsheetRepo) ListByWorkspace(ctx context.Context, workspaceID uint) ([]domain.Spreadsheet, error) {
+	var rows []Spreadsheet
+	err := r.db.WithContext(ctx).
+		Where("workspace_id = ?", workspaceID).
+		Preload("Owner").
+		Order("updated_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SpreadsheetRepo) ListByFolder(ctx context.Context, workspaceID uint, folderID *uint) ([]domain.Spreadsheet, error) {
+	query := r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID)
+	if folderID == nil {
+		query = query.Where("folder_id IS NULL")
+	} else {
+		query = query.Where("folder_id = ?", *folderID)
+	}
+
+	var rows []Spreadsheet
+	if err := query.Preload("Owner").Order("title ASC").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SpreadsheetRepo) CountByWorkspace(ctx context.Context, workspaceID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Unscoped().Model(&Spreadsheet{}).
+		Where("workspace_id = ?", workspaceID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *SpreadsheetRepo) FindByID(ctx context.Context, id uint) (*domain.Spreadsheet, error) {
+	var s Spreadsheet
+	err := r.db.WithContext(ctx).Preload("Owner").First(&s, id).Error
+	if err != nil {
+		return nil, err
+	}
+	sheet := toDomainSpreadsheet(s)
+	return &sheet, nil
+}
+
+func (r *SpreadsheetRepo) FindByIDWithoutData(ctx context.Context, id uint) (*domain.Spreadsheet, error) {
+	var s Spreadsheet
+	err := r.db.WithContext(ctx).Omit("data").First(&s, id).Error
+	if err != nil {
+		return nil, err
+	}
+	sheet := toDomainSpreadsheet(s)
+	return &sheet, nil
+}
+
+func (r *SpreadsheetRepo) Create(ctx context.Context, spreadsheet *domain.Spreadsheet) error {
+	s := Spreadsheet{
+		Title:       spreadsheet.Title,
+		OwnerID:     spreadsheet.OwnerID,
+		WorkspaceID: spreadsheet.WorkspaceID,
+		FolderID:    spreadsheet.FolderID,
+		Data:        spreadsheet.Data,
+		Version:     1,
+	}
+	if err := r.db.WithContext(ctx).Create(&s).Error; err != nil {
+		return err
+	}
+	spreadsheet.ID = s.ID
+	spreadsheet.Version = s.Version
+	spreadsheet.CreatedAt = s.CreatedAt
+	spreadsheet.UpdatedAt = s.UpdatedAt
+	return nil
+}
+
+func (r *SpreadsheetRepo) Update(ctx context.Context, spreadsheet *domain.Spreadsheet, fields map[string]any) error {
+	return updateSpreadsheet(r.db.WithContext(ctx), spreadsheet, fields)
+}
+
+func (r *SpreadsheetRepo) SaveData(ctx context.Context, spreadsheet *domain.Spreadsheet, fields map[string]any, authorID uint, mergeWindow time.Duration) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := updateSpreadsheet(tx, spreadsheet, fields); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var latest Revision
+		err := tx.Omit("data").
+			Where("spreadsheet_id = ?", spreadsheet.ID).
+			Order("created_at DESC, id DESC").
+			Limit(1).Find(&latest).Error
+		if err != nil {
+			return err
+		}
+		live := mergeWindow > 0
+		if live && latest.Live && latest.AuthorID == authorID &&
+			latest.CreatedAt.Truncate(mergeWindow).Equal(now.Truncate(mergeWindow)) {
+			return tx.Model(&latest).Updates(map[string]any{
+				"data":       spreadsheet.Data,
+				"size":       len(spreadsheet.Data),
+				"created_at": now,
+			}).Error
+		}
+		return tx.Create(&Revision{
+			SpreadsheetID: spreadsheet.ID,
+			AuthorID:      authorID,
+			Data:          spreadsheet.Data,
+			Size:          len(spreadsheet.Data),
+			Live:          live,
+			CreatedAt:     now,
+		}).Error
+	})
+}
+
+func updateSpreadsheet(db *gorm.DB, spreadsheet *domain.Spreadsheet, fields map[string]any) error {
+	s := Spreadsheet{ID: spreadsheet.ID}
+	query := db.Model(&s)
+
+	updates := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		updates[k] = v
+	}
+	if _, ok := fields["data"]; ok {
+		updates["version"] = gorm.Expr("version + 1")
+		query = query.Where("version = ?", spreadsheet.Version)
+	}
+
+	result := query.Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrConflict
+	}
+
+	// Reload to get updated timestamps
+	if err := db.First(&s, s.ID).Error; err != nil {
+		return err
+	}
+	spreadsheet.Title = s.Title
+	spreadsheet.WorkspaceID = s.WorkspaceID
+	spreadsheet.FolderID = s.FolderID
+	spreadsheet.Data = s.Data
+	spreadsheet.Version = s.Version
+	spreadsheet.UpdatedAt = s.UpdatedAt
+	return nil
+}
+
+func (r *SpreadsheetRepo) Trash(ctx context.Context, id, ownerID uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND owner_id = ?", id, ownerID).Delete(&Spreadsheet{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *SpreadsheetRepo) ListTrashedByOwner(ctx context.Context, ownerID uint) ([]domain.Spreadsheet, error) {
+	var rows []Spreadsheet
+	err := r.db.WithContext(ctx).Unscoped().
+		Where("owner_id = ? AND deleted_at IS NOT NULL", ownerID).
+		Preload("Owner").
+		Order("deleted_at DESC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SpreadsheetRepo) ListTrashedBefore(ctx context.Context, cutoff time.Time, limit int) ([]domain.Spreadsheet, error) {
+	var rows []Spreadsheet
+	err := r.db.WithContext(ctx).Unscoped().
+		Select("id", "owner_id", "title", "deleted_at").
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).
+		Order("deleted_at ASC").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Spreadsheet, len(rows))
+	for i, s := range rows {
+		out[i] = toDomainSpreadsheet(s)
+	}
+	return out, nil
+}
+
+func (r *SpreadsheetRepo) Restore(ctx context.Context, id, ownerID uint) error {
+	result := r.db.WithContext(ctx).Unscoped().Model(&Spreadsheet{}).
+		Where("id = ? AND owner_id = ? AND deleted_at IS NOT NULL", id, ownerID).
+		UpdateColumn("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *SpreadsheetRepo) Delete(ctx context.Context, id, ownerID uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Unscoped().Model(&Spreadsheet{}).
+			Where("id = ? AND owner_id = ? AND deleted_at IS NOT NULL", id, ownerID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		// Dependent rows first, so foreign keys are never violated.
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&SpreadsheetPermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&Revision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&ShareLink{}).Error; err != nil {
+			return err
+		}
+		err = tx.Where("comment_id IN (SELECT id FROM comments WHERE spreadsheet_id = ?)", id).Delete(&CommentMention{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&Comment{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&Notification{}).Error; err != nil {
+			return err
+		}
+		hooks := tx.Model(&Webhook{}).Select("id").Where("spreadsheet_id = ?", id)
+		if err := tx.Where("webhook_id IN (?)", hooks).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("spreadsheet_id = ?", id).Delete(&Webhook{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Spreadsheet{}, id).Error
+	})
+}
@@ -0,0 +1,129 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// mergeWindow is long enough that the saves in a test never straddle two
+// windows.
+const mergeWindow = 365 * 24 * time.Hour
+
+func TestSaveDataMergesOnlyLiveRevisions(t *testing.T) {
+	forEachDB(t, testSaveDataMergesOnlyLiveRevisions)
+}
+
+func testSaveDataMergesOnlyLiveRevisions(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	sheets, revisions := NewSpreadsheetRepo(db), NewRevisionRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+	bob := createTestUser(t, db, "bob@example.com")
+
+	sheet := &domain.Spreadsheet{Title: "Budget", OwnerID: ada.ID, Data: "v0"}
+	if err := sheets.Create(ctx, sheet); err != nil {
+		t.Fatal(err)
+	}
+
+	save := func(author uint, data string, window time.Duration) {
+		t.Helper()
+		if err := sheets.SaveData(ctx, sheet, map[string]any{"data": data}, author, window); err != nil {
+			t.Fatalf("save %s: %v", data, err)
+		}
+	}
+	// Ordinary saves each keep their snapshot, however close together:
+	// emptying the sheet by accident must leave the good data behind.
+	save(ada.ID, "good1", 0)
+	save(ada.ID, "good2", 0)
+	save(ada.ID, "empty", 0)
+	// Live flushes share a revision per author, without touching the
+	// revisions before them.
+	save(ada.ID, "live1", mergeWindow)
+	save(ada.ID, "live2", mergeWindow)
+	save(bob.ID, "bob1", mergeWindow)
+	save(bob.ID, "patch", 0)
+	save(bob.ID, "bob2", mergeWindow)
+
+	revs, err := revisions.ListBySpreadsheet(ctx, sheet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range revs {
+		full, err := revisions.FindByID(ctx, sheet.ID, r.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, full.Data)
+	}
+	want := []string{"bob2", "patch", "bob1", "live2", "empty", "good2", "good1"}
+	if len(got) != len(want) {
+		t.Fatalf("revisions = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("revisions = %v, want %v", got, want)
+		}
+	}
+	if !revs[3].Live || revs[4].Live {
+		t.Errorf("live flags = %v, %v; want the live revision marked", revs[3].Live, revs[4].Live)
+	}
+	if revs[3].Size != len("live2") {
+		t.Errorf("merged revision size = %d", revs[3].Size)
+	}
+	if sheet.Version != 9 {
+		t.Errorf("version = %d, want 9", sheet.Version)
+	}
+}
+
+func TestSaveDataIsAtomic(t *testing.T) {
+	forEachDB(t, testSaveDataIsAtomic)
+}
+
+func testSaveDataIsAtomic(t *testing.T, db *gorm.DB) {
+	ctx := context.Background()
+	sheets := NewSpreadsheetRepo(db)
+	ada := createTestUser(t, db, "ada@example.com")
+
+	sheet := &domain.Spreadsheet{Title: "Budget", OwnerID: ada.ID, Data: "v0"}
+	if err := sheets.Create(ctx, sheet); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := *sheet
+	if err := sheets.SaveData(ctx, sheet, map[string]any{"data": "v1"}, ada.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheets.SaveData(ctx, &stale, map[string]any{"data": "lost"}, ada.ID, 0); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("stale save: got %v, want ErrConflict", err)
+	}
+
+	// Without a revisions table the revision insert fails, which must undo
+	// the data write.
+	if err := db.Migrator().RenameTable("revisions", "revisions_gone"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sheets.SaveData(ctx, sheet, map[string]any{"data": "v2"}, ada.ID, 0); err == nil {
+		t.Fatal("save without a revisions table succeeded")
+	}
+	stored, err := sheets.FindByID(ctx, sheet.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Data != "v1" || stored.Version != 2 {
+		t.Errorf("stored = %q version %d, want v1 version 2", stored.Data, stored.Version)
+	}
+
+	if err := db.Migrator().RenameTable("revisions_gone", "revisions"); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Table("revisions").Where("data = ?", "lost").Count(&count)
+	if count != 0 {
+		t.Error("a conflicting save left a revision")
+	}
+}
@@ -0,0 +1,104 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type UserRepo struct {
+	db *gorm.DB
+}
+
+func NewUserRepo(db *gorm.DB) *UserRepo {
+	return &UserRepo{db: db}
+}
+
+func (r *UserRepo) FindByID(ctx context.Context, id uint) (*domain.User, error) {
+	var u User
+	if err := r.db.WithContext(ctx).First(&u, id).Error; err != nil {
+		return nil, err
+	}
+	user := toDomainUser(u)
+	return &user, nil
+}
+
+func (r *UserRepo) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var u User
+	err := r.db.WithContext(ctx).Where("email = ?", domain.NormalizeEmail(email)).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user := toDomainUser(u)
+	return &user, nil
+}
+
+func (r *UserRepo) FindByOIDCIdentity(ctx context.Context, identity string) (*domain.User, error) {
+	var u User
+	err := r.db.WithContext(ctx).Where("oidc_identity = ?", identity).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("user %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	user := toDomainUser(u)
+	return &user, nil
+}
+
+func (r *UserRepo) Create(ctx context.Context, user *domain.User) error {
+	u := toGormUser(user)
+	u.Email = domain.NormalizeEmail(u.Email)
+	if err := r.db.WithContext(ctx).Create(&u).Error; err != nil {
+		return err
+	}
+	user.ID = u.ID
+	user.Email = u.Email
+	user.CreatedAt = u.CreatedAt
+	user.UpdatedAt = u.UpdatedAt
+	return nil
+}
+
+func (r *UserRepo) UpdatePasswordHash(ctx context.Context, id uint, hash string) error {
+	result := r.db.WithContext(ctx).Model(&User{ID: id}).Update("password_hash", hash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) SetOIDCIdentity(ctx context.Context, id uint, identity string) error {
+	result := r.db.WithContext(ctx).Model(&User{ID: id}).Update("oidc_identity", identity)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdateProfile(ctx context.Context, user *domain.User) error {
+	u := User{ID: user.ID}
+	err := r.db.WithContext(ctx).Model(&u).Updates(map[string]any{
+		"name":       user.Name,
+		"avatar_url": user.AvatarURL,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).First(&u, u.ID).Error; err != nil {
+		return err
+	}
+	user.UpdatedAt = u.UpdatedAt
+	return nil
+}
@@ -0,0 +1,175 @@
+package gormrepo
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepo struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepo(db *gorm.DB) *WebhookRepo {
+	return &WebhookRepo{db: db}
+}
+
+func (r *WebhookRepo) Create(ctx context.Context, hook *domain.Webhook) error {
+	w := Webhook{
+		SpreadsheetID: hook.SpreadsheetID,
+		WorkspaceID:   hook.WorkspaceID,
+		CreatedByID:   hook.CreatedByID,
+		URL:           hook.URL,
+		Events:        joinEvents(hook.Events),
+		Secret:        hook.Secret,
+		Active:        hook.Active,
+	}
+	if err := r.db.WithContext(ctx).Create(&w).Error; err != nil {
+		return err
+	}
+	hook.ID = w.ID
+	hook.CreatedAt = w.CreatedAt
+	hook.UpdatedAt = w.UpdatedAt
+	return nil
+}
+
+func (r *WebhookRepo) FindByID(ctx context.Context, id uint) (*domain.Webhook, error) {
+	var w Webhook
+	if err := r.db.WithContext(ctx).First(&w, id).Error; err != nil {
+		return nil, err
+	}
+	hook := toDomainWebhook(w)
+	return &hook, nil
+}
+
+func (r *WebhookRepo) ListBySpreadsheet(ctx context.Context, spreadsheetID uint) ([]domain.Webhook, error) {
+	return r.list(r.db.WithContext(ctx).Where("spreadsheet_id = ?", spreadsheetID))
+}
+
+func (r *WebhookRepo) ListByWorkspace(ctx context.Context, workspaceID uint) ([]domain.Webhook, error) {
+	return r.list(r.db.WithContext(ctx).Where("workspace_id = ?", workspaceID))
+}
+
+func (r *WebhookRepo) ListActive(ctx context.Context, spreadsheetID, workspaceID uint) ([]domain.Webhook, error) {
+	return r.list(r.db.WithContext(ctx).
+		Where("active = ? AND (spreadsheet_id = ? OR workspace_id = ?)", true, spreadsheetID, workspaceID))
+}
+
+func (r *WebhookRepo) list(query *gorm.DB) ([]domain.Webhook, error) {
+	var rows []Webhook
+	if err := query.Order("id").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.Webhook, len(rows))
+	for i, w := range rows {
+		out[i] = toDomainWebhook(w)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepo) Update(ctx context.Context, hook *domain.Webhook) error {
+	w := Webhook{ID: hook.ID}
+	err := r.db.WithContext(ctx).Model(&w).Updates(map[string]any{
+		"url":    hook.URL,
+		"events": joinEvents(hook.Events),
+		"active": hook.Active,
+	}).Error
+	if err != nil {
+		return err
+	}
+	if err := r.db.WithContext(ctx).First(&w, w.ID).Error; err != nil {
+		return err
+	}
+	hook.UpdatedAt = w.UpdatedAt
+	return nil
+}
+
+func (r *WebhookRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", id).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Webhook{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
+
+func (r *WebhookRepo) CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	row := WebhookDelivery{
+		WebhookID:     d.WebhookID,
+		Event:         string(d.Event),
+		Payload:       string(d.Payload),
+		Status:        d.Status,
+		NextAttemptAt: d.NextAttemptAt,
+		RedeliveryOf:  d.RedeliveryOf,
+	}
+	if err := r.db.WithContext(ctx).Create(&row).Error; err != nil {
+		return err
+	}
+	d.ID = row.ID
+	d.CreatedAt = row.CreatedAt
+	return nil
+}
+
+func (r *WebhookRepo) FindDelivery(ctx context.Context, webhookID, id uint) (*domain.WebhookDelivery, error) {
+	var row WebhookDelivery
+	if err := r.db.WithContext(ctx).Where("id = ? AND webhook_id = ?", id, webhookID).First(&row).Error; err != nil {
+		return nil, err
+	}
+	d := toDomainWebhookDelivery(row)
+	return &d, nil
+}
+
+func (r *WebhookRepo) ListDeliveries(ctx context.Context, webhookID, beforeID uint, limit int) ([]domain.WebhookDelivery, error) {
+	query := r.db.WithContext(ctx).Where("webhook_id = ?", webhookID)
+	if beforeID != 0 {
+		query = query.Where("id < ?", beforeID)
+	}
+	return r.deliveries(query.Order("id DESC").Limit(limit))
+}
+
+func (r *WebhookRepo) DueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	return r.deliveries(r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", domain.DeliveryPending, now).
+		Order("next_attempt_at, id").
+		Limit(limit))
+}
+
+func (r *WebhookRepo) deliveries(query *gorm.DB) ([]domain.WebhookDelivery, error) {
+	var rows []WebhookDelivery
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := make([]domain.WebhookDelivery, len(rows))
+	for i, d := range rows {
+		out[i] = toDomainWebhookDelivery(d)
+	}
+	return out, nil
+}
+
+func (r *WebhookRepo) SaveAttempt(ctx context.Context, d *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Model(&WebhookDelivery{}).Where("id = ?", d.ID).
+		Updates(map[string]any{
+			"status":          d.Status,
+			"attempts":        d.Attempts,
+			"status_code":     d.StatusCode,
+			"last_error":      d.Error,
+			"next_attempt_at": d.NextAttemptAt,
+			"delivered_at":    d.DeliveredAt,
+		}).Error
+}
+
+func (r *WebhookRepo) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("status <> ? AND created_at < ?", domain.DeliveryPending, before).
+		Delete(&WebhookDelivery{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,129 @@
+package gormrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// personalWorkspaceName names the workspace every user gets for their own
+// spreadsheets.
+const personalWorkspaceName = "Personal"
+
+type WorkspaceRepo struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepo(db *gorm.DB) *WorkspaceRepo {
+	return &WorkspaceRepo{db: db}
+}
+
+func (r *WorkspaceRepo) Create(ctx context.Context, workspace *domain.Workspace) error {
+	w := Workspace{
+		Name:           workspace.Name,
+		OrganizationID: workspace.OrganizationID,
+		OwnerID:        workspace.OwnerID,
+		MemberRole:     string(workspace.MemberRole),
+	}
+	if err := r.db.WithContext(ctx).Create(&w).Error; err != nil {
+		return err
+	}
+	workspace.ID = w.ID
+	workspace.CreatedAt = w.CreatedAt
+	workspace.UpdatedAt = w.UpdatedAt
+	return nil
+}
+
+func (r *WorkspaceRepo) FindByID(ctx context.Context, id uint) (*domain.Workspace, error) {
+	var w Workspace
+	err := r.db.WithContext(ctx).First(&w, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspace := toDomainWorkspace(w)
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepo) FindOrCreatePersonal(ctx context.Context, userID uint) (*domain.Workspace, error) {
+	var w Workspace
+	err := r.db.WithContext(ctx).Where("owner_id = ?", userID).First(&w).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The unique owner_id index settles concurrent first uses.
+		w = Workspace{Name: personalWorkspaceName, OwnerID: &userID}
+		err = r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&w).Error
+		if err == nil {
+			err = r.db.WithContext(ctx).Where("owner_id = ?", userID).First(&w).Error
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	workspace := toDomainWorkspace(w)
+	return &workspace, nil
+}
+
+func (r *WorkspaceRepo) ListByOrganization(ctx context.Context, orgID uint) ([]domain.Workspace, error) {
+	var rows []Workspace
+	err := r.db.WithContext(ctx).
+		Where("organization_id = ?", orgID).
+		Order("name ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]domain.Workspace, len(rows))
+	for i, w := range rows {
+		out[i] = toDomainWorkspace(w)
+	}
+	return out, nil
+}
+
+func (r *WorkspaceRepo) Update(ctx context.Context, workspace *domain.Workspace) error {
+	w := Workspace{ID: workspace.ID}
+	result := r.db.WithContext(ctx).Model(&w).Updates(map[string]any{
+		"name":        workspace.Name,
+		"member_role": string(workspace.MemberRole),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	if err := r.db.WithContext(ctx).First(&w, w.ID).Error; err != nil {
+		return err
+	}
+	workspace.UpdatedAt = w.UpdatedAt
+	return nil
+}
+
+func (r *WorkspaceRepo) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ?", id).Delete(&Folder{}).Error; err != nil {
+			return err
+		}
+		hooks := tx.Model(&Webhook{}).Select("id").Where("workspace_id = ?", id)
+		if err := tx.Where("webhook_id IN (?)", hooks).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workspace_id = ?", id).Delete(&Webhook{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&Workspace{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+}
@@ -8,7 +8,8 @@ import (
 	"gorm.io/gorm/logger"
 )
 
-// Open initialises a SQLite connection and runs auto-migrations.
+// Open initialises a SQLite connection. The schema is managed by
+// gormrepo's migrations.
 func Open(dbPath string) *gorm.DB {
 	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Warn),
@@ -16,11 +17,5 @@ func Open(dbPath string) *gorm.DB {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
-
-	if err := db.AutoMigrate(&User{}, &Spreadsheet{}, &Session{}); err != nil {
-		log.Fatal("Failed to run migrations:", err)
-	}
-
-	log.Println("Database initialized successfully")
 	return db
 }
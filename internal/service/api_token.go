@@ -0,0 +1,96 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"log"
+	"time"
+)
+
+// APITokenPrefix starts every API token, which tells them apart from
+// session tokens (plain hex) and makes leaked tokens easy to scan for.
+const APITokenPrefix = "gt_"
+
+// CreateAPIToken issues a token for the user. The returned secret is the
+// only copy; the database keeps a SHA-256 hash, which is enough for
+// random 256-bit tokens and, unlike bcrypt, can be looked up directly.
+func (s *AuthService) CreateAPIToken(ctx context.Context, userID uint, req domain.CreateAPITokenRequest) (*domain.CreateAPITokenResponse, error) {
+	if !req.Scope.Valid() {
+		return nil, fmt.Errorf("%w: scope must be read or write", domain.ErrInvalidInput)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidInput)
+	}
+	// Requests are checked against the user's access anyway, but a token
+	// must not be bound to a spreadsheet the user can't see: it would
+	// start working if they were given access later.
+	if req.SpreadsheetID != nil {
+		if _, err := s.sheets.Role(ctx, *req.SpreadsheetID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	secret = APITokenPrefix + secret
+
+	token := &domain.APIToken{
+		UserID:        userID,
+		Name:          req.Name,
+		Prefix:        secret[:len(APITokenPrefix)+8],
+		TokenHash:     hashToken(secret),
+		Scope:         req.Scope,
+		SpreadsheetID: req.SpreadsheetID,
+		ExpiresAt:     req.ExpiresAt,
+	}
+	if err := s.tokens.Create(ctx, token); err != nil {
+		return nil, fmt.Errorf("create api token: %w", err)
+	}
+	return &domain.CreateAPITokenResponse{Token: secret, APIToken: *token}, nil
+}
+
+// ListAPITokens returns the user's tokens, newest first.
+func (s *AuthService) ListAPITokens(ctx context.Context, userID uint) ([]domain.APIToken, error) {
+	tokens, err := s.tokens.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of the user's tokens.
+func (s *AuthService) RevokeAPIToken(ctx context.Context, userID, id uint) error {
+	if err := s.tokens.Delete(ctx, id, userID); err != nil {
+		return fmt.Errorf("API token %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// AuthenticateAPIToken validates an API token presented from ip and
+// records its use.
+func (s *AuthService) AuthenticateAPIToken(ctx context.Context, secret, ip string) (*domain.APIToken, error) {
+	token, err := s.tokens.FindValidByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, errors.New("invalid, expired or revoked api token")
+	}
+
+	now := time.Now()
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= lastSeenInterval || token.LastUsedIP != ip {
+		if err := s.tokens.RecordUse(ctx, token.ID, now, ip); err != nil {
+			log.Printf("Failed to record use of api token %d: %v", token.ID, err)
+		}
+		token.LastUsedAt, token.LastUsedIP = &now, ip
+	}
+	return token, nil
+}
+
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"testing"
+)
+
+// oneSheet holds spreadsheet 7, owned by user 1 and shared with user 3,
+// in a workspace that grants nobody access.
+type (
+	oneSheet            struct{ domain.SpreadsheetRepository }
+	oneSheetPermissions struct{ domain.PermissionRepository }
+	noWorkspaces        struct{ domain.WorkspaceRepository }
+)
+
+func (oneSheet) FindByIDWithoutData(_ context.Context, id uint) (*domain.Spreadsheet, error) {
+	if id != 7 {
+		return nil, errors.New("record not found")
+	}
+	return &domain.Spreadsheet{ID: 7, OwnerID: 1, WorkspaceID: 1}, nil
+}
+
+func (oneSheet) FindByID(ctx context.Context, id uint) (*domain.Spreadsheet, error) {
+	return oneSheet{}.FindByIDWithoutData(ctx, id)
+}
+
+func (oneSheetPermissions) FindRole(_ context.Context, spreadsheetID, userID uint) (domain.Role, error) {
+	if spreadsheetID == 7 && userID == 3 {
+		return domain.RoleViewer, nil
+	}
+	return "", fmt.Errorf("permission %w", domain.ErrNotFound)
+}
+
+func (noWorkspaces) FindByID(context.Context, uint) (*domain.Workspace, error) {
+	return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+}
+
+// memTokens counts the tokens it stores.
+type memTokens struct {
+	domain.APITokenRepository
+	created int
+}
+
+func (r *memTokens) Create(_ context.Context, token *domain.APIToken) error {
+	r.created++
+	token.ID = uint(r.created)
+	return nil
+}
+
+func TestCreateAPITokenChecksSpreadsheetAccess(t *testing.T) {
+	sheets := &SpreadsheetService{sheets: oneSheet{}, permissions: oneSheetPermissions{}, workspaces: noWorkspaces{}}
+	tokens := &memTokens{}
+	s := NewAuthService(nil, nil, tokens, nil, sheets, nil, SessionPolicy{}, false)
+	ctx := context.Background()
+
+	create := func(userID, sheetID uint) error {
+		_, err := s.CreateAPIToken(ctx, userID, domain.CreateAPITokenRequest{
+			Name: "ci", Scope: domain.ScopeRead, SpreadsheetID: &sheetID,
+		})
+		return err
+	}
+	if err := create(1, 7); err != nil {
+		t.Errorf("owner: %v", err)
+	}
+	if err := create(3, 7); err != nil {
+		t.Errorf("user it is shared with: %v", err)
+	}
+	if err := create(2, 7); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("user without access: got %v, want ErrNotFound", err)
+	}
+	if err := create(1, 8); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing spreadsheet: got %v, want ErrNotFound", err)
+	}
+	if tokens.created != 2 {
+		t.Errorf("stored %d tokens, want 2", tokens.created)
+	}
+}
@@ -7,52 +7,167 @@ import (
 	"errors"
 	"fmt"
 	"jaggle-grids/internal/domain"
+	"log"
+	"strings"
 	"time"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
+// dummyHash is compared against when a login names an unknown or
+// password-less account, so that response times don't reveal which
+// emails are registered.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("jaggle-grids-dummy"), bcrypt.DefaultCost)
+
+// maxPasswordBytes is the most bcrypt accepts. Request validation counts
+// characters, and a character outside ASCII takes two to four bytes.
+const maxPasswordBytes = 72
+
 type AuthService struct {
-	users    domain.UserRepository
-	sessions domain.SessionRepository
+	users     domain.UserRepository
+	sessions  domain.SessionRepository
+	tokens    domain.APITokenRepository
+	resets    domain.PasswordResetRepository
+	sheets    *SpreadsheetService
+	emails    *EmailService
+	policy    SessionPolicy
+	mockLogin bool
+
+	resetsByEmail *attemptLimiter
+	resetsByIP    *attemptLimiter
 }
 
-func NewAuthService(users domain.UserRepository, sessions domain.SessionRepository) *AuthService {
-	return &AuthService{users: users, sessions: sessions}
+// NewAuthService creates the auth service. When mockLogin is true, Login
+// accepts any email without a password (local development only).
+func NewAuthService(users domain.UserRepository, sessions domain.SessionRepository, tokens domain.APITokenRepository, resets domain.PasswordResetRepository, sheets *SpreadsheetService, emails *EmailService, policy SessionPolicy, mockLogin bool) *AuthService {
+	return &AuthService{
+		users:     users,
+		sessions:  sessions,
+		tokens:    tokens,
+		resets:    resets,
+		sheets:    sheets,
+		emails:    emails,
+		policy:    policy,
+		mockLogin: mockLogin,
+
+		resetsByEmail: newAttemptLimiter(passwordResetsPerUser, passwordResetWindow),
+		resetsByIP:    newAttemptLimiter(passwordResetsPerIP, passwordResetWindow),
+	}
 }
 
-// Login finds or creates a user by email and returns a session token.
-func (s *AuthService) Login(ctx context.Context, email, name string) (*domain.AuthResponse, error) {
-	user, err := s.users.FindByEmail(ctx, email)
+// MockLoginEnabled reports whether password checks are bypassed.
+func (s *AuthService) MockLoginEnabled() bool {
+	return s.mockLogin
+}
+
+// Register creates a credential account and returns a session token.
+func (s *AuthService) Register(ctx context.Context, email, name, password string, client domain.Client) (*domain.AuthResponse, error) {
+	_, err := s.users.FindByEmail(ctx, email)
+	if err == nil {
+		return nil, domain.ErrEmailTaken
+	}
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("look up email: %w", err)
+	}
+
+	hash, err := hashPassword(password)
 	if err != nil {
+		return nil, err
+	}
+
+	user := &domain.User{Email: email, Name: name, PasswordHash: hash}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+
+	return s.issueSession(ctx, user, client)
+}
+
+// Login verifies an email and password and returns a session token.
+func (s *AuthService) Login(ctx context.Context, email, password string, client domain.Client) (*domain.AuthResponse, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil || user.PasswordHash == "" {
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return s.issueSession(ctx, user, client)
+}
+
+// MockLogin finds or creates a user by email and returns a session token
+// without checking any credentials. Only available in mock mode.
+func (s *AuthService) MockLogin(ctx context.Context, email, name string, client domain.Client) (*domain.AuthResponse, error) {
+	if !s.mockLogin {
+		return nil, errors.New("mock login is disabled")
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
 		// User not found — create one
+		email = domain.NormalizeEmail(email)
+		if name == "" {
+			name, _, _ = strings.Cut(email, "@")
+		}
 		user = &domain.User{Email: email, Name: name}
 		if err := s.users.Create(ctx, user); err != nil {
 			return nil, fmt.Errorf("create user: %w", err)
 		}
+	} else if err != nil {
+		return nil, fmt.Errorf("look up email: %w", err)
 	}
 
-	token, err := generateToken()
+	return s.issueSession(ctx, user, client)
+}
+
+// ChangePassword replaces the password of a user after verifying the
+// current one, and signs out every session except currentID.
+func (s *AuthService) ChangePassword(ctx context.Context, userID, currentID uint, current, next string) error {
+	user, err := s.users.FindByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("generate token: %w", err)
+		return fmt.Errorf("user not found: %w", err)
 	}
 
-	session := &domain.Session{
-		Token:     token,
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(7 * 24 * time.Hour),
-	}
-	if err := s.sessions.Create(ctx, session); err != nil {
-		return nil, fmt.Errorf("create session: %w", err)
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(current)); err != nil {
+		return domain.ErrInvalidCredentials
 	}
 
-	return &domain.AuthResponse{Token: token, User: *user}, nil
+	hash, err := hashPassword(next)
+	if err != nil {
+		return err
+	}
+	if err := s.users.UpdatePasswordHash(ctx, userID, hash); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	if _, err := s.sessions.DeleteOthers(ctx, userID, currentID); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+	return nil
 }
 
-// Authenticate validates a Bearer token and returns the associated session.
-func (s *AuthService) Authenticate(ctx context.Context, token string) (*domain.Session, error) {
+// Authenticate validates a Bearer token used from ip and returns the
+// associated session, renewing it (see SessionPolicy).
+func (s *AuthService) Authenticate(ctx context.Context, token, ip string) (*domain.Session, error) {
 	session, err := s.sessions.FindValidByToken(ctx, token)
 	if err != nil {
 		return nil, errors.New("invalid or expired session")
 	}
+
+	now := time.Now()
+	if now.Sub(session.LastSeenAt) >= lastSeenInterval || session.IP != ip {
+		expiresAt := s.policy.expiry(session.CreatedAt, now)
+		if !expiresAt.After(now) {
+			// MaxLifetime was shortened since the session was issued.
+			return nil, errors.New("invalid or expired session")
+		}
+		if err := s.sessions.Touch(ctx, session.ID, now, ip, expiresAt); err != nil {
+			log.Printf("Failed to renew session %d: %v", session.ID, err)
+		}
+		session.LastSeenAt, session.IP, session.ExpiresAt = now, ip, expiresAt
+	}
 	return session, nil
 }
 
@@ -61,6 +176,40 @@ func (s *AuthService) Logout(ctx context.Context, token string, userID uint) err
 	return s.sessions.DeleteByTokenAndUser(ctx, token, userID)
 }
 
+func (s *AuthService) issueSession(ctx context.Context, user *domain.User, client domain.Client) (*domain.AuthResponse, error) {
+	token, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+
+	now := time.Now()
+	session := &domain.Session{
+		Token:      token,
+		UserID:     user.ID,
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		LastSeenAt: now,
+		ExpiresAt:  s.policy.expiry(now, now),
+	}
+	if err := s.sessions.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("create session: %w", err)
+	}
+
+	return &domain.AuthResponse{Token: token, User: *user}, nil
+}
+
+// hashPassword checks a new password's length in bytes and hashes it.
+func hashPassword(password string) (string, error) {
+	if len(password) > maxPasswordBytes {
+		return "", fmt.Errorf("%w: password must be at most %d bytes; letters with accents and other symbols count as two to four", domain.ErrInvalidInput, maxPasswordBytes)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("hash password: %w", err)
+	}
+	return string(hash), nil
+}
+
 func generateToken() (string, error) {
 	bytes := make([]byte, 32)
 	if _, err := rand.Read(bytes); err != nil {
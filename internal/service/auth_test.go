@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"testing"
+)
+
+// brokenUsers fails every lookup the way an unreachable database would.
+type brokenUsers struct {
+	domain.UserRepository
+}
+
+var errDatabaseDown = errors.New("database is down")
+
+func (brokenUsers) FindByEmail(context.Context, string) (*domain.User, error) {
+	return nil, errDatabaseDown
+}
+
+func TestRegisterDoesNotReportLookupErrorsAsTaken(t *testing.T) {
+	s := NewAuthService(brokenUsers{}, nil, nil, nil, nil, nil, SessionPolicy{}, false)
+	_, err := s.Register(context.Background(), "a@example.com", "A", "password1", domain.Client{})
+	if errors.Is(err, domain.ErrEmailTaken) {
+		t.Fatal("a failed lookup was reported as a taken email")
+	}
+	if !errors.Is(err, errDatabaseDown) {
+		t.Fatalf("got %v, want the lookup error", err)
+	}
+}
+
+func TestNormalizeEmail(t *testing.T) {
+	if got := domain.NormalizeEmail("  Foo.Bar@Example.COM "); got != "foo.bar@example.com" {
+		t.Errorf("NormalizeEmail = %q", got)
+	}
+}
+
+// noUsers holds no accounts and accepts new ones.
+type noUsers struct {
+	domain.UserRepository
+}
+
+func (noUsers) FindByEmail(context.Context, string) (*domain.User, error) {
+	return nil, fmt.Errorf("user %w", domain.ErrNotFound)
+}
+
+func TestRegisterRefusesPasswordsBcryptCantHash(t *testing.T) {
+	s := NewAuthService(noUsers{}, nil, nil, nil, nil, nil, SessionPolicy{}, false)
+	// 60 characters pass request validation, but they are 120 bytes.
+	_, err := s.Register(context.Background(), "a@example.com", "A", strings.Repeat("é", 60), domain.Client{})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("got %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxMentions caps how many users one comment can @mention.
+const maxMentions = 20
+
+// mentionPattern matches an @mention: "@" followed by the user's email.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.])@([\w.%+-]+@[\w-]+(?:\.[\w-]+)+)`)
+
+// ListComments returns the comment threads of a spreadsheet, oldest
+// first. Anyone who can open the spreadsheet can read its comments.
+func (s *SpreadsheetService) ListComments(ctx context.Context, id, userID uint, req domain.ListCommentsRequest) ([]domain.CommentThread, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	return s.listComments(ctx, sheet, req)
+}
+
+func (s *SpreadsheetService) listComments(ctx context.Context, sheet *domain.Spreadsheet, req domain.ListCommentsRequest) ([]domain.CommentThread, error) {
+	comments, err := s.comments.ListThreads(ctx, sheet.ID, domain.CommentFilter{Sheet: req.Sheet, Resolved: req.Resolved})
+	if err != nil {
+		return nil, fmt.Errorf("list comments: %w", err)
+	}
+	return groupThreads(comments), nil
+}
+
+// GetThread returns the thread a comment belongs to.
+func (s *SpreadsheetService) GetThread(ctx context.Context, id, userID, commentID uint) (*domain.CommentThread, error) {
+	if _, err := s.authorize(ctx, id, userID, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+	comment, err := s.findComment(ctx, id, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.thread(ctx, id, threadOf(comment))
+}
+
+// CreateComment starts a thread on a cell or range. It needs the
+// commenter role.
+func (s *SpreadsheetService) CreateComment(ctx context.Context, id, userID uint, req domain.CreateCommentRequest) (*domain.CommentThread, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleCommenter)
+	if err != nil {
+		return nil, err
+	}
+	return s.createComment(ctx, sheet, userID, req)
+}
+
+func (s *SpreadsheetService) createComment(ctx context.Context, sheet *domain.Spreadsheet, userID uint, req domain.CreateCommentRequest) (*domain.CommentThread, error) {
+	rng, err := workbook.ParseRange(req.Anchor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: anchor must be a cell or range like B3 or B3:D5", domain.ErrInvalidInput)
+	}
+	if wb, err := workbook.Decode(sheet.Data); err == nil && wb.Sheet(req.Sheet) == nil {
+		return nil, fmt.Errorf("%w: no sheet named %q", domain.ErrInvalidInput, req.Sheet)
+	}
+
+	comment := &domain.Comment{
+		SpreadsheetID: sheet.ID,
+		Sheet:         req.Sheet,
+		Anchor:        rng.String(),
+		AuthorID:      userID,
+		Body:          strings.TrimSpace(req.Body),
+	}
+	if err := s.addComment(ctx, sheet, comment); err != nil {
+		return nil, err
+	}
+	return s.thread(ctx, sheet.ID, comment.ID)
+}
+
+// ReplyComment adds a reply to the thread of a comment.
+func (s *SpreadsheetService) ReplyComment(ctx context.Context, id, userID, commentID uint, body string) (*domain.Comment, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleCommenter)
+	if err != nil {
+		return nil, err
+	}
+	return s.replyComment(ctx, sheet, userID, commentID, body)
+}
+
+func (s *SpreadsheetService) replyComment(ctx context.Context, sheet *domain.Spreadsheet, userID, commentID uint, body string) (*domain.Comment, error) {
+	parent, err := s.findComment(ctx, sheet.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	threadID := threadOf(parent)
+	reply := &domain.Comment{
+		SpreadsheetID: sheet.ID,
+		ThreadID:      &threadID,
+		AuthorID:      userID,
+		Body:          strings.TrimSpace(body),
+	}
+	if err := s.addComment(ctx, sheet, reply); err != nil {
+		return nil, err
+	}
+	return s.findComment(ctx, sheet.ID, reply.ID)
+}
+
+// EditComment changes the text of a comment. Only its author can.
+func (s *SpreadsheetService) EditComment(ctx context.Context, id, userID, commentID uint, body string) (*domain.Comment, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleCommenter)
+	if err != nil {
+		return nil, err
+	}
+	return s.editComment(ctx, sheet, userID, commentID, body)
+}
+
+func (s *SpreadsheetService) editComment(ctx context.Context, sheet *domain.Spreadsheet, userID, commentID uint, body string) (*domain.Comment, error) {
+	comment, err := s.findComment(ctx, sheet.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, fmt.Errorf("%w: only the author can edit a comment", domain.ErrForbidden)
+	}
+
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, fmt.Errorf("%w: body is required", domain.ErrInvalidInput)
+	}
+	mentions := s.mentions(ctx, sheet, userID, body)
+	if err := s.comments.UpdateBody(ctx, comment.ID, body, mentions, time.Now()); err != nil {
+		return nil, fmt.Errorf("edit comment: %w", err)
+	}
+
+	// Only people mentioned for the first time hear about the edit.
+	notified := map[uint]bool{}
+	for _, u := range comment.Mentions {
+		notified[u.ID] = true
+	}
+	var added []uint
+	for _, m := range mentions {
+		if !notified[m] {
+			added = append(added, m)
+		}
+	}
+	s.notifications.NotifyMentioned(ctx, userID, added, sheet, comment.ID)
+	return s.findComment(ctx, sheet.ID, comment.ID)
+}
+
+// DeleteComment deletes a comment; deleting the first comment of a thread
+// deletes the whole thread. Only the author can, even after losing the
+// commenter role.
+func (s *SpreadsheetService) DeleteComment(ctx context.Context, id, userID, commentID uint) error {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleViewer)
+	if err != nil {
+		return err
+	}
+	return s.deleteComment(ctx, sheet, userID, commentID)
+}
+
+func (s *SpreadsheetService) deleteComment(ctx context.Context, sheet *domain.Spreadsheet, userID, commentID uint) error {
+	comment, err := s.findComment(ctx, sheet.ID, commentID)
+	if err != nil {
+		return err
+	}
+	if comment.AuthorID != userID {
+		return fmt.Errorf("%w: only the author can delete a comment", domain.ErrForbidden)
+	}
+	if err := s.comments.Delete(ctx, comment.ID); err != nil {
+		return fmt.Errorf("comment %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ResolveComment marks the thread of a comment as resolved, or reopens it.
+func (s *SpreadsheetService) ResolveComment(ctx context.Context, id, userID, commentID uint, resolved bool) (*domain.CommentThread, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleCommenter)
+	if err != nil {
+		return nil, err
+	}
+	return s.resolveComment(ctx, sheet, userID, commentID, resolved)
+}
+
+func (s *SpreadsheetService) resolveComment(ctx context.Context, sheet *domain.Spreadsheet, userID, commentID uint, resolved bool) (*domain.CommentThread, error) {
+	comment, err := s.findComment(ctx, sheet.ID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.ThreadID != nil {
+		return nil, fmt.Errorf("%w: replies can't be resolved; resolve the thread instead", domain.ErrInvalidInput)
+	}
+
+	var by *uint
+	var at *time.Time
+	if resolved {
+		now := time.Now()
+		by, at = &userID, &now
+	}
+	if err := s.comments.SetResolved(ctx, comment.ID, by, at); err != nil {
+		return nil, fmt.Errorf("resolve comment: %w", err)
+	}
+	return s.thread(ctx, sheet.ID, comment.ID)
+}
+
+// addComment validates the body, records who it mentions, stores it and
+// notifies them.
+func (s *SpreadsheetService) addComment(ctx context.Context, sheet *domain.Spreadsheet, comment *domain.Comment) error {
+	if comment.Body == "" {
+		return fmt.Errorf("%w: body is required", domain.ErrInvalidInput)
+	}
+	mentions := s.mentions(ctx, sheet, comment.AuthorID, comment.Body)
+	if err := s.comments.Create(ctx, comment, mentions); err != nil {
+		return fmt.Errorf("create comment: %w", err)
+	}
+	s.notifications.NotifyMentioned(ctx, comment.AuthorID, mentions, sheet, comment.ID)
+	return nil
+}
+
+// mentions returns the users @mentioned in body who can open the
+// spreadsheet, other than the author. Mentions of unknown emails or of
+// users without access are left as plain text.
+func (s *SpreadsheetService) mentions(ctx context.Context, sheet *domain.Spreadsheet, authorID uint, body string) []uint {
+	var ids []uint
+	seen := map[uint]bool{authorID: true}
+	for _, m := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if len(ids) == maxMentions {
+			break
+		}
+		user, err := s.users.FindByEmail(ctx, strings.TrimRight(m[1], "."))
+		if err != nil || seen[user.ID] {
+			continue
+		}
+		seen[user.ID] = true
+		if _, err := s.RoleOf(ctx, sheet, user.ID); err != nil {
+			continue
+		}
+		ids = append(ids, user.ID)
+	}
+	return ids
+}
+
+// findComment loads a comment of the spreadsheet.
+func (s *SpreadsheetService) findComment(ctx context.Context, id, commentID uint) (*domain.Comment, error) {
+	comment, err := s.comments.FindByID(ctx, commentID)
+	if err != nil || comment.SpreadsheetID != id {
+		return nil, fmt.Errorf("comment %w", domain.ErrNotFound)
+	}
+	return comment, nil
+}
+
+func (s *SpreadsheetService) thread(ctx context.Context, id, threadID uint) (*domain.CommentThread, error) {
+	comments, err := s.comments.ListThreads(ctx, id, domain.CommentFilter{ThreadID: &threadID})
+	if err != nil {
+		return nil, fmt.Errorf("load comment thread: %w", err)
+	}
+	threads := groupThreads(comments)
+	if len(threads) == 0 {
+		return nil, fmt.Errorf("comment %w", domain.ErrNotFound)
+	}
+	return &threads[0], nil
+}
+
+func threadOf(c *domain.Comment) uint {
+	if c.ThreadID != nil {
+		return *c.ThreadID
+	}
+	return c.ID
+}
+
+// groupThreads puts replies under the first comment of their thread.
+// comments are ordered oldest first, so threads keep that order.
+func groupThreads(comments []domain.Comment) []domain.CommentThread {
+	threads := []domain.CommentThread{}
+	index := map[uint]int{}
+	for _, c := range comments {
+		if c.ThreadID == nil {
+			index[c.ID] = len(threads)
+			threads = append(threads, domain.CommentThread{Comment: c, Replies: []domain.Comment{}})
+		}
+	}
+	for _, c := range comments {
+		if c.ThreadID == nil {
+			continue
+		}
+		if i, ok := index[*c.ThreadID]; ok {
+			threads[i].Replies = append(threads[i].Replies, c)
+		}
+	}
+	return threads
+}
+
+// validateStructure checks the structure changes sent with a save and
+// fills in the default count.
+func validateStructure(changes []domain.StructureChange) error {
+	for i := range changes {
+		c := &changes[i]
+		if c.Op == domain.RenameSheet {
+			if c.Sheet == "" || c.Name == "" {
+				return fmt.Errorf("%w: renaming a sheet needs its old and new name", domain.ErrInvalidInput)
+			}
+			continue
+		}
+		if c.Count == 0 {
+			c.Count = 1
+		}
+		limit := workbook.MaxRows
+		switch c.Op {
+		case domain.InsertRows, domain.DeleteRows:
+		case domain.InsertColumns, domain.DeleteColumns:
+			limit = workbook.MaxCols
+		default:
+			return fmt.Errorf("%w: structure op must be one of insert_rows, delete_rows, insert_columns, delete_columns, rename_sheet", domain.ErrInvalidInput)
+		}
+		if c.Sheet == "" || c.Index < 1 || c.Count < 1 || c.Index > limit {
+			return fmt.Errorf("%w: structure changes need a sheet, an index of at least 1 and a positive count", domain.ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// shiftComments moves comment threads to follow the rows and columns
+// inserted or deleted and the sheets renamed by a save, applying the
+// changes in order. The save has already succeeded, so a failure is only
+// logged.
+func (s *SpreadsheetService) shiftComments(ctx context.Context, id uint, changes []domain.StructureChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	// Threads are looked up by the name their sheet had before the save.
+	// Once a name has been looked up or taken by a rename, the stored
+	// threads under it belong to another sheet and are left alone.
+	var threads []*domain.Comment
+	onSheet := map[string][]*domain.Comment{}
+	looked := map[string]bool{}
+	before := map[uint]domain.CommentAnchor{}
+	for _, c := range changes {
+		if !looked[c.Sheet] {
+			looked[c.Sheet] = true
+			comments, err := s.comments.ListOnSheet(ctx, id, c.Sheet)
+			if err != nil {
+				log.Printf("Failed to move comments of spreadsheet %d: %v", id, err)
+				return
+			}
+			for i := range comments {
+				t := &comments[i]
+				before[t.ID] = domain.CommentAnchor{Sheet: t.Sheet, Anchor: t.Anchor}
+				threads = append(threads, t)
+				onSheet[c.Sheet] = append(onSheet[c.Sheet], t)
+			}
+		}
+
+		if c.Op == domain.RenameSheet {
+			moved := onSheet[c.Sheet]
+			delete(onSheet, c.Sheet)
+			looked[c.Name] = true
+			for _, t := range moved {
+				t.Sheet = c.Name
+			}
+			onSheet[c.Name] = append(onSheet[c.Name], moved...)
+			continue
+		}
+		for _, t := range onSheet[c.Sheet] {
+			t.Anchor = shiftAnchor(t.Anchor, c)
+		}
+	}
+
+	anchors := map[uint]domain.CommentAnchor{}
+	for _, t := range threads {
+		if a := (domain.CommentAnchor{Sheet: t.Sheet, Anchor: t.Anchor}); a != before[t.ID] {
+			anchors[t.ID] = a
+		}
+	}
+	if len(anchors) == 0 {
+		return
+	}
+	if err := s.comments.SetAnchors(ctx, anchors); err != nil {
+		log.Printf("Failed to move comments of spreadsheet %d: %v", id, err)
+	}
+}
+
+// shiftAnchor applies one structure change to an anchor. Cells after
+// inserted rows or columns move down or right, and a range spanning the
+// insertion grows. Deleting part of a range shrinks it; deleting all of
+// it leaves an empty anchor.
+func shiftAnchor(anchor string, c domain.StructureChange) string {
+	rng, err := workbook.ParseRange(anchor)
+	if err != nil {
+		return anchor
+	}
+	start, end := &rng.Start.Row, &rng.End.Row
+	limit := workbook.MaxRows
+	if c.Op == domain.InsertColumns || c.Op == domain.DeleteColumns {
+		start, end = &rng.Start.Col, &rng.End.Col
+		limit = workbook.MaxCols
+	}
+
+	switch c.Op {
+	case domain.InsertRows, domain.InsertColumns:
+		if *start >= c.Index {
+			*start += c.Count
+		}
+		if *end >= c.Index {
+			*end += c.Count
+		}
+		if *start > limit {
+			return ""
+		}
+		*end = min(*end, limit)
+	case domain.DeleteRows, domain.DeleteColumns:
+		last := c.Index + c.Count - 1
+		if *start >= c.Index && *end <= last {
+			return ""
+		}
+		*start = shiftDeleted(*start, c.Index, last, true)
+		*end = shiftDeleted(*end, c.Index, last, false)
+	}
+	return rng.String()
+}
+
+// shiftDeleted moves a range edge for rows or columns first..last being
+// deleted. An edge inside the deleted block snaps to the first kept row or
+// column after it (for a start edge) or before it (for an end edge).
+func shiftDeleted(n, first, last int, isStart bool) int {
+	switch {
+	case n < first:
+		return n
+	case n > last:
+		return n - (last - first + 1)
+	case isStart:
+		return first
+	default:
+		return first - 1
+	}
+}
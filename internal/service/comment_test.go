@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+	"testing"
+)
+
+func TestShiftAnchor(t *testing.T) {
+	change := func(op string, index, count int) domain.StructureChange {
+		return domain.StructureChange{Sheet: "Sheet1", Op: op, Index: index, Count: count}
+	}
+	tests := []struct {
+		name   string
+		anchor string
+		change domain.StructureChange
+		want   string
+	}{
+		{"rows inserted above", "B3", change(domain.InsertRows, 3, 2), "B5"},
+		{"rows inserted below", "B3", change(domain.InsertRows, 4, 1), "B3"},
+		{"rows inserted inside a range", "B3:D5", change(domain.InsertRows, 4, 1), "B3:D6"},
+		{"columns inserted to the left", "B3", change(domain.InsertColumns, 2, 1), "C3"},
+		{"pushed past the last row", fmt.Sprintf("A%d", workbook.MaxRows), change(domain.InsertRows, 1, 1), ""},
+		{"range end clamped to the last row", fmt.Sprintf("A%d:A%d", workbook.MaxRows-1, workbook.MaxRows), change(domain.InsertRows, 1, 1), fmt.Sprintf("A%d", workbook.MaxRows)},
+		{"rows deleted above", "B3", change(domain.DeleteRows, 1, 1), "B2"},
+		{"rows deleted below", "B3", change(domain.DeleteRows, 5, 1), "B3"},
+		{"rows deleted inside a range", "B3:D6", change(domain.DeleteRows, 4, 2), "B3:D4"},
+		{"range start deleted", "B3:D6", change(domain.DeleteRows, 2, 2), "B2:D4"},
+		{"range end deleted", "B3:D6", change(domain.DeleteRows, 5, 5), "B3:D4"},
+		{"columns deleted inside a range", "B3:E3", change(domain.DeleteColumns, 3, 2), "B3:C3"},
+		{"cell deleted", "B3", change(domain.DeleteRows, 3, 1), ""},
+		{"whole range deleted", "B3:D5", change(domain.DeleteColumns, 2, 3), ""},
+		{"already deleted", "", change(domain.InsertRows, 1, 1), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shiftAnchor(tt.anchor, tt.change); got != tt.want {
+				t.Errorf("shiftAnchor(%q) = %q, want %q", tt.anchor, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShiftDeleted(t *testing.T) {
+	tests := []struct {
+		n, first, last int
+		isStart        bool
+		want           int
+	}{
+		{2, 3, 5, true, 2},
+		{2, 3, 5, false, 2},
+		{6, 3, 5, true, 3},
+		{6, 3, 5, false, 3},
+		{4, 3, 5, true, 3},  // the first row after the block, once it's gone
+		{4, 3, 5, false, 2}, // the last row before the block
+		{3, 3, 5, false, 2},
+		{5, 3, 5, true, 3},
+	}
+	for _, tt := range tests {
+		if got := shiftDeleted(tt.n, tt.first, tt.last, tt.isStart); got != tt.want {
+			t.Errorf("shiftDeleted(%d, %d, %d, %v) = %d, want %d", tt.n, tt.first, tt.last, tt.isStart, got, tt.want)
+		}
+	}
+}
+
+func TestValidateStructure(t *testing.T) {
+	tests := []struct {
+		name   string
+		change domain.StructureChange
+		ok     bool
+	}{
+		{"insert", domain.StructureChange{Sheet: "Sheet1", Op: domain.InsertRows, Index: 1}, true},
+		{"no index", domain.StructureChange{Sheet: "Sheet1", Op: domain.DeleteColumns}, false},
+		{"past the last column", domain.StructureChange{Sheet: "Sheet1", Op: domain.InsertColumns, Index: workbook.MaxCols + 1}, false},
+		{"unknown op", domain.StructureChange{Sheet: "Sheet1", Op: "sort", Index: 1}, false},
+		{"rename", domain.StructureChange{Sheet: "Sheet1", Op: domain.RenameSheet, Name: "Costs"}, true},
+		{"rename without a name", domain.StructureChange{Sheet: "Sheet1", Op: domain.RenameSheet}, false},
+	}
+	for _, tt := range tests {
+		changes := []domain.StructureChange{tt.change}
+		err := validateStructure(changes)
+		if tt.ok && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%s: got %v, want ErrInvalidInput", tt.name, err)
+		}
+	}
+}
+
+func (r *memComments) ListOnSheet(_ context.Context, id uint, sheet string) ([]domain.Comment, error) {
+	var list []domain.Comment
+	for _, c := range r.comments {
+		if c.SpreadsheetID == id && c.ThreadID == nil && c.Sheet == sheet {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (r *memComments) SetAnchors(_ context.Context, anchors map[uint]domain.CommentAnchor) error {
+	for i, c := range r.comments {
+		if a, ok := anchors[c.ID]; ok {
+			r.comments[i].Sheet, r.comments[i].Anchor = a.Sheet, a.Anchor
+		}
+	}
+	return nil
+}
+
+func TestShiftCommentsFollowsRenamedSheets(t *testing.T) {
+	comments := &memComments{comments: []domain.Comment{
+		{ID: 1, SpreadsheetID: 7, Sheet: "Costs", Anchor: "B3"},
+		{ID: 2, SpreadsheetID: 7, Sheet: "Sales", Anchor: "A1"},
+		{ID: 3, SpreadsheetID: 7, Sheet: "Costs", Anchor: ""},
+		{ID: 4, SpreadsheetID: 8, Sheet: "Costs", Anchor: "B3"},
+	}}
+	s := &SpreadsheetService{comments: comments}
+
+	// Costs becomes Budget and Sales takes its old name; each change
+	// applies to the sheet that had the name at the time.
+	s.shiftComments(context.Background(), 7, []domain.StructureChange{
+		{Sheet: "Costs", Op: domain.InsertRows, Index: 2, Count: 1},
+		{Sheet: "Costs", Op: domain.RenameSheet, Name: "Budget"},
+		{Sheet: "Budget", Op: domain.DeleteRows, Index: 1, Count: 1},
+		{Sheet: "Sales", Op: domain.RenameSheet, Name: "Costs"},
+		{Sheet: "Costs", Op: domain.InsertColumns, Index: 1, Count: 1},
+	})
+
+	want := []domain.CommentAnchor{
+		{Sheet: "Budget", Anchor: "B3"},
+		{Sheet: "Costs", Anchor: "B1"},
+		{Sheet: "Budget", Anchor: ""},
+		{Sheet: "Costs", Anchor: "B3"}, // another spreadsheet
+	}
+	for i, c := range comments.comments {
+		if got := (domain.CommentAnchor{Sheet: c.Sheet, Anchor: c.Anchor}); got != want[i] {
+			t.Errorf("comment %d on %+v, want %+v", c.ID, got, want[i])
+		}
+	}
+}
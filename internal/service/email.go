@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/mail"
+	"log"
+	"strings"
+	"time"
+)
+
+// DefaultEmailRetention is how long sent and abandoned emails stay in the
+// queue table.
+const DefaultEmailRetention = 7 * 24 * time.Hour
+
+const (
+	// emailBatch is how many emails one delivery run sends at most.
+	emailBatch = 50
+	// maxEmailAttempts is how often an email is tried before giving up;
+	// with the backoff below the last try is about eight hours after the
+	// first.
+	maxEmailAttempts = 10
+	emailRetryBase   = time.Minute
+	emailRetryMax    = 6 * time.Hour
+)
+
+// EmailService queues templated emails and delivers them in the
+// background, retrying failures with exponential backoff. Queued emails
+// survive restarts.
+type EmailService struct {
+	outbox domain.EmailRepository
+	mailer mail.Mailer
+	appURL string
+}
+
+// NewEmailService creates the service. appURL is the address of the
+// frontend, used for links in emails.
+func NewEmailService(outbox domain.EmailRepository, mailer mail.Mailer, appURL string) *EmailService {
+	return &EmailService{outbox: outbox, mailer: mailer, appURL: strings.TrimRight(appURL, "/")}
+}
+
+// Link returns the frontend URL of path.
+func (s *EmailService) Link(path string) string {
+	return s.appURL + path
+}
+
+// Enqueue renders the named email template for to and queues it.
+func (s *EmailService) Enqueue(ctx context.Context, template, to string, data any) error {
+	msg, err := mail.Render(template, to, data)
+	if err != nil {
+		return err
+	}
+	email := &domain.Email{
+		To:            msg.To,
+		Subject:       msg.Subject,
+		Text:          msg.Text,
+		HTML:          msg.HTML,
+		NextAttemptAt: time.Now(),
+	}
+	if err := s.outbox.Enqueue(ctx, email); err != nil {
+		return fmt.Errorf("queue email: %w", err)
+	}
+	return nil
+}
+
+// Deliver sends the queued emails that are due. A failed email is retried
+// later; one failure doesn't stop the others.
+func (s *EmailService) Deliver(ctx context.Context) error {
+	due, err := s.outbox.Due(ctx, time.Now(), emailBatch)
+	if err != nil {
+		return fmt.Errorf("load email queue: %w", err)
+	}
+
+	for _, email := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		attempts := email.Attempts + 1
+		sendErr := s.mailer.Send(ctx, mail.Message{
+			To:      email.To,
+			Subject: email.Subject,
+			Text:    email.Text,
+			HTML:    email.HTML,
+		})
+
+		now := time.Now()
+		switch {
+		case sendErr == nil:
+			err = s.outbox.MarkSent(ctx, email.ID, attempts, now)
+		case attempts >= maxEmailAttempts:
+			log.Printf("Giving up on email %d to %s after %d attempts: %v", email.ID, email.To, attempts, sendErr)
+			err = s.outbox.GiveUp(ctx, email.ID, attempts, sendErr.Error(), now)
+		default:
+			log.Printf("Failed to send email %d to %s (attempt %d): %v", email.ID, email.To, attempts, sendErr)
+			err = s.outbox.Retry(ctx, email.ID, attempts, sendErr.Error(), now.Add(emailBackoff(attempts)))
+		}
+		if err != nil {
+			return fmt.Errorf("update email %d: %w", email.ID, err)
+		}
+	}
+	return nil
+}
+
+// PurgeEmails deletes emails sent or given up on longer than retention ago.
+func (s *EmailService) PurgeEmails(ctx context.Context, retention time.Duration) error {
+	n, err := s.outbox.DeleteFinishedBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("purge emails: %w", err)
+	}
+	if n > 0 {
+		log.Printf("Purged %d delivered emails", n)
+	}
+	return nil
+}
+
+// emailBackoff is the wait after the given failed attempt: one minute,
+// doubling each time up to emailRetryMax.
+func emailBackoff(attempts int) time.Duration {
+	d := emailRetryBase
+	for i := 1; i < attempts && d < emailRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, emailRetryMax)
+}
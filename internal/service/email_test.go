@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/mail"
+	"jaggle-grids/internal/mail/mailtest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memOutbox is an email queue in memory.
+type memOutbox struct {
+	domain.EmailRepository
+	emails []*domain.Email
+}
+
+func (r *memOutbox) Due(_ context.Context, now time.Time, limit int) ([]domain.Email, error) {
+	var due []domain.Email
+	for _, e := range r.emails {
+		if e.SentAt == nil && e.FailedAt == nil && !e.NextAttemptAt.After(now) && len(due) < limit {
+			due = append(due, *e)
+		}
+	}
+	return due, nil
+}
+
+func (r *memOutbox) Enqueue(_ context.Context, e *domain.Email) error {
+	e.ID = uint(len(r.emails) + 1)
+	r.emails = append(r.emails, e)
+	return nil
+}
+
+func (r *memOutbox) find(id uint) *domain.Email {
+	for _, e := range r.emails {
+		if e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+func (r *memOutbox) MarkSent(_ context.Context, id uint, attempts int, at time.Time) error {
+	e := r.find(id)
+	e.Attempts, e.SentAt = attempts, &at
+	return nil
+}
+
+func (r *memOutbox) Retry(_ context.Context, id uint, attempts int, lastError string, next time.Time) error {
+	e := r.find(id)
+	e.Attempts, e.LastError, e.NextAttemptAt = attempts, lastError, next
+	return nil
+}
+
+func (r *memOutbox) GiveUp(_ context.Context, id uint, attempts int, lastError string, at time.Time) error {
+	e := r.find(id)
+	e.Attempts, e.LastError, e.FailedAt = attempts, lastError, &at
+	return nil
+}
+
+func TestDeliverRetriesWithBackoffThenGivesUp(t *testing.T) {
+	server := mailtest.NewServer(t, mailtest.Options{})
+	mailer, err := mail.NewSMTPMailer(mail.SMTPConfig{
+		Host: server.Host, Port: server.Port, TLS: mail.TLSNone, From: "grids@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	email := &domain.Email{ID: 1, To: "ada@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>", NextAttemptAt: time.Now()}
+	outbox := &memOutbox{emails: []*domain.Email{email}}
+	s := NewEmailService(outbox, mailer, "")
+	ctx := context.Background()
+
+	server.Reject("451 4.3.0 Mailbox busy")
+	wait := time.Minute
+	for attempt := 1; attempt < maxEmailAttempts; attempt++ {
+		before := time.Now()
+		if err := s.Deliver(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if email.Attempts != attempt || email.FailedAt != nil || !strings.Contains(email.LastError, "Mailbox busy") {
+			t.Fatalf("attempt %d: got %+v", attempt, email)
+		}
+		if got := email.NextAttemptAt.Sub(before); got < wait || got > wait+5*time.Second {
+			t.Fatalf("attempt %d: retry in %v, want %v", attempt, got, wait)
+		}
+		// Nothing is sent again before the backoff has passed.
+		if err := s.Deliver(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if email.Attempts != attempt {
+			t.Fatalf("attempt %d: retried before the backoff", attempt)
+		}
+		email.NextAttemptAt = time.Now()
+		wait *= 2
+	}
+
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if email.Attempts != maxEmailAttempts || email.FailedAt == nil || email.SentAt != nil {
+		t.Fatalf("after the last attempt: got %+v, want it given up", email)
+	}
+
+	// The server recovers: new emails are delivered, the abandoned one isn't.
+	server.Reject("")
+	next := &domain.Email{ID: 2, To: "grace@example.com", Subject: "Hi", Text: "Hi", HTML: "<p>Hi</p>", NextAttemptAt: time.Now()}
+	outbox.emails = append(outbox.emails, next)
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if next.SentAt == nil || next.Attempts != 1 {
+		t.Errorf("got %+v, want it sent on the first attempt", next)
+	}
+	received := server.Messages()
+	if len(received) != 1 || received[0].To[0] != "grace@example.com" {
+		t.Errorf("server received %+v, want only the new email", received)
+	}
+}
+
+func TestEmailBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{9, 256 * time.Minute},
+		{10, emailRetryMax},
+		{50, emailRetryMax},
+	} {
+		if got := emailBackoff(tt.attempts); got != tt.want {
+			t.Errorf("emailBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+)
+
+// maxFolderDepth limits how deeply folders nest.
+const maxFolderDepth = 16
+
+// FolderService manages the folders of workspaces. Anyone who can use a
+// workspace can browse its folders; changing them needs the editor role
+// in the workspace.
+type FolderService struct {
+	folders    domain.FolderRepository
+	workspaces domain.WorkspaceRepository
+	orgs       domain.OrganizationRepository
+	sheets     domain.SpreadsheetRepository
+}
+
+func NewFolderService(
+	folders domain.FolderRepository,
+	workspaces domain.WorkspaceRepository,
+	orgs domain.OrganizationRepository,
+	sheets domain.SpreadsheetRepository,
+) *FolderService {
+	return &FolderService{folders: folders, workspaces: workspaces, orgs: orgs, sheets: sheets}
+}
+
+// ListFolders returns every folder in a workspace, with paths, so clients
+// can build the tree.
+func (s *FolderService) ListFolders(ctx context.Context, workspaceID, userID uint) ([]domain.Folder, error) {
+	if err := s.checkWorkspace(ctx, workspaceID, userID, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+	folders, err := s.folders.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list folders: %w", err)
+	}
+	tree := newFolderTree(folders)
+	for i := range folders {
+		folders[i].Path = tree.path(folders[i].ID)
+	}
+	return folders, nil
+}
+
+// TopLevel lists the folders and spreadsheets at a workspace's top level.
+func (s *FolderService) TopLevel(ctx context.Context, workspaceID, userID uint) (*domain.FolderContents, error) {
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, workspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	return s.contents(ctx, workspaceID, nil, role, userID)
+}
+
+// Contents returns a folder, with its path, and what is directly in it.
+func (s *FolderService) Contents(ctx context.Context, id, userID uint) (*domain.FolderContents, error) {
+	folder, err := s.folders.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("folder %w", domain.ErrNotFound)
+	}
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, folder.WorkspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if role == "" {
+		return nil, fmt.Errorf("folder %w", domain.ErrNotFound)
+	}
+	return s.contents(ctx, folder.WorkspaceID, folder, role, userID)
+}
+
+// contents lists folder, or the top level when it is nil, for a user
+// holding role in the workspace.
+func (s *FolderService) contents(ctx context.Context, workspaceID uint, folder *domain.Folder, role domain.Role, userID uint) (*domain.FolderContents, error) {
+	all, err := s.folders.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list folders: %w", err)
+	}
+	tree := newFolderTree(all)
+	out := &domain.FolderContents{Folders: []domain.Folder{}}
+	var folderID *uint
+	if folder != nil {
+		folder.Path = tree.path(folder.ID)
+		out.Folder = folder
+		folderID = &folder.ID
+	}
+	for _, f := range all {
+		if sameFolder(f.ParentID, folderID) {
+			f.Path = tree.path(f.ID)
+			out.Folders = append(out.Folders, f)
+		}
+	}
+
+	sheets, err := s.sheets.ListByFolder(ctx, workspaceID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("list spreadsheets: %w", err)
+	}
+	out.Spreadsheets = workspaceItems(sheets, role, userID)
+	return out, nil
+}
+
+func (s *FolderService) CreateFolder(ctx context.Context, workspaceID, userID uint, req domain.CreateFolderRequest) (*domain.Folder, error) {
+	if err := s.checkWorkspace(ctx, workspaceID, userID, domain.RoleEditor); err != nil {
+		return nil, err
+	}
+	all, err := s.folders.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list folders: %w", err)
+	}
+	tree := newFolderTree(all)
+	if req.ParentID != nil {
+		if _, ok := tree.byID[*req.ParentID]; !ok {
+			return nil, fmt.Errorf("parent folder %w", domain.ErrNotFound)
+		}
+		if tree.depth(*req.ParentID)+1 > maxFolderDepth {
+			return nil, fmt.Errorf("%w: folders can't be nested more than %d deep", domain.ErrInvalidInput, maxFolderDepth)
+		}
+	}
+
+	folder := &domain.Folder{WorkspaceID: workspaceID, ParentID: req.ParentID, Name: req.Name}
+	if err := s.folders.Create(ctx, folder); err != nil {
+		return nil, fmt.Errorf("create folder: %w", err)
+	}
+	tree.add(*folder)
+	folder.Path = tree.path(folder.ID)
+	return folder, nil
+}
+
+func (s *FolderService) RenameFolder(ctx context.Context, id, userID uint, name string) (*domain.Folder, error) {
+	folder, tree, err := s.authorizeFolder(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	folder.Name = name
+	if err := s.folders.Update(ctx, folder); err != nil {
+		return nil, fmt.Errorf("update folder: %w", err)
+	}
+	tree.add(*folder)
+	folder.Path = tree.path(folder.ID)
+	return folder, nil
+}
+
+// MoveFolder nests a folder, with everything in it, in another folder of
+// the same workspace, or at the top level when parentID is nil.
+func (s *FolderService) MoveFolder(ctx context.Context, id, userID uint, parentID *uint) (*domain.Folder, error) {
+	folder, tree, err := s.authorizeFolder(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if parentID != nil {
+		if _, ok := tree.byID[*parentID]; !ok {
+			return nil, fmt.Errorf("parent folder %w", domain.ErrNotFound)
+		}
+		if tree.contains(folder.ID, *parentID) {
+			return nil, fmt.Errorf("%w: a folder can't be moved into itself", domain.ErrInvalidInput)
+		}
+		if tree.depth(*parentID)+tree.height(folder.ID) > maxFolderDepth {
+			return nil, fmt.Errorf("%w: folders can't be nested more than %d deep", domain.ErrInvalidInput, maxFolderDepth)
+		}
+	}
+
+	folder.ParentID = parentID
+	if err := s.folders.Update(ctx, folder); err != nil {
+		return nil, fmt.Errorf("update folder: %w", err)
+	}
+	tree.add(*folder)
+	folder.Path = tree.path(folder.ID)
+	return folder, nil
+}
+
+// DeleteFolder removes a folder. Nothing in it is deleted: its subfolders
+// and spreadsheets move up into its parent.
+func (s *FolderService) DeleteFolder(ctx context.Context, id, userID uint) error {
+	folder, _, err := s.authorizeFolder(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.folders.Delete(ctx, folder); err != nil {
+		return fmt.Errorf("delete folder: %w", err)
+	}
+	return nil
+}
+
+// authorizeFolder loads a folder the user may change, with the folder
+// tree of its workspace.
+func (s *FolderService) authorizeFolder(ctx context.Context, id, userID uint) (*domain.Folder, *folderTree, error) {
+	folder, err := s.folders.FindByID(ctx, id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("folder %w", domain.ErrNotFound)
+	}
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, folder.WorkspaceID, userID)
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case role == "":
+		return nil, nil, fmt.Errorf("folder %w", domain.ErrNotFound)
+	case !role.Allows(domain.RoleEditor):
+		return nil, nil, fmt.Errorf("%w: editor role required in this workspace", domain.ErrForbidden)
+	}
+
+	all, err := s.folders.ListByWorkspace(ctx, folder.WorkspaceID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list folders: %w", err)
+	}
+	return folder, newFolderTree(all), nil
+}
+
+// checkWorkspace checks that the user holds at least min in a workspace.
+func (s *FolderService) checkWorkspace(ctx context.Context, workspaceID, userID uint, min domain.Role) error {
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, workspaceID, userID)
+	switch {
+	case err != nil:
+		return err
+	case role == "":
+		return fmt.Errorf("workspace %w", domain.ErrNotFound)
+	case !role.Allows(min):
+		return fmt.Errorf("%w: %s role required in this workspace", domain.ErrForbidden, min)
+	}
+	return nil
+}
+
+func sameFolder(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// ── Folder tree ──────────────────────────────
+
+// folderTree indexes the folders of a workspace by ID.
+type folderTree struct {
+	byID map[uint]domain.Folder
+}
+
+func newFolderTree(folders []domain.Folder) *folderTree {
+	t := &folderTree{byID: make(map[uint]domain.Folder, len(folders))}
+	for _, f := range folders {
+		t.add(f)
+	}
+	return t
+}
+
+func (t *folderTree) add(f domain.Folder) {
+	t.byID[f.ID] = f
+}
+
+// path returns the breadcrumbs from the top level down to folder id.
+func (t *folderTree) path(id uint) []domain.Breadcrumb {
+	var path []domain.Breadcrumb
+	for next := &id; next != nil && len(path) <= maxFolderDepth; {
+		f, ok := t.byID[*next]
+		if !ok {
+			break
+		}
+		path = append(path, domain.Breadcrumb{ID: f.ID, Name: f.Name})
+		next = f.ParentID
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// depth returns how many folders deep id is; top-level folders are 1.
+func (t *folderTree) depth(id uint) int {
+	return len(t.path(id))
+}
+
+// height returns the depth of the subtree rooted at id; 1 without
+// subfolders.
+func (t *folderTree) height(id uint) int {
+	h := 1
+	for _, f := range t.byID {
+		if f.ParentID != nil && *f.ParentID == id {
+			h = max(h, 1+t.height(f.ID))
+		}
+	}
+	return h
+}
+
+// contains reports whether id is ancestor or one of its descendants.
+func (t *folderTree) contains(ancestor, id uint) bool {
+	for _, b := range t.path(id) {
+		if b.ID == ancestor {
+			return true
+		}
+	}
+	return false
+}
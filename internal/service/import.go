@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+)
+
+// importReaders read the file formats Import accepts.
+var importReaders = map[string]func(io.Reader) (*workbook.Workbook, []workbook.Warning, error){
+	"xlsx": workbook.ReadXLSX,
+	"ods":  workbook.ReadODS,
+	"csv":  workbook.ReadCSV,
+}
+
+// Import creates a spreadsheet owned by the user from an xlsx, ods or csv
+// file, in workspaceID or their personal workspace. The warnings list what
+// the import had to drop or approximate.
+func (s *SpreadsheetService) Import(ctx context.Context, ownerID uint, workspaceID *uint, title, format string, r io.Reader) (*domain.Spreadsheet, []workbook.Warning, error) {
+	read, ok := importReaders[format]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: cannot import %q files", domain.ErrInvalidInput, format)
+	}
+	ws, err := s.targetWorkspace(ctx, workspaceID, ownerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	wb, warnings, err := read(r)
+	if err != nil {
+		if errors.Is(err, workbook.ErrInvalidFile) || errors.Is(err, workbook.ErrTooLarge) {
+			return nil, nil, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+		}
+		return nil, nil, fmt.Errorf("import %s: %w", format, err)
+	}
+
+	// Files from tools that don't calculate have formulas without values.
+	wb.RecalculateStale()
+	data, err := workbook.Encode(wb)
+	if err != nil {
+		return nil, nil, err
+	}
+	sheet := &domain.Spreadsheet{Title: title, OwnerID: ownerID, WorkspaceID: ws.ID, Data: data}
+	if err := s.sheets.Create(ctx, sheet); err != nil {
+		return nil, nil, fmt.Errorf("create spreadsheet: %w", err)
+	}
+	if err := s.recordRevision(ctx, sheet, ownerID); err != nil {
+		return nil, nil, err
+	}
+	s.index(ctx, sheet)
+	s.webhooks.EmitCreated(ctx, sheet, ownerID)
+	sheet.Role = domain.RoleOwner
+	return sheet, warnings, nil
+}
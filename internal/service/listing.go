@@ -0,0 +1,141 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"time"
+)
+
+// listQuery validates the parameters of the spreadsheet list and turns
+// them into a repository query.
+func listQuery(userID uint, req domain.ListSpreadsheetsRequest) (domain.SpreadsheetQuery, error) {
+	q := domain.SpreadsheetQuery{
+		UserID:      userID,
+		Title:       strings.TrimSpace(req.Title),
+		OwnerID:     req.OwnerID,
+		WorkspaceID: req.WorkspaceID,
+		FolderID:    req.FolderID,
+		Sort:        domain.SortUpdated,
+		Descending:  true,
+		Limit:       defaultListLimit,
+	}
+
+	switch req.View {
+	case "", ListAll:
+		q.Owned, q.Shared, q.Team = true, true, true
+	case ListOwned:
+		q.Owned = true
+	case ListShared:
+		q.Shared = true
+	case ListTeam:
+		q.Team = true
+	default:
+		return q, fmt.Errorf("%w: view must be one of all, owned, shared, team", domain.ErrInvalidInput)
+	}
+
+	if req.Sort != "" {
+		q.Sort = domain.SpreadsheetSort(req.Sort)
+		if !q.Sort.Valid() {
+			return q, fmt.Errorf("%w: sort must be one of title, created_at, updated_at", domain.ErrInvalidInput)
+		}
+		// Titles read A to Z, dates newest first.
+		q.Descending = q.Sort != domain.SortTitle
+	}
+	switch req.Order {
+	case "":
+	case "asc":
+		q.Descending = false
+	case "desc":
+		q.Descending = true
+	default:
+		return q, fmt.Errorf("%w: order must be asc or desc", domain.ErrInvalidInput)
+	}
+
+	switch {
+	case req.Limit < 0 || req.Limit > maxListLimit:
+		return q, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidInput, maxListLimit)
+	case req.Limit > 0:
+		q.Limit = req.Limit
+	}
+
+	for _, d := range []struct {
+		name  string
+		value string
+		dst   **time.Time
+	}{
+		{"created_after", req.CreatedAfter, &q.CreatedAfter},
+		{"created_before", req.CreatedBefore, &q.CreatedBefore},
+		{"updated_after", req.UpdatedAfter, &q.UpdatedAfter},
+		{"updated_before", req.UpdatedBefore, &q.UpdatedBefore},
+	} {
+		if d.value == "" {
+			continue
+		}
+		t, err := parseListDate(d.value)
+		if err != nil {
+			return q, fmt.Errorf("%w: %s must be an RFC 3339 time or YYYY-MM-DD", domain.ErrInvalidInput, d.name)
+		}
+		*d.dst = &t
+	}
+
+	if req.Cursor != "" {
+		after, err := decodeListCursor(req.Cursor, q)
+		if err != nil {
+			return q, err
+		}
+		q.After = after
+	}
+	return q, nil
+}
+
+// parseListDate accepts an RFC 3339 time or a date, which stands for
+// midnight UTC.
+func parseListDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// listCursor is what a page's next_cursor encodes: the position of the
+// page's last row, and the order it was listed in so the cursor can't be
+// used with another.
+type listCursor struct {
+	Sort  domain.SpreadsheetSort `json:"s"`
+	Desc  bool                   `json:"d,omitempty"`
+	Title string                 `json:"t,omitempty"`
+	Time  time.Time              `json:"u"`
+	ID    uint                   `json:"i"`
+}
+
+func encodeListCursor(q domain.SpreadsheetQuery, last domain.Spreadsheet) string {
+	c := listCursor{Sort: q.Sort, Desc: q.Descending, ID: last.ID}
+	switch q.Sort {
+	case domain.SortTitle:
+		c.Title = last.Title
+	case domain.SortCreated:
+		c.Time = last.CreatedAt
+	default:
+		c.Time = last.UpdatedAt
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeListCursor(s string, q domain.SpreadsheetQuery) (*domain.SpreadsheetCursor, error) {
+	var c listCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.ID == 0 {
+		return nil, fmt.Errorf("%w: invalid cursor", domain.ErrInvalidInput)
+	}
+	if c.Sort != q.Sort || c.Desc != q.Descending {
+		return nil, fmt.Errorf("%w: cursor belongs to a different sort order", domain.ErrInvalidInput)
+	}
+	return &domain.SpreadsheetCursor{Title: c.Title, Time: c.Time, ID: c.ID}, nil
+}
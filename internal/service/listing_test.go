@@ -0,0 +1,138 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"testing"
+	"time"
+)
+
+func TestListQueryDefaults(t *testing.T) {
+	q, err := listQuery(1, domain.ListSpreadsheetsRequest{Title: "  budget "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Owned || !q.Shared || !q.Team || q.Title != "budget" || q.Sort != domain.SortUpdated || !q.Descending || q.Limit != defaultListLimit || q.After != nil {
+		t.Errorf("query = %+v", q)
+	}
+
+	// Titles read A to Z and dates newest first, unless order says otherwise.
+	for _, tt := range []struct {
+		sort, order string
+		desc        bool
+	}{
+		{"title", "", false},
+		{"created_at", "", true},
+		{"updated_at", "asc", false},
+		{"title", "desc", true},
+	} {
+		q, err := listQuery(1, domain.ListSpreadsheetsRequest{Sort: tt.sort, Order: tt.order})
+		if err != nil || string(q.Sort) != tt.sort || q.Descending != tt.desc {
+			t.Errorf("sort=%s order=%s: %+v, %v", tt.sort, tt.order, q, err)
+		}
+	}
+
+	q, err = listQuery(1, domain.ListSpreadsheetsRequest{View: ListShared, Limit: maxListLimit, CreatedAfter: "2024-03-01", UpdatedBefore: "2024-03-02T10:00:00+01:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Owned || !q.Shared || q.Team || q.Limit != maxListLimit ||
+		!q.CreatedAfter.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) ||
+		!q.UpdatedBefore.Equal(time.Date(2024, 3, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("query = %+v", q)
+	}
+}
+
+func TestListQueryRejectsInvalidParameters(t *testing.T) {
+	for name, req := range map[string]domain.ListSpreadsheetsRequest{
+		"view":        {View: "mine"},
+		"sort":        {Sort: "owner"},
+		"order":       {Order: "up"},
+		"negative":    {Limit: -1},
+		"too many":    {Limit: maxListLimit + 1},
+		"date":        {CreatedBefore: "yesterday"},
+		"date format": {UpdatedAfter: "01/03/2024"},
+		"garbage":     {Cursor: "not a cursor!"},
+		"not json":    {Cursor: base64.RawURLEncoding.EncodeToString([]byte("[1,2]"))},
+		"no id":       {Cursor: base64.RawURLEncoding.EncodeToString([]byte(`{"s":"updated_at","d":true}`))},
+	} {
+		if _, err := listQuery(1, req); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("%s: got %v, want ErrInvalidInput", name, err)
+		}
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	last := domain.Spreadsheet{
+		ID:        42,
+		Title:     "Budget",
+		CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC),
+		UpdatedAt: time.Date(2024, 3, 2, 12, 0, 0, 987654321, time.UTC),
+	}
+	for _, req := range []domain.ListSpreadsheetsRequest{
+		{},
+		{Sort: "title"},
+		{Sort: "title", Order: "desc"},
+		{Sort: "created_at", Order: "asc"},
+		{Sort: "updated_at", View: ListOwned, Limit: 5},
+	} {
+		q, err := listQuery(1, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Cursor = encodeListCursor(q, last)
+		next, err := listQuery(1, req)
+		if err != nil {
+			t.Fatalf("%+v: %v", req, err)
+		}
+		want := domain.SpreadsheetCursor{ID: 42}
+		switch q.Sort {
+		case domain.SortTitle:
+			want.Title = last.Title
+		case domain.SortCreated:
+			want.Time = last.CreatedAt
+		default:
+			want.Time = last.UpdatedAt
+		}
+		// The cursor keeps the time to the nanosecond, so rows saved in
+		// the same second aren't skipped.
+		if next.After == nil || next.After.ID != want.ID || next.After.Title != want.Title || !next.After.Time.Equal(want.Time) {
+			t.Errorf("%+v: cursor decoded to %+v, want %+v", req, next.After, want)
+		}
+	}
+}
+
+func TestListCursorBelongsToItsOrder(t *testing.T) {
+	last := domain.Spreadsheet{ID: 42, Title: "Budget", UpdatedAt: time.Now()}
+	cursorFor := func(sort, order string) string {
+		q, err := listQuery(1, domain.ListSpreadsheetsRequest{Sort: sort, Order: order})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encodeListCursor(q, last)
+	}
+
+	for _, tt := range []struct {
+		from, fromOrder string
+		to, toOrder     string
+	}{
+		{"title", "", "created_at", ""},
+		{"title", "", "updated_at", ""},
+		{"updated_at", "", "created_at", ""},
+		{"title", "", "title", "desc"},
+		{"updated_at", "desc", "updated_at", "asc"},
+		{"", "", "title", ""},
+	} {
+		req := domain.ListSpreadsheetsRequest{Sort: tt.to, Order: tt.toOrder, Cursor: cursorFor(tt.from, tt.fromOrder)}
+		if _, err := listQuery(1, req); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("cursor for %s %s used with %s %s: %v", tt.from, tt.fromOrder, tt.to, tt.toOrder, err)
+		}
+	}
+
+	// The defaults and their explicit spelling are the same order.
+	req := domain.ListSpreadsheetsRequest{Sort: "updated_at", Order: "desc", Cursor: cursorFor("", "")}
+	if _, err := listQuery(1, req); err != nil {
+		t.Errorf("default cursor with explicit order: %v", err)
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultNotificationRetention is how long read notifications are kept.
+const DefaultNotificationRetention = 90 * 24 * time.Hour
+
+const (
+	defaultNotificationLimit = 50
+	maxNotificationLimit     = 200
+	// notificationBuffer is how many events a stream can fall behind
+	// before further ones are dropped for it.
+	notificationBuffer = 16
+)
+
+// NotificationService stores notifications and pushes them to the streams
+// their users have open; shares and mentions are emailed too. Other
+// services emit into it through the Notify* methods; emitting never fails
+// the action that caused it.
+type NotificationService struct {
+	notes  domain.NotificationRepository
+	users  domain.UserRepository
+	emails *EmailService
+
+	mu   sync.Mutex
+	subs map[uint]map[chan domain.NotificationEvent]struct{}
+}
+
+func NewNotificationService(notes domain.NotificationRepository, users domain.UserRepository, emails *EmailService) *NotificationService {
+	return &NotificationService{
+		notes:  notes,
+		users:  users,
+		emails: emails,
+		subs:   map[uint]map[chan domain.NotificationEvent]struct{}{},
+	}
+}
+
+// notificationEmail is the data of the share and mention email templates.
+type notificationEmail struct {
+	Actor string
+	Title string
+	Role  domain.Role
+	URL   string
+}
+
+// List returns a page of the user's notifications, newest first, with
+// their unread count.
+func (s *NotificationService) List(ctx context.Context, userID uint, req domain.ListNotificationsRequest) (*domain.NotificationPage, error) {
+	switch {
+	case req.Limit < 0 || req.Limit > maxNotificationLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidInput, maxNotificationLimit)
+	case req.Limit == 0:
+		req.Limit = defaultNotificationLimit
+	}
+
+	items, err := s.notes.List(ctx, userID, req.Unread, req.Before, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list notifications: %w", err)
+	}
+	unread, err := s.notes.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count notifications: %w", err)
+	}
+	return &domain.NotificationPage{Items: items, UnreadCount: unread}, nil
+}
+
+// UnreadCount returns how many of the user's notifications are unread.
+func (s *NotificationService) UnreadCount(ctx context.Context, userID uint) (int64, error) {
+	unread, err := s.notes.CountUnread(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("count notifications: %w", err)
+	}
+	return unread, nil
+}
+
+// MarkRead marks one of the user's notifications as read.
+func (s *NotificationService) MarkRead(ctx context.Context, userID, id uint) error {
+	if err := s.notes.MarkRead(ctx, id, userID, time.Now()); err != nil {
+		return fmt.Errorf("notification %w", domain.ErrNotFound)
+	}
+	s.publishUnread(ctx, userID)
+	return nil
+}
+
+// MarkAllRead marks every notification of the user as read.
+func (s *NotificationService) MarkAllRead(ctx context.Context, userID uint) error {
+	if err := s.notes.MarkAllRead(ctx, userID, time.Now()); err != nil {
+		return fmt.Errorf("mark notifications read: %w", err)
+	}
+	s.publishUnread(ctx, userID)
+	return nil
+}
+
+// Preferences returns whether each notification type is on for the user.
+// Every type is on until turned off.
+func (s *NotificationService) Preferences(ctx context.Context, userID uint) (map[domain.NotificationType]bool, error) {
+	stored, err := s.notes.Preferences(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("load notification preferences: %w", err)
+	}
+	prefs := make(map[domain.NotificationType]bool, len(domain.NotificationTypes))
+	for _, t := range domain.NotificationTypes {
+		enabled, ok := stored[t]
+		prefs[t] = enabled || !ok
+	}
+	return prefs, nil
+}
+
+// UpdatePreferences turns the given notification types on or off; types
+// left out keep their setting.
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID uint, prefs map[domain.NotificationType]bool) (map[domain.NotificationType]bool, error) {
+	for t := range prefs {
+		if !t.Valid() {
+			return nil, fmt.Errorf("%w: unknown notification type %q", domain.ErrInvalidInput, t)
+		}
+	}
+	if err := s.notes.SetPreferences(ctx, userID, prefs); err != nil {
+		return nil, fmt.Errorf("save notification preferences: %w", err)
+	}
+	return s.Preferences(ctx, userID)
+}
+
+// PurgeRead deletes notifications read and older than retention.
+func (s *NotificationService) PurgeRead(ctx context.Context, retention time.Duration) error {
+	n, err := s.notes.DeleteReadBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("purge notifications: %w", err)
+	}
+	if n > 0 {
+		log.Printf("Purged %d read notifications", n)
+	}
+	return nil
+}
+
+// Subscribe opens a stream of the user's notification events. The
+// returned function closes it.
+func (s *NotificationService) Subscribe(userID uint) (<-chan domain.NotificationEvent, func()) {
+	ch := make(chan domain.NotificationEvent, notificationBuffer)
+	s.mu.Lock()
+	if s.subs[userID] == nil {
+		s.subs[userID] = map[chan domain.NotificationEvent]struct{}{}
+	}
+	s.subs[userID][ch] = struct{}{}
+	s.mu.Unlock()
+
+	return ch, func() {
+		s.mu.Lock()
+		delete(s.subs[userID], ch)
+		if len(s.subs[userID]) == 0 {
+			delete(s.subs, userID)
+		}
+		s.mu.Unlock()
+	}
+}
+
+// NotifyShared tells a user that a spreadsheet was shared with them.
+func (s *NotificationService) NotifyShared(ctx context.Context, actorID, userID uint, sheet *domain.Spreadsheet, role domain.Role) {
+	actor, ok := s.notify(ctx, &domain.Notification{
+		UserID:        userID,
+		Type:          domain.NotifyShare,
+		ActorID:       &actorID,
+		SpreadsheetID: &sheet.ID,
+	}, func(actor string) string {
+		return fmt.Sprintf("%s shared “%s” with you as %s", actor, sheet.Title, role)
+	})
+	if ok {
+		s.email(ctx, userID, "share", notificationEmail{
+			Actor: actor,
+			Title: sheet.Title,
+			Role:  role,
+			URL:   s.emails.Link(fmt.Sprintf("/spreadsheet/%d", sheet.ID)),
+		})
+	}
+}
+
+// NotifyMentioned tells users they were @mentioned in a comment.
+func (s *NotificationService) NotifyMentioned(ctx context.Context, actorID uint, userIDs []uint, sheet *domain.Spreadsheet, commentID uint) {
+	for _, userID := range userIDs {
+		actor, ok := s.notify(ctx, &domain.Notification{
+			UserID:        userID,
+			Type:          domain.NotifyMention,
+			ActorID:       &actorID,
+			SpreadsheetID: &sheet.ID,
+			CommentID:     &commentID,
+		}, func(actor string) string {
+			return fmt.Sprintf("%s mentioned you in a comment on “%s”", actor, sheet.Title)
+		})
+		if ok {
+			s.email(ctx, userID, "mention", notificationEmail{
+				Actor: actor,
+				Title: sheet.Title,
+				URL:   s.emails.Link(fmt.Sprintf("/spreadsheet/%d", sheet.ID)),
+			})
+		}
+	}
+}
+
+// NotifyAccessRequest tells a spreadsheet's owner that someone asked for
+// access. A request repeated before the owner has read the first one is
+// not notified again.
+func (s *NotificationService) NotifyAccessRequest(ctx context.Context, actorID uint, sheet *domain.Spreadsheet, role domain.Role, message string) {
+	pending, err := s.notes.HasUnread(ctx, sheet.OwnerID, domain.NotifyAccessRequest, actorID, sheet.ID)
+	if err != nil {
+		log.Printf("Failed to check access requests for spreadsheet %d: %v", sheet.ID, err)
+		return
+	}
+	if pending {
+		return
+	}
+	s.notify(ctx, &domain.Notification{
+		UserID:        sheet.OwnerID,
+		Type:          domain.NotifyAccessRequest,
+		ActorID:       &actorID,
+		SpreadsheetID: &sheet.ID,
+	}, func(actor string) string {
+		text := fmt.Sprintf("%s asked for %s access to “%s”", actor, role, sheet.Title)
+		if message != "" {
+			text += ": " + message
+		}
+		return text
+	})
+}
+
+// notify stores a notification, unless it is about the user's own action
+// or they turned its type off, and pushes it to their open streams.
+// message renders the text from the actor's name. It returns that name and
+// whether the notification was stored.
+func (s *NotificationService) notify(ctx context.Context, n *domain.Notification, message func(actor string) string) (string, bool) {
+	if n.ActorID != nil && *n.ActorID == n.UserID {
+		return "", false
+	}
+	prefs, err := s.Preferences(ctx, n.UserID)
+	if err != nil {
+		log.Printf("Failed to notify user %d: %v", n.UserID, err)
+		return "", false
+	}
+	if !prefs[n.Type] {
+		return "", false
+	}
+
+	name := "Someone"
+	if n.ActorID != nil {
+		if actor, err := s.users.FindByID(ctx, *n.ActorID); err == nil {
+			n.Actor = actor
+			name = actor.Name
+		}
+	}
+	n.Message = message(name)
+	if err := s.notes.Create(ctx, n); err != nil {
+		log.Printf("Failed to notify user %d: %v", n.UserID, err)
+		return "", false
+	}
+
+	unread, err := s.notes.CountUnread(ctx, n.UserID)
+	if err != nil {
+		log.Printf("Failed to count notifications of user %d: %v", n.UserID, err)
+	}
+	s.publish(n.UserID, domain.NotificationEvent{Notification: n, UnreadCount: unread})
+	return name, true
+}
+
+// email queues a notification email to the user.
+func (s *NotificationService) email(ctx context.Context, userID uint, template string, data notificationEmail) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to email user %d: %v", userID, err)
+		return
+	}
+	if err := s.emails.Enqueue(ctx, template, user.Email, data); err != nil {
+		log.Printf("Failed to email user %d: %v", userID, err)
+	}
+}
+
+// publishUnread pushes the user's new unread count after marking read, so
+// their other tabs update.
+func (s *NotificationService) publishUnread(ctx context.Context, userID uint) {
+	unread, err := s.notes.CountUnread(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to count notifications of user %d: %v", userID, err)
+		return
+	}
+	s.publish(userID, domain.NotificationEvent{UnreadCount: unread})
+}
+
+func (s *NotificationService) publish(userID uint, ev domain.NotificationEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for ch := range s.subs[userID] {
+		select {
+		case ch <- ev:
+		default:
+			// The stream isn't keeping up; it catches up from List.
+		}
+	}
+}
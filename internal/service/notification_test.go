@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"maps"
+	"testing"
+	"time"
+)
+
+// memNotes keeps notifications and preferences in memory.
+type memNotes struct {
+	domain.NotificationRepository
+	notes []domain.Notification
+	prefs map[uint]map[domain.NotificationType]bool
+}
+
+func (r *memNotes) Create(_ context.Context, n *domain.Notification) error {
+	n.ID = uint(len(r.notes) + 1)
+	r.notes = append(r.notes, *n)
+	return nil
+}
+
+func (r *memNotes) CountUnread(_ context.Context, userID uint) (int64, error) {
+	var n int64
+	for _, note := range r.notes {
+		if note.UserID == userID && note.ReadAt == nil {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memNotes) HasUnread(_ context.Context, userID uint, t domain.NotificationType, actorID, spreadsheetID uint) (bool, error) {
+	for _, note := range r.notes {
+		if note.UserID == userID && note.Type == t && note.ReadAt == nil && *note.ActorID == actorID && *note.SpreadsheetID == spreadsheetID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memNotes) MarkAllRead(_ context.Context, userID uint, at time.Time) error {
+	for i := range r.notes {
+		if r.notes[i].UserID == userID {
+			r.notes[i].ReadAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *memNotes) Preferences(_ context.Context, userID uint) (map[domain.NotificationType]bool, error) {
+	return maps.Clone(r.prefs[userID]), nil
+}
+
+func (r *memNotes) SetPreferences(_ context.Context, userID uint, prefs map[domain.NotificationType]bool) error {
+	if r.prefs == nil {
+		r.prefs = map[uint]map[domain.NotificationType]bool{}
+	}
+	if r.prefs[userID] == nil {
+		r.prefs[userID] = map[domain.NotificationType]bool{}
+	}
+	maps.Copy(r.prefs[userID], prefs)
+	return nil
+}
+
+// of returns the types of the user's notifications, oldest first.
+func (r *memNotes) of(userID uint) []domain.NotificationType {
+	var types []domain.NotificationType
+	for _, note := range r.notes {
+		if note.UserID == userID {
+			types = append(types, note.Type)
+		}
+	}
+	return types
+}
+
+func newTestNotifications(t *testing.T) (*NotificationService, *memNotes, *memOutbox) {
+	t.Helper()
+	users := &memUsers{}
+	for _, email := range []string{"ada@example.com", "bob@example.com"} {
+		if err := users.Create(context.Background(), &domain.User{Email: email, Name: email[:3]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notes := &memNotes{}
+	outbox := &memOutbox{}
+	return NewNotificationService(notes, users, NewEmailService(outbox, nil, "https://grids.example.com/")), notes, outbox
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	ctx := context.Background()
+	s, notes, _ := newTestNotifications(t)
+
+	prefs, err := s.Preferences(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prefs) != len(domain.NotificationTypes) {
+		t.Errorf("preferences = %v", prefs)
+	}
+	for _, typ := range domain.NotificationTypes {
+		if !prefs[typ] {
+			t.Errorf("%s is off by default", typ)
+		}
+	}
+
+	prefs, err = s.UpdatePreferences(ctx, 2, map[domain.NotificationType]bool{domain.NotifyMention: false})
+	if err != nil || prefs[domain.NotifyMention] || !prefs[domain.NotifyShare] || !prefs[domain.NotifyAccessRequest] {
+		t.Fatalf("after turning mentions off: %v, %v", prefs, err)
+	}
+	// Types left out keep their setting.
+	prefs, err = s.UpdatePreferences(ctx, 2, map[domain.NotificationType]bool{domain.NotifyShare: false})
+	if err != nil || prefs[domain.NotifyMention] || prefs[domain.NotifyShare] || !prefs[domain.NotifyAccessRequest] {
+		t.Fatalf("after turning shares off: %v, %v", prefs, err)
+	}
+	prefs, err = s.UpdatePreferences(ctx, 2, map[domain.NotificationType]bool{domain.NotifyMention: true})
+	if err != nil || !prefs[domain.NotifyMention] || prefs[domain.NotifyShare] {
+		t.Fatalf("after turning mentions on: %v, %v", prefs, err)
+	}
+
+	// An unknown type is refused without changing anything.
+	_, err = s.UpdatePreferences(ctx, 2, map[domain.NotificationType]bool{domain.NotifyMention: false, "digest": true})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unknown type: %v", err)
+	}
+	if prefs, _ := s.Preferences(ctx, 2); !prefs[domain.NotifyMention] || len(notes.prefs[2]) != 2 {
+		t.Errorf("refused update changed preferences: %v", notes.prefs[2])
+	}
+
+	// Other users keep the defaults.
+	if prefs, _ := s.Preferences(ctx, 1); !prefs[domain.NotifyShare] || !prefs[domain.NotifyMention] {
+		t.Errorf("another user's preferences = %v", prefs)
+	}
+}
+
+func TestNotifyRespectsPreferences(t *testing.T) {
+	ctx := context.Background()
+	s, notes, outbox := newTestNotifications(t)
+	events, closeStream := s.Subscribe(2)
+	defer closeStream()
+	sheet := &domain.Spreadsheet{ID: 7, OwnerID: 1, Title: "Budget"}
+
+	s.NotifyShared(ctx, 1, 2, sheet, domain.RoleEditor)
+	s.NotifyMentioned(ctx, 1, []uint{2}, sheet, 3)
+	if got := notes.of(2); len(got) != 2 || len(outbox.emails) != 2 || len(events) != 2 {
+		t.Fatalf("notifications %v, %d emails, %d events with every type on", got, len(outbox.emails), len(events))
+	}
+	if ev := <-events; ev.Notification.Type != domain.NotifyShare || ev.UnreadCount != 1 || ev.Notification.Message != "ada shared “Budget” with you as editor" {
+		t.Errorf("event = %+v", ev)
+	}
+	<-events
+
+	// A type turned off is neither stored, pushed nor emailed.
+	if _, err := s.UpdatePreferences(ctx, 2, map[domain.NotificationType]bool{domain.NotifyMention: false}); err != nil {
+		t.Fatal(err)
+	}
+	s.NotifyMentioned(ctx, 1, []uint{2}, sheet, 4)
+	s.NotifyShared(ctx, 1, 2, sheet, domain.RoleViewer)
+	if got := notes.of(2); len(got) != 3 || got[2] != domain.NotifyShare || len(outbox.emails) != 3 {
+		t.Errorf("notifications %v, %d emails with mentions off", got, len(outbox.emails))
+	}
+	if ev := <-events; ev.Notification.Type != domain.NotifyShare || ev.UnreadCount != 3 {
+		t.Errorf("event = %+v", ev)
+	}
+	if len(events) != 0 {
+		t.Errorf("%d more events", len(events))
+	}
+
+	// Another user's preferences don't matter, and nobody is told about
+	// their own actions.
+	s.NotifyMentioned(ctx, 2, []uint{1, 2}, sheet, 5)
+	if got := notes.of(1); len(got) != 1 || got[0] != domain.NotifyMention {
+		t.Errorf("ada's notifications = %v", got)
+	}
+	s.NotifyShared(ctx, 1, 1, sheet, domain.RoleViewer)
+	if got := notes.of(1); len(got) != 1 {
+		t.Errorf("ada was told about their own share: %v", got)
+	}
+	if len(notes.of(2)) != 3 || len(events) != 0 {
+		t.Errorf("bob was told about their own mention")
+	}
+}
+
+func TestNotifyAccessRequest(t *testing.T) {
+	ctx := context.Background()
+	s, notes, outbox := newTestNotifications(t)
+	sheet := &domain.Spreadsheet{ID: 7, OwnerID: 1, Title: "Budget"}
+	other := &domain.Spreadsheet{ID: 8, OwnerID: 1, Title: "Plan"}
+
+	// Repeating a request before the owner has read it adds nothing.
+	s.NotifyAccessRequest(ctx, 2, sheet, domain.RoleEditor, "please")
+	s.NotifyAccessRequest(ctx, 2, sheet, domain.RoleViewer, "")
+	s.NotifyAccessRequest(ctx, 2, other, domain.RoleViewer, "")
+	if got := notes.of(1); len(got) != 2 {
+		t.Fatalf("notifications = %v", got)
+	}
+	if msg := notes.notes[0].Message; msg != "bob asked for editor access to “Budget”: please" {
+		t.Errorf("message = %q", msg)
+	}
+	if len(outbox.emails) != 0 {
+		t.Errorf("access requests sent %d emails", len(outbox.emails))
+	}
+
+	if err := s.MarkAllRead(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.NotifyAccessRequest(ctx, 2, sheet, domain.RoleViewer, "")
+	if got := notes.of(1); len(got) != 3 {
+		t.Errorf("request after reading the first: %v", got)
+	}
+
+	if _, err := s.UpdatePreferences(ctx, 1, map[domain.NotificationType]bool{domain.NotifyAccessRequest: false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.MarkAllRead(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	s.NotifyAccessRequest(ctx, 2, sheet, domain.RoleViewer, "")
+	if got := notes.of(1); len(got) != 3 {
+		t.Errorf("request with access requests off: %v", got)
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// OIDCLoginState is generated when a login starts and must be handed back
+// unchanged when the identity provider redirects to the callback.
+type OIDCLoginState struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// oidcLinkTTL is how long a user has to confirm linking their identity
+// provider account by signing in with their password.
+const oidcLinkTTL = 10 * time.Minute
+
+// OIDCLinkRequiredError is returned by Complete when the identity provider
+// account's email belongs to a password account that isn't linked to it.
+// Proof of ownership is required before they are linked: the user signs in
+// with the password and hands back Token (see LinkWithPassword).
+type OIDCLinkRequiredError struct {
+	Email string
+	Token string
+}
+
+func (e *OIDCLinkRequiredError) Error() string {
+	return fmt.Sprintf("%s has a password: sign in with it to link single sign-on", e.Email)
+}
+
+type pendingOIDCLink struct {
+	identity  string
+	email     string
+	expiresAt time.Time
+}
+
+// OIDCService signs users in through an OpenID Connect identity provider
+// using the authorization-code flow with PKCE.
+type OIDCService struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	users    domain.UserRepository
+	auth     *AuthService
+
+	mu    sync.Mutex
+	links map[string]pendingOIDCLink // by link token
+}
+
+// NewOIDCService runs provider discovery against cfg.IssuerURL.
+func NewOIDCService(ctx context.Context, cfg OIDCConfig, users domain.UserRepository, auth *AuthService) (*OIDCService, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("discover issuer: %w", err)
+	}
+
+	return &OIDCService{
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", "profile"},
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		users:    users,
+		auth:     auth,
+		links:    make(map[string]pendingOIDCLink),
+	}, nil
+}
+
+// Begin returns the provider URL to redirect the browser to, plus the state
+// that Complete needs to finish the login.
+func (s *OIDCService) Begin() (string, OIDCLoginState, error) {
+	state, err := generateToken()
+	if err != nil {
+		return "", OIDCLoginState{}, fmt.Errorf("generate state: %w", err)
+	}
+	nonce, err := generateToken()
+	if err != nil {
+		return "", OIDCLoginState{}, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	login := OIDCLoginState{State: state, Nonce: nonce, Verifier: oauth2.GenerateVerifier()}
+	url := s.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(login.Verifier))
+	return url, login, nil
+}
+
+// Complete checks that state is the one issued by Begin, exchanges the
+// authorization code, verifies the ID token and returns a session for the
+// linked user. On first login a user is created, or an existing account
+// with the same email is linked if it has no password; password accounts
+// get an *OIDCLinkRequiredError instead.
+func (s *OIDCService) Complete(ctx context.Context, code, state string, login OIDCLoginState, client domain.Client) (*domain.AuthResponse, error) {
+	if state == "" || state != login.State {
+		return nil, errors.New("state mismatch")
+	}
+
+	token, err := s.oauth.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+	idToken, err := s.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("id_token nonce mismatch")
+	}
+
+	var claims struct {
+		Email         string `json:"email"`
+		EmailVerified *bool  `json:"email_verified"`
+		Name          string `json:"name"`
+		Picture       string `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("decode claims: %w", err)
+	}
+	if claims.Email == "" {
+		return nil, errors.New("id_token has no email claim")
+	}
+	// Providers that leave the claim out make no promise about the email,
+	// so it counts as unverified.
+	if claims.EmailVerified == nil || !*claims.EmailVerified {
+		return nil, errors.New("email is not verified by the identity provider")
+	}
+	if claims.Name == "" {
+		claims.Name, _, _ = strings.Cut(claims.Email, "@")
+	}
+
+	identity := idToken.Issuer + " " + idToken.Subject
+	user, err := s.users.FindByOIDCIdentity(ctx, identity)
+	if errors.Is(err, domain.ErrNotFound) {
+		user, err = s.firstLogin(ctx, identity, claims.Email, claims.Name, claims.Picture)
+		if err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("look up identity: %w", err)
+	}
+
+	if user.Name != claims.Name || user.AvatarURL != claims.Picture {
+		user.Name = claims.Name
+		user.AvatarURL = claims.Picture
+		if err := s.users.UpdateProfile(ctx, user); err != nil {
+			return nil, fmt.Errorf("update user: %w", err)
+		}
+	}
+
+	return s.auth.issueSession(ctx, user, client)
+}
+
+// firstLogin finds or creates the user for an identity that isn't linked
+// to anyone yet.
+func (s *OIDCService) firstLogin(ctx context.Context, identity, email, name, picture string) (*domain.User, error) {
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		user = &domain.User{Email: email, Name: name, AvatarURL: picture, OIDCIdentity: identity}
+		if err := s.users.Create(ctx, user); err != nil {
+			return nil, fmt.Errorf("create user: %w", err)
+		}
+		return user, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("look up email: %w", err)
+	}
+
+	switch {
+	case user.OIDCIdentity != "":
+		return nil, fmt.Errorf("%s is linked to another single sign-on account", user.Email)
+	case user.PasswordHash != "":
+		token, err := generateToken()
+		if err != nil {
+			return nil, fmt.Errorf("generate link token: %w", err)
+		}
+		s.addPendingLink(token, pendingOIDCLink{identity: identity, email: user.Email, expiresAt: time.Now().Add(oidcLinkTTL)})
+		return nil, &OIDCLinkRequiredError{Email: user.Email, Token: token}
+	}
+
+	// Accounts without a password could only be signed in to through
+	// single sign-on before identities were recorded.
+	if err := s.users.SetOIDCIdentity(ctx, user.ID, identity); err != nil {
+		return nil, fmt.Errorf("link identity: %w", err)
+	}
+	user.OIDCIdentity = identity
+	return user, nil
+}
+
+// LinkWithPassword signs in with email and password and links the account
+// to the identity provider account of a pending link token.
+func (s *OIDCService) LinkWithPassword(ctx context.Context, token, email, password string, client domain.Client) (*domain.AuthResponse, error) {
+	s.mu.Lock()
+	link, ok := s.links[token]
+	s.mu.Unlock()
+	if !ok || time.Now().After(link.expiresAt) {
+		return nil, fmt.Errorf("%w: the single sign-on link has expired, sign in with single sign-on again", domain.ErrInvalidInput)
+	}
+	if domain.NormalizeEmail(email) != link.email {
+		return nil, fmt.Errorf("%w: sign in as %s to link single sign-on", domain.ErrInvalidInput, link.email)
+	}
+
+	resp, err := s.auth.Login(ctx, email, password, client)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	delete(s.links, token)
+	s.mu.Unlock()
+	if err := s.users.SetOIDCIdentity(ctx, resp.User.ID, link.identity); err != nil {
+		return nil, fmt.Errorf("link identity: %w", err)
+	}
+	resp.User.OIDCIdentity = link.identity
+	return resp, nil
+}
+
+func (s *OIDCService) addPendingLink(token string, link pendingOIDCLink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for t, l := range s.links {
+		if now.After(l.expiresAt) {
+			delete(s.links, t)
+		}
+	}
+	s.links[token] = link
+}
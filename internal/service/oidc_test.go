@@ -0,0 +1,379 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeIssuer is a minimal OpenID Connect provider: discovery, keys and a
+// token endpoint that checks the PKCE verifier against the challenge the
+// code was issued for.
+type fakeIssuer struct {
+	*httptest.Server
+	t   *testing.T
+	key *rsa.PrivateKey
+
+	mu            sync.Mutex
+	codes         map[string]fakeGrant
+	tokenRequests int
+}
+
+type fakeGrant struct {
+	challenge string
+	claims    map[string]any
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeIssuer{t: t, key: key, codes: make(map[string]fakeGrant)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                f.URL,
+			"authorization_endpoint":                f.URL + "/authorize",
+			"token_endpoint":                        f.URL + "/token",
+			"jwks_uri":                              f.URL + "/keys",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "alg": "RS256", "use": "sig", "kid": "test",
+			"n": b64(key.N.Bytes()),
+			"e": b64(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", f.token)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeIssuer) token(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.tokenRequests++
+	grant, ok := f.codes[r.FormValue("code")]
+	delete(f.codes, r.FormValue("code"))
+	f.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+	if !ok || b64(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     f.sign(grant.claims),
+	})
+}
+
+func (f *fakeIssuer) sign(claims map[string]any) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "test", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+	sum := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, f.key, crypto.SHA256, sum[:])
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	return signed + "." + b64(sig)
+}
+
+// authorize stands in for the user approving the login at the provider:
+// it issues a code for the authorization URL from Begin, with an ID token
+// for sub carrying the request's nonce plus extra claims.
+func (f *fakeIssuer) authorize(authURL, sub string, extra map[string]any) string {
+	u, err := url.Parse(authURL)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		f.t.Fatalf("authorization URL has no S256 challenge: %s", authURL)
+	}
+	now := time.Now()
+	claims := map[string]any{
+		"iss":   f.URL,
+		"sub":   sub,
+		"aud":   "grids",
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+		"nonce": q.Get("nonce"),
+	}
+	for k, v := range extra {
+		claims[k] = v
+	}
+	code, _ := generateToken()
+	f.mu.Lock()
+	f.codes[code] = fakeGrant{challenge: q.Get("code_challenge"), claims: claims}
+	f.mu.Unlock()
+	return code
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// memUsers is an in-memory UserRepository.
+type memUsers struct {
+	mu    sync.Mutex
+	users []domain.User
+}
+
+func (m *memUsers) find(match func(domain.User) bool) (*domain.User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, u := range m.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *memUsers) FindByID(_ context.Context, id uint) (*domain.User, error) {
+	return m.find(func(u domain.User) bool { return u.ID == id })
+}
+
+func (m *memUsers) FindByEmail(_ context.Context, email string) (*domain.User, error) {
+	return m.find(func(u domain.User) bool { return u.Email == domain.NormalizeEmail(email) })
+}
+
+func (m *memUsers) FindByOIDCIdentity(_ context.Context, identity string) (*domain.User, error) {
+	return m.find(func(u domain.User) bool { return u.OIDCIdentity != "" && u.OIDCIdentity == identity })
+}
+
+func (m *memUsers) Create(_ context.Context, user *domain.User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	user.ID = uint(len(m.users) + 1)
+	user.Email = domain.NormalizeEmail(user.Email)
+	m.users = append(m.users, *user)
+	return nil
+}
+
+func (m *memUsers) update(id uint, change func(*domain.User)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.users {
+		if m.users[i].ID == id {
+			change(&m.users[i])
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *memUsers) UpdatePasswordHash(_ context.Context, id uint, hash string) error {
+	return m.update(id, func(u *domain.User) { u.PasswordHash = hash })
+}
+
+func (m *memUsers) SetOIDCIdentity(_ context.Context, id uint, identity string) error {
+	return m.update(id, func(u *domain.User) { u.OIDCIdentity = identity })
+}
+
+func (m *memUsers) UpdateProfile(_ context.Context, user *domain.User) error {
+	return m.update(user.ID, func(u *domain.User) { u.Name, u.AvatarURL = user.Name, user.AvatarURL })
+}
+
+// memSessions accepts new sessions and nothing else.
+type memSessions struct {
+	domain.SessionRepository
+}
+
+func (memSessions) Create(context.Context, *domain.Session) error { return nil }
+
+func newTestOIDC(t *testing.T) (*OIDCService, *fakeIssuer, *memUsers) {
+	issuer := newFakeIssuer(t)
+	users := &memUsers{}
+	auth := NewAuthService(users, memSessions{}, nil, nil, nil, nil, DefaultSessionPolicy, false)
+	cfg := OIDCConfig{IssuerURL: issuer.URL, ClientID: "grids", ClientSecret: "secret", RedirectURL: "http://grids.test/callback"}
+	s, err := NewOIDCService(context.Background(), cfg, users, auth)
+	if err != nil {
+		t.Fatalf("discovery: %v", err)
+	}
+	return s, issuer, users
+}
+
+var verifiedClaims = map[string]any{"email": "Ada@Example.com", "email_verified": true, "name": "Ada"}
+
+// oidcLogin runs Begin, lets the fake provider approve it and calls Complete.
+func oidcLogin(s *OIDCService, issuer *fakeIssuer, sub string, claims map[string]any) (*domain.AuthResponse, error) {
+	authURL, state, err := s.Begin()
+	if err != nil {
+		return nil, err
+	}
+	code := issuer.authorize(authURL, sub, claims)
+	return s.Complete(context.Background(), code, state.State, state, domain.Client{})
+}
+
+func TestOIDCLoginCreatesAndReusesUser(t *testing.T) {
+	s, issuer, users := newTestOIDC(t)
+
+	first, err := oidcLogin(s, issuer, "sub-1", verifiedClaims)
+	if err != nil {
+		t.Fatalf("first login: %v", err)
+	}
+	if first.User.Email != "ada@example.com" || first.User.Name != "Ada" {
+		t.Errorf("created user = %+v", first.User)
+	}
+
+	renamed := map[string]any{"email": "ada@example.com", "email_verified": true, "name": "Ada L."}
+	second, err := oidcLogin(s, issuer, "sub-1", renamed)
+	if err != nil {
+		t.Fatalf("second login: %v", err)
+	}
+	if second.User.ID != first.User.ID || second.User.Name != "Ada L." {
+		t.Errorf("second login user = %+v, want user %d renamed", second.User, first.User.ID)
+	}
+	if len(users.users) != 1 {
+		t.Errorf("%d users, want 1", len(users.users))
+	}
+}
+
+func TestOIDCRejectsMismatchedState(t *testing.T) {
+	s, issuer, _ := newTestOIDC(t)
+	authURL, state, _ := s.Begin()
+	code := issuer.authorize(authURL, "sub-1", verifiedClaims)
+
+	for _, got := range []string{"", "forged"} {
+		if _, err := s.Complete(context.Background(), code, got, state, domain.Client{}); err == nil {
+			t.Errorf("Complete with state %q succeeded", got)
+		}
+	}
+	if issuer.tokenRequests != 0 {
+		t.Errorf("code was exchanged %d times despite the bad state", issuer.tokenRequests)
+	}
+}
+
+func TestOIDCRejectsWrongPKCEVerifier(t *testing.T) {
+	s, issuer, users := newTestOIDC(t)
+	authURL, state, _ := s.Begin()
+	code := issuer.authorize(authURL, "sub-1", verifiedClaims)
+
+	state.Verifier = strings.Repeat("x", 43)
+	if _, err := s.Complete(context.Background(), code, state.State, state, domain.Client{}); err == nil {
+		t.Fatal("Complete succeeded with the wrong code verifier")
+	}
+	if len(users.users) != 0 {
+		t.Error("a user was created")
+	}
+}
+
+func TestOIDCRejectsMismatchedNonce(t *testing.T) {
+	s, issuer, _ := newTestOIDC(t)
+	authURL, state, _ := s.Begin()
+	code := issuer.authorize(authURL, "sub-1", verifiedClaims)
+
+	// A token replayed from another login carries that login's nonce.
+	state.Nonce = "other-login"
+	_, err := s.Complete(context.Background(), code, state.State, state, domain.Client{})
+	if err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("got %v, want a nonce mismatch", err)
+	}
+}
+
+func TestOIDCRequiresVerifiedEmail(t *testing.T) {
+	s, issuer, users := newTestOIDC(t)
+	for _, claims := range []map[string]any{
+		{"email": "ada@example.com"},
+		{"email": "ada@example.com", "email_verified": false},
+		{"email_verified": true},
+	} {
+		if _, err := oidcLogin(s, issuer, "sub-1", claims); err == nil {
+			t.Errorf("login with claims %v succeeded", claims)
+		}
+	}
+	if len(users.users) != 0 {
+		t.Error("a user was created")
+	}
+}
+
+func TestOIDCLinksPasswordAccountOnlyWithPassword(t *testing.T) {
+	s, issuer, users := newTestOIDC(t)
+	hash, _ := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	owner := &domain.User{Email: "ada@example.com", Name: "Ada", PasswordHash: string(hash)}
+	users.Create(context.Background(), owner)
+
+	_, err := oidcLogin(s, issuer, "sub-1", verifiedClaims)
+	var linkErr *OIDCLinkRequiredError
+	if !errors.As(err, &linkErr) {
+		t.Fatalf("got %v, want OIDCLinkRequiredError", err)
+	}
+	if linkErr.Email != "ada@example.com" {
+		t.Errorf("link email = %q", linkErr.Email)
+	}
+
+	ctx := context.Background()
+	if _, err := s.LinkWithPassword(ctx, "made-up", "ada@example.com", "password1", domain.Client{}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unknown link token: got %v", err)
+	}
+	if _, err := s.LinkWithPassword(ctx, linkErr.Token, "ada@example.com", "wrong", domain.Client{}); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Errorf("wrong password: got %v", err)
+	}
+	if u, _ := users.FindByID(ctx, owner.ID); u.OIDCIdentity != "" {
+		t.Fatal("account was linked without the password")
+	}
+
+	resp, err := s.LinkWithPassword(ctx, linkErr.Token, "Ada@example.com", "password1", domain.Client{})
+	if err != nil {
+		t.Fatalf("link: %v", err)
+	}
+	if resp.User.ID != owner.ID {
+		t.Errorf("signed in as %d, want %d", resp.User.ID, owner.ID)
+	}
+	if _, err := s.LinkWithPassword(ctx, linkErr.Token, "ada@example.com", "password1", domain.Client{}); err == nil {
+		t.Error("link token was accepted twice")
+	}
+
+	again, err := oidcLogin(s, issuer, "sub-1", verifiedClaims)
+	if err != nil {
+		t.Fatalf("login after linking: %v", err)
+	}
+	if again.User.ID != owner.ID {
+		t.Errorf("signed in as %d, want %d", again.User.ID, owner.ID)
+	}
+
+	// Another provider account with the same email doesn't get in either.
+	if _, err := oidcLogin(s, issuer, "sub-2", verifiedClaims); err == nil || errors.As(err, &linkErr) {
+		t.Errorf("second identity for a linked account: got %v", err)
+	}
+}
+
+func TestOIDCLinksPasswordlessAccount(t *testing.T) {
+	s, issuer, users := newTestOIDC(t)
+	users.Create(context.Background(), &domain.User{Email: "ada@example.com", Name: "Ada"})
+
+	resp, err := oidcLogin(s, issuer, "sub-1", verifiedClaims)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if resp.User.ID != 1 || users.users[0].OIDCIdentity != issuer.URL+" sub-1" {
+		t.Errorf("user = %+v, want user 1 linked", users.users[0])
+	}
+}
@@ -0,0 +1,268 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+)
+
+// defaultWorkspaceName names the workspace created with every organization.
+const defaultWorkspaceName = "General"
+
+// OrganizationService manages organizations, their members and their
+// workspaces. Access to the spreadsheets inside them is decided by
+// SpreadsheetService.RoleOf.
+type OrganizationService struct {
+	orgs       domain.OrganizationRepository
+	workspaces domain.WorkspaceRepository
+	sheets     domain.SpreadsheetRepository
+	users      domain.UserRepository
+}
+
+func NewOrganizationService(
+	orgs domain.OrganizationRepository,
+	workspaces domain.WorkspaceRepository,
+	sheets domain.SpreadsheetRepository,
+	users domain.UserRepository,
+) *OrganizationService {
+	return &OrganizationService{orgs: orgs, workspaces: workspaces, sheets: sheets, users: users}
+}
+
+// ListOrganizations returns the organizations the user belongs to.
+func (s *OrganizationService) ListOrganizations(ctx context.Context, userID uint) ([]domain.Organization, error) {
+	orgs, err := s.orgs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	return orgs, nil
+}
+
+// CreateOrganization creates an organization owned by the user, with a
+// first workspace that members can edit.
+func (s *OrganizationService) CreateOrganization(ctx context.Context, userID uint, name string) (*domain.Organization, error) {
+	org := &domain.Organization{Name: name}
+	if err := s.orgs.Create(ctx, org, userID); err != nil {
+		return nil, fmt.Errorf("create organization: %w", err)
+	}
+	ws := &domain.Workspace{Name: defaultWorkspaceName, OrganizationID: &org.ID, MemberRole: domain.RoleEditor}
+	if err := s.workspaces.Create(ctx, ws); err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	org.Role = domain.OrgOwner
+	return org, nil
+}
+
+func (s *OrganizationService) GetOrganization(ctx context.Context, orgID, userID uint) (*domain.Organization, error) {
+	return s.authorizeOrg(ctx, orgID, userID, domain.OrgMember)
+}
+
+func (s *OrganizationService) RenameOrganization(ctx context.Context, orgID, userID uint, name string) (*domain.Organization, error) {
+	org, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgAdmin)
+	if err != nil {
+		return nil, err
+	}
+	org.Name = name
+	if err := s.orgs.Update(ctx, org); err != nil {
+		return nil, fmt.Errorf("update organization: %w", err)
+	}
+	return org, nil
+}
+
+// DeleteOrganization removes an organization with its members and
+// workspaces. Its spreadsheets must be moved or deleted first, trashed
+// ones included.
+func (s *OrganizationService) DeleteOrganization(ctx context.Context, orgID, userID uint) error {
+	if _, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgOwner); err != nil {
+		return err
+	}
+
+	workspaces, err := s.workspaces.ListByOrganization(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("list workspaces: %w", err)
+	}
+	for _, ws := range workspaces {
+		n, err := s.sheets.CountByWorkspace(ctx, ws.ID)
+		if err != nil {
+			return fmt.Errorf("count spreadsheets: %w", err)
+		}
+		if n > 0 {
+			return fmt.Errorf("%w: workspace %q still has spreadsheets", domain.ErrConflict, ws.Name)
+		}
+	}
+
+	if err := s.orgs.Delete(ctx, orgID); err != nil {
+		return fmt.Errorf("delete organization: %w", err)
+	}
+	return nil
+}
+
+// ── Members ──────────────────────────────────
+
+// ListMembers returns an organization's members. Any member may see them.
+func (s *OrganizationService) ListMembers(ctx context.Context, orgID, userID uint) ([]domain.OrganizationMember, error) {
+	if _, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgMember); err != nil {
+		return nil, err
+	}
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	return members, nil
+}
+
+// AddMember adds the user with the given email to an organization. Admins
+// add members and admins; only owners can add owners.
+func (s *OrganizationService) AddMember(ctx context.Context, orgID, userID uint, email string, role domain.OrgRole) (*domain.OrganizationMember, error) {
+	org, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrant(org.Role, role); err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("user %w", domain.ErrNotFound)
+	}
+	if _, err := s.orgs.FindMember(ctx, orgID, user.ID); err == nil {
+		return nil, fmt.Errorf("%w: %s is already a member", domain.ErrConflict, email)
+	}
+
+	member := &domain.OrganizationMember{OrganizationID: orgID, UserID: user.ID, Role: role}
+	if err := s.orgs.UpsertMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("add member: %w", err)
+	}
+	return member, nil
+}
+
+// UpdateMember changes a member's role. Only owners can promote to or
+// demote from owner, and the last owner can't be demoted.
+func (s *OrganizationService) UpdateMember(ctx context.Context, orgID, userID, targetUserID uint, role domain.OrgRole) (*domain.OrganizationMember, error) {
+	org, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgAdmin)
+	if err != nil {
+		return nil, err
+	}
+	member, err := s.orgs.FindMember(ctx, orgID, targetUserID)
+	if err != nil {
+		return nil, fmt.Errorf("member %w", domain.ErrNotFound)
+	}
+	if err := checkGrant(org.Role, role); err != nil {
+		return nil, err
+	}
+	if err := checkGrant(org.Role, member.Role); err != nil {
+		return nil, err
+	}
+	if member.Role == domain.OrgOwner && role != domain.OrgOwner {
+		if err := s.keepOwner(ctx, orgID); err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+	if err := s.orgs.UpsertMember(ctx, member); err != nil {
+		return nil, fmt.Errorf("update member: %w", err)
+	}
+	return member, nil
+}
+
+// RemoveMember removes a member from an organization. Admins can remove
+// members and admins, owners anyone; every member can leave. Spreadsheets
+// the member owns in the organization's workspaces pass to whoever removed
+// them or, when they leave, to an owner, so they stay with the team.
+func (s *OrganizationService) RemoveMember(ctx context.Context, orgID, userID, targetUserID uint) error {
+	min := domain.OrgAdmin
+	if targetUserID == userID {
+		min = domain.OrgMember
+	}
+	org, err := s.authorizeOrg(ctx, orgID, userID, min)
+	if err != nil {
+		return err
+	}
+	member, err := s.orgs.FindMember(ctx, orgID, targetUserID)
+	if err != nil {
+		return fmt.Errorf("member %w", domain.ErrNotFound)
+	}
+	if targetUserID != userID {
+		if err := checkGrant(org.Role, member.Role); err != nil {
+			return err
+		}
+	}
+	if member.Role == domain.OrgOwner {
+		if err := s.keepOwner(ctx, orgID); err != nil {
+			return err
+		}
+	}
+
+	heir := userID
+	if targetUserID == userID {
+		if heir, err = s.otherOwner(ctx, orgID, userID); err != nil {
+			return err
+		}
+	}
+	if err := s.orgs.RemoveMember(ctx, orgID, targetUserID, heir); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("remove member: %w", err)
+	}
+	return nil
+}
+
+// checkGrant reports whether a member with role may hand out (or take
+// away) target. Owner is reserved for owners.
+func checkGrant(role, target domain.OrgRole) error {
+	if !target.Valid() {
+		return fmt.Errorf("%w: role must be member, admin or owner", domain.ErrInvalidInput)
+	}
+	if target == domain.OrgOwner && role != domain.OrgOwner {
+		return fmt.Errorf("%w: only owners can manage owners", domain.ErrForbidden)
+	}
+	return nil
+}
+
+// keepOwner fails if removing one owner would leave the organization
+// without any.
+func (s *OrganizationService) keepOwner(ctx context.Context, orgID uint) error {
+	n, err := s.orgs.CountOwners(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	if n <= 1 {
+		return fmt.Errorf("%w: an organization needs at least one owner", domain.ErrConflict)
+	}
+	return nil
+}
+
+// otherOwner returns an owner of the organization other than userID.
+func (s *OrganizationService) otherOwner(ctx context.Context, orgID, userID uint) (uint, error) {
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return 0, fmt.Errorf("list members: %w", err)
+	}
+	for _, m := range members {
+		if m.Role == domain.OrgOwner && m.UserID != userID {
+			return m.UserID, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: an organization needs at least one owner", domain.ErrConflict)
+}
+
+// authorizeOrg loads an organization and checks that the user holds at
+// least min in it. Non-members get ErrNotFound.
+func (s *OrganizationService) authorizeOrg(ctx context.Context, orgID, userID uint, min domain.OrgRole) (*domain.Organization, error) {
+	member, err := s.orgs.FindMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("organization %w", domain.ErrNotFound)
+	}
+	if !member.Role.Allows(min) {
+		return nil, fmt.Errorf("%w: %s role required", domain.ErrForbidden, min)
+	}
+	org, err := s.orgs.FindByID(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("organization %w", domain.ErrNotFound)
+	}
+	org.Role = member.Role
+	return org, nil
+}
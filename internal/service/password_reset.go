@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"log"
+	"time"
+)
+
+const (
+	// passwordResetTTL is how long an emailed password link works.
+	passwordResetTTL = time.Hour
+	// Reset emails are limited per address and per client, so the form
+	// can't be used to flood someone's inbox.
+	passwordResetWindow   = time.Hour
+	passwordResetsPerUser = 3
+	passwordResetsPerIP   = 10
+)
+
+// passwordResetEmail is the data of the password_reset template.
+type passwordResetEmail struct {
+	Email    string
+	URL      string
+	ValidFor string
+}
+
+// RequestPasswordReset emails a link to set a new password to the account
+// with the given email, if there is one. The answer is the same either
+// way, so it doesn't reveal who has an account. Accounts created before
+// passwords existed, or through single sign-on, use it to set their first
+// password.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email, ip string) error {
+	email = domain.NormalizeEmail(email)
+	for _, limit := range []struct {
+		limiter *attemptLimiter
+		key     string
+	}{{s.resetsByEmail, email}, {s.resetsByIP, ip}} {
+		if wait := limit.limiter.blockedFor(limit.key); wait > 0 {
+			return &domain.TooManyAttemptsError{What: "password reset requests", RetryAfter: wait}
+		}
+	}
+	s.resetsByEmail.fail(email)
+	s.resetsByIP.fail(ip)
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("look up email: %w", err)
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return fmt.Errorf("generate token: %w", err)
+	}
+	reset := &domain.PasswordReset{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(passwordResetTTL),
+	}
+	if err := s.resets.Create(ctx, reset); err != nil {
+		return fmt.Errorf("create password reset: %w", err)
+	}
+	// The token goes in the fragment, which browsers don't send to servers
+	// or in Referer headers.
+	return s.emails.Enqueue(ctx, "password_reset", user.Email, passwordResetEmail{
+		Email:    user.Email,
+		URL:      s.emails.Link("/login#reset=" + token),
+		ValidFor: "an hour",
+	})
+}
+
+// ResetPassword sets the password of the account an emailed link belongs
+// to, signs out all its sessions and signs in on a new one.
+func (s *AuthService) ResetPassword(ctx context.Context, token, password string, client domain.Client) (*domain.AuthResponse, error) {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	reset, err := s.resets.Consume(ctx, hashToken(token), time.Now())
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("%w: this link is invalid, used or expired; ask for a new one", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("find password reset: %w", err)
+	}
+
+	user, err := s.users.FindByID(ctx, reset.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("find user: %w", err)
+	}
+	if err := s.users.UpdatePasswordHash(ctx, user.ID, hash); err != nil {
+		return nil, fmt.Errorf("update password: %w", err)
+	}
+	if _, err := s.sessions.DeleteOthers(ctx, user.ID, 0); err != nil {
+		return nil, fmt.Errorf("revoke sessions: %w", err)
+	}
+	return s.issueSession(ctx, user, client)
+}
+
+// PurgePasswordResets deletes expired password links.
+func (s *AuthService) PurgePasswordResets(ctx context.Context) error {
+	n, err := s.resets.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("delete expired password resets: %w", err)
+	}
+	if n > 0 {
+		log.Printf("Purged %d expired password links", n)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// resetUsers has one account, created before passwords existed.
+type resetUsers struct {
+	domain.UserRepository
+	user domain.User
+}
+
+func (r *resetUsers) FindByEmail(_ context.Context, email string) (*domain.User, error) {
+	if email != r.user.Email {
+		return nil, domain.ErrNotFound
+	}
+	u := r.user
+	return &u, nil
+}
+
+func (r *resetUsers) FindByID(_ context.Context, id uint) (*domain.User, error) {
+	if id != r.user.ID {
+		return nil, domain.ErrNotFound
+	}
+	u := r.user
+	return &u, nil
+}
+
+func (r *resetUsers) UpdatePasswordHash(_ context.Context, id uint, hash string) error {
+	r.user.PasswordHash = hash
+	return nil
+}
+
+// memResets keeps password links in memory; Consume is single use.
+type memResets struct {
+	domain.PasswordResetRepository
+	resets []domain.PasswordReset
+}
+
+func (r *memResets) Create(_ context.Context, reset *domain.PasswordReset) error {
+	r.resets = append(r.resets, *reset)
+	return nil
+}
+
+func (r *memResets) Consume(_ context.Context, hash string, now time.Time) (*domain.PasswordReset, error) {
+	for i, reset := range r.resets {
+		if reset.TokenHash == hash && reset.ExpiresAt.After(now) {
+			r.resets = append(r.resets[:i], r.resets[i+1:]...)
+			return &reset, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+// countedSessions counts the sessions of one user.
+type countedSessions struct {
+	domain.SessionRepository
+	count int
+}
+
+func (r *countedSessions) Create(context.Context, *domain.Session) error {
+	r.count++
+	return nil
+}
+
+func (r *countedSessions) DeleteOthers(_ context.Context, userID, keepID uint) (int64, error) {
+	n := r.count
+	r.count = 0
+	return int64(n), nil
+}
+
+func newResetTest() (*AuthService, *resetUsers, *memResets, *countedSessions, *memOutbox) {
+	users := &resetUsers{user: domain.User{ID: 7, Email: "old@example.com"}}
+	resets := &memResets{}
+	sessions := &countedSessions{count: 2}
+	outbox := &memOutbox{}
+	emails := NewEmailService(outbox, nil, "https://grids.example.com/")
+	s := NewAuthService(users, sessions, nil, resets, nil, emails, SessionPolicy{}, false)
+	return s, users, resets, sessions, outbox
+}
+
+// resetLink returns the token of the link in the only queued email.
+func resetLink(t *testing.T, outbox *memOutbox) string {
+	t.Helper()
+	if len(outbox.emails) != 1 {
+		t.Fatalf("queued %d emails, want 1", len(outbox.emails))
+	}
+	const prefix = "https://grids.example.com/login#reset="
+	_, rest, ok := strings.Cut(outbox.emails[0].Text, prefix)
+	if ok {
+		return strings.Fields(rest)[0]
+	}
+	t.Fatalf("no link in email:\n%s", outbox.emails[0].Text)
+	return ""
+}
+
+func TestRequestPasswordResetForUnknownEmailSendsNothing(t *testing.T) {
+	s, _, resets, _, outbox := newResetTest()
+	if err := s.RequestPasswordReset(context.Background(), "nobody@example.com", "10.0.0.1"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if len(outbox.emails) != 0 || len(resets.resets) != 0 {
+		t.Fatalf("queued %d emails and %d links for an unknown email", len(outbox.emails), len(resets.resets))
+	}
+}
+
+func TestResetPasswordSetsFirstPasswordOnce(t *testing.T) {
+	s, users, _, sessions, outbox := newResetTest()
+	ctx := context.Background()
+	if err := s.RequestPasswordReset(ctx, " Old@Example.com ", "10.0.0.1"); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	if to := outbox.emails[0].To; to != "old@example.com" {
+		t.Fatalf("email sent to %q", to)
+	}
+	token := resetLink(t, outbox)
+
+	resp, err := s.ResetPassword(ctx, token, "a new password", domain.Client{})
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if resp.User.ID != 7 || resp.Token == "" {
+		t.Fatalf("signed in as %+v", resp)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(users.user.PasswordHash), []byte("a new password")) != nil {
+		t.Fatal("password was not set")
+	}
+	if sessions.count != 1 {
+		t.Fatalf("%d sessions left, want only the new one", sessions.count)
+	}
+
+	_, err = s.ResetPassword(ctx, token, "another password", domain.Client{})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("second use: err = %v, want ErrInvalidInput", err)
+	}
+}
+
+func TestResetPasswordRefusesBadLinks(t *testing.T) {
+	s, users, resets, _, _ := newResetTest()
+	ctx := context.Background()
+	resets.resets = append(resets.resets, domain.PasswordReset{
+		UserID: 7, TokenHash: hashToken("expired"), ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	for _, token := range []string{"expired", "unknown", ""} {
+		if _, err := s.ResetPassword(ctx, token, "a new password", domain.Client{}); !errors.Is(err, domain.ErrInvalidInput) {
+			t.Errorf("token %q: err = %v, want ErrInvalidInput", token, err)
+		}
+	}
+	if users.user.PasswordHash != "" {
+		t.Fatal("password was set through a bad link")
+	}
+}
+
+func TestRequestPasswordResetIsRateLimited(t *testing.T) {
+	s, _, _, _, outbox := newResetTest()
+	ctx := context.Background()
+	for i := 0; i < passwordResetsPerUser; i++ {
+		if err := s.RequestPasswordReset(ctx, "old@example.com", "10.0.0.1"); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+	err := s.RequestPasswordReset(ctx, "old@example.com", "10.0.0.2")
+	if !errors.Is(err, domain.ErrTooManyAttempts) {
+		t.Fatalf("err = %v, want ErrTooManyAttempts", err)
+	}
+	if len(outbox.emails) != passwordResetsPerUser {
+		t.Fatalf("queued %d emails, want %d", len(outbox.emails), passwordResetsPerUser)
+	}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+)
+
+// ListPermissions returns who a spreadsheet is shared with. Anyone with
+// access may see the list.
+func (s *SpreadsheetService) ListPermissions(ctx context.Context, id, userID uint) ([]domain.SpreadsheetPermission, error) {
+	if _, err := s.authorize(ctx, id, userID, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	perms, err := s.permissions.ListBySpreadsheet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list permissions: %w", err)
+	}
+	return perms, nil
+}
+
+// Share grants (or changes) a role on a spreadsheet for the user with the
+// given email. Only the owner can share.
+func (s *SpreadsheetService) Share(ctx context.Context, id, userID uint, email string, role domain.Role) (*domain.SpreadsheetPermission, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Shareable() {
+		return nil, fmt.Errorf("%w: role must be viewer, commenter or editor", domain.ErrInvalidInput)
+	}
+
+	grantee, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("user %w", domain.ErrNotFound)
+	}
+	if grantee.ID == sheet.OwnerID {
+		return nil, fmt.Errorf("%w: the owner already has full access", domain.ErrInvalidInput)
+	}
+
+	perm := &domain.SpreadsheetPermission{SpreadsheetID: sheet.ID, UserID: grantee.ID, Role: role}
+	if err := s.permissions.Upsert(ctx, perm); err != nil {
+		return nil, fmt.Errorf("share spreadsheet: %w", err)
+	}
+	s.notifications.NotifyShared(ctx, userID, grantee.ID, sheet, role)
+	s.webhooks.EmitShared(ctx, sheet, userID, grantee, role)
+	return perm, nil
+}
+
+// RequestAccess asks the owner of a spreadsheet to share it with the user.
+// Whether the spreadsheet exists isn't revealed: requests for unknown or
+// trashed spreadsheets succeed without notifying anyone.
+func (s *SpreadsheetService) RequestAccess(ctx context.Context, id, userID uint, req domain.AccessRequest) error {
+	if req.Role == "" {
+		req.Role = domain.RoleViewer
+	}
+	if !req.Role.Shareable() {
+		return fmt.Errorf("%w: role must be viewer, commenter or editor", domain.ErrInvalidInput)
+	}
+
+	sheet, err := s.sheets.FindByID(ctx, id)
+	if err != nil {
+		return nil
+	}
+	if role, err := s.RoleOf(ctx, sheet, userID); err == nil && role.Allows(req.Role) {
+		return fmt.Errorf("%w: you already have %s access", domain.ErrInvalidInput, role)
+	}
+	s.notifications.NotifyAccessRequest(ctx, userID, sheet, req.Role, strings.TrimSpace(req.Message))
+	return nil
+}
+
+// Unshare revokes a user's access. The owner can remove anyone; other
+// users can only remove themselves.
+func (s *SpreadsheetService) Unshare(ctx context.Context, id, userID, targetUserID uint) error {
+	min := domain.RoleOwner
+	if targetUserID == userID {
+		min = domain.RoleViewer
+	}
+	if _, err := s.authorize(ctx, id, userID, min); err != nil {
+		return err
+	}
+
+	if err := s.permissions.Delete(ctx, id, targetUserID); err != nil {
+		return fmt.Errorf("permission %w", domain.ErrNotFound)
+	}
+	return nil
+}
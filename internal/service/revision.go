@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+)
+
+// RevisionRetention controls how PruneRevisions thins old revisions. Every
+// revision younger than KeepAll is kept; up to KeepHourly the newest
+// revision of each hour is kept, and beyond that the newest of each day.
+// The latest revision of a spreadsheet is never pruned.
+type RevisionRetention struct {
+	KeepAll    time.Duration
+	KeepHourly time.Duration
+}
+
+var DefaultRevisionRetention = RevisionRetention{
+	KeepAll:    24 * time.Hour,
+	KeepHourly: 30 * 24 * time.Hour,
+}
+
+// DefaultRevisionWindow is how long consecutive live-editing flushes by one
+// editor are folded into a single revision. Rooms save every few seconds,
+// which would otherwise add a revision each time.
+const DefaultRevisionWindow = 10 * time.Minute
+
+// pruneBatchSize caps the number of IDs in a single DELETE statement.
+const pruneBatchSize = 500
+
+func (s *SpreadsheetService) ListRevisions(ctx context.Context, id, userID uint) ([]domain.Revision, error) {
+	if _, err := s.authorize(ctx, id, userID, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	revs, err := s.revisions.ListBySpreadsheet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list revisions: %w", err)
+	}
+	return revs, nil
+}
+
+func (s *SpreadsheetService) GetRevision(ctx context.Context, id, userID, revisionID uint) (*domain.Revision, error) {
+	if _, err := s.authorize(ctx, id, userID, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisions.FindByID(ctx, id, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("revision %w", domain.ErrNotFound)
+	}
+	return rev, nil
+}
+
+// RestoreRevision makes a historic revision the current data. The restore
+// is itself recorded as a new revision, never merged into the previous
+// one, so it can be undone the same way.
+func (s *SpreadsheetService) RestoreRevision(ctx context.Context, id, userID, revisionID uint) (*domain.Spreadsheet, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisions.FindByID(ctx, id, revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("revision %w", domain.ErrNotFound)
+	}
+
+	if err := s.sheets.SaveData(ctx, sheet, map[string]any{"data": rev.Data}, userID, 0); err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, s.conflict(ctx, id)
+		}
+		return nil, fmt.Errorf("restore revision: %w", err)
+	}
+	s.index(ctx, sheet)
+	s.webhooks.EmitSaved(ctx, sheet, userID)
+	return sheet, nil
+}
+
+// PruneRevisions applies the retention policy to every spreadsheet.
+func (s *SpreadsheetService) PruneRevisions(ctx context.Context, policy RevisionRetention) error {
+	ids, err := s.revisions.ListSpreadsheetIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("list spreadsheets with revisions: %w", err)
+	}
+
+	now := time.Now()
+	for _, id := range ids {
+		revs, err := s.revisions.ListBySpreadsheet(ctx, id)
+		if err != nil {
+			return fmt.Errorf("list revisions of spreadsheet %d: %w", id, err)
+		}
+
+		prune := prunableRevisions(revs, now, policy)
+		for start := 0; start < len(prune); start += pruneBatchSize {
+			end := min(start+pruneBatchSize, len(prune))
+			if err := s.revisions.DeleteByIDs(ctx, prune[start:end]); err != nil {
+				return fmt.Errorf("prune revisions of spreadsheet %d: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SpreadsheetService) recordRevision(ctx context.Context, sheet *domain.Spreadsheet, authorID uint) error {
+	rev := &domain.Revision{SpreadsheetID: sheet.ID, AuthorID: authorID, Data: sheet.Data}
+	if err := s.revisions.Create(ctx, rev); err != nil {
+		return fmt.Errorf("record revision: %w", err)
+	}
+	return nil
+}
+
+// prunableRevisions returns the IDs that the policy drops. revs must be
+// ordered newest first; within each hourly or daily bucket the newest
+// revision survives.
+func prunableRevisions(revs []domain.Revision, now time.Time, policy RevisionRetention) []uint {
+	var prune []uint
+	seen := map[string]bool{}
+
+	for i, rev := range revs {
+		age := now.Sub(rev.CreatedAt)
+		if i == 0 || age <= policy.KeepAll {
+			continue
+		}
+
+		created := rev.CreatedAt.UTC()
+		var bucket string
+		if age <= policy.KeepHourly {
+			bucket = "h" + created.Truncate(time.Hour).Format(time.RFC3339)
+		} else {
+			bucket = "d" + created.Format(time.DateOnly)
+		}
+
+		if seen[bucket] {
+			prune = append(prune, rev.ID)
+		} else {
+			seen[bucket] = true
+		}
+	}
+	return prune
+}
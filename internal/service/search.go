@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+	"log"
+	"strings"
+	"unicode"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+	maxSearchTerms     = 8
+	// matchesPerResult is how many matching cells each result shows.
+	matchesPerResult = 5
+	// maxSearchEntries caps how many cells of one spreadsheet are indexed.
+	maxSearchEntries = 50_000
+	// snippetContext is how many characters a snippet shows on either
+	// side of the match.
+	snippetContext = 30
+)
+
+// Search finds the spreadsheets the user can open whose title and cells
+// contain every word of query between them, most recently updated first.
+// Each result lists the title and cells where words matched, with
+// highlighted snippets.
+func (s *SpreadsheetService) Search(ctx context.Context, userID uint, query string, limit int) (*domain.SearchResponse, error) {
+	terms := strings.Fields(query)
+	switch {
+	case len(terms) == 0:
+		return nil, fmt.Errorf("%w: q is required", domain.ErrInvalidInput)
+	case len(terms) > maxSearchTerms:
+		return nil, fmt.Errorf("%w: search for at most %d words", domain.ErrInvalidInput, maxSearchTerms)
+	}
+	switch {
+	case limit < 0 || limit > maxSearchLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidInput, maxSearchLimit)
+	case limit == 0:
+		limit = defaultSearchLimit
+	}
+
+	sheets, err := s.search.Search(ctx, userID, terms, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	roles, err := s.listRoles(ctx, userID, sheets)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &domain.SearchResponse{Results: make([]domain.SearchResult, 0, len(sheets))}
+	for _, sh := range sheets {
+		entries, err := s.search.Matches(ctx, sh.ID, terms, matchesPerResult)
+		if err != nil {
+			return nil, fmt.Errorf("search spreadsheet %d: %w", sh.ID, err)
+		}
+		result := domain.SearchResult{
+			Spreadsheet: toListItem(sh, roles[sh.ID]),
+			Matches:     make([]domain.SearchMatch, len(entries)),
+		}
+		for i, e := range entries {
+			result.Matches[i] = domain.SearchMatch{Sheet: e.Sheet, Cell: e.Cell, Snippet: snippet(e.Text, terms)}
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	return resp, nil
+}
+
+// IndexSpreadsheets adds spreadsheets that aren't in the search index yet,
+// such as those created before it existed.
+func (s *SpreadsheetService) IndexSpreadsheets(ctx context.Context) error {
+	const batchSize = 100
+	for {
+		sheets, err := s.search.ListUnindexed(ctx, batchSize)
+		if err != nil {
+			return fmt.Errorf("list unindexed spreadsheets: %w", err)
+		}
+		for i := range sheets {
+			if err := s.search.Replace(ctx, sheets[i].ID, searchEntries(&sheets[i])); err != nil {
+				return fmt.Errorf("index spreadsheet %d: %w", sheets[i].ID, err)
+			}
+		}
+		if len(sheets) < batchSize {
+			return nil
+		}
+	}
+}
+
+// index refreshes the search entries of a spreadsheet after a save. The
+// save has already succeeded, so a failure is only logged; the old entries
+// stay until the next save.
+func (s *SpreadsheetService) index(ctx context.Context, sheet *domain.Spreadsheet) {
+	if err := s.search.Replace(ctx, sheet.ID, searchEntries(sheet)); err != nil {
+		log.Printf("Failed to index spreadsheet %d for search: %v", sheet.ID, err)
+	}
+}
+
+// unindex removes a permanently deleted spreadsheet from the search index.
+func (s *SpreadsheetService) unindex(ctx context.Context, id uint) {
+	if err := s.search.Replace(ctx, id, nil); err != nil {
+		log.Printf("Failed to remove spreadsheet %d from search: %v", id, err)
+	}
+}
+
+// searchEntries extracts the searchable text of a spreadsheet: its title,
+// then the values of its cells sheet by sheet. Data saved by the editor
+// can't be read on the server and only the title is indexed.
+func searchEntries(sheet *domain.Spreadsheet) []domain.SearchEntry {
+	entries := []domain.SearchEntry{{SpreadsheetID: sheet.ID, Text: sheet.Title}}
+	wb, err := workbook.Decode(sheet.Data)
+	if err != nil {
+		return entries
+	}
+	for _, sh := range wb.Sheets {
+		for _, ref := range sh.Refs() {
+			v := sh.Value(ref)
+			if v.Kind == workbook.KindError {
+				continue
+			}
+			text := strings.TrimSpace(v.Text())
+			if text == "" {
+				continue
+			}
+			if len(entries) > maxSearchEntries {
+				return entries
+			}
+			entries = append(entries, domain.SearchEntry{SpreadsheetID: sheet.ID, Sheet: sh.Name, Cell: ref.String(), Text: text})
+		}
+	}
+	return entries
+}
+
+// snippet cuts text down to its first match of any term with some context
+// on either side, HTML-escapes it and wraps every match in <mark>. Matching
+// ignores case.
+func snippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first, last := -1, -1
+	for _, term := range terms {
+		t := []rune(strings.ToLower(term))
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) != string(t) {
+				continue
+			}
+			for j := i; j < i+len(t); j++ {
+				marked[j] = true
+			}
+			if first == -1 || i < first {
+				first, last = i, i+len(t)
+			}
+		}
+	}
+	if first == -1 {
+		first, last = 0, 0
+	}
+
+	start := max(0, first-snippetContext)
+	end := min(len(runes), last+snippetContext)
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i == end-1 || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
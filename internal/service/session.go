@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+)
+
+// SessionPolicy controls how long sessions last. Every request renews a
+// session for TTL (sliding expiry), but never past MaxLifetime after
+// sign-in. Setting both to the same value gives fixed-length sessions.
+type SessionPolicy struct {
+	TTL         time.Duration
+	MaxLifetime time.Duration
+}
+
+var DefaultSessionPolicy = SessionPolicy{
+	TTL:         7 * 24 * time.Hour,
+	MaxLifetime: 30 * 24 * time.Hour,
+}
+
+// lastSeenInterval throttles last-seen bookkeeping for sessions and API
+// tokens to one write per interval, unless the client address changes.
+const lastSeenInterval = time.Minute
+
+// expiry returns when a session created at created and used at now expires.
+func (p SessionPolicy) expiry(created, now time.Time) time.Time {
+	sliding := now.Add(p.TTL)
+	if absolute := created.Add(p.MaxLifetime); absolute.Before(sliding) {
+		return absolute
+	}
+	return sliding
+}
+
+// ListSessions returns the user's active sessions, marking currentID.
+func (s *AuthService) ListSessions(ctx context.Context, userID, currentID uint) ([]domain.Session, error) {
+	sessions, err := s.sessions.ListValidByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out one of the user's sessions, which may be the
+// current one.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, id uint) error {
+	if err := s.sessions.Delete(ctx, id, userID); err != nil {
+		return fmt.Errorf("session %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeOtherSessions signs out everywhere except the current session and
+// returns how many sessions were revoked.
+func (s *AuthService) RevokeOtherSessions(ctx context.Context, userID, currentID uint) (int64, error) {
+	n, err := s.sessions.DeleteOthers(ctx, userID, currentID)
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %w", err)
+	}
+	return n, nil
+}
+
+// PurgeSessions deletes expired sessions.
+func (s *AuthService) PurgeSessions(ctx context.Context) error {
+	if _, err := s.sessions.DeleteExpired(ctx, time.Now()); err != nil {
+		return fmt.Errorf("delete expired sessions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"log"
+	"strconv"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ShareLinkService manages public share links. Owners create, list and
+// revoke them; anyone holding a link can open the spreadsheet read-only,
+// without an account.
+type ShareLinkService struct {
+	links  domain.ShareLinkRepository
+	sheets *SpreadsheetService
+
+	// Wrong passwords are limited per link, against guessing from many
+	// addresses, and per client IP, against one client trying many links.
+	failsByLink *attemptLimiter
+	failsByIP   *attemptLimiter
+}
+
+// Share link passwords may be wrong this many times per link and per
+// client IP within linkPasswordWindow.
+const (
+	linkPasswordWindow       = 15 * time.Minute
+	linkPasswordFailsPerLink = 20
+	linkPasswordFailsPerIP   = 10
+)
+
+func NewShareLinkService(links domain.ShareLinkRepository, sheets *SpreadsheetService) *ShareLinkService {
+	return &ShareLinkService{
+		links:       links,
+		sheets:      sheets,
+		failsByLink: newAttemptLimiter(linkPasswordFailsPerLink, linkPasswordWindow),
+		failsByIP:   newAttemptLimiter(linkPasswordFailsPerIP, linkPasswordWindow),
+	}
+}
+
+// CreateLink makes a share link for a spreadsheet the user owns. Like API
+// tokens, the returned token is the only copy and the database keeps a
+// hash.
+func (s *ShareLinkService) CreateLink(ctx context.Context, id, userID uint, req domain.CreateShareLinkRequest) (*domain.CreateShareLinkResponse, error) {
+	sheet, err := s.sheets.authorize(ctx, id, userID, domain.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	if req.Mode == "" {
+		req.Mode = domain.RoleViewer
+	}
+	if req.Mode != domain.RoleViewer && req.Mode != domain.RoleCommenter {
+		return nil, fmt.Errorf("%w: mode must be viewer or commenter", domain.ErrInvalidInput)
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", domain.ErrInvalidInput)
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate token: %w", err)
+	}
+	link := &domain.ShareLink{
+		SpreadsheetID: sheet.ID,
+		CreatedByID:   userID,
+		Prefix:        secret[:8],
+		TokenHash:     hashToken(secret),
+		Mode:          req.Mode,
+		ExpiresAt:     req.ExpiresAt,
+	}
+	if req.Password != "" {
+		hash, err := hashPassword(req.Password)
+		if err != nil {
+			return nil, err
+		}
+		link.PasswordHash = hash
+	}
+	if err := s.links.Create(ctx, link); err != nil {
+		return nil, fmt.Errorf("create share link: %w", err)
+	}
+	return &domain.CreateShareLinkResponse{Token: secret, ShareLink: *link}, nil
+}
+
+// ListLinks returns a spreadsheet's share links, expired ones included,
+// newest first.
+func (s *ShareLinkService) ListLinks(ctx context.Context, id, userID uint) ([]domain.ShareLink, error) {
+	if _, err := s.sheets.authorize(ctx, id, userID, domain.RoleOwner); err != nil {
+		return nil, err
+	}
+	links, err := s.links.ListBySpreadsheet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list share links: %w", err)
+	}
+	return links, nil
+}
+
+// RevokeLink deletes a share link; it stops working immediately.
+func (s *ShareLinkService) RevokeLink(ctx context.Context, id, userID, linkID uint) error {
+	if _, err := s.sheets.authorize(ctx, id, userID, domain.RoleOwner); err != nil {
+		return err
+	}
+	if err := s.links.Delete(ctx, linkID, id); err != nil {
+		return fmt.Errorf("share link %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// Open returns the spreadsheet behind a share link.
+func (s *ShareLinkService) Open(ctx context.Context, access domain.LinkAccess) (*domain.SharedSpreadsheet, error) {
+	link, sheet, err := s.resolve(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if link.LastUsedAt == nil || now.Sub(*link.LastUsedAt) >= lastSeenInterval {
+		if err := s.links.RecordUse(ctx, link.ID, now); err != nil {
+			log.Printf("Failed to record use of share link %d: %v", link.ID, err)
+		}
+	}
+
+	return &domain.SharedSpreadsheet{
+		Title:     sheet.Title,
+		Data:      sheet.Data,
+		Version:   sheet.Version,
+		Mode:      link.Mode,
+		UpdatedAt: sheet.UpdatedAt,
+	}, nil
+}
+
+// resolve checks a share link and its password and loads its spreadsheet.
+// Unknown, revoked and expired links and links to trashed spreadsheets all
+// give ErrNotFound; a missing or wrong password gives ErrPasswordRequired,
+// and after too many wrong ones a TooManyAttemptsError.
+func (s *ShareLinkService) resolve(ctx context.Context, access domain.LinkAccess) (*domain.ShareLink, *domain.Spreadsheet, error) {
+	link, err := s.links.FindValidByHash(ctx, hashToken(access.Token))
+	if err != nil {
+		return nil, nil, fmt.Errorf("link %w", domain.ErrNotFound)
+	}
+	if link.PasswordHash != "" {
+		password, ip := access.Password, access.IP
+		if password == "" {
+			return nil, nil, domain.ErrPasswordRequired
+		}
+		linkKey := strconv.FormatUint(uint64(link.ID), 10)
+		if wait := max(s.failsByLink.blockedFor(linkKey), s.failsByIP.blockedFor(ip)); wait > 0 {
+			return nil, nil, &domain.TooManyAttemptsError{What: "incorrect passwords", RetryAfter: wait}
+		}
+		if err := bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)); err != nil {
+			s.failsByLink.fail(linkKey)
+			s.failsByIP.fail(ip)
+			return nil, nil, fmt.Errorf("%w: incorrect password", domain.ErrPasswordRequired)
+		}
+	}
+
+	sheet, err := s.sheets.sheets.FindByID(ctx, link.SpreadsheetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("link %w", domain.ErrNotFound)
+	}
+	return link, sheet, nil
+}
+
+// commentable returns the spreadsheet behind a commenter link. The link
+// stands in for the commenter role: signed-in users who hold it comment
+// under their own name without the spreadsheet being shared with them,
+// and lose that access when the link is revoked or expires.
+func (s *ShareLinkService) commentable(ctx context.Context, access domain.LinkAccess) (*domain.Spreadsheet, error) {
+	link, sheet, err := s.resolve(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	if link.Mode != domain.RoleCommenter {
+		return nil, fmt.Errorf("%w: this link is view-only", domain.ErrForbidden)
+	}
+	sheet.Role = domain.RoleCommenter
+	return sheet, nil
+}
+
+// ListComments returns the comment threads of the spreadsheet behind a
+// commenter link.
+func (s *ShareLinkService) ListComments(ctx context.Context, access domain.LinkAccess, req domain.ListCommentsRequest) ([]domain.CommentThread, error) {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.sheets.listComments(ctx, sheet, req)
+}
+
+// CreateComment starts a thread through a commenter link.
+func (s *ShareLinkService) CreateComment(ctx context.Context, access domain.LinkAccess, userID uint, req domain.CreateCommentRequest) (*domain.CommentThread, error) {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.sheets.createComment(ctx, sheet, userID, req)
+}
+
+// ReplyComment replies to a thread through a commenter link.
+func (s *ShareLinkService) ReplyComment(ctx context.Context, access domain.LinkAccess, userID, commentID uint, body string) (*domain.Comment, error) {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.sheets.replyComment(ctx, sheet, userID, commentID, body)
+}
+
+// EditComment changes the text of the user's own comment through a
+// commenter link.
+func (s *ShareLinkService) EditComment(ctx context.Context, access domain.LinkAccess, userID, commentID uint, body string) (*domain.Comment, error) {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.sheets.editComment(ctx, sheet, userID, commentID, body)
+}
+
+// DeleteComment deletes the user's own comment through a commenter link.
+func (s *ShareLinkService) DeleteComment(ctx context.Context, access domain.LinkAccess, userID, commentID uint) error {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return err
+	}
+	return s.sheets.deleteComment(ctx, sheet, userID, commentID)
+}
+
+// ResolveComment resolves or reopens a thread through a commenter link.
+func (s *ShareLinkService) ResolveComment(ctx context.Context, access domain.LinkAccess, userID, commentID uint, resolved bool) (*domain.CommentThread, error) {
+	sheet, err := s.commentable(ctx, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.sheets.resolveComment(ctx, sheet, userID, commentID, resolved)
+}
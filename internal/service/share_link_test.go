@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// protectedLinks holds password-protected links to spreadsheet 7, with
+// the link ID as their token.
+type protectedLinks struct {
+	domain.ShareLinkRepository
+	hash string
+}
+
+func (r protectedLinks) FindValidByHash(_ context.Context, hash string) (*domain.ShareLink, error) {
+	for id := uint(1); id <= 3; id++ {
+		if hashToken(fmt.Sprint(id)) == hash {
+			return &domain.ShareLink{ID: id, SpreadsheetID: 7, Mode: domain.RoleViewer, PasswordHash: r.hash}, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (protectedLinks) RecordUse(context.Context, uint, time.Time) error { return nil }
+
+func newTestShareLinks(t *testing.T) *ShareLinkService {
+	hash, err := bcrypt.GenerateFromPassword([]byte("open sesame"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewShareLinkService(protectedLinks{hash: string(hash)}, &SpreadsheetService{sheets: oneSheet{}})
+}
+
+func TestShareLinkPasswordAttemptsAreLimitedPerIP(t *testing.T) {
+	s := newTestShareLinks(t)
+	ctx := context.Background()
+
+	// One client guessing across links is stopped after its allowance,
+	// even when it finally sends the right password.
+	for i := range linkPasswordFailsPerIP {
+		token := fmt.Sprint(i%3 + 1)
+		if _, err := s.Open(ctx, domain.LinkAccess{Token: token, Password: "guess", IP: "198.51.100.1"}); !errors.Is(err, domain.ErrPasswordRequired) {
+			t.Fatalf("attempt %d: got %v, want ErrPasswordRequired", i, err)
+		}
+	}
+	_, err := s.Open(ctx, domain.LinkAccess{Token: "1", Password: "open sesame", IP: "198.51.100.1"})
+	var tooMany *domain.TooManyAttemptsError
+	if !errors.As(err, &tooMany) || tooMany.RetryAfter <= 0 || tooMany.RetryAfter > linkPasswordWindow {
+		t.Fatalf("got %v, want TooManyAttemptsError", err)
+	}
+	if !errors.Is(err, domain.ErrTooManyAttempts) {
+		t.Error("TooManyAttemptsError doesn't match ErrTooManyAttempts")
+	}
+
+	if _, err := s.Open(ctx, domain.LinkAccess{Token: "1", Password: "open sesame", IP: "198.51.100.2"}); err != nil {
+		t.Errorf("another client: %v", err)
+	}
+	// Asking for the password isn't an attempt.
+	if _, err := s.Open(ctx, domain.LinkAccess{Token: "1", Password: "", IP: "198.51.100.1"}); !errors.Is(err, domain.ErrPasswordRequired) {
+		t.Errorf("without a password: got %v, want ErrPasswordRequired", err)
+	}
+}
+
+func TestShareLinkPasswordAttemptsAreLimitedPerLink(t *testing.T) {
+	s := newTestShareLinks(t)
+	ctx := context.Background()
+
+	// Guesses spread over many addresses still use up the link's allowance.
+	for i := range linkPasswordFailsPerLink {
+		ip := fmt.Sprintf("203.0.113.%d", i)
+		if _, err := s.Open(ctx, domain.LinkAccess{Token: "1", Password: "guess", IP: ip}); !errors.Is(err, domain.ErrPasswordRequired) {
+			t.Fatalf("attempt %d: got %v, want ErrPasswordRequired", i, err)
+		}
+	}
+	if _, err := s.Open(ctx, domain.LinkAccess{Token: "1", Password: "open sesame", IP: "198.51.100.9"}); !errors.Is(err, domain.ErrTooManyAttempts) {
+		t.Fatalf("got %v, want ErrTooManyAttempts", err)
+	}
+	if _, err := s.Open(ctx, domain.LinkAccess{Token: "2", Password: "open sesame", IP: "198.51.100.9"}); err != nil {
+		t.Errorf("another link: %v", err)
+	}
+}
+
+// modeLinks holds an open link to spreadsheet 7 per mode, with the mode as
+// its token.
+type modeLinks struct{ domain.ShareLinkRepository }
+
+func (modeLinks) FindValidByHash(_ context.Context, hash string) (*domain.ShareLink, error) {
+	for _, mode := range []domain.Role{domain.RoleViewer, domain.RoleCommenter} {
+		if hashToken(string(mode)) == hash {
+			return &domain.ShareLink{ID: 1, SpreadsheetID: 7, Mode: mode}, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+// memComments keeps comments in memory, oldest first.
+type memComments struct {
+	domain.CommentRepository
+	comments []domain.Comment
+}
+
+func (r *memComments) Create(_ context.Context, c *domain.Comment, _ []uint) error {
+	c.ID = uint(len(r.comments) + 1)
+	r.comments = append(r.comments, *c)
+	return nil
+}
+
+func (r *memComments) FindByID(_ context.Context, id uint) (*domain.Comment, error) {
+	for _, c := range r.comments {
+		if c.ID == id {
+			return &c, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memComments) ListThreads(_ context.Context, id uint, filter domain.CommentFilter) ([]domain.Comment, error) {
+	var list []domain.Comment
+	for _, c := range r.comments {
+		if filter.ThreadID == nil || c.ID == *filter.ThreadID || (c.ThreadID != nil && *c.ThreadID == *filter.ThreadID) {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func TestCommenterLinkGrantsCommentAccess(t *testing.T) {
+	comments := &memComments{}
+	sheets := &SpreadsheetService{sheets: oneSheet{}, permissions: oneSheetPermissions{}, workspaces: noWorkspaces{}, comments: comments}
+	s := NewShareLinkService(modeLinks{}, sheets)
+	ctx := context.Background()
+	const stranger = 5 // the spreadsheet isn't shared with this user
+	commenter := domain.LinkAccess{Token: string(domain.RoleCommenter)}
+	viewer := domain.LinkAccess{Token: string(domain.RoleViewer)}
+	req := domain.CreateCommentRequest{Sheet: "Sheet1", Anchor: "b3", Body: "Is this net?"}
+
+	if _, err := sheets.CreateComment(ctx, 7, stranger, req); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("without the link: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.CreateComment(ctx, viewer, stranger, req); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("through a viewer link: got %v, want ErrForbidden", err)
+	}
+	if _, err := s.ListComments(ctx, viewer, domain.ListCommentsRequest{}); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("listing through a viewer link: got %v, want ErrForbidden", err)
+	}
+
+	thread, err := s.CreateComment(ctx, commenter, stranger, req)
+	if err != nil {
+		t.Fatalf("through a commenter link: %v", err)
+	}
+	if thread.AuthorID != stranger || thread.Anchor != "B3" {
+		t.Errorf("thread = %+v", thread.Comment)
+	}
+	if _, err := s.ReplyComment(ctx, commenter, stranger, thread.ID, "Yes"); err != nil {
+		t.Fatalf("reply: %v", err)
+	}
+	threads, err := s.ListComments(ctx, commenter, domain.ListCommentsRequest{})
+	if err != nil || len(threads) != 1 || len(threads[0].Replies) != 1 {
+		t.Fatalf("ListComments = %+v, %v", threads, err)
+	}
+	if err := s.DeleteComment(ctx, commenter, 3, thread.ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("deleting someone else's comment: got %v, want ErrForbidden", err)
+	}
+
+	revoked := domain.LinkAccess{Token: "revoked"}
+	if _, err := s.CreateComment(ctx, revoked, stranger, req); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("through a revoked link: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestAttemptLimiterWindow(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := newAttemptLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	l.fail("a")
+	if d := l.blockedFor("a"); d != 0 {
+		t.Fatalf("blocked after one failure for %v", d)
+	}
+	now = now.Add(10 * time.Second)
+	l.fail("a")
+	if d := l.blockedFor("a"); d != 50*time.Second {
+		t.Fatalf("blocked for %v, want the rest of the window", d)
+	}
+	if d := l.blockedFor("b"); d != 0 {
+		t.Fatalf("another key blocked for %v", d)
+	}
+
+	// A new window starts afresh, and expired keys are pruned.
+	now = now.Add(time.Minute)
+	if d := l.blockedFor("a"); d != 0 {
+		t.Fatalf("still blocked after the window for %v", d)
+	}
+	l.fail("b")
+	if _, ok := l.failures["a"]; ok {
+		t.Error("expired key was not pruned")
+	}
+	l.fail("a")
+	if d := l.blockedFor("a"); d != 0 {
+		t.Errorf("one failure in a new window blocked for %v", d)
+	}
+}
+
+func TestCreateLinkRefusesPasswordsBcryptCantHash(t *testing.T) {
+	s := newTestShareLinks(t)
+	_, err := s.CreateLink(context.Background(), 7, 1, domain.CreateShareLinkRequest{Password: strings.Repeat("é", 40)})
+	if !errors.Is(err, domain.ErrInvalidInput) {
+		t.Fatalf("got %v, want ErrInvalidInput", err)
+	}
+}
@@ -2,66 +2,194 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"jaggle-grids/internal/domain"
+	"time"
 )
 
 type SpreadsheetService struct {
-	sheets domain.SpreadsheetRepository
+	sheets      domain.SpreadsheetRepository
+	permissions domain.PermissionRepository
+	revisions   domain.RevisionRepository
+	users       domain.UserRepository
+	workspaces  domain.WorkspaceRepository
+	orgs        domain.OrganizationRepository
+	folders     domain.FolderRepository
+	search      domain.SearchRepository
+	comments    domain.CommentRepository
+
+	notifications *NotificationService
+	webhooks      *WebhookService
+
+	revisionWindow time.Duration
 }
 
-func NewSpreadsheetService(sheets domain.SpreadsheetRepository) *SpreadsheetService {
-	return &SpreadsheetService{sheets: sheets}
+func NewSpreadsheetService(
+	sheets domain.SpreadsheetRepository,
+	permissions domain.PermissionRepository,
+	revisions domain.RevisionRepository,
+	users domain.UserRepository,
+	workspaces domain.WorkspaceRepository,
+	orgs domain.OrganizationRepository,
+	folders domain.FolderRepository,
+	search domain.SearchRepository,
+	comments domain.CommentRepository,
+	notifications *NotificationService,
+	webhooks *WebhookService,
+	revisionWindow time.Duration,
+) *SpreadsheetService {
+	return &SpreadsheetService{
+		sheets:      sheets,
+		permissions: permissions,
+		revisions:   revisions,
+		users:       users,
+		workspaces:  workspaces,
+		orgs:        orgs,
+		folders:     folders,
+		search:      search,
+		comments:    comments,
+
+		notifications: notifications,
+		webhooks:      webhooks,
+
+		revisionWindow: revisionWindow,
+	}
 }
 
-func (s *SpreadsheetService) List(ctx context.Context, ownerID uint) ([]domain.SpreadsheetListItem, error) {
-	sheets, err := s.sheets.ListByOwner(ctx, ownerID)
+// List views
+const (
+	ListAll    = "all"
+	ListOwned  = "owned"
+	ListShared = "shared"
+	ListTeam   = "team"
+)
+
+// Page sizes of the spreadsheet list.
+const (
+	defaultListLimit = 50
+	maxListLimit     = 200
+)
+
+// List returns a page of the spreadsheets visible to the user: their own,
+// those shared with them, those in their organizations' workspaces, or all
+// of them (req.View is one of the List* constants), filtered and sorted as
+// requested. The most recently updated come first unless asked otherwise.
+func (s *SpreadsheetService) List(ctx context.Context, userID uint, req domain.ListSpreadsheetsRequest) (*domain.SpreadsheetPage, error) {
+	q, err := listQuery(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	limit := q.Limit
+	q.Limit++ // one extra row tells whether there is a next page
+
+	sheets, total, err := s.sheets.ListVisible(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("list spreadsheets: %w", err)
 	}
+	page := &domain.SpreadsheetPage{Items: make([]domain.SpreadsheetListItem, 0, len(sheets)), Total: total}
+	if len(sheets) > limit {
+		sheets = sheets[:limit]
+		page.NextCursor = encodeListCursor(q, sheets[limit-1])
+	}
 
-	items := make([]domain.SpreadsheetListItem, len(sheets))
+	roles, err := s.listRoles(ctx, userID, sheets)
+	if err != nil {
+		return nil, err
+	}
+	for _, sh := range sheets {
+		page.Items = append(page.Items, toListItem(sh, roles[sh.ID]))
+	}
+	return page, nil
+}
+
+// listRoles returns the user's role on each of sheets, as RoleOf would,
+// with a fixed number of queries.
+func (s *SpreadsheetService) listRoles(ctx context.Context, userID uint, sheets []domain.Spreadsheet) (map[uint]domain.Role, error) {
+	ids := make([]uint, len(sheets))
 	for i, sh := range sheets {
-		ownerName := ""
-		if sh.Owner != nil {
-			ownerName = sh.Owner.Name
-		}
-		items[i] = domain.SpreadsheetListItem{
-			ID:        sh.ID,
-			Title:     sh.Title,
-			OwnerID:   sh.OwnerID,
-			OwnerName: ownerName,
-			CreatedAt: sh.CreatedAt,
-			UpdatedAt: sh.UpdatedAt,
+		ids[i] = sh.ID
+	}
+	roles, err := s.permissions.FindRoles(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("find roles: %w", err)
+	}
+	team, err := s.teamRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sh := range sheets {
+		if sh.OwnerID == userID {
+			roles[sh.ID] = domain.RoleOwner
+		} else if role, ok := team[sh.WorkspaceID]; ok && !roles[sh.ID].Allows(role) {
+			roles[sh.ID] = role
 		}
 	}
-	return items, nil
+	return roles, nil
 }
 
-func (s *SpreadsheetService) Get(ctx context.Context, id, ownerID uint) (*domain.Spreadsheet, error) {
-	sheet, err := s.sheets.FindByIDAndOwner(ctx, id, ownerID)
+// Get returns a spreadsheet with the path of the folder it is in.
+func (s *SpreadsheetService) Get(ctx context.Context, id, userID uint) (*domain.Spreadsheet, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleViewer)
 	if err != nil {
-		return nil, fmt.Errorf("spreadsheet not found: %w", err)
+		return nil, err
+	}
+	if err := s.setPath(ctx, sheet); err != nil {
+		return nil, err
 	}
 	return sheet, nil
 }
 
-func (s *SpreadsheetService) Create(ctx context.Context, title string, ownerID uint) (*domain.Spreadsheet, error) {
+// Create makes a spreadsheet owned by the user in workspaceID, or in
+// their personal workspace when it is nil.
+func (s *SpreadsheetService) Create(ctx context.Context, title string, ownerID uint, workspaceID *uint) (*domain.Spreadsheet, error) {
+	ws, err := s.targetWorkspace(ctx, workspaceID, ownerID)
+	if err != nil {
+		return nil, err
+	}
 	sheet := &domain.Spreadsheet{
-		Title:   title,
-		OwnerID: ownerID,
-		Data:    "",
+		Title:       title,
+		OwnerID:     ownerID,
+		WorkspaceID: ws.ID,
+		Data:        "",
 	}
 	if err := s.sheets.Create(ctx, sheet); err != nil {
 		return nil, fmt.Errorf("create spreadsheet: %w", err)
 	}
+	s.index(ctx, sheet)
+	s.webhooks.EmitCreated(ctx, sheet, ownerID)
+	sheet.Role = domain.RoleOwner
 	return sheet, nil
 }
 
-func (s *SpreadsheetService) Update(ctx context.Context, id, ownerID uint, title, data string) (*domain.Spreadsheet, error) {
-	sheet, err := s.sheets.FindByIDAndOwner(ctx, id, ownerID)
+// Update changes the title and/or data. When expectedVersion is set it must
+// match the stored version; data writes are additionally checked against
+// the version loaded here, so concurrent saves never silently overwrite
+// each other. structure lists the rows and columns the new data inserted
+// or deleted; comments are moved to follow them. Every data write is
+// recorded as a revision of its own.
+func (s *SpreadsheetService) Update(ctx context.Context, id, userID uint, title, data string, structure []domain.StructureChange, expectedVersion *int) (*domain.Spreadsheet, error) {
+	return s.update(ctx, id, userID, title, data, structure, expectedVersion, 0)
+}
+
+// SaveLive is Update for the snapshots a live-editing room flushes every
+// few seconds: an editor's flushes within one revision window share a
+// single live revision instead of adding one each.
+func (s *SpreadsheetService) SaveLive(ctx context.Context, id, userID uint, data string, structure []domain.StructureChange, expectedVersion *int) (*domain.Spreadsheet, error) {
+	return s.update(ctx, id, userID, "", data, structure, expectedVersion, s.revisionWindow)
+}
+
+func (s *SpreadsheetService) update(ctx context.Context, id, userID uint, title, data string, structure []domain.StructureChange, expectedVersion *int, mergeWindow time.Duration) (*domain.Spreadsheet, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleEditor)
 	if err != nil {
-		return nil, fmt.Errorf("spreadsheet not found: %w", err)
+		return nil, err
+	}
+	if err := validateStructure(structure); err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != sheet.Version {
+		return nil, &domain.VersionConflictError{Current: sheet.Version}
 	}
 
 	fields := map[string]any{}
@@ -75,15 +203,121 @@ func (s *SpreadsheetService) Update(ctx context.Context, id, ownerID uint, title
 		return sheet, nil
 	}
 
-	if err := s.sheets.Update(ctx, sheet, fields); err != nil {
+	previousTitle := sheet.Title
+	if data != "" {
+		err = s.sheets.SaveData(ctx, sheet, fields, userID, mergeWindow)
+	} else {
+		err = s.sheets.Update(ctx, sheet, fields)
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrConflict) {
+			return nil, s.conflict(ctx, id)
+		}
 		return nil, fmt.Errorf("update spreadsheet: %w", err)
 	}
+	if data != "" {
+		s.shiftComments(ctx, sheet.ID, structure)
+		s.webhooks.EmitSaved(ctx, sheet, userID)
+	}
+	if sheet.Title != previousTitle {
+		s.webhooks.EmitRenamed(ctx, sheet, userID, previousTitle)
+	}
+	s.index(ctx, sheet)
 	return sheet, nil
 }
 
-func (s *SpreadsheetService) Delete(ctx context.Context, id, ownerID uint) error {
-	if err := s.sheets.Delete(ctx, id, ownerID); err != nil {
-		return fmt.Errorf("spreadsheet not found: %w", err)
+// conflict builds a VersionConflictError carrying the currently stored
+// version of a spreadsheet.
+func (s *SpreadsheetService) conflict(ctx context.Context, id uint) error {
+	current, err := s.sheets.FindByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+	return &domain.VersionConflictError{Current: current.Version}
+}
+
+// Delete moves a spreadsheet to the owner's trash. See trash.go.
+func (s *SpreadsheetService) Delete(ctx context.Context, id, userID uint) error {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleOwner)
+	if err != nil {
+		return err
 	}
+	if err := s.sheets.Trash(ctx, sheet.ID, sheet.OwnerID); err != nil {
+		return fmt.Errorf("delete spreadsheet: %w", err)
+	}
+	s.webhooks.EmitDeleted(ctx, sheet, userID)
 	return nil
 }
+
+// RoleOf returns the user's effective role on a spreadsheet: the higher
+// of what it is shared with them as and what its workspace grants them.
+func (s *SpreadsheetService) RoleOf(ctx context.Context, sheet *domain.Spreadsheet, userID uint) (domain.Role, error) {
+	if sheet.OwnerID == userID {
+		return domain.RoleOwner, nil
+	}
+	role, err := s.permissions.FindRole(ctx, sheet.ID, userID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return "", fmt.Errorf("find role: %w", err)
+	}
+	wsRole, err := workspaceRole(ctx, s.workspaces, s.orgs, sheet.WorkspaceID, userID)
+	if err != nil {
+		return "", err
+	}
+	if wsRole.Allows(role) {
+		role = wsRole
+	}
+	if role == "" {
+		return "", fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+	return role, nil
+}
+
+// Role returns the user's current role on a spreadsheet without loading
+// its data. Users without access get ErrNotFound.
+func (s *SpreadsheetService) Role(ctx context.Context, id, userID uint) (domain.Role, error) {
+	sheet, err := s.sheets.FindByIDWithoutData(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+	return s.RoleOf(ctx, sheet, userID)
+}
+
+// authorize loads a spreadsheet and checks that the user holds at least
+// min on it. Users without any access get ErrNotFound so that the
+// existence of other people's spreadsheets isn't revealed.
+func (s *SpreadsheetService) authorize(ctx context.Context, id, userID uint, min domain.Role) (*domain.Spreadsheet, error) {
+	sheet, err := s.sheets.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+
+	role, err := s.RoleOf(ctx, sheet, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !role.Allows(min) {
+		return nil, fmt.Errorf("%w: %s role required", domain.ErrForbidden, min)
+	}
+
+	sheet.Role = role
+	return sheet, nil
+}
+
+func toListItem(sh domain.Spreadsheet, role domain.Role) domain.SpreadsheetListItem {
+	ownerName := ""
+	if sh.Owner != nil {
+		ownerName = sh.Owner.Name
+	}
+	return domain.SpreadsheetListItem{
+		ID:          sh.ID,
+		Title:       sh.Title,
+		OwnerID:     sh.OwnerID,
+		OwnerName:   ownerName,
+		WorkspaceID: sh.WorkspaceID,
+		FolderID:    sh.FolderID,
+		Role:        role,
+		CreatedAt:   sh.CreatedAt,
+		UpdatedAt:   sh.UpdatedAt,
+		DeletedAt:   sh.DeletedAt,
+	}
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"jaggle-grids/internal/domain"
+	"testing"
+)
+
+// brokenPermissions fails every lookup, as a database that is down would.
+type brokenPermissions struct{ domain.PermissionRepository }
+
+func (brokenPermissions) FindRole(context.Context, uint, uint) (domain.Role, error) {
+	return "", errors.New("connection refused")
+}
+
+// brokenWorkspaces fails every lookup.
+type brokenWorkspaces struct{ domain.WorkspaceRepository }
+
+func (brokenWorkspaces) FindByID(context.Context, uint) (*domain.Workspace, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRoleOfPassesLookupErrorsThrough(t *testing.T) {
+	ctx := context.Background()
+	sheet := &domain.Spreadsheet{ID: 7, OwnerID: 1, WorkspaceID: 1}
+
+	for name, s := range map[string]*SpreadsheetService{
+		"permissions": {permissions: brokenPermissions{}, workspaces: noWorkspaces{}},
+		"workspaces":  {permissions: oneSheetPermissions{}, workspaces: brokenWorkspaces{}},
+	} {
+		_, err := s.RoleOf(ctx, sheet, 3)
+		if err == nil || errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("%s: got %v, want the lookup error rather than ErrNotFound", name, err)
+		}
+		// The owner doesn't need a lookup.
+		if role, err := s.RoleOf(ctx, sheet, 1); err != nil || role != domain.RoleOwner {
+			t.Errorf("%s: owner got %q, %v", name, role, err)
+		}
+	}
+
+	s := &SpreadsheetService{permissions: oneSheetPermissions{}, workspaces: noWorkspaces{}}
+	if role, err := s.RoleOf(ctx, sheet, 3); err != nil || role != domain.RoleViewer {
+		t.Errorf("shared user: got %q, %v", role, err)
+	}
+	if _, err := s.RoleOf(ctx, sheet, 4); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("user without access: got %v, want ErrNotFound", err)
+	}
+}
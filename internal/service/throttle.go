@@ -0,0 +1,62 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// attemptLimiter counts failures per key, such as a share link or a client
+// IP, and blocks a key for the rest of a fixed window once it reaches max.
+// Counts live in memory, so each server process limits on its own and a
+// restart clears them.
+type attemptLimiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	failures  map[string]*attemptWindow
+	lastPrune time.Time
+}
+
+type attemptWindow struct {
+	count int
+	ends  time.Time
+}
+
+func newAttemptLimiter(max int, window time.Duration) *attemptLimiter {
+	return &attemptLimiter{max: max, window: window, now: time.Now, failures: map[string]*attemptWindow{}}
+}
+
+// blockedFor returns how long key is still blocked, or 0 if it may try.
+func (l *attemptLimiter) blockedFor(key string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.failures[key]
+	if !ok || w.count < l.max {
+		return 0
+	}
+	return max(w.ends.Sub(l.now()), 0)
+}
+
+// fail records a failure for key.
+func (l *attemptLimiter) fail(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	if now.Sub(l.lastPrune) >= l.window {
+		for k, w := range l.failures {
+			if !now.Before(w.ends) {
+				delete(l.failures, k)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	w, ok := l.failures[key]
+	if !ok || !now.Before(w.ends) {
+		w = &attemptWindow{ends: now.Add(l.window)}
+		l.failures[key] = w
+	}
+	w.count++
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"time"
+)
+
+// DefaultTrashRetention is how long deleted spreadsheets stay in the trash
+// before they are purged.
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// ListTrash returns the user's trashed spreadsheets, most recently deleted
+// first. Only owners have a trash: while a spreadsheet is trashed it is
+// hidden from everyone it was shared with.
+func (s *SpreadsheetService) ListTrash(ctx context.Context, userID uint) ([]domain.SpreadsheetListItem, error) {
+	sheets, err := s.sheets.ListTrashedByOwner(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list trash: %w", err)
+	}
+
+	items := make([]domain.SpreadsheetListItem, len(sheets))
+	for i, sh := range sheets {
+		items[i] = toListItem(sh, domain.RoleOwner)
+	}
+	return items, nil
+}
+
+// Restore moves a spreadsheet out of the trash, restoring access for
+// everyone it is shared with.
+func (s *SpreadsheetService) Restore(ctx context.Context, id, userID uint) (*domain.Spreadsheet, error) {
+	if err := s.sheets.Restore(ctx, id, userID); err != nil {
+		return nil, fmt.Errorf("spreadsheet %w in trash", domain.ErrNotFound)
+	}
+	return s.Get(ctx, id, userID)
+}
+
+// DeleteForever permanently deletes a trashed spreadsheet with its
+// revisions and permissions.
+func (s *SpreadsheetService) DeleteForever(ctx context.Context, id, userID uint) error {
+	if err := s.sheets.Delete(ctx, id, userID); err != nil {
+		return fmt.Errorf("spreadsheet %w in trash", domain.ErrNotFound)
+	}
+	s.unindex(ctx, id)
+	return nil
+}
+
+// EmptyTrash permanently deletes all of the user's trashed spreadsheets and
+// returns how many were removed.
+func (s *SpreadsheetService) EmptyTrash(ctx context.Context, userID uint) (int, error) {
+	sheets, err := s.sheets.ListTrashedByOwner(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("list trash: %w", err)
+	}
+
+	deleted := 0
+	for _, sh := range sheets {
+		if err := s.sheets.Delete(ctx, sh.ID, sh.OwnerID); err != nil {
+			return deleted, fmt.Errorf("empty trash: %w", err)
+		}
+		s.unindex(ctx, sh.ID)
+		deleted++
+	}
+	return deleted, nil
+}
+
+// PurgeTrash permanently deletes spreadsheets that have been in the trash
+// for longer than retention.
+func (s *SpreadsheetService) PurgeTrash(ctx context.Context, retention time.Duration) error {
+	const batchSize = 500
+	cutoff := time.Now().Add(-retention)
+
+	for {
+		sheets, err := s.sheets.ListTrashedBefore(ctx, cutoff, batchSize)
+		if err != nil {
+			return fmt.Errorf("list expired trash: %w", err)
+		}
+		for _, sh := range sheets {
+			if err := s.sheets.Delete(ctx, sh.ID, sh.OwnerID); err != nil {
+				return fmt.Errorf("purge spreadsheet %d: %w", sh.ID, err)
+			}
+			s.unindex(ctx, sh.ID)
+		}
+		if len(sheets) < batchSize {
+			return nil
+		}
+	}
+}
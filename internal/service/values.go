@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+)
+
+// maxValueCells caps how many cells a single values request may read or
+// write.
+const maxValueCells = 100_000
+
+// editAttempts is how often a server-side edit is re-applied when a
+// concurrent save changes the spreadsheet underneath it.
+const editAttempts = 3
+
+// Values reads a range of one sheet. An empty rng means the sheet's used
+// range, starting at A1. With formulas set, formula cells return their
+// formula instead of their value.
+func (s *SpreadsheetService) Values(ctx context.Context, id, userID uint, sheetName, rng string, formulas bool) (*domain.ValueRange, error) {
+	_, wb, err := s.Workbook(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	sh, err := findSheet(wb, sheetName)
+	if err != nil {
+		return nil, err
+	}
+
+	a1 := workbook.Ref{Row: 1, Col: 1}
+	r := workbook.Range{Start: a1, End: a1}
+	if rng != "" {
+		if r, err = parseValueRange(rng); err != nil {
+			return nil, err
+		}
+	} else if bounds, ok := sh.Bounds(); ok {
+		r.End = bounds.End
+	} else {
+		return &domain.ValueRange{Range: sheetRange(sh, r), Values: [][]any{}}, nil
+	}
+	if r.Rows()*r.Cols() > maxValueCells {
+		return nil, fmt.Errorf("%w: range %s has more than %d cells", domain.ErrInvalidInput, r, maxValueCells)
+	}
+	return &domain.ValueRange{Range: sheetRange(sh, r), Values: sh.Values(r, formulas)}, nil
+}
+
+// UpdateValues writes rows of values starting at the top-left cell of rng.
+// When rng spans more than one cell the values must fit inside it. A nil
+// value clears its cell; rows may be shorter than others.
+func (s *SpreadsheetService) UpdateValues(ctx context.Context, id, userID uint, sheetName, rng string, values [][]any, expectedVersion *int) (*domain.UpdateValuesResponse, error) {
+	r, err := parseValueRange(rng)
+	if err != nil {
+		return nil, err
+	}
+	cells, width, err := parseInputs(values)
+	if err != nil {
+		return nil, err
+	}
+	if r.Start != r.End && (len(cells) > r.Rows() || width > r.Cols()) {
+		return nil, fmt.Errorf("%w: values (%d rows, %d columns) don't fit in range %s", domain.ErrInvalidInput, len(cells), width, r)
+	}
+
+	return s.writeValues(ctx, id, userID, sheetName, expectedVersion, cells, width, func(*workbook.Sheet) workbook.Ref {
+		return r.Start
+	})
+}
+
+// AppendValues writes rows of values below the last row that has content
+// in the columns of rng, starting at its first column. An empty rng
+// considers the whole sheet.
+func (s *SpreadsheetService) AppendValues(ctx context.Context, id, userID uint, sheetName, rng string, values [][]any, expectedVersion *int) (*domain.UpdateValuesResponse, error) {
+	r := workbook.Range{Start: workbook.Ref{Row: 1, Col: 1}, End: workbook.Ref{Row: workbook.MaxRows, Col: workbook.MaxCols}}
+	if rng != "" {
+		var err error
+		if r, err = parseValueRange(rng); err != nil {
+			return nil, err
+		}
+	}
+	cells, width, err := parseInputs(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.writeValues(ctx, id, userID, sheetName, expectedVersion, cells, width, func(sh *workbook.Sheet) workbook.Ref {
+		return workbook.Ref{Row: max(sh.LastRow(r), r.Start.Row-1) + 1, Col: r.Start.Col}
+	})
+}
+
+// cellInput is a parsed value to write.
+type cellInput struct {
+	value   workbook.Value
+	formula string
+}
+
+func parseInputs(values [][]any) ([][]cellInput, int, error) {
+	if len(values) == 0 {
+		return nil, 0, fmt.Errorf("%w: values must not be empty", domain.ErrInvalidInput)
+	}
+	width, total := 0, 0
+	rows := make([][]cellInput, len(values))
+	for i, row := range values {
+		rows[i] = make([]cellInput, len(row))
+		for j, v := range row {
+			value, formula, err := workbook.ParseInput(v)
+			if err != nil {
+				return nil, 0, fmt.Errorf("%w: row %d, column %d: %v", domain.ErrInvalidInput, i+1, j+1, err)
+			}
+			rows[i][j] = cellInput{value: value, formula: formula}
+		}
+		width = max(width, len(row))
+		total += len(row)
+	}
+	if total > maxValueCells {
+		return nil, 0, fmt.Errorf("%w: more than %d values", domain.ErrInvalidInput, maxValueCells)
+	}
+	return rows, width, nil
+}
+
+// writeValues stores cells at the position start picks on the current
+// version of the sheet.
+func (s *SpreadsheetService) writeValues(ctx context.Context, id, userID uint, sheetName string, expectedVersion *int, cells [][]cellInput, width int, start func(*workbook.Sheet) workbook.Ref) (*domain.UpdateValuesResponse, error) {
+	var written workbook.Range
+	var sh *workbook.Sheet
+	sheet, err := s.editWorkbook(ctx, id, userID, expectedVersion, func(wb *workbook.Workbook) error {
+		var err error
+		if sh, err = findSheet(wb, sheetName); err != nil {
+			return err
+		}
+		at := start(sh)
+		written = workbook.Range{Start: at, End: workbook.Ref{Row: at.Row + len(cells) - 1, Col: at.Col + width - 1}}
+		if written.End.Row > workbook.MaxRows || written.End.Col > workbook.MaxCols {
+			return fmt.Errorf("%w: values extend past the end of the sheet", domain.ErrInvalidInput)
+		}
+		var changed []workbook.Ref
+		for i, row := range cells {
+			for j, c := range row {
+				ref := workbook.Ref{Row: at.Row + i, Col: at.Col + j}
+				sh.SetContent(ref, c.value, c.formula)
+				changed = append(changed, ref)
+			}
+		}
+		wb.Recalculate(sh, changed)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	updated := 0
+	for _, row := range cells {
+		updated += len(row)
+	}
+	return &domain.UpdateValuesResponse{
+		Range:        sheetRange(sh, written),
+		UpdatedCells: updated,
+		Version:      sheet.Version,
+	}, nil
+}
+
+// editWorkbook applies edit to the stored workbook and saves it as a new
+// revision. Without an expected version, an edit that loses a race with
+// another save is re-applied to the newer data.
+func (s *SpreadsheetService) editWorkbook(ctx context.Context, id, userID uint, expectedVersion *int, edit func(*workbook.Workbook) error) (*domain.Spreadsheet, error) {
+	for attempt := 1; ; attempt++ {
+		sheet, err := s.authorize(ctx, id, userID, domain.RoleEditor)
+		if err != nil {
+			return nil, err
+		}
+		if expectedVersion != nil && *expectedVersion != sheet.Version {
+			return nil, &domain.VersionConflictError{Current: sheet.Version}
+		}
+		wb, err := decodeWorkbook(sheet)
+		if err != nil {
+			return nil, err
+		}
+		if err := edit(wb); err != nil {
+			return nil, err
+		}
+		data, err := workbook.Encode(wb)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.sheets.SaveData(ctx, sheet, map[string]any{"data": data}, userID, 0); err != nil {
+			if errors.Is(err, domain.ErrConflict) {
+				if expectedVersion == nil && attempt < editAttempts {
+					continue
+				}
+				return nil, s.conflict(ctx, id)
+			}
+			return nil, fmt.Errorf("update spreadsheet: %w", err)
+		}
+		s.index(ctx, sheet)
+		s.webhooks.EmitSaved(ctx, sheet, userID)
+		return sheet, nil
+	}
+}
+
+func findSheet(wb *workbook.Workbook, name string) (*workbook.Sheet, error) {
+	sh := wb.Sheet(name)
+	if sh == nil {
+		return nil, fmt.Errorf("sheet %q %w", name, domain.ErrNotFound)
+	}
+	return sh, nil
+}
+
+func parseValueRange(rng string) (workbook.Range, error) {
+	r, err := workbook.ParseRange(rng)
+	if err != nil {
+		return workbook.Range{}, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	return r, nil
+}
+
+// sheetRange formats a range qualified with its sheet ("Sheet1!A1:B2").
+func sheetRange(sh *workbook.Sheet, r workbook.Range) string {
+	return workbook.QuoteSheetName(sh.Name) + "!" + r.String()
+}
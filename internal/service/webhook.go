@@ -0,0 +1,630 @@
+package service
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"jaggle-grids/internal/domain"
+	"log"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWebhookLogRetention is how long finished deliveries stay in the
+// delivery log.
+const DefaultWebhookLogRetention = 30 * 24 * time.Hour
+
+const (
+	// webhookBatch is how many deliveries one run sends at most.
+	webhookBatch = 50
+	// webhookWorkers is how many webhooks one run posts to at once.
+	webhookWorkers = 8
+	// maxWebhookAttempts is how often a delivery is tried before giving
+	// up; with the backoff below the last try is about an hour after the
+	// first.
+	maxWebhookAttempts = 8
+	webhookRetryBase   = 30 * time.Second
+	webhookRetryMax    = time.Hour
+	webhookTimeout     = 10 * time.Second
+	// maxWebhookDrain is how much of a response body is read, and thrown
+	// away, so the connection can be reused.
+	maxWebhookDrain = 64 << 10
+
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// Request headers of a delivery.
+const (
+	webhookEventHeader     = "X-Grids-Event"
+	webhookDeliveryHeader  = "X-Grids-Delivery"
+	webhookSignatureHeader = "X-Grids-Signature-256"
+)
+
+// WebhookService manages webhooks and delivers their events. Owners
+// manage a spreadsheet's webhooks; a workspace's are managed by its owner,
+// or by organization admins. SpreadsheetService emits events through the
+// Emit* methods, which only queue deliveries; the background worker in
+// Deliver posts them.
+type WebhookService struct {
+	hooks       domain.WebhookRepository
+	sheets      domain.SpreadsheetRepository
+	permissions domain.PermissionRepository
+	workspaces  domain.WorkspaceRepository
+	orgs        domain.OrganizationRepository
+	users       domain.UserRepository
+	allowed     []netip.Prefix
+	client      *http.Client
+}
+
+func NewWebhookService(
+	hooks domain.WebhookRepository,
+	sheets domain.SpreadsheetRepository,
+	permissions domain.PermissionRepository,
+	workspaces domain.WorkspaceRepository,
+	orgs domain.OrganizationRepository,
+	users domain.UserRepository,
+	allowedNetworks []netip.Prefix,
+) *WebhookService {
+	return &WebhookService{
+		hooks:       hooks,
+		sheets:      sheets,
+		permissions: permissions,
+		workspaces:  workspaces,
+		orgs:        orgs,
+		users:       users,
+		allowed:     allowedNetworks,
+		client:      newWebhookClient(allowedNetworks),
+	}
+}
+
+// ── Managing webhooks ────────────────────────
+
+// ListSpreadsheetWebhooks returns the webhooks of a spreadsheet.
+func (s *WebhookService) ListSpreadsheetWebhooks(ctx context.Context, id, userID uint) ([]domain.Webhook, error) {
+	if err := s.authorizeSpreadsheet(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	hooks, err := s.hooks.ListBySpreadsheet(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// CreateSpreadsheetWebhook adds a webhook for the events of a spreadsheet.
+func (s *WebhookService) CreateSpreadsheetWebhook(ctx context.Context, id, userID uint, req domain.CreateWebhookRequest) (*domain.CreateWebhookResponse, error) {
+	if err := s.authorizeSpreadsheet(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, &domain.Webhook{SpreadsheetID: &id, CreatedByID: userID}, req)
+}
+
+// ListWorkspaceWebhooks returns the webhooks of a workspace.
+func (s *WebhookService) ListWorkspaceWebhooks(ctx context.Context, workspaceID, userID uint) ([]domain.Webhook, error) {
+	if err := s.authorizeWorkspace(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	hooks, err := s.hooks.ListByWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// CreateWorkspaceWebhook adds a webhook for the events of every
+// spreadsheet in a workspace.
+func (s *WebhookService) CreateWorkspaceWebhook(ctx context.Context, workspaceID, userID uint, req domain.CreateWebhookRequest) (*domain.CreateWebhookResponse, error) {
+	if err := s.authorizeWorkspace(ctx, workspaceID, userID); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, &domain.Webhook{WorkspaceID: &workspaceID, CreatedByID: userID}, req)
+}
+
+// GetWebhook returns a webhook the user manages.
+func (s *WebhookService) GetWebhook(ctx context.Context, id, userID uint) (*domain.Webhook, error) {
+	return s.authorizeWebhook(ctx, id, userID)
+}
+
+// UpdateWebhook changes a webhook's URL, events or whether it is active.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id, userID uint, req domain.UpdateWebhookRequest) (*domain.Webhook, error) {
+	hook, err := s.authorizeWebhook(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		if hook.URL, err = s.validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+	}
+	if req.Events != nil {
+		if hook.Events, err = validateWebhookEvents(req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if err := s.hooks.Update(ctx, hook); err != nil {
+		return nil, fmt.Errorf("update webhook: %w", err)
+	}
+	return hook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id, userID uint) error {
+	if _, err := s.authorizeWebhook(ctx, id, userID); err != nil {
+		return err
+	}
+	if err := s.hooks.Delete(ctx, id); err != nil {
+		return fmt.Errorf("webhook %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ListDeliveries returns a page of a webhook's delivery log, newest first.
+func (s *WebhookService) ListDeliveries(ctx context.Context, id, userID uint, req domain.ListDeliveriesRequest) ([]domain.WebhookDelivery, error) {
+	if _, err := s.authorizeWebhook(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	switch {
+	case req.Limit < 0 || req.Limit > maxDeliveryLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", domain.ErrInvalidInput, maxDeliveryLimit)
+	case req.Limit == 0:
+		req.Limit = defaultDeliveryLimit
+	}
+
+	deliveries, err := s.hooks.ListDeliveries(ctx, id, req.Before, req.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Redeliver queues a new delivery of the payload of an earlier one, signed
+// with the webhook's current secret and sent to its current URL.
+func (s *WebhookService) Redeliver(ctx context.Context, id, userID, deliveryID uint) (*domain.WebhookDelivery, error) {
+	if _, err := s.authorizeWebhook(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	orig, err := s.hooks.FindDelivery(ctx, id, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("delivery %w", domain.ErrNotFound)
+	}
+
+	now := time.Now()
+	d := &domain.WebhookDelivery{
+		WebhookID:     id,
+		Event:         orig.Event,
+		Payload:       orig.Payload,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &orig.ID,
+	}
+	if err := s.hooks.CreateDelivery(ctx, d); err != nil {
+		return nil, fmt.Errorf("queue delivery: %w", err)
+	}
+	return d, nil
+}
+
+func (s *WebhookService) create(ctx context.Context, hook *domain.Webhook, req domain.CreateWebhookRequest) (*domain.CreateWebhookResponse, error) {
+	var err error
+	if hook.URL, err = s.validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+	if hook.Events, err = validateWebhookEvents(req.Events); err != nil {
+		return nil, err
+	}
+	hook.Secret = req.Secret
+	if hook.Secret == "" {
+		if hook.Secret, err = generateToken(); err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+	}
+	hook.Active = true
+
+	if err := s.hooks.Create(ctx, hook); err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	return &domain.CreateWebhookResponse{Secret: hook.Secret, Webhook: *hook}, nil
+}
+
+// authorizeWebhook loads a webhook the user manages, with the errors of
+// the checks on its spreadsheet or workspace.
+func (s *WebhookService) authorizeWebhook(ctx context.Context, id, userID uint) (*domain.Webhook, error) {
+	hook, err := s.hooks.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("webhook %w", domain.ErrNotFound)
+	}
+	if hook.SpreadsheetID != nil {
+		err = s.authorizeSpreadsheet(ctx, *hook.SpreadsheetID, userID)
+	} else {
+		err = s.authorizeWorkspace(ctx, *hook.WorkspaceID, userID)
+	}
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil, fmt.Errorf("webhook %w", domain.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hook, nil
+}
+
+// authorizeSpreadsheet checks that the user holds the owner role on a
+// spreadsheet, worked out as in SpreadsheetService.RoleOf. Users with a
+// lower role get ErrForbidden, everyone else ErrNotFound.
+func (s *WebhookService) authorizeSpreadsheet(ctx context.Context, id, userID uint) error {
+	sheet, err := s.sheets.FindByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	}
+	if sheet.OwnerID == userID {
+		return nil
+	}
+	role, err := s.permissions.FindRole(ctx, sheet.ID, userID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("find role: %w", err)
+	}
+	wsRole, err := workspaceRole(ctx, s.workspaces, s.orgs, sheet.WorkspaceID, userID)
+	if err != nil {
+		return err
+	}
+	if wsRole.Allows(role) {
+		role = wsRole
+	}
+	switch role {
+	case domain.RoleOwner:
+		return nil
+	case "":
+		return fmt.Errorf("spreadsheet %w", domain.ErrNotFound)
+	default:
+		return fmt.Errorf("%w: %s role required", domain.ErrForbidden, domain.RoleOwner)
+	}
+}
+
+// authorizeWorkspace checks that the user owns a personal workspace or
+// is an admin of the organization of one.
+func (s *WebhookService) authorizeWorkspace(ctx context.Context, id, userID uint) error {
+	if _, err := s.workspaces.FindByID(ctx, id); err != nil {
+		return fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, id, userID)
+	if err != nil {
+		return err
+	}
+	switch role {
+	case domain.RoleOwner:
+		return nil
+	case "":
+		return fmt.Errorf("workspace %w", domain.ErrNotFound)
+	default:
+		return fmt.Errorf("%w: %s role required", domain.ErrForbidden, domain.OrgAdmin)
+	}
+}
+
+// validateWebhookURL normalises a webhook URL. Hosts that are plainly
+// internal are refused here to give early feedback; the check that counts
+// runs when deliveries connect (see newWebhookClient).
+func (s *WebhookService) validateWebhookURL(raw string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || u.User != nil {
+		return "", fmt.Errorf("%w: url must be an http or https URL without credentials", domain.ErrInvalidInput)
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	blocked := false
+	if ip, err := netip.ParseAddr(host); err == nil {
+		blocked = !webhookAddressAllowed(ip, s.allowed)
+	} else if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		blocked = !webhookAddressAllowed(netip.MustParseAddr("127.0.0.1"), s.allowed) &&
+			!webhookAddressAllowed(netip.IPv6Loopback(), s.allowed)
+	}
+	if blocked {
+		return "", fmt.Errorf("%w: url must not point to a loopback, private or link-local address", domain.ErrInvalidInput)
+	}
+	return u.String(), nil
+}
+
+func validateWebhookEvents(events []domain.WebhookEvent) ([]domain.WebhookEvent, error) {
+	seen := map[domain.WebhookEvent]bool{}
+	var out []domain.WebhookEvent
+	for _, e := range events {
+		if !e.Valid() {
+			return nil, fmt.Errorf("%w: unknown event %q", domain.ErrInvalidInput, e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: at least one event is required", domain.ErrInvalidInput)
+	}
+	return out, nil
+}
+
+// ── Emitting events ──────────────────────────
+
+// webhookPayload is the JSON body of a delivery.
+type webhookPayload struct {
+	Event         domain.WebhookEvent `json:"event"`
+	OccurredAt    time.Time           `json:"occurred_at"`
+	Spreadsheet   webhookSpreadsheet  `json:"spreadsheet"`
+	Actor         *webhookUser        `json:"actor,omitempty"`
+	PreviousTitle string              `json:"previous_title,omitempty"` // spreadsheet.renamed
+	Share         *webhookShare       `json:"share,omitempty"`          // spreadsheet.shared
+}
+
+type webhookSpreadsheet struct {
+	ID          uint      `json:"id"`
+	Title       string    `json:"title"`
+	OwnerID     uint      `json:"owner_id"`
+	WorkspaceID uint      `json:"workspace_id"`
+	FolderID    *uint     `json:"folder_id,omitempty"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type webhookUser struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+type webhookShare struct {
+	User webhookUser `json:"user"`
+	Role domain.Role `json:"role"`
+}
+
+// EmitCreated reports a new spreadsheet.
+func (s *WebhookService) EmitCreated(ctx context.Context, sheet *domain.Spreadsheet, actorID uint) {
+	s.emit(ctx, sheet, actorID, webhookPayload{Event: domain.EventSpreadsheetCreated})
+}
+
+// EmitSaved reports new data in a spreadsheet.
+func (s *WebhookService) EmitSaved(ctx context.Context, sheet *domain.Spreadsheet, actorID uint) {
+	s.emit(ctx, sheet, actorID, webhookPayload{Event: domain.EventSpreadsheetSaved})
+}
+
+// EmitRenamed reports a spreadsheet's new title.
+func (s *WebhookService) EmitRenamed(ctx context.Context, sheet *domain.Spreadsheet, actorID uint, previousTitle string) {
+	s.emit(ctx, sheet, actorID, webhookPayload{Event: domain.EventSpreadsheetRenamed, PreviousTitle: previousTitle})
+}
+
+// EmitShared reports that a spreadsheet was shared with a user, or their
+// role changed.
+func (s *WebhookService) EmitShared(ctx context.Context, sheet *domain.Spreadsheet, actorID uint, grantee *domain.User, role domain.Role) {
+	s.emit(ctx, sheet, actorID, webhookPayload{
+		Event: domain.EventSpreadsheetShared,
+		Share: &webhookShare{User: webhookUser{ID: grantee.ID, Email: grantee.Email, Name: grantee.Name}, Role: role},
+	})
+}
+
+// EmitDeleted reports that a spreadsheet was moved to the trash.
+func (s *WebhookService) EmitDeleted(ctx context.Context, sheet *domain.Spreadsheet, actorID uint) {
+	s.emit(ctx, sheet, actorID, webhookPayload{Event: domain.EventSpreadsheetDeleted})
+}
+
+// emit queues a delivery of p to every active webhook of the spreadsheet
+// and its workspace that subscribes to the event. Failures are logged;
+// they never fail the action that caused the event.
+func (s *WebhookService) emit(ctx context.Context, sheet *domain.Spreadsheet, actorID uint, p webhookPayload) {
+	hooks, err := s.hooks.ListActive(ctx, sheet.ID, sheet.WorkspaceID)
+	if err != nil {
+		log.Printf("Failed to find webhooks of spreadsheet %d: %v", sheet.ID, err)
+		return
+	}
+	var targets []domain.Webhook
+	for _, h := range hooks {
+		if h.Subscribed(p.Event) {
+			targets = append(targets, h)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	now := time.Now()
+	p.OccurredAt = now.UTC()
+	p.Spreadsheet = webhookSpreadsheet{
+		ID:          sheet.ID,
+		Title:       sheet.Title,
+		OwnerID:     sheet.OwnerID,
+		WorkspaceID: sheet.WorkspaceID,
+		FolderID:    sheet.FolderID,
+		Version:     sheet.Version,
+		UpdatedAt:   sheet.UpdatedAt,
+	}
+	if actor, err := s.users.FindByID(ctx, actorID); err == nil {
+		p.Actor = &webhookUser{ID: actor.ID, Email: actor.Email, Name: actor.Name}
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		log.Printf("Failed to encode %s webhook payload: %v", p.Event, err)
+		return
+	}
+
+	for _, h := range targets {
+		d := &domain.WebhookDelivery{
+			WebhookID:     h.ID,
+			Event:         p.Event,
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+		if err := s.hooks.CreateDelivery(ctx, d); err != nil {
+			log.Printf("Failed to queue %s delivery for webhook %d: %v", p.Event, h.ID, err)
+		}
+	}
+}
+
+// ── Delivering ───────────────────────────────
+
+// Deliver posts the deliveries that are due, to up to webhookWorkers
+// webhooks at once. Each webhook gets its deliveries one at a time, oldest
+// first; after a failure the rest wait for the next run, so an endpoint
+// that is down costs one timeout per run. A failed delivery is retried
+// with exponential backoff; one webhook's failures don't hold up others.
+func (s *WebhookService) Deliver(ctx context.Context) error {
+	due, err := s.hooks.DueDeliveries(ctx, time.Now(), webhookBatch)
+	if err != nil {
+		return fmt.Errorf("load webhook deliveries: %w", err)
+	}
+
+	byHook := map[uint][]*domain.WebhookDelivery{}
+	var order []uint
+	for i := range due {
+		d := &due[i]
+		if _, ok := byHook[d.WebhookID]; !ok {
+			order = append(order, d.WebhookID)
+		}
+		byHook[d.WebhookID] = append(byHook[d.WebhookID], d)
+	}
+
+	queue := make(chan []*domain.WebhookDelivery)
+	errs := make([]error, len(order))
+	var wg sync.WaitGroup
+	for w := range min(webhookWorkers, len(order)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for deliveries := range queue {
+				if err := s.deliverInOrder(ctx, deliveries); err != nil {
+					errs[w] = errors.Join(errs[w], err)
+				}
+			}
+		}()
+	}
+	for _, id := range order {
+		deliveries := byHook[id]
+		slices.SortFunc(deliveries, func(a, b *domain.WebhookDelivery) int { return cmp.Compare(a.ID, b.ID) })
+		queue <- deliveries
+	}
+	close(queue)
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// deliverInOrder posts the due deliveries of one webhook, oldest first,
+// stopping at the first that fails.
+func (s *WebhookService) deliverInOrder(ctx context.Context, deliveries []*domain.WebhookDelivery) error {
+	hook, err := s.hooks.FindByID(ctx, deliveries[0].WebhookID)
+	if err != nil {
+		return nil // deleted since; its deliveries went with it
+	}
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			return nil
+		}
+		if hook.Active {
+			s.attempt(ctx, hook, d)
+		} else {
+			d.Status = domain.DeliveryFailed
+			d.Error = "webhook is disabled"
+			d.NextAttemptAt = nil
+		}
+		if err := s.hooks.SaveAttempt(ctx, d); err != nil {
+			return fmt.Errorf("update delivery %d: %w", d.ID, err)
+		}
+		if d.Status != domain.DeliveryDelivered && hook.Active {
+			return nil
+		}
+	}
+	return nil
+}
+
+// attempt posts a delivery once and records the outcome in d.
+func (s *WebhookService) attempt(ctx context.Context, hook *domain.Webhook, d *domain.WebhookDelivery) {
+	d.Attempts++
+	d.StatusCode, d.Error = 0, ""
+
+	err := s.post(ctx, hook, d)
+	now := time.Now()
+	switch {
+	case err == nil:
+		d.Status = domain.DeliveryDelivered
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+	case d.Attempts >= maxWebhookAttempts:
+		log.Printf("Giving up on delivery %d to webhook %d after %d attempts: %v", d.ID, hook.ID, d.Attempts, err)
+		d.Status = domain.DeliveryFailed
+		d.Error = err.Error()
+		d.NextAttemptAt = nil
+	default:
+		d.Error = err.Error()
+		next := now.Add(webhookBackoff(d.Attempts))
+		d.NextAttemptAt = &next
+	}
+}
+
+func (s *WebhookService) post(ctx context.Context, hook *domain.Webhook, d *domain.WebhookDelivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Jaggle-Grids-Webhooks")
+	req.Header.Set(webhookEventHeader, string(d.Event))
+	req.Header.Set(webhookDeliveryHeader, fmt.Sprint(d.ID))
+	req.Header.Set(webhookSignatureHeader, signPayload(hook.Secret, d.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Only the status is kept: the log must not become a way to read
+	// responses from wherever the URL points.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxWebhookDrain))
+	d.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+// signPayload returns the signature header value: the hex HMAC-SHA256 of
+// the body keyed with the webhook's secret.
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookBackoff is the wait after the given failed attempt: 30 seconds,
+// doubling each time up to webhookRetryMax.
+func webhookBackoff(attempts int) time.Duration {
+	d := webhookRetryBase
+	for i := 1; i < attempts && d < webhookRetryMax; i++ {
+		d *= 2
+	}
+	return min(d, webhookRetryMax)
+}
+
+// PurgeDeliveries deletes finished deliveries older than retention from
+// the delivery log.
+func (s *WebhookService) PurgeDeliveries(ctx context.Context, retention time.Duration) error {
+	n, err := s.hooks.DeleteDeliveriesBefore(ctx, time.Now().Add(-retention))
+	if err != nil {
+		return fmt.Errorf("purge webhook deliveries: %w", err)
+	}
+	if n > 0 {
+		log.Printf("Purged %d webhook deliveries", n)
+	}
+	return nil
+}
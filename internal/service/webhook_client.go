@@ -0,0 +1,124 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errBlockedAddress is returned when a webhook URL leads to an address
+// deliveries may not reach.
+var errBlockedAddress = errors.New("address is not allowed for webhooks")
+
+// blockedNetworks are the ranges webhooks can't be delivered to: loopback,
+// private, link-local (including cloud metadata endpoints), unspecified,
+// multicast and other special-purpose addresses. Without this any
+// spreadsheet owner could make the server send requests into its own
+// network.
+var blockedNetworks = mustPrefixes(
+	"0.0.0.0/8",      // "this network", includes the unspecified address
+	"10.0.0.0/8",     // private
+	"100.64.0.0/10",  // carrier-grade NAT
+	"127.0.0.0/8",    // loopback
+	"169.254.0.0/16", // link-local, cloud metadata
+	"172.16.0.0/12",  // private
+	"192.0.0.0/24",   // IETF protocol assignments
+	"192.168.0.0/16", // private
+	"198.18.0.0/15",  // benchmarking
+	"224.0.0.0/4",    // multicast
+	"240.0.0.0/4",    // reserved, broadcast
+	"::/128",         // unspecified
+	"::1/128",        // loopback
+	"64:ff9b::/96",   // NAT64, can embed any IPv4 address
+	"64:ff9b:1::/48", // local-use NAT64
+	"fc00::/7",       // unique local
+	"fe80::/10",      // link-local
+	"ff00::/8",       // multicast
+)
+
+func mustPrefixes(cidrs ...string) []netip.Prefix {
+	out := make([]netip.Prefix, len(cidrs))
+	for i, c := range cidrs {
+		out[i] = netip.MustParsePrefix(c)
+	}
+	return out
+}
+
+// ParseAllowedNetworks parses a comma-separated list of CIDR ranges that
+// webhooks may reach even though they are blocked by default.
+func ParseAllowedNetworks(list string) ([]netip.Prefix, error) {
+	var out []netip.Prefix
+	for _, c := range strings.Split(list, ",") {
+		c = strings.TrimSpace(c)
+		if c == "" {
+			continue
+		}
+		p, err := netip.ParsePrefix(c)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: expected CIDR notation such as 10.1.0.0/16", c)
+		}
+		out = append(out, p.Masked())
+	}
+	return out, nil
+}
+
+// webhookAddressAllowed reports whether deliveries may connect to ip.
+func webhookAddressAllowed(ip netip.Addr, allowed []netip.Prefix) bool {
+	ip = ip.Unmap()
+	for _, p := range allowed {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	for _, p := range blockedNetworks {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// newWebhookClient returns the HTTP client deliveries are sent with. The
+// address check runs when each connection is dialed, after DNS
+// resolution, so a name that resolves to a public address when the
+// webhook is saved and to a blocked one later (DNS rebinding) is still
+// refused. Proxies from the environment are ignored since they would
+// make the dialed address the proxy's.
+func newWebhookClient(allowed []netip.Prefix) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: %s", errBlockedAddress, address)
+			}
+			if !webhookAddressAllowed(ap.Addr(), allowed) {
+				return fmt.Errorf("%w: %s", errBlockedAddress, ap.Addr())
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   webhookTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   webhookTimeout,
+		// A redirect counts as a failed delivery rather than being
+		// followed with the signed payload.
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"jaggle-grids/internal/domain"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memHooks keeps webhooks and their deliveries in memory. Deliver saves
+// attempts from several goroutines, so it locks.
+type memHooks struct {
+	domain.WebhookRepository
+	mu         sync.Mutex
+	hooks      map[uint]*domain.Webhook
+	deliveries []domain.WebhookDelivery
+}
+
+func (r *memHooks) FindByID(_ context.Context, id uint) (*domain.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hooks[id]
+	if !ok {
+		return nil, errors.New("record not found")
+	}
+	hook := *h
+	return &hook, nil
+}
+
+func (r *memHooks) CreateDelivery(_ context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d.ID = uint(len(r.deliveries) + 1)
+	r.deliveries = append(r.deliveries, *d)
+	return nil
+}
+
+func (r *memHooks) FindDelivery(_ context.Context, webhookID, id uint) (*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, d := range r.deliveries {
+		if d.ID == id && d.WebhookID == webhookID {
+			return &d, nil
+		}
+	}
+	return nil, errors.New("record not found")
+}
+
+func (r *memHooks) DueDeliveries(_ context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var due []domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortFunc(due, func(a, b domain.WebhookDelivery) int { return a.NextAttemptAt.Compare(*b.NextAttemptAt) })
+	return due[:min(limit, len(due))], nil
+}
+
+func (r *memHooks) SaveAttempt(_ context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deliveries[d.ID-1] = *d
+	return nil
+}
+
+// delivery returns the stored state of a delivery.
+func (r *memHooks) delivery(id uint) domain.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deliveries[id-1]
+}
+
+// queue adds a pending delivery that was due at the given time.
+func (r *memHooks) queue(t *testing.T, hookID uint, due time.Time) uint {
+	t.Helper()
+	d := &domain.WebhookDelivery{
+		WebhookID:     hookID,
+		Event:         domain.EventSpreadsheetSaved,
+		Payload:       []byte(`{"event":"spreadsheet.saved"}`),
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: &due,
+	}
+	if err := r.CreateDelivery(context.Background(), d); err != nil {
+		t.Fatal(err)
+	}
+	return d.ID
+}
+
+// newTestWebhooks returns a service whose webhooks, on spreadsheet 7 owned
+// by user 1, post to the given servers, one webhook per server with IDs
+// from 1. The client may reach loopback addresses.
+func newTestWebhooks(t *testing.T, servers ...*httptest.Server) (*WebhookService, *memHooks) {
+	t.Helper()
+	repo := &memHooks{hooks: map[uint]*domain.Webhook{}}
+	sheet := uint(7)
+	for i, srv := range servers {
+		id := uint(i + 1)
+		repo.hooks[id] = &domain.Webhook{ID: id, SpreadsheetID: &sheet, URL: srv.URL, Secret: "s3cret", Active: true}
+	}
+	allowed, err := ParseAllowedNetworks("127.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &WebhookService{
+		hooks:       repo,
+		sheets:      oneSheet{},
+		permissions: oneSheetPermissions{},
+		workspaces:  noWorkspaces{},
+		client:      newWebhookClient(allowed),
+	}
+	return s, repo
+}
+
+func TestDeliverSignsPayloads(t *testing.T) {
+	var got struct {
+		event, delivery, signature string
+		body                       []byte
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.event = r.Header.Get(webhookEventHeader)
+		got.delivery = r.Header.Get(webhookDeliveryHeader)
+		got.signature = r.Header.Get(webhookSignatureHeader)
+		got.body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+	s, repo := newTestWebhooks(t, srv)
+	id := repo.queue(t, 1, time.Now())
+
+	if err := s.Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(got.body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); got.signature != want {
+		t.Errorf("signature = %q, want %q", got.signature, want)
+	}
+	if got.event != string(domain.EventSpreadsheetSaved) || got.delivery != strconv.Itoa(int(id)) {
+		t.Errorf("event %q, delivery %q", got.event, got.delivery)
+	}
+	if d := repo.delivery(id); d.Status != domain.DeliveryDelivered || d.Attempts != 1 || d.StatusCode != http.StatusOK || d.DeliveredAt == nil {
+		t.Errorf("delivery = %+v", d)
+	}
+}
+
+func TestDeliverBacksOff(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	s, repo := newTestWebhooks(t, srv)
+	id := repo.queue(t, 1, time.Now())
+
+	before := time.Now()
+	if err := s.Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	d := repo.delivery(id)
+	if d.Status != domain.DeliveryPending || d.Attempts != 1 || d.StatusCode != http.StatusBadGateway || d.Error == "" {
+		t.Fatalf("after a failure: %+v", d)
+	}
+	if wait := d.NextAttemptAt.Sub(before); wait < webhookRetryBase || wait > webhookRetryBase+time.Minute {
+		t.Errorf("retried after %v, want about %v", wait, webhookRetryBase)
+	}
+
+	// The last attempt gives up.
+	now := time.Now()
+	d.Attempts, d.NextAttemptAt = maxWebhookAttempts-1, &now
+	repo.SaveAttempt(context.Background(), &d)
+	if err := s.Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := repo.delivery(id); d.Status != domain.DeliveryFailed || d.NextAttemptAt != nil {
+		t.Errorf("after the last attempt: %+v", d)
+	}
+}
+
+func TestWebhookBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second,
+		2: time.Minute,
+		3: 2 * time.Minute,
+		7: 32 * time.Minute,
+		8: time.Hour,
+		9: time.Hour,
+	} {
+		if got := webhookBackoff(attempts); got != want {
+			t.Errorf("webhookBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+// orderedEndpoint records the deliveries it receives and how many were in
+// flight at once, failing the ones listed in fail.
+type orderedEndpoint struct {
+	mu          sync.Mutex
+	received    []uint
+	inFlight    int
+	maxInFlight int
+	fail        map[uint]bool
+}
+
+func (e *orderedEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(r.Header.Get(webhookDeliveryHeader))
+	e.mu.Lock()
+	e.received = append(e.received, uint(id))
+	e.inFlight++
+	e.maxInFlight = max(e.maxInFlight, e.inFlight)
+	e.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	e.mu.Lock()
+	e.inFlight--
+	failed := e.fail[uint(id)]
+	e.mu.Unlock()
+	if failed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+func TestDeliverKeepsOrderPerWebhook(t *testing.T) {
+	ok := &orderedEndpoint{}
+	down := &orderedEndpoint{fail: map[uint]bool{}}
+	okSrv, downSrv := httptest.NewServer(ok), httptest.NewServer(down)
+	defer okSrv.Close()
+	defer downSrv.Close()
+	s, repo := newTestWebhooks(t, okSrv, downSrv)
+
+	// A retry of an older delivery comes due after newer ones; it still
+	// goes first.
+	now := time.Now()
+	var okIDs, downIDs []uint
+	okIDs = append(okIDs, repo.queue(t, 1, now.Add(-time.Second)))
+	for range 4 {
+		okIDs = append(okIDs, repo.queue(t, 1, now.Add(-time.Minute)))
+		downIDs = append(downIDs, repo.queue(t, 2, now.Add(-time.Minute)))
+	}
+	down.fail[downIDs[1]] = true
+
+	if err := s.Deliver(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(ok.received, okIDs) || ok.maxInFlight != 1 {
+		t.Errorf("received %v with %d at once, want %v one at a time", ok.received, ok.maxInFlight, okIDs)
+	}
+	// The failure holds back the webhook's later deliveries until the
+	// next run.
+	if !slices.Equal(down.received, downIDs[:2]) {
+		t.Errorf("failing webhook received %v, want %v", down.received, downIDs[:2])
+	}
+	for _, id := range downIDs[2:] {
+		if d := repo.delivery(id); d.Status != domain.DeliveryPending || d.Attempts != 0 {
+			t.Errorf("delivery %d after an earlier failure: %+v", id, d)
+		}
+	}
+}
+
+func TestRedeliverSendsACopyWithTheCurrentSecret(t *testing.T) {
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signatures = append(signatures, r.Header.Get(webhookSignatureHeader))
+	}))
+	defer srv.Close()
+	s, repo := newTestWebhooks(t, srv)
+	ctx := context.Background()
+	orig := repo.queue(t, 1, time.Now())
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	const stranger = 5
+	if _, err := s.Redeliver(ctx, 1, stranger, orig); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Redeliver by a stranger: got %v, want ErrNotFound", err)
+	}
+	if _, err := s.Redeliver(ctx, 1, 1, orig+100); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Redeliver of an unknown delivery: got %v, want ErrNotFound", err)
+	}
+
+	repo.hooks[1].Secret = "rotated"
+	copied, err := s.Redeliver(ctx, 1, 1, orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.ID == orig || copied.RedeliveryOf == nil || *copied.RedeliveryOf != orig || copied.Status != domain.DeliveryPending {
+		t.Fatalf("Redeliver = %+v", copied)
+	}
+	if err := s.Deliver(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(signatures) != 2 || signatures[1] != signPayload("rotated", repo.delivery(orig).Payload) {
+		t.Errorf("signatures = %q, want the second made with the rotated secret", signatures)
+	}
+	if d := repo.delivery(copied.ID); d.Status != domain.DeliveryDelivered {
+		t.Errorf("redelivery = %+v", d)
+	}
+}
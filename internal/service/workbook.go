@@ -0,0 +1,35 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"jaggle-grids/internal/workbook"
+)
+
+// Workbook loads a spreadsheet the user can view and decodes its data.
+// Spreadsheets last saved as an editor snapshot can't be read by the
+// server and return ErrUnsupported.
+func (s *SpreadsheetService) Workbook(ctx context.Context, id, userID uint) (*domain.Spreadsheet, *workbook.Workbook, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleViewer)
+	if err != nil {
+		return nil, nil, err
+	}
+	wb, err := decodeWorkbook(sheet)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sheet, wb, nil
+}
+
+func decodeWorkbook(sheet *domain.Spreadsheet) (*workbook.Workbook, error) {
+	wb, err := workbook.Decode(sheet.Data)
+	if errors.Is(err, workbook.ErrOpaque) {
+		return nil, fmt.Errorf("%w: spreadsheet is stored in the editor's binary format and can only be read in the editor", domain.ErrUnsupported)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("spreadsheet %d: %w", sheet.ID, err)
+	}
+	return wb, nil
+}
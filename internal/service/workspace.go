@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+)
+
+// ListWorkspaces returns the user's personal workspace followed by the
+// workspaces of their organizations.
+func (s *OrganizationService) ListWorkspaces(ctx context.Context, userID uint) ([]domain.Workspace, error) {
+	personal, err := s.workspaces.FindOrCreatePersonal(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("find personal workspace: %w", err)
+	}
+	out := []domain.Workspace{*personal}
+
+	orgs, err := s.orgs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+	for _, org := range orgs {
+		workspaces, err := s.workspaces.ListByOrganization(ctx, org.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list workspaces: %w", err)
+		}
+		out = append(out, workspaces...)
+	}
+	return out, nil
+}
+
+// ListOrganizationWorkspaces returns an organization's workspaces.
+func (s *OrganizationService) ListOrganizationWorkspaces(ctx context.Context, orgID, userID uint) ([]domain.Workspace, error) {
+	if _, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgMember); err != nil {
+		return nil, err
+	}
+	workspaces, err := s.workspaces.ListByOrganization(ctx, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	return workspaces, nil
+}
+
+// CreateWorkspace adds a workspace to an organization. Admins only.
+func (s *OrganizationService) CreateWorkspace(ctx context.Context, orgID, userID uint, req domain.CreateWorkspaceRequest) (*domain.Workspace, error) {
+	if _, err := s.authorizeOrg(ctx, orgID, userID, domain.OrgAdmin); err != nil {
+		return nil, err
+	}
+	role := req.MemberRole
+	if role == "" {
+		role = domain.RoleEditor
+	}
+	if !role.Shareable() {
+		return nil, fmt.Errorf("%w: member role must be viewer, commenter or editor", domain.ErrInvalidInput)
+	}
+
+	ws := &domain.Workspace{Name: req.Name, OrganizationID: &orgID, MemberRole: role}
+	if err := s.workspaces.Create(ctx, ws); err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// UpdateWorkspace renames a workspace or changes its member role. Users
+// can rename their personal workspace; organization workspaces need an
+// admin.
+func (s *OrganizationService) UpdateWorkspace(ctx context.Context, id, userID uint, req domain.UpdateWorkspaceRequest) (*domain.Workspace, error) {
+	ws, err := s.authorizeWorkspace(ctx, id, userID, domain.OrgAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != "" {
+		ws.Name = req.Name
+	}
+	if req.MemberRole != "" {
+		if ws.Personal() {
+			return nil, fmt.Errorf("%w: personal workspaces have no members", domain.ErrInvalidInput)
+		}
+		if !req.MemberRole.Shareable() {
+			return nil, fmt.Errorf("%w: member role must be viewer, commenter or editor", domain.ErrInvalidInput)
+		}
+		ws.MemberRole = req.MemberRole
+	}
+
+	if err := s.workspaces.Update(ctx, ws); err != nil {
+		return nil, fmt.Errorf("update workspace: %w", err)
+	}
+	return ws, nil
+}
+
+// DeleteWorkspace removes an empty organization workspace. Admins only;
+// personal workspaces can't be deleted.
+func (s *OrganizationService) DeleteWorkspace(ctx context.Context, id, userID uint) error {
+	ws, err := s.authorizeWorkspace(ctx, id, userID, domain.OrgAdmin)
+	if err != nil {
+		return err
+	}
+	if ws.Personal() {
+		return fmt.Errorf("%w: personal workspaces can't be deleted", domain.ErrInvalidInput)
+	}
+
+	n, err := s.sheets.CountByWorkspace(ctx, id)
+	if err != nil {
+		return fmt.Errorf("count spreadsheets: %w", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("%w: workspace still has spreadsheets", domain.ErrConflict)
+	}
+	if err := s.workspaces.Delete(ctx, id); err != nil {
+		return fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ListWorkspaceSpreadsheets returns the spreadsheets in a workspace with
+// the caller's role on each. Explicit shares aren't considered, so a role
+// may be lower than what opening the spreadsheet grants.
+func (s *OrganizationService) ListWorkspaceSpreadsheets(ctx context.Context, id, userID uint) ([]domain.SpreadsheetListItem, error) {
+	ws, err := s.authorizeWorkspace(ctx, id, userID, domain.OrgMember)
+	if err != nil {
+		return nil, err
+	}
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, ws.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sheets, err := s.sheets.ListByWorkspace(ctx, ws.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list spreadsheets: %w", err)
+	}
+	return workspaceItems(sheets, role, userID), nil
+}
+
+// workspaceItems lists spreadsheets of a workspace where the user holds
+// role, except on those they own.
+func workspaceItems(sheets []domain.Spreadsheet, role domain.Role, userID uint) []domain.SpreadsheetListItem {
+	items := make([]domain.SpreadsheetListItem, len(sheets))
+	for i, sh := range sheets {
+		if sh.OwnerID == userID {
+			items[i] = toListItem(sh, domain.RoleOwner)
+		} else {
+			items[i] = toListItem(sh, role)
+		}
+	}
+	return items
+}
+
+// authorizeWorkspace loads a workspace the user belongs to: their personal
+// one, or one in an organization where they hold at least min. Everyone
+// else gets ErrNotFound.
+func (s *OrganizationService) authorizeWorkspace(ctx context.Context, id, userID uint, min domain.OrgRole) (*domain.Workspace, error) {
+	ws, err := s.workspaces.FindByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	if ws.Personal() {
+		if ws.OwnerID == nil || *ws.OwnerID != userID {
+			return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+		}
+		return ws, nil
+	}
+
+	member, err := s.orgs.FindMember(ctx, *ws.OrganizationID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	if !member.Role.Allows(min) {
+		return nil, fmt.Errorf("%w: %s role required", domain.ErrForbidden, min)
+	}
+	return ws, nil
+}
+
+// workspaceRole returns the role a workspace grants the user on its
+// spreadsheets: owner in their personal workspace and, in organization
+// workspaces, owner for admins and the workspace's member role for other
+// members. It returns "" when the workspace grants nothing, and an error
+// only when the lookup fails.
+func workspaceRole(ctx context.Context, workspaces domain.WorkspaceRepository, orgs domain.OrganizationRepository, id, userID uint) (domain.Role, error) {
+	ws, err := workspaces.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find workspace: %w", err)
+	}
+	if ws.Personal() {
+		if ws.OwnerID != nil && *ws.OwnerID == userID {
+			return domain.RoleOwner, nil
+		}
+		return "", nil
+	}
+
+	member, err := orgs.FindMember(ctx, *ws.OrganizationID, userID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("find organization member: %w", err)
+	}
+	if member.Role.Allows(domain.OrgAdmin) {
+		return domain.RoleOwner, nil
+	}
+	return ws.MemberRole, nil
+}
+
+// ── Spreadsheets ─────────────────────────────
+
+// Move puts a spreadsheet in another workspace. It needs the owner role
+// on the spreadsheet. A personal workspace only takes its own user's
+// spreadsheets; an organization workspace needs the editor role there and
+// only takes spreadsheets whose owner is a member. Spreadsheets in an
+// organization's workspaces belong to the team, so only its admins can
+// move one out of the organization.
+func (s *SpreadsheetService) Move(ctx context.Context, id, userID, workspaceID uint) (*domain.Spreadsheet, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleOwner)
+	if err != nil {
+		return nil, err
+	}
+	ws, err := s.workspaces.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+
+	if ws.Personal() {
+		switch *ws.OwnerID {
+		case sheet.OwnerID:
+		case userID:
+			return nil, fmt.Errorf("%w: a spreadsheet can only move to its owner's personal workspace", domain.ErrInvalidInput)
+		default:
+			return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+		}
+	} else {
+		if _, err := s.targetWorkspace(ctx, &workspaceID, userID); err != nil {
+			return nil, err
+		}
+		if _, err := s.orgs.FindMember(ctx, *ws.OrganizationID, sheet.OwnerID); err != nil {
+			return nil, fmt.Errorf("%w: the spreadsheet's owner is not a member of this organization", domain.ErrInvalidInput)
+		}
+	}
+	if ws.ID == sheet.WorkspaceID {
+		return sheet, nil
+	}
+	if err := s.checkLeaveOrganization(ctx, sheet, ws, userID); err != nil {
+		return nil, err
+	}
+
+	// Folders belong to a workspace, so the spreadsheet leaves its folder.
+	fields := map[string]any{"workspace_id": ws.ID, "folder_id": nil}
+	if err := s.sheets.Update(ctx, sheet, fields); err != nil {
+		return nil, fmt.Errorf("move spreadsheet: %w", err)
+	}
+	sheet.Path = nil
+	return sheet, nil
+}
+
+// checkLeaveOrganization checks that the user may move a spreadsheet to
+// ws when that takes it out of its organization.
+func (s *SpreadsheetService) checkLeaveOrganization(ctx context.Context, sheet *domain.Spreadsheet, ws *domain.Workspace, userID uint) error {
+	from, err := s.workspaces.FindByID(ctx, sheet.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("find workspace: %w", err)
+	}
+	if from.Personal() || (!ws.Personal() && *ws.OrganizationID == *from.OrganizationID) {
+		return nil
+	}
+	member, err := s.orgs.FindMember(ctx, *from.OrganizationID, userID)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("find organization member: %w", err)
+	}
+	if err != nil || !member.Role.Allows(domain.OrgAdmin) {
+		return fmt.Errorf("%w: only organization admins can move a spreadsheet out of the organization", domain.ErrForbidden)
+	}
+	return nil
+}
+
+// File puts a spreadsheet in a folder of its workspace, or at the
+// workspace's top level when folderID is nil. It needs the editor role on
+// the spreadsheet and in the workspace.
+func (s *SpreadsheetService) File(ctx context.Context, id, userID uint, folderID *uint) (*domain.Spreadsheet, error) {
+	sheet, err := s.authorize(ctx, id, userID, domain.RoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	wsRole, err := workspaceRole(ctx, s.workspaces, s.orgs, sheet.WorkspaceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !wsRole.Allows(domain.RoleEditor) {
+		return nil, fmt.Errorf("%w: editor role required in the spreadsheet's workspace", domain.ErrForbidden)
+	}
+	if folderID != nil {
+		folder, err := s.folders.FindByID(ctx, *folderID)
+		if err != nil || folder.WorkspaceID != sheet.WorkspaceID {
+			return nil, fmt.Errorf("folder %w", domain.ErrNotFound)
+		}
+	}
+
+	if err := s.sheets.Update(ctx, sheet, map[string]any{"folder_id": folderID}); err != nil {
+		return nil, fmt.Errorf("file spreadsheet: %w", err)
+	}
+	if err := s.setPath(ctx, sheet); err != nil {
+		return nil, err
+	}
+	return sheet, nil
+}
+
+// setPath fills in the breadcrumbs of a spreadsheet's folder.
+func (s *SpreadsheetService) setPath(ctx context.Context, sheet *domain.Spreadsheet) error {
+	sheet.Path = nil
+	if sheet.FolderID == nil {
+		return nil
+	}
+	folders, err := s.folders.ListByWorkspace(ctx, sheet.WorkspaceID)
+	if err != nil {
+		return fmt.Errorf("list folders: %w", err)
+	}
+	sheet.Path = newFolderTree(folders).path(*sheet.FolderID)
+	return nil
+}
+
+// targetWorkspace resolves the workspace a user puts a spreadsheet in:
+// workspaceID if they can edit there, or their personal workspace when it
+// is nil.
+func (s *SpreadsheetService) targetWorkspace(ctx context.Context, workspaceID *uint, userID uint) (*domain.Workspace, error) {
+	if workspaceID == nil {
+		ws, err := s.workspaces.FindOrCreatePersonal(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("find personal workspace: %w", err)
+		}
+		return ws, nil
+	}
+
+	ws, err := s.workspaces.FindByID(ctx, *workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	}
+	role, err := workspaceRole(ctx, s.workspaces, s.orgs, ws.ID, userID)
+	switch {
+	case err != nil:
+		return nil, err
+	case role == "":
+		return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+	case !role.Allows(domain.RoleEditor):
+		return nil, fmt.Errorf("%w: editor role required in this workspace", domain.ErrForbidden)
+	}
+	return ws, nil
+}
+
+// teamRoles maps the workspaces of the user's organizations to the role
+// each grants them, as workspaceRole would.
+func (s *SpreadsheetService) teamRoles(ctx context.Context, userID uint) (map[uint]domain.Role, error) {
+	orgs, err := s.orgs.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list organizations: %w", err)
+	}
+
+	roles := make(map[uint]domain.Role)
+	for _, org := range orgs {
+		workspaces, err := s.workspaces.ListByOrganization(ctx, org.ID)
+		if err != nil {
+			return nil, fmt.Errorf("list workspaces: %w", err)
+		}
+		for _, ws := range workspaces {
+			roles[ws.ID] = ws.MemberRole
+			if org.Role.Allows(domain.OrgAdmin) {
+				roles[ws.ID] = domain.RoleOwner
+			}
+		}
+	}
+	return roles, nil
+}
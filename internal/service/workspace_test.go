@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/domain"
+	"testing"
+)
+
+// The team fakes hold the personal workspaces of ada (1) and bob (2), two
+// workspaces of organization 100, where ada is an admin and bob a member,
+// and one of organization 200, where bob is a member too.
+type (
+	teamWorkspaces struct{ domain.WorkspaceRepository }
+	teamMembers    struct{ domain.OrganizationRepository }
+)
+
+const (
+	ada, bob, carol   = 1, 2, 3
+	adaPersonal       = 1
+	bobPersonal       = 2
+	teamViewers       = 10 // organization 100, members are viewers
+	teamEditors       = 11 // organization 100, members are editors
+	otherTeamsEditors = 20 // organization 200
+)
+
+func (teamWorkspaces) FindByID(_ context.Context, id uint) (*domain.Workspace, error) {
+	ref := func(id uint) *uint { return &id }
+	switch id {
+	case adaPersonal:
+		return &domain.Workspace{ID: id, OwnerID: ref(ada)}, nil
+	case bobPersonal:
+		return &domain.Workspace{ID: id, OwnerID: ref(bob)}, nil
+	case teamViewers:
+		return &domain.Workspace{ID: id, OrganizationID: ref(100), MemberRole: domain.RoleViewer}, nil
+	case teamEditors:
+		return &domain.Workspace{ID: id, OrganizationID: ref(100), MemberRole: domain.RoleEditor}, nil
+	case otherTeamsEditors:
+		return &domain.Workspace{ID: id, OrganizationID: ref(200), MemberRole: domain.RoleEditor}, nil
+	}
+	return nil, fmt.Errorf("workspace %w", domain.ErrNotFound)
+}
+
+func (teamMembers) FindMember(_ context.Context, orgID, userID uint) (*domain.OrganizationMember, error) {
+	switch {
+	case orgID == 100 && userID == ada:
+		return &domain.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: domain.OrgAdmin}, nil
+	case (orgID == 100 || orgID == 200) && userID == bob:
+		return &domain.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: domain.OrgMember}, nil
+	}
+	return nil, fmt.Errorf("member %w", domain.ErrNotFound)
+}
+
+// sharedWithCarol shares every spreadsheet with carol as an editor.
+type sharedWithCarol struct{ domain.PermissionRepository }
+
+func (sharedWithCarol) FindRole(_ context.Context, _, userID uint) (domain.Role, error) {
+	if userID == carol {
+		return domain.RoleEditor, nil
+	}
+	return "", fmt.Errorf("permission %w", domain.ErrNotFound)
+}
+
+// movableSheet is spreadsheet 7, owned by bob, in the workspace it was
+// last moved to.
+type movableSheet struct {
+	domain.SpreadsheetRepository
+	workspaceID uint
+}
+
+func (r *movableSheet) FindByID(_ context.Context, id uint) (*domain.Spreadsheet, error) {
+	if id != 7 {
+		return nil, errors.New("record not found")
+	}
+	return &domain.Spreadsheet{ID: 7, OwnerID: bob, WorkspaceID: r.workspaceID}, nil
+}
+
+func (r *movableSheet) Update(_ context.Context, _ *domain.Spreadsheet, fields map[string]any) error {
+	r.workspaceID = fields["workspace_id"].(uint)
+	return nil
+}
+
+func newTeamSheets(workspaceID uint) (*SpreadsheetService, *movableSheet) {
+	sheets := &movableSheet{workspaceID: workspaceID}
+	return &SpreadsheetService{
+		sheets:      sheets,
+		permissions: sharedWithCarol{},
+		workspaces:  teamWorkspaces{},
+		orgs:        teamMembers{},
+	}, sheets
+}
+
+func TestRoleOfInheritsWorkspaceRoles(t *testing.T) {
+	s, _ := newTeamSheets(0)
+	ctx := context.Background()
+	const dana = 4
+
+	tests := []struct {
+		name        string
+		workspaceID uint
+		ownerID     uint
+		userID      uint
+		want        domain.Role
+	}{
+		{"owner of the spreadsheet", teamViewers, dana, dana, domain.RoleOwner},
+		{"owner of the personal workspace", adaPersonal, dana, ada, domain.RoleOwner},
+		{"someone else's personal workspace", adaPersonal, dana, bob, ""},
+		{"organization admin", teamViewers, dana, ada, domain.RoleOwner},
+		{"member of a viewer workspace", teamViewers, dana, bob, domain.RoleViewer},
+		{"member of an editor workspace", teamEditors, dana, bob, domain.RoleEditor},
+		{"share above the workspace role", teamViewers, dana, carol, domain.RoleEditor},
+		{"admin of another organization", otherTeamsEditors, dana, ada, ""},
+		{"unknown workspace", 99, dana, bob, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sheet := &domain.Spreadsheet{ID: 7, OwnerID: tt.ownerID, WorkspaceID: tt.workspaceID}
+			role, err := s.RoleOf(ctx, sheet, tt.userID)
+			if tt.want == "" {
+				if !errors.Is(err, domain.ErrNotFound) {
+					t.Fatalf("got %q, %v; want ErrNotFound", role, err)
+				}
+				return
+			}
+			if err != nil || role != tt.want {
+				t.Fatalf("got %q, %v; want %q", role, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveOutOfOrganizationNeedsAdmin(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name     string
+		from, to uint
+		userID   uint
+		wantErr  error
+	}{
+		{"member to their personal workspace", teamEditors, bobPersonal, bob, domain.ErrForbidden},
+		{"member to another organization", teamEditors, otherTeamsEditors, bob, domain.ErrForbidden},
+		{"member within the organization", teamViewers, teamEditors, bob, nil},
+		{"member to a workspace they can't edit", teamEditors, teamViewers, bob, domain.ErrForbidden},
+		{"admin to the owner's personal workspace", teamEditors, bobPersonal, ada, nil},
+		{"admin to their own personal workspace", teamEditors, adaPersonal, ada, domain.ErrInvalidInput},
+		{"owner from personal to the organization", bobPersonal, teamEditors, bob, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, sheets := newTeamSheets(tt.from)
+			_, err := s.Move(ctx, 7, tt.userID, tt.to)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got %v, want %v", err, tt.wantErr)
+				}
+				if sheets.workspaceID != tt.from {
+					t.Fatal("the spreadsheet moved anyway")
+				}
+				return
+			}
+			if err != nil || sheets.workspaceID != tt.to {
+				t.Fatalf("got %v, in workspace %d; want it in %d", err, sheets.workspaceID, tt.to)
+			}
+		})
+	}
+}
+
+// leavingMembers is organization 100 as teamMembers has it, plus carol as
+// its owner, and records who RemoveMember hands spreadsheets to.
+type leavingMembers struct {
+	teamMembers
+	heirs map[uint]uint
+}
+
+func (m *leavingMembers) FindMember(ctx context.Context, orgID, userID uint) (*domain.OrganizationMember, error) {
+	if orgID == 100 && userID == carol {
+		return &domain.OrganizationMember{OrganizationID: orgID, UserID: userID, Role: domain.OrgOwner}, nil
+	}
+	return m.teamMembers.FindMember(ctx, orgID, userID)
+}
+
+func (m *leavingMembers) FindByID(_ context.Context, id uint) (*domain.Organization, error) {
+	return &domain.Organization{ID: id}, nil
+}
+
+func (m *leavingMembers) ListMembers(ctx context.Context, orgID uint) ([]domain.OrganizationMember, error) {
+	var members []domain.OrganizationMember
+	for _, id := range []uint{ada, bob, carol} {
+		if member, err := m.FindMember(ctx, orgID, id); err == nil {
+			members = append(members, *member)
+		}
+	}
+	return members, nil
+}
+
+func (m *leavingMembers) RemoveMember(_ context.Context, _, userID, heirID uint) error {
+	m.heirs[userID] = heirID
+	return nil
+}
+
+func TestRemoveMemberHandsSpreadsheetsOn(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		userID, target uint
+		wantHeir       uint
+		wantErr        error
+	}{
+		{"admin removes a member", ada, bob, ada, nil},
+		{"member leaves", bob, bob, carol, nil},
+		{"member removes another", bob, ada, 0, domain.ErrForbidden},
+		{"admin removes the owner", ada, carol, 0, domain.ErrForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			members := &leavingMembers{heirs: map[uint]uint{}}
+			s := NewOrganizationService(members, teamWorkspaces{}, nil, nil)
+			err := s.RemoveMember(ctx, 100, tt.userID, tt.target)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			heir, removed := members.heirs[tt.target]
+			if tt.wantErr != nil {
+				if removed {
+					t.Fatal("removed the member anyway")
+				}
+				return
+			}
+			if heir != tt.wantHeir {
+				t.Fatalf("spreadsheets passed to %d, want %d", heir, tt.wantHeir)
+			}
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package workbook
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Recalculation. Formulas are evaluated on demand: evaluating a cell that
+// refers to a formula which is still pending evaluates that formula first,
+// so no explicit ordering is needed. The dependency graph decides which
+// formulas are pending after an edit.
+//
+// Formulas calling functions the engine doesn't implement keep the value
+// they were stored with (e.g. calculated by Excel), and are #NAME? if they
+// have none.
+
+// RecalculateAll evaluates every formula in the workbook.
+func (wb *Workbook) RecalculateAll() {
+	c := newCalculation(wb)
+	for _, sh := range wb.Sheets {
+		for ref, cell := range sh.Cells {
+			if cell.Formula != "" {
+				c.pending[cellKey{sh, ref}] = true
+			}
+		}
+	}
+	c.run()
+}
+
+// Recalculate re-evaluates the formulas affected by a change to the given
+// cells of sh: formulas among those cells, every formula that depends on
+// them directly or indirectly, and volatile formulas such as NOW().
+func (wb *Workbook) Recalculate(sh *Sheet, changed []Ref) {
+	keys := make([]cellKey, len(changed))
+	for i, ref := range changed {
+		keys[i] = cellKey{sh, ref}
+	}
+	wb.recalculate(keys)
+}
+
+// RecalculateStale evaluates formulas without a stored value, as written
+// by tools that don't calculate, and everything that depends on them.
+func (wb *Workbook) RecalculateStale() {
+	var keys []cellKey
+	for _, sh := range wb.Sheets {
+		for ref, cell := range sh.Cells {
+			if cell.Formula != "" && cell.Value.IsEmpty() {
+				keys = append(keys, cellKey{sh, ref})
+			}
+		}
+	}
+	wb.recalculate(keys)
+}
+
+func (wb *Workbook) recalculate(changed []cellKey) {
+	c := newCalculation(wb)
+	g := c.graph()
+
+	queue := append(changed, g.volatile...)
+	seen := make(map[cellKey]bool, len(queue))
+	for len(queue) > 0 {
+		k := queue[0]
+		queue = queue[1:]
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		if cell := k.sheet.Cells[k.ref]; cell != nil && cell.Formula != "" {
+			c.pending[k] = true
+		}
+		queue = append(queue, g.dependents(k)...)
+	}
+	c.run()
+}
+
+type cellKey struct {
+	sheet *Sheet
+	ref   Ref
+}
+
+// calculation holds the state of one recalculation.
+type calculation struct {
+	wb      *Workbook
+	now     time.Time
+	parsed  map[string]*parsedFormula
+	names   map[string]expr
+	pending map[cellKey]bool
+	running map[cellKey]bool
+	used    map[*Sheet]Range
+	// nameDepth guards against defined names that refer to themselves.
+	nameDepth int
+}
+
+type parsedFormula struct {
+	x expr // nil if the formula doesn't parse
+	// opaque formulas call functions the engine doesn't implement.
+	opaque   bool
+	volatile bool
+}
+
+func newCalculation(wb *Workbook) *calculation {
+	return &calculation{
+		wb:      wb,
+		now:     time.Now().UTC(),
+		parsed:  map[string]*parsedFormula{},
+		pending: map[cellKey]bool{},
+		running: map[cellKey]bool{},
+		used:    map[*Sheet]Range{},
+	}
+}
+
+// run evaluates every pending formula, in a stable order.
+func (c *calculation) run() {
+	keys := make([]cellKey, 0, len(c.pending))
+	for k := range c.pending {
+		keys = append(keys, k)
+	}
+	order := make(map[*Sheet]int, len(c.wb.Sheets))
+	for i, sh := range c.wb.Sheets {
+		order[sh] = i
+	}
+	slices.SortFunc(keys, func(a, b cellKey) int {
+		return cmp.Or(
+			cmp.Compare(order[a.sheet], order[b.sheet]),
+			cmp.Compare(a.ref.Row, b.ref.Row),
+			cmp.Compare(a.ref.Col, b.ref.Col),
+		)
+	})
+	for _, k := range keys {
+		if cell := k.sheet.Cells[k.ref]; cell != nil {
+			c.cellValue(k.sheet, k.ref, cell)
+		}
+	}
+}
+
+// cellValue returns the value of a formula cell, evaluating it first if
+// it is pending. A cell that is reached again while it is being evaluated
+// is part of a circular reference and evaluates to #CALC!.
+func (c *calculation) cellValue(sh *Sheet, ref Ref, cell *Cell) Value {
+	k := cellKey{sh, ref}
+	if c.running[k] {
+		return Error(ErrCalc)
+	}
+	if !c.pending[k] {
+		return cell.Value
+	}
+	delete(c.pending, k)
+	c.running[k] = true
+	cell.Value = c.evaluate(sh, ref, cell)
+	delete(c.running, k)
+	return cell.Value
+}
+
+func (c *calculation) evaluate(sh *Sheet, ref Ref, cell *Cell) Value {
+	p := c.parse(cell.Formula)
+	if p.x == nil || p.opaque {
+		if !cell.Value.IsEmpty() {
+			return cell.Value
+		}
+		return Error(ErrName)
+	}
+
+	e := &evaluator{calc: c, sheet: sh, cell: ref}
+	v := e.deref(e.eval(p.x))
+	switch {
+	case v.IsEmpty():
+		// A formula referring to an empty cell shows 0.
+		return Number(0)
+	case v.Kind == KindNumber:
+		return numberResult(v.Num)
+	}
+	return v
+}
+
+func (c *calculation) parse(formula string) *parsedFormula {
+	if p, ok := c.parsed[formula]; ok {
+		return p
+	}
+	p := &parsedFormula{}
+	if x, err := parseFormula(formula); err == nil {
+		p.x = x
+		walkExpr(x, func(x expr) {
+			if call, ok := x.(callExpr); ok {
+				if _, known := functions[call.name]; !known {
+					p.opaque = true
+				}
+				p.volatile = p.volatile || volatileFunctions[call.name]
+			}
+		})
+	}
+	c.parsed[formula] = p
+	return p
+}
+
+// name returns the expression of a defined name, preferring one scoped to
+// sh over a global one. It returns nil for unknown names.
+func (c *calculation) name(sh *Sheet, name string) expr {
+	if c.names == nil {
+		c.names = map[string]expr{}
+		// Global names first, so that sheet-scoped ones replace them.
+		for _, global := range []bool{true, false} {
+			for _, dn := range c.wb.Names {
+				if (dn.Sheet == "") != global {
+					continue
+				}
+				x, err := parseFormula(dn.Ref)
+				if err != nil {
+					continue
+				}
+				c.names[strings.ToUpper(dn.Sheet)+"!"+strings.ToUpper(dn.Name)] = x
+			}
+		}
+	}
+	if x, ok := c.names[strings.ToUpper(sh.Name)+"!"+name]; ok {
+		return x
+	}
+	return c.names["!"+name]
+}
+
+// bounds returns the used range of a sheet from A1, cached for the
+// duration of the calculation (recalculating doesn't add cells).
+func (c *calculation) bounds(sh *Sheet) Range {
+	if r, ok := c.used[sh]; ok {
+		return r
+	}
+	r := Range{Start: Ref{Row: 1, Col: 1}, End: Ref{Row: 1, Col: 1}}
+	if b, ok := sh.Bounds(); ok {
+		r.End = b.End
+	}
+	c.used[sh] = r
+	return r
+}
+
+// ── Dependency graph ─────────────────────────
+
+// dependencyGraph maps cells to the formulas that read them.
+type dependencyGraph struct {
+	cells    map[cellKey][]cellKey
+	ranges   []rangeDependency
+	volatile []cellKey
+}
+
+type rangeDependency struct {
+	sheet     *Sheet
+	rng       Range
+	dependent cellKey
+}
+
+func (c *calculation) graph() *dependencyGraph {
+	g := &dependencyGraph{cells: map[cellKey][]cellKey{}}
+	for _, sh := range c.wb.Sheets {
+		for ref, cell := range sh.Cells {
+			if cell.Formula == "" {
+				continue
+			}
+			k := cellKey{sh, ref}
+			p := c.parse(cell.Formula)
+			if p.volatile {
+				g.volatile = append(g.volatile, k)
+			}
+			if p.x != nil {
+				c.addPrecedents(g, k, p.x, 0)
+			}
+		}
+	}
+	return g
+}
+
+func (c *calculation) addPrecedents(g *dependencyGraph, k cellKey, x expr, depth int) {
+	walkExpr(x, func(x expr) {
+		switch x := x.(type) {
+		case refExpr:
+			sh := k.sheet
+			if x.sheet != "" {
+				if sh = c.wb.Sheet(x.sheet); sh == nil {
+					return
+				}
+			}
+			if x.rng.Start == x.rng.End {
+				p := cellKey{sh, x.rng.Start}
+				g.cells[p] = append(g.cells[p], k)
+			} else {
+				g.ranges = append(g.ranges, rangeDependency{sheet: sh, rng: x.rng, dependent: k})
+			}
+		case nameExpr:
+			if n := c.name(k.sheet, string(x)); n != nil && depth < 32 {
+				c.addPrecedents(g, k, n, depth+1)
+			}
+		}
+	})
+}
+
+// dependents returns the formulas that read the cell k.
+func (g *dependencyGraph) dependents(k cellKey) []cellKey {
+	deps := g.cells[k]
+	for _, d := range g.ranges {
+		if d.sheet == k.sheet && d.rng.Contains(k.ref) {
+			deps = append(deps, d.dependent)
+		}
+	}
+	return deps
+}
@@ -0,0 +1,171 @@
+package workbook
+
+import "testing"
+
+// newCalcWorkbook returns a workbook with the given cells on Sheet1 and
+// Sheet2, calculated.
+func newCalcWorkbook(t *testing.T, sheet1, sheet2 map[string]any) *Workbook {
+	t.Helper()
+	wb := New()
+	setCells(t, wb.Sheets[0], sheet1)
+	setCells(t, wb.AddSheet("Sheet2"), sheet2)
+	wb.RecalculateAll()
+	return wb
+}
+
+// edit sets a constant on Sheet1 and recalculates what depends on it.
+func edit(t *testing.T, wb *Workbook, a1 string, v Value) {
+	t.Helper()
+	ref, err := ParseRef(a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh := wb.Sheets[0]
+	sh.Set(ref, &Cell{Value: v})
+	wb.Recalculate(sh, []Ref{ref})
+}
+
+func checkValues(t *testing.T, wb *Workbook, want map[string]Value) {
+	t.Helper()
+	for a1, v := range want {
+		if got := valueAt(t, wb, a1); got != v {
+			t.Errorf("%s = %#v, want %#v", a1, got, v)
+		}
+	}
+}
+
+func TestRecalculateFollowsDependencies(t *testing.T) {
+	wb := newCalcWorkbook(t, map[string]any{
+		"A1": 1, "A2": 2,
+		"B1": "=A1*2",       // direct
+		"B2": "=B1+1",       // indirect
+		"B3": "=SUM(A1:A3)", // range
+		"B4": "=Rate*10",    // defined name
+		"B5": "=Sheet2!A1",  // back from another sheet
+		"C1": "=A2*2",       // unrelated
+		"C3": `=IF(A2,"y")`, // unrelated, no references to A1
+		"D1": "=COUNTBLANK(A1:A3)",
+	}, map[string]any{
+		"A1": "=Sheet1!B1+100",
+	})
+	wb.Names = append(wb.Names, DefinedName{Name: "Rate", Ref: "Sheet1!$A$1"})
+	wb.RecalculateAll()
+	// A formula that isn't affected keeps whatever value it has, even a
+	// wrong one.
+	sh := wb.Sheets[0]
+	ref, _ := ParseRef("C2")
+	sh.Set(ref, &Cell{Formula: "=A2", Value: Number(99)})
+
+	edit(t, wb, "A1", Number(5))
+
+	checkValues(t, wb, map[string]Value{
+		"B1":        Number(10),
+		"B2":        Number(11),
+		"B3":        Number(7),
+		"B4":        Number(50),
+		"Sheet2!A1": Number(110),
+		"B5":        Number(110),
+		"C1":        Number(4),
+		"C2":        Number(99),
+		"C3":        String("y"),
+		"D1":        Number(1),
+	})
+
+	// Clearing a cell inside a range reaches formulas over the range.
+	edit(t, wb, "A2", Value{})
+	checkValues(t, wb, map[string]Value{
+		"B3": Number(5),
+		"C1": Number(0),
+		"C2": Number(0),
+		"D1": Number(2),
+	})
+}
+
+func TestRecalculateVolatile(t *testing.T) {
+	wb := newCalcWorkbook(t, map[string]any{"A1": 1, "B1": "=NOW()", "B2": "=B1>0"}, nil)
+	sh := wb.Sheets[0]
+	for _, a1 := range []string{"B1", "B2"} {
+		ref, _ := ParseRef(a1)
+		sh.Cells[ref].Value = Value{}
+	}
+
+	edit(t, wb, "A1", Number(2))
+
+	if v := valueAt(t, wb, "B1"); v.Kind != KindNumber || v.Num < 45000 {
+		t.Errorf("NOW() = %#v after an unrelated edit", v)
+	}
+	checkValues(t, wb, map[string]Value{"B2": Bool(true)})
+}
+
+func TestRecalculateStale(t *testing.T) {
+	wb := New()
+	sh := wb.Sheets[0]
+	setCells(t, sh, map[string]any{
+		"A1": 2,
+		"B1": "=A1*3",  // written without a value
+		"B2": "=B1+1",  // depends on it
+		"B3": "=A1+1",  // up to date
+		"B4": "=FOO()", // opaque, no value
+	})
+	set := func(a1 string, v Value) {
+		ref, _ := ParseRef(a1)
+		sh.Cells[ref].Value = v
+	}
+	set("B2", Number(-1))
+	set("B3", Number(42))
+	opaque := &Cell{Formula: "=_xlfn.SOMETHING(A1)", Value: String("from Excel")}
+	ref, _ := ParseRef("B5")
+	sh.Set(ref, opaque)
+
+	wb.RecalculateStale()
+
+	checkValues(t, wb, map[string]Value{
+		"B1": Number(6),
+		"B2": Number(7),
+		"B3": Number(42),
+		"B4": Error(ErrName),
+		"B5": String("from Excel"),
+	})
+
+	// Recalculating an opaque formula keeps the value it was stored with.
+	edit(t, wb, "A1", Number(3))
+	checkValues(t, wb, map[string]Value{"B3": Number(4), "B5": String("from Excel")})
+}
+
+func TestCircularReferences(t *testing.T) {
+	wb := newCalcWorkbook(t, map[string]any{
+		"A1": "=A1+1",            // itself
+		"B1": "=C1", "C1": "=B1", // two cells
+		"D1": "=SUM(D2:D3)", // through a range
+		"D2": 1, "D3": "=D1",
+		"E1": "=B1*2", // reads a cycle
+		"F1": "=Loop", // a name that refers to itself
+		"G1": `=IFERROR(A1,"caught")`,
+		"H1": "=Sheet2!A1", // across sheets
+	}, map[string]any{
+		"A1": "=Sheet1!H1",
+	})
+	wb.Names = append(wb.Names, DefinedName{Name: "Loop", Ref: "Loop+1"})
+	wb.RecalculateAll()
+
+	calc := Error(ErrCalc)
+	checkValues(t, wb, map[string]Value{
+		"A1":        calc,
+		"B1":        calc,
+		"C1":        calc,
+		"D1":        calc,
+		"D3":        calc,
+		"E1":        calc,
+		"F1":        calc,
+		"G1":        String("caught"),
+		"H1":        calc,
+		"Sheet2!A1": calc,
+	})
+
+	// Breaking the cycle recovers the cells on it.
+	ref, _ := ParseRef("C1")
+	sh := wb.Sheets[0]
+	sh.Set(ref, &Cell{Value: Number(4)})
+	wb.Recalculate(sh, []Ref{ref})
+	checkValues(t, wb, map[string]Value{"B1": Number(4), "E1": Number(8)})
+}
@@ -0,0 +1,72 @@
+package workbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Format identifies stored workbook JSON.
+const (
+	Format        = "grids/workbook"
+	FormatVersion = 1
+)
+
+// ErrOpaque is returned by Decode for data the server can't read: the
+// binary snapshot the browser editor saves.
+var ErrOpaque = errors.New("workbook is stored in the editor's binary format")
+
+type document struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	*Workbook
+}
+
+// IsWorkbook reports whether data holds workbook JSON, as opposed to an
+// editor snapshot or nothing.
+func IsWorkbook(data string) bool {
+	return strings.HasPrefix(strings.TrimSpace(data), "{")
+}
+
+// Decode parses Spreadsheet.Data. Empty data is an empty workbook.
+func Decode(data string) (*Workbook, error) {
+	if strings.TrimSpace(data) == "" {
+		return New(), nil
+	}
+	if !IsWorkbook(data) {
+		return nil, ErrOpaque
+	}
+
+	doc := document{Workbook: &Workbook{}}
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return nil, fmt.Errorf("decode workbook: %w", err)
+	}
+	if doc.Format != Format {
+		return nil, fmt.Errorf("decode workbook: unknown format %q", doc.Format)
+	}
+	if doc.Version > FormatVersion {
+		return nil, fmt.Errorf("decode workbook: unsupported version %d", doc.Version)
+	}
+
+	wb := doc.Workbook
+	if len(wb.Styles) == 0 {
+		wb.Styles = []Style{{}}
+	}
+	if len(wb.Sheets) == 0 {
+		wb.AddSheet("Sheet1")
+	}
+	for _, sh := range wb.Sheets {
+		sh.init()
+	}
+	return wb, nil
+}
+
+// Encode serialises a workbook for Spreadsheet.Data.
+func Encode(wb *Workbook) (string, error) {
+	b, err := json.Marshal(document{Format: Format, Version: FormatVersion, Workbook: wb})
+	if err != nil {
+		return "", fmt.Errorf("encode workbook: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,235 @@
+package workbook
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// CSV encodings accepted by WriteCSV.
+const (
+	EncodingUTF8        = "utf-8"
+	EncodingUTF8BOM     = "utf-8-bom" // what Excel needs to detect UTF-8
+	EncodingUTF16LE     = "utf-16le"  // with BOM
+	EncodingWindows1252 = "windows-1252"
+	EncodingLatin1      = "iso-8859-1"
+)
+
+// CSVOptions configures WriteCSV. The zero value writes comma-separated
+// UTF-8.
+type CSVOptions struct {
+	Comma    rune
+	Encoding string
+}
+
+func csvEncoding(name string) (encoding.Encoding, error) {
+	switch strings.ToLower(name) {
+	case "", EncodingUTF8, "utf8":
+		return encoding.Nop, nil
+	case EncodingUTF8BOM:
+		return unicode.UTF8BOM, nil
+	case EncodingUTF16LE, "utf-16":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	case EncodingWindows1252, "cp1252":
+		return charmap.Windows1252, nil
+	case EncodingLatin1, "latin1":
+		return charmap.ISO8859_1, nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", name)
+}
+
+// ValidCSVOptions reports whether WriteCSV accepts the options.
+func ValidCSVOptions(opts CSVOptions) error {
+	if opts.Comma != 0 && (opts.Comma == '"' || opts.Comma == '\r' || opts.Comma == '\n') {
+		return fmt.Errorf("invalid delimiter %q", opts.Comma)
+	}
+	_, err := csvEncoding(opts.Encoding)
+	return err
+}
+
+// WriteCSV writes the values of one sheet, from A1 to the last used cell.
+// Formula cells are written as their computed value. Characters the
+// encoding can't represent are replaced.
+func WriteCSV(w io.Writer, sh *Sheet, opts CSVOptions) error {
+	enc, err := csvEncoding(opts.Encoding)
+	if err != nil {
+		return err
+	}
+	out := encoding.ReplaceUnsupported(enc.NewEncoder()).Writer(w)
+
+	cw := csv.NewWriter(out)
+	if opts.Comma != 0 {
+		cw.Comma = opts.Comma
+	}
+	cw.UseCRLF = true
+
+	bounds, ok := sh.Bounds()
+	if ok {
+		record := make([]string, bounds.End.Col)
+		for row := 1; row <= bounds.End.Row; row++ {
+			for col := range record {
+				record[col] = sh.Value(Ref{Row: row, Col: col + 1}).Text()
+			}
+			if err := cw.Write(record); err != nil {
+				return fmt.Errorf("write csv: %w", err)
+			}
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	// The encoding transformer buffers; closing flushes it.
+	if c, ok := out.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("write csv: %w", err)
+		}
+	}
+	return nil
+}
+
+// csvDelimiters are the delimiters ReadCSV recognises, in order of
+// preference when several fit equally well.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// csvNumberPattern matches the numbers ReadCSV converts. Numbers with
+// leading zeros, such as postcodes, stay text.
+var csvNumberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// ReadCSV converts a CSV file into a workbook with one sheet. The encoding
+// is detected: UTF-8 or UTF-16 with a byte order mark, UTF-8 without one,
+// and Windows-1252 for anything else. So is the delimiter, which may be a
+// comma, semicolon, tab or pipe. Numbers, TRUE/FALSE and error codes
+// become values; everything else, including text starting with "=", is
+// kept as text.
+func ReadCSV(r io.Reader) (*Workbook, []Warning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read csv: %w", err)
+	}
+	text, warnings, err := decodeCSV(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cr := csv.NewReader(strings.NewReader(text))
+	cr.Comma = detectDelimiter(text)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+
+	wb := New()
+	sh := wb.Sheets[0]
+	cells := 0
+	for row := 1; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if row > MaxRows || len(record) > MaxCols {
+			return nil, nil, ErrTooLarge
+		}
+		for i, field := range record {
+			v := csvValue(field)
+			if v.IsEmpty() {
+				continue
+			}
+			if cells++; cells > MaxImportCells {
+				return nil, nil, ErrTooLarge
+			}
+			sh.Set(Ref{Row: row, Col: i + 1}, &Cell{Value: v})
+		}
+	}
+	if warnings == nil {
+		warnings = []Warning{}
+	}
+	return wb, warnings, nil
+}
+
+// decodeCSV converts the file to UTF-8, dropping any byte order mark.
+func decodeCSV(data []byte) (string, []Warning, error) {
+	var enc encoding.Encoding
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		return string(data[3:]), nil, nil
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		enc = unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		enc = unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case utf8.Valid(data):
+		return string(data), nil, nil
+	default:
+		text, err := charmap.Windows1252.NewDecoder().Bytes(data)
+		if err != nil {
+			return "", nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		return string(text), []Warning{{
+			Code:    WarnEncoding,
+			Message: "The file is not UTF-8 and was read as Windows-1252",
+		}}, nil
+	}
+	text, err := enc.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	return string(text), nil, nil
+}
+
+// detectDelimiter picks the delimiter that splits the first lines into
+// the same number of fields most often, preferring more fields. A file
+// with a single column is read as comma-separated.
+func detectDelimiter(text string) rune {
+	best, bestRows, bestFields := ',', 0, 1
+	for _, d := range csvDelimiters {
+		cr := csv.NewReader(strings.NewReader(text))
+		cr.Comma = d
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		fields, rows := 0, 0
+		for range 20 {
+			record, err := cr.Read()
+			if err != nil {
+				break
+			}
+			if fields == 0 {
+				fields = len(record)
+			}
+			if len(record) == fields {
+				rows++
+			}
+		}
+		if fields > 1 && (rows > bestRows || rows == bestRows && fields > bestFields) {
+			best, bestRows, bestFields = d, rows, fields
+		}
+	}
+	return best
+}
+
+func csvValue(field string) Value {
+	switch {
+	case field == "":
+		return Value{}
+	case csvNumberPattern.MatchString(field):
+		if n, err := strconv.ParseFloat(field, 64); err == nil {
+			return Number(n)
+		}
+	case strings.EqualFold(field, "TRUE"):
+		return Bool(true)
+	case strings.EqualFold(field, "FALSE"):
+		return Bool(false)
+	case IsErrorCode(field):
+		return Error(field)
+	}
+	return String(field)
+}
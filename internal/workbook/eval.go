@@ -0,0 +1,551 @@
+package workbook
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// formulaError aborts the evaluation of an expression with an Excel error
+// value. The coercion helpers raise it with fail; eval recovers it, so a
+// function can simply call e.number(arg) and let errors propagate.
+type formulaError string
+
+func fail(code string) {
+	panic(formulaError(code))
+}
+
+// operand is the result of evaluating an expression: a Value, an area of
+// cells or a matrix of values.
+type operand any
+
+// area is a range of cells on a sheet.
+type area struct {
+	sheet *Sheet
+	rng   Range
+}
+
+// matrix is an array of values, as produced by array constants and by
+// operators applied to ranges.
+type matrix [][]Value
+
+// evaluator evaluates the formula of one cell.
+type evaluator struct {
+	calc  *calculation
+	sheet *Sheet // the sheet of the formula, for unqualified references
+	cell  Ref    // the position of the formula, for ROW() and intersections
+}
+
+func (e *evaluator) eval(x expr) (result operand) {
+	defer func() {
+		if r := recover(); r != nil {
+			code, ok := r.(formulaError)
+			if !ok {
+				panic(r)
+			}
+			result = Error(string(code))
+		}
+	}()
+
+	switch x := x.(type) {
+	case numberExpr:
+		return Number(float64(x))
+	case stringExpr:
+		return String(string(x))
+	case boolExpr:
+		return Bool(bool(x))
+	case errorExpr:
+		return Error(string(x))
+	case missingExpr:
+		return Value{}
+	case arrayExpr:
+		return matrix(x)
+	case refExpr:
+		sh := e.sheet
+		if x.sheet != "" {
+			if sh = e.calc.wb.Sheet(x.sheet); sh == nil {
+				return Error(ErrRef)
+			}
+		}
+		return area{sheet: sh, rng: x.rng}
+	case nameExpr:
+		return e.name(string(x))
+	case callExpr:
+		return e.call(x)
+	case unaryExpr:
+		return e.unary(x)
+	case binaryExpr:
+		return e.binary(x)
+	}
+	return Error(ErrValue)
+}
+
+func (e *evaluator) name(name string) operand {
+	x := e.calc.name(e.sheet, name)
+	if x == nil {
+		return Error(ErrName)
+	}
+	// Names can refer to other names; stop at loops.
+	if e.calc.nameDepth > 32 {
+		return Error(ErrCalc)
+	}
+	e.calc.nameDepth++
+	defer func() { e.calc.nameDepth-- }()
+	return e.eval(x)
+}
+
+func (e *evaluator) call(x callExpr) operand {
+	fn, ok := functions[x.name]
+	if !ok {
+		return Error(ErrName)
+	}
+	if len(x.args) < fn.minArgs || (fn.maxArgs >= 0 && len(x.args) > fn.maxArgs) {
+		return Error(ErrValue)
+	}
+	return fn.eval(e, x.args)
+}
+
+// value returns the value of a cell, evaluating it first if it is a
+// formula that hasn't been calculated yet.
+func (e *evaluator) value(sh *Sheet, ref Ref) Value {
+	c := sh.Cells[ref]
+	if c == nil {
+		return Value{}
+	}
+	if c.Formula != "" {
+		return e.calc.cellValue(sh, ref, c)
+	}
+	return c.Value
+}
+
+// deref turns an operand into a single value. A range is reduced by
+// implicit intersection with the formula's row or column; a matrix gives
+// its first element.
+func (e *evaluator) deref(op operand) Value {
+	switch op := op.(type) {
+	case Value:
+		return op
+	case area:
+		r := op.rng
+		switch {
+		case r.Start == r.End:
+			return e.value(op.sheet, r.Start)
+		case r.Cols() == 1 && e.cell.Row >= r.Start.Row && e.cell.Row <= r.End.Row:
+			return e.value(op.sheet, Ref{Row: e.cell.Row, Col: r.Start.Col})
+		case r.Rows() == 1 && e.cell.Col >= r.Start.Col && e.cell.Col <= r.End.Col:
+			return e.value(op.sheet, Ref{Row: r.Start.Row, Col: e.cell.Col})
+		}
+		return Error(ErrValue)
+	case matrix:
+		if len(op) == 0 || len(op[0]) == 0 {
+			return Error(ErrValue)
+		}
+		return op[0][0]
+	}
+	return Error(ErrValue)
+}
+
+// scalar evaluates x to a single value, which may be an error.
+func (e *evaluator) scalar(x expr) Value {
+	return e.deref(e.eval(x))
+}
+
+// number, text and boolean evaluate x and coerce the result, failing on
+// errors and on values that can't be converted.
+func (e *evaluator) number(x expr) float64 {
+	return toNumber(e.scalar(x))
+}
+
+func (e *evaluator) text(x expr) string {
+	return toText(e.scalar(x))
+}
+
+func (e *evaluator) boolean(x expr) bool {
+	return toBool(e.scalar(x))
+}
+
+// optNumber evaluates an optional argument, returning def when it is
+// absent or omitted.
+func (e *evaluator) optNumber(args []expr, i int, def float64) float64 {
+	if i >= len(args) {
+		return def
+	}
+	if _, ok := args[i].(missingExpr); ok {
+		return def
+	}
+	return e.number(args[i])
+}
+
+func toNumber(v Value) float64 {
+	n, code := coerceNumber(v)
+	if code != "" {
+		fail(code)
+	}
+	return n
+}
+
+// coerceNumber converts a value to a number, returning an error code
+// instead when it can't.
+func coerceNumber(v Value) (float64, string) {
+	switch v.Kind {
+	case KindNumber:
+		return v.Num, ""
+	case KindBool:
+		if v.Bool {
+			return 1, ""
+		}
+		return 0, ""
+	case KindEmpty:
+		return 0, ""
+	case KindString:
+		if n, ok := parseNumberText(v.Str); ok {
+			return n, ""
+		}
+		return 0, ErrValue
+	}
+	return 0, v.Str
+}
+
+// parseNumberText converts text the way Excel does when it is used as a
+// number: plain numbers, thousands separators, percentages and dates.
+func parseNumberText(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	scale := 1.0
+	if t, ok := strings.CutSuffix(s, "%"); ok {
+		s, scale = strings.TrimSpace(t), 0.01
+	}
+	if n, err := strconv.ParseFloat(strings.ReplaceAll(s, ",", ""), 64); err == nil {
+		return n * scale, true
+	}
+	if scale == 1 {
+		if n, ok := parseDateText(s); ok {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+func toText(v Value) string {
+	s, code := coerceText(v)
+	if code != "" {
+		fail(code)
+	}
+	return s
+}
+
+func coerceText(v Value) (string, string) {
+	switch v.Kind {
+	case KindNumber:
+		return formatGeneral(v.Num), ""
+	case KindError:
+		return "", v.Str
+	}
+	return v.Text(), ""
+}
+
+func toBool(v Value) bool {
+	switch v.Kind {
+	case KindBool:
+		return v.Bool
+	case KindNumber:
+		return v.Num != 0
+	case KindEmpty:
+		return false
+	case KindString:
+		switch strings.ToUpper(v.Str) {
+		case "TRUE":
+			return true
+		case "FALSE":
+			return false
+		}
+		fail(ErrValue)
+	}
+	fail(v.Str)
+	return false
+}
+
+// formatGeneral renders a number like Excel's General format does when a
+// number is turned into text: at most 15 significant digits.
+func formatGeneral(n float64) string {
+	if n == 0 {
+		return "0"
+	}
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(n, 'g', 15, 64), 64)
+	if a := math.Abs(r); a >= 1e21 || a < 1e-9 {
+		return strconv.FormatFloat(r, 'E', -1, 64)
+	}
+	return strconv.FormatFloat(r, 'f', -1, 64)
+}
+
+// ── Operators ────────────────────────────────
+
+func (e *evaluator) unary(x unaryExpr) operand {
+	op := e.eval(x.x)
+	if isMulti(op) {
+		return mapMatrix(e.matrix(op), func(v Value) Value { return unaryOp(x.op, v) })
+	}
+	return unaryOp(x.op, e.deref(op))
+}
+
+func unaryOp(op string, v Value) Value {
+	if v.Kind == KindError {
+		return v
+	}
+	if op == "+" {
+		return v
+	}
+	n, code := coerceNumber(v)
+	if code != "" {
+		return Error(code)
+	}
+	if op == "%" {
+		return Number(n / 100)
+	}
+	return Number(-n)
+}
+
+func (e *evaluator) binary(x binaryExpr) operand {
+	l, r := e.eval(x.l), e.eval(x.r)
+	if isMulti(l) || isMulti(r) {
+		return broadcast(e.matrix(l), e.matrix(r), func(a, b Value) Value { return binaryOp(x.op, a, b) })
+	}
+	return binaryOp(x.op, e.deref(l), e.deref(r))
+}
+
+func binaryOp(op string, l, r Value) Value {
+	if l.Kind == KindError {
+		return l
+	}
+	if r.Kind == KindError {
+		return r
+	}
+
+	switch op {
+	case "&":
+		a, _ := coerceText(l)
+		b, _ := coerceText(r)
+		return String(a + b)
+	case "=":
+		return Bool(compareValues(l, r) == 0)
+	case "<>":
+		return Bool(compareValues(l, r) != 0)
+	case "<":
+		return Bool(compareValues(l, r) < 0)
+	case ">":
+		return Bool(compareValues(l, r) > 0)
+	case "<=":
+		return Bool(compareValues(l, r) <= 0)
+	case ">=":
+		return Bool(compareValues(l, r) >= 0)
+	}
+
+	a, code := coerceNumber(l)
+	if code != "" {
+		return Error(code)
+	}
+	b, code := coerceNumber(r)
+	if code != "" {
+		return Error(code)
+	}
+	var n float64
+	switch op {
+	case "+":
+		n = a + b
+	case "-":
+		n = a - b
+	case "*":
+		n = a * b
+	case "/":
+		if b == 0 {
+			return Error(ErrDiv0)
+		}
+		n = a / b
+	case "^":
+		if a == 0 && b == 0 {
+			return Error(ErrNum)
+		}
+		n = math.Pow(a, b)
+	}
+	return numberResult(n)
+}
+
+// numberResult turns NaN and infinities into #NUM!.
+func numberResult(n float64) Value {
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		return Error(ErrNum)
+	}
+	return Number(n)
+}
+
+// compareValues orders values like Excel: numbers before text before
+// booleans, text case-insensitively. An empty value compares as 0, "" or
+// FALSE depending on the other side.
+func compareValues(a, b Value) int {
+	if a.Kind == KindEmpty {
+		a = emptyLike(b)
+	}
+	if b.Kind == KindEmpty {
+		b = emptyLike(a)
+	}
+	if ra, rb := kindRank(a.Kind), kindRank(b.Kind); ra != rb {
+		return ra - rb
+	}
+	switch a.Kind {
+	case KindNumber:
+		switch {
+		case a.Num < b.Num:
+			return -1
+		case a.Num > b.Num:
+			return 1
+		}
+		return 0
+	case KindString:
+		return strings.Compare(strings.ToLower(a.Str), strings.ToLower(b.Str))
+	case KindBool:
+		switch {
+		case a.Bool == b.Bool:
+			return 0
+		case b.Bool:
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func emptyLike(v Value) Value {
+	switch v.Kind {
+	case KindString:
+		return String("")
+	case KindBool:
+		return Bool(false)
+	}
+	return Number(0)
+}
+
+func kindRank(k Kind) int {
+	switch k {
+	case KindNumber, KindEmpty:
+		return 0
+	case KindString:
+		return 1
+	case KindBool:
+		return 2
+	}
+	return 3
+}
+
+// ── Ranges and arrays ────────────────────────
+
+// isMulti reports whether an operand holds more than one value.
+func isMulti(op operand) bool {
+	switch op := op.(type) {
+	case area:
+		return op.rng.Start != op.rng.End
+	case matrix:
+		return len(op) > 1 || (len(op) == 1 && len(op[0]) > 1)
+	}
+	return false
+}
+
+// used clips an area to the used part of its sheet, so that whole
+// columns and rows only cover cells that have content.
+func (e *evaluator) used(a area) Range {
+	b := e.calc.bounds(a.sheet)
+	r := a.rng
+	r.End.Row = min(r.End.Row, max(b.End.Row, r.Start.Row))
+	r.End.Col = min(r.End.Col, max(b.End.Col, r.Start.Col))
+	return r
+}
+
+// matrix converts an operand to a matrix, reading the used part of areas.
+func (e *evaluator) matrix(op operand) matrix {
+	switch op := op.(type) {
+	case matrix:
+		return op
+	case area:
+		r := e.used(op)
+		m := make(matrix, r.Rows())
+		for i := range m {
+			m[i] = make([]Value, r.Cols())
+			for j := range m[i] {
+				m[i][j] = e.value(op.sheet, Ref{Row: r.Start.Row + i, Col: r.Start.Col + j})
+			}
+		}
+		return m
+	case Value:
+		return matrix{{op}}
+	}
+	return matrix{{Error(ErrValue)}}
+}
+
+// each calls fn for every value of an operand: each cell of the used part
+// of an area, each element of a matrix, or the value itself.
+func (e *evaluator) each(op operand, fn func(v Value, inRange bool)) {
+	switch op := op.(type) {
+	case area:
+		r := e.used(op)
+		for row := r.Start.Row; row <= r.End.Row; row++ {
+			for col := r.Start.Col; col <= r.End.Col; col++ {
+				fn(e.value(op.sheet, Ref{Row: row, Col: col}), true)
+			}
+		}
+	case matrix:
+		for _, row := range op {
+			for _, v := range row {
+				fn(v, true)
+			}
+		}
+	case Value:
+		fn(op, false)
+	}
+}
+
+func mapMatrix(m matrix, fn func(Value) Value) matrix {
+	out := make(matrix, len(m))
+	for i, row := range m {
+		out[i] = make([]Value, len(row))
+		for j, v := range row {
+			out[i][j] = fn(v)
+		}
+	}
+	return out
+}
+
+// broadcast applies fn element-wise. A single row, column or value is
+// repeated to match the other side; elements outside either side are #N/A.
+func broadcast(a, b matrix, fn func(a, b Value) Value) matrix {
+	rows := max(len(a), len(b))
+	cols := max(width(a), width(b))
+	out := make(matrix, rows)
+	for i := range out {
+		out[i] = make([]Value, cols)
+		for j := range out[i] {
+			out[i][j] = fn(element(a, i, j), element(b, i, j))
+		}
+	}
+	return out
+}
+
+func width(m matrix) int {
+	if len(m) == 0 {
+		return 0
+	}
+	return len(m[0])
+}
+
+func element(m matrix, i, j int) Value {
+	if len(m) == 1 {
+		i = 0
+	}
+	if width(m) == 1 {
+		j = 0
+	}
+	if i >= len(m) || j >= width(m) {
+		return Error(ErrNA)
+	}
+	return m[i][j]
+}
@@ -0,0 +1,209 @@
+package workbook
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// roundTrip writes wb with write and reads the result back with read.
+func roundTrip(t *testing.T, wb *Workbook, write func(io.Writer, *Workbook) error, read func(io.Reader) (*Workbook, []Warning, error)) *Workbook {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := write(&buf, wb); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	out, _, err := read(&buf)
+	if err != nil {
+		t.Fatalf("read back: %v", err)
+	}
+	return out
+}
+
+// sameValue compares values, allowing numbers to differ by rounding.
+func sameValue(a, b Value) bool {
+	if a.Kind == KindNumber && b.Kind == KindNumber {
+		return math.Abs(a.Num-b.Num) <= 1e-9*math.Max(1, math.Abs(a.Num))
+	}
+	return a == b
+}
+
+// checkSameWorkbook compares the sheets, cells, styles, merges and names
+// of two workbooks. Both are recalculated first, so a formula written
+// without its value still compares equal.
+func checkSameWorkbook(t *testing.T, got, want *Workbook) {
+	t.Helper()
+	got.RecalculateStale()
+	want.RecalculateStale()
+	if !slices.Equal(sheetNames(got), sheetNames(want)) {
+		t.Fatalf("sheets = %v, want %v", sheetNames(got), sheetNames(want))
+	}
+	for i, wsh := range want.Sheets {
+		gsh := got.Sheets[i]
+		if gsh.Hidden != wsh.Hidden {
+			t.Errorf("%s: hidden = %v", wsh.Name, gsh.Hidden)
+		}
+		if !slices.Equal(gsh.Merges, wsh.Merges) {
+			t.Errorf("%s: merges = %v, want %v", wsh.Name, gsh.Merges, wsh.Merges)
+		}
+		for ref, wc := range wsh.Cells {
+			gc := gsh.Cell(ref)
+			if gc == nil {
+				t.Errorf("%s!%s is missing", wsh.Name, ref)
+				continue
+			}
+			if gc.Formula != wc.Formula {
+				t.Errorf("%s!%s formula = %q, want %q", wsh.Name, ref, gc.Formula, wc.Formula)
+			}
+			if !sameValue(gc.Value, wc.Value) {
+				t.Errorf("%s!%s = %#v, want %#v", wsh.Name, ref, gc.Value, wc.Value)
+			}
+			if gs, ws := got.CellStyle(gc), want.CellStyle(wc); styleKey(gs) != styleKey(ws) {
+				t.Errorf("%s!%s style = %+v, want %+v", wsh.Name, ref, gs, ws)
+			}
+		}
+		for ref := range gsh.Cells {
+			if wsh.Cell(ref) == nil {
+				t.Errorf("%s!%s was added", wsh.Name, ref)
+			}
+		}
+	}
+	gotNames := slices.Clone(got.Names)
+	wantNames := slices.Clone(want.Names)
+	byName := func(a, b DefinedName) int { return strings.Compare(a.Name, b.Name) }
+	slices.SortFunc(gotNames, byName)
+	slices.SortFunc(wantNames, byName)
+	if !slices.Equal(gotNames, wantNames) {
+		t.Errorf("names = %+v, want %+v", gotNames, wantNames)
+	}
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	wb, _ := readFixture(t, "sheets.xlsx", ReadXLSX)
+	got := roundTrip(t, wb, WriteXLSX, ReadXLSX)
+	checkSameWorkbook(t, got, wb)
+
+	summary := got.Sheet("Summary")
+	if summary.FrozenRows != 1 || summary.ColWidths[1] != 145 || summary.RowHeights[1] != 40 {
+		t.Errorf("frozen rows %d, widths %v, heights %v", summary.FrozenRows, summary.ColWidths, summary.RowHeights)
+	}
+}
+
+func TestODSRoundTrip(t *testing.T) {
+	wb, _ := readFixture(t, "budget.ods", ReadODS)
+	got := roundTrip(t, wb, WriteODS, ReadODS)
+	checkSameWorkbook(t, got, wb)
+
+	budget := got.Sheet("Budget")
+	if budget.ColWidths[1] != 144 || budget.RowHeights[1] != 30 {
+		t.Errorf("widths %v, heights %v", budget.ColWidths, budget.RowHeights)
+	}
+}
+
+func TestCrossFormatRoundTrip(t *testing.T) {
+	xlsx, _ := readFixture(t, "sheets.xlsx", ReadXLSX)
+	t.Run("xlsx to ods", func(t *testing.T) {
+		checkSameWorkbook(t, roundTrip(t, xlsx, WriteODS, ReadODS), xlsx)
+	})
+	ods, _ := readFixture(t, "budget.ods", ReadODS)
+	t.Run("ods to xlsx", func(t *testing.T) {
+		checkSameWorkbook(t, roundTrip(t, ods, WriteXLSX, ReadXLSX), ods)
+	})
+}
+
+// TestRoundTripFormats exports a workbook edited in the app, with number
+// formats, error values and names of both scopes, and reads it back.
+func TestRoundTripFormats(t *testing.T) {
+	wb := New()
+	sh := wb.Sheets[0]
+	setCells(t, sh, map[string]any{
+		"A1": 45322.75,
+		"A2": 45322,
+		"A3": 0.5,
+		"A4": 1234.5,
+		"A5": 0.125,
+		"A6": 12345678,
+		"B1": Error(ErrNA), // typed in, not calculated
+		"B2": "=VLOOKUP(99,A1:A6,1,FALSE)",
+		"B3": "=Total*2",
+		"B4": "=SUM(Local)",
+		"C1": "=SUM(A1:A6)",
+	})
+	for a1, code := range map[string]string{
+		"A1": "yyyy-mm-dd hh:mm",
+		"A2": "dddd, d mmmm yy",
+		"A3": "h:mm AM/PM",
+		"A4": "#,##0.00",
+		"A5": "0.0%",
+		"A6": "0.00E+00",
+	} {
+		ref, _ := ParseRef(a1)
+		sh.Cells[ref].Style = wb.StyleID(Style{NumFmt: code})
+	}
+	sh.Merges = []Range{mustRange(t, "C1:D2")}
+	other := wb.AddSheet("Other sheet")
+	setCells(t, other, map[string]any{"A1": 1, "A2": 2})
+	wb.Names = []DefinedName{
+		{Name: "Total", Ref: "Sheet1!$C$1"},
+		{Name: "Local", Ref: "'Other sheet'!$A$1:$A$2", Sheet: "Other sheet"},
+	}
+	wb.RecalculateAll()
+
+	for name, tt := range map[string]struct {
+		write func(io.Writer, *Workbook) error
+		read  func(io.Reader) (*Workbook, []Warning, error)
+	}{
+		"xlsx": {WriteXLSX, ReadXLSX},
+		"ods":  {WriteODS, ReadODS},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := roundTrip(t, wb, tt.write, tt.read)
+			// An error constant comes back as a formula of the error.
+			got.RecalculateStale()
+			if c := got.Sheets[0].Cell(Ref{Row: 1, Col: 2}); c != nil && c.Formula == "=#N/A" {
+				c.Formula = ""
+			}
+			checkSameWorkbook(t, got, wb)
+		})
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	wb, _ := readFixture(t, "semicolon.csv", ReadCSV)
+	for _, opts := range []CSVOptions{
+		{},
+		{Comma: ';', Encoding: EncodingUTF8BOM},
+		{Comma: '\t', Encoding: EncodingUTF16LE},
+		{Comma: '|', Encoding: EncodingWindows1252},
+	} {
+		var buf bytes.Buffer
+		if err := WriteCSV(&buf, wb.Sheets[0], opts); err != nil {
+			t.Fatalf("%+v: %v", opts, err)
+		}
+		got, _, err := ReadCSV(&buf)
+		if err != nil {
+			t.Fatalf("%+v: read back: %v", opts, err)
+		}
+		checkSameWorkbook(t, got, wb)
+	}
+
+	// Formulas are exported as their values.
+	calc := New()
+	setCells(t, calc.Sheets[0], map[string]any{"A1": 2, "A2": "=A1*3", "A3": `="x"&A1`, "A4": "=1/0"})
+	calc.RecalculateAll()
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, calc.Sheets[0], CSVOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkValues(t, got, map[string]Value{"A2": Number(6), "A3": String("x2"), "A4": Error(ErrDiv0)})
+	if c := got.Sheets[0].Cell(Ref{Row: 2, Col: 1}); c.Formula != "" {
+		t.Errorf("CSV kept formula %q", c.Formula)
+	}
+}
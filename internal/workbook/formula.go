@@ -0,0 +1,397 @@
+package workbook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The formula syntax tree. Expressions are built by parseFormula from the
+// tokens of Tokenize and evaluated by an evaluator (see eval.go).
+type (
+	expr interface{}
+
+	numberExpr float64
+	stringExpr string
+	boolExpr   bool
+	errorExpr  string
+	// missingExpr is an omitted function argument, as in IF(A1,,1).
+	missingExpr struct{}
+	// refExpr is a cell or range; sheet is empty for the formula's own sheet.
+	refExpr struct {
+		sheet string
+		rng   Range
+	}
+	nameExpr string
+	callExpr struct {
+		name string // upper case, without the _xlfn. prefix
+		args []expr
+	}
+	// unaryExpr is a prefix + or -, or the postfix %.
+	unaryExpr struct {
+		op string
+		x  expr
+	}
+	binaryExpr struct {
+		op   string
+		l, r expr
+	}
+	// arrayExpr is an array constant such as {1,2;3,4}.
+	arrayExpr [][]Value
+)
+
+// ParseFormula checks that a formula is well-formed and only calls
+// functions with a valid number of arguments. Unknown functions are
+// allowed; they evaluate to #NAME? like in Excel.
+func ParseFormula(formula string) error {
+	x, err := parseFormula(formula)
+	if err != nil {
+		return err
+	}
+	return checkArity(x)
+}
+
+func parseFormula(formula string) (expr, error) {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{formula: formula}
+	for _, t := range tokens {
+		if t.Kind != TokSpace {
+			p.tokens = append(p.tokens, t)
+		}
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("formula %q is empty", formula)
+	}
+	x, err := p.comparison()
+	if err != nil {
+		return nil, err
+	}
+	if t, ok := p.peek(); ok {
+		return nil, p.errorf("unexpected %q", t.Text)
+	}
+	return x, nil
+}
+
+type parser struct {
+	formula string
+	tokens  []Token
+	pos     int
+}
+
+func (p *parser) peek() (Token, bool) {
+	if p.pos >= len(p.tokens) {
+		return Token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *parser) next() (Token, bool) {
+	t, ok := p.peek()
+	if ok {
+		p.pos++
+	}
+	return t, ok
+}
+
+// op consumes the next token if it is one of the given operators.
+func (p *parser) op(ops ...string) (string, bool) {
+	t, ok := p.peek()
+	if !ok || t.Kind != TokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.Text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("formula %q: %s", p.formula, fmt.Sprintf(format, args...))
+}
+
+// Operator precedence, lowest first: comparison, &, + -, * /, ^, prefix
+// + -, postfix %. Like Excel, -2^2 is 4.
+
+func (p *parser) comparison() (expr, error) {
+	return p.binary(p.concat, "=", "<>", "<", ">", "<=", ">=")
+}
+
+func (p *parser) concat() (expr, error) {
+	return p.binary(p.additive, "&")
+}
+
+func (p *parser) additive() (expr, error) {
+	return p.binary(p.multiplicative, "+", "-")
+}
+
+func (p *parser) multiplicative() (expr, error) {
+	return p.binary(p.power, "*", "/")
+}
+
+func (p *parser) power() (expr, error) {
+	return p.binary(p.unary, "^")
+}
+
+// binary parses a left-associative chain of operand (op operand)*.
+func (p *parser) binary(operand func() (expr, error), ops ...string) (expr, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.op(ops...)
+		if !ok {
+			return l, nil
+		}
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		l = binaryExpr{op: op, l: l, r: r}
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if op, ok := p.op("+", "-"); ok {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op: op, x: x}, nil
+	}
+	x, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if _, ok := p.op("%"); !ok {
+			return x, nil
+		}
+		x = unaryExpr{op: "%", x: x}
+	}
+}
+
+func (p *parser) primary() (expr, error) {
+	t, ok := p.next()
+	if !ok {
+		return nil, p.errorf("unexpected end of formula")
+	}
+	switch t.Kind {
+	case TokNumber:
+		n, err := strconv.ParseFloat(t.Text, 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", t.Text)
+		}
+		return numberExpr(n), nil
+	case TokString:
+		return stringExpr(unquoteString(t.Text)), nil
+	case TokBool:
+		return boolExpr(strings.EqualFold(t.Text, "TRUE")), nil
+	case TokError:
+		// Sheet!#REF! keeps only the error.
+		return errorExpr(strings.ToUpper(t.Text[strings.IndexByte(t.Text, '#'):])), nil
+	case TokRef:
+		rng, err := parseRefText(t.Ref)
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		return refExpr{sheet: t.Sheet, rng: rng}, nil
+	case TokName:
+		return nameExpr(strings.ToUpper(t.Text)), nil
+	case TokFunc:
+		return p.call(t.Text)
+	case TokLParen:
+		x, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		if t, ok := p.next(); !ok || t.Kind != TokRParen {
+			return nil, p.errorf("missing )")
+		}
+		return x, nil
+	case TokLBrace:
+		return p.array()
+	}
+	return nil, p.errorf("unexpected %q", t.Text)
+}
+
+func (p *parser) call(name string) (expr, error) {
+	call := callExpr{name: functionName(name)}
+	if t, ok := p.next(); !ok || t.Kind != TokLParen {
+		return nil, p.errorf("missing ( after %s", name)
+	}
+	if t, ok := p.peek(); ok && t.Kind == TokRParen {
+		p.pos++
+		return call, nil
+	}
+	for {
+		var arg expr = missingExpr{}
+		if t, ok := p.peek(); !ok || (t.Kind != TokComma && t.Kind != TokRParen) {
+			var err error
+			if arg, err = p.comparison(); err != nil {
+				return nil, err
+			}
+		}
+		call.args = append(call.args, arg)
+
+		t, ok := p.next()
+		switch {
+		case ok && t.Kind == TokRParen:
+			return call, nil
+		case ok && t.Kind == TokComma:
+			continue
+		}
+		return nil, p.errorf("missing ) after arguments of %s", name)
+	}
+}
+
+// array parses the rest of an array constant after "{". Columns are
+// separated by "," and rows by ";".
+func (p *parser) array() (expr, error) {
+	var rows arrayExpr
+	var row []Value
+	for {
+		v, err := p.arrayElement()
+		if err != nil {
+			return nil, err
+		}
+		row = append(row, v)
+
+		t, ok := p.next()
+		switch {
+		case ok && t.Kind == TokComma && t.Text == ",":
+			continue
+		case ok && t.Kind == TokComma: // ";"
+			rows = append(rows, row)
+			row = nil
+			continue
+		case ok && t.Kind == TokRBrace:
+			rows = append(rows, row)
+			for _, r := range rows {
+				if len(r) != len(rows[0]) {
+					return nil, p.errorf("array rows have different lengths")
+				}
+			}
+			return rows, nil
+		}
+		return nil, p.errorf("missing } after array")
+	}
+}
+
+func (p *parser) arrayElement() (Value, error) {
+	neg := false
+	if _, ok := p.op("-"); ok {
+		neg = true
+	}
+	t, ok := p.next()
+	if !ok {
+		return Value{}, p.errorf("unexpected end of formula")
+	}
+	switch {
+	case t.Kind == TokNumber:
+		n, err := strconv.ParseFloat(t.Text, 64)
+		if err != nil {
+			return Value{}, p.errorf("invalid number %q", t.Text)
+		}
+		if neg {
+			n = -n
+		}
+		return Number(n), nil
+	case neg:
+	case t.Kind == TokString:
+		return String(unquoteString(t.Text)), nil
+	case t.Kind == TokBool:
+		return Bool(strings.EqualFold(t.Text, "TRUE")), nil
+	case t.Kind == TokError:
+		return Error(strings.ToUpper(t.Text)), nil
+	}
+	return Value{}, p.errorf("arrays may only contain constants")
+}
+
+// unquoteString strips the quotes of a string literal and undoubles "".
+func unquoteString(s string) string {
+	return strings.ReplaceAll(s[1:len(s)-1], `""`, `"`)
+}
+
+// functionName normalises a function name. Files written by Excel prefix
+// newer functions with _xlfn. (and some with _xlws.).
+func functionName(name string) string {
+	name = strings.ToUpper(name)
+	name = strings.TrimPrefix(name, "_XLFN.")
+	return strings.TrimPrefix(name, "_XLWS.")
+}
+
+// parseRefText parses the reference part of a TokRef: a cell or range,
+// a whole column range ("C:E") or a whole row range ("3:5").
+func parseRefText(s string) (Range, error) {
+	s = strings.ReplaceAll(s, "$", "")
+	first, second, isRange := strings.Cut(s, ":")
+	if isRange && first != "" && isAllLetters(first) {
+		c1, err1 := ColumnNumber(first)
+		c2, err2 := ColumnNumber(second)
+		if err1 != nil || err2 != nil {
+			return Range{}, fmt.Errorf("invalid reference %q", s)
+		}
+		return Range{Start: Ref{Row: 1, Col: min(c1, c2)}, End: Ref{Row: MaxRows, Col: max(c1, c2)}}, nil
+	}
+	if isRange && first != "" && first[0] >= '0' && first[0] <= '9' {
+		r1, err1 := strconv.Atoi(first)
+		r2, err2 := strconv.Atoi(second)
+		if err1 != nil || err2 != nil || min(r1, r2) < 1 || max(r1, r2) > MaxRows {
+			return Range{}, fmt.Errorf("invalid reference %q", s)
+		}
+		return Range{Start: Ref{Row: min(r1, r2), Col: 1}, End: Ref{Row: max(r1, r2), Col: MaxCols}}, nil
+	}
+	return ParseRange(s)
+}
+
+func isAllLetters(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkArity reports calls to known functions with the wrong number of
+// arguments.
+func checkArity(x expr) error {
+	var err error
+	walkExpr(x, func(x expr) {
+		call, ok := x.(callExpr)
+		if !ok || err != nil {
+			return
+		}
+		fn, ok := functions[call.name]
+		if !ok {
+			return
+		}
+		if len(call.args) < fn.minArgs || (fn.maxArgs >= 0 && len(call.args) > fn.maxArgs) {
+			err = fmt.Errorf("wrong number of arguments to %s", call.name)
+		}
+	})
+	return err
+}
+
+// walkExpr calls visit for x and every expression inside it.
+func walkExpr(x expr, visit func(expr)) {
+	visit(x)
+	switch x := x.(type) {
+	case callExpr:
+		for _, arg := range x.args {
+			walkExpr(arg, visit)
+		}
+	case unaryExpr:
+		walkExpr(x.x, visit)
+	case binaryExpr:
+		walkExpr(x.l, visit)
+		walkExpr(x.r, visit)
+	}
+}
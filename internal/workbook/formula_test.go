@@ -0,0 +1,230 @@
+package workbook
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// setCells fills sh from a map of A1 references to contents: strings
+// starting with "=" are formulas, other strings, numbers, booleans and
+// Values are constants.
+func setCells(t *testing.T, sh *Sheet, cells map[string]any) {
+	t.Helper()
+	for a1, content := range cells {
+		ref, err := ParseRef(a1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &Cell{}
+		switch v := content.(type) {
+		case string:
+			if strings.HasPrefix(v, "=") {
+				c.Formula = v
+			} else {
+				c.Value = String(v)
+			}
+		case int:
+			c.Value = Number(float64(v))
+		case float64:
+			c.Value = Number(v)
+		case bool:
+			c.Value = Bool(v)
+		case Value:
+			c.Value = v
+		default:
+			t.Fatalf("%s: unsupported content %T", a1, content)
+		}
+		sh.Set(ref, c)
+	}
+}
+
+// valueAt returns the value of a cell given as "A1" or "Sheet!A1".
+func valueAt(t *testing.T, wb *Workbook, a1 string) Value {
+	t.Helper()
+	sh := wb.Sheets[0]
+	if name, cell, ok := strings.Cut(a1, "!"); ok {
+		if sh = wb.Sheet(name); sh == nil {
+			t.Fatalf("no sheet %q", name)
+		}
+		a1 = cell
+	}
+	ref, err := ParseRef(a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sh.Value(ref)
+}
+
+// fixture is the data the formula tests refer to.
+var fixture = map[string]any{
+	"A1": 10, "A2": 20, "A3": 30, "A4": "x", "A5": true,
+	"B1": "apple", "B2": "banana", "B3": "cherry",
+	"C1": 1, "C2": 2, "C3": 3,
+	"E1": "a", "E2": "b", "E3": "a",
+	"G1": "=1/0",
+}
+
+// evalFormula calculates formula in a cell next to the fixture data.
+func evalFormula(t *testing.T, formula string) Value {
+	t.Helper()
+	wb := New()
+	sh := wb.Sheets[0]
+	setCells(t, sh, fixture)
+	setCells(t, sh, map[string]any{"Z1": formula})
+	wb.RecalculateAll()
+	return valueAt(t, wb, "Z1")
+}
+
+type formulaCase struct {
+	formula string
+	want    Value
+}
+
+// checkFormulas evaluates each formula and compares it with its expected
+// value; numbers may differ by rounding.
+func checkFormulas(t *testing.T, cases []formulaCase) {
+	t.Helper()
+	for _, tt := range cases {
+		got := evalFormula(t, tt.formula)
+		if got.Kind == KindNumber && tt.want.Kind == KindNumber && math.Abs(got.Num-tt.want.Num) < 1e-9 {
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %#v, want %#v", tt.formula, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	formula := `=SUM('My Sheet'!$A$1:B2, C:C) & "x""y" <> #N/A`
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	var kinds []TokenKind
+	for _, tok := range tokens {
+		text.WriteString(tok.Text)
+		if tok.Kind != TokSpace {
+			kinds = append(kinds, tok.Kind)
+		}
+	}
+	if "="+text.String() != formula {
+		t.Errorf("tokens spell %q", text.String())
+	}
+	want := []TokenKind{TokFunc, TokLParen, TokRef, TokComma, TokRef, TokRParen, TokOp, TokString, TokOp, TokError}
+	if !equalKinds(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+	if ref := tokens[2]; ref.Sheet != "My Sheet" || ref.Ref != "$A$1:B2" {
+		t.Errorf("sheet reference = %+v", ref)
+	}
+
+	if _, err := Tokenize(`="open`); err == nil {
+		t.Error("unterminated string tokenized")
+	}
+}
+
+func equalKinds(a, b []TokenKind) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestParseFormula(t *testing.T) {
+	for _, formula := range []string{
+		"=1+2",
+		"=SUM(A1:B2, Sheet2!C3, 'My Sheet'!D:D, 3:5)",
+		"=IF(A1,,1)",
+		"={1,2;3,4}",
+		"=-A1%",
+		"=NOSUCHFUNCTION(1)", // evaluates to #NAME?
+		"=_xlfn.XLOOKUP(1,A1:A3,B1:B3)",
+	} {
+		if err := ParseFormula(formula); err != nil {
+			t.Errorf("ParseFormula(%q): %v", formula, err)
+		}
+	}
+	for _, formula := range []string{
+		"=",
+		"=1+",
+		"=(1+2",
+		"=1+2)",
+		"=SUM(1,2",
+		"=1 2",
+		"={1,2;3}",
+		"={A1}",
+		"=IF()",
+		"=ROUND(1)",
+		"=PI(1)",
+	} {
+		if err := ParseFormula(formula); err == nil {
+			t.Errorf("ParseFormula(%q) accepted it", formula)
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		// Precedence and associativity
+		{"=1+2*3", Number(7)},
+		{"=(1+2)*3", Number(9)},
+		{"=10-2-3", Number(5)},
+		{"=2^3^2", Number(64)},
+		{"=-2^2", Number(4)},
+		{"=2*50%", Number(1)},
+		{"=1+2=3", Bool(true)},
+		{`="a"&1+1`, String("a2")},
+		{`=1&2=12`, Bool(false)}, // "12" is text
+		// Coercion
+		{`="2"+1`, Number(3)},
+		{"=TRUE+1", Number(2)},
+		{"=A6+1", Number(1)},
+		{"=A6", Number(0)},
+		{`=A1&"%"`, String("10%")},
+		// Comparison
+		{`="abc"<"abd"`, Bool(true)},
+		{`="A"="a"`, Bool(true)},
+		{`=1<"a"`, Bool(true)},
+		{`="a"<TRUE`, Bool(true)},
+		{`=A6=""`, Bool(true)},
+		{"=A6=0", Bool(true)},
+		// Arrays and ranges
+		{"=SUM({1,2;3,4}*2)", Number(20)},
+		{"=SUM(A1:A3*C1:C3)", Number(140)},
+	})
+}
+
+func TestErrorValues(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{"=1/0", Error(ErrDiv0)},
+		{`="a"+1`, Error(ErrValue)},
+		{"=0^0", Error(ErrNum)},
+		{"=10^400", Error(ErrNum)},
+		{"=NOSUCHFUNCTION(1)", Error(ErrName)},
+		{"=nosuchname", Error(ErrName)},
+		{"=Nowhere!A1", Error(ErrRef)},
+		{"=#REF!+1", Error(ErrRef)},
+		{"=NA()", Error(ErrNA)},
+		{"=A2:A3", Error(ErrValue)}, // no intersection with row 1
+		// Errors propagate through operators and functions, leftmost first.
+		{"=G1*2", Error(ErrDiv0)},
+		{"=(1/0)+NA()", Error(ErrDiv0)},
+		{"=NA()+(1/0)", Error(ErrNA)},
+		{"=SUM(1,G1)", Error(ErrDiv0)},
+		{"=LEN(NA())", Error(ErrNA)},
+		{"=IF(G1,1,2)", Error(ErrDiv0)},
+		{"=-G1", Error(ErrDiv0)},
+		// ...unless a function handles them.
+		{`=IFERROR(G1,"ok")`, String("ok")},
+		{"=ISERROR(G1)", Bool(true)},
+		{"=IF(TRUE,1,G1)", Number(1)},
+	})
+}
@@ -0,0 +1,263 @@
+package workbook
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// Dates are serial numbers: whole days since 1899-12-31 (day 1 is
+// 1900-01-01) plus the time of day as a fraction. Like Excel, serial 60 is
+// the non-existent 1900-02-29, so serials before it are one day off from a
+// plain count; it is read as 1900-02-28. Times have no time zone; TODAY
+// and NOW use UTC.
+
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// maxSerial is 10000-01-01, the first date Excel doesn't support.
+const maxSerial = 2958466
+
+func init() {
+	register(map[string]function{
+		"DATE":        {3, 3, fnDate},
+		"TIME":        {3, 3, fnTime},
+		"YEAR":        {1, 1, datePart(func(t time.Time) int { return t.Year() })},
+		"MONTH":       {1, 1, datePart(func(t time.Time) int { return int(t.Month()) })},
+		"DAY":         {1, 1, datePart(func(t time.Time) int { return t.Day() })},
+		"HOUR":        {1, 1, datePart(func(t time.Time) int { return t.Hour() })},
+		"MINUTE":      {1, 1, datePart(func(t time.Time) int { return t.Minute() })},
+		"SECOND":      {1, 1, datePart(func(t time.Time) int { return t.Second() })},
+		"TODAY":       {0, 0, func(e *evaluator, _ []expr) operand { return Number(math.Floor(timeToSerial(e.calc.now))) }},
+		"NOW":         {0, 0, func(e *evaluator, _ []expr) operand { return Number(timeToSerial(e.calc.now)) }},
+		"WEEKDAY":     {1, 2, fnWeekday},
+		"EDATE":       {2, 2, monthFunc(false)},
+		"EOMONTH":     {2, 2, monthFunc(true)},
+		"DAYS":        {2, 2, fnDays},
+		"DATEDIF":     {3, 3, fnDateDif},
+		"DATEVALUE":   {1, 1, fnDateValue},
+		"TIMEVALUE":   {1, 1, fnTimeValue},
+		"NETWORKDAYS": {2, 3, fnNetworkDays},
+	})
+}
+
+// serialToTime converts a serial number to a time, rounded to the second.
+func serialToTime(n float64) time.Time {
+	if n < 0 || n >= maxSerial {
+		fail(ErrNum)
+	}
+	switch {
+	case n < 60:
+		n++
+	case n < 61:
+		// 1900-02-29 doesn't exist.
+		n = 60 + (n - math.Floor(n))
+	}
+	secs := int64(math.Round(n * 86400))
+	return time.Unix(excelEpoch.Unix()+secs, 0).UTC()
+}
+
+// timeToSerial converts a time to a serial number, ignoring its zone.
+func timeToSerial(t time.Time) float64 {
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	n := float64(t.Unix()-excelEpoch.Unix())/86400 + float64(t.Nanosecond())/86400e9
+	if n < 61 {
+		n--
+	}
+	return n
+}
+
+// date evaluates a date argument, truncated to whole days.
+func (e *evaluator) date(x expr) time.Time {
+	return serialToTime(math.Floor(e.number(x)))
+}
+
+func fnDate(e *evaluator, args []expr) operand {
+	y, m, d := int(e.number(args[0])), int(e.number(args[1])), int(e.number(args[2]))
+	if y < 1900 {
+		y += 1900
+	}
+	if y < 1900 || y > 9999 {
+		return Error(ErrNum)
+	}
+	// time.Date normalises months and days out of range the way Excel does.
+	n := timeToSerial(time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC))
+	if n < 0 || n >= maxSerial {
+		return Error(ErrNum)
+	}
+	return Number(n)
+}
+
+func fnTime(e *evaluator, args []expr) operand {
+	secs := math.Trunc(e.number(args[0]))*3600 + math.Trunc(e.number(args[1]))*60 + math.Trunc(e.number(args[2]))
+	if secs < 0 {
+		return Error(ErrNum)
+	}
+	return Number(math.Mod(secs, 86400) / 86400)
+}
+
+func datePart(fn func(time.Time) int) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		return Number(float64(fn(serialToTime(e.number(args[0])))))
+	}
+}
+
+func fnWeekday(e *evaluator, args []expr) operand {
+	day := int(e.date(args[0]).Weekday()) // Sunday is 0
+	switch e.optNumber(args, 1, 1) {
+	case 1:
+		return Number(float64(day + 1))
+	case 2:
+		return Number(float64((day+6)%7 + 1))
+	case 3:
+		return Number(float64((day + 6) % 7))
+	}
+	return Error(ErrNum)
+}
+
+// addMonths moves t by n months, keeping the day where the target month
+// has it and using the month's last day otherwise.
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(n), 1, 0, 0, 0, 0, time.UTC)
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(t.Day(), last)-1)
+}
+
+// monthFunc builds EDATE and EOMONTH.
+func monthFunc(endOfMonth bool) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		t := addMonths(e.date(args[0]), int(e.number(args[1])))
+		if endOfMonth {
+			t = time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, time.UTC)
+		}
+		n := timeToSerial(t)
+		if n < 0 || n >= maxSerial {
+			return Error(ErrNum)
+		}
+		return Number(n)
+	}
+}
+
+func fnDays(e *evaluator, args []expr) operand {
+	end, start := e.date(args[0]), e.date(args[1])
+	return Number(timeToSerial(end) - timeToSerial(start))
+}
+
+func fnDateDif(e *evaluator, args []expr) operand {
+	start, end := e.date(args[0]), e.date(args[1])
+	unit := strings.ToUpper(e.text(args[2]))
+	if start.After(end) {
+		return Error(ErrNum)
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+	if end.Day() < start.Day() {
+		months--
+	}
+	switch unit {
+	case "Y":
+		return Number(float64(months / 12))
+	case "M":
+		return Number(float64(months))
+	case "D":
+		return Number(timeToSerial(end) - timeToSerial(start))
+	case "YM":
+		return Number(float64(months % 12))
+	case "MD":
+		return Number(timeToSerial(end) - timeToSerial(addMonths(start, months)))
+	case "YD":
+		return Number(timeToSerial(end) - timeToSerial(addMonths(start, months/12*12)))
+	}
+	return Error(ErrNum)
+}
+
+func fnDateValue(e *evaluator, args []expr) operand {
+	n, ok := parseDateText(e.text(args[0]))
+	if !ok || n < 1 {
+		return Error(ErrValue)
+	}
+	return Number(math.Floor(n))
+}
+
+func fnTimeValue(e *evaluator, args []expr) operand {
+	n, ok := parseDateText(e.text(args[0]))
+	if !ok {
+		return Error(ErrValue)
+	}
+	return Number(n - math.Floor(n))
+}
+
+// fnNetworkDays counts the weekdays from start to end inclusive, skipping
+// holidays; the count is negative if end comes before start.
+func fnNetworkDays(e *evaluator, args []expr) operand {
+	start, end := math.Floor(e.number(args[0])), math.Floor(e.number(args[1]))
+	holidays := map[float64]bool{}
+	if len(args) > 2 && !isMissing(args[2]) {
+		for _, n := range e.numbers(args[2:]) {
+			holidays[math.Floor(n)] = true
+		}
+	}
+	sign := 1.0
+	if end < start {
+		start, end, sign = end, start, -1
+	}
+	if end-start > maxSerial {
+		return Error(ErrNum)
+	}
+	count := 0.0
+	for n := start; n <= end; n++ {
+		switch serialToTime(n).Weekday() {
+		case time.Saturday, time.Sunday:
+			continue
+		}
+		if !holidays[n] {
+			count++
+		}
+	}
+	return Number(sign * count)
+}
+
+// ── Parsing ──────────────────────────────────
+
+var (
+	dateLayouts = []string{
+		"2006-1-2", "2006/1/2", "1/2/2006", "1/2/06",
+		"2-Jan-2006", "2-Jan-06", "2 Jan 2006", "Jan 2, 2006", "Jan 2 2006",
+		"2 January 2006", "January 2, 2006", "January 2 2006",
+	}
+	timeLayouts = []string{
+		"15:04", "15:04:05", "3:04 PM", "3:04:05 PM", "3:04PM", "3:04:05PM", "3 PM", "3PM",
+	}
+)
+
+// parseDateText converts text such as "2024-03-01", "3/1/2024",
+// "1-Mar-2024 14:30" or "2:30 PM" to a serial number. Numeric dates are
+// read month first.
+func parseDateText(s string) (float64, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if len(s) < 3 || len(s) > 40 || !strings.ContainsAny(s, "0123456789") {
+		return 0, false
+	}
+	for _, tl := range timeLayouts {
+		if t, err := time.Parse(tl, s); err == nil {
+			return float64(t.Hour()*3600+t.Minute()*60+t.Second()) / 86400, true
+		}
+	}
+	for _, dl := range dateLayouts {
+		layouts := []string{dl}
+		if len(s) > len(dl) {
+			for _, tl := range timeLayouts {
+				layouts = append(layouts, dl+" "+tl)
+			}
+		}
+		for _, layout := range layouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				n := timeToSerial(t)
+				return n, n >= 1 && n < maxSerial
+			}
+		}
+	}
+	if t, err := time.Parse("2006-01-02T15:04:05", s); err == nil {
+		n := timeToSerial(t)
+		return n, n >= 1 && n < maxSerial
+	}
+	return 0, false
+}
@@ -0,0 +1,340 @@
+package workbook
+
+import "math"
+
+func init() {
+	register(map[string]function{
+		"VLOOKUP": {3, 4, lookupFunc(true)},
+		"HLOOKUP": {3, 4, lookupFunc(false)},
+		"XLOOKUP": {3, 6, fnXLookup},
+		"MATCH":   {2, 3, fnMatch},
+		"INDEX":   {2, 3, fnIndex},
+		"CHOOSE":  {2, -1, fnChoose},
+		"ROW":     {0, 1, position(func(r Ref) int { return r.Row })},
+		"COLUMN":  {0, 1, position(func(r Ref) int { return r.Col })},
+		"ROWS":    {1, 1, size(func(rows, _ int) int { return rows })},
+		"COLUMNS": {1, 1, size(func(_, cols int) int { return cols })},
+	})
+}
+
+// grid gives positional access to the values of an area or matrix. rows
+// and cols cover the used part of an area, fullRows and fullCols all of
+// it; at works anywhere in it.
+type grid struct {
+	e                  *evaluator
+	area               *area
+	m                  matrix
+	rows, cols         int
+	fullRows, fullCols int
+}
+
+func (e *evaluator) grid(op operand) grid {
+	switch op := op.(type) {
+	case area:
+		r := e.used(op)
+		return grid{e: e, area: &op, rows: r.Rows(), cols: r.Cols(), fullRows: op.rng.Rows(), fullCols: op.rng.Cols()}
+	case Value:
+		if op.Kind == KindError {
+			fail(op.Str)
+		}
+		return grid{m: matrix{{op}}, rows: 1, cols: 1, fullRows: 1, fullCols: 1}
+	case matrix:
+		return grid{m: op, rows: len(op), cols: width(op), fullRows: len(op), fullCols: width(op)}
+	}
+	fail(ErrValue)
+	return grid{}
+}
+
+func (g grid) at(i, j int) Value {
+	if g.area != nil {
+		return g.e.value(g.area.sheet, Ref{Row: g.area.rng.Start.Row + i, Col: g.area.rng.Start.Col + j})
+	}
+	if i < len(g.m) && j < len(g.m[i]) {
+		return g.m[i][j]
+	}
+	return Value{}
+}
+
+// vector returns the values of a one-row or one-column grid.
+func (g grid) vector() []Value {
+	if g.rows > 1 && g.cols > 1 {
+		fail(ErrNA)
+	}
+	n := max(g.rows, g.cols)
+	out := make([]Value, n)
+	for k := range out {
+		if g.rows > 1 {
+			out[k] = g.at(k, 0)
+		} else {
+			out[k] = g.at(0, k)
+		}
+	}
+	return out
+}
+
+// sub returns the cells of a grid starting at (i, j) as an operand.
+func (g grid) sub(i, j, rows, cols int) operand {
+	if g.area != nil {
+		start := Ref{Row: g.area.rng.Start.Row + i, Col: g.area.rng.Start.Col + j}
+		end := Ref{Row: start.Row + rows - 1, Col: start.Col + cols - 1}
+		return area{sheet: g.area.sheet, rng: Range{Start: start, End: end}}
+	}
+	out := make(matrix, rows)
+	for r := range out {
+		out[r] = make([]Value, cols)
+		for c := range out[r] {
+			out[r][c] = g.at(i+r, j+c)
+		}
+	}
+	return out
+}
+
+// ── Matching ─────────────────────────────────
+
+// lookupEqual reports whether v matches the lookup value; text matches
+// case-insensitively and, with wildcards, by pattern.
+func lookupEqual(v, want Value, wildcards bool) bool {
+	if wildcards && want.Kind == KindString && v.Kind == KindString {
+		return wildcardPattern(want.Str).MatchString(v.Str)
+	}
+	return v.Kind == want.Kind && compareValues(v, want) == 0
+}
+
+// findExact returns the index of the first (or, reversed, last) value
+// matching want, or -1.
+func findExact(values []Value, want Value, wildcards, reversed bool) int {
+	for k := range values {
+		if reversed {
+			k = len(values) - 1 - k
+		}
+		if lookupEqual(values[k], want, wildcards) {
+			return k
+		}
+	}
+	return -1
+}
+
+// findApprox returns the index of the largest value <= want (dir 1) or the
+// smallest value >= want (dir -1) among values of the same type, or -1.
+// Unlike Excel it doesn't require the values to be sorted.
+func findApprox(values []Value, want Value, dir int) int {
+	best := -1
+	for k, v := range values {
+		if kindRank(v.Kind) != kindRank(want.Kind) || v.IsEmpty() {
+			continue
+		}
+		d := compareValues(v, want) * dir
+		if d > 0 {
+			continue
+		}
+		if d == 0 {
+			return k
+		}
+		if best < 0 || compareValues(v, values[best])*dir > 0 {
+			best = k
+		}
+	}
+	return best
+}
+
+// ── Functions ────────────────────────────────
+
+// lookupFunc builds VLOOKUP (searching the first column) and HLOOKUP
+// (searching the first row).
+func lookupFunc(vertical bool) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		want := e.scalar(args[0])
+		if want.Kind == KindError {
+			return want
+		}
+		table := e.grid(e.eval(args[1]))
+		index := int(e.number(args[2]))
+		approx := len(args) < 4 || isMissing(args[3]) || e.boolean(args[3])
+
+		n, other := table.rows, table.fullCols
+		if !vertical {
+			n, other = table.cols, table.fullRows
+		}
+		if index < 1 {
+			return Error(ErrValue)
+		}
+		if index > other {
+			return Error(ErrRef)
+		}
+
+		keys := make([]Value, n)
+		for k := range keys {
+			if vertical {
+				keys[k] = table.at(k, 0)
+			} else {
+				keys[k] = table.at(0, k)
+			}
+		}
+		var k int
+		if approx {
+			k = findApprox(keys, want, 1)
+		} else {
+			k = findExact(keys, want, true, false)
+		}
+		if k < 0 {
+			return Error(ErrNA)
+		}
+		if vertical {
+			return table.at(k, index-1)
+		}
+		return table.at(index-1, k)
+	}
+}
+
+func fnMatch(e *evaluator, args []expr) operand {
+	want := e.scalar(args[0])
+	if want.Kind == KindError {
+		return want
+	}
+	values := e.grid(e.eval(args[1])).vector()
+	var k int
+	switch mode := e.optNumber(args, 2, 1); {
+	case mode == 0:
+		k = findExact(values, want, true, false)
+	case mode > 0:
+		k = findApprox(values, want, 1)
+	default:
+		k = findApprox(values, want, -1)
+	}
+	if k < 0 {
+		return Error(ErrNA)
+	}
+	return Number(float64(k + 1))
+}
+
+// fnXLookup supports match modes 0 (exact), -1 (exact or next smaller), 1
+// (exact or next larger) and 2 (wildcards), and searching first-to-last
+// or last-to-first. The binary search modes (±2) search linearly.
+func fnXLookup(e *evaluator, args []expr) operand {
+	want := e.scalar(args[0])
+	if want.Kind == KindError {
+		return want
+	}
+	lookup := e.grid(e.eval(args[1]))
+	keys := lookup.vector()
+	results := e.grid(e.eval(args[2]))
+	matchMode := int(e.optNumber(args, 4, 0))
+	searchMode := int(e.optNumber(args, 5, 1))
+	if searchMode == 0 || math.Abs(float64(searchMode)) > 2 {
+		return Error(ErrValue)
+	}
+	reversed := searchMode < 0
+
+	k := -1
+	switch matchMode {
+	case 0, 2:
+		k = findExact(keys, want, matchMode == 2, reversed)
+	case -1, 1:
+		if k = findExact(keys, want, false, reversed); k < 0 {
+			k = findApprox(keys, want, -matchMode)
+		}
+	default:
+		return Error(ErrValue)
+	}
+	if k < 0 {
+		if len(args) > 3 && !isMissing(args[3]) {
+			return e.eval(args[3])
+		}
+		return Error(ErrNA)
+	}
+
+	// Return the matching row (or column) of the result range.
+	if lookup.cols == 1 {
+		if results.fullRows < len(keys) {
+			return Error(ErrValue)
+		}
+		return results.sub(k, 0, 1, results.fullCols)
+	}
+	if results.fullCols < len(keys) {
+		return Error(ErrValue)
+	}
+	return results.sub(0, k, results.fullRows, 1)
+}
+
+// fnIndex returns a cell of a range or array; a row or column of 0
+// returns the whole column or row.
+func fnIndex(e *evaluator, args []expr) operand {
+	op := e.eval(args[0])
+	rows, cols := 1, 1
+	switch op := op.(type) {
+	case area:
+		rows, cols = op.rng.Rows(), op.rng.Cols()
+	case matrix:
+		rows, cols = len(op), width(op)
+	case Value:
+		if op.Kind == KindError {
+			return op
+		}
+	}
+	g := e.grid(op)
+
+	row := int(e.optNumber(args, 1, 0))
+	col := int(e.optNumber(args, 2, 0))
+	if len(args) < 3 && rows == 1 {
+		// INDEX(A1:E1, 3) picks a column of a single row.
+		row, col = 1, row
+	}
+	if row < 0 || col < 0 || row > rows || col > cols {
+		return Error(ErrRef)
+	}
+	switch {
+	case row == 0 && col == 0:
+		return op
+	case row == 0:
+		return g.sub(0, col-1, rows, 1)
+	case col == 0 && cols > 1:
+		return g.sub(row-1, 0, 1, cols)
+	case col == 0:
+		col = 1
+	}
+	return g.at(row-1, col-1)
+}
+
+func fnChoose(e *evaluator, args []expr) operand {
+	i := int(e.number(args[0]))
+	if i < 1 || i >= len(args) {
+		return Error(ErrValue)
+	}
+	return e.eval(args[i])
+}
+
+// position builds ROW and COLUMN, which default to the formula's cell.
+func position(fn func(Ref) int) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		if len(args) == 0 || isMissing(args[0]) {
+			return Number(float64(fn(e.cell)))
+		}
+		a, ok := e.eval(args[0]).(area)
+		if !ok {
+			return Error(ErrValue)
+		}
+		return Number(float64(fn(a.rng.Start)))
+	}
+}
+
+// size builds ROWS and COLUMNS.
+func size(fn func(rows, cols int) int) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		switch op := e.eval(args[0]).(type) {
+		case area:
+			return Number(float64(fn(op.rng.Rows(), op.rng.Cols())))
+		case matrix:
+			return Number(float64(fn(len(op), width(op))))
+		case Value:
+			if op.Kind == KindError {
+				return op
+			}
+		}
+		return Number(1)
+	}
+}
+
+func isMissing(x expr) bool {
+	_, ok := x.(missingExpr)
+	return ok
+}
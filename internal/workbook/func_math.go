@@ -0,0 +1,693 @@
+package workbook
+
+import (
+	"math"
+	"math/rand/v2"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// function is a built-in worksheet function. It receives its arguments
+// unevaluated, so that IF and friends only evaluate the branch they need
+// and lookups can work on ranges.
+type function struct {
+	minArgs int
+	maxArgs int // -1 for any number
+	eval    func(e *evaluator, args []expr) operand
+}
+
+// functions is filled by the init functions of the func_*.go files.
+var functions = map[string]function{}
+
+// volatileFunctions change value without any input changing.
+var volatileFunctions = map[string]bool{
+	"NOW": true, "TODAY": true, "RAND": true, "RANDBETWEEN": true,
+}
+
+func register(fns map[string]function) {
+	for name, fn := range fns {
+		functions[name] = fn
+	}
+}
+
+func init() {
+	register(map[string]function{
+		// Aggregates
+		"SUM":        {1, -1, fnSum},
+		"PRODUCT":    {1, -1, fnProduct},
+		"AVERAGE":    {1, -1, fnAverage},
+		"MIN":        {1, -1, fnMin},
+		"MAX":        {1, -1, fnMax},
+		"MEDIAN":     {1, -1, fnMedian},
+		"COUNT":      {1, -1, fnCount},
+		"COUNTA":     {1, -1, fnCountA},
+		"COUNTBLANK": {1, 1, fnCountBlank},
+		"SUMPRODUCT": {1, -1, fnSumProduct},
+		"COUNTIF":    {2, 2, fnCountIf},
+		"COUNTIFS":   {2, -1, fnCountIfs},
+		"SUMIF":      {2, 3, fnSumIf},
+		"SUMIFS":     {3, -1, ifsAggregate(sumValue)},
+		"AVERAGEIF":  {2, 3, fnAverageIf},
+		"AVERAGEIFS": {3, -1, ifsAggregate(aggAverage)},
+		"MINIFS":     {3, -1, ifsAggregate(aggMin)},
+		"MAXIFS":     {3, -1, ifsAggregate(aggMax)},
+
+		// Math
+		"ABS":         {1, 1, math1(math.Abs)},
+		"SIGN":        {1, 1, math1(sign)},
+		"INT":         {1, 1, math1(math.Floor)},
+		"SQRT":        {1, 1, math1(math.Sqrt)},
+		"EXP":         {1, 1, math1(math.Exp)},
+		"LN":          {1, 1, math1(logPositive(math.Log))},
+		"LOG10":       {1, 1, math1(logPositive(math.Log10))},
+		"LOG":         {1, 2, fnLog},
+		"POWER":       {2, 2, fnPower},
+		"MOD":         {2, 2, fnMod},
+		"ROUND":       {2, 2, roundFunc(math.Round)},
+		"ROUNDUP":     {2, 2, roundFunc(roundAway)},
+		"ROUNDDOWN":   {2, 2, roundFunc(math.Trunc)},
+		"TRUNC":       {1, 2, roundFunc(math.Trunc)},
+		"CEILING":     {1, 2, multipleFunc(math.Ceil)},
+		"FLOOR":       {1, 2, multipleFunc(math.Floor)},
+		"PI":          {0, 0, func(*evaluator, []expr) operand { return Number(math.Pi) }},
+		"RAND":        {0, 0, func(*evaluator, []expr) operand { return Number(rand.Float64()) }},
+		"RANDBETWEEN": {2, 2, fnRandBetween},
+
+		// Logical
+		"IF":      {1, 3, fnIf},
+		"IFS":     {2, -1, fnIfs},
+		"IFERROR": {2, 2, fnIfError},
+		"IFNA":    {2, 2, fnIfNA},
+		"AND":     {1, -1, logical(func(n, t int) bool { return t == n })},
+		"OR":      {1, -1, logical(func(_, t int) bool { return t > 0 })},
+		"XOR":     {1, -1, logical(func(_, t int) bool { return t%2 == 1 })},
+		"NOT":     {1, 1, func(e *evaluator, args []expr) operand { return Bool(!e.boolean(args[0])) }},
+		"TRUE":    {0, 0, func(*evaluator, []expr) operand { return Bool(true) }},
+		"FALSE":   {0, 0, func(*evaluator, []expr) operand { return Bool(false) }},
+		"SWITCH":  {3, -1, fnSwitch},
+
+		// Information
+		"ISBLANK":   {1, 1, is(func(v Value) bool { return v.IsEmpty() })},
+		"ISNUMBER":  {1, 1, is(func(v Value) bool { return v.Kind == KindNumber })},
+		"ISTEXT":    {1, 1, is(func(v Value) bool { return v.Kind == KindString })},
+		"ISNONTEXT": {1, 1, is(func(v Value) bool { return v.Kind != KindString })},
+		"ISLOGICAL": {1, 1, is(func(v Value) bool { return v.Kind == KindBool })},
+		"ISERROR":   {1, 1, is(func(v Value) bool { return v.Kind == KindError })},
+		"ISERR":     {1, 1, is(func(v Value) bool { return v.Kind == KindError && v.Str != ErrNA })},
+		"ISNA":      {1, 1, is(func(v Value) bool { return v.Kind == KindError && v.Str == ErrNA })},
+		"ISEVEN":    {1, 1, func(e *evaluator, args []expr) operand { return Bool(int64(e.number(args[0]))%2 == 0) }},
+		"ISODD":     {1, 1, func(e *evaluator, args []expr) operand { return Bool(int64(e.number(args[0]))%2 != 0) }},
+		"NA":        {0, 0, func(*evaluator, []expr) operand { return Error(ErrNA) }},
+		"N":         {1, 1, fnN},
+	})
+}
+
+// ── Aggregates ───────────────────────────────
+
+// numbers collects the numbers SUM and friends work on. Values typed
+// directly as arguments are converted (text "2" counts as 2); text,
+// booleans and empty cells inside ranges and arrays are skipped. Errors
+// anywhere abort with that error.
+func (e *evaluator) numbers(args []expr) []float64 {
+	var nums []float64
+	for _, arg := range args {
+		if _, ok := arg.(missingExpr); ok {
+			continue
+		}
+		e.each(e.eval(arg), func(v Value, inRange bool) {
+			switch {
+			case v.Kind == KindError:
+				fail(v.Str)
+			case v.Kind == KindNumber:
+				nums = append(nums, v.Num)
+			case !inRange:
+				nums = append(nums, toNumber(v))
+			}
+		})
+	}
+	return nums
+}
+
+func fnSum(e *evaluator, args []expr) operand {
+	return sumValue(e.numbers(args))
+}
+
+func fnProduct(e *evaluator, args []expr) operand {
+	p := 1.0
+	for _, n := range e.numbers(args) {
+		p *= n
+	}
+	return numberResult(p)
+}
+
+func fnAverage(e *evaluator, args []expr) operand {
+	return aggAverage(e.numbers(args))
+}
+
+func fnMin(e *evaluator, args []expr) operand {
+	return aggMin(e.numbers(args))
+}
+
+func fnMax(e *evaluator, args []expr) operand {
+	return aggMax(e.numbers(args))
+}
+
+func fnMedian(e *evaluator, args []expr) operand {
+	nums := e.numbers(args)
+	if len(nums) == 0 {
+		return Error(ErrNum)
+	}
+	slices.Sort(nums)
+	mid := len(nums) / 2
+	if len(nums)%2 == 1 {
+		return Number(nums[mid])
+	}
+	return Number((nums[mid-1] + nums[mid]) / 2)
+}
+
+func aggSum(nums []float64) float64 {
+	var s float64
+	for _, n := range nums {
+		s += n
+	}
+	return s
+}
+
+func sumValue(nums []float64) Value {
+	return numberResult(aggSum(nums))
+}
+
+func aggAverage(nums []float64) Value {
+	if len(nums) == 0 {
+		return Error(ErrDiv0)
+	}
+	return Number(aggSum(nums) / float64(len(nums)))
+}
+
+func aggMin(nums []float64) Value {
+	if len(nums) == 0 {
+		return Number(0)
+	}
+	return Number(slices.Min(nums))
+}
+
+func aggMax(nums []float64) Value {
+	if len(nums) == 0 {
+		return Number(0)
+	}
+	return Number(slices.Max(nums))
+}
+
+func fnCount(e *evaluator, args []expr) operand {
+	n := 0
+	for _, arg := range args {
+		e.each(e.eval(arg), func(v Value, inRange bool) {
+			if v.Kind == KindNumber {
+				n++
+			} else if _, code := coerceNumber(v); !inRange && code == "" && !v.IsEmpty() {
+				n++
+			}
+		})
+	}
+	return Number(float64(n))
+}
+
+func fnCountA(e *evaluator, args []expr) operand {
+	n := 0
+	for _, arg := range args {
+		if _, ok := arg.(missingExpr); ok {
+			continue
+		}
+		e.each(e.eval(arg), func(v Value, _ bool) {
+			if !v.IsEmpty() {
+				n++
+			}
+		})
+	}
+	return Number(float64(n))
+}
+
+func fnCountBlank(e *evaluator, args []expr) operand {
+	a, ok := e.eval(args[0]).(area)
+	if !ok {
+		return Error(ErrValue)
+	}
+	// Cells outside the used range are blank too.
+	r := a.rng
+	blank := r.Rows() * r.Cols()
+	e.each(a, func(v Value, _ bool) {
+		if !v.IsEmpty() && !(v.Kind == KindString && v.Str == "") {
+			blank--
+		}
+	})
+	return Number(float64(blank))
+}
+
+func fnSumProduct(e *evaluator, args []expr) operand {
+	var arrays []matrix
+	for _, arg := range args {
+		m := e.matrix(e.eval(arg))
+		if len(arrays) > 0 && (len(m) != len(arrays[0]) || width(m) != width(arrays[0])) {
+			return Error(ErrValue)
+		}
+		arrays = append(arrays, m)
+	}
+	var sum float64
+	for i := range arrays[0] {
+		for j := range arrays[0][i] {
+			p := 1.0
+			for _, m := range arrays {
+				v := m[i][j]
+				switch v.Kind {
+				case KindError:
+					return v
+				case KindNumber:
+					p *= v.Num
+				default:
+					p = 0
+				}
+			}
+			sum += p
+		}
+	}
+	return numberResult(sum)
+}
+
+// ── Conditional aggregates ───────────────────
+
+// criterion is a condition such as ">5", "<>done" or "a*" used by COUNTIF
+// and friends.
+type criterion struct {
+	op      string
+	value   Value
+	pattern *regexp.Regexp // for text with wildcards, compared with = or <>
+}
+
+func parseCriterion(v Value) criterion {
+	if v.Kind != KindString {
+		return criterion{op: "=", value: v}
+	}
+	s := v.Str
+	op := "="
+	for _, prefix := range []string{"<=", ">=", "<>", "=", "<", ">"} {
+		if rest, ok := strings.CutPrefix(s, prefix); ok {
+			op, s = prefix, rest
+			break
+		}
+	}
+
+	c := criterion{op: op}
+	switch {
+	case s == "":
+		c.value = Value{}
+	case strings.EqualFold(s, "TRUE") || strings.EqualFold(s, "FALSE"):
+		c.value = Bool(strings.EqualFold(s, "TRUE"))
+	case IsErrorCode(strings.ToUpper(s)):
+		c.value = Error(strings.ToUpper(s))
+	default:
+		if n, ok := parseNumberText(s); ok {
+			c.value = Number(n)
+		} else {
+			c.value = String(s)
+			if op == "=" || op == "<>" {
+				c.pattern = wildcardPattern(s)
+			}
+		}
+	}
+	return c
+}
+
+func (c criterion) match(v Value) bool {
+	if c.value.IsEmpty() {
+		empty := v.IsEmpty() || (v.Kind == KindString && v.Str == "")
+		if c.op == "<>" {
+			return !empty
+		}
+		return c.op == "=" && empty
+	}
+	if c.pattern != nil {
+		matched := v.Kind == KindString && c.pattern.MatchString(v.Str)
+		return matched == (c.op == "=")
+	}
+
+	same := v.Kind == c.value.Kind
+	if c.value.Kind == KindNumber && v.Kind == KindString {
+		// "=5" also matches the text "5".
+		if n, ok := parseNumberText(v.Str); ok && c.op == "=" {
+			return n == c.value.Num
+		}
+	}
+	if !same {
+		return c.op == "<>"
+	}
+	d := compareValues(v, c.value)
+	switch c.op {
+	case "=":
+		return d == 0
+	case "<>":
+		return d != 0
+	case "<":
+		return d < 0
+	case ">":
+		return d > 0
+	case "<=":
+		return d <= 0
+	}
+	return d >= 0
+}
+
+// wildcardPattern compiles Excel wildcards (* and ?, escaped with ~) into
+// a case-insensitive regexp matching the whole text.
+func wildcardPattern(s string) *regexp.Regexp {
+	return regexp.MustCompile("(?is)^" + wildcardExpr(s) + "$")
+}
+
+// wildcardExpr translates Excel wildcards into unanchored regexp syntax.
+func wildcardExpr(s string) string {
+	var b strings.Builder
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case r == '~' && i+1 < len(runes):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case r == '*':
+			b.WriteString(".*")
+		case r == '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// conditions evaluates (range, criterion) pairs and returns the shape of
+// the ranges and a predicate telling whether position (i, j) matches all
+// of them.
+func (e *evaluator) conditions(args []expr) (rows, cols int, match func(i, j int) bool) {
+	if len(args)%2 != 0 {
+		fail(ErrValue)
+	}
+	type condition struct {
+		g grid
+		c criterion
+	}
+	var conds []condition
+	for i := 0; i < len(args); i += 2 {
+		g := e.grid(e.eval(args[i]))
+		if len(conds) > 0 && (g.rows != conds[0].g.rows || g.cols != conds[0].g.cols) {
+			fail(ErrValue)
+		}
+		conds = append(conds, condition{g: g, c: parseCriterion(e.scalar(args[i+1]))})
+	}
+	return conds[0].g.rows, conds[0].g.cols, func(i, j int) bool {
+		for _, cond := range conds {
+			if !cond.c.match(cond.g.at(i, j)) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func fnCountIf(e *evaluator, args []expr) operand {
+	return fnCountIfs(e, args)
+}
+
+func fnCountIfs(e *evaluator, args []expr) operand {
+	rows, cols, match := e.conditions(args)
+	n := 0
+	for i := range rows {
+		for j := range cols {
+			if match(i, j) {
+				n++
+			}
+		}
+	}
+	// Blank cells beyond the used part of the sheet aren't counted.
+	return Number(float64(n))
+}
+
+// matchingNumbers returns the numbers of values at the positions where
+// match holds.
+func matchingNumbers(values grid, rows, cols int, match func(i, j int) bool) []float64 {
+	var nums []float64
+	for i := range rows {
+		for j := range cols {
+			if !match(i, j) {
+				continue
+			}
+			v := values.at(i, j)
+			if v.Kind == KindError {
+				fail(v.Str)
+			}
+			if v.Kind == KindNumber {
+				nums = append(nums, v.Num)
+			}
+		}
+	}
+	return nums
+}
+
+// ifValues evaluates the optional value range of SUMIF and AVERAGEIF,
+// which defaults to the criteria range.
+func (e *evaluator) ifValues(args []expr) grid {
+	if len(args) > 2 {
+		if _, ok := args[2].(missingExpr); !ok {
+			return e.grid(e.eval(args[2]))
+		}
+	}
+	return e.grid(e.eval(args[0]))
+}
+
+func fnSumIf(e *evaluator, args []expr) operand {
+	rows, cols, match := e.conditions(args[:2])
+	return sumValue(matchingNumbers(e.ifValues(args), rows, cols, match))
+}
+
+func fnAverageIf(e *evaluator, args []expr) operand {
+	rows, cols, match := e.conditions(args[:2])
+	return aggAverage(matchingNumbers(e.ifValues(args), rows, cols, match))
+}
+
+// ifsAggregate builds SUMIFS, AVERAGEIFS, MINIFS and MAXIFS, which take
+// the value range first and then (range, criterion) pairs.
+func ifsAggregate(agg func([]float64) Value) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		values := e.grid(e.eval(args[0]))
+		rows, cols, match := e.conditions(args[1:])
+		if values.rows != rows || values.cols != cols {
+			return Error(ErrValue)
+		}
+		return agg(matchingNumbers(values, rows, cols, match))
+	}
+}
+
+// ── Math ─────────────────────────────────────
+
+func math1(fn func(float64) float64) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		return numberResult(fn(e.number(args[0])))
+	}
+}
+
+func sign(n float64) float64 {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// logPositive makes logarithms of zero or negative numbers #NUM!.
+func logPositive(fn func(float64) float64) func(float64) float64 {
+	return func(n float64) float64 {
+		if n <= 0 {
+			return math.NaN()
+		}
+		return fn(n)
+	}
+}
+
+func fnLog(e *evaluator, args []expr) operand {
+	n := e.number(args[0])
+	base := e.optNumber(args, 1, 10)
+	if n <= 0 || base <= 0 || base == 1 {
+		return Error(ErrNum)
+	}
+	return Number(math.Log(n) / math.Log(base))
+}
+
+func fnPower(e *evaluator, args []expr) operand {
+	return binaryOp("^", Number(e.number(args[0])), Number(e.number(args[1])))
+}
+
+func fnMod(e *evaluator, args []expr) operand {
+	n, d := e.number(args[0]), e.number(args[1])
+	if d == 0 {
+		return Error(ErrDiv0)
+	}
+	// The result has the sign of the divisor.
+	return numberResult(n - d*math.Floor(n/d))
+}
+
+func roundAway(n float64) float64 {
+	if n < 0 {
+		return -math.Ceil(-n)
+	}
+	return math.Ceil(n)
+}
+
+// roundFunc builds ROUND, ROUNDUP, ROUNDDOWN and TRUNC; fn rounds to an
+// integer.
+func roundFunc(fn func(float64) float64) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		n := e.number(args[0])
+		digits := math.Trunc(e.optNumber(args, 1, 0))
+		p := math.Pow(10, digits)
+		return numberResult(fn(roundSignificant(n*p)) / p)
+	}
+}
+
+// multipleFunc builds CEILING and FLOOR, which round to a multiple of the
+// significance (default 1).
+func multipleFunc(fn func(float64) float64) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		n := e.number(args[0])
+		sig := e.optNumber(args, 1, 1)
+		switch {
+		case sig == 0:
+			return Number(0)
+		case n > 0 && sig < 0:
+			return Error(ErrNum)
+		}
+		return numberResult(fn(roundSignificant(n/sig)) * sig)
+	}
+}
+
+// roundSignificant rounds to 15 significant digits, the precision Excel
+// works with, so that 1.005*100 rounds like 100.5 rather than
+// 100.49999999999999.
+func roundSignificant(n float64) float64 {
+	if n == 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return n
+	}
+	r, _ := strconv.ParseFloat(strconv.FormatFloat(n, 'g', 15, 64), 64)
+	return r
+}
+
+func fnRandBetween(e *evaluator, args []expr) operand {
+	lo, hi := math.Ceil(e.number(args[0])), math.Floor(e.number(args[1]))
+	if lo > hi {
+		return Error(ErrNum)
+	}
+	return Number(lo + math.Floor(rand.Float64()*(hi-lo+1)))
+}
+
+// ── Logical ──────────────────────────────────
+
+func fnIf(e *evaluator, args []expr) operand {
+	if e.boolean(args[0]) {
+		if len(args) < 2 {
+			return Bool(true)
+		}
+		return e.eval(args[1])
+	}
+	if len(args) < 3 {
+		return Bool(false)
+	}
+	return e.eval(args[2])
+}
+
+func fnIfs(e *evaluator, args []expr) operand {
+	if len(args)%2 != 0 {
+		return Error(ErrValue)
+	}
+	for i := 0; i < len(args); i += 2 {
+		if e.boolean(args[i]) {
+			return e.eval(args[i+1])
+		}
+	}
+	return Error(ErrNA)
+}
+
+func fnIfError(e *evaluator, args []expr) operand {
+	op := e.eval(args[0])
+	if v := e.deref(op); v.Kind == KindError {
+		return e.eval(args[1])
+	}
+	return op
+}
+
+func fnIfNA(e *evaluator, args []expr) operand {
+	op := e.eval(args[0])
+	if v := e.deref(op); v.Kind == KindError && v.Str == ErrNA {
+		return e.eval(args[1])
+	}
+	return op
+}
+
+// logical builds AND, OR and XOR from a rule on the number of values and
+// how many of them are true. Text in ranges is ignored.
+func logical(rule func(n, trues int) bool) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		n, trues := 0, 0
+		for _, arg := range args {
+			e.each(e.eval(arg), func(v Value, inRange bool) {
+				if v.IsEmpty() || (inRange && v.Kind == KindString) {
+					return
+				}
+				n++
+				if toBool(v) {
+					trues++
+				}
+			})
+		}
+		if n == 0 {
+			return Error(ErrValue)
+		}
+		return Bool(rule(n, trues))
+	}
+}
+
+func fnSwitch(e *evaluator, args []expr) operand {
+	v := e.scalar(args[0])
+	if v.Kind == KindError {
+		return v
+	}
+	i := 1
+	for ; i+1 < len(args); i += 2 {
+		if compareValues(v, e.scalar(args[i])) == 0 {
+			return e.eval(args[i+1])
+		}
+	}
+	if i < len(args) {
+		return e.eval(args[i])
+	}
+	return Error(ErrNA)
+}
+
+// ── Information ──────────────────────────────
+
+func is(pred func(Value) bool) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		return Bool(pred(e.scalar(args[0])))
+	}
+}
+
+func fnN(e *evaluator, args []expr) operand {
+	switch v := e.scalar(args[0]); v.Kind {
+	case KindNumber:
+		return v
+	case KindBool:
+		return Number(toNumber(v))
+	case KindError:
+		return v
+	}
+	return Number(0)
+}
@@ -0,0 +1,260 @@
+package workbook
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+func init() {
+	register(map[string]function{
+		"CONCATENATE": {1, -1, fnConcatenate},
+		"CONCAT":      {1, -1, fnConcat},
+		"TEXTJOIN":    {3, -1, fnTextJoin},
+		"LEFT":        {1, 2, fnLeft},
+		"RIGHT":       {1, 2, fnRight},
+		"MID":         {3, 3, fnMid},
+		"LEN":         {1, 1, fnLen},
+		"LOWER":       {1, 1, text1(strings.ToLower)},
+		"UPPER":       {1, 1, text1(strings.ToUpper)},
+		"PROPER":      {1, 1, text1(properCase)},
+		"TRIM":        {1, 1, text1(func(s string) string { return strings.Join(strings.Fields(s), " ") })},
+		"SUBSTITUTE":  {3, 4, fnSubstitute},
+		"REPLACE":     {4, 4, fnReplace},
+		"FIND":        {2, 3, findFunc(false)},
+		"SEARCH":      {2, 3, findFunc(true)},
+		"REPT":        {2, 2, fnRept},
+		"EXACT":       {2, 2, fnExact},
+		"VALUE":       {1, 1, fnValue},
+		"TEXT":        {2, 2, fnText},
+		"CHAR":        {1, 1, fnChar},
+		"CODE":        {1, 1, fnCode},
+		"T":           {1, 1, fnT},
+	})
+}
+
+func text1(fn func(string) string) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		return String(fn(e.text(args[0])))
+	}
+}
+
+func properCase(s string) string {
+	var b strings.Builder
+	prev := ' '
+	for _, r := range s {
+		if unicode.IsLetter(prev) {
+			b.WriteRune(unicode.ToLower(r))
+		} else {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+func fnConcatenate(e *evaluator, args []expr) operand {
+	var b strings.Builder
+	for _, arg := range args {
+		b.WriteString(e.text(arg))
+	}
+	return String(b.String())
+}
+
+// fnConcat is CONCATENATE that also accepts ranges.
+func fnConcat(e *evaluator, args []expr) operand {
+	var b strings.Builder
+	for _, arg := range args {
+		e.each(e.eval(arg), func(v Value, _ bool) {
+			b.WriteString(toText(v))
+		})
+	}
+	return String(b.String())
+}
+
+func fnTextJoin(e *evaluator, args []expr) operand {
+	sep := e.text(args[0])
+	ignoreEmpty := e.boolean(args[1])
+	var parts []string
+	for _, arg := range args[2:] {
+		e.each(e.eval(arg), func(v Value, _ bool) {
+			if s := toText(v); s != "" || !ignoreEmpty {
+				parts = append(parts, s)
+			}
+		})
+	}
+	return String(strings.Join(parts, sep))
+}
+
+// count evaluates a character count argument, which must not be negative.
+func (e *evaluator) count(args []expr, i int, def float64) int {
+	n := e.optNumber(args, i, def)
+	if n < 0 {
+		fail(ErrValue)
+	}
+	return int(n)
+}
+
+func fnLeft(e *evaluator, args []expr) operand {
+	r := []rune(e.text(args[0]))
+	return String(string(r[:min(e.count(args, 1, 1), len(r))]))
+}
+
+func fnRight(e *evaluator, args []expr) operand {
+	r := []rune(e.text(args[0]))
+	return String(string(r[len(r)-min(e.count(args, 1, 1), len(r)):]))
+}
+
+func fnMid(e *evaluator, args []expr) operand {
+	r := []rune(e.text(args[0]))
+	start := int(e.number(args[1]))
+	n := e.count(args, 2, 0)
+	if start < 1 {
+		return Error(ErrValue)
+	}
+	if start > len(r) {
+		return String("")
+	}
+	return String(string(r[start-1 : min(start-1+n, len(r))]))
+}
+
+func fnLen(e *evaluator, args []expr) operand {
+	return Number(float64(utf8.RuneCountInString(e.text(args[0]))))
+}
+
+func fnExact(e *evaluator, args []expr) operand {
+	return Bool(e.text(args[0]) == e.text(args[1]))
+}
+
+func fnSubstitute(e *evaluator, args []expr) operand {
+	s, old, repl := e.text(args[0]), e.text(args[1]), e.text(args[2])
+	if old == "" {
+		return String(s)
+	}
+	if len(args) < 4 {
+		return String(strings.ReplaceAll(s, old, repl))
+	}
+	instance := int(e.number(args[3]))
+	if instance < 1 {
+		return Error(ErrValue)
+	}
+	at := 0
+	for i := 1; ; i++ {
+		k := strings.Index(s[at:], old)
+		if k < 0 {
+			return String(s)
+		}
+		at += k
+		if i == instance {
+			return String(s[:at] + repl + s[at+len(old):])
+		}
+		at += len(old)
+	}
+}
+
+func fnReplace(e *evaluator, args []expr) operand {
+	r := []rune(e.text(args[0]))
+	start := int(e.number(args[1]))
+	n := e.count(args, 2, 0)
+	repl := e.text(args[3])
+	if start < 1 {
+		return Error(ErrValue)
+	}
+	from := min(start-1, len(r))
+	to := min(from+n, len(r))
+	return String(string(r[:from]) + repl + string(r[to:]))
+}
+
+// findFunc builds FIND (case-sensitive) and SEARCH (case-insensitive,
+// with wildcards). Positions count characters from 1.
+func findFunc(search bool) func(*evaluator, []expr) operand {
+	return func(e *evaluator, args []expr) operand {
+		needle, haystack := e.text(args[0]), []rune(e.text(args[1]))
+		start := int(e.optNumber(args, 2, 1))
+		if start < 1 || start > len(haystack)+1 {
+			return Error(ErrValue)
+		}
+		rest := string(haystack[start-1:])
+		var k int
+		switch {
+		case !search:
+			k = strings.Index(rest, needle)
+		case strings.ContainsAny(needle, "*?~"):
+			re := regexp.MustCompile("(?is)" + wildcardExpr(needle))
+			k = -1
+			if loc := re.FindStringIndex(rest); loc != nil {
+				k = loc[0]
+			}
+		default:
+			k = strings.Index(strings.ToLower(rest), strings.ToLower(needle))
+		}
+		if k < 0 {
+			return Error(ErrValue)
+		}
+		return Number(float64(start + utf8.RuneCountInString(rest[:k])))
+	}
+}
+
+func fnRept(e *evaluator, args []expr) operand {
+	s := e.text(args[0])
+	n := e.count(args, 1, 0)
+	if len(s)*n > 32767 {
+		return Error(ErrValue)
+	}
+	return String(strings.Repeat(s, n))
+}
+
+func fnValue(e *evaluator, args []expr) operand {
+	v := e.scalar(args[0])
+	switch v.Kind {
+	case KindNumber, KindError:
+		return v
+	case KindEmpty:
+		return Number(0)
+	}
+	if n, ok := parseNumberText(toText(v)); ok {
+		return Number(n)
+	}
+	return Error(ErrValue)
+}
+
+func fnChar(e *evaluator, args []expr) operand {
+	n := int(e.number(args[0]))
+	if n < 1 || n > 255 {
+		return Error(ErrValue)
+	}
+	// Windows-1252 and Latin-1 agree with Unicode outside 128-159.
+	return String(string(rune(n)))
+}
+
+func fnCode(e *evaluator, args []expr) operand {
+	s := e.text(args[0])
+	if s == "" {
+		return Error(ErrValue)
+	}
+	r, _ := utf8.DecodeRuneInString(s)
+	return Number(float64(r))
+}
+
+func fnT(e *evaluator, args []expr) operand {
+	v := e.scalar(args[0])
+	if v.Kind == KindString || v.Kind == KindError {
+		return v
+	}
+	return String("")
+}
+
+func fnText(e *evaluator, args []expr) operand {
+	v := e.scalar(args[0])
+	format := e.text(args[1])
+	if v.Kind == KindError {
+		return v
+	}
+	n, code := coerceNumber(v)
+	if code != "" {
+		// Text that isn't a number is returned unchanged.
+		return String(toText(v))
+	}
+	return String(FormatNumber(n, format))
+}
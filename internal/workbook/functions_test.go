@@ -0,0 +1,206 @@
+package workbook
+
+import "testing"
+
+func TestAggregateFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		// Text and booleans in ranges are skipped, typed arguments counted.
+		{"=SUM(A1:A5)", Number(60)},
+		{`=SUM(A1:A3,"5",TRUE)`, Number(66)},
+		{`=SUM("x")`, Error(ErrValue)},
+		{"=SUM(A6:A9)", Number(0)},
+		{"=PRODUCT(C1:C3,2)", Number(12)},
+		{"=AVERAGE(A1:A5)", Number(20)},
+		{"=AVERAGE(A6:A9)", Error(ErrDiv0)},
+		{"=MIN(A1:A3)", Number(10)},
+		{"=MAX(A1:A3,C1:C3)", Number(30)},
+		{"=MAX(A6:A9)", Number(0)},
+		{"=MEDIAN(A1:A3,C1)", Number(15)},
+		{"=COUNT(A1:A9)", Number(3)},
+		{"=COUNTA(A1:A9)", Number(5)},
+		{"=COUNTBLANK(A1:A9)", Number(4)},
+		{"=SUMPRODUCT(A1:A3,C1:C3)", Number(140)},
+		{"=SUMPRODUCT(A1:A3,C1:C2)", Error(ErrValue)},
+		// Criteria
+		{`=COUNTIF(A1:A5,">15")`, Number(2)},
+		{`=COUNTIF(E1:E3,"a")`, Number(2)},
+		{`=COUNTIF(B1:B3,"*an*")`, Number(1)},
+		{`=COUNTIF(B1:B3,"<>apple")`, Number(2)},
+		{`=COUNTIFS(E1:E3,"a",C1:C3,">1")`, Number(1)},
+		{`=SUMIF(E1:E3,"a",A1:A3)`, Number(40)},
+		{`=SUMIF(A1:A3,">=20")`, Number(50)},
+		{`=SUMIFS(A1:A3,E1:E3,"a",C1:C3,"<3")`, Number(10)},
+		{`=AVERAGEIF(E1:E3,"a",A1:A3)`, Number(20)},
+		{`=AVERAGEIF(E1:E3,"z",A1:A3)`, Error(ErrDiv0)},
+		{`=MINIFS(A1:A3,E1:E3,"a")`, Number(10)},
+		{`=MAXIFS(A1:A3,E1:E3,"a")`, Number(30)},
+	})
+}
+
+func TestMathFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{"=ABS(-2.5)", Number(2.5)},
+		{"=SIGN(-3)", Number(-1)},
+		{"=INT(-2.5)", Number(-3)},
+		{"=SQRT(16)", Number(4)},
+		{"=SQRT(-1)", Error(ErrNum)},
+		{"=LN(0)", Error(ErrNum)},
+		{"=LOG(8,2)", Number(3)},
+		{"=LOG10(1000)", Number(3)},
+		{"=POWER(2,10)", Number(1024)},
+		{"=MOD(-7,3)", Number(2)},
+		{"=MOD(7,0)", Error(ErrDiv0)},
+		{"=ROUND(2.345,2)", Number(2.35)},
+		{"=ROUND(-2.5,0)", Number(-3)},
+		{"=ROUND(1234,-2)", Number(1200)},
+		{"=ROUNDUP(2.301,1)", Number(2.4)},
+		{"=ROUNDDOWN(-2.39,1)", Number(-2.3)},
+		{"=TRUNC(8.9)", Number(8)},
+		{"=CEILING(4.2,2)", Number(6)},
+		{"=FLOOR(4.8,2)", Number(4)},
+		{"=PI()", Number(3.141592653589793)},
+		{"=RANDBETWEEN(3,3)", Number(3)},
+	})
+}
+
+func TestLogicalFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{`=IF(A1>5,"big","small")`, String("big")},
+		{"=IF(FALSE,1)", Bool(false)},
+		{"=IF(A6,1,2)", Number(2)},
+		{`=IF("x",1,2)`, Error(ErrValue)},
+		{`=IFS(A1>20,"a",A1>5,"b")`, String("b")},
+		{"=IFS(FALSE,1)", Error(ErrNA)},
+		{"=IFNA(NA(),0)", Number(0)},
+		{"=IFNA(G1,0)", Error(ErrDiv0)},
+		{"=AND(TRUE,A1>5)", Bool(true)},
+		{"=AND(A1:A5)", Bool(true)},
+		{"=OR(FALSE,C1=2)", Bool(false)},
+		{"=XOR(TRUE,TRUE,TRUE)", Bool(true)},
+		{"=NOT(0)", Bool(true)},
+		{"=TRUE()", Bool(true)},
+		{`=SWITCH(C2,1,"one",2,"two")`, String("two")},
+		{`=SWITCH(C3,1,"one","other")`, String("other")},
+		{"=SWITCH(C3,1,2)", Error(ErrNA)},
+		// Information
+		{"=ISBLANK(A6)", Bool(true)},
+		{"=ISNUMBER(A1)", Bool(true)},
+		{"=ISTEXT(A4)", Bool(true)},
+		{"=ISNONTEXT(A6)", Bool(true)},
+		{"=ISLOGICAL(A5)", Bool(true)},
+		{"=ISERR(NA())", Bool(false)},
+		{"=ISNA(NA())", Bool(true)},
+		{"=ISEVEN(-2.5)", Bool(true)},
+		{"=ISODD(3)", Bool(true)},
+		{"=N(A5)", Number(1)},
+		{"=N(A4)", Number(0)},
+	})
+}
+
+func TestTextFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{`=CONCATENATE(B1,"-",C1)`, String("apple-1")},
+		{"=CONCAT(B1:B3)", String("applebananacherry")},
+		{`=TEXTJOIN(", ",TRUE,B1:B3,A6)`, String("apple, banana, cherry")},
+		{`=TEXTJOIN("-",FALSE,C1,A6,C2)`, String("1--2")},
+		{`=LEFT("hello",2)`, String("he")},
+		{`=LEFT("hello")`, String("h")},
+		{`=LEFT("hello",-1)`, Error(ErrValue)},
+		{`=RIGHT("hello",3)`, String("llo")},
+		{`=MID("héllo",2,3)`, String("éll")},
+		{`=LEN("héllo")`, Number(5)},
+		{`=LOWER("AbC")`, String("abc")},
+		{`=UPPER("AbC")`, String("ABC")},
+		{`=PROPER("hello wORLD")`, String("Hello World")},
+		{`=TRIM("  a   b ")`, String("a b")},
+		{`=SUBSTITUTE("a-b-c","-","+")`, String("a+b+c")},
+		{`=SUBSTITUTE("a-b-c","-","+",2)`, String("a-b+c")},
+		{`=REPLACE("abcdef",2,3,"X")`, String("aXef")},
+		{`=FIND("b","abcb")`, Number(2)},
+		{`=FIND("B","abcb")`, Error(ErrValue)},
+		{`=FIND("b","abcb",3)`, Number(4)},
+		{`=SEARCH("B","abcb")`, Number(2)},
+		{`=SEARCH("?c","abcb")`, Number(2)},
+		{`=REPT("ab",3)`, String("ababab")},
+		{`=EXACT("a","A")`, Bool(false)},
+		{`=VALUE("1,234.5")`, Number(1234.5)},
+		{`=VALUE("abc")`, Error(ErrValue)},
+		{`=TEXT(1234.5,"#,##0.00")`, String("1,234.50")},
+		{`=TEXT(0.25,"0%")`, String("25%")},
+		{`=TEXT("abc","0")`, String("abc")},
+		{"=CHAR(65)", String("A")},
+		{`=CODE("A")`, Number(65)},
+		{"=T(A1)", String("")},
+		{"=T(B1)", String("apple")},
+	})
+}
+
+func TestDateFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{"=DATE(2024,1,31)", Number(45322)},
+		{"=DATE(2024,14,1)", Number(45689)}, // 2025-02-01
+		{"=DATE(1900,3,1)", Number(61)},
+		{"=DATE(1900,2,28)", Number(59)},
+		{"=DATE(10000,1,1)", Error(ErrNum)},
+		{"=TIME(12,30,0)", Number(0.5208333333333334)},
+		{"=TIME(25,0,0)", Number(1.0 / 24)},
+		{"=YEAR(45322)", Number(2024)},
+		{"=MONTH(45322)", Number(1)},
+		{"=DAY(45322)", Number(31)},
+		{"=DAY(60)", Number(28)},
+		{"=HOUR(0.75)", Number(18)},
+		{"=MINUTE(TIME(1,2,3))", Number(2)},
+		{"=SECOND(TIME(1,2,3))", Number(3)},
+		{"=YEAR(-1)", Error(ErrNum)},
+		{"=WEEKDAY(DATE(2024,1,31))", Number(4)}, // a Wednesday
+		{"=WEEKDAY(DATE(2024,1,31),2)", Number(3)},
+		{"=WEEKDAY(DATE(2024,1,31),3)", Number(2)},
+		{"=WEEKDAY(1,4)", Error(ErrNum)},
+		{"=EDATE(DATE(2024,1,31),1)", Number(45351)},    // 2024-02-29
+		{"=EOMONTH(DATE(2024,1,15),-1)", Number(45291)}, // 2023-12-31
+		{"=DAYS(DATE(2024,3,1),DATE(2024,2,1))", Number(29)},
+		{`=DATEDIF(DATE(2020,5,15),DATE(2024,3,1),"Y")`, Number(3)},
+		{`=DATEDIF(DATE(2020,5,15),DATE(2024,3,1),"M")`, Number(45)},
+		{`=DATEDIF(DATE(2020,5,15),DATE(2024,3,1),"YM")`, Number(9)},
+		{`=DATEDIF(DATE(2020,5,15),DATE(2024,3,1),"MD")`, Number(15)},
+		{`=DATEDIF(DATE(2024,3,1),DATE(2020,5,15),"Y")`, Error(ErrNum)},
+		{`=DATEVALUE("2024-01-31")`, Number(45322)},
+		{`=DATEVALUE("31-Jan-2024 14:30")`, Number(45322)},
+		{`=DATEVALUE("1/31/2024")`, Number(45322)},
+		{`=DATEVALUE("soon")`, Error(ErrValue)},
+		{`=TIMEVALUE("6:00 PM")`, Number(0.75)},
+		{"=NETWORKDAYS(DATE(2024,1,1),DATE(2024,1,14))", Number(10)},
+		{"=NETWORKDAYS(DATE(2024,1,14),DATE(2024,1,1),DATE(2024,1,1))", Number(-9)},
+	})
+}
+
+func TestLookupFunctions(t *testing.T) {
+	checkFormulas(t, []formulaCase{
+		{"=VLOOKUP(20,A1:B3,2,FALSE)", String("banana")},
+		{"=VLOOKUP(25,A1:B3,2)", String("banana")},
+		{"=VLOOKUP(5,A1:B3,2)", Error(ErrNA)},
+		{"=VLOOKUP(40,A1:B3,2,FALSE)", Error(ErrNA)},
+		{"=VLOOKUP(20,A1:B3,3,FALSE)", Error(ErrRef)},
+		{`=VLOOKUP("BAN*",B1:C3,2,FALSE)`, Number(2)},
+		{"=HLOOKUP(2,{1,2;\"a\",\"b\"},2,FALSE)", String("b")},
+		{`=XLOOKUP("cherry",B1:B3,A1:A3)`, Number(30)},
+		{`=XLOOKUP("fig",B1:B3,A1:A3,"none")`, String("none")},
+		{`=XLOOKUP("a",E1:E3,C1:C3,,0,-1)`, Number(3)},
+		{"=XLOOKUP(25,A1:A3,C1:C3,,1)", Number(3)},
+		{"=_xlfn.XLOOKUP(10,A1:A3,B1:B3)", String("apple")},
+		{"=MATCH(30,A1:A3,0)", Number(3)},
+		{"=MATCH(25,A1:A3)", Number(2)},
+		{`=MATCH("b?n*",B1:B3,0)`, Number(2)},
+		{"=MATCH(99,A1:A3,0)", Error(ErrNA)},
+		{"=INDEX(B1:B3,2)", String("banana")},
+		{"=INDEX(A1:C3,3,3)", Number(3)},
+		{"=INDEX(A1:C3,4,1)", Error(ErrRef)},
+		{"=SUM(INDEX(A1:C3,0,3))", Number(6)},
+		{`=CHOOSE(2,"a","b","c")`, String("b")},
+		{`=CHOOSE(4,"a","b","c")`, Error(ErrValue)},
+		{"=ROW(B7)", Number(7)},
+		{"=COLUMN()", Number(26)},
+		{"=ROWS(A1:C3)", Number(3)},
+		{"=COLUMNS({1,2,3})", Number(3)},
+	})
+}
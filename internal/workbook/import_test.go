@@ -0,0 +1,408 @@
+package workbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// readFixture imports a file from testdata.
+func readFixture(t *testing.T, name string, read func(io.Reader) (*Workbook, []Warning, error)) (*Workbook, []Warning) {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	wb, warnings, err := read(f)
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return wb, warnings
+}
+
+// checkFormulasAt compares the formulas of cells given as "Sheet!A1".
+func checkFormulasAt(t *testing.T, wb *Workbook, want map[string]string) {
+	t.Helper()
+	for a1, formula := range want {
+		name, cell, _ := strings.Cut(a1, "!")
+		ref, err := ParseRef(cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := wb.Sheet(name).Cell(ref)
+		if c == nil || c.Formula != formula {
+			t.Errorf("%s has formula %+v, want %q", a1, c, formula)
+		}
+	}
+}
+
+func cellStyle(t *testing.T, wb *Workbook, a1 string) Style {
+	t.Helper()
+	name, cell, _ := strings.Cut(a1, "!")
+	ref, err := ParseRef(cell)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wb.CellStyle(wb.Sheet(name).Cell(ref))
+}
+
+func sheetNames(wb *Workbook) []string {
+	var names []string
+	for _, sh := range wb.Sheets {
+		names = append(names, sh.Name)
+	}
+	return names
+}
+
+func warningCodes(warnings []Warning) []string {
+	var codes []string
+	for _, w := range warnings {
+		codes = append(codes, w.Code)
+	}
+	slices.Sort(codes)
+	return slices.Compact(codes)
+}
+
+func mustRange(t *testing.T, s string) Range {
+	t.Helper()
+	rng, err := ParseRange(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rng
+}
+
+func TestReadXLSX(t *testing.T) {
+	wb, warnings := readFixture(t, "sheets.xlsx", ReadXLSX)
+
+	if got := sheetNames(wb); !slices.Equal(got, []string{"Summary", "Data", "Hidden"}) {
+		t.Fatalf("sheets = %v", got)
+	}
+	if !wb.Sheet("Hidden").Hidden || wb.Sheet("Data").Hidden {
+		t.Error("visibility not kept")
+	}
+	checkValues(t, wb, map[string]Value{
+		"Summary!A1": String("Quarterly report"),
+		"Data!A4":    String("Café"),
+		"Data!B3":    Number(80.5),
+		"Data!C2":    Bool(true),
+		"Hidden!A1":  Number(0.05),
+	})
+	checkFormulasAt(t, wb, map[string]string{
+		"Summary!B2": "=SUM(Data!B2:B4)",
+		"Summary!B3": "=Data!B2/B2",
+		"Summary!B4": "=Rate*B2",
+	})
+	wb.RecalculateStale()
+	checkValues(t, wb, map[string]Value{
+		"Summary!B2": Number(1300),
+		"Summary!B4": Number(65),
+	})
+
+	summary := wb.Sheet("Summary")
+	wantMerges := []Range{mustRange(t, "A1:C1"), mustRange(t, "A5:A7")}
+	if !slices.Equal(summary.Merges, wantMerges) {
+		t.Errorf("merges = %v, want %v", summary.Merges, wantMerges)
+	}
+	if st := cellStyle(t, wb, "Summary!A1"); !st.Bold || st.HAlign != "center" {
+		t.Errorf("A1 style = %+v", st)
+	}
+	if st := cellStyle(t, wb, "Summary!B3"); st.NumFmt != "0.00%" {
+		t.Errorf("B3 format = %q", st.NumFmt)
+	}
+	if summary.FrozenRows != 1 || summary.ColWidths[1] != 145 || summary.RowHeights[1] != 40 {
+		t.Errorf("frozen rows %d, widths %v, heights %v", summary.FrozenRows, summary.ColWidths, summary.RowHeights)
+	}
+
+	wantNames := []DefinedName{{Name: "Items", Ref: "Data!$A$2:$A$4", Sheet: "Data"}, {Name: "Rate", Ref: "Hidden!$A$1"}}
+	slices.SortFunc(wb.Names, func(a, b DefinedName) int { return strings.Compare(a.Name, b.Name) })
+	if !slices.Equal(wb.Names, wantNames) {
+		t.Errorf("names = %+v", wb.Names)
+	}
+
+	if len(warnings) != 1 || warnings[0].Code != WarnMergedCells || warnings[0].Sheet != "Summary" {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
+
+func TestReadXLSXWarnings(t *testing.T) {
+	wb, warnings := readFixture(t, "warnings.xlsx", ReadXLSX)
+
+	want := []string{
+		WarnCharts, WarnComments, WarnConditionalFormatting, WarnDataValidation,
+		WarnGradientFill, WarnImages, WarnPatternFill, WarnTables,
+	}
+	slices.Sort(want)
+	if got := warningCodes(warnings); !slices.Equal(got, want) {
+		t.Errorf("warning codes = %v, want %v", got, want)
+	}
+	for _, w := range warnings {
+		switch w.Code {
+		case WarnDataValidation:
+			if w.Sheet != "Sheet1" || w.Ref != "A1:A5" {
+				t.Errorf("data validation warning = %+v", w)
+			}
+		case WarnConditionalFormatting:
+			if w.Sheet != "Sheet1" || w.Ref != "B1:B5" {
+				t.Errorf("conditional formatting warning = %+v", w)
+			}
+		}
+		if w.Message == "" {
+			t.Errorf("warning %s has no message", w.Code)
+		}
+	}
+
+	// What could be imported still is.
+	checkValues(t, wb, map[string]Value{
+		"Sheet1!A5": Number(50),
+		"Table!B3":  Number(2),
+		"Table!D1":  String("gradient"),
+	})
+	if st := cellStyle(t, wb, "Table!D2"); st.Fill != "#FFFF00" {
+		t.Errorf("patterned fill imported as %q", st.Fill)
+	}
+}
+
+func TestReadODS(t *testing.T) {
+	wb, warnings := readFixture(t, "budget.ods", ReadODS)
+
+	if got := sheetNames(wb); !slices.Equal(got, []string{"Budget", "Rates"}) {
+		t.Fatalf("sheets = %v", got)
+	}
+	if !wb.Sheet("Rates").Hidden || wb.Sheet("Budget").Hidden {
+		t.Error("visibility not kept")
+	}
+	checkValues(t, wb, map[string]Value{
+		"Budget!A1": String("Household  budget"),
+		"Budget!A3": String("Power\nand water"),
+		"Budget!B2": Number(1200),
+		"Budget!C2": Number(45322),
+		"Budget!C3": Number(18.5 / 24),
+		"Budget!A5": Bool(true),
+		"Budget!B4": Number(1280.5),
+		"Budget!D4": Number(64.025),
+		"Budget!E4": Error(ErrDiv0),
+		"Budget!F4": String("high"),
+		"Rates!A1":  Number(0.05),
+		"Rates!A2":  String("same"),
+		"Rates!A3":  String("same"),
+	})
+	checkFormulasAt(t, wb, map[string]string{
+		"Budget!B4": "=SUM(B2:B3)",
+		"Budget!C4": "=B3/B4",
+		"Budget!D4": "=B4*Rates!A1",
+		"Budget!E4": "=1/0",
+		"Budget!F4": `=IF(B4>1000,"high","low")`,
+		"Budget!G4": "=SUM(B:B)",
+	})
+
+	budget := wb.Sheet("Budget")
+	wantMerges := []Range{mustRange(t, "A1:C1"), mustRange(t, "B5:C6")}
+	if !slices.Equal(budget.Merges, wantMerges) {
+		t.Errorf("merges = %v, want %v", budget.Merges, wantMerges)
+	}
+	if len(budget.Cells) != 16 {
+		t.Errorf("Budget has %d cells, want 16", len(budget.Cells))
+	}
+
+	for a1, want := range map[string]Style{
+		"Budget!A1": {Bold: true, Fill: "#FFFF00", HAlign: "center", VAlign: "center"},
+		"Budget!A3": {Italic: true, FontColor: "#0000FF", Wrap: true, Border: &Border{Bottom: &BorderEdge{Style: "thin", Color: "#FF0000"}}},
+		"Budget!B2": {NumFmt: "#,##0.00"}, // from the column
+		"Budget!C2": {NumFmt: "dd.mm.yyyy"},
+		"Budget!C3": {NumFmt: "hh:mm:ss"},
+		"Budget!C4": {NumFmt: "0.0%"},
+		"Budget!D4": {},
+	} {
+		if got := cellStyle(t, wb, a1); styleKey(got) != styleKey(want) {
+			t.Errorf("%s style = %+v, want %+v", a1, got, want)
+		}
+	}
+
+	wantWidths := map[int]float64{1: 144, 2: 85, 3: 85, 4: 85, 5: 85, 6: 85, 7: 85}
+	if len(budget.ColWidths) != len(wantWidths) {
+		t.Errorf("column widths = %v", budget.ColWidths)
+	}
+	for col, px := range wantWidths {
+		if budget.ColWidths[col] != px {
+			t.Errorf("column %d is %v px, want %v", col, budget.ColWidths[col], px)
+		}
+	}
+	if len(budget.RowHeights) != 1 || budget.RowHeights[1] != 30 {
+		t.Errorf("row heights = %v", budget.RowHeights)
+	}
+
+	wantNames := []DefinedName{{Name: "Rate", Ref: "Rates!$A$1"}, {Name: "Double", Ref: "Budget!$B$4*2"}}
+	if !slices.Equal(wb.Names, wantNames) {
+		t.Errorf("names = %+v", wb.Names)
+	}
+
+	if got := warningCodes(warnings); !slices.Equal(got, []string{WarnComments, WarnDataValidation, WarnNumberFormats}) {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
+
+func TestExcelFormula(t *testing.T) {
+	for of, want := range map[string]string{
+		"of:=[.A1]+1":                       "=A1+1",
+		"of:=SUM([.$A$1:.B2];[$Sheet2.C3])": "=SUM($A$1:B2,Sheet2!C3)",
+		"of:=[$'My Sheet'.A1:.B2]":          "='My Sheet'!A1:B2",
+		"of:=[$'It''s'.A1]":                 "='It''s'!A1",
+		"of:=SUM([.A$1:.A$1048576])":        "=SUM(A:A)",
+		"of:=SUM([.$A2:.$XFD3])":            "=SUM(2:3)",
+		`of:=CONCAT("[.A1]";"a;b")`:         `=CONCAT("[.A1]","a;b")`,
+		"of:=SUM({1;2|3;4})":                "=SUM({1,2;3,4})",
+		"of:=[.#REF!]+1":                    "=#REF!+1",
+		"=[.A1]":                            "=A1",
+		"$Sheet1.$A$1:.$B$2":                "=Sheet1!$A$1:$B$2",
+		"msoxl:=SUM(A1:A2)":                 "=SUM(A1:A2)",
+	} {
+		if got := ExcelFormula(of); got != want {
+			t.Errorf("ExcelFormula(%q) = %q, want %q", of, got, want)
+		}
+	}
+	// OpenFormula and ExcelFormula are inverses.
+	for _, formula := range []string{
+		"=SUM(A1:B2,Sheet2!C3)",
+		"='My Sheet'!$A$1*2",
+		"=SUM(C:C)+SUM(3:5)",
+		`=IF(A1>1,"a;b",{1,2;3,4})`,
+	} {
+		if got := ExcelFormula("of:=" + OpenFormula(formula)); got != formula {
+			t.Errorf("round trip of %q gave %q", formula, got)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	tests := []struct {
+		file     string
+		want     map[string]Value
+		warnings []string
+	}{
+		{
+			file: "semicolon.csv", // UTF-8 with a byte order mark
+			want: map[string]Value{
+				"A1": String("Name"),
+				"B2": Number(1200),
+				"C2": String("Due; monthly"),
+				"C3": String("two\nlines"),
+				"B4": String("007"),
+				"C4": String("=1+1"),
+				"B5": Bool(true),
+				"C5": Error(ErrNA),
+			},
+		},
+		{
+			file: "tabs_utf16.csv",
+			want: map[string]Value{"A2": String("Zürich"), "B2": Number(421878), "A3": String("Genève")},
+		},
+		{
+			file:     "windows1252.csv",
+			want:     map[string]Value{"A2": String("café"), "B2": String("€5"), "A3": String("naïve"), "B3": Number(-150)},
+			warnings: []string{WarnEncoding},
+		},
+		{
+			file: "pipe.csv",
+			want: map[string]Value{"C1": String("c"), "A3": String("x|y"), "C3": Number(5)},
+		},
+		{
+			file: "one_column.csv",
+			want: map[string]Value{"A1": String("Total"), "A3": Number(2), "B1": {}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			wb, warnings := readFixture(t, tt.file, ReadCSV)
+			if len(wb.Sheets) != 1 {
+				t.Fatalf("%d sheets", len(wb.Sheets))
+			}
+			checkValues(t, wb, tt.want)
+			if got := warningCodes(warnings); !slices.Equal(got, tt.warnings) {
+				t.Errorf("warnings = %+v", warnings)
+			}
+		})
+	}
+}
+
+func TestImportSizeLimits(t *testing.T) {
+	// A row of 1,000 cells, repeated until one more would be too many.
+	row := strings.Repeat("1,", 999) + "1\n"
+	rows := MaxImportCells / 1000
+	if _, _, err := ReadCSV(strings.NewReader(strings.Repeat(row, rows))); err != nil {
+		t.Errorf("CSV with %d cells: %v", MaxImportCells, err)
+	}
+	if _, _, err := ReadCSV(strings.NewReader(strings.Repeat(row, rows) + "1\n")); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("CSV with one cell too many: got %v, want ErrTooLarge", err)
+	}
+
+	f := excelize.NewFile()
+	sw, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := make([]any, 1000)
+	for i := range values {
+		values[i] = 1
+	}
+	for r := 1; r <= rows+1; r++ {
+		cell, _ := excelize.CoordinatesToCellName(1, r)
+		if err := sw.SetRow(cell, values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := f.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadXLSX(&buf); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("XLSX with too many cells: got %v, want ErrTooLarge", err)
+	}
+
+	// 300,000 rows in a few hundred bytes of XML.
+	data, err := os.ReadFile(filepath.Join("testdata", "too_many_cells.ods"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadODS(bytes.NewReader(data)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("ODS with too many cells: got %v, want ErrTooLarge", err)
+	}
+}
+
+func TestImportInvalidFiles(t *testing.T) {
+	xlsx, err := os.ReadFile(filepath.Join("testdata", "sheets.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var plainZip bytes.Buffer
+	zw := zip.NewWriter(&plainZip)
+	w, _ := zw.Create("content.xml")
+	io.WriteString(w, "<x/>")
+	zw.Close()
+
+	for name, tt := range map[string]struct {
+		read func(io.Reader) (*Workbook, []Warning, error)
+		data []byte
+	}{
+		"text as xlsx":     {ReadXLSX, []byte("not a zip")},
+		"text as ods":      {ReadODS, []byte("not a zip")},
+		"xlsx as ods":      {ReadODS, xlsx},
+		"zip without type": {ReadODS, plainZip.Bytes()},
+	} {
+		if _, _, err := tt.read(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidFile) {
+			t.Errorf("%s: got %v, want ErrInvalidFile", name, err)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package workbook
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TokenKind classifies formula tokens.
+type TokenKind uint8
+
+const (
+	TokNumber TokenKind = iota
+	TokString
+	TokBool
+	TokError
+	TokRef  // a cell, range, whole column or whole row, optionally sheet-qualified
+	TokName // a defined name
+	TokFunc // a function name; the next token is TokLParen
+	TokOp   // + - * / ^ & % = <> < > <= >=
+	TokLParen
+	TokRParen
+	TokComma
+	TokLBrace // array constants
+	TokRBrace
+	TokSpace
+)
+
+// Token is one lexeme of a formula. For TokRef, Sheet holds the unquoted
+// sheet name (empty for local references) and Ref the reference without
+// the sheet prefix, as written ("$A$1:B2", "C:C", "3:5").
+type Token struct {
+	Kind  TokenKind
+	Text  string
+	Sheet string
+	Ref   string
+}
+
+var (
+	cellRangePattern = regexp.MustCompile(`^\$?[A-Za-z]{1,3}\$?[0-9]+(:\$?[A-Za-z]{1,3}\$?[0-9]+)?`)
+	colRangePattern  = regexp.MustCompile(`^\$?[A-Za-z]{1,3}:\$?[A-Za-z]{1,3}`)
+	rowRangePattern  = regexp.MustCompile(`^\$?[0-9]+:\$?[0-9]+`)
+	numberPattern    = regexp.MustCompile(`^([0-9]+\.?[0-9]*|\.[0-9]+)([eE][+-]?[0-9]+)?`)
+	namePattern      = regexp.MustCompile(`^[A-Za-z_\\][A-Za-z0-9_.\\]*`)
+	errorPattern     = regexp.MustCompile(`^#(NULL!|DIV/0!|VALUE!|REF!|NAME\?|NUM!|N/A|SPILL!|CALC!)`)
+)
+
+// Tokenize splits a formula (with or without the leading "=") into tokens.
+// Concatenating the Text of every token reproduces the input.
+func Tokenize(formula string) ([]Token, error) {
+	s := strings.TrimPrefix(formula, "=")
+	var tokens []Token
+	for len(s) > 0 {
+		tok, err := nextToken(s)
+		if err != nil {
+			return nil, fmt.Errorf("formula %q: %w", formula, err)
+		}
+		tokens = append(tokens, tok)
+		s = s[len(tok.Text):]
+	}
+	return tokens, nil
+}
+
+func nextToken(s string) (Token, error) {
+	c := s[0]
+	switch {
+	case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+		n := 1
+		for n < len(s) && strings.IndexByte(" \t\n\r", s[n]) >= 0 {
+			n++
+		}
+		return Token{Kind: TokSpace, Text: s[:n]}, nil
+	case c == '"':
+		for i := 1; i < len(s); i++ {
+			if s[i] == '"' {
+				if i+1 < len(s) && s[i+1] == '"' {
+					i++
+					continue
+				}
+				return Token{Kind: TokString, Text: s[:i+1]}, nil
+			}
+		}
+		return Token{}, fmt.Errorf("unterminated string")
+	case c == '\'':
+		return sheetRef(s)
+	case c == '#':
+		if m := errorPattern.FindString(strings.ToUpper(s)); m != "" {
+			return Token{Kind: TokError, Text: s[:len(m)]}, nil
+		}
+		return Token{}, fmt.Errorf("unknown error value")
+	case c == '(':
+		return Token{Kind: TokLParen, Text: "("}, nil
+	case c == ')':
+		return Token{Kind: TokRParen, Text: ")"}, nil
+	case c == '{':
+		return Token{Kind: TokLBrace, Text: "{"}, nil
+	case c == '}':
+		return Token{Kind: TokRBrace, Text: "}"}, nil
+	case c == ',' || c == ';':
+		return Token{Kind: TokComma, Text: s[:1]}, nil
+	case c == '<' || c == '>':
+		if len(s) > 1 && (s[1] == '=' || (c == '<' && s[1] == '>')) {
+			return Token{Kind: TokOp, Text: s[:2]}, nil
+		}
+		return Token{Kind: TokOp, Text: s[:1]}, nil
+	case strings.IndexByte("+-*/^&%=", c) >= 0:
+		return Token{Kind: TokOp, Text: s[:1]}, nil
+	}
+
+	if m := rowRangePattern.FindString(s); m != "" && !followedByName(s, len(m)) {
+		return Token{Kind: TokRef, Text: m, Ref: m}, nil
+	}
+	if m := numberPattern.FindString(s); m != "" {
+		return Token{Kind: TokNumber, Text: m}, nil
+	}
+	if c == '$' || isLetter(c) || c == '_' || c == '\\' {
+		if m := cellRangePattern.FindString(s); m != "" && !followedByName(s, len(m)) {
+			return Token{Kind: TokRef, Text: m, Ref: m}, nil
+		}
+		if m := colRangePattern.FindString(s); m != "" && !followedByName(s, len(m)) {
+			return Token{Kind: TokRef, Text: m, Ref: m}, nil
+		}
+		if m := namePattern.FindString(s); m != "" {
+			rest := s[len(m):]
+			switch {
+			case strings.HasPrefix(rest, "!"):
+				return sheetRef(s)
+			case strings.HasPrefix(rest, "("):
+				return Token{Kind: TokFunc, Text: m}, nil
+			case strings.EqualFold(m, "TRUE") || strings.EqualFold(m, "FALSE"):
+				return Token{Kind: TokBool, Text: m}, nil
+			}
+			return Token{Kind: TokName, Text: m}, nil
+		}
+	}
+	return Token{}, fmt.Errorf("unexpected %q", s[:1])
+}
+
+// sheetRef lexes Sheet1!A1 or 'My sheet'!A1:B2.
+func sheetRef(s string) (Token, error) {
+	var sheet string
+	var n int
+	if s[0] == '\'' {
+		var b strings.Builder
+		i := 1
+		for ; i < len(s); i++ {
+			if s[i] == '\'' {
+				if i+1 < len(s) && s[i+1] == '\'' {
+					b.WriteByte('\'')
+					i++
+					continue
+				}
+				break
+			}
+			b.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return Token{}, fmt.Errorf("unterminated sheet name")
+		}
+		sheet, n = b.String(), i+1
+	} else {
+		sheet = namePattern.FindString(s)
+		n = len(sheet)
+	}
+	if n >= len(s) || s[n] != '!' {
+		return Token{}, fmt.Errorf("expected ! after sheet name")
+	}
+	rest := s[n+1:]
+	for _, p := range []*regexp.Regexp{cellRangePattern, colRangePattern, rowRangePattern} {
+		if m := p.FindString(rest); m != "" && !followedByName(rest, len(m)) {
+			return Token{Kind: TokRef, Text: s[:n+1+len(m)], Sheet: sheet, Ref: m}, nil
+		}
+	}
+	if strings.HasPrefix(strings.ToUpper(rest), ErrRef) {
+		return Token{Kind: TokError, Text: s[:n+1+len(ErrRef)]}, nil
+	}
+	return Token{}, fmt.Errorf("invalid reference after %s!", sheet)
+}
+
+// followedByName reports whether s continues an identifier at i, which
+// means the match so far was a prefix of a name (e.g. "A1B").
+func followedByName(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return isLetter(c) || c >= '0' && c <= '9' || c == '_' || c == '.' || c == '(' || c == '!'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// QuoteSheetName quotes a sheet name for use in a reference when needed.
+func QuoteSheetName(name string) string {
+	if namePattern.FindString(name) == name && !cellRangePattern.MatchString(name) {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
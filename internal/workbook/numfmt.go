@@ -0,0 +1,387 @@
+package workbook
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// builtinNumFmts are the number formats Excel refers to by ID instead of
+// storing the format code (ECMA-376 Part 1, 18.8.30).
+var builtinNumFmts = map[int]string{
+	1:  "0",
+	2:  "0.00",
+	3:  "#,##0",
+	4:  "#,##0.00",
+	9:  "0%",
+	10: "0.00%",
+	11: "0.00E+00",
+	12: "# ?/?",
+	13: "# ??/??",
+	14: "mm-dd-yy",
+	15: "d-mmm-yy",
+	16: "d-mmm",
+	17: "mmm-yy",
+	18: "h:mm AM/PM",
+	19: "h:mm:ss AM/PM",
+	20: "h:mm",
+	21: "h:mm:ss",
+	22: "m/d/yy h:mm",
+	37: "#,##0 ;(#,##0)",
+	38: "#,##0 ;[Red](#,##0)",
+	39: "#,##0.00;(#,##0.00)",
+	40: "#,##0.00;[Red](#,##0.00)",
+	45: "mm:ss",
+	46: "[h]:mm:ss",
+	47: "mmss.0",
+	48: "##0.0E+0",
+	49: "@",
+}
+
+// builtinNumFmtID returns the built-in ID of a format code, or 0.
+func builtinNumFmtID(code string) int {
+	for id, c := range builtinNumFmts {
+		if c == code {
+			return id
+		}
+	}
+	return 0
+}
+
+// FormatNumber renders a number with an Excel number format such as
+// "#,##0.00", "0%", "0.00E+00" or "yyyy-mm-dd hh:mm". Sections for
+// negative numbers and zero (";") and quoted literals are supported;
+// colours, conditions and fractions are ignored.
+func FormatNumber(n float64, format string) string {
+	sections := splitFormat(format)
+	section := sections[0]
+	switch {
+	case n < 0 && len(sections) > 1:
+		section, n = sections[1], -n
+	case n == 0 && len(sections) > 2:
+		section = sections[2]
+	}
+	if strings.EqualFold(strings.TrimSpace(section), "General") || section == "" {
+		return formatGeneral(n)
+	}
+	tokens := formatTokens(section)
+	if isDateFormat(tokens) {
+		return formatDate(n, tokens)
+	}
+	return formatDecimal(n, tokens)
+}
+
+// splitFormat splits a format into its ";" sections, ignoring ";" inside
+// quotes.
+func splitFormat(format string) []string {
+	var sections []string
+	start, quoted := 0, false
+	for i := 0; i < len(format); i++ {
+		switch format[i] {
+		case '"':
+			quoted = !quoted
+		case '\\':
+			i++
+		case ';':
+			if !quoted {
+				sections = append(sections, format[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(sections, format[start:])
+}
+
+// formatToken is a run of format characters: a literal (quoted text,
+// escaped characters and anything without meaning) or a code such as
+// "yyyy", "0.00" or "%".
+type formatToken struct {
+	literal bool
+	text    string
+}
+
+func formatTokens(section string) []formatToken {
+	var tokens []formatToken
+	lit := func(s string) {
+		if n := len(tokens); n > 0 && tokens[n-1].literal {
+			tokens[n-1].text += s
+			return
+		}
+		tokens = append(tokens, formatToken{literal: true, text: s})
+	}
+	for i := 0; i < len(section); {
+		c := section[i]
+		switch {
+		case c == '"':
+			end := strings.IndexByte(section[i+1:], '"')
+			if end < 0 {
+				end = len(section) - i - 1
+			}
+			lit(section[i+1 : i+1+end])
+			i += end + 2
+		case c == '\\' && i+1 < len(section):
+			lit(section[i+1 : i+2])
+			i += 2
+		case c == '[':
+			// Colours and conditions; elapsed time ([h]) isn't supported.
+			end := strings.IndexByte(section[i:], ']')
+			if end < 0 {
+				end = len(section) - i - 1
+			}
+			i += end + 1
+		case c == '_' || c == '*':
+			// Padding to the width of the next character.
+			i += 2
+		case strings.HasPrefix(strings.ToUpper(section[i:]), "AM/PM"):
+			tokens = append(tokens, formatToken{text: "AM/PM"})
+			i += 5
+		case strings.HasPrefix(strings.ToUpper(section[i:]), "A/P"):
+			tokens = append(tokens, formatToken{text: "A/P"})
+			i += 3
+		case strings.ContainsRune("yYmMdDhHsS", rune(c)):
+			j := i
+			for j < len(section) && unicode.ToLower(rune(section[j])) == unicode.ToLower(rune(c)) {
+				j++
+			}
+			tokens = append(tokens, formatToken{text: strings.ToLower(section[i:j])})
+			i = j
+		case strings.ContainsRune("0#?.,%", rune(c)) || ((c == 'E' || c == 'e') && i+1 < len(section) && (section[i+1] == '+' || section[i+1] == '-')):
+			j := i
+			for j < len(section) {
+				d := section[j]
+				if strings.ContainsRune("0#?.,%", rune(d)) {
+					j++
+				} else if (d == 'E' || d == 'e') && j+1 < len(section) && (section[j+1] == '+' || section[j+1] == '-') {
+					j += 2
+				} else {
+					break
+				}
+			}
+			tokens = append(tokens, formatToken{text: section[i:j]})
+			i = j
+		default:
+			lit(section[i : i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+func isDateFormat(tokens []formatToken) bool {
+	for _, t := range tokens {
+		if !t.literal && strings.ContainsAny(t.text[:1], "ymdhsA") {
+			return true
+		}
+	}
+	return false
+}
+
+func formatDecimal(n float64, tokens []formatToken) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		if t.literal {
+			b.WriteString(t.text)
+		} else {
+			b.WriteString(formatDigits(n, t.text))
+			// Only the first placeholder run shows the number.
+			n = math.NaN()
+		}
+	}
+	return b.String()
+}
+
+// formatDigits formats n with a placeholder run like "#,##0.00", "0%" or
+// "0.00E+00".
+func formatDigits(n float64, code string) string {
+	if math.IsNaN(n) {
+		return strings.Map(func(r rune) rune {
+			if r == '%' {
+				return r
+			}
+			return -1
+		}, code)
+	}
+	percent := strings.Count(code, "%")
+	for range percent {
+		n *= 100
+	}
+	code = strings.ReplaceAll(code, "%", "")
+
+	mantissa, exponent, scientific := strings.Cut(strings.ToUpper(code), "E")
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	// Trailing commas scale by thousands.
+	for strings.HasSuffix(intPart, ",") {
+		intPart = intPart[:len(intPart)-1]
+		n /= 1000
+	}
+	thousands := strings.Contains(intPart, ",")
+	minInt := strings.Count(intPart, "0")
+	decimals := strings.Count(fracPart, "0") + strings.Count(fracPart, "#") + strings.Count(fracPart, "?")
+	minDecimals := strings.Count(fracPart, "0")
+
+	exp := 0
+	if scientific && n != 0 {
+		exp = int(math.Floor(math.Log10(math.Abs(n))))
+		n /= math.Pow(10, float64(exp))
+	}
+
+	neg := n < 0
+	// Halves round away from zero, unlike FormatFloat.
+	scale := math.Pow(10, float64(decimals))
+	s := strconv.FormatFloat(math.Round(math.Abs(roundSignificant(n))*scale)/scale, 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+	// Optional decimals (#) drop trailing zeros.
+	for len(frac) > minDecimals && strings.HasSuffix(frac, "0") {
+		frac = frac[:len(frac)-1]
+	}
+	if whole == "0" && minInt == 0 {
+		whole = ""
+	}
+	for len(whole) < minInt {
+		whole = "0" + whole
+	}
+	if thousands {
+		whole = groupThousands(whole)
+	}
+
+	out := whole
+	if frac != "" || (decimals > 0 && minDecimals > 0) || strings.HasSuffix(mantissa, ".") {
+		out += "." + frac
+	}
+	if neg && strings.Trim(out, "0.,") != "" {
+		out = "-" + out
+	}
+	if scientific {
+		sign := "+"
+		if exp < 0 {
+			sign, exp = "-", -exp
+		}
+		digits := strconv.Itoa(exp)
+		for len(digits) < strings.Count(exponent, "0") {
+			digits = "0" + digits
+		}
+		out += "E" + sign + digits
+	}
+	return out + strings.Repeat("%", percent)
+}
+
+func groupThousands(s string) string {
+	if len(s) <= 3 {
+		return s
+	}
+	var b strings.Builder
+	lead := len(s) % 3
+	if lead > 0 {
+		b.WriteString(s[:lead])
+	}
+	for i := lead; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// formatDate renders a serial number with date and time codes. "m" and
+// "mm" are minutes after an hour or before a second code, months
+// otherwise; hours are 12-hour when the format has AM/PM.
+func formatDate(n float64, tokens []formatToken) string {
+	if n < 0 || n >= maxSerial {
+		return formatGeneral(n)
+	}
+	t := serialToTime(n)
+	twelveHour := false
+	for _, tok := range tokens {
+		if !tok.literal && (tok.text == "AM/PM" || tok.text == "A/P") {
+			twelveHour = true
+		}
+	}
+	var b strings.Builder
+	for i, tok := range tokens {
+		if tok.literal {
+			b.WriteString(tok.text)
+			continue
+		}
+		code := tok.text
+		switch {
+		case code == "AM/PM" || code == "A/P":
+			mark := "AM"
+			if t.Hour() >= 12 {
+				mark = "PM"
+			}
+			if code == "A/P" {
+				mark = mark[:1]
+			}
+			b.WriteString(mark)
+		case code[0] == 'y':
+			if len(code) <= 2 {
+				b.WriteString(pad(t.Year()%100, 2))
+			} else {
+				b.WriteString(pad(t.Year(), 4))
+			}
+		case code[0] == 'm' && len(code) <= 2 && isMinutes(tokens, i):
+			b.WriteString(pad(t.Minute(), len(code)))
+		case code[0] == 'm':
+			switch len(code) {
+			case 1, 2:
+				b.WriteString(pad(int(t.Month()), len(code)))
+			case 3:
+				b.WriteString(t.Month().String()[:3])
+			case 5:
+				b.WriteString(t.Month().String()[:1])
+			default:
+				b.WriteString(t.Month().String())
+			}
+		case code[0] == 'd':
+			switch len(code) {
+			case 1, 2:
+				b.WriteString(pad(t.Day(), len(code)))
+			case 3:
+				b.WriteString(t.Weekday().String()[:3])
+			default:
+				b.WriteString(t.Weekday().String())
+			}
+		case code[0] == 'h':
+			h := t.Hour()
+			if twelveHour {
+				if h = h % 12; h == 0 {
+					h = 12
+				}
+			}
+			b.WriteString(pad(h, min(len(code), 2)))
+		case code[0] == 's':
+			b.WriteString(pad(t.Second(), min(len(code), 2)))
+		default:
+			// Number placeholders have no meaning in a date format.
+			b.WriteString(code)
+		}
+	}
+	return b.String()
+}
+
+// isMinutes reports whether the "m" or "mm" code at tokens[i] means
+// minutes rather than the month: it does after hours or before seconds.
+func isMinutes(tokens []formatToken, i int) bool {
+	for j := i - 1; j >= 0; j-- {
+		if !tokens[j].literal {
+			if tokens[j].text[0] == 'h' {
+				return true
+			}
+			break
+		}
+	}
+	for j := i + 1; j < len(tokens); j++ {
+		if !tokens[j].literal {
+			return tokens[j].text[0] == 's'
+		}
+	}
+	return false
+}
+
+// pad formats n with at least width digits.
+func pad(n, width int) string {
+	return fmt.Sprintf("%0*d", width, n)
+}
@@ -0,0 +1,627 @@
+package workbook
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const odsMimeType = "application/vnd.oasis.opendocument.spreadsheet"
+
+const odsManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+ <manifest:file-entry manifest:full-path="/" manifest:version="1.2" manifest:media-type="application/vnd.oasis.opendocument.spreadsheet"/>
+ <manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>
+`
+
+const odsNamespaces = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" ` +
+	`xmlns:style="urn:oasis:names:tc:opendocument:xmlns:style:1.0" ` +
+	`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" ` +
+	`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" ` +
+	`xmlns:fo="urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0" ` +
+	`xmlns:number="urn:oasis:names:tc:opendocument:xmlns:datastyle:1.0" ` +
+	`xmlns:of="urn:oasis:names:tc:opendocument:xmlns:of:1.2" ` +
+	`office:version="1.2"`
+
+// WriteODS writes the workbook as an OpenDocument spreadsheet. Values,
+// formulas, cell styles, simple number formats, merged cells, column
+// widths, row heights, hidden sheets and defined names are written;
+// frozen panes are not. Numbers with a date or time format are written as
+// dates and times.
+func WriteODS(w io.Writer, wb *Workbook) error {
+	zw := zip.NewWriter(w)
+
+	// The mimetype must be the first entry and stored uncompressed.
+	mt, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+	if _, err := io.WriteString(mt, odsMimeType); err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+
+	manifest, err := zw.Create("META-INF/manifest.xml")
+	if err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+	if _, err := io.WriteString(manifest, odsManifest); err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+
+	content, err := zw.Create("content.xml")
+	if err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+	bw := bufio.NewWriter(content)
+	(&odsWriter{w: bw, wb: wb}).content()
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("write ods: %w", err)
+	}
+	return nil
+}
+
+type odsWriter struct {
+	w  *bufio.Writer
+	wb *Workbook
+
+	colStyles map[float64]string // width in px → style name
+	rowStyles map[float64]string // height in px → style name
+	dateKinds map[int]string     // style index → "date" or "time"
+}
+
+func (o *odsWriter) printf(format string, args ...any) {
+	fmt.Fprintf(o.w, format, args...)
+}
+
+func (o *odsWriter) content() {
+	o.printf(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	o.printf(`<office:document-content %s>`, odsNamespaces)
+	o.automaticStyles()
+	o.printf(`<office:body><office:spreadsheet>`)
+	for _, sh := range o.wb.Sheets {
+		o.table(sh)
+	}
+	o.namedExpressions("")
+	o.printf(`</office:spreadsheet></office:body></office:document-content>`)
+}
+
+// ── Styles ───────────────────────────────────
+
+func (o *odsWriter) automaticStyles() {
+	o.printf(`<office:automatic-styles>`)
+	o.printf(`<style:style style:name="ta1" style:family="table"><style:table-properties table:display="true"/></style:style>`)
+	o.printf(`<style:style style:name="ta2" style:family="table"><style:table-properties table:display="false"/></style:style>`)
+
+	o.colStyles = map[float64]string{}
+	o.rowStyles = map[float64]string{}
+	o.dateKinds = map[int]string{}
+	for _, sh := range o.wb.Sheets {
+		for _, px := range sortedValues(sh.ColWidths) {
+			if _, ok := o.colStyles[px]; !ok {
+				name := "co" + strconv.Itoa(len(o.colStyles)+1)
+				o.colStyles[px] = name
+				o.printf(`<style:style style:name="%s" style:family="table-column"><style:table-column-properties style:column-width="%s"/></style:style>`, name, odsLength(px))
+			}
+		}
+		for _, px := range sortedValues(sh.RowHeights) {
+			if _, ok := o.rowStyles[px]; !ok {
+				name := "ro" + strconv.Itoa(len(o.rowStyles)+1)
+				o.rowStyles[px] = name
+				o.printf(`<style:style style:name="%s" style:family="table-row"><style:table-row-properties style:row-height="%s" style:use-optimal-row-height="false"/></style:style>`, name, odsLength(px))
+			}
+		}
+	}
+
+	for i, st := range o.wb.Styles {
+		if i == 0 {
+			continue
+		}
+		o.cellStyle(i, st)
+	}
+	o.printf(`</office:automatic-styles>`)
+}
+
+// odsLength converts pixels (at 96 dpi) to a length in inches.
+func odsLength(px float64) string {
+	return strconv.FormatFloat(px/96, 'f', 4, 64) + "in"
+}
+
+func (o *odsWriter) cellStyle(i int, st Style) {
+	dataStyle := ""
+	if kind := odsDateKind(st.NumFmt); kind != "" {
+		o.dateKinds[i] = kind
+	}
+	if st.NumFmt != "" {
+		if ds, ok := odsNumberStyle("N"+strconv.Itoa(i), st.NumFmt); ok {
+			o.printf(`%s`, ds)
+			dataStyle = fmt.Sprintf(` style:data-style-name="N%d"`, i)
+		}
+	}
+	o.printf(`<style:style style:name="ce%d" style:family="table-cell"%s>`, i, dataStyle)
+
+	o.printf(`<style:table-cell-properties`)
+	if st.Fill != "" {
+		o.printf(` fo:background-color="%s"`, xmlEscape(st.Fill))
+	}
+	switch st.VAlign {
+	case "top", "bottom":
+		o.printf(` style:vertical-align="%s"`, st.VAlign)
+	case "center":
+		o.printf(` style:vertical-align="middle"`)
+	}
+	if st.Wrap {
+		o.printf(` fo:wrap-option="wrap"`)
+	}
+	if b := st.Border; b != nil {
+		for _, e := range []struct {
+			side string
+			edge *BorderEdge
+		}{{"top", b.Top}, {"right", b.Right}, {"bottom", b.Bottom}, {"left", b.Left}} {
+			if e.edge != nil {
+				o.printf(` fo:border-%s="%s"`, e.side, xmlEscape(odsBorder(e.edge)))
+			}
+		}
+	}
+	o.printf(`/>`)
+
+	switch st.HAlign {
+	case "left":
+		o.printf(`<style:paragraph-properties fo:text-align="start"/>`)
+	case "right":
+		o.printf(`<style:paragraph-properties fo:text-align="end"/>`)
+	case "center", "justify":
+		o.printf(`<style:paragraph-properties fo:text-align="%s"/>`, st.HAlign)
+	}
+
+	o.printf(`<style:text-properties`)
+	if st.Bold {
+		o.printf(` fo:font-weight="bold"`)
+	}
+	if st.Italic {
+		o.printf(` fo:font-style="italic"`)
+	}
+	if st.Underline {
+		o.printf(` style:text-underline-style="solid" style:text-underline-width="auto" style:text-underline-color="font-color"`)
+	}
+	if st.Strike {
+		o.printf(` style:text-line-through-style="solid"`)
+	}
+	if st.FontName != "" {
+		o.printf(` fo:font-family="%s"`, xmlEscape(st.FontName))
+	}
+	if st.FontSize > 0 {
+		o.printf(` fo:font-size="%spt"`, strconv.FormatFloat(st.FontSize, 'f', -1, 64))
+	}
+	if st.FontColor != "" {
+		o.printf(` fo:color="%s"`, xmlEscape(st.FontColor))
+	}
+	o.printf(`/></style:style>`)
+}
+
+func odsBorder(e *BorderEdge) string {
+	color := e.Color
+	if color == "" {
+		color = "#000000"
+	}
+	switch e.Style {
+	case "medium":
+		return "1.75pt solid " + color
+	case "thick":
+		return "2.5pt solid " + color
+	case "dashed":
+		return "0.75pt dashed " + color
+	case "dotted":
+		return "0.75pt dotted " + color
+	case "double":
+		return "2.5pt double " + color
+	default:
+		return "0.75pt solid " + color
+	}
+}
+
+// odsNumberStyle translates the common number formats (fixed decimals,
+// thousands separators, percentages, scientific, dates and times) into an
+// ODF data style. Other formats, such as currencies, are left to the
+// default.
+func odsNumberStyle(name, code string) (string, bool) {
+	code = splitFormat(code)[0] // only the positive section
+	if kind := odsDateKind(code); kind != "" {
+		return odsDateStyle(name, kind, formatTokens(code)), true
+	}
+	if code == "@" {
+		return fmt.Sprintf(`<number:text-style style:name="%s"><number:text-content/></number:text-style>`, name), true
+	}
+
+	percent := strings.HasSuffix(code, "%")
+	mantissa, exponent, scientific := strings.Cut(strings.TrimSuffix(code, "%"), "E+")
+	if strings.Trim(mantissa, "#,0.") != "" {
+		return "", false
+	}
+	grouping := strings.Contains(mantissa, ",")
+	decimals := 0
+	if _, frac, ok := strings.Cut(mantissa, "."); ok {
+		decimals = len(frac)
+	}
+	intDigits := strings.Count(strings.SplitN(mantissa, ".", 2)[0], "0")
+
+	var b strings.Builder
+	switch {
+	case scientific:
+		fmt.Fprintf(&b, `<number:number-style style:name="%s"><number:scientific-number number:decimal-places="%d" number:min-integer-digits="%d" number:min-exponent-digits="%d"/></number:number-style>`,
+			name, decimals, intDigits, len(exponent))
+	case percent:
+		fmt.Fprintf(&b, `<number:percentage-style style:name="%s"><number:number number:decimal-places="%d" number:min-integer-digits="%d"/><number:text>%%</number:text></number:percentage-style>`,
+			name, decimals, intDigits)
+	default:
+		fmt.Fprintf(&b, `<number:number-style style:name="%s"><number:number number:decimal-places="%d" number:min-integer-digits="%d" number:grouping="%t"/></number:number-style>`,
+			name, decimals, intDigits, grouping)
+	}
+	return b.String(), true
+}
+
+// odsDateKind returns "date" for formats with a year, month or day,
+// "time" for formats with only hours, minutes and seconds, and "" for
+// anything else.
+func odsDateKind(code string) string {
+	tokens := formatTokens(splitFormat(code)[0])
+	if !isDateFormat(tokens) {
+		return ""
+	}
+	for i, t := range tokens {
+		if t.literal {
+			continue
+		}
+		if c := t.text[0]; c == 'y' || c == 'd' || c == 'm' && !isMinutes(tokens, i) {
+			return "date"
+		}
+	}
+	return "time"
+}
+
+// odsDateStyle translates date and time codes into an ODF date or time
+// style. Anything else, such as "." between the parts, is written as text.
+func odsDateStyle(name, kind string, tokens []formatToken) string {
+	var b strings.Builder
+	part := func(element string, long, textual bool) {
+		b.WriteString(`<number:` + element)
+		if long {
+			b.WriteString(` number:style="long"`)
+		}
+		if textual {
+			b.WriteString(` number:textual="true"`)
+		}
+		b.WriteString(`/>`)
+	}
+	fmt.Fprintf(&b, `<number:%s-style style:name="%s">`, kind, name)
+	for i, t := range tokens {
+		code := t.text
+		switch {
+		case t.literal:
+			fmt.Fprintf(&b, `<number:text>%s</number:text>`, xmlEscape(code))
+		case code == "AM/PM" || code == "A/P":
+			part("am-pm", false, false)
+		case code[0] == 'y':
+			part("year", len(code) > 2, false)
+		case code[0] == 'm' && len(code) <= 2 && isMinutes(tokens, i):
+			part("minutes", len(code) == 2, false)
+		case code[0] == 'm':
+			part("month", len(code) == 2 || len(code) == 4, len(code) > 2)
+		case code[0] == 'd' && len(code) <= 2:
+			part("day", len(code) == 2, false)
+		case code[0] == 'd':
+			part("day-of-week", len(code) > 3, false)
+		case code[0] == 'h':
+			part("hours", len(code) > 1, false)
+		case code[0] == 's':
+			part("seconds", len(code) > 1, false)
+		default:
+			fmt.Fprintf(&b, `<number:text>%s</number:text>`, xmlEscape(code))
+		}
+	}
+	fmt.Fprintf(&b, `</number:%s-style>`, kind)
+	return b.String()
+}
+
+// ── Tables ───────────────────────────────────
+
+func (o *odsWriter) table(sh *Sheet) {
+	tableStyle := "ta1"
+	if sh.Hidden {
+		tableStyle = "ta2"
+	}
+	o.printf(`<table:table table:name="%s" table:style-name="%s">`, xmlEscape(sh.Name), tableStyle)
+
+	bounds, hasCells := sh.Bounds()
+	lastCol, lastRow := 1, 0
+	if hasCells {
+		lastCol, lastRow = bounds.End.Col, bounds.End.Row
+	}
+	// Styled empty cells, merges and sized rows/columns extend the table.
+	for ref := range sh.Cells {
+		lastCol, lastRow = max(lastCol, ref.Col), max(lastRow, ref.Row)
+	}
+	for _, m := range sh.Merges {
+		lastCol, lastRow = max(lastCol, m.End.Col), max(lastRow, m.End.Row)
+	}
+	for col := range sh.ColWidths {
+		lastCol = max(lastCol, col)
+	}
+	for row := range sh.RowHeights {
+		lastRow = max(lastRow, row)
+	}
+
+	o.columns(sh, lastCol)
+
+	spans := map[Ref]Range{}
+	covered := map[Ref]bool{}
+	for _, m := range sh.Merges {
+		spans[m.Start] = m
+		for r := m.Start.Row; r <= m.End.Row; r++ {
+			for c := m.Start.Col; c <= m.End.Col; c++ {
+				if (Ref{Row: r, Col: c}) != m.Start {
+					covered[Ref{Row: r, Col: c}] = true
+				}
+			}
+		}
+	}
+
+	rows := map[int][]int{} // row → columns with content
+	for ref := range sh.Cells {
+		rows[ref.Row] = append(rows[ref.Row], ref.Col)
+	}
+	for ref := range spans {
+		rows[ref.Row] = append(rows[ref.Row], ref.Col)
+	}
+	for ref := range covered {
+		rows[ref.Row] = append(rows[ref.Row], ref.Col)
+	}
+
+	emptyRows := 0
+	flushEmpty := func() {
+		if emptyRows > 0 {
+			o.printf(`<table:table-row table:number-rows-repeated="%d"><table:table-cell/></table:table-row>`, emptyRows)
+			emptyRows = 0
+		}
+	}
+	for row := 1; row <= lastRow; row++ {
+		cols := rows[row]
+		rowStyle := ""
+		if px, ok := sh.RowHeights[row]; ok {
+			rowStyle = fmt.Sprintf(` table:style-name="%s"`, o.rowStyles[px])
+		}
+		if len(cols) == 0 && rowStyle == "" {
+			emptyRows++
+			continue
+		}
+		flushEmpty()
+		o.printf(`<table:table-row%s>`, rowStyle)
+		sort.Ints(cols)
+		next := 1
+		for i, col := range cols {
+			if i > 0 && col == cols[i-1] {
+				continue
+			}
+			if gap := col - next; gap > 0 {
+				o.printf(`<table:table-cell table:number-columns-repeated="%d"/>`, gap)
+			}
+			ref := Ref{Row: row, Col: col}
+			if covered[ref] {
+				o.printf(`<table:covered-table-cell/>`)
+			} else {
+				o.cell(sh, ref, spans)
+			}
+			next = col + 1
+		}
+		if len(cols) == 0 {
+			o.printf(`<table:table-cell/>`)
+		}
+		o.printf(`</table:table-row>`)
+	}
+	flushEmpty()
+	if lastRow == 0 {
+		o.printf(`<table:table-row><table:table-cell/></table:table-row>`)
+	}
+	o.namedExpressions(sh.Name)
+	o.printf(`</table:table>`)
+}
+
+func (o *odsWriter) columns(sh *Sheet, lastCol int) {
+	run, runStyle := 0, ""
+	flush := func() {
+		if run == 0 {
+			return
+		}
+		repeated := ""
+		if run > 1 {
+			repeated = fmt.Sprintf(` table:number-columns-repeated="%d"`, run)
+		}
+		style := ""
+		if runStyle != "" {
+			style = fmt.Sprintf(` table:style-name="%s"`, runStyle)
+		}
+		o.printf(`<table:table-column%s%s/>`, style, repeated)
+	}
+	for col := 1; col <= lastCol; col++ {
+		style := ""
+		if px, ok := sh.ColWidths[col]; ok {
+			style = o.colStyles[px]
+		}
+		if col > 1 && style != runStyle {
+			flush()
+			run = 0
+		}
+		runStyle = style
+		run++
+	}
+	flush()
+}
+
+func (o *odsWriter) cell(sh *Sheet, ref Ref, spans map[Ref]Range) {
+	c := sh.Cells[ref]
+	o.printf(`<table:table-cell`)
+	if m, ok := spans[ref]; ok {
+		o.printf(` table:number-columns-spanned="%d" table:number-rows-spanned="%d"`, m.Cols(), m.Rows())
+	}
+	if c == nil {
+		o.printf(`/>`)
+		return
+	}
+	if c.Style > 0 && c.Style < len(o.wb.Styles) {
+		o.printf(` table:style-name="ce%d"`, c.Style)
+	}
+	switch {
+	case c.Formula != "":
+		o.printf(` table:formula="%s"`, xmlEscape("of:="+OpenFormula(c.Formula)))
+	case c.Value.Kind == KindError:
+		// Only formulas have error values in ODF.
+		o.printf(` table:formula="%s"`, xmlEscape("of:="+c.Value.Str))
+	}
+	switch c.Value.Kind {
+	case KindNumber:
+		n := c.Value.Num
+		switch kind := o.dateKinds[c.Style]; {
+		case kind == "date" && n >= 0 && n < maxSerial:
+			o.printf(` office:value-type="date" office:date-value="%s"`, serialToTime(n).Format("2006-01-02T15:04:05"))
+		case kind == "time" && n >= 0 && n < maxSerial:
+			secs := int64(math.Round(n * 86400))
+			o.printf(` office:value-type="time" office:time-value="PT%02dH%02dM%02dS"`, secs/3600, secs/60%60, secs%60)
+		default:
+			o.printf(` office:value-type="float" office:value="%s"`, c.Value.Text())
+		}
+	case KindBool:
+		o.printf(` office:value-type="boolean" office:boolean-value="%t"`, c.Value.Bool)
+	case KindString, KindError:
+		o.printf(` office:value-type="string"`)
+	case KindEmpty:
+		o.printf(`/>`)
+		return
+	}
+	o.printf(`>`)
+	for _, line := range strings.Split(c.Value.Text(), "\n") {
+		o.printf(`<text:p>%s</text:p>`, xmlEscape(line))
+	}
+	o.printf(`</table:table-cell>`)
+}
+
+// namedExpressions writes the names scoped to a sheet, or the workbook's
+// names when sheet is "". Sheet names go at the end of their table; names
+// scoped to a sheet that no longer exists are written for the workbook.
+func (o *odsWriter) namedExpressions(sheet string) {
+	var names []DefinedName
+	for _, dn := range o.wb.Names {
+		scope := dn.Sheet
+		if o.wb.Sheet(scope) == nil {
+			scope = ""
+		}
+		if scope == sheet {
+			names = append(names, dn)
+		}
+	}
+	if len(names) == 0 {
+		return
+	}
+	if sheet == "" {
+		sheet = o.wb.Sheets[0].Name
+	}
+	base := "$" + odsSheetName(sheet) + ".$A$1"
+	o.printf(`<table:named-expressions>`)
+	for _, dn := range names {
+		o.printf(`<table:named-expression table:name="%s" table:base-cell-address="%s" table:expression="%s"/>`,
+			xmlEscape(dn.Name), xmlEscape(base), xmlEscape("of:="+OpenFormula(dn.Ref)))
+	}
+	o.printf(`</table:named-expressions>`)
+}
+
+// ── Formulas ─────────────────────────────────
+
+// OpenFormula translates an Excel formula to OpenFormula syntax as used by
+// ODF: references become [.A1] / [$Sheet.A1:.B2] and arguments are
+// separated by semicolons. Formulas that can't be tokenised are returned
+// unchanged.
+func OpenFormula(formula string) string {
+	tokens, err := Tokenize(formula)
+	if err != nil {
+		return strings.TrimPrefix(formula, "=")
+	}
+	var b strings.Builder
+	inArray := false
+	for _, t := range tokens {
+		switch t.Kind {
+		case TokRef:
+			b.WriteString(odsRef(t))
+		case TokFunc:
+			b.WriteString(strings.TrimPrefix(t.Text, "_xlfn."))
+		case TokLBrace:
+			inArray = true
+			b.WriteString(t.Text)
+		case TokRBrace:
+			inArray = false
+			b.WriteString(t.Text)
+		case TokComma:
+			switch {
+			case inArray && t.Text == ";":
+				b.WriteString("|")
+			default:
+				b.WriteString(";")
+			}
+		default:
+			b.WriteString(t.Text)
+		}
+	}
+	return b.String()
+}
+
+func odsRef(t Token) string {
+	start, end, isRange := strings.Cut(t.Ref, ":")
+	if isRange {
+		switch {
+		case rowRangePattern.MatchString(t.Ref) && !cellRangePattern.MatchString(t.Ref):
+			start, end = "$A"+start, "$"+ColumnName(MaxCols)+end
+		case colRangePattern.MatchString(t.Ref) && !cellRangePattern.MatchString(t.Ref):
+			start, end = start+"$1", end+"$"+strconv.Itoa(MaxRows)
+		}
+	}
+	prefix := "."
+	if t.Sheet != "" {
+		prefix = "$" + odsSheetName(t.Sheet) + "."
+	}
+	if !isRange {
+		return "[" + prefix + start + "]"
+	}
+	return "[" + prefix + start + ":." + end + "]"
+}
+
+func odsSheetName(name string) string {
+	if strings.ContainsAny(name, " .'!-") {
+		return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+	}
+	return name
+}
+
+// ── Helpers ──────────────────────────────────
+
+// xmlEscape escapes text for use in element content and attribute values.
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func sortedValues(m map[int]float64) []float64 {
+	vals := make([]float64, 0, len(m))
+	for _, k := range sortedKeys(m) {
+		vals = append(vals, m[k])
+	}
+	return vals
+}
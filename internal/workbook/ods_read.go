@@ -0,0 +1,1019 @@
+package workbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxODSContentSize caps the uncompressed content.xml of an imported ODS
+// file, like excelize's limit for the XML parts of an XLSX file.
+const maxODSContentSize = 64 << 20
+
+// unsupportedODSElements maps elements of content.xml to the warning
+// raised when a file contains them.
+var unsupportedODSElements = map[xml.Name]struct {
+	code    string
+	message string
+}{
+	{Space: odsNSDraw, Local: "object"}:                {WarnCharts, "Charts were not imported"},
+	{Space: odsNSDraw, Local: "image"}:                 {WarnImages, "Images and drawings were not imported"},
+	{Space: odsNSTable, Local: "data-pilot-table"}:     {WarnPivotTables, "Pivot tables were imported as plain values"},
+	{Space: odsNSOffice, Local: "annotation"}:          {WarnComments, "Cell comments were not imported"},
+	{Space: odsNSTable, Local: "content-validation"}:   {WarnDataValidation, "Data validation was not imported"},
+	{Space: odsNSCalcExt, Local: "conditional-format"}: {WarnConditionalFormatting, "Conditional formatting was not imported"},
+}
+
+const (
+	odsNSOffice  = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsNSStyle   = "urn:oasis:names:tc:opendocument:xmlns:style:1.0"
+	odsNSText    = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	odsNSTable   = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsNSDraw    = "urn:oasis:names:tc:opendocument:xmlns:drawing:1.0"
+	odsNSFo      = "urn:oasis:names:tc:opendocument:xmlns:xsl-fo-compatible:1.0"
+	odsNSNumber  = "urn:oasis:names:tc:opendocument:xmlns:datastyle:1.0"
+	odsNSCalcExt = "urn:org:documentfoundation:names:experimental:calc:xmlns:calcext:1.0"
+)
+
+// ReadODS converts an OpenDocument spreadsheet. Values, formulas, cell
+// styles, simple number formats, merged cells, column widths, row
+// heights, hidden sheets and defined names are kept; everything else is
+// reported as a warning.
+func ReadODS(r io.Reader) (*Workbook, []Warning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read ods: %w", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrInvalidFile
+	}
+	content, err := odsContent(zr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	imp := &odsImporter{
+		wb:         &Workbook{Styles: []Style{{}}},
+		styles:     map[string]*odsStyle{},
+		dataStyles: map[string]string{},
+		cellStyles: map[string]int{},
+		warned:     map[string]bool{},
+	}
+	for _, file := range zr.File {
+		if strings.HasPrefix(file.Name, "Basic/") || strings.HasPrefix(file.Name, "Scripts/") {
+			imp.warn(Warning{Code: WarnMacros, Message: "Macros were removed"})
+		}
+	}
+	if err := imp.read(xml.NewDecoder(bytes.NewReader(content))); err != nil {
+		return nil, nil, err
+	}
+	if len(imp.wb.Sheets) == 0 {
+		return nil, nil, ErrInvalidFile
+	}
+	if imp.warnings == nil {
+		imp.warnings = []Warning{}
+	}
+	return imp.wb, imp.warnings, nil
+}
+
+// odsContent returns the content.xml of a file that declares itself an
+// OpenDocument spreadsheet.
+func odsContent(zr *zip.Reader) ([]byte, error) {
+	var mimetype, content *zip.File
+	for _, f := range zr.File {
+		switch f.Name {
+		case "mimetype":
+			mimetype = f
+		case "content.xml":
+			content = f
+		}
+	}
+	if mimetype == nil || content == nil {
+		return nil, ErrInvalidFile
+	}
+	mt, err := readZipFile(mimetype, 256)
+	if err != nil || strings.TrimSpace(string(mt)) != odsMimeType {
+		return nil, ErrInvalidFile
+	}
+	data, err := readZipFile(content, maxODSContentSize)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readZipFile reads a zip entry, failing with ErrTooLarge past limit bytes.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, ErrInvalidFile
+	}
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, nil
+}
+
+type odsImporter struct {
+	wb         *Workbook
+	styles     map[string]*odsStyle // automatic styles by name
+	dataStyles map[string]string    // data style name → Excel format code
+	cellStyles map[string]int       // cell style name → Workbook.Styles index
+	warnings   []Warning
+	warned     map[string]bool // codes already reported
+	cells      int
+
+	sheet     *Sheet
+	inTable   bool           // names read now are scoped to sheet
+	row, col  int            // last row and column read in the sheet
+	colStyles map[int]string // default cell style by column
+}
+
+// odsStyle is an automatic style of any family; only the properties of
+// its family are set.
+type odsStyle struct {
+	cell      Style
+	dataStyle string
+	width     float64 // table-column, px
+	height    float64 // table-row, px; 0 for the optimal height
+	hidden    bool    // table
+}
+
+func (imp *odsImporter) warn(w Warning) {
+	if imp.warned[w.Code] {
+		return
+	}
+	imp.warned[w.Code] = true
+	imp.warnings = append(imp.warnings, w)
+}
+
+func attr(se xml.StartElement, space, local string) string {
+	for _, a := range se.Attr {
+		if a.Name.Space == space && a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// repeated reads a number-*-repeated attribute, which defaults to 1.
+func repeated(se xml.StartElement, local string) int {
+	n, err := strconv.Atoi(attr(se, odsNSTable, local))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return n
+}
+
+func (imp *odsImporter) read(d *xml.Decoder) error {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		if ee, ok := tok.(xml.EndElement); ok && ee.Name == (xml.Name{Space: odsNSTable, Local: "table"}) {
+			trimSizes(imp.sheet)
+			imp.inTable = false
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if w, ok := unsupportedODSElements[se.Name]; ok {
+			imp.warn(Warning{Code: w.code, Message: w.message})
+		}
+		switch se.Name {
+		case xml.Name{Space: odsNSStyle, Local: "style"}:
+			if err := imp.style(d, se); err != nil {
+				return err
+			}
+		case xml.Name{Space: odsNSNumber, Local: "number-style"},
+			xml.Name{Space: odsNSNumber, Local: "percentage-style"},
+			xml.Name{Space: odsNSNumber, Local: "text-style"},
+			xml.Name{Space: odsNSNumber, Local: "date-style"},
+			xml.Name{Space: odsNSNumber, Local: "time-style"}:
+			if err := imp.dataStyle(d, se); err != nil {
+				return err
+			}
+		case xml.Name{Space: odsNSTable, Local: "table"}:
+			imp.table(se)
+		case xml.Name{Space: odsNSTable, Local: "table-column"}:
+			imp.column(se)
+		case xml.Name{Space: odsNSTable, Local: "table-row"}:
+			if err := imp.tableRow(d, se); err != nil {
+				return err
+			}
+		case xml.Name{Space: odsNSTable, Local: "named-range"}:
+			imp.name(se, attr(se, odsNSTable, "cell-range-address"))
+		case xml.Name{Space: odsNSTable, Local: "named-expression"}:
+			imp.name(se, attr(se, odsNSTable, "expression"))
+		}
+	}
+}
+
+// ── Styles ───────────────────────────────────
+
+func (imp *odsImporter) style(d *xml.Decoder, se xml.StartElement) error {
+	st := &odsStyle{dataStyle: attr(se, odsNSStyle, "data-style-name")}
+	imp.styles[attr(se, odsNSStyle, "name")] = st
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name == se.Name {
+				return nil
+			}
+		case xml.StartElement:
+			if t.Name.Space != odsNSStyle {
+				continue
+			}
+			switch t.Name.Local {
+			case "table-properties":
+				st.hidden = attr(t, odsNSTable, "display") == "false"
+			case "table-column-properties":
+				st.width = odsPixels(attr(t, odsNSStyle, "column-width"))
+			case "table-row-properties":
+				if attr(t, odsNSStyle, "use-optimal-row-height") != "true" {
+					st.height = odsPixels(attr(t, odsNSStyle, "row-height"))
+				}
+			case "table-cell-properties":
+				cellProperties(&st.cell, t)
+			case "paragraph-properties":
+				switch attr(t, odsNSFo, "text-align") {
+				case "start", "left":
+					st.cell.HAlign = "left"
+				case "end", "right":
+					st.cell.HAlign = "right"
+				case "center":
+					st.cell.HAlign = "center"
+				case "justify":
+					st.cell.HAlign = "justify"
+				}
+			case "text-properties":
+				textProperties(&st.cell, t)
+			}
+		}
+	}
+}
+
+func cellProperties(st *Style, se xml.StartElement) {
+	if bg := attr(se, odsNSFo, "background-color"); bg != "transparent" {
+		st.Fill = hexColor(bg)
+	}
+	switch attr(se, odsNSStyle, "vertical-align") {
+	case "top":
+		st.VAlign = "top"
+	case "middle":
+		st.VAlign = "center"
+	case "bottom":
+		st.VAlign = "bottom"
+	}
+	st.Wrap = attr(se, odsNSFo, "wrap-option") == "wrap"
+
+	var border Border
+	all := odsBorderEdge(attr(se, odsNSFo, "border"))
+	for _, e := range []struct {
+		side string
+		edge **BorderEdge
+	}{{"top", &border.Top}, {"right", &border.Right}, {"bottom", &border.Bottom}, {"left", &border.Left}} {
+		*e.edge = all
+		if v := attr(se, odsNSFo, "border-"+e.side); v != "" {
+			*e.edge = odsBorderEdge(v)
+		}
+	}
+	if border != (Border{}) {
+		st.Border = &border
+	}
+}
+
+// odsBorderEdge parses a border such as "0.75pt solid #000000", the
+// reverse of odsBorder.
+func odsBorderEdge(s string) *BorderEdge {
+	fields := strings.Fields(s)
+	if len(fields) < 2 || fields[0] == "none" || fields[1] == "none" {
+		return nil
+	}
+	edge := &BorderEdge{Style: "thin"}
+	if len(fields) > 2 && hexColor(fields[2]) != "#000000" {
+		edge.Color = hexColor(fields[2])
+	}
+	width := odsPixels(fields[0])
+	switch fields[1] {
+	case "dashed":
+		edge.Style = "dashed"
+	case "dotted":
+		edge.Style = "dotted"
+	case "double":
+		edge.Style = "double"
+	default:
+		switch {
+		case width >= 3:
+			edge.Style = "thick"
+		case width >= 2:
+			edge.Style = "medium"
+		}
+	}
+	return edge
+}
+
+func textProperties(st *Style, se xml.StartElement) {
+	st.Bold = attr(se, odsNSFo, "font-weight") == "bold"
+	st.Italic = attr(se, odsNSFo, "font-style") == "italic"
+	if u := attr(se, odsNSStyle, "text-underline-style"); u != "" && u != "none" {
+		st.Underline = true
+	}
+	if s := attr(se, odsNSStyle, "text-line-through-style"); s != "" && s != "none" {
+		st.Strike = true
+	}
+	st.FontName = strings.Trim(attr(se, odsNSFo, "font-family"), `'"`)
+	if st.FontName == "" {
+		st.FontName = attr(se, odsNSStyle, "font-name")
+	}
+	if pt, ok := strings.CutSuffix(attr(se, odsNSFo, "font-size"), "pt"); ok {
+		st.FontSize, _ = strconv.ParseFloat(pt, 64)
+	}
+	st.FontColor = hexColor(attr(se, odsNSFo, "color"))
+	if st.FontColor == "#000000" {
+		st.FontColor = ""
+	}
+}
+
+// odsUnits are the pixels (at 96 dpi) in each ODF length unit.
+var odsUnits = map[string]float64{"in": 96, "cm": 96 / 2.54, "mm": 96 / 25.4, "pt": 96.0 / 72, "pc": 16, "px": 1}
+
+// odsPixels converts an ODF length to pixels.
+func odsPixels(length string) float64 {
+	for unit, px := range odsUnits {
+		if n, ok := strings.CutSuffix(length, unit); ok {
+			if v, err := strconv.ParseFloat(n, 64); err == nil {
+				return math.Round(v * px)
+			}
+		}
+	}
+	return 0
+}
+
+// dataStyle translates the number, date and time styles odsNumberStyle
+// writes back into Excel format codes. Other data styles, such as
+// currencies, are not imported.
+func (imp *odsImporter) dataStyle(d *xml.Decoder, se xml.StartElement) error {
+	name := attr(se, odsNSStyle, "name")
+	if se.Name.Local == "date-style" || se.Name.Local == "time-style" {
+		code, err := odsDateCode(d, se)
+		if err != nil {
+			return err
+		}
+		imp.dataStyles[name] = code
+		return nil
+	}
+	code := ""
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name != se.Name {
+				continue
+			}
+			switch se.Name.Local {
+			case "text-style":
+				code = "@"
+			case "percentage-style":
+				code += "%"
+			}
+			if code != "" && code != "%" {
+				imp.dataStyles[name] = code
+			}
+			return nil
+		case xml.StartElement:
+			switch t.Name {
+			case xml.Name{Space: odsNSNumber, Local: "number"}:
+				code = odsNumberCode(t, false)
+			case xml.Name{Space: odsNSNumber, Local: "scientific-number"}:
+				code = odsNumberCode(t, true)
+			}
+		}
+	}
+}
+
+// odsDateCode reads a date or time style as an Excel format code, the
+// reverse of odsDateStyle. Text is quoted unless it is punctuation Excel
+// shows as is.
+func odsDateCode(d *xml.Decoder, se xml.StartElement) (string, error) {
+	var b strings.Builder
+	inText := false
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name == se.Name {
+				return b.String(), nil
+			}
+			inText = false
+		case xml.CharData:
+			if !inText {
+				continue
+			}
+			if text := string(t); strings.Trim(text, " -/:.,") == "" {
+				b.WriteString(text)
+			} else {
+				b.WriteString(`"` + strings.ReplaceAll(text, `"`, "") + `"`)
+			}
+		case xml.StartElement:
+			if t.Name.Space != odsNSNumber {
+				continue
+			}
+			long := attr(t, odsNSNumber, "style") == "long"
+			pick := func(short, longCode string) string {
+				if long {
+					return longCode
+				}
+				return short
+			}
+			switch t.Name.Local {
+			case "text":
+				inText = true
+			case "year":
+				b.WriteString(pick("yy", "yyyy"))
+			case "month":
+				if attr(t, odsNSNumber, "textual") == "true" {
+					b.WriteString(pick("mmm", "mmmm"))
+				} else {
+					b.WriteString(pick("m", "mm"))
+				}
+			case "day":
+				b.WriteString(pick("d", "dd"))
+			case "day-of-week":
+				b.WriteString(pick("ddd", "dddd"))
+			case "hours":
+				b.WriteString(pick("h", "hh"))
+			case "minutes":
+				b.WriteString(pick("m", "mm"))
+			case "seconds":
+				b.WriteString(pick("s", "ss"))
+			case "am-pm":
+				b.WriteString("AM/PM")
+			}
+		}
+	}
+}
+
+// skipTo reads up to the end of the element named name.
+func (imp *odsImporter) skipTo(d *xml.Decoder, name xml.Name) {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		if t, ok := tok.(xml.EndElement); ok && t.Name == name {
+			return
+		}
+	}
+}
+
+func odsNumberCode(se xml.StartElement, scientific bool) string {
+	num := func(local string) int {
+		n, _ := strconv.Atoi(attr(se, odsNSNumber, local))
+		return max(0, min(n, 30))
+	}
+	intDigits := max(num("min-integer-digits"), 1)
+	code := strings.Repeat("0", intDigits)
+	if attr(se, odsNSNumber, "grouping") == "true" {
+		code = "#," + strings.Repeat("#", max(0, 3-intDigits)) + code
+	}
+	if decimals := num("decimal-places"); decimals > 0 {
+		code += "." + strings.Repeat("0", decimals)
+	}
+	if scientific {
+		code += "E+" + strings.Repeat("0", max(num("min-exponent-digits"), 1))
+	}
+	return code
+}
+
+// cellStyle returns the Workbook.Styles index of a cell style.
+func (imp *odsImporter) cellStyle(name string) int {
+	if name == "" || name == "Default" {
+		return 0
+	}
+	if idx, ok := imp.cellStyles[name]; ok {
+		return idx
+	}
+	idx := 0
+	if st, ok := imp.styles[name]; ok {
+		cell := st.cell
+		if st.dataStyle != "" {
+			if code, ok := imp.dataStyles[st.dataStyle]; ok {
+				cell.NumFmt = code
+			} else {
+				imp.warn(Warning{Code: WarnNumberFormats, Message: "Some number formats were not imported"})
+			}
+		}
+		idx = imp.wb.StyleID(cell)
+	}
+	imp.cellStyles[name] = idx
+	return idx
+}
+
+// ── Tables ───────────────────────────────────
+
+func (imp *odsImporter) table(se xml.StartElement) {
+	imp.sheet = imp.wb.AddSheet(attr(se, odsNSTable, "name"))
+	if st, ok := imp.styles[attr(se, odsNSTable, "style-name")]; ok {
+		imp.sheet.Hidden = st.hidden
+	}
+	imp.inTable = true
+	imp.row, imp.col = 0, 0
+	imp.colStyles = map[int]string{}
+}
+
+// column records column widths and default cell styles. Widths past the
+// last column with content are dropped once the sheet is complete (see
+// trimSizes).
+func (imp *odsImporter) column(se xml.StartElement) {
+	sh := imp.sheet
+	if sh == nil {
+		return
+	}
+	st := imp.styles[attr(se, odsNSTable, "style-name")]
+	cellStyle := attr(se, odsNSTable, "default-cell-style-name")
+	n := min(repeated(se, "number-columns-repeated"), MaxCols-imp.col)
+	for col := imp.col + 1; col <= imp.col+n; col++ {
+		if st != nil && st.width > 0 {
+			sh.ColWidths[col] = st.width
+		}
+		if cellStyle != "" && cellStyle != "Default" {
+			imp.colStyles[col] = cellStyle
+		}
+	}
+	imp.col += n
+}
+
+func (imp *odsImporter) tableRow(d *xml.Decoder, se xml.StartElement) error {
+	sh := imp.sheet
+	if sh == nil {
+		return ErrInvalidFile
+	}
+	rows := repeated(se, "number-rows-repeated")
+	first := imp.row + 1
+	imp.row += rows
+	height := 0.0
+	if st, ok := imp.styles[attr(se, odsNSTable, "style-name")]; ok {
+		height = st.height
+	}
+
+	cells, err := imp.rowCells(d, se)
+	if err != nil {
+		return err
+	}
+	if first > MaxRows {
+		if len(cells) > 0 {
+			return ErrTooLarge
+		}
+		return nil
+	}
+	// Files pad sheets with one huge run of empty rows; only rows with
+	// content are copied.
+	if len(cells) == 0 {
+		if height > 0 && rows == 1 {
+			sh.RowHeights[first] = height
+		}
+		return nil
+	}
+	for row := first; row < first+rows && row <= MaxRows; row++ {
+		if height > 0 {
+			sh.RowHeights[row] = height
+		}
+		for _, c := range cells {
+			if c.span != nil {
+				sh.Merges = append(sh.Merges, Range{
+					Start: Ref{Row: row, Col: c.col},
+					End:   Ref{Row: min(row+c.span.Row-1, MaxRows), Col: c.col + c.span.Col - 1},
+				})
+			}
+			if c.cell == nil {
+				continue
+			}
+			if imp.cells++; imp.cells > MaxImportCells {
+				return ErrTooLarge
+			}
+			cell := *c.cell
+			sh.Set(Ref{Row: row, Col: c.col}, &cell)
+		}
+	}
+	return nil
+}
+
+// odsCell is a cell read from a row; cell is nil for empty cells that
+// only start a merge.
+type odsCell struct {
+	col  int
+	cell *Cell
+	span *Ref // rows and columns spanned
+}
+
+// rowCells reads the cells of a table-row element.
+func (imp *odsImporter) rowCells(d *xml.Decoder, row xml.StartElement) ([]odsCell, error) {
+	var cells []odsCell
+	col := 1
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name == row.Name {
+				return cells, nil
+			}
+		case xml.StartElement:
+			if t.Name.Space != odsNSTable {
+				imp.skipTo(d, t.Name)
+				continue
+			}
+			n := repeated(t, "number-columns-repeated")
+			switch t.Name.Local {
+			case "covered-table-cell":
+				imp.skipTo(d, t.Name)
+			case "table-cell":
+				c, err := imp.cell(d, t, col)
+				if err != nil {
+					return nil, err
+				}
+				var span *Ref
+				rs, _ := strconv.Atoi(attr(t, odsNSTable, "number-rows-spanned"))
+				cs, _ := strconv.Atoi(attr(t, odsNSTable, "number-columns-spanned"))
+				if rs > 1 || cs > 1 {
+					span = &Ref{Row: max(rs, 1), Col: max(cs, 1)}
+				}
+				// Runs of empty cells pad rows; they are skipped even when
+				// styled.
+				if c == nil && span == nil || c != nil && c.Value.IsEmpty() && c.Formula == "" && n > 1 {
+					break
+				}
+				for k := range n {
+					if col+k > MaxCols {
+						return nil, ErrTooLarge
+					}
+					cells = append(cells, odsCell{col: col + k, cell: c, span: span})
+				}
+			}
+			col += n
+		}
+	}
+}
+
+// cell reads a table-cell element. It returns nil for cells with nothing
+// worth keeping.
+func (imp *odsImporter) cell(d *xml.Decoder, se xml.StartElement, col int) (*Cell, error) {
+	text, err := imp.cellText(d, se)
+	if err != nil {
+		return nil, err
+	}
+	style := attr(se, odsNSTable, "style-name")
+	if style == "" {
+		style = imp.colStyles[col]
+	}
+	c := &Cell{Style: imp.cellStyle(style)}
+	if f := attr(se, odsNSTable, "formula"); f != "" {
+		c.Formula = ExcelFormula(f)
+	}
+
+	value := attr(se, odsNSOffice, "value")
+	switch attr(se, odsNSOffice, "value-type") {
+	case "float", "percentage", "currency":
+		if n, err := strconv.ParseFloat(value, 64); err == nil {
+			c.Value = Number(n)
+		}
+	case "date":
+		if n, ok := odsDate(attr(se, odsNSOffice, "date-value")); ok {
+			c.Value = Number(n)
+			code := "yyyy-mm-dd"
+			if n != math.Floor(n) {
+				code += " hh:mm:ss"
+			}
+			c.Style = imp.defaultFormat(c.Style, code)
+		}
+	case "time":
+		if n, ok := odsDuration(attr(se, odsNSOffice, "time-value")); ok {
+			c.Value = Number(n)
+			c.Style = imp.defaultFormat(c.Style, "hh:mm:ss")
+		}
+	case "boolean":
+		c.Value = Bool(attr(se, odsNSOffice, "boolean-value") == "true")
+	case "string":
+		if s := attr(se, odsNSOffice, "string-value"); s != "" {
+			text = s
+		}
+		switch {
+		case attr(se, odsNSCalcExt, "value-type") == "error" || c.Formula != "" && IsErrorCode(text):
+			if !IsErrorCode(text) {
+				text = ErrValue
+			}
+			c.Value = Error(text)
+		case text != "":
+			c.Value = String(text)
+		}
+	}
+	if c.Value.IsEmpty() && c.Formula == "" && c.Style == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// defaultFormat gives a style a number format if it has none.
+func (imp *odsImporter) defaultFormat(idx int, code string) int {
+	st := imp.wb.Styles[idx]
+	if st.NumFmt != "" {
+		return idx
+	}
+	st.NumFmt = code
+	return imp.wb.StyleID(st)
+}
+
+// cellText reads the paragraphs of a cell, one per line.
+func (imp *odsImporter) cellText(d *xml.Decoder, se xml.StartElement) (string, error) {
+	var b strings.Builder
+	paragraphs := 0
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name == se.Name {
+				return b.String(), nil
+			}
+		case xml.CharData:
+			if paragraphs > 0 {
+				b.Write(t)
+			}
+		case xml.StartElement:
+			if w, ok := unsupportedODSElements[t.Name]; ok {
+				imp.warn(Warning{Code: w.code, Message: w.message})
+				imp.skipTo(d, t.Name)
+				continue
+			}
+			if t.Name.Space != odsNSText {
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				if paragraphs++; paragraphs > 1 {
+					b.WriteByte('\n')
+				}
+			case "s":
+				n, err := strconv.Atoi(attr(t, odsNSText, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				b.WriteString(strings.Repeat(" ", min(n, 1000)))
+			case "tab":
+				b.WriteByte('\t')
+			case "line-break":
+				b.WriteByte('\n')
+			}
+		}
+	}
+}
+
+// odsDate converts an ODF date such as "2024-01-31" or
+// "2024-01-31T12:30:00" to a serial number.
+func odsDate(s string) (float64, bool) {
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			n := timeToSerial(t)
+			return n, n >= 0 && n < maxSerial
+		}
+	}
+	return 0, false
+}
+
+// odsDuration converts an ODF time such as "PT12H30M00S" to a fraction
+// of a day.
+func odsDuration(s string) (float64, bool) {
+	rest, ok := strings.CutPrefix(s, "PT")
+	if !ok {
+		return 0, false
+	}
+	secs := 0.0
+	for _, unit := range []struct {
+		suffix string
+		scale  float64
+	}{{"H", 3600}, {"M", 60}, {"S", 1}} {
+		part, after, found := strings.Cut(rest, unit.suffix)
+		if !found {
+			continue
+		}
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs += n * unit.scale
+		rest = after
+	}
+	return secs / 86400, rest == ""
+}
+
+// trimSizes drops column widths past the last column with content, as
+// files give every column of the sheet a width.
+func trimSizes(sh *Sheet) {
+	if sh == nil {
+		return
+	}
+	lastCol := 0
+	for ref := range sh.Cells {
+		lastCol = max(lastCol, ref.Col)
+	}
+	for _, m := range sh.Merges {
+		lastCol = max(lastCol, m.End.Col)
+	}
+	for col := range sh.ColWidths {
+		if col > lastCol {
+			delete(sh.ColWidths, col)
+		}
+	}
+}
+
+func (imp *odsImporter) name(se xml.StartElement, ref string) {
+	name := attr(se, odsNSTable, "name")
+	if name == "" || ref == "" {
+		return
+	}
+	dn := DefinedName{Name: name, Ref: strings.TrimPrefix(ExcelFormula(ref), "=")}
+	if imp.inTable {
+		dn.Sheet = imp.sheet.Name
+	}
+	imp.wb.Names = append(imp.wb.Names, dn)
+}
+
+// ── Formulas ─────────────────────────────────
+
+// ExcelFormula translates an OpenFormula formula, with or without its
+// "of:" namespace prefix, back to Excel syntax: the reverse of
+// OpenFormula. A bare cell range address such as "$Sheet1.$A$1:.$B$2"
+// is translated as a reference.
+func ExcelFormula(formula string) string {
+	if i := strings.Index(formula, ":="); i > 0 && isNamespace(formula[:i]) {
+		formula = formula[i+1:]
+	}
+	if !strings.HasPrefix(formula, "=") {
+		return "=" + odsToExcelRef(formula)
+	}
+
+	var b strings.Builder
+	inArray := false
+	for i := 0; i < len(formula); i++ {
+		switch ch := formula[i]; ch {
+		case '"':
+			end := i + 1
+			for end < len(formula) {
+				if formula[end] == '"' {
+					if end+1 < len(formula) && formula[end+1] == '"' {
+						end += 2
+						continue
+					}
+					break
+				}
+				end++
+			}
+			end = min(end, len(formula)-1)
+			b.WriteString(formula[i : end+1])
+			i = end
+		case '[':
+			end := i + 1
+			quoted := false
+			for end < len(formula) && (quoted || formula[end] != ']') {
+				if formula[end] == '\'' {
+					quoted = !quoted
+				}
+				end++
+			}
+			b.WriteString(odsToExcelRef(formula[i+1 : min(end, len(formula))]))
+			i = end
+		case '{':
+			inArray = true
+			b.WriteByte(ch)
+		case '}':
+			inArray = false
+			b.WriteByte(ch)
+		case ';':
+			b.WriteByte(',')
+		case '|':
+			if inArray {
+				b.WriteByte(';')
+			} else {
+				b.WriteByte(ch)
+			}
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+func isNamespace(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isLetter(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// odsToExcelRef translates the inside of an OpenFormula reference, such
+// as ".A1", "$'My Sheet'.$A$1:.B2" or "Sheet1.A:Sheet1.A", to Excel
+// syntax. Whole rows and columns, which OpenFormula spells out, become
+// "1:3" and "A:C" again.
+func odsToExcelRef(ref string) string {
+	start, end, isRange := cutUnquoted(ref, ':')
+	sheet, startCell := odsRefPart(start)
+	_, endCell := odsRefPart(end)
+	if strings.Contains(ref, "#REF!") || startCell == "" || isRange && endCell == "" {
+		return ErrRef
+	}
+
+	out := startCell
+	if isRange {
+		out = startCell + ":" + endCell
+		s, err1 := ParseRef(startCell)
+		e, err2 := ParseRef(endCell)
+		if err1 == nil && err2 == nil {
+			sc, sr := splitCell(startCell)
+			ec, er := splitCell(endCell)
+			switch {
+			case s.Row == 1 && e.Row == MaxRows:
+				out = sc + ":" + ec
+			case s.Col == 1 && e.Col == MaxCols:
+				out = sr + ":" + er
+			}
+		}
+	}
+	if sheet != "" {
+		return QuoteSheetName(sheet) + "!" + out
+	}
+	return out
+}
+
+// splitCell splits "$A$1" into "$A" and "$1".
+func splitCell(cell string) (col, row string) {
+	i := strings.IndexFunc(strings.TrimPrefix(cell, "$"), func(r rune) bool { return !('A' <= r && r <= 'Z' || 'a' <= r && r <= 'z') })
+	if i < 0 {
+		return cell, ""
+	}
+	i += len(cell) - len(strings.TrimPrefix(cell, "$"))
+	return cell[:i], cell[i:]
+}
+
+// odsRefPart splits "$Sheet1.$A$1" into the sheet name and the cell.
+func odsRefPart(part string) (sheet, cell string) {
+	part = strings.TrimPrefix(part, "$")
+	i := strings.LastIndexByte(part, '.')
+	if i < 0 {
+		return "", part
+	}
+	sheet, cell = part[:i], part[i+1:]
+	if len(sheet) >= 2 && sheet[0] == '\'' && sheet[len(sheet)-1] == '\'' {
+		sheet = strings.ReplaceAll(sheet[1:len(sheet)-1], "''", "'")
+	}
+	return sheet, cell
+}
+
+// cutUnquoted is strings.Cut ignoring separators inside single quotes.
+func cutUnquoted(s string, sep byte) (before, after string, found bool) {
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'':
+			quoted = !quoted
+		case sep:
+			if !quoted {
+				return s[:i], s[i+1:], true
+			}
+		}
+	}
+	return s, "", false
+}
@@ -0,0 +1,147 @@
+package workbook
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Limits of an Excel worksheet.
+const (
+	MaxRows = 1_048_576
+	MaxCols = 16_384
+)
+
+// Ref addresses a cell. Row and Col are 1-based.
+type Ref struct {
+	Row int
+	Col int
+}
+
+// ColumnName converts a 1-based column number to letters (1 → "A", 27 → "AA").
+func ColumnName(col int) string {
+	var b []byte
+	for col > 0 {
+		col--
+		b = append([]byte{byte('A' + col%26)}, b...)
+		col /= 26
+	}
+	return string(b)
+}
+
+// ColumnNumber converts column letters to a 1-based column number.
+func ColumnNumber(name string) (int, error) {
+	if name == "" || len(name) > 3 {
+		return 0, fmt.Errorf("invalid column %q", name)
+	}
+	col := 0
+	for _, r := range strings.ToUpper(name) {
+		if r < 'A' || r > 'Z' {
+			return 0, fmt.Errorf("invalid column %q", name)
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	if col > MaxCols {
+		return 0, fmt.Errorf("column %q out of range", name)
+	}
+	return col, nil
+}
+
+// ParseRef parses an A1-style reference. Absolute markers ("$B$2") are
+// accepted and ignored.
+func ParseRef(s string) (Ref, error) {
+	s = strings.TrimSpace(s)
+	rest := strings.TrimPrefix(s, "$")
+	i := 0
+	for i < len(rest) && (rest[i] >= 'A' && rest[i] <= 'Z' || rest[i] >= 'a' && rest[i] <= 'z') {
+		i++
+	}
+	col, err := ColumnNumber(rest[:i])
+	if err != nil {
+		return Ref{}, fmt.Errorf("invalid cell reference %q", s)
+	}
+	// Atoi alone would take "A+1" or "A-0" for a row.
+	digits := strings.TrimPrefix(rest[i:], "$")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Ref{}, fmt.Errorf("invalid cell reference %q", s)
+	}
+	row, err := strconv.Atoi(digits)
+	if err != nil || row < 1 || row > MaxRows {
+		return Ref{}, fmt.Errorf("invalid cell reference %q", s)
+	}
+	return Ref{Row: row, Col: col}, nil
+}
+
+func (r Ref) String() string {
+	return ColumnName(r.Col) + strconv.Itoa(r.Row)
+}
+
+// MarshalText lets refs be used as JSON object keys ("A1").
+func (r Ref) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Ref) UnmarshalText(b []byte) error {
+	ref, err := ParseRef(string(b))
+	if err != nil {
+		return err
+	}
+	*r = ref
+	return nil
+}
+
+// Range is a rectangular block of cells, inclusive on both ends.
+type Range struct {
+	Start Ref
+	End   Ref
+}
+
+// ParseRange parses "A1:D20" or a single cell "B2". The corners may be
+// given in any order; the result is normalised so Start is top-left.
+func ParseRange(s string) (Range, error) {
+	first, second, found := strings.Cut(s, ":")
+	start, err := ParseRef(first)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range %q", s)
+	}
+	end := start
+	if found {
+		if end, err = ParseRef(second); err != nil {
+			return Range{}, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	return Range{
+		Start: Ref{Row: min(start.Row, end.Row), Col: min(start.Col, end.Col)},
+		End:   Ref{Row: max(start.Row, end.Row), Col: max(start.Col, end.Col)},
+	}, nil
+}
+
+func (r Range) String() string {
+	if r.Start == r.End {
+		return r.Start.String()
+	}
+	return r.Start.String() + ":" + r.End.String()
+}
+
+func (r Range) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Range) UnmarshalText(b []byte) error {
+	rng, err := ParseRange(string(b))
+	if err != nil {
+		return err
+	}
+	*r = rng
+	return nil
+}
+
+// Rows and Cols return the size of the range.
+func (r Range) Rows() int { return r.End.Row - r.Start.Row + 1 }
+func (r Range) Cols() int { return r.End.Col - r.Start.Col + 1 }
+
+// Contains reports whether ref lies inside the range.
+func (r Range) Contains(ref Ref) bool {
+	return ref.Row >= r.Start.Row && ref.Row <= r.End.Row &&
+		ref.Col >= r.Start.Col && ref.Col <= r.End.Col
+}
@@ -0,0 +1,68 @@
+package workbook
+
+import "testing"
+
+func TestParseRef(t *testing.T) {
+	for in, want := range map[string]Ref{
+		"A1":       {Row: 1, Col: 1},
+		"b2":       {Row: 2, Col: 2},
+		"$C$3":     {Row: 3, Col: 3},
+		"$D4":      {Row: 4, Col: 4},
+		"E$5":      {Row: 5, Col: 5},
+		" AA10 ":   {Row: 10, Col: 27},
+		"XFD1":     {Row: 1, Col: MaxCols},
+		"A1048576": {Row: MaxRows, Col: 1},
+		"A01":      {Row: 1, Col: 1},
+	} {
+		got, err := ParseRef(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRef(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+
+	for _, in := range []string{
+		"", "A", "1", "A0", "A-1", "A+1", "A 1", "A1B", "A1.5", "A1e3", "A0x1",
+		"XFE1", "AAAA1", "A1048577", "$$A1", "A$$1", "A1$", "1A", "Ä1",
+	} {
+		if got, err := ParseRef(in); err == nil {
+			t.Errorf("ParseRef(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestRefText(t *testing.T) {
+	for _, a1 := range []string{"A1", "Z9", "AA10", "AZ1", "BA1", "ZZ1", "AAA1", "XFD1048576"} {
+		ref, err := ParseRef(a1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ref.String(); got != a1 {
+			t.Errorf("%q came back as %q", a1, got)
+		}
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	for in, want := range map[string]string{
+		"A1:B2":   "A1:B2",
+		"B2:A1":   "A1:B2", // corners in any order
+		"A2:B1":   "A1:B2",
+		"$A$1:B2": "A1:B2",
+		"C3":      "C3",
+		"C3:C3":   "C3",
+	} {
+		got, err := ParseRange(in)
+		if err != nil || got.String() != want {
+			t.Errorf("ParseRange(%q) = %v, %v, want %s", in, got, err, want)
+		}
+	}
+	if r := mustRange(t, "B2:D5"); r.Rows() != 4 || r.Cols() != 3 {
+		t.Errorf("B2:D5 is %d×%d", r.Rows(), r.Cols())
+	}
+
+	for _, in := range []string{"", ":", "A1:", ":B2", "A1:B2:C3", "A1-B2", "A:B", "1:2", "Sheet1!A1"} {
+		if got, err := ParseRange(in); err == nil {
+			t.Errorf("ParseRange(%q) = %v, want an error", in, got)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package workbook
+
+import (
+	"strconv"
+)
+
+// Kind is the type of a cell value.
+type Kind uint8
+
+const (
+	KindEmpty Kind = iota
+	KindNumber
+	KindString
+	KindBool
+	KindError
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNumber:
+		return "number"
+	case KindString:
+		return "string"
+	case KindBool:
+		return "bool"
+	case KindError:
+		return "error"
+	default:
+		return "empty"
+	}
+}
+
+// Excel error values.
+const (
+	ErrNull  = "#NULL!"
+	ErrDiv0  = "#DIV/0!"
+	ErrValue = "#VALUE!"
+	ErrRef   = "#REF!"
+	ErrName  = "#NAME?"
+	ErrNum   = "#NUM!"
+	ErrNA    = "#N/A"
+	ErrSpill = "#SPILL!"
+	ErrCalc  = "#CALC!"
+)
+
+// IsErrorCode reports whether s is one of the Excel error values.
+func IsErrorCode(s string) bool {
+	switch s {
+	case ErrNull, ErrDiv0, ErrValue, ErrRef, ErrName, ErrNum, ErrNA, ErrSpill, ErrCalc:
+		return true
+	}
+	return false
+}
+
+// Value is a typed cell value. Str holds the text of strings and the code
+// of errors.
+type Value struct {
+	Kind Kind
+	Num  float64
+	Str  string
+	Bool bool
+}
+
+func Number(n float64) Value  { return Value{Kind: KindNumber, Num: n} }
+func String(s string) Value   { return Value{Kind: KindString, Str: s} }
+func Bool(b bool) Value       { return Value{Kind: KindBool, Bool: b} }
+func Error(code string) Value { return Value{Kind: KindError, Str: code} }
+
+func (v Value) IsEmpty() bool { return v.Kind == KindEmpty }
+
+// Text renders the value the way a plain-text export shows it, without
+// number formatting.
+func (v Value) Text() string {
+	switch v.Kind {
+	case KindNumber:
+		return strconv.FormatFloat(v.Num, 'f', -1, 64)
+	case KindString, KindError:
+		return v.Str
+	case KindBool:
+		if v.Bool {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return ""
+	}
+}
+
+// Any returns the value as a plain Go value (float64, string, bool or
+// nil); errors return their code.
+func (v Value) Any() any {
+	switch v.Kind {
+	case KindNumber:
+		return v.Num
+	case KindString, KindError:
+		return v.Str
+	case KindBool:
+		return v.Bool
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,91 @@
+package workbook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseInput interprets a JSON value written to a cell through the values
+// API. Numbers, booleans and strings are stored as such and null or ""
+// clears the cell. Strings starting with "=" are formulas and Excel error
+// codes ("#N/A") are errors; a leading apostrophe keeps either as text.
+func ParseInput(v any) (Value, string, error) {
+	switch v := v.(type) {
+	case nil:
+		return Value{}, "", nil
+	case float64:
+		return Number(v), "", nil
+	case bool:
+		return Bool(v), "", nil
+	case string:
+		switch {
+		case v == "":
+			return Value{}, "", nil
+		case strings.HasPrefix(v, "'"):
+			return String(v[1:]), "", nil
+		case strings.HasPrefix(v, "=") && len(v) > 1:
+			if err := ParseFormula(v); err != nil {
+				return Value{}, "", err
+			}
+			return Value{}, v, nil
+		case IsErrorCode(v):
+			return Error(v), "", nil
+		}
+		return String(v), "", nil
+	}
+	return Value{}, "", fmt.Errorf("unsupported value %v", v)
+}
+
+// SetContent replaces the value and formula at ref, keeping its style.
+func (sh *Sheet) SetContent(ref Ref, value Value, formula string) {
+	c := &Cell{Value: value, Formula: formula}
+	if old := sh.Cells[ref]; old != nil {
+		c.Style = old.Style
+	}
+	sh.Set(ref, c)
+}
+
+// Values returns the cells of rng row by row as plain JSON values (see
+// Value.Any); empty cells are nil. With formulas set, formula cells return
+// their formula instead, and strings are escaped so that writing the
+// result back through ParseInput reproduces the cells.
+func (sh *Sheet) Values(rng Range, formulas bool) [][]any {
+	rows := make([][]any, rng.Rows())
+	for i := range rows {
+		row := make([]any, rng.Cols())
+		for j := range row {
+			c := sh.Cells[Ref{Row: rng.Start.Row + i, Col: rng.Start.Col + j}]
+			switch {
+			case c == nil:
+			case formulas && c.Formula != "":
+				row[j] = c.Formula
+			case formulas && c.Value.Kind == KindString && needsQuote(c.Value.Str):
+				row[j] = "'" + c.Value.Str
+			default:
+				row[j] = c.Value.Any()
+			}
+		}
+		rows[i] = row
+	}
+	return rows
+}
+
+func needsQuote(s string) bool {
+	return strings.HasPrefix(s, "=") || strings.HasPrefix(s, "'") || IsErrorCode(s)
+}
+
+// LastRow returns the last row at or below rng.Start.Row that has content
+// in any of the columns of rng, or 0 if there is none.
+func (sh *Sheet) LastRow(rng Range) int {
+	last := 0
+	for ref, c := range sh.Cells {
+		if ref.Row < rng.Start.Row || ref.Col < rng.Start.Col || ref.Col > rng.End.Col {
+			continue
+		}
+		if c.Value.IsEmpty() && c.Formula == "" {
+			continue
+		}
+		last = max(last, ref.Row)
+	}
+	return last
+}
@@ -0,0 +1,126 @@
+package workbook
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseInput(t *testing.T) {
+	for _, tt := range []struct {
+		in      any
+		value   Value
+		formula string
+	}{
+		{nil, Value{}, ""},
+		{"", Value{}, ""},
+		{1.5, Number(1.5), ""},
+		{float64(0), Number(0), ""},
+		{true, Bool(true), ""},
+		{false, Bool(false), ""},
+		{"text", String("text"), ""},
+		{"42", String("42"), ""}, // strings are never read as numbers
+		{"TRUE", String("TRUE"), ""},
+		{" ", String(" "), ""},
+		{"=", String("="), ""},
+		{"=SUM(A1:A2)", Value{}, "=SUM(A1:A2)"},
+		{"#N/A", Error(ErrNA), ""},
+		{"#DIV/0!", Error(ErrDiv0), ""},
+		{"#n/a", String("#n/a"), ""},
+		{"'=SUM(A1:A2)", String("=SUM(A1:A2)"), ""},
+		{"'#N/A", String("#N/A"), ""},
+		{"'", String(""), ""},
+		{"''quoted", String("'quoted"), ""},
+	} {
+		value, formula, err := ParseInput(tt.in)
+		if err != nil || value != tt.value || formula != tt.formula {
+			t.Errorf("ParseInput(%#v) = %#v, %q, %v; want %#v, %q", tt.in, value, formula, err, tt.value, tt.formula)
+		}
+	}
+
+	for _, in := range []any{"=SUM(", "=1+", map[string]any{"a": 1}, []any{1.0}, 3} {
+		if value, formula, err := ParseInput(in); err == nil {
+			t.Errorf("ParseInput(%#v) = %#v, %q, want an error", in, value, formula)
+		}
+	}
+}
+
+func TestValues(t *testing.T) {
+	wb := New()
+	sh := wb.Sheets[0]
+	setCells(t, sh, map[string]any{
+		"A1": "name", "B1": 2, "C1": "=B1*2",
+		"A2": String("=quoted"), "B2": true, "C2": "#N/A",
+		"A3": Error(ErrDiv0),
+	})
+	wb.RecalculateAll()
+	rng := mustRange(t, "A1:D3")
+
+	got := sh.Values(rng, false)
+	want := [][]any{
+		{"name", 2.0, 4.0, nil},
+		{"=quoted", true, "#N/A", nil},
+		{"#DIV/0!", nil, nil, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("values = %#v, want %#v", got, want)
+	}
+
+	// Formulas, and strings that would read back as something else, are
+	// returned so that writing them back reproduces the cells.
+	got = sh.Values(rng, true)
+	want = [][]any{
+		{"name", 2.0, "=B1*2", nil},
+		{"'=quoted", true, "'#N/A", nil},
+		{"#DIV/0!", nil, nil, nil},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("formulas = %#v, want %#v", got, want)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows [][]any
+	if err := json.Unmarshal(b, &rows); err != nil {
+		t.Fatal(err)
+	}
+	copied := New().Sheets[0]
+	for i, row := range rows {
+		for j, v := range row {
+			value, formula, err := ParseInput(v)
+			if err != nil {
+				t.Fatalf("%v: %v", v, err)
+			}
+			copied.SetContent(Ref{Row: i + 1, Col: j + 1}, value, formula)
+		}
+	}
+	for ref, c := range sh.Cells {
+		cc := copied.Cell(ref)
+		if cc == nil || cc.Formula != c.Formula || (c.Formula == "" && cc.Value != c.Value) {
+			t.Errorf("%s: wrote back %#v, want %#v", ref, cc, c)
+		}
+	}
+}
+
+func TestLastRow(t *testing.T) {
+	sh := New().Sheets[0]
+	if got := sh.LastRow(mustRange(t, "A1:B1")); got != 0 {
+		t.Errorf("empty sheet: %d", got)
+	}
+	setCells(t, sh, map[string]any{"A1": 1, "A3": "=A1", "B5": "x", "D9": 1})
+	ref, _ := ParseRef("A7")
+	sh.Set(ref, &Cell{Style: 1}) // formatted but empty
+
+	for rng, want := range map[string]int{
+		"A1:A1": 3,
+		"A1:B1": 5,
+		"A4:A4": 0, // nothing at or below the start
+		"C1:C1": 0,
+		"A1:D1": 9,
+	} {
+		if got := sh.LastRow(mustRange(t, rng)); got != want {
+			t.Errorf("LastRow(%s) = %d, want %d", rng, got, want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Package workbook is the server's model of a spreadsheet document: sheets
+// of typed cells with formulas and styles. It is stored in
+// Spreadsheet.Data as JSON (see Encode and Decode) and is what imports,
+// exports and the cell APIs work on.
+package workbook
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Workbook is a spreadsheet document.
+type Workbook struct {
+	Sheets []*Sheet `json:"sheets"`
+	// Styles is shared by all cells; Cell.Style indexes into it. Styles[0]
+	// is always the default style.
+	Styles []Style       `json:"styles"`
+	Names  []DefinedName `json:"names,omitempty"`
+
+	styleIndex map[string]int // lazily built by StyleID
+}
+
+// Sheet is a single worksheet.
+type Sheet struct {
+	Name       string          `json:"name"`
+	Cells      map[Ref]*Cell   `json:"cells"`
+	Merges     []Range         `json:"merges,omitempty"`
+	ColWidths  map[int]float64 `json:"col_widths,omitempty"`  // pixels, by 1-based column
+	RowHeights map[int]float64 `json:"row_heights,omitempty"` // pixels, by 1-based row
+	FrozenRows int             `json:"frozen_rows,omitempty"`
+	FrozenCols int             `json:"frozen_cols,omitempty"`
+	Hidden     bool            `json:"hidden,omitempty"`
+}
+
+// Cell holds a value and, for formula cells, the formula that produced it.
+type Cell struct {
+	Value   Value
+	Formula string // including the leading "=", empty for constants
+	Style   int    // index into Workbook.Styles
+}
+
+// Style is the formatting of a cell. Colours are "#RRGGBB".
+type Style struct {
+	Bold      bool    `json:"bold,omitempty"`
+	Italic    bool    `json:"italic,omitempty"`
+	Underline bool    `json:"underline,omitempty"`
+	Strike    bool    `json:"strike,omitempty"`
+	FontName  string  `json:"font_name,omitempty"`
+	FontSize  float64 `json:"font_size,omitempty"`
+	FontColor string  `json:"font_color,omitempty"`
+	Fill      string  `json:"fill,omitempty"`
+	HAlign    string  `json:"h_align,omitempty"` // left, center, right, justify
+	VAlign    string  `json:"v_align,omitempty"` // top, center, bottom
+	Wrap      bool    `json:"wrap,omitempty"`
+	NumFmt    string  `json:"num_fmt,omitempty"` // Excel format code, e.g. "0.00%"
+	Border    *Border `json:"border,omitempty"`
+}
+
+// Border describes the four edges of a cell; nil edges have no border.
+type Border struct {
+	Top    *BorderEdge `json:"top,omitempty"`
+	Right  *BorderEdge `json:"right,omitempty"`
+	Bottom *BorderEdge `json:"bottom,omitempty"`
+	Left   *BorderEdge `json:"left,omitempty"`
+}
+
+type BorderEdge struct {
+	Style string `json:"style"` // thin, medium, thick, dashed, dotted, double
+	Color string `json:"color,omitempty"`
+}
+
+// DefinedName is a named reference such as Rate → Sheet1!$B$1. Sheet is
+// set for names scoped to one sheet.
+type DefinedName struct {
+	Name  string `json:"name"`
+	Ref   string `json:"ref"`
+	Sheet string `json:"sheet,omitempty"`
+}
+
+// New returns a workbook with one empty sheet.
+func New() *Workbook {
+	wb := &Workbook{Styles: []Style{{}}}
+	wb.AddSheet("Sheet1")
+	return wb
+}
+
+// AddSheet appends an empty sheet. The name is made unique if needed.
+func (wb *Workbook) AddSheet(name string) *Sheet {
+	unique := name
+	for i := 2; wb.Sheet(unique) != nil; i++ {
+		unique = fmt.Sprintf("%s (%d)", name, i)
+	}
+	sh := &Sheet{Name: unique}
+	sh.init()
+	wb.Sheets = append(wb.Sheets, sh)
+	return sh
+}
+
+// init allocates the maps of a sheet.
+func (sh *Sheet) init() {
+	if sh.Cells == nil {
+		sh.Cells = map[Ref]*Cell{}
+	}
+	if sh.ColWidths == nil {
+		sh.ColWidths = map[int]float64{}
+	}
+	if sh.RowHeights == nil {
+		sh.RowHeights = map[int]float64{}
+	}
+}
+
+// Sheet finds a sheet by name, ignoring case like Excel does.
+func (wb *Workbook) Sheet(name string) *Sheet {
+	for _, sh := range wb.Sheets {
+		if strings.EqualFold(sh.Name, name) {
+			return sh
+		}
+	}
+	return nil
+}
+
+// StyleID returns the index of style in Styles, adding it if it is new.
+func (wb *Workbook) StyleID(style Style) int {
+	if wb.styleIndex == nil {
+		wb.styleIndex = make(map[string]int, len(wb.Styles))
+		for i := len(wb.Styles) - 1; i >= 0; i-- {
+			wb.styleIndex[styleKey(wb.Styles[i])] = i
+		}
+	}
+	key := styleKey(style)
+	if id, ok := wb.styleIndex[key]; ok {
+		return id
+	}
+	wb.Styles = append(wb.Styles, style)
+	wb.styleIndex[key] = len(wb.Styles) - 1
+	return len(wb.Styles) - 1
+}
+
+// CellStyle returns the style of a cell (the default style for nil).
+func (wb *Workbook) CellStyle(c *Cell) Style {
+	if c == nil || c.Style <= 0 || c.Style >= len(wb.Styles) {
+		return Style{}
+	}
+	return wb.Styles[c.Style]
+}
+
+func styleKey(s Style) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// Cell returns the cell at ref, or nil if it is empty.
+func (sh *Sheet) Cell(ref Ref) *Cell {
+	return sh.Cells[ref]
+}
+
+// Value returns the value at ref (empty if there is no cell).
+func (sh *Sheet) Value(ref Ref) Value {
+	if c := sh.Cells[ref]; c != nil {
+		return c.Value
+	}
+	return Value{}
+}
+
+// Set stores a cell; a cell with no value, formula or style is removed.
+func (sh *Sheet) Set(ref Ref, c *Cell) {
+	if c == nil || (c.Value.IsEmpty() && c.Formula == "" && c.Style == 0) {
+		delete(sh.Cells, ref)
+		return
+	}
+	sh.Cells[ref] = c
+}
+
+// Bounds returns the smallest range containing every non-empty cell.
+func (sh *Sheet) Bounds() (Range, bool) {
+	var r Range
+	found := false
+	for ref, c := range sh.Cells {
+		if c.Value.IsEmpty() && c.Formula == "" {
+			continue
+		}
+		if !found {
+			r = Range{Start: ref, End: ref}
+			found = true
+			continue
+		}
+		r.Start.Row = min(r.Start.Row, ref.Row)
+		r.Start.Col = min(r.Start.Col, ref.Col)
+		r.End.Row = max(r.End.Row, ref.Row)
+		r.End.Col = max(r.End.Col, ref.Col)
+	}
+	return r, found
+}
+
+// Refs returns the refs of all stored cells in row-major order.
+func (sh *Sheet) Refs() []Ref {
+	refs := make([]Ref, 0, len(sh.Cells))
+	for ref := range sh.Cells {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		if refs[i].Row != refs[j].Row {
+			return refs[i].Row < refs[j].Row
+		}
+		return refs[i].Col < refs[j].Col
+	})
+	return refs
+}
+
+// ── JSON ─────────────────────────────────────
+
+// cellJSON is the stored form of a cell: {"v": 1.5} for a number,
+// {"v": "text"}, {"v": true}, {"e": "#N/A"} for errors, plus "f" for the
+// formula and "s" for a non-default style. omitempty only drops a nil V,
+// so 0, "" and false survive.
+type cellJSON struct {
+	V any    `json:"v,omitempty"`
+	E string `json:"e,omitempty"`
+	F string `json:"f,omitempty"`
+	S int    `json:"s,omitempty"`
+}
+
+func (c Cell) MarshalJSON() ([]byte, error) {
+	out := cellJSON{F: c.Formula, S: c.Style}
+	switch c.Value.Kind {
+	case KindNumber:
+		out.V = c.Value.Num
+	case KindString:
+		out.V = c.Value.Str
+	case KindBool:
+		out.V = c.Value.Bool
+	case KindError:
+		out.E = c.Value.Str
+	}
+	return json.Marshal(out)
+}
+
+func (c *Cell) UnmarshalJSON(b []byte) error {
+	var in cellJSON
+	if err := json.Unmarshal(b, &in); err != nil {
+		return err
+	}
+	c.Formula = in.F
+	c.Style = in.S
+	switch v := in.V.(type) {
+	case float64:
+		c.Value = Number(v)
+	case string:
+		c.Value = String(v)
+	case bool:
+		c.Value = Bool(v)
+	case nil:
+		c.Value = Value{}
+	default:
+		return fmt.Errorf("unsupported cell value %v", v)
+	}
+	if in.E != "" {
+		c.Value = Error(in.E)
+	}
+	return nil
+}
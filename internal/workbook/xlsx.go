@@ -0,0 +1,493 @@
+package workbook
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// MaxImportCells caps the size of an imported workbook. The whole document
+// is stored in one column, so anything bigger would make every load and
+// save of the spreadsheet slow.
+const MaxImportCells = 250_000
+
+// ErrTooLarge is returned when an import exceeds MaxImportCells.
+var ErrTooLarge = fmt.Errorf("workbook has more than %d cells", MaxImportCells)
+
+// ErrInvalidFile is returned for files that can't be read in the format
+// they were imported as.
+var ErrInvalidFile = errors.New("file is not a valid spreadsheet")
+
+// Warning describes something in an imported file that Grids could not
+// bring across, or only approximately.
+type Warning struct {
+	Code    string `json:"code"`
+	Sheet   string `json:"sheet,omitempty"`
+	Ref     string `json:"ref,omitempty"`
+	Message string `json:"message"`
+}
+
+// Warning codes.
+const (
+	WarnCharts                = "charts"
+	WarnImages                = "images"
+	WarnPivotTables           = "pivot_tables"
+	WarnMacros                = "macros"
+	WarnComments              = "comments"
+	WarnTables                = "tables"
+	WarnExternalLinks         = "external_links"
+	WarnMergedCells           = "merged_cells"
+	WarnDataValidation        = "data_validation"
+	WarnConditionalFormatting = "conditional_formatting"
+	WarnGradientFill          = "gradient_fill"
+	WarnPatternFill           = "pattern_fill"
+	WarnNumberFormats         = "number_formats"
+	WarnEncoding              = "encoding"
+)
+
+// unsupportedParts maps package part prefixes to the warning raised when
+// a file contains them.
+var unsupportedParts = []struct {
+	prefix  string
+	code    string
+	message string
+}{
+	{"xl/charts/", WarnCharts, "Charts were not imported"},
+	{"xl/media/", WarnImages, "Images and drawings were not imported"},
+	{"xl/pivotTables/", WarnPivotTables, "Pivot tables were imported as plain values"},
+	{"xl/vbaProject.bin", WarnMacros, "Macros were removed"},
+	{"xl/comments", WarnComments, "Cell comments were not imported"},
+	{"xl/threadedComments/", WarnComments, "Cell comments were not imported"},
+	{"xl/tables/", WarnTables, "Excel tables were imported as plain ranges"},
+	{"xl/externalLinks/", WarnExternalLinks, "Links to other workbooks were imported with their last cached values"},
+}
+
+// ReadXLSX converts an Excel workbook. Values, formulas, styles, merged
+// cells, column widths, row heights, frozen panes and defined names are
+// kept; everything else is reported as a warning.
+func ReadXLSX(r io.Reader) (*Workbook, []Warning, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read xlsx: %w", err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, nil, ErrInvalidFile
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{
+		UnzipSizeLimit:    512 << 20,
+		UnzipXMLSizeLimit: 64 << 20,
+	})
+	if err != nil {
+		return nil, nil, ErrInvalidFile
+	}
+	defer f.Close()
+
+	imp := &xlsxImporter{
+		f:      f,
+		wb:     &Workbook{Styles: []Style{{}}},
+		styles: map[int]int{0: 0},
+		warned: map[string]bool{},
+	}
+	imp.partWarnings(zr)
+	imp.defaultFont()
+
+	for _, name := range f.GetSheetList() {
+		if err := imp.sheet(name); err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(imp.wb.Sheets) == 0 {
+		return nil, nil, ErrInvalidFile
+	}
+	imp.definedNames()
+
+	if imp.warnings == nil {
+		imp.warnings = []Warning{}
+	}
+	return imp.wb, imp.warnings, nil
+}
+
+type xlsxImporter struct {
+	f        *excelize.File
+	wb       *Workbook
+	styles   map[int]int // excelize style ID → Workbook.Styles index
+	warnings []Warning
+	warned   map[string]bool // codes already reported without a location
+	cells    int
+
+	fontName string
+	fontSize float64
+}
+
+func (imp *xlsxImporter) warn(w Warning) {
+	if w.Sheet == "" && w.Ref == "" {
+		if imp.warned[w.Code] {
+			return
+		}
+		imp.warned[w.Code] = true
+	}
+	imp.warnings = append(imp.warnings, w)
+}
+
+func (imp *xlsxImporter) partWarnings(zr *zip.Reader) {
+	for _, file := range zr.File {
+		for _, p := range unsupportedParts {
+			if strings.HasPrefix(file.Name, p.prefix) {
+				imp.warn(Warning{Code: p.code, Message: p.message})
+			}
+		}
+	}
+}
+
+// defaultFont records the workbook's base font so that cells using it
+// don't get an explicit font name and size.
+func (imp *xlsxImporter) defaultFont() {
+	imp.fontName, _ = imp.f.GetDefaultFont()
+	imp.fontSize = 11
+	if st, err := imp.f.GetStyle(0); err == nil && st.Font != nil && st.Font.Size > 0 {
+		imp.fontSize = st.Font.Size
+	}
+}
+
+func (imp *xlsxImporter) sheet(name string) error {
+	f := imp.f
+	sh := imp.wb.AddSheet(name)
+	if visible, err := f.GetSheetVisible(name); err == nil && !visible {
+		sh.Hidden = true
+	}
+
+	defaultHeight := 15.0
+	if props, err := f.GetSheetProps(name); err == nil && props.DefaultRowHeight != nil && *props.DefaultRowHeight > 0 {
+		defaultHeight = *props.DefaultRowHeight
+	}
+
+	rows, err := f.Rows(name)
+	if err != nil {
+		// Chart sheets and dialog sheets have no cells.
+		return nil
+	}
+	defer rows.Close()
+
+	maxCol := 0
+	for row := 1; rows.Next(); row++ {
+		if opts := rows.GetRowOpts(); opts.Height > 0 && opts.Height != defaultHeight {
+			sh.RowHeights[row] = math.Round(opts.Height * 4 / 3)
+		}
+		cols, err := rows.Columns(excelize.Options{RawCellValue: true})
+		if err != nil {
+			return fmt.Errorf("read sheet %q: %w", name, err)
+		}
+		for i, raw := range cols {
+			ref := Ref{Row: row, Col: i + 1}
+			c, err := imp.cell(name, ref, raw)
+			if err != nil {
+				return err
+			}
+			if c == nil {
+				continue
+			}
+			if imp.cells++; imp.cells > MaxImportCells {
+				return ErrTooLarge
+			}
+			sh.Set(ref, c)
+			maxCol = max(maxCol, ref.Col)
+		}
+	}
+	if err := rows.Error(); err != nil {
+		return fmt.Errorf("read sheet %q: %w", name, err)
+	}
+
+	imp.merges(sh)
+	for _, m := range sh.Merges {
+		maxCol = max(maxCol, m.End.Col)
+	}
+	imp.columnWidths(sh, maxCol)
+	imp.panes(sh)
+	imp.sheetWarnings(sh)
+	return nil
+}
+
+// cell reads one cell. It returns nil for cells with nothing worth
+// keeping.
+func (imp *xlsxImporter) cell(sheet string, ref Ref, raw string) (*Cell, error) {
+	f := imp.f
+	axis := ref.String()
+
+	styleID, err := f.GetCellStyle(sheet, axis)
+	if err != nil {
+		return nil, fmt.Errorf("read %s!%s: %w", sheet, axis, err)
+	}
+	formula, err := f.GetCellFormula(sheet, axis)
+	if err != nil {
+		return nil, fmt.Errorf("read %s!%s: %w", sheet, axis, err)
+	}
+	if raw == "" && formula == "" && styleID == 0 {
+		return nil, nil
+	}
+
+	c := &Cell{Style: imp.style(styleID)}
+	if formula != "" {
+		c.Formula = "=" + formula
+	}
+	if raw == "" {
+		return c, nil
+	}
+
+	typ, err := f.GetCellType(sheet, axis)
+	if err != nil {
+		return nil, fmt.Errorf("read %s!%s: %w", sheet, axis, err)
+	}
+	switch typ {
+	case excelize.CellTypeBool:
+		c.Value = Bool(raw == "1" || strings.EqualFold(raw, "true"))
+	case excelize.CellTypeError:
+		c.Value = Error(raw)
+	case excelize.CellTypeUnset, excelize.CellTypeNumber:
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			c.Value = Number(n)
+		} else {
+			c.Value = String(raw)
+		}
+	default:
+		c.Value = String(raw)
+	}
+	return c, nil
+}
+
+// style converts an excelize style, caching the result per style ID.
+func (imp *xlsxImporter) style(id int) int {
+	if idx, ok := imp.styles[id]; ok {
+		return idx
+	}
+	src, err := imp.f.GetStyle(id)
+	if err != nil {
+		imp.styles[id] = 0
+		return 0
+	}
+
+	var st Style
+	if font := src.Font; font != nil {
+		st.Bold = font.Bold
+		st.Italic = font.Italic
+		st.Underline = font.Underline != "" && font.Underline != "none"
+		st.Strike = font.Strike
+		if font.Family != "" && font.Family != imp.fontName {
+			st.FontName = font.Family
+		}
+		if font.Size > 0 && font.Size != imp.fontSize {
+			st.FontSize = font.Size
+		}
+		st.FontColor = hexColor(imp.f.GetBaseColor(font.Color, font.ColorIndexed, font.ColorTheme))
+		if st.FontColor == "#000000" {
+			st.FontColor = ""
+		}
+	}
+
+	switch src.Fill.Type {
+	case "pattern":
+		if src.Fill.Pattern > 0 && len(src.Fill.Color) > 0 {
+			st.Fill = hexColor(src.Fill.Color[0])
+			if src.Fill.Pattern > 1 {
+				imp.warn(Warning{Code: WarnPatternFill, Message: "Patterned fills were imported as solid colours"})
+			}
+		}
+	case "gradient":
+		if len(src.Fill.Color) > 0 {
+			st.Fill = hexColor(src.Fill.Color[0])
+		}
+		imp.warn(Warning{Code: WarnGradientFill, Message: "Gradient fills were imported as solid colours"})
+	}
+
+	if a := src.Alignment; a != nil {
+		switch a.Horizontal {
+		case "left", "center", "right", "justify":
+			st.HAlign = a.Horizontal
+		case "centerContinuous":
+			st.HAlign = "center"
+		}
+		switch a.Vertical {
+		case "top", "center", "bottom":
+			st.VAlign = a.Vertical
+		}
+		st.Wrap = a.WrapText
+	}
+
+	if src.CustomNumFmt != nil {
+		st.NumFmt = *src.CustomNumFmt
+	} else {
+		st.NumFmt = builtinNumFmts[src.NumFmt]
+	}
+
+	var border Border
+	for _, b := range src.Border {
+		if b.Style <= 0 {
+			continue
+		}
+		edge := &BorderEdge{Style: borderStyle(b.Style), Color: hexColor(b.Color)}
+		switch b.Type {
+		case "top":
+			border.Top = edge
+		case "right":
+			border.Right = edge
+		case "bottom":
+			border.Bottom = edge
+		case "left":
+			border.Left = edge
+		}
+	}
+	if border != (Border{}) {
+		st.Border = &border
+	}
+
+	idx := imp.wb.StyleID(st)
+	imp.styles[id] = idx
+	return idx
+}
+
+// borderStyle maps excelize's border style numbers onto the styles the
+// editor can draw.
+func borderStyle(n int) string {
+	switch n {
+	case 2, 8, 10, 12: // medium, mediumDashed, mediumDashDot, mediumDashDotDot
+		return "medium"
+	case 3, 9, 11, 13: // dashed, dashDot, dashDotDot, slantDashDot
+		return "dashed"
+	case 4, 7: // dotted, hair
+		return "dotted"
+	case 5:
+		return "thick"
+	case 6:
+		return "double"
+	default:
+		return "thin"
+	}
+}
+
+// hexColor normalises an ARGB or RGB hex string to "#RRGGBB".
+func hexColor(s string) string {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 8 {
+		s = s[2:]
+	}
+	if len(s) != 6 {
+		return ""
+	}
+	if _, err := strconv.ParseUint(s, 16, 32); err != nil {
+		return ""
+	}
+	return "#" + strings.ToUpper(s)
+}
+
+func (imp *xlsxImporter) merges(sh *Sheet) {
+	merged, err := imp.f.GetMergeCells(sh.Name)
+	if err != nil {
+		return
+	}
+	for _, m := range merged {
+		rng, err := ParseRange(m.GetStartAxis() + ":" + m.GetEndAxis())
+		if err != nil {
+			continue
+		}
+		sh.Merges = append(sh.Merges, rng)
+		// Rows skips empty cells at the end of a row, but an empty merged
+		// cell is often there only for its style.
+		if sh.Cell(rng.Start) == nil {
+			if id, err := imp.f.GetCellStyle(sh.Name, rng.Start.String()); err == nil && id != 0 {
+				if idx := imp.style(id); idx != 0 {
+					sh.Set(rng.Start, &Cell{Style: idx})
+				}
+			}
+		}
+	}
+	if len(sh.Merges) > 0 {
+		imp.warn(Warning{
+			Code:    WarnMergedCells,
+			Sheet:   sh.Name,
+			Message: fmt.Sprintf("%d merged %s kept for export but shown unmerged in the editor", len(sh.Merges), plural(len(sh.Merges), "range is", "ranges are")),
+		})
+	}
+}
+
+// columnWidths records every column up to maxCol whose width differs from
+// the sheet default. Widths are converted from characters to pixels the
+// way Excel does for its default font.
+func (imp *xlsxImporter) columnWidths(sh *Sheet, maxCol int) {
+	defaultWidth := 9.140625
+	if props, err := imp.f.GetSheetProps(sh.Name); err == nil && props.DefaultColWidth != nil && *props.DefaultColWidth > 0 {
+		defaultWidth = *props.DefaultColWidth
+	}
+	for col := 1; col <= maxCol; col++ {
+		w, err := imp.f.GetColWidth(sh.Name, ColumnName(col))
+		if err != nil || w == defaultWidth {
+			continue
+		}
+		sh.ColWidths[col] = math.Round(w*7 + 5)
+	}
+}
+
+func (imp *xlsxImporter) panes(sh *Sheet) {
+	panes, err := imp.f.GetPanes(sh.Name)
+	if err != nil || !panes.Freeze {
+		return
+	}
+	sh.FrozenCols = panes.XSplit
+	sh.FrozenRows = panes.YSplit
+}
+
+func (imp *xlsxImporter) sheetWarnings(sh *Sheet) {
+	if dv, err := imp.f.GetDataValidations(sh.Name); err == nil {
+		for _, v := range dv {
+			imp.warn(Warning{
+				Code:    WarnDataValidation,
+				Sheet:   sh.Name,
+				Ref:     v.Sqref,
+				Message: "Data validation was not imported",
+			})
+		}
+	}
+	if cf, err := imp.f.GetConditionalFormats(sh.Name); err == nil {
+		refs := make([]string, 0, len(cf))
+		for ref := range cf {
+			refs = append(refs, ref)
+		}
+		sort.Strings(refs)
+		for _, ref := range refs {
+			imp.warn(Warning{
+				Code:    WarnConditionalFormatting,
+				Sheet:   sh.Name,
+				Ref:     ref,
+				Message: "Conditional formatting was not imported",
+			})
+		}
+	}
+}
+
+func (imp *xlsxImporter) definedNames() {
+	for _, dn := range imp.f.GetDefinedName() {
+		// Names Excel manages itself (print areas, filters) start with "_xlnm.".
+		if strings.HasPrefix(dn.Name, "_xlnm.") {
+			continue
+		}
+		name := DefinedName{Name: dn.Name, Ref: strings.TrimPrefix(dn.RefersTo, "=")}
+		if dn.Scope != "" && dn.Scope != "Workbook" {
+			name.Sheet = dn.Scope
+		}
+		imp.wb.Names = append(imp.wb.Names, name)
+	}
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
@@ -0,0 +1,239 @@
+package workbook
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// WriteXLSX writes the workbook as an Excel file. Formula cells carry
+// their last computed value, and Excel is asked to recalculate on open.
+func WriteXLSX(w io.Writer, wb *Workbook) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles := make([]int, len(wb.Styles))
+	for i, st := range wb.Styles {
+		if i == 0 {
+			continue
+		}
+		id, err := f.NewStyle(excelStyle(st))
+		if err != nil {
+			return fmt.Errorf("write xlsx: style %d: %w", i, err)
+		}
+		styles[i] = id
+	}
+
+	for i, sh := range wb.Sheets {
+		if i == 0 {
+			if err := f.SetSheetName("Sheet1", sh.Name); err != nil {
+				return fmt.Errorf("write xlsx: %w", err)
+			}
+		} else if _, err := f.NewSheet(sh.Name); err != nil {
+			return fmt.Errorf("write xlsx: %w", err)
+		}
+		if err := writeXLSXSheet(f, sh, styles); err != nil {
+			return fmt.Errorf("write xlsx: sheet %q: %w", sh.Name, err)
+		}
+	}
+	// Hide sheets last: Excel refuses to hide the only visible sheet.
+	for _, sh := range wb.Sheets {
+		if sh.Hidden {
+			_ = f.SetSheetVisible(sh.Name, false)
+		}
+	}
+
+	for _, dn := range wb.Names {
+		scope := "Workbook"
+		if dn.Sheet != "" {
+			scope = dn.Sheet
+		}
+		if err := f.SetDefinedName(&excelize.DefinedName{Name: dn.Name, RefersTo: dn.Ref, Scope: scope}); err != nil {
+			return fmt.Errorf("write xlsx: name %q: %w", dn.Name, err)
+		}
+	}
+
+	recalc := true
+	if err := f.SetCalcProps(&excelize.CalcPropsOptions{FullCalcOnLoad: &recalc}); err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write xlsx: %w", err)
+	}
+	return nil
+}
+
+func writeXLSXSheet(f *excelize.File, sh *Sheet, styles []int) error {
+	sw, err := f.NewStreamWriter(sh.Name)
+	if err != nil {
+		return err
+	}
+
+	// Column widths and panes must be set before the first row.
+	for _, col := range sortedKeys(sh.ColWidths) {
+		if err := sw.SetColWidth(col, col, max(sh.ColWidths[col]-5, 0)/7); err != nil {
+			return err
+		}
+	}
+	if sh.FrozenRows > 0 || sh.FrozenCols > 0 {
+		pane := "bottomRight"
+		switch {
+		case sh.FrozenCols == 0:
+			pane = "bottomLeft"
+		case sh.FrozenRows == 0:
+			pane = "topRight"
+		}
+		if err := sw.SetPanes(&excelize.Panes{
+			Freeze:      true,
+			XSplit:      sh.FrozenCols,
+			YSplit:      sh.FrozenRows,
+			TopLeftCell: Ref{Row: sh.FrozenRows + 1, Col: sh.FrozenCols + 1}.String(),
+			ActivePane:  pane,
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Group cells by row. Rows that only have a custom height still need
+	// a row element.
+	rows := map[int][]Ref{}
+	for _, ref := range sh.Refs() {
+		rows[ref.Row] = append(rows[ref.Row], ref)
+	}
+	for row := range sh.RowHeights {
+		if _, ok := rows[row]; !ok {
+			rows[row] = nil
+		}
+	}
+	order := make([]int, 0, len(rows))
+	for row := range rows {
+		order = append(order, row)
+	}
+	sort.Ints(order)
+
+	for _, row := range order {
+		refs := rows[row]
+		var values []any
+		if len(refs) > 0 {
+			values = make([]any, refs[len(refs)-1].Col)
+		}
+		for _, ref := range refs {
+			values[ref.Col-1] = excelCell(sh.Cells[ref], styles)
+		}
+		var opts []excelize.RowOpts
+		if height, ok := sh.RowHeights[row]; ok {
+			opts = append(opts, excelize.RowOpts{Height: height * 3 / 4})
+		}
+		if err := sw.SetRow(Ref{Row: row, Col: 1}.String(), values, opts...); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range sh.Merges {
+		if err := sw.MergeCell(m.Start.String(), m.End.String()); err != nil {
+			return err
+		}
+	}
+	return sw.Flush()
+}
+
+// excelCell converts a cell for the stream writer, which can't write
+// error values: a formula that evaluated to an error is written without
+// its value, and an error constant as a formula of just the error, so
+// both show the error once Excel recalculates.
+func excelCell(c *Cell, styles []int) excelize.Cell {
+	out := excelize.Cell{Formula: strings.TrimPrefix(c.Formula, "=")}
+	if c.Style > 0 && c.Style < len(styles) {
+		out.StyleID = styles[c.Style]
+	}
+	switch c.Value.Kind {
+	case KindNumber:
+		out.Value = c.Value.Num
+	case KindBool:
+		out.Value = c.Value.Bool
+	case KindString:
+		out.Value = c.Value.Str
+	case KindError:
+		if out.Formula == "" {
+			out.Formula = c.Value.Str
+		}
+	}
+	return out
+}
+
+func excelStyle(st Style) *excelize.Style {
+	out := &excelize.Style{}
+	if st.Bold || st.Italic || st.Underline || st.Strike || st.FontName != "" || st.FontSize > 0 || st.FontColor != "" {
+		out.Font = &excelize.Font{
+			Bold:   st.Bold,
+			Italic: st.Italic,
+			Strike: st.Strike,
+			Family: st.FontName,
+			Size:   st.FontSize,
+			Color:  strings.TrimPrefix(st.FontColor, "#"),
+		}
+		if st.Underline {
+			out.Font.Underline = "single"
+		}
+	}
+	if st.Fill != "" {
+		out.Fill = excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{strings.TrimPrefix(st.Fill, "#")}}
+	}
+	if st.HAlign != "" || st.VAlign != "" || st.Wrap {
+		out.Alignment = &excelize.Alignment{Horizontal: st.HAlign, Vertical: st.VAlign, WrapText: st.Wrap}
+	}
+	if st.NumFmt != "" {
+		if id := builtinNumFmtID(st.NumFmt); id > 0 {
+			out.NumFmt = id
+		} else {
+			code := st.NumFmt
+			out.CustomNumFmt = &code
+		}
+	}
+	if b := st.Border; b != nil {
+		for _, e := range []struct {
+			side string
+			edge *BorderEdge
+		}{{"top", b.Top}, {"right", b.Right}, {"bottom", b.Bottom}, {"left", b.Left}} {
+			if e.edge == nil {
+				continue
+			}
+			out.Border = append(out.Border, excelize.Border{
+				Type:  e.side,
+				Style: excelBorderStyle(e.edge.Style),
+				Color: strings.TrimPrefix(e.edge.Color, "#"),
+			})
+		}
+	}
+	return out
+}
+
+// excelBorderStyle is the inverse of borderStyle.
+func excelBorderStyle(s string) int {
+	switch s {
+	case "medium":
+		return 2
+	case "dashed":
+		return 3
+	case "dotted":
+		return 4
+	case "thick":
+		return 5
+	case "double":
+		return 6
+	default:
+		return 1
+	}
+}
+
+func sortedKeys(m map[int]float64) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
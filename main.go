@@ -1,15 +1,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"jaggle-grids/internal/handler"
+	"jaggle-grids/internal/mail"
 	"jaggle-grids/internal/middleware"
+	"jaggle-grids/internal/realtime"
+	"jaggle-grids/internal/repository/gormrepo"
+	"jaggle-grids/internal/repository/postgres"
 	"jaggle-grids/internal/repository/sqlite"
 	"jaggle-grids/internal/service"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // Version is set at build time via -ldflags.
@@ -18,31 +28,197 @@ var Version = "dev"
 func main() {
 	// ── Config ────────────────────────────────
 	port := envOr("PORT", "8080")
+	dbDriver := envOr("DB_DRIVER", "sqlite")
 	dbPath := envOr("DB_PATH", "jaggle_grids.db")
+	dbDSN := os.Getenv("DB_DSN")
+	dbAutoMigrate := envBool("DB_AUTO_MIGRATE", true)
+	searchAllowLike := envBool("SEARCH_ALLOW_LIKE", false)
 	corsOrigin := envOr("CORS_ORIGIN", "http://localhost:5173")
+	trustedProxies := envList("TRUSTED_PROXIES")
+	authMode := envOr("AUTH_MODE", "password")
+	oidcCfg := service.OIDCConfig{
+		IssuerURL:    os.Getenv("OIDC_ISSUER_URL"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  envOr("OIDC_REDIRECT_URL", "http://localhost:8080/api/auth/oidc/callback"),
+	}
+	oidcPostLogin := envOr("OIDC_POST_LOGIN_REDIRECT", "/login")
+	revisionRetention := service.RevisionRetention{
+		KeepAll:    envDuration("REVISION_KEEP_ALL", service.DefaultRevisionRetention.KeepAll),
+		KeepHourly: envDuration("REVISION_KEEP_HOURLY", service.DefaultRevisionRetention.KeepHourly),
+	}
+	revisionWindow := envDuration("REVISION_MERGE_WINDOW", service.DefaultRevisionWindow)
+	trashRetention := envDuration("TRASH_RETENTION", service.DefaultTrashRetention)
+	notificationRetention := envDuration("NOTIFICATION_RETENTION", service.DefaultNotificationRetention)
+	sessionPolicy := service.SessionPolicy{
+		TTL:         envDuration("SESSION_TTL", service.DefaultSessionPolicy.TTL),
+		MaxLifetime: envDuration("SESSION_MAX_LIFETIME", service.DefaultSessionPolicy.MaxLifetime),
+	}
+	realtimeFlush := envDuration("REALTIME_FLUSH_INTERVAL", 2*time.Second)
+	appURL := envOr("APP_URL", corsOrigin)
+	mailDriver := envOr("MAIL_DRIVER", "log")
+	mailFrom := envOr("MAIL_FROM", "Jaggle Grids <grids@localhost>")
+	webhookNetworks, err := service.ParseAllowedNetworks(os.Getenv("WEBHOOK_ALLOWED_NETWORKS"))
+	if err != nil {
+		log.Fatalf("Invalid WEBHOOK_ALLOWED_NETWORKS: %v", err)
+	}
 
 	if os.Getenv("GIN_MODE") == "release" {
 		gin.SetMode(gin.ReleaseMode)
 	}
+	if authMode != "password" && authMode != "mock" {
+		log.Fatalf("Invalid AUTH_MODE %q: expected \"password\" or \"mock\"", authMode)
+	}
+	if sessionPolicy.TTL <= 0 || sessionPolicy.MaxLifetime <= 0 {
+		log.Fatal("SESSION_TTL and SESSION_MAX_LIFETIME must be positive")
+	}
+	if authMode == "mock" {
+		log.Println("WARNING: AUTH_MODE=mock, anyone can sign in as any email without a password")
+	}
 
 	// ── Database ──────────────────────────────
-	db := sqlite.Open(dbPath)
+	var db *gorm.DB
+	switch dbDriver {
+	case "sqlite":
+		if dbDSN != "" {
+			dbPath = dbDSN
+		}
+		db = sqlite.Open(dbPath)
+	case "postgres":
+		if dbDSN == "" {
+			log.Fatal("DB_DSN is required when DB_DRIVER=postgres")
+		}
+		db = postgres.Open(dbDSN)
+	default:
+		log.Fatalf("Invalid DB_DRIVER %q: expected \"sqlite\" or \"postgres\"", dbDriver)
+	}
+
+	if len(os.Args) > 1 {
+		if os.Args[1] != "migrate" {
+			log.Fatalf("Unknown command %q: expected \"migrate\"", os.Args[1])
+		}
+		os.Exit(runMigrate(db, os.Args[2:]))
+	}
+
+	if dbAutoMigrate {
+		applied, err := gormrepo.MigrateUp(db)
+		if err != nil {
+			log.Fatal("Failed to run migrations:", err)
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %d (%s)", m.Version, m.Name)
+		}
+	} else {
+		pending, err := gormrepo.PendingMigrations(db)
+		if err != nil {
+			log.Fatal("Failed to check migrations:", err)
+		}
+		if len(pending) > 0 {
+			log.Fatalf("Database schema is out of date (%d pending migrations): run \"jaggle-grids migrate up\"", len(pending))
+		}
+	}
+	log.Println("Database initialized successfully")
+
+	// ── Mail ──────────────────────────────────
+	mailer, err := newMailer(mailDriver, mailFrom)
+	if err != nil {
+		log.Fatal("Failed to initialise mail:", err)
+	}
 
 	// ── Repositories ──────────────────────────
-	userRepo := sqlite.NewUserRepo(db)
-	sessionRepo := sqlite.NewSessionRepo(db)
-	sheetRepo := sqlite.NewSpreadsheetRepo(db)
+	userRepo := gormrepo.NewUserRepo(db)
+	sessionRepo := gormrepo.NewSessionRepo(db)
+	tokenRepo := gormrepo.NewAPITokenRepo(db)
+	resetRepo := gormrepo.NewPasswordResetRepo(db)
+	sheetRepo := gormrepo.NewSpreadsheetRepo(db)
+	permissionRepo := gormrepo.NewPermissionRepo(db)
+	revisionRepo := gormrepo.NewRevisionRepo(db)
+	orgRepo := gormrepo.NewOrganizationRepo(db)
+	workspaceRepo := gormrepo.NewWorkspaceRepo(db)
+	folderRepo := gormrepo.NewFolderRepo(db)
+	shareLinkRepo := gormrepo.NewShareLinkRepo(db)
+	commentRepo := gormrepo.NewCommentRepo(db)
+	notificationRepo := gormrepo.NewNotificationRepo(db)
+	emailRepo := gormrepo.NewEmailRepo(db)
+	webhookRepo := gormrepo.NewWebhookRepo(db)
+	searchRepo, err := gormrepo.NewSearchRepo(db)
+	if err != nil {
+		log.Fatal("Failed to initialise search:", err)
+	}
+	switch {
+	case searchRepo.FullText():
+		log.Println("Search uses the full-text index")
+	case dbDriver == "sqlite" && !searchAllowLike:
+		// Without FTS5 every search scans all indexed cells with LIKE, which
+		// is easy to miss until the database has grown.
+		log.Fatal("SQLite was built without FTS5: build with -tags sqlite_fts5 (as make build and the Docker image do), or set SEARCH_ALLOW_LIKE=true to search with LIKE")
+	case dbDriver == "sqlite":
+		log.Println("WARNING: SQLite was built without FTS5, search scans every indexed cell with LIKE; build with -tags sqlite_fts5")
+	}
 
 	// ── Services ──────────────────────────────
-	authSvc := service.NewAuthService(userRepo, sessionRepo)
-	sheetSvc := service.NewSpreadsheetService(sheetRepo)
+	emailSvc := service.NewEmailService(emailRepo, mailer, appURL)
+	notifySvc := service.NewNotificationService(notificationRepo, userRepo, emailSvc)
+	webhookSvc := service.NewWebhookService(webhookRepo, sheetRepo, permissionRepo, workspaceRepo, orgRepo, userRepo, webhookNetworks)
+	sheetSvc := service.NewSpreadsheetService(sheetRepo, permissionRepo, revisionRepo, userRepo, workspaceRepo, orgRepo, folderRepo, searchRepo, commentRepo, notifySvc, webhookSvc, revisionWindow)
+	authSvc := service.NewAuthService(userRepo, sessionRepo, tokenRepo, resetRepo, sheetSvc, emailSvc, sessionPolicy, authMode == "mock")
+	orgSvc := service.NewOrganizationService(orgRepo, workspaceRepo, sheetRepo, userRepo)
+	folderSvc := service.NewFolderService(folderRepo, workspaceRepo, orgRepo, sheetRepo)
+	linkSvc := service.NewShareLinkService(shareLinkRepo, sheetSvc)
+
+	var oidcSvc *service.OIDCService
+	if oidcCfg.IssuerURL != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		svc, err := service.NewOIDCService(ctx, oidcCfg, userRepo, authSvc)
+		cancel()
+		if err != nil {
+			log.Fatal("Failed to initialise OIDC:", err)
+		}
+		oidcSvc = svc
+		log.Printf("OIDC single sign-on enabled (issuer %s)", oidcCfg.IssuerURL)
+	}
+
+	hub := realtime.NewHub(sheetSvc, realtimeFlush)
+
+	// ── Background jobs ───────────────────────
+	go service.RunEvery(context.Background(), time.Hour, "Revision pruning", func(ctx context.Context) error {
+		return sheetSvc.PruneRevisions(ctx, revisionRetention)
+	})
+	go service.RunEvery(context.Background(), time.Hour, "Trash purge", func(ctx context.Context) error {
+		return sheetSvc.PurgeTrash(ctx, trashRetention)
+	})
+	go service.RunEvery(context.Background(), time.Hour, "Session cleanup", authSvc.PurgeSessions)
+	go service.RunEvery(context.Background(), time.Hour, "Password link cleanup", authSvc.PurgePasswordResets)
+	go service.RunEvery(context.Background(), time.Hour, "Search indexing", sheetSvc.IndexSpreadsheets)
+	go service.RunEvery(context.Background(), time.Hour, "Notification cleanup", func(ctx context.Context) error {
+		return notifySvc.PurgeRead(ctx, notificationRetention)
+	})
+	go service.RunEvery(context.Background(), 15*time.Second, "Email delivery", emailSvc.Deliver)
+	go service.RunEvery(context.Background(), time.Hour, "Email cleanup", func(ctx context.Context) error {
+		return emailSvc.PurgeEmails(ctx, service.DefaultEmailRetention)
+	})
+	go service.RunEvery(context.Background(), 5*time.Second, "Webhook delivery", webhookSvc.Deliver)
+	go service.RunEvery(context.Background(), time.Hour, "Webhook log cleanup", func(ctx context.Context) error {
+		return webhookSvc.PurgeDeliveries(ctx, service.DefaultWebhookLogRetention)
+	})
 
 	// ── Handlers ──────────────────────────────
-	authHandler := handler.NewAuthHandler(authSvc)
+	authHandler := handler.NewAuthHandler(authSvc, oidcSvc, oidcPostLogin)
 	sheetHandler := handler.NewSpreadsheetHandler(sheetSvc)
+	orgHandler := handler.NewOrganizationHandler(orgSvc)
+	folderHandler := handler.NewFolderHandler(folderSvc)
+	linkHandler := handler.NewShareLinkHandler(linkSvc)
+	notifyHandler := handler.NewNotificationHandler(notifySvc)
+	webhookHandler := handler.NewWebhookHandler(webhookSvc)
+	realtimeHandler := handler.NewRealtimeHandler(hub, sheetSvc, corsOrigin)
 
 	// ── Router ────────────────────────────────
 	r := gin.Default()
+	// X-Forwarded-For is only believed from these proxies; anyone else
+	// could use it to pick the IP that rate limits and sessions record.
+	if err := r.SetTrustedProxies(trustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	r.Use(middleware.CORS(corsOrigin))
 
 	// Health check
@@ -51,20 +227,131 @@ func main() {
 	})
 
 	// Public routes
+	r.GET("/api/auth/config", authHandler.Config)
+	r.POST("/api/auth/register", authHandler.Register)
 	r.POST("/api/auth/login", authHandler.Login)
+	r.POST("/api/auth/password/forgot", authHandler.ForgotPassword)
+	r.POST("/api/auth/password/reset", authHandler.ResetPassword)
+	if oidcSvc != nil {
+		r.GET("/api/auth/oidc/login", authHandler.OIDCLogin)
+		r.GET("/api/auth/oidc/callback", authHandler.OIDCCallback)
+	}
+	// Share links never write to the spreadsheet: this is their only public
+	// route, and commenter links add comment routes for signed-in users.
+	r.GET("/api/public/links/:token", linkHandler.Open)
 
 	// Protected routes
 	auth := r.Group("/api")
 	auth.Use(middleware.AuthRequired(authSvc))
 	{
 		auth.GET("/auth/me", authHandler.GetCurrentUser)
-		auth.POST("/auth/logout", authHandler.Logout)
+
+		// Account management and the owner's sharing, link and webhook
+		// settings need a session; API tokens are refused.
+		session := auth.Group("", middleware.SessionRequired())
+		session.POST("/auth/logout", authHandler.Logout)
+		session.POST("/auth/password", authHandler.ChangePassword)
+		session.GET("/auth/tokens", authHandler.ListAPITokens)
+		session.POST("/auth/tokens", authHandler.CreateAPIToken)
+		session.DELETE("/auth/tokens/:tokenId", authHandler.RevokeAPIToken)
+		session.GET("/auth/sessions", authHandler.ListSessions)
+		session.DELETE("/auth/sessions", authHandler.RevokeOtherSessions)
+		session.DELETE("/auth/sessions/:sessionId", authHandler.RevokeSession)
+
+		// Comments through a commenter link. API tokens are refused, so a
+		// token limited to one spreadsheet can't reach others by link.
+		session.GET("/links/:token/comments", linkHandler.ListComments)
+		session.POST("/links/:token/comments", linkHandler.CreateComment)
+		session.PATCH("/links/:token/comments/:commentId", linkHandler.EditComment)
+		session.DELETE("/links/:token/comments/:commentId", linkHandler.DeleteComment)
+		session.POST("/links/:token/comments/:commentId/replies", linkHandler.ReplyComment)
+		session.POST("/links/:token/comments/:commentId/resolve", linkHandler.ResolveComment)
+		session.POST("/links/:token/comments/:commentId/reopen", linkHandler.ReopenComment)
 
 		auth.GET("/spreadsheets", sheetHandler.List)
+		auth.GET("/search", sheetHandler.Search)
 		auth.POST("/spreadsheets", sheetHandler.Create)
+		auth.POST("/spreadsheets/import", sheetHandler.Import)
 		auth.GET("/spreadsheets/:id", sheetHandler.Get)
 		auth.PATCH("/spreadsheets/:id", sheetHandler.Update)
 		auth.DELETE("/spreadsheets/:id", sheetHandler.Delete)
+		auth.PUT("/spreadsheets/:id/workspace", sheetHandler.Move)
+		auth.PUT("/spreadsheets/:id/folder", sheetHandler.File)
+		auth.GET("/spreadsheets/:id/export", sheetHandler.Export)
+		auth.GET("/spreadsheets/:id/sheets/:sheet/values", sheetHandler.GetValues)
+		auth.PUT("/spreadsheets/:id/sheets/:sheet/values", sheetHandler.UpdateValues)
+		// Gin can't route a literal colon after a parameter, so
+		// ".../values:append" is matched as an action segment.
+		auth.POST("/spreadsheets/:id/sheets/:sheet/:action", sheetHandler.SheetAction)
+
+		auth.POST("/spreadsheets/:id/access-requests", sheetHandler.RequestAccess)
+		session.GET("/spreadsheets/:id/permissions", sheetHandler.ListPermissions)
+		session.POST("/spreadsheets/:id/permissions", sheetHandler.Share)
+		session.DELETE("/spreadsheets/:id/permissions/:userId", sheetHandler.Unshare)
+		session.GET("/spreadsheets/:id/links", linkHandler.List)
+		session.POST("/spreadsheets/:id/links", linkHandler.Create)
+		session.DELETE("/spreadsheets/:id/links/:linkId", linkHandler.Revoke)
+		session.GET("/spreadsheets/:id/webhooks", webhookHandler.ListSpreadsheetWebhooks)
+		session.POST("/spreadsheets/:id/webhooks", webhookHandler.CreateSpreadsheetWebhook)
+
+		auth.GET("/spreadsheets/:id/comments", sheetHandler.ListComments)
+		auth.POST("/spreadsheets/:id/comments", sheetHandler.CreateComment)
+		auth.GET("/spreadsheets/:id/comments/:commentId", sheetHandler.GetCommentThread)
+		auth.PATCH("/spreadsheets/:id/comments/:commentId", sheetHandler.EditComment)
+		auth.DELETE("/spreadsheets/:id/comments/:commentId", sheetHandler.DeleteComment)
+		auth.POST("/spreadsheets/:id/comments/:commentId/replies", sheetHandler.ReplyComment)
+		auth.POST("/spreadsheets/:id/comments/:commentId/resolve", sheetHandler.ResolveComment)
+		auth.POST("/spreadsheets/:id/comments/:commentId/reopen", sheetHandler.ReopenComment)
+
+		auth.GET("/spreadsheets/:id/revisions", sheetHandler.ListRevisions)
+		auth.GET("/spreadsheets/:id/revisions/:revisionId", sheetHandler.GetRevision)
+		auth.POST("/spreadsheets/:id/revisions/:revisionId/restore", sheetHandler.RestoreRevision)
+
+		auth.POST("/spreadsheets/:id/restore", sheetHandler.Restore)
+		auth.GET("/trash", sheetHandler.ListTrash)
+		auth.DELETE("/trash", sheetHandler.EmptyTrash)
+		auth.DELETE("/trash/:id", sheetHandler.DeleteForever)
+
+		auth.GET("/organizations", orgHandler.List)
+		auth.POST("/organizations", orgHandler.Create)
+		auth.GET("/organizations/:orgId", orgHandler.Get)
+		auth.PATCH("/organizations/:orgId", orgHandler.Update)
+		auth.DELETE("/organizations/:orgId", orgHandler.Delete)
+		auth.GET("/organizations/:orgId/members", orgHandler.ListMembers)
+		auth.POST("/organizations/:orgId/members", orgHandler.AddMember)
+		auth.PATCH("/organizations/:orgId/members/:userId", orgHandler.UpdateMember)
+		auth.DELETE("/organizations/:orgId/members/:userId", orgHandler.RemoveMember)
+		auth.GET("/organizations/:orgId/workspaces", orgHandler.ListOrganizationWorkspaces)
+		auth.POST("/organizations/:orgId/workspaces", orgHandler.CreateWorkspace)
+
+		auth.GET("/workspaces", orgHandler.ListWorkspaces)
+		auth.PATCH("/workspaces/:workspaceId", orgHandler.UpdateWorkspace)
+		auth.DELETE("/workspaces/:workspaceId", orgHandler.DeleteWorkspace)
+		auth.GET("/workspaces/:workspaceId/spreadsheets", orgHandler.ListWorkspaceSpreadsheets)
+		auth.GET("/workspaces/:workspaceId/folders", folderHandler.List)
+		auth.POST("/workspaces/:workspaceId/folders", folderHandler.Create)
+		session.GET("/workspaces/:workspaceId/webhooks", webhookHandler.ListWorkspaceWebhooks)
+		session.POST("/workspaces/:workspaceId/webhooks", webhookHandler.CreateWorkspaceWebhook)
+		auth.GET("/workspaces/:workspaceId/contents", folderHandler.TopLevel)
+		auth.GET("/folders/:folderId", folderHandler.Get)
+		auth.PATCH("/folders/:folderId", folderHandler.Rename)
+		auth.PUT("/folders/:folderId/parent", folderHandler.Move)
+		auth.DELETE("/folders/:folderId", folderHandler.Delete)
+
+		auth.GET("/notifications", notifyHandler.List)
+		auth.POST("/notifications/read-all", notifyHandler.MarkAllRead)
+		auth.POST("/notifications/:notificationId/read", notifyHandler.MarkRead)
+		auth.GET("/notifications/preferences", notifyHandler.GetPreferences)
+		auth.PUT("/notifications/preferences", notifyHandler.UpdatePreferences)
+		auth.GET("/notifications/stream", notifyHandler.Stream)
+
+		session.GET("/webhooks/:webhookId", webhookHandler.Get)
+		session.PATCH("/webhooks/:webhookId", webhookHandler.Update)
+		session.DELETE("/webhooks/:webhookId", webhookHandler.Delete)
+		session.GET("/webhooks/:webhookId/deliveries", webhookHandler.ListDeliveries)
+		session.POST("/webhooks/:webhookId/deliveries/:deliveryId/redeliver", webhookHandler.Redeliver)
+
+		auth.GET("/spreadsheets/:id/ws", realtimeHandler.Connect)
 	}
 
 	// Serve static frontend files in production
@@ -81,9 +368,70 @@ func main() {
 	}
 }
 
+// newMailer builds the mailer selected by MAIL_DRIVER: "log" prints
+// emails, "file" saves them to MAIL_DIR and "smtp" sends them.
+func newMailer(driver, from string) (mail.Mailer, error) {
+	switch driver {
+	case "log":
+		return mail.LogMailer{}, nil
+	case "file":
+		return mail.NewFileMailer(envOr("MAIL_DIR", "mail"), from)
+	case "smtp":
+		port, err := strconv.Atoi(envOr("SMTP_PORT", "587"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SMTP_PORT: %w", err)
+		}
+		return mail.NewSMTPMailer(mail.SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			TLS:      envOr("SMTP_TLS", mail.TLSStartTLS),
+			From:     from,
+		})
+	default:
+		return nil, fmt.Errorf("invalid MAIL_DRIVER %q: expected \"log\", \"file\" or \"smtp\"", driver)
+	}
+}
+
 func envOr(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+func envBool(key string, fallback bool) bool {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: expected true or false", key, v)
+	}
+	return b
+}
+
+// envList splits a comma-separated variable, dropping empty items.
+func envList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func envDuration(key string, fallback time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("Invalid %s %q: %v", key, v, err)
+	}
+	return d
+}
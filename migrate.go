@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"jaggle-grids/internal/repository/gormrepo"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"gorm.io/gorm"
+)
+
+const migrateUsage = `usage: jaggle-grids migrate <command>
+
+commands:
+  up         apply all pending migrations
+  down [N] [--force]
+             roll back the last N applied migrations (default 1); rolling
+             back the baseline, which drops every table, needs --force
+  status     list migrations and whether they have been applied`
+
+// runMigrate implements the migrate subcommand and returns the exit code.
+func runMigrate(db *gorm.DB, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := gormrepo.MigrateUp(db)
+		for _, m := range applied {
+			fmt.Printf("applied %d %s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate up:", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+
+	case "down":
+		steps, force := 1, false
+		for _, arg := range args[1:] {
+			if arg == "--force" || arg == "-force" {
+				force = true
+				continue
+			}
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "invalid step count %q\n", arg)
+				return 2
+			}
+			steps = n
+		}
+		rolledBack, err := gormrepo.MigrateDown(db, steps, force)
+		for _, m := range rolledBack {
+			fmt.Printf("rolled back %d %s\n", m.Version, m.Name)
+		}
+		if errors.Is(err, gormrepo.ErrBaselineRollback) {
+			fmt.Fprintf(os.Stderr, "migrate down: %v; pass --force to do it anyway\n", err)
+			return 1
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate down:", err)
+			return 1
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("nothing to roll back")
+		}
+
+	case "status":
+		states, err := gormrepo.MigrationStatus(db)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "migrate status:", err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+		for _, s := range states {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		w.Flush()
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}